@@ -0,0 +1,173 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cache is a key-value cache of the encoded values with expiration.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value of the key, or false if the key is not found or is expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set sets the value of the key, which expires after the ttl.
+	// A non-positive ttl means the value doesn't expire.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Noop is a Cache which doesn't store any value.
+// It could be used to disable the caching without changing the call sites.
+type Noop struct{}
+
+// Get implements Cache interface, the key is never found.
+func (Noop) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+
+// Set implements Cache interface, the value is discarded.
+func (Noop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+// MemoryOption is an option of the Memory cache.
+type MemoryOption func(*Memory) error
+
+// MaxEntriesOpt is an option that limits the number of entries of the Memory cache.
+// When the limit is reached, the expired entries are removed, and if none, the entry expiring first is evicted.
+func MaxEntriesOpt(n int) MemoryOption {
+	return func(m *Memory) error {
+		if n <= 0 {
+			return errors.New("max entries must be positive")
+		}
+		m.maxEntries = n
+		return nil
+	}
+}
+
+// ClockOpt is an option that sets the clock of the Memory cache expiration.
+// By default, the time.Now is used.
+func ClockOpt(clock func() time.Time) MemoryOption {
+	return func(m *Memory) error {
+		if clock == nil {
+			return errors.New("clock is not set")
+		}
+		m.now = clock
+		return nil
+	}
+}
+
+// Memory is an in-memory Cache.
+// It is safe for concurrent use.
+type Memory struct {
+	now        func() time.Time
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value []byte
+	// expires is the expiration time, zero if the entry doesn't expire.
+	expires time.Time
+}
+
+// NewMemory creates a new in-memory cache.
+func NewMemory(opts ...MemoryOption) (*Memory, error) {
+	m := Memory{
+		now:     time.Now,
+		entries: make(map[string]memoryEntry),
+	}
+	for _, opt := range opts {
+		if err := opt(&m); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Get implements Cache interface.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !e.expires.IsZero() && !m.now().Before(e.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements Cache interface.
+// The value is stored as is, and must not be modified afterwards.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	if _, ok := m.entries[key]; !ok && m.maxEntries > 0 && len(m.entries) >= m.maxEntries {
+		m.evict(now)
+	}
+	m.entries[key] = e
+	return nil
+}
+
+// Len returns the number of the entries, including the expired ones that were not removed yet.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// evict removes the expired entries, or the entry expiring first if none is expired.
+func (m *Memory) evict(now time.Time) {
+	var (
+		first    string
+		firstExp time.Time
+		found    bool
+	)
+	for key, e := range m.entries {
+		if e.expires.IsZero() {
+			continue
+		}
+		if !now.Before(e.expires) {
+			delete(m.entries, key)
+			continue
+		}
+		if !found || e.expires.Before(firstExp) {
+			first, firstExp, found = key, e.expires, true
+		}
+	}
+	if len(m.entries) < m.maxEntries {
+		return
+	}
+	if !found {
+		// None of the entries expires, evict any of them.
+		for key := range m.entries {
+			first = key
+			break
+		}
+	}
+	delete(m.entries, first)
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	m, err := NewMemory(ClockOpt(func() time.Time { return now }), MaxEntriesOpt(2))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if err = m.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok, _ := m.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("expected value 1 but got %q, %v", v, ok)
+	}
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("expected missing key")
+	}
+
+	t.Run("eviction", func(t *testing.T) {
+		_ = m.Set(ctx, "b", []byte("2"), time.Hour)
+		_ = m.Set(ctx, "c", []byte("3"), 0)
+		if m.Len() != 2 {
+			t.Fatalf("expected 2 entries but got %d", m.Len())
+		}
+		if _, ok, _ := m.Get(ctx, "a"); ok {
+			t.Error("expected the entry expiring first to be evicted")
+		}
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		if _, ok, _ := m.Get(ctx, "b"); ok {
+			t.Error("expected expired key")
+		}
+		if v, ok, _ := m.Get(ctx, "c"); !ok || string(v) != "3" {
+			t.Errorf("expected non-expiring value 3 but got %q, %v", v, ok)
+		}
+	})
+}
+
+func TestNoop(t *testing.T) {
+	var c Cache = Noop{}
+	if err := c.Set(context.Background(), "a", []byte("1"), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := c.Get(context.Background(), "a"); ok {
+		t.Error("expected no value")
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache defines the Cache interface of the encoded values with expiration, used by the parsed filters cache
+// of the filtering.Interpreter and the pagination.TokenValidator.
+// The package provides the in-memory and the no-op implementations, while the applications can back the Cache
+// with i.e. Redis or groupcache without changing the call sites.
+package cache
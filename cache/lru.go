@@ -0,0 +1,150 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// LRUOption is an option of the LRU cache.
+type LRUOption func(*lruOptions) error
+
+// lruOptions are the options of the LRU cache, independent of its value type.
+type lruOptions struct {
+	now func() time.Time
+}
+
+// LRUClockOpt is an option that sets the clock of the LRU cache expiration.
+// By default, the time.Now is used.
+func LRUClockOpt(clock func() time.Time) LRUOption {
+	return func(o *lruOptions) error {
+		if clock == nil {
+			return errors.New("clock is not set")
+		}
+		o.now = clock
+		return nil
+	}
+}
+
+// Compile-time check to verify that the LRU of the encoded values implements the Cache interface.
+var _ Cache = (*LRU[[]byte])(nil)
+
+// LRU is an in-memory cache of the values of type V limited in size,
+// which evicts the least recently used entry when it is full.
+// The LRU[[]byte] implements the Cache interface, and could be used with the filtering.CacheOpt,
+// whereas the LRU[expr.FilterExpr] keeps the parsed expressions as they are, see the filtering.ExprCacheOpt.
+// It is safe for concurrent use.
+type LRU[V any] struct {
+	now  func() time.Time
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru is the list of the lruEntry, the most recently used first.
+	lru list.List
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+	// expires is the expiration time, zero if the entry doesn't expire.
+	expires time.Time
+}
+
+// NewLRU creates a new LRU cache of at most size entries.
+func NewLRU[V any](size int, opts ...LRUOption) (*LRU[V], error) {
+	if size <= 0 {
+		return nil, errors.New("size must be positive")
+	}
+	o := lruOptions{now: time.Now}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	return &LRU[V]{
+		now:     o.now,
+		size:    size,
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// Get returns the value of the key, or false if the key is not found or is expired.
+// A found entry becomes the most recently used one.
+func (c *LRU[V]) Get(_ context.Context, key string) (V, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	e, ok := c.entries[key]
+	if !ok {
+		return zero, false, nil
+	}
+	le := e.Value.(*lruEntry[V])
+	if !le.expires.IsZero() && !c.now().Before(le.expires) {
+		c.remove(e)
+		return zero, false, nil
+	}
+	c.lru.MoveToFront(e)
+	return le.value, true, nil
+}
+
+// Set sets the value of the key, which expires after the ttl.
+// A non-positive ttl means the value doesn't expire.
+// The value is stored as is, and must not be modified afterwards.
+func (c *LRU[V]) Set(_ context.Context, key string, value V, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	le := &lruEntry[V]{key: key, value: value}
+	if ttl > 0 {
+		le.expires = c.now().Add(ttl)
+	}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = le
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	for c.lru.Len() >= c.size {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(le)
+	return nil
+}
+
+// Len returns the number of the entries, including the expired ones that were not removed yet.
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes all the entries, i.e. after the options of the interpreter using the cache changed.
+func (c *LRU[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	clear(c.entries)
+}
+
+func (c *LRU[V]) remove(e *list.Element) {
+	le := c.lru.Remove(e).(*lruEntry[V])
+	delete(c.entries, le.key)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, err := NewLRU[[]byte](2, LRUClockOpt(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if err = c.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, ok, _ := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("expected value 1 but got %q, %v", v, ok)
+	}
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("expected missing key")
+	}
+
+	t.Run("least recently used evicted", func(t *testing.T) {
+		_ = c.Set(ctx, "b", []byte("2"), 0)
+		_, _, _ = c.Get(ctx, "a")
+		_ = c.Set(ctx, "c", []byte("3"), 0)
+		if c.Len() != 2 {
+			t.Fatalf("expected 2 entries but got %d", c.Len())
+		}
+		if _, ok, _ := c.Get(ctx, "b"); ok {
+			t.Error("expected the least recently used entry to be evicted")
+		}
+		if _, ok, _ := c.Get(ctx, "a"); !ok {
+			t.Error("expected the recently used entry to be kept")
+		}
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		_ = c.Set(ctx, "c", []byte("4"), 0)
+		if v, ok, _ := c.Get(ctx, "c"); !ok || string(v) != "4" {
+			t.Errorf("expected replaced value 4 but got %q, %v", v, ok)
+		}
+		if c.Len() != 2 {
+			t.Errorf("expected 2 entries but got %d", c.Len())
+		}
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		if _, ok, _ := c.Get(ctx, "a"); ok {
+			t.Error("expected expired key")
+		}
+		if c.Len() != 1 {
+			t.Errorf("expected the expired entry to be removed, but got %d entries", c.Len())
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		c.Purge()
+		if c.Len() != 0 {
+			t.Errorf("expected no entries after purge but got %d", c.Len())
+		}
+	})
+}
+
+func TestLRU_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewLRU[[]byte](2)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				key := strconv.Itoa((g + n) % 3)
+				if _, ok, _ := c.Get(ctx, key); !ok {
+					_ = c.Set(ctx, key, []byte(key), 0)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if c.Len() > 2 {
+		t.Errorf("expected at most 2 entries but got %d", c.Len())
+	}
+}
+
+func TestNewLRU_Invalid(t *testing.T) {
+	if _, err := NewLRU[[]byte](0); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := NewLRU[[]byte](1, LRUClockOpt(nil)); err == nil {
+		t.Error("expected error")
+	}
+}
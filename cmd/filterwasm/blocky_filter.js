@@ -0,0 +1,54 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// JavaScript bindings of the filterwasm module.
+// The wasm_exec.js of the Go distribution needs to be loaded first, as it defines the global Go class.
+
+let started = null;
+
+// loadFilterModule starts the filter.wasm module served at given URL, once.
+function loadFilterModule(url) {
+  if (started === null) {
+    const go = new Go();
+    started = WebAssembly.instantiateStreaming(fetch(url), go.importObject).then((res) => {
+      // The module keeps running, the promise of the run resolves only when it exits.
+      go.run(res.instance);
+      return globalThis.blockyFilter;
+    });
+  }
+  return started;
+}
+
+// newFilterValidator returns the validator of the filters of the message with given full name.
+// The descriptorSet is the serialized google.protobuf.FileDescriptorSet (Uint8Array) declaring the message,
+// and the options are the optional JSON encoded filtering.InterpreterOptions.
+// The validate method of the validator returns {valid, code, pos, snippet, message}.
+async function newFilterValidator(url, descriptorSet, messageName, options) {
+  const mod = await loadFilterModule(url);
+  const v = mod.newValidator(descriptorSet, messageName, options || "");
+  if (v.error !== undefined) {
+    throw new Error(v.error);
+  }
+  return {
+    validate(filter) {
+      const res = v.validate(filter);
+      if (res.error !== undefined) {
+        throw new Error(res.error);
+      }
+      return res;
+    },
+  };
+}
+
+globalThis.newFilterValidator = newFilterValidator;
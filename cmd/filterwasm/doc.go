@@ -0,0 +1,28 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command filterwasm is a WebAssembly build of the filtering interpreter,
+// which allows the web consoles to validate the filters client side, with the same engine as the server.
+// The messages are resolved from the serialized file descriptor set, i.e. obtained by the gRPC server reflection.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o filter.wasm ./cmd/filterwasm
+//
+// and serve the filter.wasm along with the wasm_exec.js of the Go distribution and the blocky_filter.js bindings.
+// Once started, the module registers the global blockyFilter object, wrapped by the bindings:
+//
+//	const v = await newFilterValidator("filter.wasm", descriptorSet, "pkg.Message", '{"max_depth": 8}');
+//	const res = v.validate('name = "foo"'); // {valid: false, code: "...", pos: 0, snippet: "...", message: "..."}
+package main
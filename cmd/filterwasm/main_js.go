@@ -0,0 +1,72 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+func main() {
+	js.Global().Set("blockyFilter", js.ValueOf(map[string]any{
+		"newValidator": js.FuncOf(jsNewValidator),
+	}))
+
+	// Keep the module running, so that the registered functions could be called.
+	select {}
+}
+
+// jsNewValidator implements blockyFilter.newValidator(descriptorSet: Uint8Array, name: string, options?: string).
+// It returns the validator object, or an object with the error message.
+// The errors are not thrown, as a panic in the callback would terminate the Go runtime.
+func jsNewValidator(_ js.Value, args []js.Value) any {
+	if len(args) < 2 {
+		return jsError("newValidator requires the descriptor set and the message name")
+	}
+	fds := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(fds, args[0])
+
+	var options string
+	if len(args) > 2 && args[2].Type() == js.TypeString {
+		options = args[2].String()
+	}
+
+	v, err := newValidator(fds, args[1].String(), options)
+	if err != nil {
+		return jsError(err.Error())
+	}
+
+	return js.ValueOf(map[string]any{
+		"validate": js.FuncOf(func(_ js.Value, args []js.Value) any {
+			if len(args) < 1 || args[0].Type() != js.TypeString {
+				return jsError("validate requires the filter string")
+			}
+			res := v.validate(args[0].String())
+			return js.ValueOf(map[string]any{
+				"valid":   res.Valid,
+				"code":    res.Code,
+				"pos":     res.Pos,
+				"snippet": res.Snippet,
+				"message": res.Message,
+			})
+		}),
+	})
+}
+
+// jsError returns the object with given error message, converted to the Error by the blocky_filter.js bindings.
+func jsError(msg string) any {
+	return js.ValueOf(map[string]any{"error": msg})
+}
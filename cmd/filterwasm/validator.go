@@ -0,0 +1,86 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+// result is the outcome of the filter validation, passed to the JavaScript caller.
+type result struct {
+	Valid   bool   `json:"valid"`
+	Code    string `json:"code,omitempty"`
+	Pos     int    `json:"pos"`
+	Snippet string `json:"snippet,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// validator validates the filters of a single message.
+type validator struct {
+	i *filtering.Interpreter
+}
+
+// newValidator creates a validator of the message with given full name,
+// resolved from the serialized file descriptor set.
+// The options are the JSON encoded filtering.InterpreterOptions, and could be empty.
+func newValidator(descriptorSet []byte, name string, options string) (*validator, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(descriptorSet, &fds); err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set: %w", err)
+	}
+
+	var opts filtering.InterpreterOptions
+	if options != "" {
+		if err := json.Unmarshal([]byte(options), &opts); err != nil {
+			return nil, fmt.Errorf("invalid interpreter options: %w", err)
+		}
+	}
+
+	i, err := filtering.NewInterpreterFromDescriptorSet(&fds, protoreflect.FullName(name), opts.Opt())
+	if err != nil {
+		return nil, err
+	}
+	return &validator{i: i}, nil
+}
+
+// validate parses the filter and reports whether it is valid.
+func (v *validator) validate(filter string) result {
+	x, err := v.i.Parse(filter)
+	if err == nil {
+		if x != nil {
+			x.Free()
+		}
+		return result{Valid: true}
+	}
+
+	var fe *filtering.FilterError
+	if !errors.As(err, &fe) {
+		return result{Code: filtering.ErrorCodeUnknown.String(), Message: err.Error()}
+	}
+	return result{
+		Code:    fe.Code.String(),
+		Pos:     int(fe.Pos),
+		Snippet: fe.Snippet,
+		Message: fe.Error(),
+	}
+}
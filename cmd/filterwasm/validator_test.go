@@ -0,0 +1,87 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestValidator(t *testing.T) {
+	fds, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(testpb.File_internal_testpb_message_proto)},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal file descriptor set: %v", err)
+	}
+
+	t.Run("new", func(t *testing.T) {
+		tc := []struct {
+			name    string
+			fds     []byte
+			message string
+			options string
+		}{
+			{name: "invalid descriptor set", fds: []byte("foo"), message: "testpb.Message"},
+			{name: "message not found", fds: fds, message: "testpb.Unknown"},
+			{name: "invalid options", fds: fds, message: "testpb.Message", options: "{"},
+			{name: "unknown preset", fds: fds, message: "testpb.Message", options: `{"preset": "unknown"}`},
+		}
+		for _, tt := range tc {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := newValidator(tt.fds, tt.message, tt.options); err == nil {
+					t.Fatal("expected error")
+				}
+			})
+		}
+	})
+
+	v, err := newValidator(fds, "testpb.Message", `{"max_depth": 2}`)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   result
+	}{
+		{name: "valid", filter: `name = "foo" AND i32 > 1`, want: result{Valid: true}},
+		{name: "empty", filter: "", want: result{Valid: true}},
+		{name: "syntax", filter: `name = `, want: result{Code: filtering.ErrorCodeInvalidSyntax.String()}},
+		{name: "field", filter: `unknown = 1`, want: result{Code: filtering.ErrorCodeFieldNotFound.String(), Snippet: "unknown"}},
+		{name: "depth", filter: `(((name = "foo")))`, want: result{Code: filtering.ErrorCodeLimitExceeded.String()}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := v.validate(tt.filter)
+			if got.Valid != tt.want.Valid || got.Code != tt.want.Code {
+				t.Fatalf("expected valid: %v, code: %q, got: %+v", tt.want.Valid, tt.want.Code, got)
+			}
+			if tt.want.Snippet != "" && got.Snippet != tt.want.Snippet {
+				t.Errorf("expected snippet %q, got %q", tt.want.Snippet, got.Snippet)
+			}
+			if !got.Valid && got.Message == "" {
+				t.Error("expected error message")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+const (
+	filterbuildPackage = protogen.GoImportPath("github.com/blockysource/blocky-aip/filterbuild")
+	timePackage        = protogen.GoImportPath("time")
+)
+
+// selector is a generated typed selector of a field path.
+type selector struct {
+	// name is the name of the generated function, i.e.: DriverName.
+	name string
+	// path is the dot separated field path, i.e.: driver.name.
+	path string
+	// constructor is the filterbuild constructor of the selector.
+	constructor string
+	// valueType is the Go type of the field values, empty for the message fields.
+	valueType string
+	depth     int
+}
+
+// generateFile generates the filter builder packages of the top level messages of the file.
+func generateFile(gen *protogen.Plugin, file *protogen.File, depth int) {
+	for _, msg := range file.Messages {
+		if msg.Desc.IsMapEntry() {
+			continue
+		}
+		generateMessage(gen, file, msg, depth)
+	}
+}
+
+func generateMessage(gen *protogen.Plugin, file *protogen.File, msg *protogen.Message, depth int) {
+	pkg := strings.ToLower(msg.GoIdent.GoName) + "filter"
+	filename := path.Join(path.Dir(file.GeneratedFilenamePrefix), pkg, path.Base(file.GeneratedFilenamePrefix)+".filter.pb.go")
+	g := gen.NewGeneratedFile(filename, protogen.GoImportPath(path.Join(string(file.GoImportPath), pkg)))
+
+	g.P("// Code generated by protoc-gen-go-aipfilter. DO NOT EDIT.")
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("// Package ", pkg, " contains the typed filter builders of the ", msg.Desc.FullName(), " message.")
+	g.P("package ", pkg)
+	g.P()
+
+	mi := info.MapMsgInfo(msg.Desc)
+	var sels []selector
+	sels = collectSelectors(g, mi, sels, msg, "", "", 0, depth)
+
+	g.P("var desc = (*", msg.GoIdent, ")(nil).ProtoReflect().Descriptor()")
+	g.P()
+	g.P("var (")
+	for _, s := range sels {
+		if s.valueType == "" {
+			g.P("f", s.name, " = ", filterbuildPackage.Ident(s.constructor), "(desc, ", strconv.Quote(s.path), ")")
+			continue
+		}
+		g.P("f", s.name, " = ", filterbuildPackage.Ident(s.constructor), "[", s.valueType, "](desc, ", strconv.Quote(s.path), ")")
+	}
+	g.P(")")
+	for _, s := range sels {
+		g.P()
+		g.P("// ", s.name, " returns the typed selector of the ", s.path, " field.")
+		if s.valueType == "" {
+			g.P("func ", s.name, "() ", filterbuildPackage.Ident(typeOf(s.constructor)), " { return f", s.name, " }")
+			continue
+		}
+		g.P("func ", s.name, "() ", filterbuildPackage.Ident(typeOf(s.constructor)), "[", s.valueType, "] { return f", s.name, " }")
+	}
+}
+
+// collectSelectors appends the selectors of the msg fields, expanding the singular message fields up to the maxDepth.
+// If two field paths result in the same function name, the shallower one is kept.
+func collectSelectors(g *protogen.GeneratedFile, mi info.MessagesInfo, sels []selector, msg *protogen.Message, pathPrefix, namePrefix string, depth, maxDepth int) []selector {
+	for _, f := range msg.Fields {
+		if f.Desc.IsMap() || mi.GetFieldInfo(f.Desc).FilteringForbidden {
+			continue
+		}
+		s := selector{
+			name:  namePrefix + f.GoName,
+			path:  pathPrefix + string(f.Desc.Name()),
+			depth: depth,
+		}
+
+		s.valueType = valueType(g, f)
+		switch {
+		case s.valueType == "" && f.Desc.IsList():
+			continue
+		case s.valueType == "":
+			s.constructor = "NewMessageField"
+		case f.Desc.IsList():
+			s.constructor = "NewRepeatedField"
+		default:
+			s.constructor = "NewField"
+		}
+		sels = addSelector(sels, s)
+
+		if s.constructor == "NewMessageField" && depth < maxDepth {
+			sels = collectSelectors(g, mi, sels, f.Message, s.path+".", s.name, depth+1, maxDepth)
+		}
+	}
+	return sels
+}
+
+func addSelector(sels []selector, s selector) []selector {
+	for i, o := range sels {
+		if o.name != s.name {
+			continue
+		}
+		if s.depth < o.depth {
+			sels[i] = s
+		}
+		return sels
+	}
+	return append(sels, s)
+}
+
+// valueType returns the Go type of the field values, which is empty for the non-scalar message fields.
+func valueType(g *protogen.GeneratedFile, f *protogen.Field) string {
+	switch f.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "[]byte"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.EnumKind:
+		return g.QualifiedGoIdent(f.Enum.GoIdent)
+	case protoreflect.MessageKind:
+		switch f.Desc.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return g.QualifiedGoIdent(timePackage.Ident("Time"))
+		case "google.protobuf.Duration":
+			return g.QualifiedGoIdent(timePackage.Ident("Duration"))
+		}
+	}
+	return ""
+}
+
+// typeOf returns the filterbuild type name created by the constructor.
+func typeOf(constructor string) string {
+	return strings.TrimPrefix(constructor, "New")
+}
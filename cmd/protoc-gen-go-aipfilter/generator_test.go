@@ -0,0 +1,101 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+// codeGeneratorRequest returns the request of the file fd generation, with all its dependencies.
+func codeGeneratorRequest(fd protoreflect.FileDescriptor) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{fd.Path()}}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(fd)
+	return req
+}
+
+func TestGenerateFile(t *testing.T) {
+	fd := new(testpb.Message).ProtoReflect().Descriptor().ParentFile()
+	gen, err := protogen.Options{}.New(codeGeneratorRequest(fd))
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			generateFile(gen, f, 1)
+		}
+	}
+
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("failed to generate: %s", resp.GetError())
+	}
+
+	var content string
+	for _, f := range resp.File {
+		if f.GetName() == "github.com/blockysource/blocky-aip/internal/testpb/messagefilter/message.filter.pb.go" {
+			content = f.GetContent()
+		}
+	}
+	if content == "" {
+		var names []string
+		for _, f := range resp.File {
+			names = append(names, f.GetName())
+		}
+		t.Fatalf("message filter file not generated, got: %v", names)
+	}
+
+	// Collapse the alignment of the generated var block.
+	content = strings.Join(strings.Fields(content), " ")
+	for _, want := range []string{
+		"package messagefilter",
+		`fStr = filterbuild.NewField[string](desc, "str")`,
+		"func Str() filterbuild.Field[string] { return fStr }",
+		"func Enum() filterbuild.Field[testpb.Enum] { return fEnum }",
+		"func Timestamp() filterbuild.Field[time.Time] { return fTimestamp }",
+		"func RpStr() filterbuild.RepeatedField[string] { return fRpStr }",
+		"func Sub() filterbuild.MessageField { return fSub }",
+		`fSubName = filterbuild.NewField[string](desc, "sub.name")`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected generated code to contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"func NoFilter()", "func MapStrI32()", "func SubSubName()"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("expected generated code not to contain %q", unwanted)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command protoc-gen-go-aipfilter is a protoc plugin, which generates the typed filter builders
+// of the messages, based on the filterbuild package.
+// Each top level message gets its own package, i.e.: the carfilter of the Car message,
+// with the functions returning the typed selectors of its fields, i.e.: carfilter.DriverName().Eq("x").
+//
+// The depth parameter sets how many levels of the singular message fields are expanded (default 1),
+// i.e.: --go-aipfilter_opt=depth=2.
+package main
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	var flags flag.FlagSet
+	depth := flags.Int("depth", 1, "levels of the singular message fields to expand")
+
+	protogen.Options{ParamFunc: flags.Set}.Run(func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			generateFile(gen, f, *depth)
+		}
+		return nil
+	})
+}
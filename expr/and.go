@@ -26,7 +26,7 @@ func init() {
 var andExprPool = &sync.Pool{
 	New: func() any {
 		return &AndExpr{
-			Expr:       make([]FilterExpr, 0, 10),
+			Expr:       make([]FilterExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -35,7 +35,9 @@ var andExprPool = &sync.Pool{
 // AcquireAndExpr acquires an AndExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireAndExpr() *AndExpr {
-	return andExprPool.Get().(*AndExpr)
+	x := andExprPool.Get().(*AndExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Free puts the AndExpr back to the pool.
@@ -48,12 +50,56 @@ func (e *AndExpr) Free() {
 			sub.Free()
 		}
 	}
+	clear(e.Expr)
+	e.Expr = e.Expr[:0]
 	if e.isAcquired {
-		e.Expr = e.Expr[:0]
+		trackFree(e)
+		if !isRetainable(cap(e.Expr)) {
+			return
+		}
 		andExprPool.Put(e)
 	}
 }
 
+// And returns the conjunction of the filter expressions, taking over their ownership.
+// The nil and match-all expressions are skipped, and the AndExpr operands are flattened,
+// so that i.e. a base access scope could be combined with a user filter without special-casing the empty filter.
+// If no expression remains, the result is nil, and a single remaining expression is returned as is.
+func And(x ...FilterExpr) FilterExpr {
+	ae := AcquireAndExpr()
+	for _, sub := range x {
+		switch st := sub.(type) {
+		case *AndExpr:
+			if st == nil {
+				continue
+			}
+			ae.Expr = append(ae.Expr, st.Expr...)
+			clear(st.Expr)
+			st.Expr = st.Expr[:0]
+			st.Free()
+		case *MatchAllExpr:
+			st.Free()
+		default:
+			if !isNilExpr(sub) {
+				ae.Expr = append(ae.Expr, sub)
+			}
+		}
+	}
+
+	switch len(ae.Expr) {
+	case 0:
+		ae.Free()
+		return nil
+	case 1:
+		only := ae.Expr[0]
+		clear(ae.Expr)
+		ae.Expr = ae.Expr[:0]
+		ae.Free()
+		return only
+	}
+	return ae
+}
+
 var _ FilterExpr = (*AndExpr)(nil)
 
 // AndExpr is an expression that can be evaluated.
@@ -65,10 +111,9 @@ type AndExpr struct {
 
 // Equals returns true if the given expression is equal to the current one.
 func (e *AndExpr) Equals(other Expr) bool {
-	if other == nil {
+	if e == nil || other == nil {
 		return false
 	}
-
 	a, ok := other.(*AndExpr)
 	if !ok {
 		return false
@@ -79,7 +124,7 @@ func (e *AndExpr) Equals(other Expr) bool {
 	}
 
 	for i := range e.Expr {
-		if !e.Expr[i].Equals(a.Expr[i]) {
+		if !equalExpr(e.Expr[i], a.Expr[i]) {
 			return false
 		}
 	}
@@ -94,7 +139,7 @@ func (e *AndExpr) Clone() Expr {
 
 	clone := AcquireAndExpr()
 	for _, expr := range e.Expr {
-		clone.Expr = append(clone.Expr, expr.Clone().(FilterExpr))
+		clone.Expr = append(clone.Expr, cloneFilterExpr(expr))
 	}
 	return clone
 }
@@ -0,0 +1,136 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestAnd(t *testing.T) {
+	LeakCheck(t)
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) FilterExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+
+	tc := []struct {
+		name string
+		x    func() []FilterExpr
+		want func() FilterExpr
+	}{
+		{
+			name: "empty",
+			x:    func() []FilterExpr { return nil },
+			want: func() FilterExpr { return nil },
+		},
+		{
+			name: "nil and match all",
+			x:    func() []FilterExpr { return []FilterExpr{nil, AcquireMatchAllExpr(), (*AndExpr)(nil)} },
+			want: func() FilterExpr { return nil },
+		},
+		{
+			name: "single",
+			x:    func() []FilterExpr { return []FilterExpr{nil, eq("i32", int64(1)), AcquireMatchAllExpr()} },
+			want: func() FilterExpr { return eq("i32", int64(1)) },
+		},
+		{
+			name: "conjunction",
+			x:    func() []FilterExpr { return []FilterExpr{eq("i32", int64(1)), eq("str", "a")} },
+			want: func() FilterExpr { return c.And(eq("i32", int64(1)), eq("str", "a")) },
+		},
+		{
+			name: "flattened",
+			x: func() []FilterExpr {
+				return []FilterExpr{eq("i32", int64(1)), c.And(eq("str", "a"), c.Or(eq("bool", true), eq("i64", int64(2))))}
+			},
+			want: func() FilterExpr {
+				return c.And(eq("i32", int64(1)), eq("str", "a"), c.Or(eq("bool", true), eq("i64", int64(2))))
+			},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := And(tt.x()...)
+			want := tt.want()
+			defer FreeAll(got, want)
+			if want == nil {
+				if got != nil {
+					t.Fatalf("expected nil but got %T", got)
+				}
+				return
+			}
+			if !want.Equals(got) {
+				t.Errorf("expected %v but got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestOr(t *testing.T) {
+	LeakCheck(t)
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) FilterExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+
+	tc := []struct {
+		name string
+		x    func() []FilterExpr
+		want func() FilterExpr
+	}{
+		{
+			name: "empty",
+			x:    func() []FilterExpr { return []FilterExpr{nil} },
+			want: func() FilterExpr { return nil },
+		},
+		{
+			name: "single",
+			x:    func() []FilterExpr { return []FilterExpr{nil, eq("i32", int64(1))} },
+			want: func() FilterExpr { return eq("i32", int64(1)) },
+		},
+		{
+			name: "flattened",
+			x: func() []FilterExpr {
+				return []FilterExpr{c.Or(eq("i32", int64(1)), eq("str", "a")), c.And(eq("bool", true), eq("i64", int64(2)))}
+			},
+			want: func() FilterExpr {
+				return c.Or(eq("i32", int64(1)), eq("str", "a"), c.And(eq("bool", true), eq("i64", int64(2))))
+			},
+		},
+		{
+			name: "match all",
+			x:    func() []FilterExpr { return []FilterExpr{eq("i32", int64(1)), AcquireMatchAllExpr()} },
+			want: func() FilterExpr { return AcquireMatchAllExpr() },
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Or(tt.x()...)
+			want := tt.want()
+			defer FreeAll(got, want)
+			if want == nil {
+				if got != nil {
+					t.Fatalf("expected nil but got %T", got)
+				}
+				return
+			}
+			if !want.Equals(got) {
+				t.Errorf("expected %v but got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func init() {
+	gob.Register(new(AnyElementExpr))
+}
+
+var anyElementExprPool = &sync.Pool{
+	New: func() any {
+		return &AnyElementExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireAnyElementExpr acquires an AnyElementExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquireAnyElementExpr() *AnyElementExpr {
+	x := anyElementExprPool.Get().(*AnyElementExpr)
+	trackAcquire(x)
+	return x
+}
+
+// Compile-time check to verify that AnyElementExpr implements Expr and FilterExpr interface.
+var (
+	_ FilterExpr = (*AnyElementExpr)(nil)
+	_ Expr       = (*AnyElementExpr)(nil)
+)
+
+// AnyElementExpr is an expression that matches if any element of a repeated message field
+// satisfies the Filter. It is used as the right hand side of the CompareExpr with the HAS comparator,
+// i.e. the filter `items:{sku: "abc"}` matches if some element of the items has the sku equal to "abc".
+// The field selectors within the Filter are relative to the element Message.
+// If All is set, the expression matches only if every element satisfies the Filter,
+// i.e. the filter `all(items, quantity > 0)`.
+type AnyElementExpr struct {
+	// Message is the full name of the element message.
+	Message protoreflect.FullName
+
+	// Filter is a filter that needs to be satisfied by an element.
+	// A nil Filter matches any element.
+	Filter FilterExpr
+
+	// All is the universal quantifier of the expression, which requires all the elements to satisfy the Filter.
+	// An empty repeated field satisfies all the filters.
+	All bool
+
+	isAcquired bool
+}
+
+// Clone returns a copy of the current expression.
+func (e *AnyElementExpr) Clone() Expr {
+	if e == nil {
+		return nil
+	}
+	ae := AcquireAnyElementExpr()
+	ae.Message = e.Message
+	ae.Filter = cloneFilterExpr(e.Filter)
+	ae.All = e.All
+	return ae
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (e *AnyElementExpr) Equals(other Expr) bool {
+	if e == nil || other == nil {
+		return false
+	}
+	oa, ok := other.(*AnyElementExpr)
+	if !ok {
+		return false
+	}
+	if e.Message != oa.Message || e.All != oa.All {
+		return false
+	}
+	return equalExpr(e.Filter, oa.Filter)
+}
+
+// Free puts the AnyElementExpr back to the pool.
+func (e *AnyElementExpr) Free() {
+	if e == nil {
+		return
+	}
+	if e.Filter != nil {
+		e.Filter.Free()
+		e.Filter = nil
+	}
+	if e.isAcquired {
+		trackFree(e)
+		e.Message = ""
+		e.All = false
+		anyElementExprPool.Put(e)
+	}
+}
+
+// Complexity of the AnyElementExpr is 1 + complexity of the element filter.
+func (e *AnyElementExpr) Complexity() int64 {
+	if e.Filter == nil {
+		return 1
+	}
+	return 1 + e.Filter.Complexity()
+}
+
+func (e *AnyElementExpr) isFilterExpr() {}
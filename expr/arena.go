@@ -0,0 +1,94 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"context"
+	"sync"
+)
+
+// Arena is a request-scoped owner of the expressions.
+// The expressions created during a single request, i.e. the filter, order by and update expressions,
+// are added to the arena and released back to the pools at once, with a single call to Release at the end of the request.
+// This removes the bookkeeping of the Free calls of each expression, and the risk of freeing
+// an expression that is still in use by other parts of the request.
+//
+// The expressions owned by the arena must not be freed individually.
+// An expression added to the arena multiple times is freed only once.
+// The zero value is ready to use, and an Arena is safe for concurrent use.
+type Arena struct {
+	mu    sync.Mutex
+	exprs []Expr
+	owned map[Expr]struct{}
+}
+
+// NewArena creates a new empty arena.
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// Own adds the expression to the arena, so that it is freed on Release.
+// A nil expression is ignored.
+func (a *Arena) Own(x Expr) {
+	if x == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.owned == nil {
+		a.owned = make(map[Expr]struct{})
+	}
+	if _, ok := a.owned[x]; ok {
+		return
+	}
+	a.owned[x] = struct{}{}
+	a.exprs = append(a.exprs, x)
+}
+
+// Len returns the number of expressions owned by the arena.
+func (a *Arena) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.exprs)
+}
+
+// Release frees all the expressions owned by the arena, in the reverse order of adding them.
+// No further use of the released expressions is allowed.
+// The arena is empty afterward and can be reused for the next request.
+func (a *Arena) Release() {
+	a.mu.Lock()
+	exprs := a.exprs
+	a.exprs = nil
+	a.owned = nil
+	a.mu.Unlock()
+
+	for i := len(exprs) - 1; i >= 0; i-- {
+		exprs[i].Free()
+	}
+}
+
+type arenaCtxKey struct{}
+
+// WithArena returns a copy of the context, that carries the arena.
+// It is used to pass the request-scoped arena, i.e. from the RPC interceptor to the handlers.
+func WithArena(ctx context.Context, a *Arena) context.Context {
+	return context.WithValue(ctx, arenaCtxKey{}, a)
+}
+
+// ArenaFromContext returns the arena carried by the context, if any.
+func ArenaFromContext(ctx context.Context) (*Arena, bool) {
+	a, ok := ctx.Value(arenaCtxKey{}).(*Arena)
+	return a, ok && a != nil
+}
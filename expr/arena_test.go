@@ -0,0 +1,64 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	a := NewArena()
+
+	left := AcquireValueExpr()
+	left.Value = int64(1)
+	and := AcquireAndExpr()
+	cmp := AcquireCompareExpr()
+	cmp.Left = left
+	cmp.Comparator = EQ
+	cmp.Right = AcquireValueExpr()
+	and.Expr = append(and.Expr, cmp)
+
+	a.Own(and)
+	a.Own(and)
+	a.Own(nil)
+	if got := a.Len(); got != 1 {
+		t.Fatalf("expected 1 owned expression, got %d", got)
+	}
+
+	a.Release()
+	if got := a.Len(); got != 0 {
+		t.Fatalf("expected empty arena after release, got %d", got)
+	}
+	if cmp.Comparator != 0 {
+		t.Errorf("expected owned expressions to be freed")
+	}
+
+	// The arena is reusable after release.
+	a.Own(AcquireValueExpr())
+	a.Release()
+}
+
+func TestArenaContext(t *testing.T) {
+	if _, ok := ArenaFromContext(context.Background()); ok {
+		t.Fatalf("expected no arena in the background context")
+	}
+
+	a := NewArena()
+	got, ok := ArenaFromContext(WithArena(context.Background(), a))
+	if !ok || got != a {
+		t.Errorf("expected arena from the context")
+	}
+}
@@ -26,7 +26,7 @@ func init() {
 var arrayExprPool = &sync.Pool{
 	New: func() any {
 		return &ArrayExpr{
-			Elements:   make([]FilterExpr, 0, 10),
+			Elements:   make([]FilterExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -35,7 +35,9 @@ var arrayExprPool = &sync.Pool{
 // AcquireArrayExpr acquires an ArrayExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireArrayExpr() *ArrayExpr {
-	return arrayExprPool.Get().(*ArrayExpr)
+	x := arrayExprPool.Get().(*ArrayExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that ArrayExpr implements Expr, FilterExpr and UpdateValueExpr interface.
@@ -61,14 +63,14 @@ func (e *ArrayExpr) Clone() Expr {
 	}
 	clone := AcquireArrayExpr()
 	for _, expr := range e.Elements {
-		clone.Elements = append(clone.Elements, expr.Clone().(FilterExpr))
+		clone.Elements = append(clone.Elements, cloneFilterExpr(expr))
 	}
 	return clone
 }
 
 // Equals returns true if the given expression is equal to the current one.
 func (e *ArrayExpr) Equals(other Expr) bool {
-	if other == nil {
+	if e == nil || other == nil {
 		return false
 	}
 
@@ -82,7 +84,7 @@ func (e *ArrayExpr) Equals(other Expr) bool {
 	}
 
 	for i, expr := range e.Elements {
-		if !expr.Equals(oa.Elements[i]) {
+		if !equalExpr(expr, oa.Elements[i]) {
 			return false
 		}
 	}
@@ -94,8 +96,18 @@ func (e *ArrayExpr) Free() {
 	if e == nil {
 		return
 	}
+	for _, elem := range e.Elements {
+		if elem != nil {
+			elem.Free()
+		}
+	}
+	clear(e.Elements)
+	e.Elements = e.Elements[:0]
 	if e.isAcquired {
-		e.Elements = e.Elements[:0]
+		trackFree(e)
+		if !isRetainable(cap(e.Elements)) {
+			return
+		}
 		arrayExprPool.Put(e)
 	}
 }
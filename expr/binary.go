@@ -0,0 +1,167 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"fmt"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(BinaryExpr))
+}
+
+var binaryExprPool = &sync.Pool{
+	New: func() any {
+		return &BinaryExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireBinaryExpr acquires a BinaryExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquireBinaryExpr() *BinaryExpr {
+	x := binaryExprPool.Get().(*BinaryExpr)
+	trackAcquire(x)
+	return x
+}
+
+// Compile-time check to verify that BinaryExpr implements Expr and FilterExpr interface.
+var (
+	_ FilterExpr = (*BinaryExpr)(nil)
+	_ Expr       = (*BinaryExpr)(nil)
+)
+
+// BinaryExpr is an arithmetic operation on the right hand side of the CompareExpr,
+// i.e. the filter `expire_time < create_time + duration("24h")` results in the CompareExpr
+// with the BinaryExpr of the create_time FieldSelectorExpr and the 24h ValueExpr on the right hand side.
+// The operands are either the ValueExpr, FieldSelectorExpr, FunctionCallExpr or another BinaryExpr,
+// and their types are validated by the interpreter, i.e. a timestamp could be only added a duration.
+type BinaryExpr struct {
+	// Left is the left operand.
+	Left FilterExpr
+
+	// Op is the arithmetic operator.
+	Op Operator
+
+	// Right is the right operand.
+	Right FilterExpr
+
+	isAcquired bool
+}
+
+// Clone returns a copy of the BinaryExpr.
+func (x *BinaryExpr) Clone() Expr {
+	if x == nil {
+		return nil
+	}
+	clone := AcquireBinaryExpr()
+	clone.Op = x.Op
+	clone.Left = cloneFilterExpr(x.Left)
+	clone.Right = cloneFilterExpr(x.Right)
+	return clone
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (x *BinaryExpr) Equals(other Expr) bool {
+	if x == nil || other == nil {
+		return false
+	}
+	ob, ok := other.(*BinaryExpr)
+	if !ok {
+		return false
+	}
+	if x.Op != ob.Op {
+		return false
+	}
+	return equalExpr(x.Left, ob.Left) && equalExpr(x.Right, ob.Right)
+}
+
+// Free puts the BinaryExpr back to the pool.
+func (x *BinaryExpr) Free() {
+	if x == nil {
+		return
+	}
+	x.Op = 0
+	if x.Left != nil {
+		x.Left.Free()
+		x.Left = nil
+	}
+	if x.Right != nil {
+		x.Right.Free()
+		x.Right = nil
+	}
+	if x.isAcquired {
+		trackFree(x)
+		binaryExprPool.Put(x)
+	}
+}
+
+// Complexity of the BinaryExpr is 1 + complexity of its operands.
+func (x *BinaryExpr) Complexity() int64 {
+	var c int64 = 1
+	if x.Left != nil {
+		c += x.Left.Complexity()
+	}
+	if x.Right != nil {
+		c += x.Right.Complexity()
+	}
+	return c
+}
+
+func (x *BinaryExpr) isFilterExpr() {}
+
+// Operator is a defined type for the arithmetic operators of the BinaryExpr.
+type Operator int
+
+// String returns the string representation of the operator.
+func (o Operator) String() string {
+	if o < 0 || o > Operator(len(_OperatorStrings)-1) {
+		return fmt.Sprintf("Operator(%d)", o)
+	}
+	return _OperatorStrings[o]
+}
+
+// Precedence returns the precedence of the operator, the higher binds tighter.
+func (o Operator) Precedence() int {
+	switch o {
+	case MUL, DIV:
+		return 2
+	case ADD, SUB:
+		return 1
+	}
+	return 0
+}
+
+const (
+	_ Operator = iota
+	// ADD is the addition operator.
+	ADD
+	// SUB is the subtraction operator.
+	SUB
+	// MUL is the multiplication operator.
+	MUL
+	// DIV is the division operator.
+	DIV
+)
+
+var _OperatorStrings = [...]string{
+	ADD: "+",
+	SUB: "-",
+	MUL: "*",
+	DIV: "/",
+}
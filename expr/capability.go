@@ -0,0 +1,290 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SupportLevel is the level of support of a filter expression by a backend.
+type SupportLevel int
+
+const (
+	// FullySupported means the whole expression is translated into the backend query.
+	FullySupported SupportLevel = iota
+	// NeedsResidual means that only some of the top level conjunctions are supported,
+	// thus the backend query needs a residual in-memory evaluation of the others.
+	NeedsResidual
+	// Unsupported means that none of the top level conjunctions could be translated into the backend query.
+	Unsupported
+)
+
+var _SupportLevelStrings = [...]string{
+	FullySupported: "FULLY_SUPPORTED",
+	NeedsResidual:  "NEEDS_RESIDUAL",
+	Unsupported:    "UNSUPPORTED",
+}
+
+// String returns the string representation of the support level.
+func (l SupportLevel) String() string {
+	if l < 0 || int(l) >= len(_SupportLevelStrings) {
+		return fmt.Sprintf("SupportLevel(%d)", l)
+	}
+	return _SupportLevelStrings[l]
+}
+
+// Capability describes the filter expressions supported by a converter of the expressions into a backend query.
+// The zero value supports only the logical expressions.
+type Capability struct {
+	// Comparators are the supported comparators of the CompareExpr.
+	Comparators []Comparator
+
+	// StringSearch enables the StringSearchExpr values, i.e.: name = "foo*".
+	StringSearch bool
+
+	// RegexMatch enables the RegexMatchExpr.
+	RegexMatch bool
+
+	// Presence enables the PresenceExpr, i.e.: sub:*.
+	Presence bool
+
+	// FieldComparisons enables comparing a field with another field, i.e.: create_time = update_time.
+	FieldComparisons bool
+
+	// AnyElement enables the AnyElementExpr patterns of the repeated message fields.
+	AnyElement bool
+
+	// AllElements enables the AnyElementExpr with the universal quantifier, i.e.: all(items, quantity > 0).
+	AllElements bool
+
+	// Arithmetic enables the BinaryExpr values, i.e.: expire_time < create_time + duration("24h").
+	Arithmetic bool
+
+	// CaseInsensitive enables the case-insensitive equality and string search comparisons.
+	CaseInsensitive bool
+
+	// CaseInsensitiveRanges enables the case-insensitive LT, LE, GT and GE comparisons of the strings.
+	CaseInsensitiveRanges bool
+
+	// MessageValues enables comparing a field with a message or map value.
+	MessageValues bool
+
+	// Functions are the full names of the supported function calls, i.e.: "geo.Distance".
+	Functions []string
+
+	// RandomOrder enables the random order of the OrderByExpr, i.e.: random().
+	RandomOrder bool
+
+	// Collations are the names of the supported collations of the OrderByExpr fields, i.e.: "ci".
+	Collations []string
+}
+
+// Check checks the level of support of the expression x by the capability.
+// The unsupported expressions are described by the returned reasons.
+// A nil expression is fully supported.
+func (c *Capability) Check(x FilterExpr) (SupportLevel, []string) {
+	if x == nil {
+		return FullySupported, nil
+	}
+	reasons := c.unsupported(x, nil)
+	if len(reasons) == 0 {
+		return FullySupported, nil
+	}
+	for _, cx := range conjunctions(x, nil) {
+		if len(c.unsupported(cx, nil)) == 0 {
+			return NeedsResidual, reasons
+		}
+	}
+	return Unsupported, reasons
+}
+
+// CheckOrderBy checks if the order by expression o is supported by the capability.
+// The unsupported ordering elements are described by the returned reasons, which are nil if o is fully supported.
+func (c *Capability) CheckOrderBy(o *OrderByExpr) []string {
+	if o == nil {
+		return nil
+	}
+	var reasons []string
+	for _, f := range o.Fields {
+		switch {
+		case f.Random:
+			if !c.RandomOrder {
+				reasons = addReason(reasons, "random order")
+			}
+		case f.Collation != "":
+			if !slices.Contains(c.Collations, f.Collation) {
+				reasons = addReason(reasons, fmt.Sprintf("collation %s", f.Collation))
+			}
+		}
+	}
+	return reasons
+}
+
+// conjunctions returns the top level conjunctions of the expression.
+func conjunctions(x FilterExpr, out []FilterExpr) []FilterExpr {
+	switch xt := x.(type) {
+	case *AndExpr:
+		for _, e := range xt.Expr {
+			out = conjunctions(e, out)
+		}
+		return out
+	case *SequenceExpr:
+		for _, e := range xt.Factors {
+			out = conjunctions(e, out)
+		}
+		return out
+	case *CompositeExpr:
+		return conjunctions(xt.Expr, out)
+	case *SearchExpr:
+		return conjunctions(xt.Expr, out)
+	}
+	return append(out, x)
+}
+
+// unsupported appends the reasons of the unsupported parts of the expression.
+func (c *Capability) unsupported(x FilterExpr, reasons []string) []string {
+	switch xt := x.(type) {
+	case *AndExpr:
+		for _, e := range xt.Expr {
+			reasons = c.unsupported(e, reasons)
+		}
+	case *SequenceExpr:
+		for _, e := range xt.Factors {
+			reasons = c.unsupported(e, reasons)
+		}
+	case *OrExpr:
+		for _, e := range xt.Expr {
+			reasons = c.unsupported(e, reasons)
+		}
+	case *NotExpr:
+		reasons = c.unsupported(xt.Expr, reasons)
+	case *CompositeExpr:
+		reasons = c.unsupported(xt.Expr, reasons)
+	case *SearchExpr:
+		reasons = c.unsupported(xt.Expr, reasons)
+	case *RegexMatchExpr:
+		if !c.RegexMatch {
+			reasons = addReason(reasons, "regex match")
+		}
+	case *PresenceExpr:
+		if !c.Presence {
+			reasons = addReason(reasons, "presence")
+		}
+	case *FunctionCallExpr:
+		reasons = c.unsupportedFunction(xt, reasons)
+	case *CompareExpr:
+		reasons = c.unsupportedCompare(xt, reasons)
+	case *MatchAllExpr:
+	default:
+		reasons = addReason(reasons, fmt.Sprintf("expression %T", x))
+	}
+	return reasons
+}
+
+func (c *Capability) unsupportedCompare(x *CompareExpr, reasons []string) []string {
+	if !slices.Contains(c.Comparators, x.Comparator) {
+		reasons = addReason(reasons, fmt.Sprintf("comparator %s", x.Comparator))
+	}
+	if x.CaseInsensitive {
+		switch x.Comparator {
+		case LT, LE, GT, GE:
+			if !c.CaseInsensitiveRanges {
+				reasons = addReason(reasons, fmt.Sprintf("case-insensitive %s comparison", x.Comparator))
+			}
+		default:
+			if !c.CaseInsensitive {
+				reasons = addReason(reasons, "case-insensitive comparison")
+			}
+		}
+	}
+
+	switch lt := x.Left.(type) {
+	case *FieldSelectorExpr:
+	case *FunctionCallExpr:
+		reasons = c.unsupportedFunction(lt, reasons)
+	default:
+		reasons = addReason(reasons, fmt.Sprintf("left hand side %T", x.Left))
+	}
+	return c.unsupportedValue(x.Right, reasons)
+}
+
+func (c *Capability) unsupportedValue(x FilterExpr, reasons []string) []string {
+	switch xt := x.(type) {
+	case *ValueExpr:
+		switch xt.Value.(type) {
+		case protoreflect.Message, map[string]any:
+			if !c.MessageValues {
+				reasons = addReason(reasons, "message value")
+			}
+		}
+	case *ArrayExpr:
+		for _, e := range xt.Elements {
+			reasons = c.unsupportedValue(e, reasons)
+		}
+	case *MapValueExpr:
+		if !c.MessageValues {
+			reasons = addReason(reasons, "map value")
+		}
+	case *StringSearchExpr:
+		if !c.StringSearch {
+			reasons = addReason(reasons, "string search")
+		}
+	case *AnyElementExpr:
+		if !c.AnyElement {
+			reasons = addReason(reasons, "any element pattern")
+		} else if xt.All && !c.AllElements {
+			reasons = addReason(reasons, "all elements quantifier")
+		} else if xt.Filter != nil {
+			reasons = c.unsupported(xt.Filter, reasons)
+		}
+	case *FieldSelectorExpr:
+		if !c.FieldComparisons {
+			reasons = addReason(reasons, "field comparison")
+		}
+	case *FunctionCallExpr:
+		reasons = c.unsupportedFunction(xt, reasons)
+	case *BinaryExpr:
+		if !c.Arithmetic {
+			reasons = addReason(reasons, "arithmetic")
+		} else {
+			reasons = c.unsupportedValue(xt.Left, reasons)
+			reasons = c.unsupportedValue(xt.Right, reasons)
+		}
+	default:
+		reasons = addReason(reasons, fmt.Sprintf("right hand side %T", x))
+	}
+	return reasons
+}
+
+func (c *Capability) unsupportedFunction(x *FunctionCallExpr, reasons []string) []string {
+	name := x.Name
+	if x.PkgName != "" {
+		name = x.PkgName + "." + x.Name
+	}
+	if !slices.Contains(c.Functions, name) {
+		reasons = addReason(reasons, fmt.Sprintf("function %s", name))
+	}
+	return reasons
+}
+
+func addReason(reasons []string, reason string) []string {
+	if slices.Contains(reasons, reason) {
+		return reasons
+	}
+	return append(reasons, reason)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestCapability_Check(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) *CompareExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+	fieldEq := func() *CompareExpr {
+		return c.Compare(c.MustSelect("i32"), EQ, c.MustSelect("i64"))
+	}
+	capability := Capability{Comparators: []Comparator{EQ, NE}}
+
+	tc := []struct {
+		name    string
+		x       FilterExpr
+		want    SupportLevel
+		reasons []string
+	}{
+		{name: "nil", x: nil, want: FullySupported},
+		{name: "supported", x: c.And(eq("i32", int64(1)), c.Not(eq("str", "a"))), want: FullySupported},
+		{name: "residual conjunction", x: c.And(eq("i32", int64(1)), fieldEq()), want: NeedsResidual, reasons: []string{"field comparison"}},
+		{
+			name:    "residual composite",
+			x:       c.And(c.Composite(c.And(eq("i32", int64(1)), fieldEq())), c.Compare(c.MustSelect("i32"), GT, c.Value(int64(1)))),
+			want:    NeedsResidual,
+			reasons: []string{"field comparison", "comparator >"},
+		},
+		{name: "unsupported disjunction", x: c.Or(eq("i32", int64(1)), fieldEq()), want: Unsupported, reasons: []string{"field comparison"}},
+		{
+			name:    "function",
+			x:       c.Compare(c.MustSelect("str"), EQ, c.FunctionCall("pkg", "fn")),
+			want:    Unsupported,
+			reasons: []string{"function pkg.fn"},
+		},
+		{name: "message value", x: eq("sub", new(testpb.Message).ProtoReflect()), want: Unsupported, reasons: []string{"message value"}},
+		{name: "presence", x: c.Presence(c.MustSelect("sub")), want: Unsupported, reasons: []string{"presence"}},
+		{
+			name:    "arithmetic",
+			x:       c.Compare(c.MustSelect("i32"), EQ, c.Binary(c.Value(int64(1)), ADD, c.Value(int64(2)))),
+			want:    Unsupported,
+			reasons: []string{"arithmetic"},
+		},
+		{
+			name:    "all elements",
+			x:       c.Compare(c.MustSelect("rp_sub"), HAS, anyElement(true)),
+			want:    Unsupported,
+			reasons: []string{"comparator :", "any element pattern"},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reasons := capability.Check(tt.x)
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("expected reasons %v but got %v", tt.reasons, reasons)
+			}
+		})
+	}
+
+	capability.Functions = []string{"pkg.fn"}
+	if got, _ := capability.Check(c.Compare(c.MustSelect("str"), EQ, c.FunctionCall("pkg", "fn"))); got != FullySupported {
+		t.Errorf("expected %s but got %s", FullySupported, got)
+	}
+
+	capability.Comparators = append(capability.Comparators, HAS)
+	capability.AnyElement = true
+	all := c.Compare(c.MustSelect("rp_sub"), HAS, anyElement(true))
+	defer all.Free()
+	if _, reasons := capability.Check(all); !reflect.DeepEqual(reasons, []string{"all elements quantifier"}) {
+		t.Errorf("expected all elements quantifier reason but got %v", reasons)
+	}
+	capability.AllElements = true
+	if got, _ := capability.Check(all); got != FullySupported {
+		t.Errorf("expected %s but got %s", FullySupported, got)
+	}
+}
+
+func TestCapability_CheckOrderBy(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	collated := c.MustOrderByField("name", ASC)
+	collated.Collation = "ci"
+	o := c.OrderBy(c.MustOrderByField("i64", DESC), collated, c.RandomOrder())
+	defer o.Free()
+
+	var capability Capability
+	if got, want := capability.CheckOrderBy(o), []string{"collation ci", "random order"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected reasons %v but got %v", want, got)
+	}
+
+	capability = Capability{RandomOrder: true, Collations: []string{"ci"}}
+	if got := capability.CheckOrderBy(o); got != nil {
+		t.Errorf("expected no reasons but got %v", got)
+	}
+	if got := capability.CheckOrderBy(nil); got != nil {
+		t.Errorf("expected no reasons but got %v", got)
+	}
+}
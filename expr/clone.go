@@ -0,0 +1,155 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Clone returns a deep copy of the expression, i.e. a FilterExpr, *OrderByExpr or *UpdateExpr.
+// The copy is acquired from the pools, and does not share any node with the original,
+// thus it must be released with its own Free call, regardless of the original.
+// A nil expression is cloned as nil.
+func Clone[T Expr](x T) T {
+	var zero T
+	if isNilExpr(x) {
+		return zero
+	}
+	c, ok := x.Clone().(T)
+	if !ok {
+		return zero
+	}
+	return c
+}
+
+// Equal reports whether the expressions are deeply equal.
+// Two nil expressions are equal, and a nil expression is not equal to a non-nil one.
+// The complexities of the nodes are not compared.
+func Equal[T Expr](a, b T) bool {
+	return equalExpr(a, b)
+}
+
+// equalExpr checks the equality of possibly nil expressions.
+func equalExpr(a, b Expr) bool {
+	an, bn := isNilExpr(a), isNilExpr(b)
+	if an || bn {
+		return an && bn
+	}
+	return a.Equals(b)
+}
+
+// cloneFilterExpr clones possibly nil filter expression.
+func cloneFilterExpr(x FilterExpr) FilterExpr {
+	if isNilExpr(x) {
+		return nil
+	}
+	c, _ := x.Clone().(FilterExpr)
+	return c
+}
+
+// isNilExpr checks if the expression is nil, or a typed nil pointer.
+func isNilExpr(x Expr) bool {
+	if x == nil {
+		return true
+	}
+	rv := reflect.ValueOf(x)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// cloneValue returns a deep copy of the ValueExpr value.
+func cloneValue(v any) any {
+	switch vt := v.(type) {
+	case protoreflect.Message:
+		return proto.Clone(vt.Interface()).ProtoReflect()
+	case proto.Message:
+		return proto.Clone(vt)
+	case map[string]any:
+		mp := make(map[string]any, len(vt))
+		for k, v := range vt {
+			mp[k] = cloneValue(v)
+		}
+		return mp
+	case []any:
+		cp := make([]any, len(vt))
+		for i, v := range vt {
+			cp[i] = cloneValue(v)
+		}
+		return cp
+	case []byte:
+		return bytes.Clone(vt)
+	case *big.Rat:
+		return new(big.Rat).Set(vt)
+	default:
+		return v
+	}
+}
+
+// equalValue checks the deep equality of the ValueExpr values.
+func equalValue(a, b any) bool {
+	switch at := a.(type) {
+	case protoreflect.Message:
+		bt, ok := b.(protoreflect.Message)
+		return ok && proto.Equal(at.Interface(), bt.Interface())
+	case proto.Message:
+		bt, ok := b.(proto.Message)
+		return ok && proto.Equal(at, bt)
+	case map[string]any:
+		bt, ok := b.(map[string]any)
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for k, v := range at {
+			bv, ok := bt[k]
+			if !ok || !equalValue(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bt, ok := b.([]any)
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+		for i := range at {
+			if !equalValue(at[i], bt[i]) {
+				return false
+			}
+		}
+		return true
+	case []byte:
+		bt, ok := b.([]byte)
+		return ok && bytes.Equal(at, bt)
+	case time.Time:
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	case *big.Rat:
+		bt, ok := b.(*big.Rat)
+		return ok && at.Cmp(bt) == 0
+	default:
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+			return reflect.DeepEqual(a, b)
+		}
+		return a == b
+	}
+}
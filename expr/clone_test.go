@@ -0,0 +1,174 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestClone(t *testing.T) {
+	LeakCheck(t)
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) *CompareExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+
+	tc := []struct {
+		name string
+		x    func() Expr
+	}{
+		{name: "compare", x: func() Expr { return eq("i32", int64(1)) }},
+		{name: "and or not", x: func() Expr {
+			return c.And(c.Or(eq("i32", int64(1)), eq("str", "a")), c.Not(eq("bool", true)))
+		}},
+		{name: "composite", x: func() Expr { return c.Composite(eq("i32", int64(1))) }},
+		{name: "in array", x: func() Expr {
+			return c.Compare(c.MustSelect("str"), IN, c.Array(c.Value("a"), c.Value("b")))
+		}},
+		{name: "function call", x: func() Expr {
+			return c.Compare(c.MustSelect("str"), EQ, c.FunctionCall("pkg", "fn", c.Value("a"), c.Value(int64(1))))
+		}},
+		{name: "map value", x: func() Expr {
+			return c.MapValue(MapValueExprEntry{Key: c.Value("k"), Value: c.Value(int64(1))})
+		}},
+		{name: "match all", x: func() Expr { return AcquireMatchAllExpr() }},
+		{name: "bytes value", x: func() Expr { return c.Value([]byte("abc")) }},
+		{name: "decimal value", x: func() Expr { return c.Value(big.NewRat(41, 4)) }},
+		{name: "nested map value", x: func() Expr {
+			return c.Value(map[string]any{"a": map[string]any{"b": []any{int64(1)}}})
+		}},
+		{name: "order by", x: func() Expr {
+			return c.OrderBy(c.MustOrderByField("i32", DESC), c.MustOrderByField("sub.name", ASC))
+		}},
+		{name: "update", x: func() Expr {
+			u := AcquireUpdateExpr()
+			u.Elements = append(u.Elements,
+				UpdateFieldValue{Field: c.MustSelect("i32"), Value: c.Value(int64(1))},
+				UpdateFieldValue{Field: c.MustSelect("str")},
+			)
+			return u
+		}},
+		{name: "nil children", x: func() Expr {
+			return c.And(AcquireCompareExpr(), AcquireNotExpr(), AcquireCompositeExpr())
+		}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x := tt.x()
+			cl := Clone(x)
+			if cl == x {
+				t.Fatal("expected clone to be a different expression")
+			}
+			if !Equal(x, cl) || !Equal(cl, x) {
+				t.Fatalf("expected clone to be equal to the original")
+			}
+			want := tt.x()
+			defer want.Free()
+
+			// Releasing the original must not affect the clone.
+			x.Free()
+			if !Equal(cl, want) {
+				t.Errorf("expected clone to be unaffected by freeing the original")
+			}
+			cl.Free()
+		})
+	}
+}
+
+func TestClone_Nil(t *testing.T) {
+	var x FilterExpr
+	if got := Clone(x); got != nil {
+		t.Errorf("expected nil clone but got %v", got)
+	}
+	var ce *CompareExpr
+	if got := Clone(ce); got != nil {
+		t.Errorf("expected nil clone but got %v", got)
+	}
+	if !Equal[FilterExpr](nil, nil) {
+		t.Error("expected nil expressions to be equal")
+	}
+	if Equal[FilterExpr](nil, AcquireValueExpr()) || Equal[FilterExpr](AcquireValueExpr(), nil) {
+		t.Error("expected nil expression not to be equal to a non-nil one")
+	}
+}
+
+func TestClone_ValueIsolation(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+
+	b := []byte("abc")
+	bv := c.Value(b)
+	bc := Clone(bv)
+	b[0] = 'x'
+	if string(bc.Value.([]byte)) != "abc" {
+		t.Errorf("expected cloned bytes to be unaffected, got %s", bc.Value)
+	}
+
+	r := big.NewRat(1, 2)
+	rv := c.Value(r)
+	rc := Clone(rv)
+	r.SetInt64(2)
+	if rc.Value.(*big.Rat).Cmp(big.NewRat(1, 2)) != 0 {
+		t.Errorf("expected cloned decimal to be unaffected, got %s", rc.Value)
+	}
+
+	m := map[string]any{"a": map[string]any{"b": int64(1)}}
+	mv := c.Value(m)
+	mc := Clone(mv)
+	m["a"].(map[string]any)["b"] = int64(2)
+	if Equal(mv, mc) {
+		t.Error("expected cloned map to be unaffected by mutating the original")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) *CompareExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+
+	tc := []struct {
+		name string
+		a, b FilterExpr
+		want bool
+	}{
+		{name: "same", a: eq("i32", int64(1)), b: eq("i32", int64(1)), want: true},
+		{name: "different value", a: eq("i32", int64(1)), b: eq("i32", int64(2))},
+		{name: "different field", a: eq("i32", int64(1)), b: eq("i64", int64(1))},
+		{name: "different comparator", a: eq("i32", int64(1)), b: c.Compare(c.MustSelect("i32"), GT, c.Value(int64(1)))},
+		{name: "and vs or", a: c.And(eq("i32", int64(1))), b: c.Or(eq("i32", int64(1)))},
+		{name: "function args", a: c.FunctionCall("pkg", "fn", c.Value("a")), b: c.FunctionCall("pkg", "fn", c.Value("b"))},
+		{name: "nil child", a: AcquireNotExpr(), b: c.Not(eq("i32", int64(1)))},
+		{name: "both nil children", a: AcquireNotExpr(), b: AcquireNotExpr(), want: true},
+		{name: "element quantifier", a: anyElement(false), b: anyElement(true)},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer FreeAll(tt.a, tt.b)
+			if got := Equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func anyElement(all bool) *AnyElementExpr {
+	ae := AcquireAnyElementExpr()
+	ae.Message = "testpb.Message"
+	ae.All = all
+	return ae
+}
@@ -35,7 +35,9 @@ var compareExprPool = &sync.Pool{
 // AcquireCompareExpr acquires a CompareExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireCompareExpr() *CompareExpr {
-	return compareExprPool.Get().(*CompareExpr)
+	x := compareExprPool.Get().(*CompareExpr)
+	trackAcquire(x)
+	return x
 }
 
 var _ FilterExpr = (*CompareExpr)(nil)
@@ -51,6 +53,10 @@ type CompareExpr struct {
 	// Right is the right hand side of the expression, the value to compare to.
 	Right FilterExpr
 
+	// CaseInsensitive is true if the string values should be compared regardless of their case,
+	// i.e. with the LOWER() or ILIKE in SQL, or with the Unicode case folding like the strings.EqualFold.
+	CaseInsensitive bool
+
 	isAcquired bool
 }
 
@@ -61,18 +67,15 @@ func (x *CompareExpr) Clone() Expr {
 	}
 	clone := AcquireCompareExpr()
 	clone.Comparator = x.Comparator
-	if x.Left != nil {
-		clone.Left = x.Left.Clone().(FilterExpr)
-	}
-	if x.Right != nil {
-		clone.Right = x.Right.Clone().(FilterExpr)
-	}
+	clone.CaseInsensitive = x.CaseInsensitive
+	clone.Left = cloneFilterExpr(x.Left)
+	clone.Right = cloneFilterExpr(x.Right)
 	return clone
 }
 
 // Equals returns true if the given expression is equal to the current one.
 func (x *CompareExpr) Equals(other Expr) bool {
-	if other == nil {
+	if x == nil || other == nil {
 		return false
 	}
 
@@ -81,19 +84,11 @@ func (x *CompareExpr) Equals(other Expr) bool {
 		return false
 	}
 
-	if x.Comparator != oc.Comparator {
-		return false
-	}
-
-	if !x.Left.Equals(oc.Left) {
-		return false
-	}
-
-	if !x.Right.Equals(oc.Right) {
+	if x.Comparator != oc.Comparator || x.CaseInsensitive != oc.CaseInsensitive {
 		return false
 	}
 
-	return true
+	return equalExpr(x.Left, oc.Left) && equalExpr(x.Right, oc.Right)
 }
 
 // Free puts the CompareExpr back to the pool.
@@ -102,13 +97,17 @@ func (x *CompareExpr) Free() {
 		return
 	}
 	x.Comparator = 0
+	x.CaseInsensitive = false
 	if x.Left != nil {
 		x.Left.Free()
+		x.Left = nil
 	}
 	if x.Right != nil {
 		x.Right.Free()
+		x.Right = nil
 	}
 	if x.isAcquired {
+		trackFree(x)
 		compareExprPool.Put(x)
 	}
 }
@@ -131,13 +130,51 @@ func (x *CompareExpr) isFilterExpr() {}
 type Comparator int
 
 // String returns the string representation of the comparator.
+// The custom comparators are represented by their registered symbols.
 func (c Comparator) String() string {
 	if c < 0 || c > Comparator(len(_ComparatorStrings)-1) {
+		if sym, ok := c.CustomSymbol(); ok {
+			return sym
+		}
 		return fmt.Sprintf("Comparator(%d)", c)
 	}
 	return _ComparatorStrings[c]
 }
 
+// IsCustom checks if the comparator is a custom comparator, i.e. not one of the standard comparators.
+func (c Comparator) IsCustom() bool {
+	return c > IN
+}
+
+// CustomSymbol returns the symbol of the registered custom comparator.
+func (c Comparator) CustomSymbol() (string, bool) {
+	sym, ok := customComparators.Load(c)
+	if !ok {
+		return "", false
+	}
+	return sym.(string), true
+}
+
+// customComparators are the symbols of the registered custom comparators.
+var customComparators sync.Map
+
+// RegisterComparator registers the symbol of the custom comparator, i.e. expr.Comparator(100) as "~",
+// so that it is rendered by its String method and the filter unparsing.
+// The custom comparator value needs to be greater than the IN comparator, and the registration is global.
+// Registering the same symbol again is a no-op, while a different symbol of the comparator is an error.
+func RegisterComparator(c Comparator, symbol string) error {
+	if !c.IsCustom() {
+		return fmt.Errorf("comparator %s is not a custom comparator", c)
+	}
+	if symbol == "" {
+		return fmt.Errorf("empty symbol of the comparator %d", c)
+	}
+	if prev, loaded := customComparators.LoadOrStore(c, symbol); loaded && prev.(string) != symbol {
+		return fmt.Errorf("comparator %d is already registered as %q", c, prev)
+	}
+	return nil
+}
+
 const (
 	_ Comparator = iota
 	// EQ is the equal to comparator.
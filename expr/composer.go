@@ -76,6 +76,15 @@ func (c *Composer) And(sub ...FilterExpr) *AndExpr {
 	return ae
 }
 
+// Sequence returns a SequenceExpr of the factors with equal weights, that can be used to compose a filter expression.
+func (c *Composer) Sequence(factors ...FilterExpr) *SequenceExpr {
+	se := AcquireSequenceExpr()
+	for _, f := range factors {
+		se.Add(f, 1)
+	}
+	return se
+}
+
 // Or returns an OrExpr that can be used to compose a filter expression.
 func (c *Composer) Or(sub ...FilterExpr) *OrExpr {
 	oe := AcquireOrExpr()
@@ -120,6 +129,22 @@ func (c *Composer) Compare(left FilterExpr, cmp Comparator, right FilterExpr) *C
 	return ce
 }
 
+// Binary returns a BinaryExpr that can be used to compose the arithmetic value of a comparison.
+func (c *Composer) Binary(left FilterExpr, op Operator, right FilterExpr) *BinaryExpr {
+	be := AcquireBinaryExpr()
+	be.Left = left
+	be.Op = op
+	be.Right = right
+	return be
+}
+
+// Presence returns a PresenceExpr that can be used to compose a filter expression.
+func (c *Composer) Presence(field FilterExpr) *PresenceExpr {
+	pe := AcquirePresenceExpr()
+	pe.Field = field
+	return pe
+}
+
 // FunctionCall returns a FunctionCallExpr that can be used to compose a filter expression.
 func (c *Composer) FunctionCall(pkgName, name string, args ...FilterExpr) *FunctionCallExpr {
 	fc := AcquireFunctionCallExpr()
@@ -173,6 +198,13 @@ func (c *Composer) MustOrderByField(field string, o Order) *OrderByFieldExpr {
 	return oe
 }
 
+// RandomOrder returns an OrderByFieldExpr of the random order, that can be used to compose an order by expression.
+func (c *Composer) RandomOrder() *OrderByFieldExpr {
+	oe := AcquireOrderByFieldExpr()
+	oe.Random = true
+	return oe
+}
+
 // Pagination returns a PaginationExpr that can be used to compose a filter expression.
 func (c *Composer) Pagination(pageSize, skip int32) *PaginationExpr {
 	pe := AcquirePaginationExpr()
@@ -213,9 +245,9 @@ func (c *Composer) Pagination(pageSize, skip int32) *PaginationExpr {
 func (c *Composer) parseSelector(s string) (*FieldSelectorExpr, error) {
 	var fd protoreflect.FieldDescriptor
 
-	out := AcquireFieldSelectorExpr()
-
-	out.Message = c.Desc.FullName()
+	root := AcquireFieldSelectorExpr()
+	root.Message = c.Desc.FullName()
+	out := root
 
 	md := c.Desc
 
@@ -238,6 +270,7 @@ func (c *Composer) parseSelector(s string) (*FieldSelectorExpr, error) {
 				}
 			}
 			if !found {
+				root.Free()
 				return nil, fmt.Errorf("selector: %s is not a valid field in the message: %s", field, c.Desc.FullName())
 			}
 		}
@@ -245,10 +278,12 @@ func (c *Composer) parseSelector(s string) (*FieldSelectorExpr, error) {
 
 		if i < len(split)-1 {
 			if fd.Kind() != protoreflect.MessageKind {
+				root.Free()
 				return nil, fmt.Errorf("selector: %s cannot traverse through a non-message field: %s", field, fd.FullName())
 			}
 
 			if fd.Cardinality() == protoreflect.Repeated {
+				root.Free()
 				return nil, fmt.Errorf("selector: %s cannot be based on a repeated field: %s", field, fd.FullName())
 			}
 			next := AcquireFieldSelectorExpr()
@@ -258,5 +293,5 @@ func (c *Composer) parseSelector(s string) (*FieldSelectorExpr, error) {
 			out = next
 		}
 	}
-	return out, nil
+	return root, nil
 }
@@ -34,7 +34,9 @@ var compositeExprPool = &sync.Pool{
 // AcquireCompositeExpr acquires a CompositeExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireCompositeExpr() *CompositeExpr {
-	return compositeExprPool.Get().(*CompositeExpr)
+	x := compositeExprPool.Get().(*CompositeExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that CompositeExpr implements Expr and FilterExpr interface.
@@ -70,7 +72,7 @@ func (e *CompositeExpr) Equals(other Expr) bool {
 		return false
 	}
 	if oc, ok := other.(*CompositeExpr); ok {
-		return e.Expr.Equals(oc.Expr)
+		return equalExpr(e.Expr, oc.Expr)
 	}
 	return false
 }
@@ -85,6 +87,7 @@ func (e *CompositeExpr) Free() {
 		e.Expr = nil
 	}
 	if e.isAcquired {
+		trackFree(e)
 		compositeExprPool.Put(e)
 	}
 }
@@ -0,0 +1,210 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import "math"
+
+// CostModel assigns the weights to the expression nodes, used by the EstimateCost to compute the cost of a filter.
+// Unlike the Complexity, which counts the nodes increased by the complexities assigned by the parser,
+// the cost model reflects the execution cost of the backend, i.e. the string search on a non-indexed column,
+// or the comparison of a repeated field with thousands of elements.
+// The weights need to be non-negative. The zero value assigns no weights, see the DefaultCostModel.
+type CostModel struct {
+	// Logical is the weight of each AND, OR, NOT, sequence and composite expression.
+	Logical int64
+
+	// Compare is the weight of the comparison.
+	Compare int64
+
+	// Field is the weight of the field selector.
+	Field int64
+
+	// Fields are the weights of the field selectors by their paths, i.e.: 'sub.name',
+	// which take precedence over the Field weight, i.e. of the non-indexed fields.
+	Fields map[string]int64
+
+	// Value is the weight of the literal value.
+	Value int64
+
+	// ArrayElement is the weight of each element of the array value, i.e.: i32 IN [1, 2, 3].
+	ArrayElement int64
+
+	// StringSearch is the weight of the string search value, i.e.: name = "foo*".
+	StringSearch int64
+
+	// RegexMatch is the weight of the regular expression match.
+	RegexMatch int64
+
+	// Presence is the weight of the presence test, i.e.: sub:*.
+	Presence int64
+
+	// FunctionCall is the weight of the function call.
+	FunctionCall int64
+
+	// Functions are the weights of the function calls by their full names, i.e.: "geo.Distance",
+	// which take precedence over the FunctionCall weight.
+	Functions map[string]int64
+
+	// AnyElement is the weight of the element pattern of the repeated message field, i.e.: items:{sku: "abc"}.
+	AnyElement int64
+
+	// Arithmetic is the weight of the arithmetic operation.
+	Arithmetic int64
+
+	// Cardinality is the expected number of the elements of the repeated and map fields by their paths.
+	// The cost of the comparison of such field is multiplied by its cardinality, as each element needs to be compared.
+	// The cardinality of the fields not listed is 1.
+	Cardinality map[string]int64
+
+	// IncludeComplexity adds the complexities assigned by the parser, i.e. the field complexity annotations,
+	// to the weights of the field selectors, function calls, string searches and regular expression matches.
+	IncludeComplexity bool
+}
+
+// DefaultCostModel is the cost model of the relative weights, where the string searches, regular expressions
+// and function calls are the most expensive, and the complexities assigned by the parser are included.
+var DefaultCostModel = CostModel{
+	Logical:           1,
+	Compare:           1,
+	ArrayElement:      1,
+	StringSearch:      10,
+	RegexMatch:        20,
+	Presence:          1,
+	FunctionCall:      5,
+	AnyElement:        5,
+	Arithmetic:        2,
+	IncludeComplexity: true,
+}
+
+// EstimateCost estimates the cost of the filter expression x with the cost model m.
+// The service could compare the cost with its quota before executing the query.
+// The cost saturates at the math.MaxInt64, and a nil expression costs nothing.
+func EstimateCost(x FilterExpr, m CostModel) int64 {
+	if isNilExpr(x) {
+		return 0
+	}
+	return m.cost(x)
+}
+
+func (m *CostModel) cost(x FilterExpr) int64 {
+	switch xt := x.(type) {
+	case *AndExpr:
+		return m.sumCost(m.Logical, xt.Expr)
+	case *OrExpr:
+		return m.sumCost(m.Logical, xt.Expr)
+	case *SequenceExpr:
+		return m.sumCost(m.Logical, xt.Factors)
+	case *NotExpr:
+		return addCost(m.Logical, m.cost(xt.Expr))
+	case *CompositeExpr:
+		return addCost(m.Logical, m.cost(xt.Expr))
+	case *SearchExpr:
+		return m.cost(xt.Expr)
+	case *CompareExpr:
+		c := addCost(m.Compare, m.cost(xt.Right))
+		if fs, ok := xt.Left.(*FieldSelectorExpr); ok {
+			c = mulCost(c, m.cardinality(fs.Path()))
+		}
+		return addCost(m.cost(xt.Left), c)
+	case *RegexMatchExpr:
+		c := m.RegexMatch
+		if m.IncludeComplexity {
+			c = addCost(c, xt.MatchComplexity)
+		}
+		if fs, ok := xt.Left.(*FieldSelectorExpr); ok {
+			c = mulCost(c, m.cardinality(fs.Path()))
+		}
+		return addCost(m.cost(xt.Left), c)
+	case *PresenceExpr:
+		return addCost(m.Presence, m.cost(xt.Field))
+	case *FieldSelectorExpr:
+		c, ok := m.Fields[xt.Path()]
+		if !ok {
+			c = m.Field
+		}
+		if m.IncludeComplexity {
+			c = addCost(c, xt.FieldComplexity)
+		}
+		return c
+	case *FunctionCallExpr:
+		name := xt.Name
+		if xt.PkgName != "" {
+			name = xt.PkgName + "." + xt.Name
+		}
+		c, ok := m.Functions[name]
+		if !ok {
+			c = m.FunctionCall
+		}
+		if m.IncludeComplexity {
+			c = addCost(c, xt.CallComplexity)
+		}
+		return m.sumCost(c, xt.Arguments)
+	case *ArrayExpr:
+		c := mulCost(m.ArrayElement, int64(len(xt.Elements)))
+		return m.sumCost(c, xt.Elements)
+	case *StringSearchExpr:
+		c := m.StringSearch
+		if m.IncludeComplexity {
+			c = addCost(c, xt.SearchComplexity)
+		}
+		return c
+	case *AnyElementExpr:
+		if xt.Filter == nil {
+			return m.AnyElement
+		}
+		return addCost(m.AnyElement, m.cost(xt.Filter))
+	case *BinaryExpr:
+		return addCost(m.Arithmetic, addCost(m.cost(xt.Left), m.cost(xt.Right)))
+	case *MatchAllExpr:
+		return 0
+	default:
+		// The values, i.e. the ValueExpr and the MapValueExpr.
+		return m.Value
+	}
+}
+
+// sumCost returns the base cost increased by the costs of the expressions.
+func (m *CostModel) sumCost(base int64, exprs []FilterExpr) int64 {
+	c := base
+	for _, e := range exprs {
+		c = addCost(c, m.cost(e))
+	}
+	return c
+}
+
+// cardinality returns the expected number of the elements of the field at the path.
+func (m *CostModel) cardinality(path string) int64 {
+	if n, ok := m.Cardinality[path]; ok {
+		return n
+	}
+	return 1
+}
+
+func addCost(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func mulCost(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
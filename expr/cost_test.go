@@ -0,0 +1,97 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestEstimateCost(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	search := func(v string) *StringSearchExpr {
+		ss := AcquireStringSearchExpr()
+		ss.Value = v
+		ss.SuffixWildcard = true
+		return ss
+	}
+	model := CostModel{
+		Logical:      1,
+		Compare:      2,
+		Field:        1,
+		Fields:       map[string]int64{"sub.name": 10},
+		ArrayElement: 1,
+		StringSearch: 50,
+		FunctionCall: 5,
+		Functions:    map[string]int64{"geo.Distance": 100},
+		Cardinality:  map[string]int64{"rp_str": 20},
+	}
+
+	tc := []struct {
+		name string
+		x    FilterExpr
+		m    CostModel
+		want int64
+	}{
+		{name: "nil", x: nil, m: model, want: 0},
+		{name: "compare", x: c.Compare(c.MustSelect("i32"), EQ, c.Value(int64(1))), m: model, want: 3},
+		{name: "field weight", x: c.Compare(c.MustSelect("sub.name"), EQ, c.Value("a")), m: model, want: 12},
+		{name: "array", x: c.Compare(c.MustSelect("i32"), IN, c.Array(c.Value(int64(1)), c.Value(int64(2)))), m: model, want: 5},
+		{name: "string search", x: c.Compare(c.MustSelect("str"), EQ, search("foo")), m: model, want: 53},
+		{name: "cardinality", x: c.Compare(c.MustSelect("rp_str"), HAS, c.Value("a")), m: model, want: 41},
+		{name: "function", x: c.Compare(c.MustSelect("i32"), LT, c.FunctionCall("geo", "Distance", c.Value(int64(1)))), m: model, want: 103},
+		{name: "default function", x: c.Compare(c.MustSelect("i32"), LT, c.FunctionCall("", "fn")), m: model, want: 8},
+		{
+			name: "logical",
+			x:    c.And(c.Not(c.Compare(c.MustSelect("i32"), EQ, c.Value(int64(1)))), c.Compare(c.MustSelect("i64"), EQ, c.Value(int64(2)))),
+			m:    model,
+			want: 8,
+		},
+		{name: "zero model", x: c.Compare(c.MustSelect("str"), EQ, search("foo")), m: CostModel{}, want: 0},
+		{
+			name: "saturated",
+			x:    c.Compare(c.MustSelect("rp_str"), HAS, c.Value("a")),
+			m:    CostModel{Compare: 2, Cardinality: map[string]int64{"rp_str": math.MaxInt64}},
+			want: math.MaxInt64,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.x != nil {
+				defer tt.x.Free()
+			}
+			if got := EstimateCost(tt.x, tt.m); got != tt.want {
+				t.Errorf("expected cost %d but got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEstimateCost_IncludeComplexity(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	sel := c.MustSelect("i32")
+	sel.FieldComplexity = 7
+	x := c.Compare(sel, EQ, c.Value(int64(1)))
+	defer x.Free()
+
+	if got := EstimateCost(x, CostModel{Compare: 1}); got != 1 {
+		t.Errorf("expected cost 1 without the complexity but got %d", got)
+	}
+	if got := EstimateCost(x, CostModel{Compare: 1, IncludeComplexity: true}); got != 8 {
+		t.Errorf("expected cost 8 with the complexity but got %d", got)
+	}
+}
@@ -17,10 +17,9 @@ package expr
 // Expr is a generic expression interface that can be used to represent
 // any expression.
 type Expr interface {
-	// Free releases expression resources.
+	// Freeable releases expression resources back to the pool.
 	// No further calls to the expression are allowed after calling Free.
-	// This should release the resource related to the expression back to the pool.
-	Free()
+	Freeable
 
 	// Equals returns true if the expression is equal to the other expression.
 	Equals(other Expr) bool
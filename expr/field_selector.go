@@ -36,7 +36,9 @@ var fieldSelectorExpr = &sync.Pool{
 // AcquireFieldSelectorExpr acquires a FieldSelectorExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireFieldSelectorExpr() *FieldSelectorExpr {
-	return fieldSelectorExpr.Get().(*FieldSelectorExpr)
+	x := fieldSelectorExpr.Get().(*FieldSelectorExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that FieldSelectorExpr implements Expr and FilterExpr interface.
@@ -76,8 +78,8 @@ func (e *FieldSelectorExpr) Clone() Expr {
 	clone.Message = e.Message
 	clone.Field = e.Field
 	clone.FieldComplexity = e.FieldComplexity
-	if e.Traversal != nil {
-		clone.Traversal = e.Traversal.Clone().(FilterExpr)
+	if !isNilExpr(e.Traversal) {
+		clone.Traversal = e.Traversal.Clone()
 	}
 	return clone
 }
@@ -99,27 +101,20 @@ func (e *FieldSelectorExpr) Equals(o Expr) bool {
 		return false
 	}
 
-	if e.Traversal != nil && of.Traversal == nil {
-		return false
-	}
-
-	if e.Traversal == nil && of.Traversal != nil {
-		return false
-	}
-
-	if e.Traversal != nil && !e.Traversal.Equals(of.Traversal) {
-		return false
-	}
-	return true
+	return equalExpr(e.Traversal, of.Traversal)
 }
 
 // Free puts the FieldSelectorExpr back to the pool.
 func (e *FieldSelectorExpr) Free() {
+	if e == nil {
+		return
+	}
 	if e.Traversal != nil {
 		e.Traversal.Free()
 		e.Traversal = nil
 	}
 	if e.isAcquired {
+		trackFree(e)
 		e.Message = ""
 		e.Field = ""
 		e.FieldComplexity = 0
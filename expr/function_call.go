@@ -27,7 +27,7 @@ func init() {
 var functionCallExprPool = &sync.Pool{
 	New: func() any {
 		return &FunctionCallExpr{
-			Arguments:  make([]FilterExpr, 0, 10),
+			Arguments:  make([]FilterExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -36,7 +36,9 @@ var functionCallExprPool = &sync.Pool{
 // AcquireFunctionCallExpr acquires a FunctionCallExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireFunctionCallExpr() *FunctionCallExpr {
-	return functionCallExprPool.Get().(*FunctionCallExpr)
+	x := functionCallExprPool.Get().(*FunctionCallExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that FunctionCallExpr implements Expr and FilterExpr interface.
@@ -77,14 +79,14 @@ func (x *FunctionCallExpr) Clone() Expr {
 	clone.Name = x.Name
 	clone.CallComplexity = x.CallComplexity
 	for _, a := range x.Arguments {
-		clone.Arguments = append(clone.Arguments, a.Clone().(FilterExpr))
+		clone.Arguments = append(clone.Arguments, cloneFilterExpr(a))
 	}
 	return clone
 }
 
 // Equals returns true if the given expression is equal to the current one.
 func (x *FunctionCallExpr) Equals(other Expr) bool {
-	if other == nil {
+	if x == nil || other == nil {
 		return false
 	}
 	oc, ok := other.(*FunctionCallExpr)
@@ -95,7 +97,7 @@ func (x *FunctionCallExpr) Equals(other Expr) bool {
 		return false
 	}
 	for i := range x.Arguments {
-		if !x.Arguments[i].Equals(oc.Arguments[i]) {
+		if !equalExpr(x.Arguments[i], oc.Arguments[i]) {
 			return false
 		}
 	}
@@ -118,13 +120,20 @@ func (x *FunctionCallExpr) Free() {
 		return
 	}
 	for _, a := range x.Arguments {
-		a.Free()
+		if a != nil {
+			a.Free()
+		}
 	}
+	clear(x.Arguments)
+	x.Arguments = x.Arguments[:0]
 	if x.isAcquired {
+		trackFree(x)
 		x.PkgName = ""
 		x.Name = ""
 		x.CallComplexity = 0
-		x.Arguments = x.Arguments[:0]
+		if !isRetainable(cap(x.Arguments)) {
+			return
+		}
 		functionCallExprPool.Put(x)
 	}
 }
@@ -0,0 +1,116 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+// Freeable is a resource released back to its pool with the Free method,
+// i.e. any Expr, the parser.ParsedFilter or the filtering.ParseContext.
+type Freeable interface {
+	// Free releases the resource back to the pool.
+	// No further calls to the resource are allowed after calling Free.
+	Free()
+}
+
+// TestingT is the subset of the testing.TB used by the LeakCheck.
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+// maxReportedLeaks is the maximum number of leaks with stack traces reported by the LeakCheck.
+const maxReportedLeaks = 10
+
+// Leak is an expression acquired from the pool, which was not freed yet.
+type Leak struct {
+	// Expr is the leaked expression.
+	Expr Expr
+
+	// Stack is the stack trace of the acquisition of the expression.
+	Stack string
+}
+
+// Leaks returns the expressions acquired from the pools and not freed since the last ResetLeaks call,
+// in the order of their acquisition.
+// The expressions are tracked only if the package is built with the 'exprleak' build tag,
+// i.e.: 'go test -tags exprleak ./...', otherwise it always returns nil.
+// See LeakDetection.
+func Leaks() []Leak {
+	return leaks()
+}
+
+// ResetLeaks stops tracking the expressions acquired so far, so that the following Leaks call
+// returns only the expressions acquired afterward.
+func ResetLeaks() {
+	resetLeaks()
+}
+
+// LeakCheck fails the test if any expression acquired after the call is not freed
+// by the time the test and its deferred calls finish.
+// Each leak is reported with the stack trace of its acquisition.
+// It is meant for the authors of the code manipulating the pooled expressions,
+// i.e. the translators of the expressions into the database queries:
+//
+//	func TestTranslate(t *testing.T) {
+//		expr.LeakCheck(t)
+//		...
+//	}
+//
+// The check requires the 'exprleak' build tag, i.e.: 'go test -tags exprleak ./...',
+// otherwise it does nothing. See LeakDetection.
+// As the pools are shared by the process, the expressions acquired by the parallel tests
+// are reported as well, thus the checked test should not run in parallel with the others.
+func LeakCheck(t TestingT) {
+	if !LeakDetection {
+		return
+	}
+	t.Helper()
+	mark := leakMark()
+	t.Cleanup(func() {
+		t.Helper()
+		leaks := leaksSince(mark)
+		if len(leaks) == 0 {
+			return
+		}
+		t.Errorf("expr: %d expressions acquired and not freed", len(leaks))
+		for i, l := range leaks {
+			if i == maxReportedLeaks {
+				t.Errorf("expr: %d more leaks not reported", len(leaks)-i)
+				break
+			}
+			t.Errorf("expr: %T acquired at:\n%s", l.Expr, l.Stack)
+		}
+	})
+}
+
+// FreeAll frees all the given resources, skipping the nil ones.
+// A resource given multiple times is freed only once.
+// It is useful to release the partially built expressions on the error paths.
+func FreeAll(xs ...Freeable) {
+	for i, x := range xs {
+		if x == nil || isFreedBefore(xs[:i], x) {
+			continue
+		}
+		x.Free()
+	}
+}
+
+func isFreedBefore(xs []Freeable, x Freeable) bool {
+	for _, prev := range xs {
+		if prev == x {
+			return true
+		}
+	}
+	return false
+}
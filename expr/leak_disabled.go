@@ -0,0 +1,32 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !exprleak
+
+package expr
+
+// LeakDetection is true if the acquired expressions are tracked by the pools.
+const LeakDetection = false
+
+func trackAcquire(Expr) {}
+
+func trackFree(Expr) {}
+
+func leaks() []Leak { return nil }
+
+func leakMark() uint64 { return 0 }
+
+func leaksSince(uint64) []Leak { return nil }
+
+func resetLeaks() {}
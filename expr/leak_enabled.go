@@ -0,0 +1,115 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build exprleak
+
+package expr
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LeakDetection is true if the acquired expressions are tracked by the pools.
+const LeakDetection = true
+
+var leakTracker = struct {
+	mu    sync.Mutex
+	seq   uint64
+	alive map[Expr]trackedExpr
+}{alive: make(map[Expr]trackedExpr)}
+
+type trackedExpr struct {
+	seq uint64
+	pcs []uintptr
+}
+
+func trackAcquire(x Expr) {
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+
+	leakTracker.mu.Lock()
+	leakTracker.seq++
+	leakTracker.alive[x] = trackedExpr{seq: leakTracker.seq, pcs: pcs}
+	leakTracker.mu.Unlock()
+}
+
+func trackFree(x Expr) {
+	leakTracker.mu.Lock()
+	delete(leakTracker.alive, x)
+	leakTracker.mu.Unlock()
+}
+
+func leaks() []Leak {
+	return leaksSince(0)
+}
+
+func leakMark() uint64 {
+	leakTracker.mu.Lock()
+	defer leakTracker.mu.Unlock()
+	return leakTracker.seq
+}
+
+func leaksSince(mark uint64) []Leak {
+	leakTracker.mu.Lock()
+	tracked := make([]trackedExpr, 0, len(leakTracker.alive))
+	exprs := make(map[uint64]Expr, len(leakTracker.alive))
+	for x, te := range leakTracker.alive {
+		if te.seq <= mark {
+			continue
+		}
+		tracked = append(tracked, te)
+		exprs[te.seq] = x
+	}
+	leakTracker.mu.Unlock()
+
+	if len(tracked) == 0 {
+		return nil
+	}
+	slices.SortFunc(tracked, func(a, b trackedExpr) int { return cmp.Compare(a.seq, b.seq) })
+
+	out := make([]Leak, len(tracked))
+	for i, te := range tracked {
+		out[i] = Leak{Expr: exprs[te.seq], Stack: formatStack(te.pcs)}
+	}
+	return out
+}
+
+func resetLeaks() {
+	leakTracker.mu.Lock()
+	clear(leakTracker.alive)
+	leakTracker.mu.Unlock()
+}
+
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
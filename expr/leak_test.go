@@ -0,0 +1,130 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFreeAll(t *testing.T) {
+	ResetLeaks()
+
+	v := AcquireValueExpr()
+	v.Value = int64(1)
+	arr := AcquireArrayExpr()
+	arr.Elements = append(arr.Elements, v)
+	ce := AcquireCompareExpr()
+	ce.Left = AcquireFieldSelectorExpr()
+	ce.Right = AcquireValueExpr()
+
+	// The nil and duplicated expressions are skipped.
+	FreeAll(arr, nil, ce, (*NotExpr)(nil), arr)
+
+	if v.Value != nil {
+		t.Errorf("expected the array element to be freed")
+	}
+	if ce.Left != nil || ce.Right != nil {
+		t.Errorf("expected the compare expression to be freed")
+	}
+	if leaks := Leaks(); len(leaks) != 0 {
+		t.Errorf("expected no leaks but got %d", len(leaks))
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	if !LeakDetection {
+		if Leaks() != nil {
+			t.Errorf("expected no leaks without the leak detection")
+		}
+		t.Skip("the leak detection requires the exprleak build tag")
+	}
+	ResetLeaks()
+
+	ce := AcquireCompareExpr()
+	ce.Left = AcquireFieldSelectorExpr()
+	ce.Right = AcquireValueExpr()
+	leaked := AcquireNotExpr()
+	ce.Free()
+
+	leaks := Leaks()
+	if len(leaks) != 1 {
+		t.Fatalf("expected a single leak but got %d", len(leaks))
+	}
+	if leaks[0].Expr != leaked {
+		t.Errorf("expected leaked NotExpr but got %T", leaks[0].Expr)
+	}
+	if !strings.Contains(leaks[0].Stack, "TestLeaks") {
+		t.Errorf("expected the stack trace of the acquisition but got:\n%s", leaks[0].Stack)
+	}
+
+	leaked.Free()
+	if leaks = Leaks(); len(leaks) != 0 {
+		t.Errorf("expected no leaks but got %d", len(leaks))
+	}
+}
+
+type fakeT struct {
+	errs     []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestLeakCheck(t *testing.T) {
+	if !LeakDetection {
+		t.Skip("the leak detection requires the exprleak build tag")
+	}
+
+	// The expressions acquired before the check are not reported.
+	before := AcquireValueExpr()
+	defer before.Free()
+
+	ft := &fakeT{}
+	LeakCheck(ft)
+	freed := AcquireCompareExpr()
+	leaked := AcquireNotExpr()
+	freed.Free()
+	ft.finish()
+
+	if len(ft.errs) != 2 {
+		t.Fatalf("expected a summary and a single leak report but got: %v", ft.errs)
+	}
+	if !strings.Contains(ft.errs[1], "*expr.NotExpr") || !strings.Contains(ft.errs[1], "TestLeakCheck") {
+		t.Errorf("expected the leaked NotExpr with its stack trace but got:\n%s", ft.errs[1])
+	}
+	leaked.Free()
+
+	ft = &fakeT{}
+	LeakCheck(ft)
+	AcquireCompareExpr().Free()
+	ft.finish()
+	if len(ft.errs) != 0 {
+		t.Errorf("expected no leaks but got: %v", ft.errs)
+	}
+}
@@ -34,7 +34,9 @@ var mapKeyExprPool = &sync.Pool{
 // AcquireMapKeyExpr acquires a MapKeyExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireMapKeyExpr() *MapKeyExpr {
-	return mapKeyExprPool.Get().(*MapKeyExpr)
+	x := mapKeyExprPool.Get().(*MapKeyExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that MapKeyExpr implements Expr and FilterExpr interface.
@@ -62,12 +64,11 @@ func (e *MapKeyExpr) Clone() Expr {
 	}
 
 	mk := AcquireMapKeyExpr()
-	if e.Key != nil {
-		mk.Key = e.Key.Clone().(FilterExpr)
+	if !isNilExpr(e.Key) {
+		mk.Key = e.Key.Clone()
 	}
-
-	if e.Traversal != nil {
-		mk.Traversal = e.Traversal.Clone().(FilterExpr)
+	if !isNilExpr(e.Traversal) {
+		mk.Traversal = e.Traversal.Clone()
 	}
 	return mk
 }
@@ -82,15 +83,7 @@ func (e *MapKeyExpr) Equals(other Expr) bool {
 		return false
 	}
 
-	if e.Key != nil && !e.Key.Equals(om.Key) {
-		return false
-	}
-
-	if e.Traversal != nil && !e.Traversal.Equals(om.Traversal) {
-		return false
-	}
-
-	return true
+	return equalExpr(e.Key, om.Key) && equalExpr(e.Traversal, om.Traversal)
 }
 
 // Complexity returns the complexity of the expression.
@@ -128,7 +121,8 @@ func (e *MapKeyExpr) Free() {
 	if !e.isAcquired {
 		return
 	}
-	*e = MapKeyExpr{}
+	trackFree(e)
+	*e = MapKeyExpr{isAcquired: true}
 	mapKeyExprPool.Put(e)
 }
 
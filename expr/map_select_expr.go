@@ -21,7 +21,7 @@ import (
 var mapSelectKeysExprPool = &sync.Pool{
 	New: func() any {
 		return &MapSelectKeysExpr{
-			Keys:       make([]*MapKeyExpr, 0, 10),
+			Keys:       make([]*MapKeyExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -30,7 +30,9 @@ var mapSelectKeysExprPool = &sync.Pool{
 // AcquireMapSelectKeysExpr acquires a MapSelectKeysExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireMapSelectKeysExpr() *MapSelectKeysExpr {
-	return mapSelectKeysExprPool.Get().(*MapSelectKeysExpr)
+	x := mapSelectKeysExprPool.Get().(*MapSelectKeysExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that MapSelectKeysExpr implements Expr interface.
@@ -46,13 +48,23 @@ type MapSelectKeysExpr struct {
 
 // Free puts the MapSelectKeysExpr back to the pool.
 func (e *MapSelectKeysExpr) Free() {
-	if e == nil || !e.isAcquired {
+	if e == nil {
 		return
 	}
 	for _, key := range e.Keys {
-		key.Free()
+		if key != nil {
+			key.Free()
+		}
 	}
+	clear(e.Keys)
 	e.Keys = e.Keys[:0]
+	if !e.isAcquired {
+		return
+	}
+	trackFree(e)
+	if !isRetainable(cap(e.Keys)) {
+		return
+	}
 	mapSelectKeysExprPool.Put(e)
 }
 
@@ -63,7 +75,7 @@ func (e *MapSelectKeysExpr) Clone() Expr {
 	}
 	clone := AcquireMapSelectKeysExpr()
 	for _, key := range e.Keys {
-		clone.Keys = append(clone.Keys, key.Clone().(*MapKeyExpr))
+		clone.Keys = append(clone.Keys, Clone(key))
 	}
 	return clone
 }
@@ -81,7 +93,7 @@ func (e *MapSelectKeysExpr) Equals(other Expr) bool {
 		return false
 	}
 	for i, key := range e.Keys {
-		if !key.Equals(om.Keys[i]) {
+		if !equalExpr(key, om.Keys[i]) {
 			return false
 		}
 	}
@@ -26,7 +26,7 @@ func init() {
 var mapValueExprPool = &sync.Pool{
 	New: func() any {
 		return &MapValueExpr{
-			Values:     make([]MapValueExprEntry, 0, 10),
+			Values:     make([]MapValueExprEntry, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -35,7 +35,9 @@ var mapValueExprPool = &sync.Pool{
 // AcquireMapValueExpr acquires a MapValueExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireMapValueExpr() *MapValueExpr {
-	return mapValueExprPool.Get().(*MapValueExpr)
+	x := mapValueExprPool.Get().(*MapValueExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that MapValueExpr implements Expr and FilterExpr interface.
@@ -65,8 +67,8 @@ func (e *MapValueExpr) Clone() Expr {
 	clone := AcquireMapValueExpr()
 	for _, entry := range e.Values {
 		clone.Values = append(clone.Values, MapValueExprEntry{
-			Key:   entry.Key.Clone().(*ValueExpr),
-			Value: entry.Value.Clone().(FilterExpr),
+			Key:   Clone(entry.Key),
+			Value: cloneFilterExpr(entry.Value),
 		})
 	}
 	return clone
@@ -91,10 +93,10 @@ func (e *MapValueExpr) Equals(other Expr) bool {
 	}
 
 	for i := range e.Values {
-		if !e.Values[i].Key.Equals(oe.Values[i].Key) {
+		if !equalExpr(e.Values[i].Key, oe.Values[i].Key) {
 			return false
 		}
-		if !e.Values[i].Value.Equals(oe.Values[i].Value) {
+		if !equalExpr(e.Values[i].Value, oe.Values[i].Value) {
 			return false
 		}
 	}
@@ -114,10 +116,15 @@ func (e *MapValueExpr) Free() {
 			entry.Value.Free()
 		}
 	}
+	clear(e.Values)
+	e.Values = e.Values[:0]
 	if !e.isAcquired {
 		return
 	}
-	e.Values = e.Values[:0]
+	trackFree(e)
+	if !isRetainable(cap(e.Values)) {
+		return
+	}
 	mapValueExprPool.Put(e)
 }
 
@@ -0,0 +1,91 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(MatchAllExpr))
+}
+
+var matchAllExprPool = &sync.Pool{
+	New: func() any {
+		return &MatchAllExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireMatchAllExpr acquires a match-all expression from the pool.
+func AcquireMatchAllExpr() *MatchAllExpr {
+	x := matchAllExprPool.Get().(*MatchAllExpr)
+	trackAcquire(x)
+	return x
+}
+
+// MatchAllExpr is an expression matching all the messages.
+// It is the explicit result of an empty filter, returned by the interpreter with the MatchAllOpt,
+// so that the callers don't need to special-case the nil expression.
+type MatchAllExpr struct {
+	isAcquired bool
+}
+
+// Free puts the match-all expression back to the pool.
+func (e *MatchAllExpr) Free() {
+	if e == nil {
+		return
+	}
+	if e.isAcquired {
+		trackFree(e)
+		matchAllExprPool.Put(e)
+	}
+}
+
+// Equals returns true if the other expression is a match-all expression.
+func (e *MatchAllExpr) Equals(other Expr) bool {
+	if e == nil || other == nil {
+		return false
+	}
+	_, ok := other.(*MatchAllExpr)
+	return ok
+}
+
+// Clone returns a copy of the match-all expression.
+func (e *MatchAllExpr) Clone() Expr {
+	if e == nil {
+		return nil
+	}
+	return AcquireMatchAllExpr()
+}
+
+// Complexity of the MatchAllExpr is 1.
+func (e *MatchAllExpr) Complexity() int64 {
+	return 1
+}
+
+func (*MatchAllExpr) isFilterExpr() {}
+
+// IsMatchAll checks if the filter expression matches all the messages,
+// i.e. it is nil or the MatchAllExpr.
+func IsMatchAll(x FilterExpr) bool {
+	if isNilExpr(x) {
+		return true
+	}
+	_, ok := x.(*MatchAllExpr)
+	return ok
+}
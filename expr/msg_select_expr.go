@@ -23,7 +23,7 @@ import (
 var messageSelectExprPool = &sync.Pool{
 	New: func() any {
 		return &MessageSelectExpr{
-			Fields:     make([]*FieldSelectorExpr, 0, 10),
+			Fields:     make([]*FieldSelectorExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -31,7 +31,9 @@ var messageSelectExprPool = &sync.Pool{
 
 // AcquireMessageSelectExpr acquires a select expression from the pool.
 func AcquireMessageSelectExpr() *MessageSelectExpr {
-	return messageSelectExprPool.Get().(*MessageSelectExpr)
+	x := messageSelectExprPool.Get().(*MessageSelectExpr)
+	trackAcquire(x)
+	return x
 }
 
 // MessageSelectExpr is a select expression.
@@ -49,11 +51,21 @@ type MessageSelectExpr struct {
 
 // Free frees the select expression.
 func (e *MessageSelectExpr) Free() {
+	if e == nil {
+		return
+	}
 	for _, path := range e.Fields {
-		path.Free()
+		if path != nil {
+			path.Free()
+		}
 	}
+	clear(e.Fields)
+	e.Fields = e.Fields[:0]
 	if e.isAcquired {
-		e.Fields = e.Fields[:0]
+		trackFree(e)
+		if !isRetainable(cap(e.Fields)) {
+			return
+		}
 		messageSelectExprPool.Put(e)
 	}
 }
@@ -68,12 +80,12 @@ func (e *MessageSelectExpr) Equals(other Expr) bool {
 		return false
 	}
 
-	if len(e.Fields) != len(oe.Fields) {
+	if e.Message != oe.Message || len(e.Fields) != len(oe.Fields) {
 		return false
 	}
 
 	for i := range e.Fields {
-		if !e.Fields[i].Equals(oe.Fields[i]) {
+		if !equalExpr(e.Fields[i], oe.Fields[i]) {
 			return false
 		}
 	}
@@ -83,9 +95,13 @@ func (e *MessageSelectExpr) Equals(other Expr) bool {
 
 // Clone returns a deep copy of the select expression.
 func (e *MessageSelectExpr) Clone() Expr {
+	if e == nil {
+		return nil
+	}
 	clone := AcquireMessageSelectExpr()
+	clone.Message = e.Message
 	for _, path := range e.Fields {
-		clone.Fields = append(clone.Fields, path.Clone().(*FieldSelectorExpr))
+		clone.Fields = append(clone.Fields, Clone(path))
 	}
 	return clone
 }
@@ -34,7 +34,9 @@ var notExprPool = &sync.Pool{
 // AcquireNotExpr acquires a NotExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireNotExpr() *NotExpr {
-	return notExprPool.Get().(*NotExpr)
+	x := notExprPool.Get().(*NotExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that NotExpr implements Expr and FilterExpr interface.
@@ -57,7 +59,7 @@ func (e *NotExpr) Clone() Expr {
 		return nil
 	}
 	ne := AcquireNotExpr()
-	ne.Expr = e.Expr.Clone().(FilterExpr)
+	ne.Expr = cloneFilterExpr(e.Expr)
 	return ne
 }
 
@@ -67,7 +69,7 @@ func (e *NotExpr) Equals(other Expr) bool {
 		return false
 	}
 	if oc, ok := other.(*NotExpr); ok {
-		return e.Expr.Equals(oc.Expr)
+		return equalExpr(e.Expr, oc.Expr)
 	}
 	return false
 }
@@ -82,6 +84,7 @@ func (e *NotExpr) Free() {
 		e.Expr = nil
 	}
 	if e.isAcquired {
+		trackFree(e)
 		notExprPool.Put(e)
 	}
 }
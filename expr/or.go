@@ -26,7 +26,7 @@ func init() {
 var orExprPool = &sync.Pool{
 	New: func() any {
 		return &OrExpr{
-			Expr:       make([]FilterExpr, 0, 10),
+			Expr:       make([]FilterExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -35,7 +35,56 @@ var orExprPool = &sync.Pool{
 // AcquireOrExpr acquires an OrExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireOrExpr() *OrExpr {
-	return orExprPool.Get().(*OrExpr)
+	x := orExprPool.Get().(*OrExpr)
+	trackAcquire(x)
+	return x
+}
+
+// Or returns the disjunction of the filter expressions, taking over their ownership.
+// The nil expressions are skipped, and the OrExpr operands are flattened.
+// A match-all operand makes the result a MatchAllExpr, and the other operands are freed.
+// If no expression remains, the result is nil, and a single remaining expression is returned as is.
+func Or(x ...FilterExpr) FilterExpr {
+	oe := AcquireOrExpr()
+	matchAll := false
+	for _, sub := range x {
+		switch st := sub.(type) {
+		case *OrExpr:
+			if st == nil {
+				continue
+			}
+			oe.Expr = append(oe.Expr, st.Expr...)
+			clear(st.Expr)
+			st.Expr = st.Expr[:0]
+			st.Free()
+		case *MatchAllExpr:
+			if st != nil {
+				matchAll = true
+				st.Free()
+			}
+		default:
+			if !isNilExpr(sub) {
+				oe.Expr = append(oe.Expr, sub)
+			}
+		}
+	}
+
+	if matchAll {
+		oe.Free()
+		return AcquireMatchAllExpr()
+	}
+	switch len(oe.Expr) {
+	case 0:
+		oe.Free()
+		return nil
+	case 1:
+		only := oe.Expr[0]
+		clear(oe.Expr)
+		oe.Expr = oe.Expr[:0]
+		oe.Free()
+		return only
+	}
+	return oe
 }
 
 // Compile-time check to verify that OrExpr implements Expr and FilterExpr interface.
@@ -61,7 +110,7 @@ func (e *OrExpr) Clone() Expr {
 
 	clone := AcquireOrExpr()
 	for _, expr := range e.Expr {
-		clone.Expr = append(clone.Expr, expr.Clone().(FilterExpr))
+		clone.Expr = append(clone.Expr, cloneFilterExpr(expr))
 	}
 	return clone
 }
@@ -77,7 +126,7 @@ func (e *OrExpr) Equals(other Expr) bool {
 			return false
 		}
 		for i, expr := range e.Expr {
-			if !expr.Equals(oc.Expr[i]) {
+			if !equalExpr(expr, oc.Expr[i]) {
 				return false
 			}
 		}
@@ -97,10 +146,15 @@ func (e *OrExpr) Free() {
 			sub.Free()
 		}
 	}
+	clear(e.Expr)
+	e.Expr = e.Expr[:0]
 	if !e.isAcquired {
 		return
 	}
-	e.Expr = e.Expr[:0]
+	trackFree(e)
+	if !isRetainable(cap(e.Expr)) {
+		return
+	}
 	orExprPool.Put(e)
 }
 
@@ -28,7 +28,7 @@ var (
 	orderExprPool = &sync.Pool{
 		New: func() any {
 			return &OrderByExpr{
-				Fields:     make([]*OrderByFieldExpr, 0, 10),
+				Fields:     make([]*OrderByFieldExpr, 0, initialCapacity()),
 				isAcquired: true,
 			}
 		},
@@ -44,7 +44,9 @@ var (
 
 // AcquireOrderByExpr acquires an OrderByExpr from the pool.
 func AcquireOrderByExpr() *OrderByExpr {
-	return orderExprPool.Get().(*OrderByExpr)
+	x := orderExprPool.Get().(*OrderByExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that OrderByExpr implements Expr interface.
@@ -68,7 +70,7 @@ func (o *OrderByExpr) Merge(other *OrderByExpr) {
 		foundIndex := -1
 		for i := 0; i < ln-1; i++ {
 			f := o.Fields[i]
-			if f.Field.Equals(field.Field) {
+			if f.Random == field.Random && f.Field.Equals(field.Field) {
 				foundIndex = i
 
 				break
@@ -91,10 +93,15 @@ func (o *OrderByExpr) Free() {
 			sub.Free()
 		}
 	}
+	clear(o.Fields)
+	o.Fields = o.Fields[:0]
 	if !o.isAcquired {
 		return
 	}
-	o.Fields = o.Fields[:0]
+	trackFree(o)
+	if !isRetainable(cap(o.Fields)) {
+		return
+	}
 	orderExprPool.Put(o)
 }
 
@@ -102,7 +109,7 @@ func (o *OrderByExpr) Free() {
 func (o *OrderByExpr) Complexity() int64 {
 	complexity := int64(1)
 	for _, expr := range o.Fields {
-		complexity += expr.Field.Complexity()
+		complexity += expr.Complexity()
 	}
 	return complexity
 }
@@ -123,7 +130,7 @@ func (o *OrderByExpr) Equals(order Expr) bool {
 	}
 
 	for i := range o.Fields {
-		if !o.Fields[i].Equals(other.Fields[i]) {
+		if !equalExpr(o.Fields[i], other.Fields[i]) {
 			return false
 		}
 	}
@@ -137,14 +144,16 @@ func (o *OrderByExpr) Clone() Expr {
 	}
 	clone := AcquireOrderByExpr()
 	for _, field := range o.Fields {
-		clone.Fields = append(clone.Fields, field.Clone().(*OrderByFieldExpr))
+		clone.Fields = append(clone.Fields, Clone(field))
 	}
 	return clone
 }
 
 // AcquireOrderByFieldExpr acquires an OrderByFieldExpr from the pool.
 func AcquireOrderByFieldExpr() *OrderByFieldExpr {
-	return orderFieldExprPool.Get().(*OrderByFieldExpr)
+	x := orderFieldExprPool.Get().(*OrderByFieldExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that OrderByFieldExpr implements Expr interface.
@@ -159,6 +168,14 @@ type OrderByFieldExpr struct {
 	// Order is the order of the order by expression
 	Order Order
 
+	// Collation is the name of the collation the string field is ordered with, i.e. 'ci'.
+	// If empty, the default collation of the backend is used.
+	Collation string
+
+	// Random determines the random order, i.e. 'random()', which shuffles the resources
+	// equal by the preceding fields. The Field of the random order is nil.
+	Random bool
+
 	isAcquired bool
 }
 
@@ -168,8 +185,10 @@ func (o *OrderByFieldExpr) Clone() Expr {
 		return nil
 	}
 	clone := AcquireOrderByFieldExpr()
-	clone.Field = o.Field.Clone().(*FieldSelectorExpr)
+	clone.Field = Clone(o.Field)
 	clone.Order = o.Order
+	clone.Collation = o.Collation
+	clone.Random = o.Random
 	return clone
 }
 
@@ -184,7 +203,10 @@ func (o *OrderByFieldExpr) Equals(other Expr) bool {
 		return false
 	}
 
-	return o.Field.Equals(oe.Field) && o.Order == oe.Order
+	if o.Random || oe.Random {
+		return o.Random == oe.Random
+	}
+	return equalExpr(o.Field, oe.Field) && o.Order == oe.Order && o.Collation == oe.Collation
 }
 
 // Free puts the OrderByFieldExpr back to the pool.
@@ -192,15 +214,25 @@ func (o *OrderByFieldExpr) Free() {
 	if o == nil {
 		return
 	}
-	o.Field.Free()
+	if o.Field != nil {
+		o.Field.Free()
+		o.Field = nil
+	}
+	o.Order = 0
+	o.Collation = ""
+	o.Random = false
 	if !o.isAcquired {
 		return
 	}
+	trackFree(o)
 	orderFieldExprPool.Put(o)
 }
 
 // Complexity returns the complexity of the expression
 func (o *OrderByFieldExpr) Complexity() int64 {
+	if o.Random {
+		return 1
+	}
 	return o.Field.Complexity()
 }
 
@@ -34,7 +34,9 @@ var paginationExprPool = &sync.Pool{
 // AcquirePaginationExpr acquires a PaginationExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquirePaginationExpr() *PaginationExpr {
-	return paginationExprPool.Get().(*PaginationExpr)
+	x := paginationExprPool.Get().(*PaginationExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Compile-time check to verify that PaginationExpr implements Expr interface.
@@ -56,6 +58,7 @@ func (x *PaginationExpr) Free() {
 	if x == nil || !x.isAcquired {
 		return
 	}
+	trackFree(x)
 	x.PageSize = 0
 	x.Skip = 0
 	paginationExprPool.Put(x)
@@ -0,0 +1,78 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"sync/atomic"
+)
+
+// DefaultPoolInitialCapacity is the default initial capacity of the slices
+// of the expressions created by the pools.
+const DefaultPoolInitialCapacity = 10
+
+var (
+	poolInitialCapacity     atomic.Int64
+	poolMaxRetainedCapacity atomic.Int64
+)
+
+func init() {
+	poolInitialCapacity.Store(DefaultPoolInitialCapacity)
+}
+
+// SetPoolInitialCapacity sets the initial capacity of the slices, i.e. ArrayExpr.Elements or AndExpr.Expr,
+// of the expressions newly allocated by the pools.
+// A larger capacity reduces the number of slice growths for the large expressions, at the cost of memory
+// allocated for the small ones. A negative value is treated as zero.
+// It only affects the expressions allocated after the call, and is safe for concurrent use.
+func SetPoolInitialCapacity(n int) {
+	if n < 0 {
+		n = 0
+	}
+	poolInitialCapacity.Store(int64(n))
+}
+
+// PoolInitialCapacity returns the initial capacity of the slices of the expressions allocated by the pools.
+func PoolInitialCapacity() int {
+	return int(poolInitialCapacity.Load())
+}
+
+// SetPoolMaxRetainedCapacity sets the maximum capacity of the slices of the expressions
+// that are put back to the pools on Free.
+// An expression which slice has grown above the limit, i.e. an ArrayExpr with thousands of elements,
+// is not put back to the pool, but left for the garbage collector, so that the pool does not retain large allocations.
+// Zero value means no limit, which is the default. A negative value is treated as zero.
+// It is safe for concurrent use.
+func SetPoolMaxRetainedCapacity(n int) {
+	if n < 0 {
+		n = 0
+	}
+	poolMaxRetainedCapacity.Store(int64(n))
+}
+
+// PoolMaxRetainedCapacity returns the maximum capacity of the slices of the expressions that are put back to the pools.
+// Zero value means no limit.
+func PoolMaxRetainedCapacity() int {
+	return int(poolMaxRetainedCapacity.Load())
+}
+
+func initialCapacity() int {
+	return int(poolInitialCapacity.Load())
+}
+
+// isRetainable checks if an expression with a slice of given capacity could be put back to the pool.
+func isRetainable(capacity int) bool {
+	max := poolMaxRetainedCapacity.Load()
+	return max == 0 || int64(capacity) <= max
+}
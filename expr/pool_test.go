@@ -0,0 +1,90 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPoolCapacity(t *testing.T) {
+	defer SetPoolInitialCapacity(DefaultPoolInitialCapacity)
+	defer SetPoolMaxRetainedCapacity(0)
+
+	SetPoolInitialCapacity(-1)
+	if got := PoolInitialCapacity(); got != 0 {
+		t.Errorf("expected initial capacity 0, got %d", got)
+	}
+
+	SetPoolInitialCapacity(64)
+	if got := PoolInitialCapacity(); got != 64 {
+		t.Errorf("expected initial capacity 64, got %d", got)
+	}
+
+	if !isRetainable(1 << 20) {
+		t.Errorf("expected any capacity to be retainable without the limit")
+	}
+
+	SetPoolMaxRetainedCapacity(100)
+	if got := PoolMaxRetainedCapacity(); got != 100 {
+		t.Errorf("expected max retained capacity 100, got %d", got)
+	}
+	if !isRetainable(100) {
+		t.Errorf("expected capacity 100 to be retainable")
+	}
+	if isRetainable(101) {
+		t.Errorf("expected capacity 101 not to be retainable")
+	}
+}
+
+func BenchmarkArrayExpr_Large(b *testing.B) {
+	defer SetPoolInitialCapacity(DefaultPoolInitialCapacity)
+	defer SetPoolMaxRetainedCapacity(0)
+
+	for _, size := range []int{10, 1000, 10000} {
+		for _, cfg := range []struct {
+			name        string
+			initialCap  int
+			maxRetained int
+		}{
+			{name: "default"},
+			{name: "presized", initialCap: size},
+			{name: "bounded", maxRetained: 100},
+		} {
+			b.Run(fmt.Sprintf("%s/%d", cfg.name, size), func(b *testing.B) {
+				SetPoolInitialCapacity(DefaultPoolInitialCapacity)
+				if cfg.initialCap > 0 {
+					SetPoolInitialCapacity(cfg.initialCap)
+				}
+				SetPoolMaxRetainedCapacity(cfg.maxRetained)
+
+				values := make([]*ValueExpr, size)
+				for i := range values {
+					values[i] = &ValueExpr{Value: int64(i)}
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ae := AcquireArrayExpr()
+					for _, v := range values {
+						ae.Elements = append(ae.Elements, v)
+					}
+					ae.Free()
+				}
+			})
+		}
+	}
+}
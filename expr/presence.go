@@ -0,0 +1,104 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(PresenceExpr))
+}
+
+var presenceExprPool = &sync.Pool{
+	New: func() any {
+		return &PresenceExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquirePresenceExpr acquires a PresenceExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquirePresenceExpr() *PresenceExpr {
+	x := presenceExprPool.Get().(*PresenceExpr)
+	trackAcquire(x)
+	return x
+}
+
+var _ FilterExpr = (*PresenceExpr)(nil)
+
+// PresenceExpr is a restriction that tests the presence of a field, i.e.: sub:*.
+// A singular message field or a nullable scalar field is present if it is set, which translates to 'IS NOT NULL',
+// whereas a repeated or map field is present if it is not empty.
+// The presence of a map key, i.e.: labels.env:*, is not a PresenceExpr,
+// but a CompareExpr with the HAS comparator and the key as the right hand side.
+type PresenceExpr struct {
+	// Field is the field selector of the tested field.
+	Field FilterExpr
+
+	isAcquired bool
+}
+
+// Clone returns a copy of the PresenceExpr.
+func (x *PresenceExpr) Clone() Expr {
+	if x == nil {
+		return nil
+	}
+	clone := AcquirePresenceExpr()
+	clone.Field = cloneFilterExpr(x.Field)
+	return clone
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (x *PresenceExpr) Equals(other Expr) bool {
+	if x == nil || other == nil {
+		return false
+	}
+	oc, ok := other.(*PresenceExpr)
+	if !ok {
+		return false
+	}
+	return equalExpr(x.Field, oc.Field)
+}
+
+// Free puts the PresenceExpr back to the pool.
+func (x *PresenceExpr) Free() {
+	if x == nil {
+		return
+	}
+	if x.Field != nil {
+		x.Field.Free()
+		x.Field = nil
+	}
+	if !x.isAcquired {
+		return
+	}
+	trackFree(x)
+	*x = PresenceExpr{isAcquired: true}
+	presenceExprPool.Put(x)
+}
+
+// Complexity returns the complexity of the expression.
+// It is the complexity of the field selector increased by 1 for the node.
+func (x *PresenceExpr) Complexity() int64 {
+	if x.Field == nil {
+		return 1
+	}
+	return x.Field.Complexity() + 1
+}
+
+func (x *PresenceExpr) isFilterExpr() {}
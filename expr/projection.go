@@ -0,0 +1,136 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"slices"
+	"strings"
+)
+
+// ReferencedFields returns the sorted and unique paths of the fields referenced by the filter expression x,
+// rendered by the FieldSelectorExpr.Path, i.e.: ['map_str_i32.key', 'sub.name'].
+// The fields of the AnyElementExpr filters are relative to the element message,
+// thus only the path of the repeated field is returned for them.
+func ReferencedFields(x FilterExpr) []string {
+	paths := referencedFields(x, nil)
+	if len(paths) == 0 {
+		return nil
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// ResidualFields returns the sorted and unique paths of the fields referenced by the top level conjunctions
+// of the expression x, which are not supported by the capability and need to be evaluated in memory.
+// A nil result means that the whole expression is translated into the backend query.
+func (c *Capability) ResidualFields(x FilterExpr) []string {
+	if x == nil {
+		return nil
+	}
+	var paths []string
+	for _, cx := range conjunctions(x, nil) {
+		if len(c.unsupported(cx, nil)) > 0 {
+			paths = referencedFields(cx, paths)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// Projection returns the minimal set of the field paths a backend needs to fetch in order to return
+// the fields of the readMask, and to evaluate the residual part of the expression x in memory.
+// The paths nested in another returned path are pruned, i.e.: 'sub.name' is covered by the 'sub'.
+// An empty readMask, or the one containing a wildcard '*' path, selects all the fields,
+// and thus a nil projection is returned, meaning that no projection could be pushed down.
+func (c *Capability) Projection(x FilterExpr, readMask []string) []string {
+	if len(readMask) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(readMask))
+	for _, p := range readMask {
+		if p == "*" {
+			return nil
+		}
+		paths = append(paths, p)
+	}
+	paths = append(paths, c.ResidualFields(x)...)
+	return prunePaths(paths)
+}
+
+// referencedFields appends the paths of the fields referenced by the expression x.
+func referencedFields(x FilterExpr, paths []string) []string {
+	switch xt := x.(type) {
+	case *AndExpr:
+		for _, e := range xt.Expr {
+			paths = referencedFields(e, paths)
+		}
+	case *SequenceExpr:
+		for _, e := range xt.Factors {
+			paths = referencedFields(e, paths)
+		}
+	case *OrExpr:
+		for _, e := range xt.Expr {
+			paths = referencedFields(e, paths)
+		}
+	case *NotExpr:
+		paths = referencedFields(xt.Expr, paths)
+	case *CompositeExpr:
+		paths = referencedFields(xt.Expr, paths)
+	case *SearchExpr:
+		paths = referencedFields(xt.Expr, paths)
+	case *CompareExpr:
+		paths = referencedFields(xt.Left, paths)
+		paths = referencedFields(xt.Right, paths)
+	case *RegexMatchExpr:
+		paths = referencedFields(xt.Left, paths)
+	case *PresenceExpr:
+		paths = referencedFields(xt.Field, paths)
+	case *FunctionCallExpr:
+		for _, arg := range xt.Arguments {
+			paths = referencedFields(arg, paths)
+		}
+	case *ArrayExpr:
+		for _, e := range xt.Elements {
+			paths = referencedFields(e, paths)
+		}
+	case *BinaryExpr:
+		paths = referencedFields(xt.Left, paths)
+		paths = referencedFields(xt.Right, paths)
+	case *FieldSelectorExpr:
+		if xt != nil {
+			paths = append(paths, xt.Path())
+		}
+	}
+	return paths
+}
+
+// prunePaths sorts the paths and removes the duplicates and the paths nested in another path.
+func prunePaths(paths []string) []string {
+	slices.Sort(paths)
+	out := paths[:0]
+	for _, p := range paths {
+		if len(out) > 0 {
+			last := out[len(out)-1]
+			if p == last || strings.HasPrefix(p, last+".") {
+				continue
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestReferencedFields(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	x := c.And(
+		c.Compare(c.MustSelect("i32"), EQ, c.MustSelect("i64")),
+		c.Or(
+			c.Presence(c.MustSelect("sub")),
+			c.Not(c.Compare(c.MustSelect("sub.name"), EQ, c.Value("foo"))),
+		),
+		c.Compare(c.MustSelect("i32"), GT, c.Value(int64(1))),
+	)
+	want := []string{"i32", "i64", "sub", "sub.name"}
+	if got := ReferencedFields(x); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+	if got := ReferencedFields(nil); got != nil {
+		t.Errorf("expected nil but got %v", got)
+	}
+}
+
+func TestCapability_Projection(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	capability := Capability{Comparators: []Comparator{EQ, NE}}
+	x := c.And(
+		c.Compare(c.MustSelect("str"), EQ, c.Value("foo")),
+		c.Compare(c.MustSelect("i32"), EQ, c.MustSelect("i64")),
+		c.Compare(c.MustSelect("sub.i64"), GT, c.Value(int64(1))),
+	)
+
+	if got, want := capability.ResidualFields(x), []string{"i32", "i64", "sub.i64"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected residual fields %v but got %v", want, got)
+	}
+
+	tc := []struct {
+		name     string
+		x        FilterExpr
+		readMask []string
+		want     []string
+	}{
+		{name: "empty read mask", x: x, want: nil},
+		{name: "wildcard read mask", x: x, readMask: []string{"name", "*"}, want: nil},
+		{name: "fully supported", x: c.Compare(c.MustSelect("str"), EQ, c.Value("foo")), readMask: []string{"name"}, want: []string{"name"}},
+		{name: "residual", x: x, readMask: []string{"name", "i32"}, want: []string{"i32", "i64", "name", "sub.i64"}},
+		{name: "pruned", x: x, readMask: []string{"sub", "i64"}, want: []string{"i32", "i64", "sub"}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capability.Projection(tt.x, tt.readMask); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
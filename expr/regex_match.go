@@ -0,0 +1,117 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(RegexMatchExpr))
+}
+
+var regexMatchExprPool = &sync.Pool{
+	New: func() any {
+		return &RegexMatchExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireRegexMatchExpr acquires a RegexMatchExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquireRegexMatchExpr() *RegexMatchExpr {
+	x := regexMatchExprPool.Get().(*RegexMatchExpr)
+	trackAcquire(x)
+	return x
+}
+
+var _ FilterExpr = (*RegexMatchExpr)(nil)
+
+// RegexMatchExpr is a restriction that matches a string field with a regular expression, i.e.: name =~ "^foo.*bar$".
+// The pattern is validated by the interpreter with the RE2 syntax, as accepted by the regexp package.
+// The pattern is not anchored, thus it matches any substring of the value, unless it uses the ^ and $ anchors.
+// NOTE: This is an extension to the standard.
+type RegexMatchExpr struct {
+	// Left is the field selector of the matched string field.
+	Left FilterExpr
+
+	// Pattern is the regular expression pattern.
+	Pattern string
+
+	// MatchComplexity is the complexity assigned by the parser.
+	MatchComplexity int64
+
+	isAcquired bool
+}
+
+// Clone returns a copy of the RegexMatchExpr.
+func (x *RegexMatchExpr) Clone() Expr {
+	if x == nil {
+		return nil
+	}
+	clone := AcquireRegexMatchExpr()
+	clone.Pattern = x.Pattern
+	clone.MatchComplexity = x.MatchComplexity
+	clone.Left = cloneFilterExpr(x.Left)
+	return clone
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (x *RegexMatchExpr) Equals(other Expr) bool {
+	if x == nil || other == nil {
+		return false
+	}
+	oc, ok := other.(*RegexMatchExpr)
+	if !ok {
+		return false
+	}
+	if x.Pattern != oc.Pattern {
+		return false
+	}
+	return equalExpr(x.Left, oc.Left)
+}
+
+// Free puts the RegexMatchExpr back to the pool.
+func (x *RegexMatchExpr) Free() {
+	if x == nil {
+		return
+	}
+	if x.Left != nil {
+		x.Left.Free()
+		x.Left = nil
+	}
+	if !x.isAcquired {
+		return
+	}
+	trackFree(x)
+	*x = RegexMatchExpr{isAcquired: true}
+	regexMatchExprPool.Put(x)
+}
+
+// Complexity returns the complexity of the expression.
+// The complexity is taken from the field options and multiplied by 4, as the regular expression match
+// is at least as expensive as the search with both prefix and suffix wildcards.
+// Resultant complexity is increased by 1 for the node.
+func (x *RegexMatchExpr) Complexity() int64 {
+	fc := x.MatchComplexity
+	if fc == 0 {
+		fc = 1
+	}
+	return fc*4 + 1
+}
+
+func (x *RegexMatchExpr) isFilterExpr() {}
@@ -0,0 +1,107 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+// SplitSargable partitions the top level conjunctions of the filter expression x into the pushdown part,
+// which is safe to push to the datastore, and the residual part, which needs to be evaluated in memory.
+// The conjunction is pushed down if it is sargable, i.e. it could be resolved with an index seek:
+//
+//   - the EQ, LT, LE, GT, GE, IN and HAS comparison of an indexable field with the literal values,
+//   - the prefix string search, i.e.: name = "foo*", but not the leading wildcard one,
+//   - the presence test of an indexable field, i.e.: sub:*,
+//   - the OR of the sargable expressions.
+//
+// The indexable function decides if the field with the path, rendered by the FieldSelectorExpr.Path, is indexed.
+// The result of both parts is either a nil, a single expression or an AndExpr of the conjunctions.
+// The x is not modified, and the returned expressions are its copies, which need to be freed by the caller.
+func SplitSargable(x FilterExpr, indexable func(fieldPath string) bool) (pushdown, residual FilterExpr) {
+	if isNilExpr(x) {
+		return nil, nil
+	}
+
+	var pd, rd []FilterExpr
+	for _, cx := range conjunctions(x, nil) {
+		if _, ok := cx.(*MatchAllExpr); ok {
+			continue
+		}
+		if isSargable(cx, indexable) {
+			pd = append(pd, cloneFilterExpr(cx))
+		} else {
+			rd = append(rd, cloneFilterExpr(cx))
+		}
+	}
+	return joinConjunctions(pd), joinConjunctions(rd)
+}
+
+// isSargable checks if the expression could be resolved with an index seek.
+func isSargable(x FilterExpr, indexable func(string) bool) bool {
+	switch xt := x.(type) {
+	case *OrExpr:
+		for _, e := range xt.Expr {
+			if !isSargable(e, indexable) {
+				return false
+			}
+		}
+		return len(xt.Expr) > 0
+	case *CompositeExpr:
+		return isSargable(xt.Expr, indexable)
+	case *PresenceExpr:
+		fs, ok := xt.Field.(*FieldSelectorExpr)
+		return ok && indexable(fs.Path())
+	case *CompareExpr:
+		fs, ok := xt.Left.(*FieldSelectorExpr)
+		if !ok || xt.CaseInsensitive || !indexable(fs.Path()) {
+			return false
+		}
+		switch xt.Comparator {
+		case EQ, LT, LE, GT, GE, IN, HAS:
+		default:
+			return false
+		}
+		return isSargableValue(xt.Right)
+	}
+	return false
+}
+
+// isSargableValue checks if the right hand side of the comparison is a literal value, known before the query.
+func isSargableValue(x FilterExpr) bool {
+	switch xt := x.(type) {
+	case *ValueExpr:
+		return true
+	case *StringSearchExpr:
+		return !xt.PrefixWildcard
+	case *ArrayExpr:
+		for _, e := range xt.Elements {
+			if !isSargableValue(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// joinConjunctions returns the AND of the conjunctions, the single conjunction or nil.
+func joinConjunctions(exprs []FilterExpr) FilterExpr {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	}
+	ae := AcquireAndExpr()
+	ae.Expr = exprs
+	return ae
+}
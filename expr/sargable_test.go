@@ -0,0 +1,93 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestSplitSargable(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) *CompareExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+	search := func(prefix bool) *StringSearchExpr {
+		ss := AcquireStringSearchExpr()
+		ss.Value = "foo"
+		ss.PrefixWildcard = prefix
+		ss.SuffixWildcard = !prefix
+		return ss
+	}
+	indexed := map[string]bool{"i32": true, "i64": true, "str": true, "sub.name": true}
+	indexable := func(path string) bool { return indexed[path] }
+
+	tc := []struct {
+		name     string
+		x        FilterExpr
+		pushdown string
+		residual string
+	}{
+		{name: "nil"},
+		{name: "match all", x: AcquireMatchAllExpr()},
+		{name: "single", x: eq("i32", int64(1)), pushdown: `i32 = 1`},
+		{name: "not indexed", x: eq("bool", true), residual: `bool = true`},
+		{
+			name:     "conjunctions",
+			x:        c.And(eq("i32", int64(1)), eq("bool", true), c.Compare(c.MustSelect("i64"), GT, c.Value(int64(2)))),
+			pushdown: `i32 = 1 AND i64 > 2`,
+			residual: `bool = true`,
+		},
+		{name: "or", x: c.Or(eq("i32", int64(1)), eq("str", "a")), pushdown: `i32 = 1 OR str = "a"`},
+		{name: "or with residual", x: c.Or(eq("i32", int64(1)), eq("bool", true)), residual: `i32 = 1 OR bool = true`},
+		{name: "not", x: c.Not(eq("i32", int64(1))), residual: `NOT i32 = 1`},
+		{name: "not equal", x: c.Compare(c.MustSelect("i32"), NE, c.Value(int64(1))), residual: `i32 != 1`},
+		{name: "in", x: c.Compare(c.MustSelect("i32"), IN, c.Array(c.Value(int64(1)), c.Value(int64(2)))), pushdown: `i32 IN [1, 2]`},
+		{name: "prefix search", x: c.Compare(c.MustSelect("str"), EQ, search(false)), pushdown: `str = "foo*"`},
+		{name: "leading wildcard", x: c.Compare(c.MustSelect("str"), EQ, search(true)), residual: `str = "*foo"`},
+		{name: "field comparison", x: c.Compare(c.MustSelect("i32"), EQ, c.MustSelect("i64")), residual: `i32 = i64`},
+		{name: "presence", x: c.And(c.Presence(c.MustSelect("sub")), eq("sub.name", "a")), pushdown: `sub.name = "a"`, residual: `sub:*`},
+		{name: "function", x: c.Compare(c.MustSelect("str"), EQ, c.FunctionCall("pkg", "fn")), residual: `str = pkg.fn()`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.x != nil {
+				defer tt.x.Free()
+			}
+			pushdown, residual := SplitSargable(tt.x, indexable)
+			if pushdown != nil {
+				defer pushdown.Free()
+			}
+			if residual != nil {
+				defer residual.Free()
+			}
+
+			for _, part := range []struct {
+				name string
+				x    FilterExpr
+				want string
+			}{{"pushdown", pushdown, tt.pushdown}, {"residual", residual, tt.residual}} {
+				got, err := String(part.x)
+				if err != nil {
+					t.Fatalf("failed to render the %s: %v", part.name, err)
+				}
+				if got != part.want {
+					t.Errorf("expected %s %s but got %s", part.name, part.want, got)
+				}
+			}
+		})
+	}
+}
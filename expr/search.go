@@ -0,0 +1,130 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(SearchExpr))
+}
+
+var searchExprPool = &sync.Pool{
+	New: func() any {
+		return &SearchExpr{
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireSearchExpr acquires a SearchExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquireSearchExpr() *SearchExpr {
+	x := searchExprPool.Get().(*SearchExpr)
+	trackAcquire(x)
+	return x
+}
+
+var _ FilterExpr = (*SearchExpr)(nil)
+
+// SearchExpr is a free-text search query, which is kept apart from the strict filter restrictions.
+// The backends with the full-text search, i.e. the Elasticsearch, could use the Query, Terms and Fields directly.
+// The other backends could use the Expr, which is an equivalent conjunction of the terms, where each term
+// is a case-insensitive string search with both wildcards over any of the searchable fields.
+type SearchExpr struct {
+	// Query is the raw search query.
+	Query string
+
+	// Terms are the search terms of the query, split by the whitespace, with the quoted phrases kept together.
+	Terms []string
+
+	// Fields are the dot separated paths of the searchable fields.
+	Fields []string
+
+	// Expr is the filter expression equivalent of the search.
+	Expr FilterExpr
+
+	isAcquired bool
+}
+
+// Clone returns a copy of the SearchExpr.
+func (x *SearchExpr) Clone() Expr {
+	if x == nil {
+		return nil
+	}
+	clone := AcquireSearchExpr()
+	clone.Query = x.Query
+	clone.Terms = append(clone.Terms, x.Terms...)
+	clone.Fields = append(clone.Fields, x.Fields...)
+	clone.Expr = cloneFilterExpr(x.Expr)
+	return clone
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (x *SearchExpr) Equals(other Expr) bool {
+	if x == nil || other == nil {
+		return false
+	}
+	oc, ok := other.(*SearchExpr)
+	if !ok {
+		return false
+	}
+	if x.Query != oc.Query || len(x.Terms) != len(oc.Terms) || len(x.Fields) != len(oc.Fields) {
+		return false
+	}
+	for i := range x.Terms {
+		if x.Terms[i] != oc.Terms[i] {
+			return false
+		}
+	}
+	for i := range x.Fields {
+		if x.Fields[i] != oc.Fields[i] {
+			return false
+		}
+	}
+	return equalExpr(x.Expr, oc.Expr)
+}
+
+// Free puts the SearchExpr back to the pool.
+func (x *SearchExpr) Free() {
+	if x == nil {
+		return
+	}
+	if x.Expr != nil {
+		x.Expr.Free()
+		x.Expr = nil
+	}
+	if !x.isAcquired {
+		return
+	}
+	trackFree(x)
+	x.Query = ""
+	x.Terms = x.Terms[:0]
+	x.Fields = x.Fields[:0]
+	searchExprPool.Put(x)
+}
+
+// Complexity returns the complexity of the expression.
+// It is the complexity of the equivalent Expr increased by 1 for the node.
+func (x *SearchExpr) Complexity() int64 {
+	if x.Expr == nil {
+		return 1
+	}
+	return x.Expr.Complexity() + 1
+}
+
+func (x *SearchExpr) isFilterExpr() {}
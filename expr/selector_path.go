@@ -0,0 +1,228 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrInvalidSelectorPath is an error returned by the ParseSelectorPath for a malformed or unknown path.
+var ErrInvalidSelectorPath = errors.New("invalid selector path")
+
+// Path returns the dot separated path of the selector, including the map keys, i.e.: 'sub.name' or 'map_str_i32.key'.
+// The path is rendered in the filter syntax, where the map keys which are not valid identifiers are double-quoted,
+// i.e. 'map_str_i32."a.b"', and the field names like the keywords are surrounded with the backticks.
+// It could be parsed back with the ParseSelectorPath.
+func (e *FieldSelectorExpr) Path() string {
+	if e == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for cur := Expr(e); !isNilExpr(cur); {
+		switch ct := cur.(type) {
+		case *FieldSelectorExpr:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			writeIdent(&sb, string(ct.Field))
+			cur = ct.Traversal
+		case *MapKeyExpr:
+			sb.WriteByte('.')
+			writeMapKey(&sb, ct.Key)
+			cur = ct.Traversal
+		default:
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+// writeMapKey writes the map key of the selector path.
+func writeMapKey(sb *strings.Builder, key Expr) {
+	ve, ok := key.(*ValueExpr)
+	if !ok || ve == nil {
+		sb.WriteByte('*')
+		return
+	}
+	switch v := ve.Value.(type) {
+	case string:
+		if isIdent(v) {
+			sb.WriteString(v)
+		} else {
+			writeQuoted(sb, v)
+		}
+	default:
+		fmt.Fprint(sb, v)
+	}
+}
+
+// ParseSelectorPath parses the selector path of the message md, rendered by the FieldSelectorExpr.Path.
+// The path segments are the proto field names, and the keys following the map fields,
+// which are converted to the Go values of the map key kind, i.e. int64 for the int32 keys.
+// The returned expression needs to be released with the Free method.
+func ParseSelectorPath(md protoreflect.MessageDescriptor, path string) (*FieldSelectorExpr, error) {
+	if md == nil {
+		return nil, fmt.Errorf("%w: message descriptor is not set", ErrInvalidSelectorPath)
+	}
+	segments, err := splitSelectorPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		root *FieldSelectorExpr
+		tail Expr
+	)
+	attach := func(x Expr) {
+		switch tt := tail.(type) {
+		case *FieldSelectorExpr:
+			tt.Traversal = x
+		case *MapKeyExpr:
+			tt.Traversal = x
+		}
+		tail = x
+	}
+	fail := func(format string, args ...any) (*FieldSelectorExpr, error) {
+		root.Free()
+		return nil, fmt.Errorf("%w: %q: %s", ErrInvalidSelectorPath, path, fmt.Sprintf(format, args...))
+	}
+
+	for i := 0; i < len(segments); i++ {
+		if md == nil {
+			return fail("cannot traverse through a non-message field")
+		}
+		fd := md.Fields().ByName(protoreflect.Name(segments[i]))
+		if fd == nil {
+			return fail("field %s not found in the message: %s", segments[i], md.FullName())
+		}
+		fs := AcquireFieldSelectorExpr()
+		fs.Message = md.FullName()
+		fs.Field = fd.Name()
+		if root == nil {
+			root = fs
+			tail = fs
+		} else {
+			attach(fs)
+		}
+
+		md = nil
+		switch {
+		case fd.IsMap():
+			if i+1 == len(segments) {
+				continue
+			}
+			i++
+			key, err := parseMapKey(fd.MapKey(), segments[i])
+			if err != nil {
+				return fail("%v", err)
+			}
+			mk := AcquireMapKeyExpr()
+			mk.Key = key
+			attach(mk)
+			md = fd.MapValue().Message()
+		case fd.IsList():
+			if i+1 < len(segments) {
+				return fail("cannot traverse through a repeated field: %s", fd.Name())
+			}
+		default:
+			md = fd.Message()
+		}
+	}
+	return root, nil
+}
+
+// parseMapKey parses the map key of the field kind.
+func parseMapKey(fd protoreflect.FieldDescriptor, s string) (*ValueExpr, error) {
+	var (
+		v   any
+		err error
+	)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = s
+	case protoreflect.BoolKind:
+		v, err = strconv.ParseBool(s)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err = strconv.ParseInt(s, 10, 32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err = strconv.ParseUint(s, 10, 32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err = strconv.ParseUint(s, 10, 64)
+	default:
+		return nil, fmt.Errorf("unsupported map key kind: %s", fd.Kind())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s map key: %s", fd.Kind(), s)
+	}
+	ve := AcquireValueExpr()
+	ve.Value = v
+	return ve, nil
+}
+
+// splitSelectorPath splits the path by the dots, which are not within the double quotes or backticks,
+// and unquotes the segments.
+func splitSelectorPath(path string) ([]string, error) {
+	var (
+		segments []string
+		sb       strings.Builder
+		quote    rune
+		escaped  bool
+		quoted   bool
+	)
+	for _, r := range path {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			sb.WriteRune(r)
+		case r == '"' || r == '`':
+			if sb.Len() > 0 || quoted {
+				return nil, fmt.Errorf("%w: %q: unexpected quote within a segment", ErrInvalidSelectorPath, path)
+			}
+			quote, quoted = r, true
+		case r == '.':
+			if sb.Len() == 0 && !quoted {
+				return nil, fmt.Errorf("%w: %q: empty segment", ErrInvalidSelectorPath, path)
+			}
+			segments = append(segments, sb.String())
+			sb.Reset()
+			quoted = false
+		default:
+			if quoted {
+				return nil, fmt.Errorf("%w: %q: unexpected character after the quoted segment", ErrInvalidSelectorPath, path)
+			}
+			sb.WriteRune(r)
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("%w: %q: unterminated quote", ErrInvalidSelectorPath, path)
+	}
+	if sb.Len() == 0 && !quoted {
+		return nil, fmt.Errorf("%w: %q: empty segment", ErrInvalidSelectorPath, path)
+	}
+	return append(segments, sb.String()), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestParseSelectorPath(t *testing.T) {
+	LeakCheck(t)
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tc := []struct {
+		path string
+		// want is the rendered path, the same as the path if empty.
+		want string
+		err  bool
+	}{
+		{path: "i32"},
+		{path: "sub.sub.name"},
+		{path: "map_str_i32"},
+		{path: "map_str_i32.key"},
+		{path: `map_str_i32."a.b"`},
+		{path: `map_str_i32."a\"b"`},
+		{path: `map_str_msg.key.sub.name`},
+		{path: "map_i32_str.-5"},
+		{path: "`NOT`"},
+		{path: `map_str_i32."key"`, want: "map_str_i32.key"},
+		{path: "`i32`", want: "i32"},
+		{path: "", err: true},
+		{path: "sub..name", err: true},
+		{path: "sub.", err: true},
+		{path: "unknown", err: true},
+		{path: "i32.sub", err: true},
+		{path: "rp_sub.name", err: true},
+		{path: "map_i32_str.key", err: true},
+		{path: `map_str_i32."key`, err: true},
+		{path: `map_str_i32."a"b`, err: true},
+	}
+	for _, tt := range tc {
+		t.Run(tt.path, func(t *testing.T) {
+			fs, err := ParseSelectorPath(md, tt.path)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidSelectorPath) {
+					t.Fatalf("expected error %v but got %v", ErrInvalidSelectorPath, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer fs.Free()
+
+			want := tt.want
+			if want == "" {
+				want = tt.path
+			}
+			if got := fs.Path(); got != want {
+				t.Errorf("expected path %s but got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestParseSelectorPath_MapKey(t *testing.T) {
+	LeakCheck(t)
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	fs, err := ParseSelectorPath(md, "map_i32_str.7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer fs.Free()
+
+	mk, ok := fs.Traversal.(*MapKeyExpr)
+	if !ok {
+		t.Fatalf("expected map key expression but got %T", fs.Traversal)
+	}
+	if v := mk.Key.(*ValueExpr).Value; v != int64(7) {
+		t.Errorf("expected int64 key 7 but got %T %v", v, v)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/gob"
+	"sync"
+)
+
+func init() {
+	gob.Register(new(SequenceExpr))
+}
+
+var sequenceExprPool = &sync.Pool{
+	New: func() any {
+		return &SequenceExpr{
+			Factors:    make([]FilterExpr, 0, initialCapacity()),
+			Weights:    make([]float64, 0, initialCapacity()),
+			isAcquired: true,
+		}
+	},
+}
+
+// AcquireSequenceExpr acquires a SequenceExpr from the pool.
+// Once acquired it must be released via Free method.
+func AcquireSequenceExpr() *SequenceExpr {
+	x := sequenceExprPool.Get().(*SequenceExpr)
+	trackAcquire(x)
+	return x
+}
+
+var _ FilterExpr = (*SequenceExpr)(nil)
+
+// SequenceExpr is a sequence of the whitespace separated factors, i.e.: `New York Giants`.
+// It is satisfied if all the factors are, as the AndExpr, but it preserves the order of the factors
+// along with their relevance weights, so that the search backends could rank the matches
+// by the AIP-160 sequence semantics.
+type SequenceExpr struct {
+	// Factors are the expressions of the sequence factors, in the order of the sequence.
+	Factors []FilterExpr
+
+	// Weights are the relevance weights of the Factors at the same indexes.
+	Weights []float64
+
+	isAcquired bool
+}
+
+// Add appends the factor x with its relevance weight.
+func (e *SequenceExpr) Add(x FilterExpr, weight float64) {
+	e.Factors = append(e.Factors, x)
+	e.Weights = append(e.Weights, weight)
+}
+
+// Weight returns the relevance weight of the i-th factor, which is 1 if not set.
+func (e *SequenceExpr) Weight(i int) float64 {
+	if i < 0 || i >= len(e.Weights) {
+		return 1
+	}
+	return e.Weights[i]
+}
+
+// Free puts the SequenceExpr back to the pool.
+func (e *SequenceExpr) Free() {
+	if e == nil {
+		return
+	}
+	for _, sub := range e.Factors {
+		if sub != nil {
+			sub.Free()
+		}
+	}
+	clear(e.Factors)
+	e.Factors = e.Factors[:0]
+	e.Weights = e.Weights[:0]
+	if e.isAcquired {
+		trackFree(e)
+		if !isRetainable(cap(e.Factors)) {
+			return
+		}
+		sequenceExprPool.Put(e)
+	}
+}
+
+// Equals returns true if the given expression is equal to the current one.
+func (e *SequenceExpr) Equals(other Expr) bool {
+	if e == nil || other == nil {
+		return false
+	}
+	s, ok := other.(*SequenceExpr)
+	if !ok {
+		return false
+	}
+
+	if len(e.Factors) != len(s.Factors) {
+		return false
+	}
+
+	for i := range e.Factors {
+		if e.Weight(i) != s.Weight(i) || !equalExpr(e.Factors[i], s.Factors[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a deep copy of the SequenceExpr.
+func (e *SequenceExpr) Clone() Expr {
+	if e == nil {
+		return nil
+	}
+
+	clone := AcquireSequenceExpr()
+	for i, expr := range e.Factors {
+		clone.Add(cloneFilterExpr(expr), e.Weight(i))
+	}
+	return clone
+}
+
+// Complexity of the SequenceExpr is the sum of complexities of the factors + 1.
+func (e *SequenceExpr) Complexity() int64 {
+	var complexity int64 = 1
+	for _, expr := range e.Factors {
+		complexity += expr.Complexity()
+	}
+	return complexity
+}
+
+func (e *SequenceExpr) isFilterExpr() {}
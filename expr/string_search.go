@@ -16,6 +16,8 @@ package expr
 
 import (
 	"encoding/gob"
+	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -34,7 +36,9 @@ var stringSearchExprPool = &sync.Pool{
 // AcquireStringSearchExpr acquires a StringSearchExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireStringSearchExpr() *StringSearchExpr {
-	return stringSearchExprPool.Get().(*StringSearchExpr)
+	x := stringSearchExprPool.Get().(*StringSearchExpr)
+	trackAcquire(x)
+	return x
 }
 
 
@@ -56,9 +60,58 @@ type StringSearchExpr struct {
 	// SearchComplexity is the complexity assigned by the parser.
 	SearchComplexity int64
 
+	// CaseInsensitive is true if the value should be searched regardless of its case.
+	// It matches the CaseInsensitive flag of the containing CompareExpr.
+	CaseInsensitive bool
+
+	// Pattern is the original string the expression was parsed from, i.e. '*foo*' of the wildcard
+	// string literal, or 'foo' of the substring HAS comparison and of the search query term.
+	Pattern string
+
 	isAcquired bool
 }
 
+// LikePattern returns the SQL LIKE pattern of the search, where the wildcards are the '%' characters
+// and the '%', '_' and escape characters of the Value are escaped with the escape character, i.e.:
+// the value '10%_off' with the suffix wildcard and the '\' escape results in '10\%\_off%'.
+// The pattern is used with the ESCAPE clause of the escape character.
+// The CaseInsensitive flag is not reflected in the pattern, i.e. an ILIKE or LOWER should be used.
+func (x *StringSearchExpr) LikePattern(escape rune) string {
+	var sb strings.Builder
+	sb.Grow(len(x.Value) + 2)
+	if x.PrefixWildcard {
+		sb.WriteByte('%')
+	}
+	for _, r := range x.Value {
+		if r == '%' || r == '_' || r == escape {
+			sb.WriteRune(escape)
+		}
+		sb.WriteRune(r)
+	}
+	if x.SuffixWildcard {
+		sb.WriteByte('%')
+	}
+	return sb.String()
+}
+
+// RegexPattern returns the regular expression of the search, in the RE2 syntax.
+// The Value is quoted and anchored at the sides without the wildcard, i.e.: 'foo.bar*' results in '^foo\.bar'.
+// The case-insensitive search is prefixed with the '(?i)' flag.
+func (x *StringSearchExpr) RegexPattern() string {
+	var sb strings.Builder
+	if x.CaseInsensitive {
+		sb.WriteString("(?i)")
+	}
+	if !x.PrefixWildcard {
+		sb.WriteByte('^')
+	}
+	sb.WriteString(regexp.QuoteMeta(x.Value))
+	if !x.SuffixWildcard {
+		sb.WriteByte('$')
+	}
+	return sb.String()
+}
+
 // Clone returns a copy of the StringSearchExpr.
 func (x *StringSearchExpr) Clone() Expr {
 	if x == nil {
@@ -69,6 +122,8 @@ func (x *StringSearchExpr) Clone() Expr {
 	clone.PrefixWildcard = x.PrefixWildcard
 	clone.SuffixWildcard = x.SuffixWildcard
 	clone.SearchComplexity = x.SearchComplexity
+	clone.CaseInsensitive = x.CaseInsensitive
+	clone.Pattern = x.Pattern
 	return clone
 }
 
@@ -80,7 +135,8 @@ func (x *StringSearchExpr) Equals(other Expr) bool {
 	if oc, ok := other.(*StringSearchExpr); ok {
 		return x.Value == oc.Value &&
 			x.PrefixWildcard == oc.PrefixWildcard &&
-			x.SuffixWildcard == oc.SuffixWildcard
+			x.SuffixWildcard == oc.SuffixWildcard &&
+			x.CaseInsensitive == oc.CaseInsensitive
 	}
 	return false
 }
@@ -90,7 +146,8 @@ func (x *StringSearchExpr) Free() {
 	if x == nil || !x.isAcquired {
 		return
 	}
-	*x = StringSearchExpr{}
+	trackFree(x)
+	*x = StringSearchExpr{isAcquired: true}
 	stringSearchExprPool.Put(x)
 }
 
@@ -0,0 +1,87 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestStringSearchExpr_LikePattern(t *testing.T) {
+	tc := []struct {
+		name   string
+		x      StringSearchExpr
+		escape rune
+		want   string
+	}{
+		{name: "exact", x: StringSearchExpr{Value: "foo"}, escape: '\\', want: `foo`},
+		{name: "prefix", x: StringSearchExpr{Value: "foo", SuffixWildcard: true}, escape: '\\', want: `foo%`},
+		{name: "suffix", x: StringSearchExpr{Value: "foo", PrefixWildcard: true}, escape: '\\', want: `%foo`},
+		{name: "substring", x: StringSearchExpr{Value: "foo", PrefixWildcard: true, SuffixWildcard: true}, escape: '\\', want: `%foo%`},
+		{name: "escaped", x: StringSearchExpr{Value: `10%_off\`, SuffixWildcard: true}, escape: '\\', want: `10\%\_off\\%`},
+		{name: "custom escape", x: StringSearchExpr{Value: `a!b%`, PrefixWildcard: true}, escape: '!', want: `%a!!b!%`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.x.LikePattern(tt.escape); got != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStringSearchExpr_RegexPattern(t *testing.T) {
+	tc := []struct {
+		name    string
+		x       StringSearchExpr
+		want    string
+		match   string
+		noMatch string
+	}{
+		{name: "prefix", x: StringSearchExpr{Value: "fo.o", SuffixWildcard: true}, want: `^fo\.o`, match: "fo.obar", noMatch: "foxo"},
+		{name: "suffix", x: StringSearchExpr{Value: "foo", PrefixWildcard: true}, want: `foo$`, match: "barfoo", noMatch: "foobar"},
+		{name: "substring", x: StringSearchExpr{Value: "a+b", PrefixWildcard: true, SuffixWildcard: true}, want: `a\+b`, match: "xa+by", noMatch: "aab"},
+		{name: "case insensitive", x: StringSearchExpr{Value: "foo", SuffixWildcard: true, CaseInsensitive: true}, want: `(?i)^foo`, match: "FOObar", noMatch: "barFOO"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.x.RegexPattern()
+			if got != tt.want {
+				t.Fatalf("expected %q but got %q", tt.want, got)
+			}
+			re := regexp.MustCompile(got)
+			if !re.MatchString(tt.match) {
+				t.Errorf("expected %q to match %q", got, tt.match)
+			}
+			if re.MatchString(tt.noMatch) {
+				t.Errorf("expected %q not to match %q", got, tt.noMatch)
+			}
+		})
+	}
+}
+
+func TestStringSearchExpr_Clone(t *testing.T) {
+	x := AcquireStringSearchExpr()
+	defer x.Free()
+	x.Value = "foo"
+	x.Pattern = "*foo"
+	x.PrefixWildcard = true
+
+	clone := x.Clone().(*StringSearchExpr)
+	defer clone.Free()
+	if clone.Pattern != x.Pattern || !clone.Equals(x) {
+		t.Errorf("expected clone %+v to equal %+v", clone, x)
+	}
+}
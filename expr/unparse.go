@@ -0,0 +1,580 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// anyFullName is the full name of the google.protobuf.Any message.
+const anyFullName protoreflect.FullName = "google.protobuf.Any"
+
+// ErrNotRenderable is an error returned by the String if the expression has no AIP-160 filter representation.
+var ErrNotRenderable = errors.New("expression cannot be rendered as a filter")
+
+// String renders the filter expression back into a canonical AIP-160 filter string,
+// with the quoted strings, the AND, OR and NOT operators, and the parentheses where the precedence requires them.
+// The rendered filter parses into an equivalent expression with the interpreter of the same message and options.
+// The enum values are rendered by their names, resolved with the descriptors of the protoregistry.GlobalFiles.
+// The SearchExpr is rendered as its equivalent filter expression.
+// The expressions without a filter representation, i.e. the message values, result in ErrNotRenderable.
+// A nil expression is an empty filter.
+func String(x FilterExpr) (string, error) {
+	return StringWithFiles(x, protoregistry.GlobalFiles)
+}
+
+// StringWithFiles renders the filter expression the same way as the String,
+// but resolves the enum values with the descriptors of given files.
+func StringWithFiles(x FilterExpr, files *protoregistry.Files) (string, error) {
+	if IsMatchAll(x) {
+		return "", nil
+	}
+	u := unparser{files: files}
+	if err := u.writeFilter(x, precTop); err != nil {
+		return "", err
+	}
+	return u.sb.String(), nil
+}
+
+// The precedence of the enclosing expression, the higher binds tighter.
+const (
+	precTop = iota
+	precAnd
+	precSequence
+	precOr
+	precNot
+)
+
+type unparser struct {
+	sb    strings.Builder
+	files *protoregistry.Files
+}
+
+func (u *unparser) writeFilter(x FilterExpr, prec int) error {
+	switch xt := x.(type) {
+	case *AndExpr:
+		return u.writeJunction(xt.Expr, " AND ", precAnd, prec)
+	case *SequenceExpr:
+		return u.writeJunction(xt.Factors, " ", precSequence, prec)
+	case *OrExpr:
+		return u.writeJunction(xt.Expr, " OR ", precOr, prec)
+	case *NotExpr:
+		if prec == precNot {
+			// The NOT operator precedes only a simple expression.
+			u.sb.WriteRune('(')
+			defer u.sb.WriteRune(')')
+		}
+		u.sb.WriteString("NOT ")
+		return u.writeFilter(xt.Expr, precNot)
+	case *CompositeExpr:
+		u.sb.WriteRune('(')
+		if err := u.writeFilter(xt.Expr, precTop); err != nil {
+			return err
+		}
+		u.sb.WriteRune(')')
+		return nil
+	case *SearchExpr:
+		return u.writeFilter(xt.Expr, prec)
+	case *CompareExpr:
+		return u.writeCompare(xt)
+	case *RegexMatchExpr:
+		if _, err := u.writeSelector(xt.Left); err != nil {
+			return err
+		}
+		u.sb.WriteString(" =~ ")
+		writeQuoted(&u.sb, xt.Pattern)
+		return nil
+	case *PresenceExpr:
+		if _, err := u.writeSelector(xt.Field); err != nil {
+			return err
+		}
+		u.sb.WriteString(":*")
+		return nil
+	case *FunctionCallExpr:
+		return u.writeFunctionCall(xt)
+	case nil:
+		return fmt.Errorf("%w: nil expression", ErrNotRenderable)
+	default:
+		return fmt.Errorf("%w: %T", ErrNotRenderable, x)
+	}
+}
+
+// writeJunction writes the AND or OR expressions, surrounded by the parentheses if the precedence requires it.
+func (u *unparser) writeJunction(exprs []FilterExpr, op string, own, prec int) error {
+	if len(exprs) == 0 {
+		return fmt.Errorf("%w: empty%s expression", ErrNotRenderable, strings.TrimRight(op, " "))
+	}
+	if prec > own {
+		u.sb.WriteRune('(')
+	}
+	for i, e := range exprs {
+		if i > 0 {
+			u.sb.WriteString(op)
+		}
+		if err := u.writeFilter(e, own); err != nil {
+			return err
+		}
+	}
+	if prec > own {
+		u.sb.WriteRune(')')
+	}
+	return nil
+}
+
+func (u *unparser) writeCompare(x *CompareExpr) error {
+	if ae, ok := x.Right.(*AnyElementExpr); ok && x.Comparator == HAS && (ae.All || !isStructPattern(ae.Filter)) {
+		return u.writeQuantifier(x.Left, ae)
+	}
+	fd, err := u.writeSelector(x.Left)
+	if err != nil {
+		return err
+	}
+	if x.Comparator == HAS {
+		u.sb.WriteRune(':')
+		if fd != nil && fd.IsMap() {
+			// The HAS comparison of a map selects its keys.
+			fd = fd.MapKey()
+		}
+	} else {
+		if x.Comparator.IsCustom() {
+			if _, ok := x.Comparator.CustomSymbol(); !ok {
+				return fmt.Errorf("%w: unregistered comparator %s", ErrNotRenderable, x.Comparator)
+			}
+		}
+		u.sb.WriteRune(' ')
+		u.sb.WriteString(x.Comparator.String())
+		u.sb.WriteRune(' ')
+	}
+	return u.writeValue(x.Right, fd)
+}
+
+// writeSelector writes the field selector, and returns the descriptor of the selected value, if it is resolvable.
+func (u *unparser) writeSelector(x FilterExpr) (protoreflect.FieldDescriptor, error) {
+	switch xt := x.(type) {
+	case *FieldSelectorExpr:
+	case *FunctionCallExpr:
+		return nil, u.writeFunctionCall(xt)
+	default:
+		return nil, fmt.Errorf("%w: %T is not a field selector", ErrNotRenderable, x)
+	}
+
+	var fd protoreflect.FieldDescriptor
+	start := u.sb.Len()
+	for cur, first := Expr(x), true; cur != nil; first = false {
+		switch ct := cur.(type) {
+		case *FieldSelectorExpr:
+			if fd != nil && fd.Message() != nil && fd.Message().FullName() == anyFullName && ct.Message != anyFullName {
+				// The field of the message unpacked from the google.protobuf.Any field.
+				u.writeAnyUnpack(start, ct.Message)
+			}
+			if !first {
+				u.sb.WriteRune('.')
+			}
+			writeIdent(&u.sb, string(ct.Field))
+			fd = u.findField(ct.Message.Append(ct.Field))
+			cur = ct.Traversal
+		case *MapKeyExpr:
+			u.sb.WriteRune('.')
+			key, ok := ct.Key.(*ValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("%w: map key of type %T", ErrNotRenderable, ct.Key)
+			}
+			if s, ok := key.Value.(string); ok && isIdent(s) {
+				u.sb.WriteString(s)
+			} else if err := u.writeScalar(key.Value, nil); err != nil {
+				return nil, err
+			}
+			if fd != nil && fd.IsMap() {
+				fd = fd.MapValue()
+			}
+			cur = ct.Traversal
+		default:
+			return nil, fmt.Errorf("%w: selector traversal of type %T", ErrNotRenderable, cur)
+		}
+	}
+	return fd, nil
+}
+
+// writeAnyUnpack wraps the selector of the google.protobuf.Any field, written since the start, with the any.Unpack
+// type assertion of the message type, i.e.: any.Unpack(payload, my.Type).
+func (u *unparser) writeAnyUnpack(start int, typ protoreflect.FullName) {
+	s := u.sb.String()
+	u.sb = strings.Builder{}
+	u.sb.WriteString(s[:start])
+	u.sb.WriteString("any.Unpack(")
+	u.sb.WriteString(s[start:])
+	u.sb.WriteString(", ")
+	u.sb.WriteString(string(typ))
+	u.sb.WriteRune(')')
+}
+
+func (u *unparser) findField(name protoreflect.FullName) protoreflect.FieldDescriptor {
+	if u.files == nil {
+		return nil
+	}
+	d, err := u.files.FindDescriptorByName(name)
+	if err != nil {
+		return nil
+	}
+	fd, _ := d.(protoreflect.FieldDescriptor)
+	return fd
+}
+
+func (u *unparser) writeFunctionCall(x *FunctionCallExpr) error {
+	if x.PkgName != "" {
+		u.sb.WriteString(x.PkgName)
+		u.sb.WriteRune('.')
+	}
+	u.sb.WriteString(x.Name)
+	u.sb.WriteRune('(')
+	for i, arg := range x.Arguments {
+		if i > 0 {
+			u.sb.WriteString(", ")
+		}
+		if err := u.writeValue(arg, nil); err != nil {
+			return err
+		}
+	}
+	u.sb.WriteRune(')')
+	return nil
+}
+
+// writeValue writes the right hand side value of a comparison with the field fd, which could be nil if unknown.
+func (u *unparser) writeValue(x FilterExpr, fd protoreflect.FieldDescriptor) error {
+	switch xt := x.(type) {
+	case *ValueExpr:
+		return u.writeScalar(xt.Value, fd)
+	case *StringSearchExpr:
+		var sb strings.Builder
+		if xt.PrefixWildcard {
+			sb.WriteRune('*')
+		}
+		sb.WriteString(xt.Value)
+		if xt.SuffixWildcard {
+			sb.WriteRune('*')
+		}
+		writeQuoted(&u.sb, sb.String())
+		return nil
+	case *ArrayExpr:
+		u.sb.WriteRune('[')
+		for i, e := range xt.Elements {
+			if i > 0 {
+				u.sb.WriteString(", ")
+			}
+			if err := u.writeValue(e, fd); err != nil {
+				return err
+			}
+		}
+		u.sb.WriteRune(']')
+		return nil
+	case *AnyElementExpr:
+		return u.writeAnyElement(xt)
+	case *FieldSelectorExpr:
+		_, err := u.writeSelector(xt)
+		return err
+	case *FunctionCallExpr:
+		return u.writeFunctionCall(xt)
+	case *BinaryExpr:
+		return u.writeBinary(xt, fd)
+	default:
+		return fmt.Errorf("%w: value of type %T", ErrNotRenderable, x)
+	}
+}
+
+// writeBinary writes the arithmetic expression, i.e.: create_time + 86400s.
+// The filtering language has no grouping of the operations, thus the operands of a lower precedence,
+// or the right operand of the same precedence, are not renderable.
+func (u *unparser) writeBinary(x *BinaryExpr, fd protoreflect.FieldDescriptor) error {
+	if lb, ok := x.Left.(*BinaryExpr); ok && lb.Op.Precedence() < x.Op.Precedence() {
+		return fmt.Errorf("%w: left operand of %s requires grouping", ErrNotRenderable, x.Op)
+	}
+	if rb, ok := x.Right.(*BinaryExpr); ok && rb.Op.Precedence() <= x.Op.Precedence() {
+		return fmt.Errorf("%w: right operand of %s requires grouping", ErrNotRenderable, x.Op)
+	}
+	if err := u.writeValue(x.Left, fd); err != nil {
+		return err
+	}
+	u.sb.WriteRune(' ')
+	u.sb.WriteString(x.Op.String())
+	u.sb.WriteRune(' ')
+	return u.writeValue(x.Right, fd)
+}
+
+// writeAnyElement writes the struct pattern of the element equality comparisons, i.e.: {name: "foo", i32: 1}.
+func (u *unparser) writeAnyElement(x *AnyElementExpr) error {
+	var exprs []FilterExpr
+	switch ft := x.Filter.(type) {
+	case *AndExpr:
+		exprs = ft.Expr
+	case *CompareExpr:
+		exprs = []FilterExpr{ft}
+	case nil:
+	default:
+		return fmt.Errorf("%w: any element filter of type %T", ErrNotRenderable, x.Filter)
+	}
+	u.sb.WriteRune('{')
+	for i, e := range exprs {
+		ce, ok := e.(*CompareExpr)
+		if !ok || ce.Comparator != EQ {
+			return fmt.Errorf("%w: any element filter is not a struct pattern", ErrNotRenderable)
+		}
+		fs, ok := ce.Left.(*FieldSelectorExpr)
+		if !ok || fs.Traversal != nil {
+			return fmt.Errorf("%w: any element filter is not a struct pattern", ErrNotRenderable)
+		}
+		if i > 0 {
+			u.sb.WriteString(", ")
+		}
+		writeIdent(&u.sb, string(fs.Field))
+		u.sb.WriteString(": ")
+		if err := u.writeValue(ce.Right, u.findField(fs.Message.Append(fs.Field))); err != nil {
+			return err
+		}
+	}
+	u.sb.WriteRune('}')
+	return nil
+}
+
+// writeQuantifier writes the element filter of the repeated field as the collection function, i.e.: all(items, quantity > 0).
+func (u *unparser) writeQuantifier(left FilterExpr, x *AnyElementExpr) error {
+	if x.All {
+		if x.Filter == nil {
+			return fmt.Errorf("%w: all elements expression without a filter", ErrNotRenderable)
+		}
+		u.sb.WriteString("all(")
+	} else {
+		u.sb.WriteString("any(")
+	}
+	if _, err := u.writeSelector(left); err != nil {
+		return err
+	}
+	if x.Filter != nil {
+		u.sb.WriteString(", ")
+		switch x.Filter.(type) {
+		case *CompareExpr, *RegexMatchExpr, *PresenceExpr, *CompositeExpr, *FunctionCallExpr:
+			if err := u.writeFilter(x.Filter, precTop); err != nil {
+				return err
+			}
+		default:
+			// The function argument is a single restriction, thus the other filters need to be grouped.
+			u.sb.WriteRune('(')
+			if err := u.writeFilter(x.Filter, precTop); err != nil {
+				return err
+			}
+			u.sb.WriteRune(')')
+		}
+	}
+	u.sb.WriteRune(')')
+	return nil
+}
+
+// isStructPattern checks if the element filter consists only of the equality comparisons of the element fields,
+// which could be written as the struct pattern.
+func isStructPattern(x FilterExpr) bool {
+	var exprs []FilterExpr
+	switch ft := x.(type) {
+	case nil:
+		return true
+	case *AndExpr:
+		exprs = ft.Expr
+	case *CompareExpr:
+		exprs = []FilterExpr{ft}
+	default:
+		return false
+	}
+	for _, e := range exprs {
+		ce, ok := e.(*CompareExpr)
+		if !ok || ce.Comparator != EQ {
+			return false
+		}
+		fs, ok := ce.Left.(*FieldSelectorExpr)
+		if !ok || fs.Traversal != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeScalar writes the value literal.
+func (u *unparser) writeScalar(v any, fd protoreflect.FieldDescriptor) error {
+	switch vt := v.(type) {
+	case nil:
+		u.sb.WriteString("null")
+	case string:
+		writeQuoted(&u.sb, vt)
+	case bool:
+		u.sb.WriteString(strconv.FormatBool(vt))
+	case int:
+		u.sb.WriteString(strconv.Itoa(vt))
+	case int32:
+		u.sb.WriteString(strconv.FormatInt(int64(vt), 10))
+	case int64:
+		u.sb.WriteString(strconv.FormatInt(vt, 10))
+	case uint32:
+		u.sb.WriteString(strconv.FormatUint(uint64(vt), 10))
+	case uint64:
+		u.sb.WriteString(strconv.FormatUint(vt, 10))
+	case float32:
+		return u.writeFloat(float64(vt))
+	case float64:
+		return u.writeFloat(vt)
+	case []byte:
+		u.sb.WriteString("0x")
+		u.sb.WriteString(hex.EncodeToString(vt))
+	case time.Time:
+		u.sb.WriteString(vt.UTC().Format(time.RFC3339Nano))
+	case time.Duration:
+		writeDuration(&u.sb, vt)
+	case *big.Rat:
+		return u.writeDecimal(vt)
+	case [16]byte:
+		writeQuoted(&u.sb, FormatUUID(vt))
+	case *fieldmaskpb.FieldMask:
+		writeQuoted(&u.sb, strings.Join(vt.GetPaths(), ","))
+	case protoreflect.EnumNumber:
+		if fd == nil || fd.Enum() == nil {
+			return fmt.Errorf("%w: enum number %d of unknown field", ErrNotRenderable, vt)
+		}
+		ev := fd.Enum().Values().ByNumber(vt)
+		if ev == nil {
+			return fmt.Errorf("%w: enum number %d is not a value of %s", ErrNotRenderable, vt, fd.Enum().FullName())
+		}
+		writeQuoted(&u.sb, string(ev.Name()))
+	default:
+		return fmt.Errorf("%w: value of type %T", ErrNotRenderable, v)
+	}
+	return nil
+}
+
+// writeDuration writes the duration in seconds with the 's' suffix, i.e. '1.000000001s'.
+// Contrary to the time.Duration.Seconds, it never loses the nanosecond precision.
+func writeDuration(sb *strings.Builder, d time.Duration) {
+	if d < 0 {
+		sb.WriteRune('-')
+	}
+	// The time.Duration minimum value has no positive counterpart, thus the absolute parts are computed separately.
+	sec, nsec := d/time.Second, d%time.Second
+	if sec < 0 {
+		sec = -sec
+	}
+	if nsec < 0 {
+		nsec = -nsec
+	}
+	sb.WriteString(strconv.FormatInt(int64(sec), 10))
+	if nsec > 0 {
+		frac := strconv.FormatInt(int64(nsec)+int64(time.Second), 10)[1:]
+		sb.WriteRune('.')
+		sb.WriteString(strings.TrimRight(frac, "0"))
+	}
+	sb.WriteRune('s')
+}
+
+func (u *unparser) writeFloat(f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%w: float value %v", ErrNotRenderable, f)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsRune(s, '.') {
+		s += ".0"
+	}
+	u.sb.WriteString(s)
+	return nil
+}
+
+// writeDecimal writes the exact decimal representation of the rational number, i.e. '-10.25'.
+// The rationals with no finite decimal representation, i.e. 1/3, are not renderable.
+func (u *unparser) writeDecimal(r *big.Rat) error {
+	if r.IsInt() {
+		u.sb.WriteString(r.Num().String())
+		return nil
+	}
+	// The denominator of a finite decimal has no prime factors other than 2 and 5,
+	// and the number of its fractional digits is the greater of their exponents.
+	d := new(big.Int).Set(r.Denom())
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+
+	var fives int
+	five, quo, rem := big.NewInt(5), new(big.Int), new(big.Int)
+	for quo.QuoRem(d, five, rem); rem.Sign() == 0; quo.QuoRem(d, five, rem) {
+		d.Set(quo)
+		fives++
+	}
+	if !d.IsInt64() || d.Int64() != 1 {
+		return fmt.Errorf("%w: decimal value %s has no finite representation", ErrNotRenderable, r.String())
+	}
+	u.sb.WriteString(r.FloatString(max(twos, fives)))
+	return nil
+}
+
+// writeQuoted writes the double-quoted string, with the quotes and backslashes escaped.
+func writeQuoted(sb *strings.Builder, s string) {
+	sb.WriteRune('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteRune('"')
+}
+
+// writeIdent writes the field name, the names which are not valid unquoted selector segments,
+// i.e. the keywords like AND, are surrounded with the backticks.
+func writeIdent(sb *strings.Builder, s string) {
+	if isIdent(s) {
+		sb.WriteString(s)
+		return
+	}
+	sb.WriteRune('`')
+	for _, r := range s {
+		if r == '`' || r == '\\' {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteRune('`')
+}
+
+// isIdent checks if the s could be written as an unquoted selector segment.
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	switch s {
+	case "AND", "OR", "NOT", "IN", "null", "true", "false":
+		return false
+	}
+	return true
+}
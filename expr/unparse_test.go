@@ -0,0 +1,118 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestString(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	eq := func(field string, v any) *CompareExpr {
+		return c.Compare(c.MustSelect(field), EQ, c.Value(v))
+	}
+
+	tc := []struct {
+		name string
+		x    FilterExpr
+		want string
+		err  error
+	}{
+		{name: "nil", x: nil, want: ``},
+		{name: "match all", x: AcquireMatchAllExpr(), want: ``},
+		{name: "and of or", x: c.And(c.Or(eq("i32", 1), eq("i32", 2)), eq("str", "a")), want: `i32 = 1 OR i32 = 2 AND str = "a"`},
+		{name: "or of and", x: c.Or(c.And(eq("i32", 1), eq("i32", 2)), eq("str", "a")), want: `(i32 = 1 AND i32 = 2) OR str = "a"`},
+		{name: "sequence", x: c.Sequence(c.Or(eq("i32", 1), eq("i32", 2)), eq("str", "a")), want: `i32 = 1 OR i32 = 2 str = "a"`},
+		{name: "and in sequence", x: c.Sequence(c.And(eq("i32", 1), eq("i32", 2)), eq("str", "a")), want: `(i32 = 1 AND i32 = 2) str = "a"`},
+		{name: "not of or", x: c.Not(c.Or(eq("i32", 1), eq("i32", 2))), want: `NOT (i32 = 1 OR i32 = 2)`},
+		{name: "not of not", x: c.Not(c.Not(eq("bool", true))), want: `NOT (NOT bool = true)`},
+		{name: "function", x: c.Compare(c.MustSelect("str"), EQ, c.FunctionCall("pkg", "fn", c.Value("a"), c.Value(int64(1)))), want: `str = pkg.fn("a", 1)`},
+		{name: "enum", x: eq("enum", testpb.Enum_ONE.Number()), want: `enum = "ONE"`},
+		{name: "nested field", x: eq("sub.name", "a"), want: `sub.name = "a"`},
+		{name: "duration nanos", x: eq("duration", 315360000*time.Second+time.Nanosecond), want: `duration = 315360000.000000001s`},
+		{name: "negative duration", x: eq("duration", -1500*time.Millisecond), want: `duration = -1.5s`},
+		{name: "min duration", x: eq("duration", time.Duration(math.MinInt64)), want: `duration = -9223372036.854775808s`},
+		{name: "timestamp nanos", x: eq("timestamp", time.Unix(1700000000, 1).In(time.FixedZone("X", 3600))), want: `timestamp = 2023-11-14T22:13:20.000000001Z`},
+		{name: "decimal", x: eq("double", big.NewRat(-41, 40)), want: `double = -1.025`},
+		{name: "integer decimal", x: eq("double", new(big.Rat).SetFrac(new(big.Int).Lsh(big.NewInt(1), 70), big.NewInt(1))), want: `double = 1180591620717411303424`},
+		{name: "infinite decimal", x: eq("double", big.NewRat(1, 3)), err: ErrNotRenderable},
+		{name: "uuid", x: eq("str", [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}), want: `str = "123e4567-e89b-12d3-a456-426614174000"`},
+		{name: "empty and", x: c.And(), err: ErrNotRenderable},
+		{name: "message value", x: eq("sub", new(testpb.Message).ProtoReflect()), err: ErrNotRenderable},
+		{name: "unregistered comparator", x: c.Compare(c.MustSelect("str"), Comparator(199), c.Value("a")), err: ErrNotRenderable},
+		{
+			name: "arithmetic",
+			x:    c.Compare(c.MustSelect("i32"), GT, c.Binary(c.MustSelect("i64"), ADD, c.Binary(c.Value(int64(2)), MUL, c.Value(int64(3))))),
+			want: `i32 > i64 + 2 * 3`,
+		},
+		{
+			name: "arithmetic grouping",
+			x:    c.Compare(c.MustSelect("i32"), GT, c.Binary(c.Binary(c.MustSelect("i64"), ADD, c.Value(int64(2))), MUL, c.Value(int64(3)))),
+			err:  ErrNotRenderable,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := String(tt.x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterComparator(t *testing.T) {
+	c := Composer{Desc: new(testpb.Message).ProtoReflect().Descriptor()}
+	like := Comparator(200)
+
+	if err := RegisterComparator(like, "~"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RegisterComparator(like, "~"); err != nil {
+		t.Fatalf("expected registering the same symbol to be a no-op but got: %v", err)
+	}
+	if err := RegisterComparator(like, "^="); err == nil {
+		t.Fatalf("expected error of the conflicting symbol")
+	}
+	if err := RegisterComparator(EQ, "~"); err == nil {
+		t.Fatalf("expected error of the standard comparator")
+	}
+	if like.String() != "~" {
+		t.Errorf("expected comparator string ~ but got %s", like)
+	}
+
+	got, err := String(c.Compare(c.MustSelect("str"), like, c.Value("a%")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `str ~ "a%"`; got != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
@@ -21,7 +21,7 @@ import (
 var updateExprPool = &sync.Pool{
 	New: func() any {
 		return &UpdateExpr{
-			Elements:   make([]UpdateFieldValue, 0, 10),
+			Elements:   make([]UpdateFieldValue, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -30,7 +30,9 @@ var updateExprPool = &sync.Pool{
 // AcquireUpdateExpr acquires an UpdateExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireUpdateExpr() *UpdateExpr {
-	return updateExprPool.Get().(*UpdateExpr)
+	x := updateExprPool.Get().(*UpdateExpr)
+	trackAcquire(x)
+	return x
 }
 
 // UpdateExpr is an expression that contains fields to update along with their values.
@@ -38,6 +40,8 @@ func AcquireUpdateExpr() *UpdateExpr {
 // In that case, the elements of the Value UpdateExpr, are relative to the field of the parent UpdateExpr.
 type UpdateExpr struct {
 	// Elements is a list of fields to update along with their values.
+	// The fieldmask.Parser orders them by the mask paths, and then by the field numbers and map keys
+	// of the expanded message and map values.
 	Elements []UpdateFieldValue
 
 	isAcquired bool
@@ -56,8 +60,13 @@ func (e *UpdateExpr) Free() {
 			sub.Value.Free()
 		}
 	}
+	clear(e.Elements)
+	e.Elements = e.Elements[:0]
 	if e.isAcquired {
-		e.Elements = e.Elements[:0]
+		trackFree(e)
+		if !isRetainable(cap(e.Elements)) {
+			return
+		}
 		updateExprPool.Put(e)
 	}
 }
@@ -69,20 +78,20 @@ func (e *UpdateExpr) Clone() Expr {
 	}
 	clone := AcquireUpdateExpr()
 	for _, expr := range e.Elements {
-		clone.Elements = append(clone.Elements, UpdateFieldValue{
-			Field: expr.Field.Clone().(*FieldSelectorExpr),
-			Value: expr.Value.Clone().(UpdateValueExpr),
-		})
+		fv := UpdateFieldValue{Field: Clone(expr.Field)}
+		if !isNilExpr(expr.Value) {
+			fv.Value, _ = expr.Value.Clone().(UpdateValueExpr)
+		}
+		clone.Elements = append(clone.Elements, fv)
 	}
 	return clone
 }
 
 // Equals returns true if the given expression is equal to the current one.
 func (e *UpdateExpr) Equals(other Expr) bool {
-	if other == nil {
+	if e == nil || other == nil {
 		return false
 	}
-
 	oa, ok := other.(*UpdateExpr)
 	if !ok {
 		return false
@@ -93,10 +102,10 @@ func (e *UpdateExpr) Equals(other Expr) bool {
 	}
 
 	for i := range e.Elements {
-		if !e.Elements[i].Field.Equals(oa.Elements[i].Field) {
+		if !equalExpr(e.Elements[i].Field, oa.Elements[i].Field) {
 			return false
 		}
-		if !e.Elements[i].Value.Equals(oa.Elements[i].Value) {
+		if !equalExpr(e.Elements[i].Value, oa.Elements[i].Value) {
 			return false
 		}
 	}
@@ -125,7 +134,7 @@ type UpdateValueExpr interface {
 var arrayUpdateExprPool = &sync.Pool{
 	New: func() any {
 		return &ArrayUpdateExpr{
-			Elements:   make([]*UpdateExpr, 0, 10),
+			Elements:   make([]*UpdateExpr, 0, initialCapacity()),
 			isAcquired: true,
 		}
 	},
@@ -134,7 +143,9 @@ var arrayUpdateExprPool = &sync.Pool{
 // AcquireArrayUpdateExpr acquires an ArrayUpdateExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireArrayUpdateExpr() *ArrayUpdateExpr {
-	return arrayUpdateExprPool.Get().(*ArrayUpdateExpr)
+	x := arrayUpdateExprPool.Get().(*ArrayUpdateExpr)
+	trackAcquire(x)
+	return x
 }
 
 // ArrayUpdateExpr is an expression that can be used as a value in UpdateExpr.
@@ -152,10 +163,17 @@ func (e *ArrayUpdateExpr) Free() {
 		return
 	}
 	for _, sub := range e.Elements {
-		sub.Free()
+		if sub != nil {
+			sub.Free()
+		}
 	}
+	clear(e.Elements)
+	e.Elements = e.Elements[:0]
 	if e.isAcquired {
-		e.Elements = e.Elements[:0]
+		trackFree(e)
+		if !isRetainable(cap(e.Elements)) {
+			return
+		}
 		arrayUpdateExprPool.Put(e)
 	}
 }
@@ -167,17 +185,16 @@ func (e *ArrayUpdateExpr) Clone() Expr {
 	}
 	clone := AcquireArrayUpdateExpr()
 	for _, expr := range e.Elements {
-		clone.Elements = append(clone.Elements, expr.Clone().(*UpdateExpr))
+		clone.Elements = append(clone.Elements, Clone(expr))
 	}
 	return clone
 }
 
 // Equals returns true if the given expression is equal to the current one.
 func (e *ArrayUpdateExpr) Equals(other Expr) bool {
-	if other == nil {
+	if e == nil || other == nil {
 		return false
 	}
-
 	oa, ok := other.(*ArrayUpdateExpr)
 	if !ok {
 		return false
@@ -188,7 +205,7 @@ func (e *ArrayUpdateExpr) Equals(other Expr) bool {
 	}
 
 	for i := range e.Elements {
-		if !e.Elements[i].Equals(oa.Elements[i]) {
+		if !equalExpr(e.Elements[i], oa.Elements[i]) {
 			return false
 		}
 	}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"log/slog"
+)
+
+// UpdateSize are the size metrics of an update expression.
+// They mirror the complexity model of the filter expressions,
+// so that the services could enforce the update payload budgets.
+type UpdateSize struct {
+	// Fields is the number of the updated fields, including the fields of the nested update expressions.
+	Fields int
+
+	// Depth is the maximum number of the field selectors and map keys of the updated paths,
+	// including the relative paths of the nested update expressions.
+	Depth int
+
+	// Values is the number of the updated values,
+	// where each element of an array and each entry of a map is counted separately.
+	Values int
+
+	// MaxLen is the length of the longest array, map or array update value.
+	MaxLen int
+
+	// Complexity is the sum of the complexities of the updated fields and their values.
+	Complexity int64
+}
+
+// LogValue implements the slog.LogValuer interface, so that the update shapes are logged consistently.
+func (s UpdateSize) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("fields", s.Fields),
+		slog.Int("depth", s.Depth),
+		slog.Int("values", s.Values),
+		slog.Int("max_len", s.MaxLen),
+		slog.Int64("complexity", s.Complexity),
+	)
+}
+
+// add aggregates the o size into the s.
+func (s *UpdateSize) add(o UpdateSize) {
+	s.Fields += o.Fields
+	s.Values += o.Values
+	s.Complexity += o.Complexity
+	s.Depth = max(s.Depth, o.Depth)
+	s.MaxLen = max(s.MaxLen, o.MaxLen)
+}
+
+// UpdatePathSize is the size of a single path of the UpdateExpr.
+type UpdatePathSize struct {
+	// Path is the dot separated path of the updated field, i.e. 'sub.name' or 'map_field.key'.
+	Path string
+
+	UpdateSize
+}
+
+// UpdateMetrics are the total and per-path size metrics of the UpdateExpr.
+type UpdateMetrics struct {
+	UpdateSize
+
+	// Paths are the sizes of the paths of the UpdateExpr, in the order of its elements.
+	Paths []UpdatePathSize
+}
+
+// LogValue implements the slog.LogValuer interface.
+// The total size is followed by the "paths" group with the size of each path.
+func (m UpdateMetrics) LogValue() slog.Value {
+	paths := make([]slog.Attr, 0, len(m.Paths))
+	for _, p := range m.Paths {
+		paths = append(paths, slog.Any(p.Path, p.UpdateSize))
+	}
+	attrs := append(m.UpdateSize.LogValue().Group(), slog.Attr{Key: "paths", Value: slog.GroupValue(paths...)})
+	return slog.GroupValue(attrs...)
+}
+
+// Metrics computes the total and per-path size metrics of the UpdateExpr.
+// The complexity of each path is the sum of the complexities of its field selectors, where each map key adds 1,
+// and the complexity of its value, where a value adds 1, and an array or a map adds 1 and its elements.
+func (e *UpdateExpr) Metrics() UpdateMetrics {
+	var m UpdateMetrics
+	if e == nil {
+		return m
+	}
+	m.Paths = make([]UpdatePathSize, 0, len(e.Elements))
+	for _, elem := range e.Elements {
+		ps := UpdatePathSize{Path: elem.Field.Path(), UpdateSize: elem.size()}
+		m.Paths = append(m.Paths, ps)
+		m.add(ps.UpdateSize)
+	}
+	return m
+}
+
+// Complexity returns the total complexity of the UpdateExpr, see Metrics.
+func (e *UpdateExpr) Complexity() int64 {
+	return e.size().Complexity
+}
+
+// size returns the total size of the UpdateExpr.
+func (e *UpdateExpr) size() UpdateSize {
+	var s UpdateSize
+	if e == nil {
+		return s
+	}
+	for _, elem := range e.Elements {
+		s.add(elem.size())
+	}
+	return s
+}
+
+// size returns the size of the updated field along with its value.
+func (v UpdateFieldValue) size() UpdateSize {
+	s := updateValueSize(v.Value)
+	depth, complexity := selectorSize(v.Field)
+	s.Fields++
+	s.Depth += depth
+	s.Complexity += complexity
+	return s
+}
+
+// updateValueSize returns the size of the update value.
+func updateValueSize(x UpdateValueExpr) UpdateSize {
+	if isNilExpr(x) {
+		return UpdateSize{}
+	}
+	switch vx := x.(type) {
+	case *ValueExpr:
+		return UpdateSize{Values: 1, Complexity: vx.Complexity()}
+	case *ArrayExpr:
+		return UpdateSize{Values: len(vx.Elements), MaxLen: len(vx.Elements), Complexity: vx.Complexity()}
+	case *MapValueExpr:
+		return UpdateSize{Values: len(vx.Values), MaxLen: len(vx.Values), Complexity: vx.Complexity()}
+	case *UpdateExpr:
+		return vx.size()
+	case *ArrayUpdateExpr:
+		s := UpdateSize{MaxLen: len(vx.Elements), Complexity: 1}
+		for _, elem := range vx.Elements {
+			s.add(elem.size())
+		}
+		return s
+	}
+	return UpdateSize{}
+}
+
+// selectorSize returns the number of the field selectors and map keys of the selector path, along with their complexity.
+func selectorSize(x Expr) (depth int, complexity int64) {
+	for !isNilExpr(x) {
+		switch sx := x.(type) {
+		case *FieldSelectorExpr:
+			depth++
+			complexity += sx.FieldComplexity
+			x = sx.Traversal
+		case *MapKeyExpr:
+			depth++
+			complexity++
+			x = sx.Traversal
+		default:
+			return depth, complexity
+		}
+	}
+	return depth, complexity
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestUpdateExpr_Metrics(t *testing.T) {
+	LeakCheck(t)
+
+	field := func(name protoreflect.Name, complexity int64, traversal Expr) *FieldSelectorExpr {
+		fs := AcquireFieldSelectorExpr()
+		fs.Field = name
+		fs.FieldComplexity = complexity
+		fs.Traversal = traversal
+		return fs
+	}
+	value := func(v any) *ValueExpr {
+		ve := AcquireValueExpr()
+		ve.Value = v
+		return ve
+	}
+	update := func(elems ...UpdateFieldValue) *UpdateExpr {
+		ue := AcquireUpdateExpr()
+		ue.Elements = append(ue.Elements, elems...)
+		return ue
+	}
+
+	// rp_sub: [{name: "a", sub.i32: 1}, {name: "b"}]
+	arr := AcquireArrayUpdateExpr()
+	arr.Elements = append(arr.Elements,
+		update(
+			UpdateFieldValue{Field: field("name", 1, nil), Value: value("a")},
+			UpdateFieldValue{Field: field("sub", 1, field("i32", 2, nil)), Value: value(int64(1))},
+		),
+		update(UpdateFieldValue{Field: field("name", 1, nil), Value: value("b")}),
+	)
+	mk := AcquireMapKeyExpr()
+	mk.Key = value("key")
+	x := update(
+		UpdateFieldValue{Field: field("rp_sub", 1, nil), Value: arr},
+		UpdateFieldValue{Field: field("map", 1, mk), Value: value("v")},
+		UpdateFieldValue{Field: field("none", 1, nil)},
+	)
+	defer x.Free()
+
+	m := x.Metrics()
+	want := []UpdatePathSize{
+		{Path: "rp_sub", UpdateSize: UpdateSize{Fields: 4, Depth: 3, Values: 3, MaxLen: 2, Complexity: 10}},
+		{Path: "map.key", UpdateSize: UpdateSize{Fields: 1, Depth: 2, Values: 1, Complexity: 3}},
+		{Path: "none", UpdateSize: UpdateSize{Fields: 1, Depth: 1, Complexity: 1}},
+	}
+	if len(m.Paths) != len(want) {
+		t.Fatalf("expected %d paths but got %d", len(want), len(m.Paths))
+	}
+	for i := range want {
+		if m.Paths[i] != want[i] {
+			t.Errorf("expected path %+v but got %+v", want[i], m.Paths[i])
+		}
+	}
+	total := UpdateSize{Fields: 6, Depth: 3, Values: 4, MaxLen: 2, Complexity: 14}
+	if m.UpdateSize != total {
+		t.Errorf("expected total %+v but got %+v", total, m.UpdateSize)
+	}
+	if c := x.Complexity(); c != total.Complexity {
+		t.Errorf("expected complexity %d but got %d", total.Complexity, c)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("update", "metrics", m)
+	const wantLog = "metrics.fields=6 metrics.depth=3 metrics.values=4 metrics.max_len=2 metrics.complexity=14 metrics.paths.rp_sub.fields=4"
+	if !strings.Contains(buf.String(), wantLog) {
+		t.Errorf("expected log to contain %q but got %q", wantLog, buf.String())
+	}
+}
+
+func TestUpdateExpr_Metrics_Nil(t *testing.T) {
+	var x *UpdateExpr
+	if m := x.Metrics(); m.UpdateSize != (UpdateSize{}) || len(m.Paths) != 0 {
+		t.Errorf("expected empty metrics but got %+v", m)
+	}
+}
@@ -15,16 +15,24 @@
 package expr
 
 import (
-	"bytes"
 	"encoding/gob"
+	"encoding/hex"
+	"math/big"
 	"sync"
+	"time"
 
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func init() {
 	gob.Register(new(ValueExpr))
+	// The standard values of the ValueExpr, which are not the gob predeclared types, need to be registered,
+	// so that the expressions holding them could be encoded.
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register(protoreflect.EnumNumber(0))
+	gob.Register([16]byte{})
+	gob.Register(new(big.Rat))
 }
 
 var valueExprPool = &sync.Pool{
@@ -38,7 +46,9 @@ var valueExprPool = &sync.Pool{
 // AcquireValueExpr acquires a ValueExpr from the pool.
 // Once acquired it must be released via Free method.
 func AcquireValueExpr() *ValueExpr {
-	return valueExprPool.Get().(*ValueExpr)
+	x := valueExprPool.Get().(*ValueExpr)
+	trackAcquire(x)
+	return x
 }
 
 // Free puts the ValueExpr back to the pool.
@@ -46,7 +56,10 @@ func (x *ValueExpr) Free() {
 	if x == nil || !x.isAcquired {
 		return
 	}
+	trackFree(x)
 	x.Value = nil
+	x.NullKind = 0
+	x.DecodedValue = nil
 	valueExprPool.Put(x)
 }
 
@@ -67,15 +80,28 @@ var (
 // - []byte
 // - time.Time
 // - time.Duration
+// - *big.Rat - google.type.Decimal value
+// - [16]byte - UUID value of the string fields
+// - *fieldmaskpb.FieldMask - google.protobuf.FieldMask value
 // - protoreflect.EnumNumber -- enum value
 // - protoreflect.Message - message value (dynamicpb.Message for dynamic structs)
 // - structpb.Value
 // - nil - used for nullable fields
+// The values of the well-known wrapper fields, i.e. google.protobuf.Int64Value, are of the wrapped type.
 // This can be extended by custom types.
 type ValueExpr struct {
 	// Value is the value of the expression.
 	Value any
 
+	// NullKind is the declared kind of the null value, i.e. of a nullable function call argument.
+	// It is set only if the Value is nil and its kind is known.
+	NullKind protoreflect.Kind
+
+	// DecodedValue is the structured form of the Value, decoded while parsing, if any,
+	// i.e. the resourcename.Name of the resource name string fields.
+	// It is derived from the Value, thus it is not compared by the Equals method.
+	DecodedValue any
+
 	isAcquired bool
 }
 
@@ -86,25 +112,9 @@ func (x *ValueExpr) Clone() Expr {
 	}
 	clone := AcquireValueExpr()
 
-	switch vt := x.Value.(type) {
-	case protoreflect.Message:
-		clone.Value = proto.Clone(vt.Interface())
-	case proto.Message:
-		clone.Value = proto.Clone(vt)
-	case map[string]any:
-		mp := make(map[string]any, len(vt))
-		for k, v := range vt {
-			mp[k] = v
-		}
-		clone.Value = mp
-	case []byte:
-		cp := make([]byte, len(vt))
-		copy(cp, vt)
-	default:
-		clone.Value = x.Value
-	}
-
-	clone.Value = x.Value
+	clone.Value = cloneValue(x.Value)
+	clone.NullKind = x.NullKind
+	clone.DecodedValue = x.DecodedValue
 	return clone
 }
 
@@ -119,46 +129,10 @@ func (x *ValueExpr) Equals(other Expr) bool {
 		return false
 	}
 
-	switch vt := x.Value.(type) {
-	case protoreflect.Message:
-		ovt, ok := ov.Value.(protoreflect.Message)
-		if !ok {
-			return false
-		}
-		return proto.Equal(vt.Interface(), ovt.Interface())
-	case proto.Message:
-		ovt, ok := ov.Value.(proto.Message)
-		if !ok {
-			return false
-		}
-		return proto.Equal(vt, ovt)
-	case map[string]any:
-		ovt, ok := ov.Value.(map[string]any)
-		if !ok {
-			return false
-		}
-		if len(vt) != len(ovt) {
-			return false
-		}
-		for k, v := range vt {
-			ovv, ok := ovt[k]
-			if !ok {
-				return false
-			}
-			if v != ovv {
-				return false
-			}
-		}
-		return true
-	case []byte:
-		ovt, ok := ov.Value.([]byte)
-		if !ok {
-			return false
-		}
-		return bytes.Equal(vt, ovt)
-	default:
-		return x.Value == ov.Value
+	if x.Value == nil {
+		return ov.Value == nil && x.NullKind == ov.NullKind
 	}
+	return equalValue(x.Value, ov.Value)
 }
 
 // Complexity of the ValueExpr is 1.
@@ -168,3 +142,19 @@ func (x *ValueExpr) Complexity() int64 {
 
 func (*ValueExpr) isFilterExpr()      {}
 func (*ValueExpr) isUpdateValueExpr() {}
+
+// FormatUUID returns the canonical lower case form of the UUID value,
+// i.e. '123e4567-e89b-12d3-a456-426614174000'.
+func FormatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestValueExpr_NullKindEquals(t *testing.T) {
+	a, b := AcquireValueExpr(), AcquireValueExpr()
+	defer a.Free()
+	defer b.Free()
+
+	a.NullKind = protoreflect.StringKind
+	if a.Equals(b) {
+		t.Errorf("expected nulls of different kinds not to be equal")
+	}
+	b.NullKind = protoreflect.StringKind
+	if !a.Equals(b) {
+		t.Errorf("expected nulls of the same kind to be equal")
+	}
+	if c := a.Clone().(*ValueExpr); c.NullKind != protoreflect.StringKind {
+		t.Errorf("expected clone to keep the null kind")
+	}
+}
@@ -28,7 +28,9 @@ var wildcardExprPool = &sync.Pool{
 
 // AcquireWildcardExpr acquires a wildcard expression from the pool.
 func AcquireWildcardExpr() *WildcardExpr {
-	return wildcardExprPool.Get().(*WildcardExpr)
+	x := wildcardExprPool.Get().(*WildcardExpr)
+	trackAcquire(x)
+	return x
 }
 
 // WildcardExpr is a wildcard expression.
@@ -39,14 +41,18 @@ type WildcardExpr struct {
 
 // Free frees the wildcard expression.
 func (e *WildcardExpr) Free() {
+	if e == nil {
+		return
+	}
 	if e.isAcquired {
+		trackFree(e)
 		wildcardExprPool.Put(e)
 	}
 }
 
 // Equals returns true if the wildcard expression is equal to the other expression.
 func (e *WildcardExpr) Equals(other Expr) bool {
-	if other == nil {
+	if e == nil || other == nil {
 		return false
 	}
 	_, ok := other.(*WildcardExpr)
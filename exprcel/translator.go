@@ -0,0 +1,637 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprcel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+var (
+	// ErrUnsupported is an error returned when the expression cannot be translated into a CEL expression.
+	ErrUnsupported = errors.New("unsupported expression")
+
+	// ErrInvalidField is an error returned when the field selector doesn't match the message descriptor.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// Capability describes the expressions supported by the Translator,
+// so that the filters could be checked before being translated into a CEL expression.
+var Capability = expr.Capability{
+	Comparators:      []expr.Comparator{expr.EQ, expr.NE, expr.LT, expr.LE, expr.GT, expr.GE, expr.HAS, expr.IN},
+	StringSearch:     true,
+	RegexMatch:       true,
+	Presence:         true,
+	FieldComparisons: true,
+	AnyElement:       true,
+	AllElements:      true,
+	Arithmetic:       true,
+	CaseInsensitive:  true,
+	Functions:        []string{"size"},
+}
+
+// Option is an option of the Translator.
+type Option func(*Translator) error
+
+// VariableOpt is an option that sets the name of the CEL variable holding the filtered message,
+// i.e. with VariableOpt("resource") the `name = "foo"` filter is translated into `resource.name == "foo"`.
+// By default, the fields are referenced directly, as if they were declared as the CEL variables.
+func VariableOpt(name string) Option {
+	return func(t *Translator) error {
+		if name == "" {
+			return errors.New("variable name is empty")
+		}
+		t.variable = name
+		return nil
+	}
+}
+
+// Translator translates the filter expressions of given message into the CEL expressions.
+// The mapping of the expressions is:
+//   - AndExpr, SequenceExpr, OrExpr and NotExpr into the &&, || and ! operators,
+//   - EQ, NE, LT, LE, GT and GE comparisons into the ==, !=, <, <=, > and >= operators,
+//   - IN comparison and HAS comparison of a repeated field into the `in` operator,
+//   - HAS comparison of a map field into the `in` operator of the map key, and map key selectors into the index operator,
+//   - StringSearchExpr into the startsWith, endsWith and contains functions,
+//   - RegexMatchExpr into the matches function,
+//   - PresenceExpr into the has macro, or the size function of the repeated and map fields,
+//   - SearchExpr into the translation of its equivalent expression,
+//   - AnyElementExpr into the exists macro, or the all macro of its universal quantifier,
+//   - size function call of a repeated or map field into the size function,
+//   - BinaryExpr into the +, -, * and / operators, except for the multiplication and division of the durations,
+//   - case-insensitive string comparisons into the matches function with the (?i) flag.
+//
+// The time.Time and time.Duration values are rendered with the timestamp and duration functions.
+// The other function calls and message values are not supported.
+// A Translator is safe for concurrent use.
+type Translator struct {
+	msg      protoreflect.MessageDescriptor
+	variable string
+}
+
+// NewTranslator creates a new translator for the filters of the msg message.
+func NewTranslator(msg protoreflect.MessageDescriptor, opts ...Option) (*Translator, error) {
+	if msg == nil {
+		return nil, errors.New("message descriptor is not set")
+	}
+	t := Translator{msg: msg}
+	for _, opt := range opts {
+		if err := opt(&t); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// Translate translates the filter expression into the CEL expression source.
+// A nil expression or the expr.MatchAllExpr results in the `true` expression.
+// The result could be compiled into the cel-go Ast with the Compile method of the CEL environment.
+func (t *Translator) Translate(x expr.FilterExpr) (string, error) {
+	if x == nil {
+		return "true", nil
+	}
+	var sb strings.Builder
+	if err := t.write(&sb, t.msg, t.variable, 0, x); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (t *Translator) write(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, depth int, x expr.FilterExpr) error {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		return t.writeLogical(sb, md, scope, depth, " && ", xt.Expr)
+	case *expr.SequenceExpr:
+		return t.writeLogical(sb, md, scope, depth, " && ", xt.Factors)
+	case *expr.OrExpr:
+		return t.writeLogical(sb, md, scope, depth, " || ", xt.Expr)
+	case *expr.NotExpr:
+		sb.WriteString("!(")
+		if err := t.write(sb, md, scope, depth, xt.Expr); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+		return nil
+	case *expr.CompositeExpr:
+		return t.write(sb, md, scope, depth, xt.Expr)
+	case *expr.MatchAllExpr:
+		sb.WriteString("true")
+		return nil
+	case *expr.CompareExpr:
+		return t.writeCompare(sb, md, scope, depth, xt)
+	case *expr.SearchExpr:
+		return t.write(sb, md, scope, depth, xt.Expr)
+	case *expr.RegexMatchExpr:
+		left, ok := xt.Left.(*expr.FieldSelectorExpr)
+		if !ok {
+			return fmt.Errorf("%w: left hand side %T", ErrUnsupported, xt.Left)
+		}
+		path, _, _, err := selectorPath(md, scope, left)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(path)
+		sb.WriteString(".matches(")
+		if err = writeValue(sb, xt.Pattern); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+		return nil
+	case *expr.PresenceExpr:
+		return t.writePresence(sb, md, scope, xt)
+	}
+	return fmt.Errorf("%w: %T", ErrUnsupported, x)
+}
+
+// writePresence writes the presence test of a field.
+// The repeated and map fields are present if they are not empty, other fields are tested with the has macro,
+// which requires the field to be selected from a message, i.e. with the VariableOpt.
+func (t *Translator) writePresence(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, x *expr.PresenceExpr) error {
+	field, ok := x.Field.(*expr.FieldSelectorExpr)
+	if !ok {
+		return fmt.Errorf("%w: presence of %T", ErrUnsupported, x.Field)
+	}
+	path, fd, _, err := selectorPath(md, scope, field)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fd.IsList() || fd.IsMap():
+		sb.WriteString("size(")
+		sb.WriteString(path)
+		sb.WriteString(") > 0")
+		return nil
+	case !strings.Contains(path, "."):
+		return fmt.Errorf("%w: presence of the top level field %q without the variable", ErrUnsupported, path)
+	}
+	sb.WriteString("has(")
+	sb.WriteString(path)
+	sb.WriteByte(')')
+	return nil
+}
+
+func (t *Translator) writeLogical(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, depth int, op string, xs []expr.FilterExpr) error {
+	sb.WriteByte('(')
+	for i, x := range xs {
+		if i > 0 {
+			sb.WriteString(op)
+		}
+		if err := t.write(sb, md, scope, depth, x); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte(')')
+	return nil
+}
+
+var comparisonOperators = [...]string{
+	expr.EQ: "==",
+	expr.LE: "<=",
+	expr.LT: "<",
+	expr.GE: ">=",
+	expr.GT: ">",
+	expr.NE: "!=",
+}
+
+// writeCaseInsensitiveCompare writes the case-insensitive comparison of the string values with the matches function,
+// and the (?i) flag of the anchored regular expression.
+func writeCaseInsensitiveCompare(sb *strings.Builder, path string, fd protoreflect.FieldDescriptor, isMapKey bool, depth int, x *expr.CompareExpr) error {
+	var pattern string
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		s, ok := rt.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: case-insensitive comparison of %T", ErrUnsupported, rt.Value)
+		}
+		pattern = "^" + regexp.QuoteMeta(s) + "$"
+	case *expr.ArrayExpr:
+		alts := make([]string, 0, len(rt.Elements))
+		for _, e := range rt.Elements {
+			ve, ok := e.(*expr.ValueExpr)
+			if !ok {
+				return fmt.Errorf("%w: array element %T", ErrUnsupported, e)
+			}
+			s, ok := ve.Value.(string)
+			if !ok {
+				return fmt.Errorf("%w: case-insensitive comparison of %T", ErrUnsupported, ve.Value)
+			}
+			alts = append(alts, regexp.QuoteMeta(s))
+		}
+		pattern = "^(?:" + strings.Join(alts, "|") + ")$"
+	case *expr.StringSearchExpr:
+		pattern = regexp.QuoteMeta(rt.Value)
+		if !rt.PrefixWildcard {
+			pattern = "^" + pattern
+		}
+		if !rt.SuffixWildcard {
+			pattern += "$"
+		}
+	default:
+		return fmt.Errorf("%w: case-insensitive comparison of %T", ErrUnsupported, x.Right)
+	}
+	match := ".matches(" + strconv.Quote("(?i)"+pattern) + ")"
+
+	switch x.Comparator {
+	case expr.EQ, expr.IN:
+	case expr.HAS:
+		if fd.IsList() || fd.IsMap() && !isMapKey {
+			// Any element or map key matches.
+			elem := "e" + strconv.Itoa(depth)
+			sb.WriteString(path)
+			sb.WriteString(".exists(")
+			sb.WriteString(elem)
+			sb.WriteString(", ")
+			sb.WriteString(elem)
+			sb.WriteString(match)
+			sb.WriteByte(')')
+			return nil
+		}
+	case expr.NE:
+		sb.WriteByte('!')
+	default:
+		return fmt.Errorf("%w: case-insensitive %s comparison", ErrUnsupported, x.Comparator)
+	}
+	sb.WriteString(path)
+	sb.WriteString(match)
+	return nil
+}
+
+// writeSizeCompare writes the comparison of the size function call of a repeated or map field, i.e.: size(items) > 2.
+func writeSizeCompare(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, fc *expr.FunctionCallExpr, x *expr.CompareExpr) error {
+	if fc.PkgName != "" || fc.Name != "size" || len(fc.Arguments) != 1 {
+		return fmt.Errorf("%w: function %s", ErrUnsupported, fc.Name)
+	}
+	fs, ok := fc.Arguments[0].(*expr.FieldSelectorExpr)
+	if !ok {
+		return fmt.Errorf("%w: size argument %T", ErrUnsupported, fc.Arguments[0])
+	}
+	path, fd, isMapKey, err := selectorPath(md, scope, fs)
+	if err != nil {
+		return err
+	}
+	if !(fd.IsList() || fd.IsMap()) || isMapKey {
+		return fmt.Errorf("%w: size of the field %q", ErrUnsupported, fd.Name())
+	}
+	ve, ok := x.Right.(*expr.ValueExpr)
+	if !ok {
+		return fmt.Errorf("%w: size compared with %T", ErrUnsupported, x.Right)
+	}
+	op, err := comparisonOperator(x.Comparator)
+	if err != nil {
+		return err
+	}
+	sb.WriteString("size(" + path + ") " + op + " ")
+	return writeValue(sb, ve.Value)
+}
+
+func comparisonOperator(c expr.Comparator) (string, error) {
+	if int(c) < len(comparisonOperators) && comparisonOperators[c] != "" {
+		return comparisonOperators[c], nil
+	}
+	return "", fmt.Errorf("%w: comparator %s", ErrUnsupported, c)
+}
+
+func (t *Translator) writeCompare(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, depth int, x *expr.CompareExpr) error {
+	if fc, ok := x.Left.(*expr.FunctionCallExpr); ok {
+		return writeSizeCompare(sb, md, scope, fc, x)
+	}
+	left, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok {
+		return fmt.Errorf("%w: left hand side %T", ErrUnsupported, x.Left)
+	}
+	path, fd, isMapKey, err := selectorPath(md, scope, left)
+	if err != nil {
+		return err
+	}
+
+	if x.CaseInsensitive {
+		return writeCaseInsensitiveCompare(sb, path, fd, isMapKey, depth, x)
+	}
+
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		if x.Comparator == expr.HAS && (fd.IsList() || fd.IsMap() && !isMapKey) {
+			// The repeated field element or the map key presence, i.e.: labels:"key".
+			if err = writeValue(sb, rt.Value); err != nil {
+				return err
+			}
+			sb.WriteString(" in ")
+			sb.WriteString(path)
+			return nil
+		}
+		op := "=="
+		if x.Comparator != expr.HAS {
+			if op, err = comparisonOperator(x.Comparator); err != nil {
+				return err
+			}
+		}
+		sb.WriteString(path)
+		sb.WriteString(" " + op + " ")
+		return writeValue(sb, rt.Value)
+	case *expr.ArrayExpr:
+		if x.Comparator != expr.IN {
+			return fmt.Errorf("%w: array with the %s comparator", ErrUnsupported, x.Comparator)
+		}
+		sb.WriteString(path)
+		sb.WriteString(" in [")
+		for i, e := range rt.Elements {
+			ve, ok := e.(*expr.ValueExpr)
+			if !ok {
+				return fmt.Errorf("%w: array element %T", ErrUnsupported, e)
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err = writeValue(sb, ve.Value); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+		return nil
+	case *expr.StringSearchExpr:
+		switch x.Comparator {
+		case expr.EQ, expr.HAS:
+		case expr.NE:
+			sb.WriteByte('!')
+		default:
+			return fmt.Errorf("%w: string search with the %s comparator", ErrUnsupported, x.Comparator)
+		}
+		sb.WriteString(path)
+		switch {
+		case rt.PrefixWildcard && rt.SuffixWildcard:
+			sb.WriteString(".contains(")
+		case rt.PrefixWildcard:
+			sb.WriteString(".endsWith(")
+		case rt.SuffixWildcard:
+			sb.WriteString(".startsWith(")
+		default:
+			sb.WriteString(" == ")
+			sb.WriteString(strconv.Quote(rt.Value))
+			return nil
+		}
+		sb.WriteString(strconv.Quote(rt.Value))
+		sb.WriteByte(')')
+		return nil
+	case *expr.AnyElementExpr:
+		if rt.All && rt.Filter == nil {
+			sb.WriteString("true")
+			return nil
+		}
+		if rt.Filter == nil {
+			sb.WriteString("size(")
+			sb.WriteString(path)
+			sb.WriteString(") > 0")
+			return nil
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			return fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+		}
+		// Each nesting level declares its own iteration variable, so that the outer elements stay accessible.
+		elem := "e" + strconv.Itoa(depth)
+		sb.WriteString(path)
+		if rt.All {
+			sb.WriteString(".all(")
+		} else {
+			sb.WriteString(".exists(")
+		}
+		sb.WriteString(elem)
+		sb.WriteString(", ")
+		if err = t.write(sb, fd.Message(), elem, depth+1, rt.Filter); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+		return nil
+	case *expr.FieldSelectorExpr:
+		op, err := comparisonOperator(x.Comparator)
+		if err != nil {
+			return err
+		}
+		rpath, _, _, err := selectorPath(md, scope, rt)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(path + " " + op + " " + rpath)
+		return nil
+	case *expr.BinaryExpr:
+		op, err := comparisonOperator(x.Comparator)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(path + " " + op + " ")
+		return writeArithmetic(sb, md, scope, fd, rt)
+	}
+	return fmt.Errorf("%w: right hand side %T", ErrUnsupported, x.Right)
+}
+
+// writeArithmetic writes the arithmetic expression x, resulting in a value of the field fd.
+// The operands are surrounded by the parentheses only if the precedence of the operators requires it.
+func writeArithmetic(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, fd protoreflect.FieldDescriptor, x *expr.BinaryExpr) error {
+	if (x.Op == expr.MUL || x.Op == expr.DIV) && fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == "google.protobuf.Duration" {
+		// CEL doesn't define the multiplication and division of the durations.
+		return fmt.Errorf("%w: operator %s of the duration", ErrUnsupported, x.Op)
+	}
+	if err := writeOperand(sb, md, scope, fd, x.Left, func(p int) bool { return p < x.Op.Precedence() }); err != nil {
+		return err
+	}
+	sb.WriteString(" " + x.Op.String() + " ")
+	return writeOperand(sb, md, scope, fd, x.Right, func(p int) bool { return p <= x.Op.Precedence() })
+}
+
+// writeOperand writes the operand of the arithmetic expression, the nested arithmetic expression
+// is surrounded by the parentheses if its operator precedence satisfies the group function.
+func writeOperand(sb *strings.Builder, md protoreflect.MessageDescriptor, scope string, fd protoreflect.FieldDescriptor, x expr.FilterExpr, group func(int) bool) error {
+	switch ot := x.(type) {
+	case *expr.ValueExpr:
+		return writeValue(sb, ot.Value)
+	case *expr.FieldSelectorExpr:
+		path, _, _, err := selectorPath(md, scope, ot)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(path)
+		return nil
+	case *expr.BinaryExpr:
+		if !group(ot.Op.Precedence()) {
+			return writeArithmetic(sb, md, scope, fd, ot)
+		}
+		sb.WriteByte('(')
+		if err := writeArithmetic(sb, md, scope, fd, ot); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+		return nil
+	}
+	return fmt.Errorf("%w: arithmetic operand %T", ErrUnsupported, x)
+}
+
+// selectorPath resolves the CEL selection path of the field selector, relative to the scope variable.
+// It returns the descriptor of the last selected field, and whether the path ends with a map key.
+func selectorPath(md protoreflect.MessageDescriptor, scope string, fs *expr.FieldSelectorExpr) (string, protoreflect.FieldDescriptor, bool, error) {
+	var (
+		sb       strings.Builder
+		fd       protoreflect.FieldDescriptor
+		isMapKey bool
+	)
+	sb.WriteString(scope)
+	for cur := expr.Expr(fs); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if fd != nil {
+				switch {
+				case isMapKey:
+					md = fd.MapValue().Message()
+				case fd.Kind() == protoreflect.MessageKind:
+					md = fd.Message()
+				default:
+					return "", nil, false, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+				}
+			}
+			if md == nil {
+				return "", nil, false, fmt.Errorf("%w: %q is not a message field", ErrInvalidField, ct.Field)
+			}
+			fd = md.Fields().ByName(ct.Field)
+			if fd == nil {
+				return "", nil, false, fmt.Errorf("%w: field %q not found in the message %s", ErrInvalidField, ct.Field, md.FullName())
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			isMapKey = false
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			kv, ok := ct.Key.(*expr.ValueExpr)
+			if !ok {
+				return "", nil, false, fmt.Errorf("%w: map key %T", ErrUnsupported, ct.Key)
+			}
+			sb.WriteByte('[')
+			if err := writeValue(&sb, kv.Value); err != nil {
+				return "", nil, false, err
+			}
+			sb.WriteByte(']')
+			isMapKey = true
+			cur = ct.Traversal
+		default:
+			return "", nil, false, fmt.Errorf("%w: field traversal %T", ErrUnsupported, cur)
+		}
+	}
+	return sb.String(), fd, isMapKey, nil
+}
+
+// writeValue writes the expression value as the CEL literal.
+func writeValue(sb *strings.Builder, v any) error {
+	switch vt := v.(type) {
+	case nil:
+		sb.WriteString("null")
+	case bool:
+		sb.WriteString(strconv.FormatBool(vt))
+	case string:
+		sb.WriteString(strconv.Quote(vt))
+	case []byte:
+		sb.WriteByte('b')
+		sb.WriteString(strconv.Quote(string(vt)))
+	case [16]byte:
+		sb.WriteString(strconv.Quote(expr.FormatUUID(vt)))
+	case int64:
+		sb.WriteString(strconv.FormatInt(vt, 10))
+	case int32:
+		sb.WriteString(strconv.FormatInt(int64(vt), 10))
+	case uint64:
+		sb.WriteString(strconv.FormatUint(vt, 10))
+		sb.WriteByte('u')
+	case uint32:
+		sb.WriteString(strconv.FormatUint(uint64(vt), 10))
+		sb.WriteByte('u')
+	case float64:
+		writeDouble(sb, vt)
+	case float32:
+		writeDouble(sb, float64(vt))
+	case protoreflect.EnumNumber:
+		sb.WriteString(strconv.FormatInt(int64(vt), 10))
+	case time.Time:
+		sb.WriteString("timestamp(")
+		sb.WriteString(strconv.Quote(vt.UTC().Format(time.RFC3339Nano)))
+		sb.WriteByte(')')
+	case time.Duration:
+		sb.WriteString("duration(")
+		sb.WriteString(strconv.Quote(vt.String()))
+		sb.WriteByte(')')
+	case *structpb.Value:
+		return writeValue(sb, vt.AsInterface())
+	case []any:
+		sb.WriteByte('[')
+		for i, e := range vt {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := writeValue(sb, e); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(vt))
+		for k := range vt {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strconv.Quote(k))
+			sb.WriteString(": ")
+			if err := writeValue(sb, vt[k]); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	case protoreflect.Message:
+		return fmt.Errorf("%w: message value %s", ErrUnsupported, vt.Descriptor().FullName())
+	default:
+		return fmt.Errorf("%w: value of type %T", ErrUnsupported, v)
+	}
+	return nil
+}
+
+// writeDouble writes the float value as the CEL double literal, which needs to be distinguishable from an int.
+func writeDouble(sb *strings.Builder, f float64) {
+	switch {
+	case math.IsNaN(f):
+		sb.WriteString(`double("NaN")`)
+	case math.IsInf(f, 1):
+		sb.WriteString(`double("Infinity")`)
+	case math.IsInf(f, -1):
+		sb.WriteString(`double("-Infinity")`)
+	default:
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		sb.WriteString(s)
+		if !strings.ContainsAny(s, ".e") {
+			sb.WriteString(".0")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprcel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestTranslator_Translate(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.RegexMatchOpt(), filtering.CollectionFunctionsOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		opts   []Option
+		want   string
+		err    error
+	}{
+		{name: "empty", filter: ``, want: `true`},
+		{name: "equal", filter: `i32 = 1`, want: `i32 == 1`},
+		{name: "regex match", filter: `sub.name =~ "^fo+$"`, want: `sub.name.matches("^fo+$")`},
+		{name: "unsigned", filter: `u64 > 1`, want: `u64 > 1u`},
+		{name: "double", filter: `double <= 2`, want: `double <= 2.0`},
+		{name: "bytes", filter: `bytes = "Zm9v"`, want: `bytes == b"foo"`},
+		{name: "nested", filter: `sub.name != "foo"`, want: `sub.name != "foo"`},
+		{
+			name:   "and or not",
+			filter: `str = "a" AND (i32 < 1 OR NOT bool = true)`,
+			want:   `(str == "a" && (i32 < 1 || !(bool == true)))`,
+		},
+		{name: "in", filter: `str IN ["a", "b"]`, want: `str in ["a", "b"]`},
+		{name: "repeated has", filter: `rp_str:"foo"`, want: `"foo" in rp_str`},
+		{name: "map key presence", filter: `map_str_i32:"key"`, want: `"key" in map_str_i32`},
+		{name: "prefix search", filter: `str = "foo*"`, want: `str.startsWith("foo")`},
+		{name: "suffix search", filter: `str = "*foo"`, want: `str.endsWith("foo")`},
+		{name: "contains search", filter: `NOT str = "*foo*"`, want: `!(str.contains("foo"))`},
+		{
+			name:   "timestamp",
+			filter: `timestamp > 2023-01-02T03:04:05Z`,
+			want:   `timestamp > timestamp("2023-01-02T03:04:05Z")`,
+		},
+		{name: "duration", filter: `duration < 1.5s`, want: `duration < duration("1.5s")`},
+		{name: "field to field", filter: `i32 = i64`, want: `i32 == i64`},
+		{name: "arithmetic", filter: `i64 > i32 + 2 * i64`, want: `i64 > i32 + 2 * i64`},
+		{name: "timestamp arithmetic", filter: `timestamp < timestamp_optional + 24h`, want: `timestamp < timestamp_optional + duration("24h0m0s")`},
+		{name: "duration multiplication", filter: `duration > duration_optional * 2`, err: ErrUnsupported},
+		{
+			name:   "any element",
+			filter: `rp_sub:{name: "foo", i32: 1}`,
+			want:   `rp_sub.exists(e0, (e0.name == "foo" && e0.i32 == 1))`,
+		},
+		{name: "any", filter: `any(rp_sub, i32 > 1)`, want: `rp_sub.exists(e0, e0.i32 > 1)`},
+		{name: "all", filter: `all(rp_sub, name = "foo")`, want: `rp_sub.all(e0, e0.name == "foo")`},
+		{name: "size", filter: `size(rp_str) >= 2`, want: `size(rp_str) >= 2`},
+		{name: "map size", filter: `size(map_str_i32) = 0`, want: `size(map_str_i32) == 0`},
+		{
+			name:   "variable",
+			filter: `sub.name = "foo"`,
+			opts:   []Option{VariableOpt("resource")},
+			want:   `resource.sub.name == "foo"`,
+		},
+		{name: "message value", filter: `sub = testpb.Message{name: "foo"}`, err: ErrUnsupported},
+		{name: "nested presence", filter: `sub.sub:*`, want: `has(sub.sub)`},
+		{name: "repeated presence", filter: `rp_str:*`, want: `size(rp_str) > 0`},
+		{name: "variable presence", filter: `str_optional:*`, opts: []Option{VariableOpt("resource")}, want: `has(resource.str_optional)`},
+		{name: "top level presence", filter: `sub:*`, err: ErrUnsupported},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			if x != nil {
+				defer x.Free()
+			}
+
+			tr, err := NewTranslator(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create translator: %v", err)
+			}
+
+			got, err := tr.Translate(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslator_CaseInsensitive(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.CaseInsensitiveOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   string
+		err    error
+	}{
+		{name: "equal", filter: `str = "F.o"`, want: `str.matches("(?i)^F\\.o$")`},
+		{name: "not equal", filter: `str != "foo"`, want: `!str.matches("(?i)^foo$")`},
+		{name: "in", filter: `str IN ["a", "b"]`, want: `str.matches("(?i)^(?:a|b)$")`},
+		{name: "repeated has", filter: `rp_str:"Foo"`, want: `rp_str.exists(e0, e0.matches("(?i)^Foo$"))`},
+		{name: "map key presence", filter: `map_str_i32:"Key"`, want: `map_str_i32.exists(e0, e0.matches("(?i)^Key$"))`},
+		{name: "suffix search", filter: `str = "*foo"`, want: `str.matches("(?i)foo$")`},
+		{name: "non string", filter: `i32 = 1`, want: `i32 == 1`},
+		{name: "less than", filter: `str < "foo"`, err: ErrUnsupported},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := tr.Translate(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslator_Search(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.SearchableFieldsOpt("name"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.ParseWithSearch(`i32 = 1`, `Foo`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.Translate(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `(i32 == 1 && name.matches("(?i)Foo"))`; got != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
+
+func TestTranslator_MatchAll(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.MatchAllOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.Parse(``)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.Translate(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != `true` {
+		t.Errorf("expected true but got %s", got)
+	}
+}
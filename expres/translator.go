@@ -0,0 +1,475 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expres
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+var (
+	// ErrUnsupported is an error returned when the expression cannot be translated into a search query.
+	ErrUnsupported = errors.New("unsupported expression")
+
+	// ErrInvalidField is an error returned when the field selector doesn't match the message descriptor.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// Capability describes the expressions supported by the Translator,
+// so that the filters could be checked before being translated into a search query.
+var Capability = expr.Capability{
+	Comparators:     []expr.Comparator{expr.EQ, expr.NE, expr.LT, expr.LE, expr.GT, expr.GE, expr.HAS, expr.IN},
+	StringSearch:    true,
+	Presence:        true,
+	AnyElement:      true,
+	AllElements:     true,
+	CaseInsensitive: true,
+}
+
+// Query is a single query of the query DSL, i.e.: {"term": {"name": "foo"}}.
+type Query map[string]any
+
+// FieldPathFn is a function that maps the field selector path, i.e.: "sub.name", into the index field path.
+// It allows to rename the fields, which names in the index mapping differ from the proto field names.
+type FieldPathFn func(path string) (string, error)
+
+// Option is an option of the Translator.
+type Option func(*Translator) error
+
+// FieldPathOpt is an option that sets the field path mapping function of the translator.
+func FieldPathOpt(fn FieldPathFn) Option {
+	return func(t *Translator) error {
+		if fn == nil {
+			return errors.New("field path function is nil")
+		}
+		t.fieldPath = fn
+		return nil
+	}
+}
+
+// TextFieldsOpt is an option that marks the index paths of the analyzed text fields.
+// The prefix string search of a text field, i.e.: `title = "foo*"`, is translated into
+// the match_phrase_prefix query, instead of the wildcard query used for the keyword fields.
+func TextFieldsOpt(paths ...string) Option {
+	return func(t *Translator) error {
+		if t.textFields == nil {
+			t.textFields = make(map[string]struct{}, len(paths))
+		}
+		for _, p := range paths {
+			t.textFields[p] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// Translator translates the filter expressions of given message into the Elasticsearch bool queries.
+// The mapping of the expressions is:
+//   - AndExpr, OrExpr and NotExpr into the must, should and must_not clauses of the bool query,
+//   - SequenceExpr into the must clauses, boosted by the factor weights,
+//   - EQ and NE comparisons into the term query, and the null comparisons into the exists query,
+//   - LT, LE, GT and GE comparisons into the range query,
+//   - IN comparison into the terms query,
+//   - HAS comparison of a repeated field into the term query, and of a map field into the key exists query,
+//   - StringSearchExpr into the wildcard or match_phrase_prefix query,
+//   - AnyElementExpr into the nested query, and its universal quantifier into the negated nested query of the negated filter,
+//   - PresenceExpr into the exists query, which doesn't match the null values and empty arrays,
+//   - SearchExpr into the multi_match phrase query of each term over the searchable fields.
+//
+// The RegexMatchExpr is not supported, as the regexp query uses the anchored Lucene syntax, instead of the RE2.
+// The case-insensitive string comparisons set the case_insensitive parameter of the term and wildcard queries.
+// The time.Time values are formatted with the RFC 3339, and the enum values are represented by their numbers.
+// The comparisons of two fields, function calls and message values are not supported.
+// A Translator is safe for concurrent use.
+type Translator struct {
+	msg        protoreflect.MessageDescriptor
+	fieldPath  FieldPathFn
+	textFields map[string]struct{}
+}
+
+// NewTranslator creates a new translator for the filters of the msg message.
+func NewTranslator(msg protoreflect.MessageDescriptor, opts ...Option) (*Translator, error) {
+	if msg == nil {
+		return nil, errors.New("message descriptor is not set")
+	}
+	t := Translator{msg: msg}
+	for _, opt := range opts {
+		if err := opt(&t); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// Translate translates the filter expression into the query.
+// A nil expression or the expr.MatchAllExpr results in the match_all query.
+func (t *Translator) Translate(x expr.FilterExpr) (Query, error) {
+	if x == nil {
+		return matchAllQuery(), nil
+	}
+	return t.translate(t.msg, "", x)
+}
+
+// TranslateJSON translates the filter expression into the JSON encoded query.
+func (t *Translator) TranslateJSON(x expr.FilterExpr) ([]byte, error) {
+	q, err := t.Translate(x)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(q)
+}
+
+// termQuery returns the term query of the value, which for the case-insensitive string values
+// sets the case_insensitive parameter.
+func termQuery(path string, v any, caseInsensitive bool) Query {
+	if _, ok := v.(string); ok && caseInsensitive {
+		return Query{"term": map[string]any{path: map[string]any{"value": v, "case_insensitive": true}}}
+	}
+	return Query{"term": map[string]any{path: v}}
+}
+
+func matchAllQuery() Query {
+	return Query{"match_all": map[string]any{}}
+}
+
+func boolQuery(clause string, qs ...any) Query {
+	return Query{"bool": map[string]any{clause: qs}}
+}
+
+func (t *Translator) translate(md protoreflect.MessageDescriptor, prefix string, x expr.FilterExpr) (Query, error) {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		return t.translateBool(md, prefix, "must", xt.Expr)
+	case *expr.SequenceExpr:
+		return t.translateSequence(md, prefix, xt)
+	case *expr.OrExpr:
+		q, err := t.translateBool(md, prefix, "should", xt.Expr)
+		if err != nil {
+			return nil, err
+		}
+		q["bool"].(map[string]any)["minimum_should_match"] = 1
+		return q, nil
+	case *expr.NotExpr:
+		inner, err := t.translate(md, prefix, xt.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return boolQuery("must_not", inner), nil
+	case *expr.CompositeExpr:
+		return t.translate(md, prefix, xt.Expr)
+	case *expr.MatchAllExpr:
+		return matchAllQuery(), nil
+	case *expr.CompareExpr:
+		return t.translateCompare(md, prefix, xt)
+	case *expr.SearchExpr:
+		return t.translateSearch(xt)
+	case *expr.PresenceExpr:
+		field, ok := xt.Field.(*expr.FieldSelectorExpr)
+		if !ok {
+			return nil, fmt.Errorf("%w: presence of %T", ErrUnsupported, xt.Field)
+		}
+		path, _, _, err := t.selectorPath(md, prefix, field)
+		if err != nil {
+			return nil, err
+		}
+		return Query{"exists": map[string]any{"field": path}}, nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupported, x)
+}
+
+// translateSearch translates the free-text search into the full-text multi_match queries,
+// instead of its equivalent wildcard expression.
+func (t *Translator) translateSearch(x *expr.SearchExpr) (Query, error) {
+	fields := make([]string, 0, len(x.Fields))
+	for _, path := range x.Fields {
+		if t.fieldPath != nil {
+			var err error
+			if path, err = t.fieldPath(path); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, path)
+	}
+
+	qs := make([]any, 0, len(x.Terms))
+	for _, term := range x.Terms {
+		qs = append(qs, Query{"multi_match": map[string]any{"query": term, "fields": fields, "type": "phrase"}})
+	}
+	if len(qs) == 1 {
+		return qs[0].(Query), nil
+	}
+	return boolQuery("must", qs...), nil
+}
+
+func (t *Translator) translateBool(md protoreflect.MessageDescriptor, prefix, clause string, xs []expr.FilterExpr) (Query, error) {
+	qs := make([]any, 0, len(xs))
+	for _, x := range xs {
+		q, err := t.translate(md, prefix, x)
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+	return boolQuery(clause, qs...), nil
+}
+
+// translateSequence translates the sequence into the must clauses, where the factors with a weight other than 1
+// are wrapped in the boosted bool queries, so that the relevance score honors the factor weights.
+func (t *Translator) translateSequence(md protoreflect.MessageDescriptor, prefix string, x *expr.SequenceExpr) (Query, error) {
+	qs := make([]any, 0, len(x.Factors))
+	for i, f := range x.Factors {
+		q, err := t.translate(md, prefix, f)
+		if err != nil {
+			return nil, err
+		}
+		if w := x.Weight(i); w != 1 {
+			q = Query{"bool": map[string]any{"must": []any{q}, "boost": w}}
+		}
+		qs = append(qs, q)
+	}
+	return boolQuery("must", qs...), nil
+}
+
+var rangeOperators = [...]string{
+	expr.LE: "lte",
+	expr.LT: "lt",
+	expr.GE: "gte",
+	expr.GT: "gt",
+}
+
+func (t *Translator) translateCompare(md protoreflect.MessageDescriptor, prefix string, x *expr.CompareExpr) (Query, error) {
+	left, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok {
+		return nil, fmt.Errorf("%w: left hand side %T", ErrUnsupported, x.Left)
+	}
+	path, fd, isMapKey, err := t.selectorPath(md, prefix, left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		v, err := queryValue(rt.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch x.Comparator {
+		case expr.HAS:
+			if fd.IsMap() && !isMapKey {
+				if x.CaseInsensitive {
+					return nil, fmt.Errorf("%w: case-insensitive map key presence", ErrUnsupported)
+				}
+				// The map key presence, i.e.: labels:"key".
+				return Query{"exists": map[string]any{"field": path + "." + fmt.Sprint(v)}}, nil
+			}
+			return termQuery(path, v, x.CaseInsensitive), nil
+		case expr.EQ:
+			if v == nil {
+				return boolQuery("must_not", Query{"exists": map[string]any{"field": path}}), nil
+			}
+			return termQuery(path, v, x.CaseInsensitive), nil
+		case expr.NE:
+			if v == nil {
+				return Query{"exists": map[string]any{"field": path}}, nil
+			}
+			return boolQuery("must_not", termQuery(path, v, x.CaseInsensitive)), nil
+		case expr.LT, expr.LE, expr.GT, expr.GE:
+			if _, ok := v.(string); ok && x.CaseInsensitive {
+				return nil, fmt.Errorf("%w: case-insensitive %s comparison", ErrUnsupported, x.Comparator)
+			}
+			return Query{"range": map[string]any{path: map[string]any{rangeOperators[x.Comparator]: v}}}, nil
+		}
+		return nil, fmt.Errorf("%w: comparator %s", ErrUnsupported, x.Comparator)
+	case *expr.ArrayExpr:
+		if x.Comparator != expr.IN {
+			return nil, fmt.Errorf("%w: array with the %s comparator", ErrUnsupported, x.Comparator)
+		}
+		vs := make([]any, 0, len(rt.Elements))
+		for _, e := range rt.Elements {
+			ve, ok := e.(*expr.ValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("%w: array element %T", ErrUnsupported, e)
+			}
+			v, err := queryValue(ve.Value)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+		if x.CaseInsensitive {
+			// The terms query cannot match regardless of the case, thus each value is matched by a term query.
+			qs := make([]any, len(vs))
+			for i, v := range vs {
+				qs[i] = termQuery(path, v, true)
+			}
+			q := boolQuery("should", qs...)
+			q["bool"].(map[string]any)["minimum_should_match"] = 1
+			return q, nil
+		}
+		return Query{"terms": map[string]any{path: vs}}, nil
+	case *expr.StringSearchExpr:
+		var q Query
+		if _, isText := t.textFields[path]; isText && rt.SuffixWildcard && !rt.PrefixWildcard {
+			q = Query{"match_phrase_prefix": map[string]any{path: rt.Value}}
+		} else {
+			pattern := escapeWildcard(rt.Value)
+			if rt.PrefixWildcard {
+				pattern = "*" + pattern
+			}
+			if rt.SuffixWildcard {
+				pattern += "*"
+			}
+			wq := map[string]any{"value": pattern}
+			if x.CaseInsensitive {
+				wq["case_insensitive"] = true
+			}
+			q = Query{"wildcard": map[string]any{path: wq}}
+		}
+		switch x.Comparator {
+		case expr.EQ, expr.HAS:
+			return q, nil
+		case expr.NE:
+			return boolQuery("must_not", q), nil
+		}
+		return nil, fmt.Errorf("%w: string search with the %s comparator", ErrUnsupported, x.Comparator)
+	case *expr.AnyElementExpr:
+		inner := Query{"match_all": map[string]any{}}
+		if rt.All && rt.Filter == nil {
+			return inner, nil
+		}
+		if rt.Filter != nil {
+			if fd.Kind() != protoreflect.MessageKind {
+				return nil, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+			}
+			// The nested query fields are the full paths, thus the element selectors are prefixed with the field path.
+			elemPrefix := pathOf(prefix, left)
+			if inner, err = t.translate(fd.Message(), elemPrefix, rt.Filter); err != nil {
+				return nil, err
+			}
+		}
+		if rt.All {
+			// None of the elements fails the filter.
+			return boolQuery("must_not", Query{"nested": map[string]any{"path": path, "query": boolQuery("must_not", inner)}}), nil
+		}
+		return Query{"nested": map[string]any{"path": path, "query": inner}}, nil
+	}
+	return nil, fmt.Errorf("%w: right hand side %T", ErrUnsupported, x.Right)
+}
+
+// pathOf returns the proto field path of the field selector, prefixed with the prefix.
+func pathOf(prefix string, fs *expr.FieldSelectorExpr) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for cur := expr.Expr(fs); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			if kv, ok := ct.Key.(*expr.ValueExpr); ok {
+				sb.WriteByte('.')
+				sb.WriteString(fmt.Sprint(kv.Value))
+			}
+			cur = ct.Traversal
+		default:
+			cur = nil
+		}
+	}
+	return sb.String()
+}
+
+// selectorPath resolves the index path of the field selector.
+// It returns the descriptor of the last selected field, and whether the path ends with a map key.
+func (t *Translator) selectorPath(md protoreflect.MessageDescriptor, prefix string, fs *expr.FieldSelectorExpr) (string, protoreflect.FieldDescriptor, bool, error) {
+	var (
+		fd       protoreflect.FieldDescriptor
+		isMapKey bool
+	)
+	for cur := expr.Expr(fs); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if fd != nil {
+				switch {
+				case isMapKey:
+					md = fd.MapValue().Message()
+				case fd.Kind() == protoreflect.MessageKind:
+					md = fd.Message()
+				default:
+					return "", nil, false, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+				}
+			}
+			if md == nil {
+				return "", nil, false, fmt.Errorf("%w: %q is not a message field", ErrInvalidField, ct.Field)
+			}
+			fd = md.Fields().ByName(ct.Field)
+			if fd == nil {
+				return "", nil, false, fmt.Errorf("%w: field %q not found in the message %s", ErrInvalidField, ct.Field, md.FullName())
+			}
+			isMapKey = false
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			if _, ok := ct.Key.(*expr.ValueExpr); !ok {
+				return "", nil, false, fmt.Errorf("%w: map key %T", ErrUnsupported, ct.Key)
+			}
+			isMapKey = true
+			cur = ct.Traversal
+		default:
+			return "", nil, false, fmt.Errorf("%w: field traversal %T", ErrUnsupported, cur)
+		}
+	}
+
+	path := pathOf(prefix, fs)
+	if t.fieldPath != nil {
+		var err error
+		if path, err = t.fieldPath(path); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return path, fd, isMapKey, nil
+}
+
+// queryValue converts the expression value into the query value.
+func queryValue(v any) (any, error) {
+	switch vt := v.(type) {
+	case protoreflect.EnumNumber:
+		return int32(vt), nil
+	case time.Time:
+		return vt.Format(time.RFC3339Nano), nil
+	case [16]byte:
+		return expr.FormatUUID(vt), nil
+	case *structpb.Value:
+		return vt.AsInterface(), nil
+	case protoreflect.Message:
+		return nil, fmt.Errorf("%w: message value %s", ErrUnsupported, vt.Descriptor().FullName())
+	}
+	return v, nil
+}
+
+var wildcardReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+
+// escapeWildcard escapes the special characters of the wildcard query.
+func escapeWildcard(s string) string {
+	return wildcardReplacer.Replace(s)
+}
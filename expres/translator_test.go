@@ -0,0 +1,333 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expres
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestTranslator_TranslateJSON(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.RegexMatchOpt(), filtering.CollectionFunctionsOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		opts   []Option
+		want   string
+		err    error
+	}{
+		{
+			name:   "regex match",
+			filter: `str =~ "^foo"`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "empty",
+			filter: ``,
+			want:   `{"match_all":{}}`,
+		},
+		{
+			name:   "presence",
+			filter: `sub.sub:*`,
+			want:   `{"exists":{"field":"sub.sub"}}`,
+		},
+		{
+			name:   "equal",
+			filter: `i32 = 1`,
+			want:   `{"term":{"i32":1}}`,
+		},
+		{
+			name:   "not equal",
+			filter: `str != "foo"`,
+			want:   `{"bool":{"must_not":[{"term":{"str":"foo"}}]}}`,
+		},
+		{
+			name:   "range",
+			filter: `sub.i64 >= 5`,
+			want:   `{"range":{"sub.i64":{"gte":5}}}`,
+		},
+		{
+			name:   "and not",
+			filter: `i32 < 10 AND NOT bool = true`,
+			want:   `{"bool":{"must":[{"range":{"i32":{"lt":10}}},{"bool":{"must_not":[{"term":{"bool":true}}]}}]}}`,
+		},
+		{
+			name:   "or",
+			filter: `i32 < 1 OR i32 > 10`,
+			want:   `{"bool":{"minimum_should_match":1,"should":[{"range":{"i32":{"lt":1}}},{"range":{"i32":{"gt":10}}}]}}`,
+		},
+		{
+			name:   "in",
+			filter: `str IN ["a", "b"]`,
+			want:   `{"terms":{"str":["a","b"]}}`,
+		},
+		{
+			name:   "repeated has",
+			filter: `rp_str:"foo"`,
+			want:   `{"term":{"rp_str":"foo"}}`,
+		},
+		{
+			name:   "map key presence",
+			filter: `map_str_i32:"key"`,
+			want:   `{"exists":{"field":"map_str_i32.key"}}`,
+		},
+		{
+			name:   "wildcard search",
+			filter: `str = "*fo?o*"`,
+			want:   `{"wildcard":{"str":{"value":"*fo\\?o*"}}}`,
+		},
+		{
+			name:   "text prefix search",
+			filter: `name = "foo*"`,
+			opts:   []Option{TextFieldsOpt("name")},
+			want:   `{"match_phrase_prefix":{"name":"foo"}}`,
+		},
+		{
+			name:   "negated search",
+			filter: `NOT str = "foo*"`,
+			want:   `{"bool":{"must_not":[{"wildcard":{"str":{"value":"foo*"}}}]}}`,
+		},
+		{
+			name:   "nested",
+			filter: `rp_sub:{name: "foo"}`,
+			want:   `{"nested":{"path":"rp_sub","query":{"term":{"rp_sub.name":"foo"}}}}`,
+		},
+		{
+			name:   "all elements",
+			filter: `all(rp_sub, name = "foo")`,
+			want:   `{"bool":{"must_not":[{"nested":{"path":"rp_sub","query":{"bool":{"must_not":[{"term":{"rp_sub.name":"foo"}}]}}}}]}}`,
+		},
+		{
+			name:   "size",
+			filter: `size(rp_str) > 2`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "field path mapping",
+			filter: `sub.name = "foo"`,
+			opts: []Option{FieldPathOpt(func(path string) (string, error) {
+				return path + ".keyword", nil
+			})},
+			want: `{"term":{"sub.name.keyword":"foo"}}`,
+		},
+		{
+			name:   "field to field",
+			filter: `i32 = i64`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "message value",
+			filter: `sub = testpb.Message{name: "foo"}`,
+			err:    ErrUnsupported,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			if x != nil {
+				defer x.Free()
+			}
+
+			tr, err := NewTranslator(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create translator: %v", err)
+			}
+
+			got, err := tr.TranslateJSON(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslator_CaseInsensitive(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.CaseInsensitiveOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   string
+		err    error
+	}{
+		{
+			name:   "equal",
+			filter: `str = "Foo"`,
+			want:   `{"term":{"str":{"case_insensitive":true,"value":"Foo"}}}`,
+		},
+		{
+			name:   "non string",
+			filter: `i32 = 1`,
+			want:   `{"term":{"i32":1}}`,
+		},
+		{
+			name:   "in",
+			filter: `str IN ["a", "B"]`,
+			want:   `{"bool":{"minimum_should_match":1,"should":[{"term":{"str":{"case_insensitive":true,"value":"a"}}},{"term":{"str":{"case_insensitive":true,"value":"B"}}}]}}`,
+		},
+		{
+			name:   "wildcard",
+			filter: `str = "*Foo"`,
+			want:   `{"wildcard":{"str":{"case_insensitive":true,"value":"*Foo"}}}`,
+		},
+		{
+			name:   "map key presence",
+			filter: `map_str_i32:"Key"`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "range",
+			filter: `str > "foo"`,
+			err:    ErrUnsupported,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := tr.TranslateJSON(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslator_Search(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.SearchableFieldsOpt("name", "sub.str"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.ParseWithSearch(`i32 = 1`, `foo "bar baz"`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md, FieldPathOpt(func(path string) (string, error) {
+		return strings.ReplaceAll(path, "sub.", "parent."), nil
+	}))
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.TranslateJSON(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"bool":{"must":[{"term":{"i32":1}},{"bool":{"must":[` +
+		`{"multi_match":{"fields":["name","parent.str"],"query":"foo","type":"phrase"}},` +
+		`{"multi_match":{"fields":["name","parent.str"],"query":"bar baz","type":"phrase"}}]}}]}}`
+	if string(got) != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
+
+func TestTranslator_MatchAll(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.MatchAllOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.Parse(``)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.TranslateJSON(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"match_all":{}}`; string(got) != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
+
+func TestTranslator_Sequence(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.SequenceOpt(func(i, n int) float64 {
+		return float64(n - i)
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.Parse(`i32 = 1 str = "foo"`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.TranslateJSON(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"bool":{"must":[{"bool":{"boost":2,"must":[{"term":{"i32":1}}]}},{"term":{"str":"foo"}}]}}`
+	if string(got) != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exprfirestore translates the filter expressions into the conditions of the Firestore queries.
+// The conditions are the arguments of the firestore.Query.Where calls, so that the package doesn't depend
+// on the Firestore client itself. The filter constructs which cannot be expressed with the Firestore queries,
+// i.e. the OR across different fields, are reported by the UnsupportedError, so that the callers could
+// filter the query results in memory with the remainder.
+package exprfirestore
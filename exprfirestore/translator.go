@@ -0,0 +1,466 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprfirestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+var (
+	// ErrUnsupported is an error returned when the expression cannot be translated into the Firestore query conditions.
+	// It is wrapped by the UnsupportedError.
+	ErrUnsupported = errors.New("unsupported expression")
+
+	// ErrInvalidField is an error returned when the field selector doesn't match the message descriptor.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// MaxDisjunctions is the maximum number of the values of the Firestore 'in', 'not-in' and 'array-contains-any' operators.
+const MaxDisjunctions = 30
+
+// Capability describes the expressions supported by the Translator,
+// so that the filters could be checked before being translated into the Firestore query conditions.
+// Only the prefix string searches, i.e.: name = "foo*", are translated,
+// and the OR and NOT expressions are translated only if they could be expressed with the 'in', 'not-in' and '!=' operators.
+var Capability = expr.Capability{
+	Comparators:  []expr.Comparator{expr.EQ, expr.NE, expr.LT, expr.LE, expr.GT, expr.GE, expr.HAS, expr.IN},
+	StringSearch: true,
+}
+
+// The operators of the Firestore query conditions.
+const (
+	OpEqual            = "=="
+	OpNotEqual         = "!="
+	OpLess             = "<"
+	OpLessOrEqual      = "<="
+	OpGreater          = ">"
+	OpGreaterOrEqual   = ">="
+	OpArrayContains    = "array-contains"
+	OpArrayContainsAny = "array-contains-any"
+	OpIn               = "in"
+	OpNotIn            = "not-in"
+)
+
+// Where is a single condition of the Firestore query, the equivalent of the arguments of the firestore.Query.Where,
+// i.e.: q = q.Where(w.Path, w.Op, w.Value).
+type Where struct {
+	// Path is the dot separated path of the document field.
+	Path string
+
+	// Op is the Firestore operator, i.e.: "==" or "array-contains".
+	Op string
+
+	// Value is the compared value. The values of the 'in', 'not-in' and 'array-contains-any' operators are []any.
+	Value any
+}
+
+// Unsupported is a part of the filter, which cannot be translated into the Firestore query conditions.
+type Unsupported struct {
+	// Expr is the untranslated expression. It is a part of the translated filter,
+	// thus it is valid only until the filter is freed.
+	Expr expr.FilterExpr
+
+	// Reason describes why the expression is not supported.
+	Reason string
+}
+
+// UnsupportedError is an error returned by the Translate, when some of the conjunctions of the filter
+// cannot be translated. The conditions of the remaining conjunctions are still returned along with the error,
+// so that the query could be narrowed down, and its results filtered in memory with the Remainder.
+type UnsupportedError struct {
+	// Constructs are the untranslated conjunctions of the filter.
+	Constructs []Unsupported
+}
+
+// Error implements the error interface.
+func (e *UnsupportedError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrUnsupported.Error())
+	sb.WriteString(": ")
+	for i, c := range e.Constructs {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(c.Reason)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the ErrUnsupported, so that the error could be checked with errors.Is.
+func (e *UnsupportedError) Unwrap() error {
+	return ErrUnsupported
+}
+
+// Remainder returns the untranslated expressions, which all need to be matched by the query results.
+func (e *UnsupportedError) Remainder() []expr.FilterExpr {
+	out := make([]expr.FilterExpr, 0, len(e.Constructs))
+	for _, c := range e.Constructs {
+		out = append(out, c.Expr)
+	}
+	return out
+}
+
+// FieldPathFn is a function that maps the field selector path, i.e.: "sub.name", into the document field path.
+// It allows to rename the fields, which names in the collection differ from the proto field names.
+type FieldPathFn func(path string) (string, error)
+
+// Option is an option of the Translator.
+type Option func(*Translator) error
+
+// FieldPathOpt is an option that sets the field path mapping function of the translator.
+func FieldPathOpt(fn FieldPathFn) Option {
+	return func(t *Translator) error {
+		if fn == nil {
+			return errors.New("field path function is nil")
+		}
+		t.fieldPath = fn
+		return nil
+	}
+}
+
+// Translator translates the filter expressions of given message into the Firestore query conditions.
+// The top-level conjunctions of the filter are translated separately, and the mapping of each of them is:
+//   - EQ, NE, LT, LE, GT and GE comparisons into the "==", "!=", "<", "<=", ">" and ">=" operators,
+//   - IN comparison into the "in" operator, or the "array-contains-any" operator for a repeated field,
+//   - HAS comparison of a repeated field into the "array-contains" operator,
+//   - prefix StringSearchExpr, i.e.: name = "foo*", into the range of the ">=" and "<" operators,
+//   - NotExpr of the EQ and IN comparisons into the "!=" and "not-in" operators,
+//   - OrExpr of the EQ comparisons of the same field into the "in" operator.
+//
+// The other conjunctions, i.e. OR across different fields, the case-insensitive comparisons, the map key presence
+// or comparing two fields, are reported by the UnsupportedError.
+// A Translator is safe for concurrent use.
+type Translator struct {
+	msg       protoreflect.MessageDescriptor
+	fieldPath FieldPathFn
+}
+
+// NewTranslator creates a new translator for the filters of the msg message.
+func NewTranslator(msg protoreflect.MessageDescriptor, opts ...Option) (*Translator, error) {
+	if msg == nil {
+		return nil, errors.New("message descriptor is not set")
+	}
+	t := Translator{msg: msg}
+	for _, opt := range opts {
+		if err := opt(&t); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// Translate translates the filter expression into the Firestore query conditions, which all need to be met.
+// A nil expression or the expr.MatchAllExpr results in no conditions, which matches all documents.
+// If some of the conjunctions cannot be translated, the conditions of the others are returned
+// along with the *UnsupportedError describing the untranslated ones.
+func (t *Translator) Translate(x expr.FilterExpr) ([]Where, error) {
+	var (
+		wheres []Where
+		ue     UnsupportedError
+	)
+	for _, cx := range conjunctions(x, nil) {
+		ws, reason, err := t.translate(cx)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			ue.Constructs = append(ue.Constructs, Unsupported{Expr: cx, Reason: reason})
+			continue
+		}
+		wheres = append(wheres, ws...)
+	}
+	if len(ue.Constructs) > 0 {
+		return wheres, &ue
+	}
+	return wheres, nil
+}
+
+// conjunctions flattens the top-level AndExpr, SequenceExpr and CompositeExpr of the x.
+func conjunctions(x expr.FilterExpr, out []expr.FilterExpr) []expr.FilterExpr {
+	switch xt := x.(type) {
+	case nil, *expr.MatchAllExpr:
+		return out
+	case *expr.AndExpr:
+		for _, sub := range xt.Expr {
+			out = conjunctions(sub, out)
+		}
+		return out
+	case *expr.SequenceExpr:
+		for _, sub := range xt.Factors {
+			out = conjunctions(sub, out)
+		}
+		return out
+	case *expr.CompositeExpr:
+		return conjunctions(xt.Expr, out)
+	}
+	return append(out, x)
+}
+
+// translate translates a single conjunction.
+// If it is not supported, the non-empty reason is returned.
+func (t *Translator) translate(x expr.FilterExpr) ([]Where, string, error) {
+	switch xt := x.(type) {
+	case *expr.CompareExpr:
+		return t.translateCompare(xt)
+	case *expr.NotExpr:
+		return t.translateNot(xt)
+	case *expr.OrExpr:
+		return t.translateOr(xt)
+	case *expr.SearchExpr:
+		return t.translate(xt.Expr)
+	case *expr.AndExpr, *expr.SequenceExpr, *expr.CompositeExpr:
+		// The nested conjunctions, i.e. of the search expression.
+		var wheres []Where
+		for _, cx := range conjunctions(xt, nil) {
+			ws, reason, err := t.translate(cx)
+			if err != nil || reason != "" {
+				return nil, reason, err
+			}
+			wheres = append(wheres, ws...)
+		}
+		return wheres, "", nil
+	}
+	return nil, fmt.Sprintf("%T expression", x), nil
+}
+
+var comparisonOperators = [...]string{
+	expr.EQ: OpEqual,
+	expr.LE: OpLessOrEqual,
+	expr.LT: OpLess,
+	expr.GE: OpGreaterOrEqual,
+	expr.GT: OpGreater,
+	expr.NE: OpNotEqual,
+}
+
+func (t *Translator) translateCompare(x *expr.CompareExpr) ([]Where, string, error) {
+	left, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok {
+		return nil, fmt.Sprintf("left hand side %T", x.Left), nil
+	}
+	path, fd, isMapKey, err := t.selectorPath(left)
+	if err != nil {
+		return nil, "", err
+	}
+	if x.CaseInsensitive {
+		return nil, fmt.Sprintf("case-insensitive %s comparison of %q", x.Comparator, path), nil
+	}
+	isList := fd.IsList() && !isMapKey
+
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		v, reason := documentValue(rt.Value)
+		if reason != "" {
+			return nil, reason, nil
+		}
+		switch {
+		case x.Comparator == expr.HAS && fd.IsMap() && !isMapKey:
+			return nil, fmt.Sprintf("map key presence of %q", path), nil
+		case x.Comparator == expr.HAS && isList:
+			return []Where{{Path: path, Op: OpArrayContains, Value: v}}, "", nil
+		case x.Comparator == expr.HAS:
+			return []Where{{Path: path, Op: OpEqual, Value: v}}, "", nil
+		}
+		if int(x.Comparator) < len(comparisonOperators) && comparisonOperators[x.Comparator] != "" {
+			return []Where{{Path: path, Op: comparisonOperators[x.Comparator], Value: v}}, "", nil
+		}
+	case *expr.ArrayExpr:
+		if x.Comparator != expr.IN {
+			break
+		}
+		values, reason := arrayValues(rt)
+		if reason != "" {
+			return nil, reason, nil
+		}
+		op := OpIn
+		if isList {
+			op = OpArrayContainsAny
+		}
+		return []Where{{Path: path, Op: op, Value: values}}, "", nil
+	case *expr.StringSearchExpr:
+		if x.Comparator != expr.EQ || rt.PrefixWildcard || !rt.SuffixWildcard || isList {
+			return nil, fmt.Sprintf("string search of %q other than the prefix match", path), nil
+		}
+		// The prefix match is the range of the strings starting with the prefix,
+		// where the U+F8FF is the highest code point of the Unicode private use area.
+		return []Where{
+			{Path: path, Op: OpGreaterOrEqual, Value: rt.Value},
+			{Path: path, Op: OpLess, Value: rt.Value + "\uf8ff"},
+		}, "", nil
+	case *expr.FieldSelectorExpr:
+		return nil, fmt.Sprintf("comparison of the fields %q", path), nil
+	}
+	return nil, fmt.Sprintf("%s comparison of %q with %T", x.Comparator, path, x.Right), nil
+}
+
+// translateNot translates the negated EQ and IN comparisons into the "!=" and "not-in" operators.
+func (t *Translator) translateNot(x *expr.NotExpr) ([]Where, string, error) {
+	inner := x.Expr
+	for {
+		cx, ok := inner.(*expr.CompositeExpr)
+		if !ok {
+			break
+		}
+		inner = cx.Expr
+	}
+	cmp, ok := inner.(*expr.CompareExpr)
+	if !ok || (cmp.Comparator != expr.EQ && cmp.Comparator != expr.IN) {
+		return nil, "NOT of an expression other than the EQ or IN comparison", nil
+	}
+	ws, reason, err := t.translateCompare(cmp)
+	if err != nil || reason != "" {
+		return nil, reason, err
+	}
+	switch {
+	case len(ws) == 1 && ws[0].Op == OpEqual:
+		ws[0].Op = OpNotEqual
+	case len(ws) == 1 && ws[0].Op == OpIn:
+		ws[0].Op = OpNotIn
+	default:
+		return nil, "NOT of an expression other than the EQ or IN comparison", nil
+	}
+	return ws, "", nil
+}
+
+// translateOr translates the disjunction of the EQ comparisons of the same field into the "in" operator.
+func (t *Translator) translateOr(x *expr.OrExpr) ([]Where, string, error) {
+	var (
+		path   string
+		values []any
+	)
+	for _, sub := range x.Expr {
+		cmp, ok := sub.(*expr.CompareExpr)
+		if !ok || cmp.Comparator != expr.EQ {
+			return nil, "OR of an expression other than the EQ comparison", nil
+		}
+		ws, reason, err := t.translateCompare(cmp)
+		if err != nil || reason != "" {
+			return nil, reason, err
+		}
+		if len(ws) != 1 || ws[0].Op != OpEqual {
+			return nil, "OR of an expression other than the EQ comparison", nil
+		}
+		if path != "" && ws[0].Path != path {
+			return nil, fmt.Sprintf("OR across the fields %q and %q", path, ws[0].Path), nil
+		}
+		path = ws[0].Path
+		values = append(values, ws[0].Value)
+	}
+	if len(values) > MaxDisjunctions {
+		return nil, fmt.Sprintf("OR of more than %d values of %q", MaxDisjunctions, path), nil
+	}
+	return []Where{{Path: path, Op: OpIn, Value: values}}, "", nil
+}
+
+// arrayValues converts the array expression into the values of the "in" operators.
+func arrayValues(x *expr.ArrayExpr) ([]any, string) {
+	if len(x.Elements) > MaxDisjunctions {
+		return nil, fmt.Sprintf("array of more than %d values", MaxDisjunctions)
+	}
+	values := make([]any, 0, len(x.Elements))
+	for _, e := range x.Elements {
+		ve, ok := e.(*expr.ValueExpr)
+		if !ok {
+			return nil, fmt.Sprintf("array element %T", e)
+		}
+		v, reason := documentValue(ve.Value)
+		if reason != "" {
+			return nil, reason
+		}
+		values = append(values, v)
+	}
+	return values, ""
+}
+
+// selectorPath resolves the document path of the field selector.
+// It returns the descriptor of the last selected field, and whether the path ends with a map key.
+func (t *Translator) selectorPath(fs *expr.FieldSelectorExpr) (string, protoreflect.FieldDescriptor, bool, error) {
+	var (
+		sb       strings.Builder
+		md       = t.msg
+		fd       protoreflect.FieldDescriptor
+		isMapKey bool
+	)
+	for cur := expr.Expr(fs); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if fd != nil {
+				switch {
+				case isMapKey:
+					md = fd.MapValue().Message()
+				case fd.Kind() == protoreflect.MessageKind:
+					md = fd.Message()
+				default:
+					return "", nil, false, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+				}
+			}
+			if md == nil {
+				return "", nil, false, fmt.Errorf("%w: %q is not a message field", ErrInvalidField, ct.Field)
+			}
+			fd = md.Fields().ByName(ct.Field)
+			if fd == nil {
+				return "", nil, false, fmt.Errorf("%w: field %q not found in the message %s", ErrInvalidField, ct.Field, md.FullName())
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			isMapKey = false
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			kv, ok := ct.Key.(*expr.ValueExpr)
+			if !ok {
+				return "", nil, false, fmt.Errorf("%w: map key %T", ErrInvalidField, ct.Key)
+			}
+			sb.WriteByte('.')
+			sb.WriteString(fmt.Sprint(kv.Value))
+			isMapKey = true
+			cur = ct.Traversal
+		default:
+			return "", nil, false, fmt.Errorf("%w: field traversal %T", ErrInvalidField, cur)
+		}
+	}
+
+	path := sb.String()
+	if t.fieldPath != nil {
+		var err error
+		if path, err = t.fieldPath(path); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return path, fd, isMapKey, nil
+}
+
+// documentValue converts the expression value into the document value.
+// If the value is not supported, the non-empty reason is returned.
+func documentValue(v any) (any, string) {
+	switch vt := v.(type) {
+	case protoreflect.EnumNumber:
+		return int64(vt), ""
+	case [16]byte:
+		return expr.FormatUUID(vt), ""
+	case *structpb.Value:
+		return vt.AsInterface(), ""
+	case protoreflect.Message:
+		return nil, fmt.Sprintf("message value %s", vt.Descriptor().FullName())
+	}
+	return v, ""
+}
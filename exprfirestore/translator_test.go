@@ -0,0 +1,177 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprfirestore
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestTranslator_Translate(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.CaseInsensitiveFieldsOpt("testpb.Message.name"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name        string
+		filter      string
+		opts        []Option
+		want        []Where
+		unsupported []string
+		err         error
+	}{
+		{
+			name:   "empty",
+			filter: ``,
+		},
+		{
+			name:   "equal",
+			filter: `i32 = 1`,
+			want:   []Where{{Path: "i32", Op: "==", Value: int64(1)}},
+		},
+		{
+			name:   "and of comparisons",
+			filter: `sub.i64 >= 5 AND (str != "foo" AND bool = true)`,
+			want: []Where{
+				{Path: "sub.i64", Op: ">=", Value: int64(5)},
+				{Path: "str", Op: "!=", Value: "foo"},
+				{Path: "bool", Op: "==", Value: true},
+			},
+		},
+		{
+			name:   "in",
+			filter: `str IN ["a", "b"]`,
+			want:   []Where{{Path: "str", Op: "in", Value: []any{"a", "b"}}},
+		},
+		{
+			name:   "repeated has",
+			filter: `rp_str:"foo"`,
+			want:   []Where{{Path: "rp_str", Op: "array-contains", Value: "foo"}},
+		},
+		{
+			name:   "repeated in",
+			filter: `rp_i32 IN [1, 2]`,
+			want:   []Where{{Path: "rp_i32", Op: "array-contains-any", Value: []any{int64(1), int64(2)}}},
+		},
+		{
+			name:   "enum",
+			filter: `enum = "ONE"`,
+			want:   []Where{{Path: "enum", Op: "==", Value: int64(1)}},
+		},
+		{
+			name:   "prefix search",
+			filter: `str = "foo*"`,
+			want: []Where{
+				{Path: "str", Op: ">=", Value: "foo"},
+				{Path: "str", Op: "<", Value: "foo\uf8ff"},
+			},
+		},
+		{
+			name:   "not equal",
+			filter: `NOT i32 = 1`,
+			want:   []Where{{Path: "i32", Op: "!=", Value: int64(1)}},
+		},
+		{
+			name:   "not in",
+			filter: `NOT (str IN ["a", "b"])`,
+			want:   []Where{{Path: "str", Op: "not-in", Value: []any{"a", "b"}}},
+		},
+		{
+			name:   "or of the same field",
+			filter: `i32 = 1 OR i32 = 2`,
+			want:   []Where{{Path: "i32", Op: "in", Value: []any{int64(1), int64(2)}}},
+		},
+		{
+			name:        "or across fields",
+			filter:      `i32 = 1 AND (i32 = 2 OR i64 = 3)`,
+			want:        []Where{{Path: "i32", Op: "==", Value: int64(1)}},
+			unsupported: []string{`OR across the fields "i32" and "i64"`},
+		},
+		{
+			name:        "not of range",
+			filter:      `NOT i32 > 1 AND str = "*foo" AND i32 = i64 AND map_str_str:"key" AND name = "foo"`,
+			unsupported: []string{"NOT of an expression other than the EQ or IN comparison", `string search of "str" other than the prefix match`, `comparison of the fields "i32"`, `map key presence of "map_str_str"`, `case-insensitive = comparison of "name"`},
+		},
+		{
+			name:   "field path mapping",
+			filter: `sub.str = "foo"`,
+			opts: []Option{FieldPathOpt(func(path string) (string, error) {
+				return strings.ReplaceAll(path, "sub.", "parent."), nil
+			})},
+			want: []Where{{Path: "parent.str", Op: "==", Value: "foo"}},
+		},
+		{
+			name:   "field path mapping error",
+			filter: `sub.name = "foo"`,
+			opts: []Option{FieldPathOpt(func(path string) (string, error) {
+				return "", ErrInvalidField
+			})},
+			err: ErrInvalidField,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			if x != nil {
+				defer x.Free()
+			}
+
+			tr, err := NewTranslator(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create translator: %v", err)
+			}
+
+			got, err := tr.Translate(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if tt.unsupported != nil {
+				var ue *UnsupportedError
+				if !errors.As(err, &ue) || !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("expected unsupported error but got %v", err)
+				}
+				var reasons []string
+				for _, c := range ue.Constructs {
+					reasons = append(reasons, c.Reason)
+				}
+				if !reflect.DeepEqual(reasons, tt.unsupported) {
+					t.Errorf("expected unsupported %q but got %q", tt.unsupported, reasons)
+				}
+				if len(ue.Remainder()) != len(tt.unsupported) {
+					t.Errorf("expected %d remainder expressions but got %d", len(tt.unsupported), len(ue.Remainder()))
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
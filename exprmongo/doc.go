@@ -0,0 +1,18 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exprmongo translates the filter expressions into the MongoDB query filter documents.
+// The documents are built of the D, E and A types, which mirror the bson.D, bson.E and bson.A types
+// of the MongoDB driver, so that the package doesn't depend on the driver itself.
+package exprmongo
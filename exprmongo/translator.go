@@ -0,0 +1,486 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprmongo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+var (
+	// ErrUnsupported is an error returned when the expression cannot be translated into a MongoDB filter.
+	ErrUnsupported = errors.New("unsupported expression")
+
+	// ErrInvalidField is an error returned when the field selector doesn't match the message descriptor.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// Capability describes the expressions supported by the Translator,
+// so that the filters could be checked before being translated into a MongoDB filter.
+var Capability = expr.Capability{
+	Comparators:      []expr.Comparator{expr.EQ, expr.NE, expr.LT, expr.LE, expr.GT, expr.GE, expr.HAS, expr.IN},
+	StringSearch:     true,
+	RegexMatch:       true,
+	Presence:         true,
+	FieldComparisons: true,
+	AnyElement:       true,
+	AllElements:      true,
+	CaseInsensitive:  true,
+	Functions:        []string{"size"},
+}
+
+// E is a single element of the filter document, the equivalent of the bson.E.
+type E struct {
+	Key   string
+	Value any
+}
+
+// D is an ordered filter document, the equivalent of the bson.D.
+type D []E
+
+// A is an array of the filter document values, the equivalent of the bson.A.
+type A []any
+
+// Map returns the document as a map, with the nested D and A values converted to maps and slices,
+// which could be directly passed to the MongoDB driver as a filter.
+func (d D) Map() map[string]any {
+	m := make(map[string]any, len(d))
+	for _, e := range d {
+		m[e.Key] = mapValue(e.Value)
+	}
+	return m
+}
+
+func mapValue(v any) any {
+	switch vt := v.(type) {
+	case D:
+		return vt.Map()
+	case A:
+		out := make([]any, len(vt))
+		for i, e := range vt {
+			out[i] = mapValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// FieldPathFn is a function that maps the field selector path, i.e.: "sub.name", into the document field path.
+// It allows to rename the fields, which names in the collection differ from the proto field names.
+// The selectors within the AnyElementExpr are mapped relative to the element document.
+type FieldPathFn func(path string) (string, error)
+
+// Option is an option of the Translator.
+type Option func(*Translator) error
+
+// FieldPathOpt is an option that sets the field path mapping function of the translator.
+func FieldPathOpt(fn FieldPathFn) Option {
+	return func(t *Translator) error {
+		if fn == nil {
+			return errors.New("field path function is nil")
+		}
+		t.fieldPath = fn
+		return nil
+	}
+}
+
+// Translator translates the filter expressions of given message into the MongoDB filter documents.
+// The mapping of the expressions is:
+//   - AndExpr, SequenceExpr, OrExpr and NotExpr into the $and, $or and $nor operators,
+//   - EQ, NE, LT, LE, GT and GE comparisons into the $eq, $ne, $lt, $lte, $gt and $gte operators,
+//   - IN comparison into the $in operator, and its negation into the $nin operator,
+//   - HAS comparison of a repeated field into the $elemMatch operator, and of a map field into the key $exists operator,
+//   - StringSearchExpr into the anchored $regex operator,
+//   - RegexMatchExpr into the $regex operator with the pattern as is,
+//   - PresenceExpr into the $exists operator, with the non-empty array and document checks,
+//   - SearchExpr into the translation of its equivalent expression,
+//   - case-insensitive string comparisons into the anchored $regex operator with the "i" option,
+//   - comparison of two fields into the $expr aggregation operator,
+//   - AnyElementExpr with the universal quantifier into the negated $elemMatch of the negated filter,
+//   - comparison of the size function call of a repeated field into the $expr with the $size operator.
+//
+// The other function calls and message values are not supported.
+// A Translator is safe for concurrent use.
+type Translator struct {
+	msg       protoreflect.MessageDescriptor
+	fieldPath FieldPathFn
+}
+
+// NewTranslator creates a new translator for the filters of the msg message.
+func NewTranslator(msg protoreflect.MessageDescriptor, opts ...Option) (*Translator, error) {
+	if msg == nil {
+		return nil, errors.New("message descriptor is not set")
+	}
+	t := Translator{msg: msg}
+	for _, opt := range opts {
+		if err := opt(&t); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// Translate translates the filter expression into the MongoDB filter document.
+// A nil expression or the expr.MatchAllExpr results in an empty document, which matches all documents.
+func (t *Translator) Translate(x expr.FilterExpr) (D, error) {
+	if expr.IsMatchAll(x) {
+		return D{}, nil
+	}
+	return t.translate(t.msg, x)
+}
+
+func (t *Translator) translate(md protoreflect.MessageDescriptor, x expr.FilterExpr) (D, error) {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		return t.translateLogical(md, "$and", xt.Expr)
+	case *expr.SequenceExpr:
+		return t.translateLogical(md, "$and", xt.Factors)
+	case *expr.OrExpr:
+		return t.translateLogical(md, "$or", xt.Expr)
+	case *expr.NotExpr:
+		inner, err := t.translate(md, xt.Expr)
+		if err != nil {
+			return nil, err
+		}
+		if nin, ok := notIn(inner); ok {
+			return nin, nil
+		}
+		return D{{Key: "$nor", Value: A{inner}}}, nil
+	case *expr.CompositeExpr:
+		return t.translate(md, xt.Expr)
+	case *expr.MatchAllExpr:
+		return D{}, nil
+	case *expr.CompareExpr:
+		return t.translateCompare(md, xt)
+	case *expr.SearchExpr:
+		return t.translate(md, xt.Expr)
+	case *expr.RegexMatchExpr:
+		left, ok := xt.Left.(*expr.FieldSelectorExpr)
+		if !ok {
+			return nil, fmt.Errorf("%w: left hand side %T", ErrUnsupported, xt.Left)
+		}
+		path, _, _, err := t.selectorPath(md, left)
+		if err != nil {
+			return nil, err
+		}
+		return D{{Key: path, Value: D{{Key: "$regex", Value: xt.Pattern}}}}, nil
+	case *expr.PresenceExpr:
+		return t.translatePresence(md, xt)
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupported, x)
+}
+
+// translatePresence translates the presence of a field.
+// The repeated field is present if it has the first element, the map field if it is not an empty document,
+// and any other field if it exists and is not null.
+func (t *Translator) translatePresence(md protoreflect.MessageDescriptor, x *expr.PresenceExpr) (D, error) {
+	field, ok := x.Field.(*expr.FieldSelectorExpr)
+	if !ok {
+		return nil, fmt.Errorf("%w: presence of %T", ErrUnsupported, x.Field)
+	}
+	path, fd, _, err := t.selectorPath(md, field)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case fd.IsList():
+		return D{{Key: path + ".0", Value: D{{Key: "$exists", Value: true}}}}, nil
+	case fd.IsMap():
+		return D{{Key: path, Value: D{{Key: "$exists", Value: true}, {Key: "$ne", Value: D{}}}}}, nil
+	}
+	return D{{Key: path, Value: D{{Key: "$exists", Value: true}, {Key: "$ne", Value: nil}}}}, nil
+}
+
+func (t *Translator) translateLogical(md protoreflect.MessageDescriptor, op string, xs []expr.FilterExpr) (D, error) {
+	arr := make(A, 0, len(xs))
+	for _, x := range xs {
+		d, err := t.translate(md, x)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, d)
+	}
+	return D{{Key: op, Value: arr}}, nil
+}
+
+var comparisonOperators = [...]string{
+	expr.EQ: "$eq",
+	expr.LE: "$lte",
+	expr.LT: "$lt",
+	expr.GE: "$gte",
+	expr.GT: "$gt",
+	expr.NE: "$ne",
+}
+
+// notIn returns the $nin document of the negated $in document of a single field, i.e.: {field: {$in: [a, b]}}.
+func notIn(d D) (D, bool) {
+	if len(d) != 1 || strings.HasPrefix(d[0].Key, "$") {
+		return nil, false
+	}
+	ops, ok := d[0].Value.(D)
+	if !ok || len(ops) != 1 || ops[0].Key != "$in" {
+		return nil, false
+	}
+	return D{{Key: d[0].Key, Value: D{{Key: "$nin", Value: ops[0].Value}}}}, true
+}
+
+func (t *Translator) translateCompare(md protoreflect.MessageDescriptor, x *expr.CompareExpr) (D, error) {
+	if fc, ok := x.Left.(*expr.FunctionCallExpr); ok {
+		return t.translateSizeCompare(md, fc, x)
+	}
+	left, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok {
+		return nil, fmt.Errorf("%w: left hand side %T", ErrUnsupported, x.Left)
+	}
+	path, fd, isMapKey, err := t.selectorPath(md, left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		v, err := documentValue(rt.Value)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok && x.CaseInsensitive {
+			return caseInsensitiveCompare(path, fd, isMapKey, x.Comparator, s)
+		}
+		switch {
+		case x.Comparator == expr.HAS && fd.IsMap() && !isMapKey:
+			// The map key presence, i.e.: labels:"key".
+			return D{{Key: path + "." + fmt.Sprint(v), Value: D{{Key: "$exists", Value: true}}}}, nil
+		case x.Comparator == expr.HAS && fd.IsList():
+			return D{{Key: path, Value: D{{Key: "$elemMatch", Value: D{{Key: "$eq", Value: v}}}}}}, nil
+		case x.Comparator == expr.HAS:
+			return D{{Key: path, Value: D{{Key: "$eq", Value: v}}}}, nil
+		}
+		op, err := comparisonOperator(x.Comparator)
+		if err != nil {
+			return nil, err
+		}
+		return D{{Key: path, Value: D{{Key: op, Value: v}}}}, nil
+	case *expr.ArrayExpr:
+		if x.Comparator != expr.IN {
+			return nil, fmt.Errorf("%w: array with the %s comparator", ErrUnsupported, x.Comparator)
+		}
+		arr := make(A, 0, len(rt.Elements))
+		for _, e := range rt.Elements {
+			ve, ok := e.(*expr.ValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("%w: array element %T", ErrUnsupported, e)
+			}
+			v, err := documentValue(ve.Value)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := v.(string); ok && x.CaseInsensitive {
+				// The $in operator cannot match regardless of the case, thus each value is matched by a regex.
+				v = D{{Key: path, Value: caseInsensitiveRegex("^" + regexp.QuoteMeta(s) + "$")}}
+			}
+			arr = append(arr, v)
+		}
+		if x.CaseInsensitive {
+			return D{{Key: "$or", Value: arr}}, nil
+		}
+		return D{{Key: path, Value: D{{Key: "$in", Value: arr}}}}, nil
+	case *expr.StringSearchExpr:
+		pattern := regexp.QuoteMeta(rt.Value)
+		if !rt.PrefixWildcard {
+			pattern = "^" + pattern
+		}
+		if !rt.SuffixWildcard {
+			pattern += "$"
+		}
+		re := D{{Key: "$regex", Value: pattern}}
+		if x.CaseInsensitive {
+			re = caseInsensitiveRegex(pattern)
+		}
+		switch x.Comparator {
+		case expr.EQ, expr.HAS:
+			return D{{Key: path, Value: re}}, nil
+		case expr.NE:
+			return D{{Key: path, Value: D{{Key: "$not", Value: re}}}}, nil
+		}
+		return nil, fmt.Errorf("%w: string search with the %s comparator", ErrUnsupported, x.Comparator)
+	case *expr.AnyElementExpr:
+		if rt.All && rt.Filter == nil {
+			return D{}, nil
+		}
+		if rt.Filter == nil {
+			// Any element matches, thus the array needs to be non-empty.
+			return D{{Key: path + ".0", Value: D{{Key: "$exists", Value: true}}}}, nil
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			return nil, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+		}
+		inner, err := t.translate(fd.Message(), rt.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if rt.All {
+			// None of the elements fails the filter.
+			return D{{Key: path, Value: D{{Key: "$not", Value: D{{Key: "$elemMatch", Value: D{{Key: "$nor", Value: A{inner}}}}}}}}}, nil
+		}
+		return D{{Key: path, Value: D{{Key: "$elemMatch", Value: inner}}}}, nil
+	case *expr.FieldSelectorExpr:
+		op, err := comparisonOperator(x.Comparator)
+		if err != nil {
+			return nil, err
+		}
+		rpath, _, _, err := t.selectorPath(md, rt)
+		if err != nil {
+			return nil, err
+		}
+		return D{{Key: "$expr", Value: D{{Key: op, Value: A{"$" + path, "$" + rpath}}}}}, nil
+	}
+	return nil, fmt.Errorf("%w: right hand side %T", ErrUnsupported, x.Right)
+}
+
+// translateSizeCompare translates the comparison of the size function call of a repeated field,
+// i.e.: size(items) > 2, into the $expr with the $size of the array, where a missing array is empty.
+func (t *Translator) translateSizeCompare(md protoreflect.MessageDescriptor, fc *expr.FunctionCallExpr, x *expr.CompareExpr) (D, error) {
+	if fc.PkgName != "" || fc.Name != "size" || len(fc.Arguments) != 1 {
+		return nil, fmt.Errorf("%w: function %s", ErrUnsupported, fc.Name)
+	}
+	fs, ok := fc.Arguments[0].(*expr.FieldSelectorExpr)
+	if !ok {
+		return nil, fmt.Errorf("%w: size argument %T", ErrUnsupported, fc.Arguments[0])
+	}
+	path, fd, isMapKey, err := t.selectorPath(md, fs)
+	if err != nil {
+		return nil, err
+	}
+	if !fd.IsList() || isMapKey {
+		return nil, fmt.Errorf("%w: size of the field %q", ErrUnsupported, fd.Name())
+	}
+	ve, ok := x.Right.(*expr.ValueExpr)
+	if !ok {
+		return nil, fmt.Errorf("%w: size compared with %T", ErrUnsupported, x.Right)
+	}
+	op, err := comparisonOperator(x.Comparator)
+	if err != nil {
+		return nil, err
+	}
+	size := D{{Key: "$size", Value: D{{Key: "$ifNull", Value: A{"$" + path, A{}}}}}}
+	return D{{Key: "$expr", Value: D{{Key: op, Value: A{size, ve.Value}}}}}, nil
+}
+
+// caseInsensitiveCompare translates the case-insensitive comparison of the string value into the anchored $regex.
+func caseInsensitiveCompare(path string, fd protoreflect.FieldDescriptor, isMapKey bool, c expr.Comparator, s string) (D, error) {
+	re := caseInsensitiveRegex("^" + regexp.QuoteMeta(s) + "$")
+	switch {
+	case c == expr.HAS && fd.IsMap() && !isMapKey:
+		return nil, fmt.Errorf("%w: case-insensitive map key presence", ErrUnsupported)
+	case c == expr.HAS && fd.IsList():
+		return D{{Key: path, Value: D{{Key: "$elemMatch", Value: re}}}}, nil
+	case c == expr.EQ, c == expr.HAS:
+		return D{{Key: path, Value: re}}, nil
+	case c == expr.NE:
+		return D{{Key: path, Value: D{{Key: "$not", Value: re}}}}, nil
+	}
+	return nil, fmt.Errorf("%w: case-insensitive %s comparison", ErrUnsupported, c)
+}
+
+func caseInsensitiveRegex(pattern string) D {
+	return D{{Key: "$regex", Value: pattern}, {Key: "$options", Value: "i"}}
+}
+
+func comparisonOperator(c expr.Comparator) (string, error) {
+	if int(c) < len(comparisonOperators) && comparisonOperators[c] != "" {
+		return comparisonOperators[c], nil
+	}
+	return "", fmt.Errorf("%w: comparator %s", ErrUnsupported, c)
+}
+
+// selectorPath resolves the document path of the field selector.
+// It returns the descriptor of the last selected field, and whether the path ends with a map key.
+func (t *Translator) selectorPath(md protoreflect.MessageDescriptor, fs *expr.FieldSelectorExpr) (string, protoreflect.FieldDescriptor, bool, error) {
+	var (
+		sb       strings.Builder
+		fd       protoreflect.FieldDescriptor
+		isMapKey bool
+	)
+	for cur := expr.Expr(fs); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if fd != nil {
+				switch {
+				case isMapKey:
+					md = fd.MapValue().Message()
+				case fd.Kind() == protoreflect.MessageKind:
+					md = fd.Message()
+				default:
+					return "", nil, false, fmt.Errorf("%w: field %q is not a message", ErrInvalidField, fd.Name())
+				}
+			}
+			if md == nil {
+				return "", nil, false, fmt.Errorf("%w: %q is not a message field", ErrInvalidField, ct.Field)
+			}
+			fd = md.Fields().ByName(ct.Field)
+			if fd == nil {
+				return "", nil, false, fmt.Errorf("%w: field %q not found in the message %s", ErrInvalidField, ct.Field, md.FullName())
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			isMapKey = false
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			kv, ok := ct.Key.(*expr.ValueExpr)
+			if !ok {
+				return "", nil, false, fmt.Errorf("%w: map key %T", ErrUnsupported, ct.Key)
+			}
+			sb.WriteByte('.')
+			sb.WriteString(fmt.Sprint(kv.Value))
+			isMapKey = true
+			cur = ct.Traversal
+		default:
+			return "", nil, false, fmt.Errorf("%w: field traversal %T", ErrUnsupported, cur)
+		}
+	}
+
+	path := sb.String()
+	if t.fieldPath != nil {
+		var err error
+		if path, err = t.fieldPath(path); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return path, fd, isMapKey, nil
+}
+
+// documentValue converts the expression value into the document value.
+func documentValue(v any) (any, error) {
+	switch vt := v.(type) {
+	case protoreflect.EnumNumber:
+		return int32(vt), nil
+	case [16]byte:
+		return expr.FormatUUID(vt), nil
+	case *structpb.Value:
+		return vt.AsInterface(), nil
+	case protoreflect.Message:
+		return nil, fmt.Errorf("%w: message value %s", ErrUnsupported, vt.Descriptor().FullName())
+	}
+	return v, nil
+}
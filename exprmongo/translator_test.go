@@ -0,0 +1,327 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprmongo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestTranslator_Translate(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.RegexMatchOpt(), filtering.CollectionFunctionsOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		opts   []Option
+		want   D
+		err    error
+	}{
+		{
+			name:   "empty",
+			filter: ``,
+			want:   D{},
+		},
+		{
+			name:   "equal",
+			filter: `i32 = 1`,
+			want:   D{{Key: "i32", Value: D{{Key: "$eq", Value: int64(1)}}}},
+		},
+		{
+			name:   "nested greater or equal",
+			filter: `sub.i64 >= 5`,
+			want:   D{{Key: "sub.i64", Value: D{{Key: "$gte", Value: int64(5)}}}},
+		},
+		{
+			name:   "and not",
+			filter: `str != "foo" AND NOT bool = true`,
+			want: D{{Key: "$and", Value: A{
+				D{{Key: "str", Value: D{{Key: "$ne", Value: "foo"}}}},
+				D{{Key: "$nor", Value: A{D{{Key: "bool", Value: D{{Key: "$eq", Value: true}}}}}}},
+			}}},
+		},
+		{
+			name:   "or",
+			filter: `i32 < 1 OR i32 > 10`,
+			want: D{{Key: "$or", Value: A{
+				D{{Key: "i32", Value: D{{Key: "$lt", Value: int64(1)}}}},
+				D{{Key: "i32", Value: D{{Key: "$gt", Value: int64(10)}}}},
+			}}},
+		},
+		{
+			name:   "in",
+			filter: `str IN ["a", "b"]`,
+			want:   D{{Key: "str", Value: D{{Key: "$in", Value: A{"a", "b"}}}}},
+		},
+		{
+			name:   "not in",
+			filter: `str NOT IN ["a", "b"]`,
+			want:   D{{Key: "str", Value: D{{Key: "$nin", Value: A{"a", "b"}}}}},
+		},
+		{
+			name:   "repeated has",
+			filter: `rp_str:"foo"`,
+			want:   D{{Key: "rp_str", Value: D{{Key: "$elemMatch", Value: D{{Key: "$eq", Value: "foo"}}}}}},
+		},
+		{
+			name:   "map key presence",
+			filter: `map_str_i32:"key"`,
+			want:   D{{Key: "map_str_i32.key", Value: D{{Key: "$exists", Value: true}}}},
+		},
+		{
+			name:   "prefix search",
+			filter: `str = "fo.o*"`,
+			want:   D{{Key: "str", Value: D{{Key: "$regex", Value: `^fo\.o`}}}},
+		},
+		{
+			name:   "suffix search",
+			filter: `str = "*foo"`,
+			want:   D{{Key: "str", Value: D{{Key: "$regex", Value: `foo$`}}}},
+		},
+		{
+			name:   "regex match",
+			filter: `sub.name =~ "^fo+$"`,
+			want:   D{{Key: "sub.name", Value: D{{Key: "$regex", Value: `^fo+$`}}}},
+		},
+		{
+			name:   "field to field",
+			filter: `i32 = i64`,
+			want:   D{{Key: "$expr", Value: D{{Key: "$eq", Value: A{"$i32", "$i64"}}}}},
+		},
+		{
+			name:   "any element",
+			filter: `rp_sub:{name: "foo"}`,
+			want: D{{Key: "rp_sub", Value: D{{Key: "$elemMatch", Value: D{
+				{Key: "name", Value: D{{Key: "$eq", Value: "foo"}}},
+			}}}}},
+		},
+		{
+			name:   "all elements",
+			filter: `all(rp_sub, i32 > 1)`,
+			want: D{{Key: "rp_sub", Value: D{{Key: "$not", Value: D{{Key: "$elemMatch", Value: D{
+				{Key: "$nor", Value: A{D{{Key: "i32", Value: D{{Key: "$gt", Value: int64(1)}}}}}},
+			}}}}}}},
+		},
+		{
+			name:   "size",
+			filter: `size(rp_str) > 2`,
+			want: D{{Key: "$expr", Value: D{{Key: "$gt", Value: A{
+				D{{Key: "$size", Value: D{{Key: "$ifNull", Value: A{"$rp_str", A{}}}}}},
+				int64(2),
+			}}}}},
+		},
+		{
+			name:   "map size",
+			filter: `size(map_str_i32) > 2`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "field path mapping",
+			filter: `sub.name = "foo"`,
+			opts: []Option{FieldPathOpt(func(path string) (string, error) {
+				return strings.ReplaceAll(path, "sub.", "parent."), nil
+			})},
+			want: D{{Key: "parent.name", Value: D{{Key: "$eq", Value: "foo"}}}},
+		},
+		{
+			name:   "message value",
+			filter: `sub = testpb.Message{name: "foo"}`,
+			err:    ErrUnsupported,
+		},
+		{
+			name:   "presence",
+			filter: `sub:*`,
+			want:   D{{Key: "sub", Value: D{{Key: "$exists", Value: true}, {Key: "$ne", Value: nil}}}},
+		},
+		{
+			name:   "repeated presence",
+			filter: `rp_str:*`,
+			want:   D{{Key: "rp_str.0", Value: D{{Key: "$exists", Value: true}}}},
+		},
+		{
+			name:   "map presence",
+			filter: `map_str_i32:*`,
+			want:   D{{Key: "map_str_i32", Value: D{{Key: "$exists", Value: true}, {Key: "$ne", Value: D{}}}}},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			if x != nil {
+				defer x.Free()
+			}
+
+			tr, err := NewTranslator(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create translator: %v", err)
+			}
+
+			got, err := tr.Translate(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestD_Map(t *testing.T) {
+	d := D{{Key: "$or", Value: A{
+		D{{Key: "a", Value: D{{Key: "$eq", Value: 1}}}},
+	}}}
+	want := map[string]any{"$or": []any{
+		map[string]any{"a": map[string]any{"$eq": 1}},
+	}}
+	if got := d.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
+
+func TestTranslator_CaseInsensitive(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.CaseInsensitiveOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+
+	ci := func(pattern string) D {
+		return D{{Key: "$regex", Value: pattern}, {Key: "$options", Value: "i"}}
+	}
+	tc := []struct {
+		name   string
+		filter string
+		want   D
+		err    error
+	}{
+		{name: "equal", filter: `str = "F.o"`, want: D{{Key: "str", Value: ci(`^F\.o$`)}}},
+		{name: "not equal", filter: `str != "foo"`, want: D{{Key: "str", Value: D{{Key: "$not", Value: ci(`^foo$`)}}}}},
+		{name: "non string", filter: `i32 = 1`, want: D{{Key: "i32", Value: D{{Key: "$eq", Value: int64(1)}}}}},
+		{
+			name:   "in",
+			filter: `str IN ["a", "b"]`,
+			want: D{{Key: "$or", Value: A{
+				D{{Key: "str", Value: ci(`^a$`)}},
+				D{{Key: "str", Value: ci(`^b$`)}},
+			}}},
+		},
+		{name: "repeated has", filter: `rp_str:"Foo"`, want: D{{Key: "rp_str", Value: D{{Key: "$elemMatch", Value: ci(`^Foo$`)}}}}},
+		{name: "prefix search", filter: `str = "Foo*"`, want: D{{Key: "str", Value: ci(`^Foo`)}}},
+		{name: "map key presence", filter: `map_str_i32:"Key"`, err: ErrUnsupported},
+		{name: "less than", filter: `str < "foo"`, err: ErrUnsupported},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := tr.Translate(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslator_Search(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.SearchableFieldsOpt("name", "rp_str"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.ParseWithSearch(``, `fo.o`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.Translate(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := D{{Key: "$or", Value: A{
+		D{{Key: "name", Value: D{{Key: "$regex", Value: `fo\.o`}, {Key: "$options", Value: "i"}}}},
+		D{{Key: "rp_str", Value: D{{Key: "$regex", Value: `fo\.o`}, {Key: "$options", Value: "i"}}}},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
+
+func TestTranslator_MatchAll(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md, filtering.MatchAllOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := interpreter.Parse(``)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	tr, err := NewTranslator(md)
+	if err != nil {
+		t.Fatalf("failed to create translator: %v", err)
+	}
+	got, err := tr.Translate(x)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, D{}) {
+		t.Errorf("expected empty document but got %v", got)
+	}
+}
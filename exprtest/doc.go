@@ -0,0 +1,25 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exprtest provides a test kit asserting that two implementations of the filter semantics agree,
+// i.e. an in-memory evaluator and the SQL generated by a converter executed on SQLite.
+// It generates random messages and filters for a message descriptor, matches each filter with both
+// implementations, and reports the filters and messages on which the results differ.
+// The package doesn't depend on any database driver, the implementations are provided as the Matcher.
+// The SQLite-backed Matcher is provided by the separate github.com/blockysource/blocky-aip/exprtest/sqlitetest module.
+//
+// The package also runs the declarative filter test cases, the Suite, defined in JSON or YAML files.
+// Each case defines the filter text along with the expected canonical expression or the expected error code,
+// so that the conformance suites of the resources could be maintained without writing Go code per case.
+package exprtest
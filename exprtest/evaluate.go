@@ -0,0 +1,133 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// ErrUnsupported is an error returned by the Evaluate on the expressions it doesn't support.
+var ErrUnsupported = errors.New("unsupported expression")
+
+// Compile-time check to verify that the Evaluate could be used as the Matcher.
+var _ Matcher = MatcherFunc(Evaluate)
+
+// Evaluate is the reference in-memory evaluator of the filters produced by the Generator.
+// It supports the logical expressions and the comparisons of the singular scalar fields with the values,
+// and could be used as the Kit.Reference wrapped in the MatcherFunc.
+func Evaluate(x expr.FilterExpr, msgs []proto.Message) ([]bool, error) {
+	out := make([]bool, len(msgs))
+	for i, msg := range msgs {
+		var err error
+		if out[i], err = eval(x, msg.ProtoReflect()); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func eval(x expr.FilterExpr, msg protoreflect.Message) (bool, error) {
+	switch xt := x.(type) {
+	case nil, *expr.MatchAllExpr:
+		return true, nil
+	case *expr.AndExpr:
+		for _, sub := range xt.Expr {
+			if ok, err := eval(sub, msg); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *expr.OrExpr:
+		for _, sub := range xt.Expr {
+			if ok, err := eval(sub, msg); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *expr.NotExpr:
+		ok, err := eval(xt.Expr, msg)
+		return !ok, err
+	case *expr.CompositeExpr:
+		return eval(xt.Expr, msg)
+	case *expr.CompareExpr:
+		return evalCompare(xt, msg)
+	}
+	return false, fmt.Errorf("%w: %T", ErrUnsupported, x)
+}
+
+func evalCompare(x *expr.CompareExpr, msg protoreflect.Message) (bool, error) {
+	fs, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok || fs.Traversal != nil {
+		return false, fmt.Errorf("%w: comparison of %T", ErrUnsupported, x.Left)
+	}
+	ve, ok := x.Right.(*expr.ValueExpr)
+	if !ok {
+		return false, fmt.Errorf("%w: comparison with %T", ErrUnsupported, x.Right)
+	}
+	c, err := compareField(msg, fs.Field, ve.Value)
+	if err != nil {
+		return false, err
+	}
+	switch x.Comparator {
+	case expr.EQ:
+		return c == 0, nil
+	case expr.NE:
+		return c != 0, nil
+	case expr.LT:
+		return c < 0, nil
+	case expr.LE:
+		return c <= 0, nil
+	case expr.GT:
+		return c > 0, nil
+	case expr.GE:
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("%w: comparator %s", ErrUnsupported, x.Comparator)
+}
+
+// compareField compares the value of the singular scalar field with the value v.
+// The booleans are only compared for equality, thus any difference is reported as greater.
+func compareField(msg protoreflect.Message, name protoreflect.Name, v any) (int, error) {
+	fd := msg.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Cardinality() == protoreflect.Repeated {
+		return 0, fmt.Errorf("%w: field %s", ErrUnsupported, name)
+	}
+	fv := msg.Get(fd)
+	switch vt := v.(type) {
+	case int64:
+		return cmp.Compare(fv.Int(), vt), nil
+	case uint64:
+		return cmp.Compare(fv.Uint(), vt), nil
+	case float64:
+		return cmp.Compare(fv.Float(), vt), nil
+	case string:
+		return cmp.Compare(fv.String(), vt), nil
+	case protoreflect.EnumNumber:
+		return cmp.Compare(fv.Enum(), vt), nil
+	case bool:
+		if fv.Bool() == vt {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	return 0, fmt.Errorf("%w: value %T", ErrUnsupported, v)
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestEvaluate(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	msgs := []proto.Message{
+		&testpb.Message{Name: "a", I64: 1, Bool: true},
+		&testpb.Message{Name: "b", I64: 2},
+		&testpb.Message{Name: "ab", I64: 3, Bool: true},
+	}
+
+	tc := []struct {
+		filter string
+		want   []bool
+		err    error
+	}{
+		{filter: "", want: []bool{true, true, true}},
+		{filter: `name = "a"`, want: []bool{true, false, false}},
+		{filter: `name > "a"`, want: []bool{false, true, true}},
+		{filter: "i64 >= 2 AND bool = true", want: []bool{false, false, true}},
+		{filter: "i64 < 2 OR NOT bool = true", want: []bool{true, true, false}},
+		{filter: "(i64 != 2)", want: []bool{true, false, true}},
+		{filter: "rp_i32:1", err: ErrUnsupported},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer expr.FreeAll(x)
+
+			got, err := Evaluate(x, msgs)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("message %d: expected %v but got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+// ErrInvalidSuite is an error returned when the Suite is not properly defined.
+var ErrInvalidSuite = errors.New("invalid fixture suite")
+
+// Fixture is a single declarative filter test case.
+type Fixture struct {
+	// Name is the name of the case, used in the reported failures.
+	Name string `json:"name"`
+
+	// Filter is the filter text interpreted by the case.
+	Filter string `json:"filter"`
+
+	// Want is the expected canonical encoding of the interpreted expression, rendered by the expr.String.
+	// An empty Want, along with an empty Error, expects the filter to match all the messages.
+	Want string `json:"want,omitempty"`
+
+	// Error is the expected string representation of the filtering.ErrorCode, i.e. 'FIELD_NOT_FOUND'.
+	Error string `json:"error,omitempty"`
+}
+
+// Suite is a set of the fixtures defined for a single message.
+// The suites are typically maintained as JSON or YAML files, so that large conformance suites
+// don't require writing Go code per case.
+type Suite struct {
+	// Message is the full name of the message the fixtures are defined for.
+	// If set, it is verified against the descriptor the suite is checked with.
+	Message string `json:"message,omitempty"`
+
+	// Cases are the fixtures of the suite.
+	Cases []Fixture `json:"cases"`
+}
+
+// UnmarshalFn decodes the suite data into v.
+// The suite is described with the json tags, thus i.e. the sigs.k8s.io/yaml Unmarshal decodes the YAML suites.
+type UnmarshalFn func(data []byte, v any) error
+
+// LoadSuite reads the suite from r and decodes it with the unmarshal function.
+// A nil unmarshal function decodes the suite as JSON, rejecting unknown fields.
+func LoadSuite(r io.Reader, unmarshal UnmarshalFn) (*Suite, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Suite
+	if unmarshal == nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&s)
+	} else {
+		err = unmarshal(data, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSuite, err)
+	}
+	if err = s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadSuiteFile reads the suite from the file at given path, the same way as the LoadSuite.
+func LoadSuiteFile(path string, unmarshal UnmarshalFn) (*Suite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := LoadSuite(f, unmarshal)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Validate checks if the cases of the suite are well-defined.
+func (s *Suite) Validate() error {
+	for i, c := range s.Cases {
+		if c.Name == "" {
+			return fmt.Errorf("%w: case %d has no name", ErrInvalidSuite, i)
+		}
+		if c.Error == "" {
+			continue
+		}
+		if c.Want != "" {
+			return fmt.Errorf("%w: case %q expects both the expression and the error", ErrInvalidSuite, c.Name)
+		}
+		if _, ok := filtering.ParseErrorCode(c.Error); !ok {
+			return fmt.Errorf("%w: case %q expects unknown error code %q", ErrInvalidSuite, c.Name, c.Error)
+		}
+	}
+	return nil
+}
+
+// FixtureFailure is a case of the suite whose outcome differs from the expected one.
+type FixtureFailure struct {
+	// Case is the name of the failed case.
+	Case string
+
+	// Filter is the filter of the failed case.
+	Filter string
+
+	// Want is the expected outcome, either the canonical expression or the error code.
+	Want string
+
+	// Got is the actual outcome, either the canonical expression or the error.
+	Got string
+}
+
+// String returns a human-readable description of the failure.
+func (f FixtureFailure) String() string {
+	return fmt.Sprintf("case %q, filter: %q, want: %s, got: %s", f.Case, f.Filter, f.Want, f.Got)
+}
+
+// Check interprets the filters of the suite with the interpreter of the message descriptor
+// created with given options, and returns the cases with unexpected outcome.
+// An error is returned if the suite is not valid for the descriptor, or the interpreter could not be created.
+func (s *Suite) Check(md protoreflect.MessageDescriptor, opts ...filtering.Option) ([]FixtureFailure, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if md == nil {
+		return nil, fmt.Errorf("%w: message descriptor is not set", ErrInvalidSuite)
+	}
+	if s.Message != "" && protoreflect.FullName(s.Message) != md.FullName() {
+		return nil, fmt.Errorf("%w: suite is defined for %s, but checked with %s", ErrInvalidSuite, s.Message, md.FullName())
+	}
+	i, err := filtering.NewInterpreter(md, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []FixtureFailure
+	for _, c := range s.Cases {
+		want := fmt.Sprintf("%q", c.Want)
+		if c.Error != "" {
+			want = "error " + c.Error
+		}
+		got := outcome(i, c.Filter)
+		if got != want {
+			failures = append(failures, FixtureFailure{Case: c.Name, Filter: c.Filter, Want: want, Got: got})
+		}
+	}
+	return failures, nil
+}
+
+// Assert checks the suite and fails the test on any error or failed case.
+func (s *Suite) Assert(t expr.TestingT, md protoreflect.MessageDescriptor, opts ...filtering.Option) {
+	t.Helper()
+	failures, err := s.Check(md, opts...)
+	if err != nil {
+		t.Errorf("exprtest: %v", err)
+		return
+	}
+	for _, f := range failures {
+		t.Errorf("exprtest: failed %s", f)
+	}
+}
+
+// outcome interprets the filter and returns its canonical expression, quoted, or the error code.
+func outcome(i *filtering.Interpreter, filter string) string {
+	x, err := i.Parse(filter)
+	if err != nil {
+		var fe *filtering.FilterError
+		if errors.As(err, &fe) {
+			return "error " + fe.Code.String()
+		}
+		return "error " + filtering.ErrorCodeUnknown.String()
+	}
+	defer expr.FreeAll(x)
+
+	str, err := expr.String(x)
+	if err != nil {
+		return fmt.Sprintf("unrenderable expression: %v", err)
+	}
+	return fmt.Sprintf("%q", str)
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestLoadSuiteFile(t *testing.T) {
+	s, err := LoadSuiteFile("testdata/message.json", nil)
+	if err != nil {
+		t.Fatalf("failed to load suite: %v", err)
+	}
+	if len(s.Cases) != 5 {
+		t.Fatalf("expected 5 cases but got %d", len(s.Cases))
+	}
+	s.Assert(t, new(testpb.Message).ProtoReflect().Descriptor())
+}
+
+func TestLoadSuite(t *testing.T) {
+	tc := []struct {
+		name      string
+		data      string
+		unmarshal UnmarshalFn
+		err       error
+	}{
+		{
+			name: "valid",
+			data: `{"cases": [{"name": "a", "filter": "i32 = 1", "want": "i32 = 1"}]}`,
+		},
+		{
+			name: "unknown field",
+			data: `{"cases": [{"name": "a", "filter": "i32 = 1", "expected": "i32 = 1"}]}`,
+			err:  ErrInvalidSuite,
+		},
+		{
+			name: "unnamed case",
+			data: `{"cases": [{"filter": "i32 = 1"}]}`,
+			err:  ErrInvalidSuite,
+		},
+		{
+			name: "want and error",
+			data: `{"cases": [{"name": "a", "filter": "i32 = 1", "want": "i32 = 1", "error": "INVALID_VALUE"}]}`,
+			err:  ErrInvalidSuite,
+		},
+		{
+			name: "unknown error code",
+			data: `{"cases": [{"name": "a", "filter": "i32 = 1", "error": "NOT_A_CODE"}]}`,
+			err:  ErrInvalidSuite,
+		},
+		{
+			name: "custom unmarshal",
+			data: `{"cases": [{"name": "a", "filter": "i32 = 1", "expected": "i32 = 1"}]}`,
+			// The json.Unmarshal accepts the unknown fields.
+			unmarshal: json.Unmarshal,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSuite(strings.NewReader(tt.data), tt.unmarshal)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSuite_Check(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	s := &Suite{
+		Message: "testpb.Message",
+		Cases: []Fixture{
+			{Name: "pass", Filter: `i32 = 1`, Want: `i32 = 1`},
+			{Name: "wrong expr", Filter: `i32 = 1`, Want: `i32 = 2`},
+			{Name: "unexpected error", Filter: `unknown = 1`, Want: `unknown = 1`},
+			{Name: "wrong error", Filter: `unknown = 1`, Error: "INVALID_VALUE"},
+			{Name: "missing error", Filter: `i32 = 1`, Error: "INVALID_VALUE"},
+		},
+	}
+
+	failures, err := s.Check(md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []FixtureFailure{
+		{Case: "wrong expr", Filter: `i32 = 1`, Want: `"i32 = 2"`, Got: `"i32 = 1"`},
+		{Case: "unexpected error", Filter: `unknown = 1`, Want: `"unknown = 1"`, Got: "error FIELD_NOT_FOUND"},
+		{Case: "wrong error", Filter: `unknown = 1`, Want: "error INVALID_VALUE", Got: "error FIELD_NOT_FOUND"},
+		{Case: "missing error", Filter: `i32 = 1`, Want: "error INVALID_VALUE", Got: `"i32 = 1"`},
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("expected %d failures but got %d: %v", len(want), len(failures), failures)
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("expected failure %s but got %s", want[i], failures[i])
+		}
+	}
+
+	var ft fakeT
+	s.Assert(&ft, md)
+	if len(ft.errs) != len(want) {
+		t.Errorf("expected %d reported errors but got %d", len(want), len(ft.errs))
+	}
+}
+
+func TestSuite_Check_Options(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	s := &Suite{Cases: []Fixture{{Name: "match all", Filter: ``}}}
+	s.Assert(t, md, filtering.MatchAllOpt())
+
+	s.Message = "testpb.Other"
+	if _, err := s.Check(md); !errors.Is(err, ErrInvalidSuite) {
+		t.Errorf("expected error %v but got %v", ErrInvalidSuite, err)
+	}
+}
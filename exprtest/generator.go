@@ -0,0 +1,226 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// DefaultMaxDepth is the default maximum depth of the logical operators in the generated filters.
+const DefaultMaxDepth = 3
+
+// Option is an option function of the Generator.
+type Option func(g *Generator)
+
+// MaxDepthOption sets the maximum depth of the logical operators in the generated filters.
+func MaxDepthOption(depth int) Option {
+	return func(g *Generator) {
+		g.maxDepth = depth
+	}
+}
+
+// FieldsOption restricts the fields used by the generated messages and filters.
+// By default, all the singular scalar fields of the message are used.
+// The fields that are not singular scalars are ignored.
+func FieldsOption(fields ...protoreflect.Name) Option {
+	return func(g *Generator) {
+		g.names = fields
+	}
+}
+
+// Generator generates random messages and filters for a message descriptor.
+// The values of both are drawn from the same small domains, so that the filters
+// match some of the messages, and the edge cases, i.e. unset fields, are frequent.
+// The Generator is deterministic for a given seed, and is not safe for concurrent use.
+type Generator struct {
+	md       protoreflect.MessageDescriptor
+	rnd      *rand.Rand
+	maxDepth int
+	names    []protoreflect.Name
+	fields   []protoreflect.FieldDescriptor
+}
+
+// NewGenerator creates a new Generator for the message descriptor, seeded with the seed.
+func NewGenerator(md protoreflect.MessageDescriptor, seed int64, opts ...Option) *Generator {
+	g := &Generator{
+		md:       md,
+		rnd:      rand.New(rand.NewSource(seed)),
+		maxDepth: DefaultMaxDepth,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if len(g.names) > 0 {
+		for _, name := range g.names {
+			if fd := md.Fields().ByName(name); fd != nil && isScalar(fd) {
+				g.fields = append(g.fields, fd)
+			}
+		}
+	} else {
+		for i := 0; i < md.Fields().Len(); i++ {
+			if fd := md.Fields().Get(i); isScalar(fd) {
+				g.fields = append(g.fields, fd)
+			}
+		}
+	}
+	return g
+}
+
+// Fields returns the fields used by the generated messages and filters.
+func (g *Generator) Fields() []protoreflect.FieldDescriptor {
+	return g.fields
+}
+
+// Message generates a random message, with each of the generator fields either set or left unset.
+func (g *Generator) Message() proto.Message {
+	msg := dynamicpb.NewMessage(g.md)
+	for _, fd := range g.fields {
+		if g.rnd.Intn(4) == 0 {
+			continue
+		}
+		msg.Set(fd, g.value(fd))
+	}
+	return msg
+}
+
+// Filter generates a random filter over the generator fields.
+// It returns an empty filter if the generator has no fields.
+func (g *Generator) Filter() string {
+	if len(g.fields) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	g.writeFilter(&sb, g.rnd.Intn(g.maxDepth+1))
+	return sb.String()
+}
+
+func (g *Generator) writeFilter(sb *strings.Builder, depth int) {
+	if depth <= 0 {
+		g.writeRestriction(sb)
+		return
+	}
+
+	switch g.rnd.Intn(3) {
+	case 0:
+		sb.WriteString("NOT ")
+		g.writeComposite(sb, depth-1)
+	default:
+		op := " AND "
+		if g.rnd.Intn(2) == 0 {
+			op = " OR "
+		}
+		n := 2 + g.rnd.Intn(2)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				sb.WriteString(op)
+			}
+			g.writeComposite(sb, g.rnd.Intn(depth))
+		}
+	}
+}
+
+// writeComposite writes a filter, which is parenthesized unless it is a single restriction,
+// so that the precedence of the logical operators doesn't matter.
+func (g *Generator) writeComposite(sb *strings.Builder, depth int) {
+	if depth <= 0 {
+		g.writeRestriction(sb)
+		return
+	}
+	sb.WriteByte('(')
+	g.writeFilter(sb, depth)
+	sb.WriteByte(')')
+}
+
+func (g *Generator) writeRestriction(sb *strings.Builder) {
+	fd := g.fields[g.rnd.Intn(len(g.fields))]
+
+	comparators := []string{"=", "!=", "<", "<=", ">", ">="}
+	switch fd.Kind() {
+	case protoreflect.BoolKind, protoreflect.EnumKind:
+		comparators = comparators[:2]
+	}
+
+	sb.WriteString(string(fd.Name()))
+	sb.WriteByte(' ')
+	sb.WriteString(comparators[g.rnd.Intn(len(comparators))])
+	sb.WriteByte(' ')
+	sb.WriteString(g.literal(fd))
+}
+
+// literal returns a random value of the field formatted as a filter literal.
+func (g *Generator) literal(fd protoreflect.FieldDescriptor) string {
+	v := g.value(fd)
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return strconv.Quote(v.String())
+	case protoreflect.EnumKind:
+		return strconv.Quote(string(fd.Enum().Values().ByNumber(v.Enum()).Name()))
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return v.String()
+	}
+}
+
+var (
+	stringDomain = []string{"", "a", "ab", "b"}
+	floatDomain  = []float64{-1.5, 0, 1.5, 2}
+)
+
+// value returns a random value of the field, drawn from the small domain of its kind.
+func (g *Generator) value(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(g.rnd.Intn(2) == 0)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(stringDomain[g.rnd.Intn(len(stringDomain))])
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(g.rnd.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(g.rnd.Intn(5) - 2))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(g.rnd.Intn(5) - 2))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(g.rnd.Intn(4)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(g.rnd.Intn(4)))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(floatDomain[g.rnd.Intn(len(floatDomain))]))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(floatDomain[g.rnd.Intn(len(floatDomain))])
+	default:
+		return fd.Default()
+	}
+}
+
+// isScalar checks if the field is a singular scalar field supported by the Generator.
+func isScalar(fd protoreflect.FieldDescriptor) bool {
+	if fd.Cardinality() == protoreflect.Repeated {
+		return false
+	}
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind, protoreflect.BytesKind:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+const (
+	// DefaultFilters is the default number of the filters generated by the Kit.
+	DefaultFilters = 200
+
+	// DefaultMessages is the default number of the messages generated by the Kit.
+	DefaultMessages = 50
+
+	// maxReportedDrifts is the maximum number of drifts reported by the Kit.Assert.
+	maxReportedDrifts = 10
+)
+
+// ErrInvalidKit is an error returned when the Kit is not properly configured.
+var ErrInvalidKit = errors.New("invalid kit")
+
+// Matcher matches the filter expression against the messages.
+// It returns the match result for each of the messages, in the order of the messages.
+// An implementation backed by a database, i.e. SQLite, is expected to insert the messages,
+// execute the query translated from the expression, and report which messages were returned.
+type Matcher interface {
+	Match(x expr.FilterExpr, msgs []proto.Message) ([]bool, error)
+}
+
+// MatcherFunc is a function implementing the Matcher.
+type MatcherFunc func(x expr.FilterExpr, msgs []proto.Message) ([]bool, error)
+
+// Match implements the Matcher interface.
+func (f MatcherFunc) Match(x expr.FilterExpr, msgs []proto.Message) ([]bool, error) {
+	return f(x, msgs)
+}
+
+// Drift is a semantic difference between the reference and the candidate Matcher.
+type Drift struct {
+	// Filter is the filter on which the matchers disagree.
+	Filter string
+
+	// Message is the message on which the matchers disagree.
+	Message proto.Message
+
+	// Want is the result of the reference matcher.
+	Want bool
+
+	// Got is the result of the candidate matcher.
+	Got bool
+}
+
+// String returns a human-readable description of the drift.
+func (d Drift) String() string {
+	return fmt.Sprintf("filter: %q, message: {%s}, want: %v, got: %v",
+		d.Filter, prototext.MarshalOptions{}.Format(d.Message), d.Want, d.Got)
+}
+
+// Kit asserts that the candidate Matcher agrees with the reference Matcher
+// Typically, the Reference is the in-memory Evaluate, and the Candidate executes
+// Typically, the Reference is an in-memory evaluator, and the Candidate executes
+// the SQL generated by a converter on SQLite, so that the semantic drift of the converter is caught.
+type Kit struct {
+	// Interpreter parses the generated filters.
+	// It must be created for the same message descriptor as the Generator.
+	Interpreter *filtering.Interpreter
+
+	// Generator generates the filters and messages.
+	Generator *Generator
+
+	// Reference is the matcher defining the expected results.
+	Reference Matcher
+
+	// Candidate is the matcher checked against the Reference.
+	Candidate Matcher
+
+	// Filters is the number of the generated filters, DefaultFilters if zero.
+	Filters int
+
+	// Messages is the number of the generated messages, DefaultMessages if zero.
+	Messages int
+}
+
+// Run generates the messages and filters, matches each filter with both matchers,
+// and returns the drifts between them.
+// An error is returned if a filter could not be parsed or matched.
+func (k *Kit) Run() ([]Drift, error) {
+	if k.Interpreter == nil || k.Generator == nil || k.Reference == nil || k.Candidate == nil {
+		return nil, fmt.Errorf("%w: the interpreter, generator and both matchers are required", ErrInvalidKit)
+	}
+	nf, nm := k.Filters, k.Messages
+	if nf == 0 {
+		nf = DefaultFilters
+	}
+	if nm == 0 {
+		nm = DefaultMessages
+	}
+
+	msgs := make([]proto.Message, nm)
+	for i := range msgs {
+		msgs[i] = k.Generator.Message()
+	}
+
+	var drifts []Drift
+	for i := 0; i < nf; i++ {
+		filter := k.Generator.Filter()
+		d, err := k.check(filter, msgs)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, d...)
+	}
+	return drifts, nil
+}
+
+func (k *Kit) check(filter string, msgs []proto.Message) ([]Drift, error) {
+	x, err := k.Interpreter.Parse(filter)
+	if err != nil {
+		return nil, fmt.Errorf("parsing filter %q failed: %w", filter, err)
+	}
+	defer expr.FreeAll(x)
+
+	want, err := k.Reference.Match(x, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("reference matcher failed on filter %q: %w", filter, err)
+	}
+	got, err := k.Candidate.Match(x, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("candidate matcher failed on filter %q: %w", filter, err)
+	}
+	if len(want) != len(msgs) || len(got) != len(msgs) {
+		return nil, fmt.Errorf("%w: matchers returned %d and %d results for %d messages", ErrInvalidKit, len(want), len(got), len(msgs))
+	}
+
+	var drifts []Drift
+	for i, msg := range msgs {
+		if want[i] != got[i] {
+			drifts = append(drifts, Drift{Filter: filter, Message: msg, Want: want[i], Got: got[i]})
+		}
+	}
+	return drifts, nil
+}
+
+// Assert runs the Kit and fails the test on any error or drift between the matchers.
+func (k *Kit) Assert(t expr.TestingT) {
+	t.Helper()
+	drifts, err := k.Run()
+	if err != nil {
+		t.Errorf("exprtest: %v", err)
+		return
+	}
+	for i, d := range drifts {
+		if i == maxReportedDrifts {
+			t.Errorf("exprtest: %d more drifts not reported", len(drifts)-i)
+			break
+		}
+		t.Errorf("exprtest: drift %s", d)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+var testFields = []protoreflect.Name{"name", "i32", "i64", "u32", "u64", "sf64", "bool", "double", "float", "enum"}
+
+type fakeT struct {
+	errs []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(func()) {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func newTestKit(t *testing.T, candidate Matcher) *Kit {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	return &Kit{
+		Interpreter: interpreter,
+		Generator:   NewGenerator(md, 1, FieldsOption(testFields...)),
+		Reference:   MatcherFunc(Evaluate),
+		Candidate:   candidate,
+	}
+}
+
+func TestKit_Assert(t *testing.T) {
+	newTestKit(t, MatcherFunc(Evaluate)).Assert(t)
+}
+
+func TestKit_Drift(t *testing.T) {
+	// The candidate treats the NOT EQUAL as EQUAL.
+	broken := func(x expr.FilterExpr, msgs []proto.Message) ([]bool, error) {
+		c := expr.Clone(x)
+		defer expr.FreeAll(c)
+		breakNE(c)
+		return Evaluate(c, msgs)
+	}
+
+	k := newTestKit(t, MatcherFunc(broken))
+	drifts, err := k.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(drifts) == 0 {
+		t.Fatal("expected drifts but got none")
+	}
+	if !strings.Contains(drifts[0].Filter, "!=") {
+		t.Errorf("expected drift on a not equal filter but got: %s", drifts[0])
+	}
+
+	ft := &fakeT{}
+	k.Assert(ft)
+	if len(ft.errs) != maxReportedDrifts+1 {
+		t.Errorf("expected %d reported errors but got %d", maxReportedDrifts+1, len(ft.errs))
+	}
+}
+
+func TestKit_Invalid(t *testing.T) {
+	ft := &fakeT{}
+	(&Kit{}).Assert(ft)
+	if len(ft.errs) != 1 || !strings.Contains(ft.errs[0], ErrInvalidKit.Error()) {
+		t.Errorf("expected invalid kit error but got: %v", ft.errs)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	a := NewGenerator(md, 7, FieldsOption(testFields...), MaxDepthOption(4))
+	b := NewGenerator(md, 7, FieldsOption(testFields...), MaxDepthOption(4))
+	if len(a.Fields()) != len(testFields) {
+		t.Fatalf("expected %d fields but got %d", len(testFields), len(a.Fields()))
+	}
+	for i := 0; i < 100; i++ {
+		fa, fb := a.Filter(), b.Filter()
+		if fa != fb {
+			t.Fatalf("expected deterministic filters but got %q and %q", fa, fb)
+		}
+		x, err := interpreter.Parse(fa)
+		if err != nil {
+			t.Fatalf("generated filter %q is invalid: %v", fa, err)
+		}
+		x.Free()
+
+		if !proto.Equal(a.Message(), b.Message()) {
+			t.Fatal("expected deterministic messages")
+		}
+	}
+}
+
+// breakNE replaces the NOT EQUAL comparisons of the expression with the EQUAL.
+func breakNE(x expr.FilterExpr) {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		for _, sub := range xt.Expr {
+			breakNE(sub)
+		}
+	case *expr.OrExpr:
+		for _, sub := range xt.Expr {
+			breakNE(sub)
+		}
+	case *expr.NotExpr:
+		breakNE(xt.Expr)
+	case *expr.CompositeExpr:
+		breakNE(xt.Expr)
+	case *expr.CompareExpr:
+		if xt.Comparator == expr.NE {
+			xt.Comparator = expr.EQ
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlitetest provides the exprtest.Matcher backed by the SQLite database.
+// The Matcher inserts the messages into an in-memory table, with a column per singular scalar field,
+// and selects the ones matching the SQL generated from the filter expression with the Where function.
+// It is a separate module, so that the blocky-aip module doesn't depend on the database driver.
+package sqlitetest
@@ -0,0 +1,171 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitetest
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	_ "modernc.org/sqlite"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/exprtest"
+)
+
+// tableName is the name of the table the messages are inserted into.
+const tableName = "messages"
+
+// rowIDColumn is the column of the message index.
+const rowIDColumn = "_row"
+
+var _ exprtest.Matcher = (*Matcher)(nil)
+
+// Matcher is the exprtest.Matcher executing the filters on the SQLite database.
+// Each call of the Match replaces the content of the table with the provided messages.
+// The Matcher is not safe for concurrent use.
+type Matcher struct {
+	db     *sql.DB
+	fields []protoreflect.FieldDescriptor
+}
+
+// NewMatcher creates a new Matcher backed by the in-memory SQLite database,
+// with the table of the singular scalar fields of the message descriptor.
+// The Matcher needs to be closed after use.
+func NewMatcher(md protoreflect.MessageDescriptor) (*Matcher, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Each connection to the in-memory database has its own database.
+	db.SetMaxOpenConns(1)
+
+	m := &Matcher{db: db}
+	columns := []string{quoteIdent(rowIDColumn) + " INTEGER PRIMARY KEY"}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		typ, ok := columnType(fd)
+		if !ok {
+			continue
+		}
+		m.fields = append(m.fields, fd)
+		columns = append(columns, quoteIdent(string(fd.Name()))+" "+typ)
+	}
+
+	if _, err = db.Exec("CREATE TABLE " + tableName + " (" + strings.Join(columns, ", ") + ")"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close closes the underlying database.
+func (m *Matcher) Close() error {
+	return m.db.Close()
+}
+
+// Match implements the exprtest.Matcher interface.
+// It inserts the messages into the table and selects the ones matching the SQL translated from the expression.
+func (m *Matcher) Match(x expr.FilterExpr, msgs []proto.Message) ([]bool, error) {
+	where, args, err := Where(x)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.insert(msgs); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query("SELECT "+quoteIdent(rowIDColumn)+" FROM "+tableName+" WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %q failed: %w", where, err)
+	}
+	defer rows.Close()
+
+	out := make([]bool, len(msgs))
+	for rows.Next() {
+		var i int
+		if err = rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		out[i] = true
+	}
+	return out, rows.Err()
+}
+
+// insert replaces the rows of the table with the messages.
+func (m *Matcher) insert(msgs []proto.Message) (err error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM " + tableName); err != nil {
+		return err
+	}
+
+	columns := []string{quoteIdent(rowIDColumn)}
+	for _, fd := range m.fields {
+		columns = append(columns, quoteIdent(string(fd.Name())))
+	}
+	stmt, err := tx.Prepare("INSERT INTO " + tableName + " (" + strings.Join(columns, ", ") +
+		") VALUES (?" + strings.Repeat(", ?", len(m.fields)) + ")")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]any, len(m.fields)+1)
+	for i, msg := range msgs {
+		pm := msg.ProtoReflect()
+		args[0] = i
+		for j, fd := range m.fields {
+			if args[j+1], err = sqlValue(pm.Get(fd).Interface()); err != nil {
+				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			}
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// columnType returns the SQLite column type of the singular scalar field.
+func columnType(fd protoreflect.FieldDescriptor) (string, bool) {
+	if fd.Cardinality() == protoreflect.Repeated {
+		return "", false
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind, protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "INTEGER", true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "REAL", true
+	case protoreflect.StringKind:
+		return "TEXT", true
+	}
+	return "", false
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitetest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/exprtest"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+var testFields = []protoreflect.Name{"name", "i32", "i64", "u32", "u64", "sf64", "bool", "double", "float", "enum"}
+
+func newTestMatcher(t *testing.T) *Matcher {
+	m, err := NewMatcher(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create matcher: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMatcher_Kit(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	for _, seed := range []int64{1, 2, 3} {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			k := &exprtest.Kit{
+				Interpreter: interpreter,
+				Generator:   exprtest.NewGenerator(md, seed, exprtest.FieldsOption(testFields...)),
+				Reference:   exprtest.MatcherFunc(exprtest.Evaluate),
+				Candidate:   newTestMatcher(t),
+			}
+			k.Assert(t)
+		})
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	m := newTestMatcher(t)
+
+	msgs := []proto.Message{
+		&testpb.Message{Name: "a", I64: 1, Bool: true},
+		&testpb.Message{Name: "b", I64: 2},
+		&testpb.Message{Name: "ab", I64: 3, Bool: true},
+	}
+
+	tc := []struct {
+		filter string
+		want   []bool
+	}{
+		{filter: "", want: []bool{true, true, true}},
+		{filter: `name = "a"`, want: []bool{true, false, false}},
+		{filter: `name > "a"`, want: []bool{false, true, true}},
+		{filter: "i64 >= 2 AND bool = true", want: []bool{false, false, true}},
+		{filter: "i64 < 2 OR NOT bool = true", want: []bool{true, true, false}},
+		{filter: "(i64 != 2)", want: []bool{true, false, true}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer expr.FreeAll(x)
+
+			got, err := m.Match(x, msgs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("message %d: expected %v but got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWhere(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		where  string
+		args   []any
+		err    error
+	}{
+		{filter: "", where: "1"},
+		{filter: "i64 > 2", where: `"i64" > ?`, args: []any{int64(2)}},
+		{filter: `name = "a" AND bool != true`, where: `("name" = ? AND "bool" <> ?)`, args: []any{"a", int64(1)}},
+		{filter: "NOT (u32 <= 1 OR double < 1.5)", where: `NOT ((("u32" <= ? OR "double" < ?)))`, args: []any{int64(1), 1.5}},
+		{filter: "u64 = 18446744073709551615", err: ErrUnsupported},
+		{filter: "rp_i32:1", err: ErrUnsupported},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer expr.FreeAll(x)
+
+			where, args, err := Where(x)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if where != tt.where {
+				t.Errorf("expected where %q but got %q", tt.where, where)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.args) {
+				t.Errorf("expected args %v but got %v", tt.args, args)
+			}
+		})
+	}
+}
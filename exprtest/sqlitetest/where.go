@@ -0,0 +1,157 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlitetest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// ErrUnsupported is an error returned when the expression could not be translated into SQL.
+var ErrUnsupported = errors.New("unsupported expression")
+
+// Where translates the filter expression into the SQL condition of the WHERE clause, with the positional '?' arguments.
+// It supports the logical expressions of the comparisons of the top level fields with the values,
+// i.e. the filters of the exprtest.Generator. A nil expression results in the condition matching all the rows.
+func Where(x expr.FilterExpr) (string, []any, error) {
+	var (
+		sb   strings.Builder
+		args []any
+	)
+	if err := writeWhere(&sb, &args, x); err != nil {
+		return "", nil, err
+	}
+	return sb.String(), args, nil
+}
+
+func writeWhere(sb *strings.Builder, args *[]any, x expr.FilterExpr) error {
+	switch xt := x.(type) {
+	case nil, *expr.MatchAllExpr:
+		sb.WriteString("1")
+	case *expr.AndExpr:
+		return writeJoined(sb, args, " AND ", xt.Expr)
+	case *expr.OrExpr:
+		return writeJoined(sb, args, " OR ", xt.Expr)
+	case *expr.NotExpr:
+		sb.WriteString("NOT (")
+		if err := writeWhere(sb, args, xt.Expr); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+	case *expr.CompositeExpr:
+		sb.WriteByte('(')
+		if err := writeWhere(sb, args, xt.Expr); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+	case *expr.CompareExpr:
+		return writeCompare(sb, args, xt)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupported, x)
+	}
+	return nil
+}
+
+func writeJoined(sb *strings.Builder, args *[]any, op string, xs []expr.FilterExpr) error {
+	sb.WriteByte('(')
+	for i, sub := range xs {
+		if i > 0 {
+			sb.WriteString(op)
+		}
+		if err := writeWhere(sb, args, sub); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte(')')
+	return nil
+}
+
+func writeCompare(sb *strings.Builder, args *[]any, x *expr.CompareExpr) error {
+	fs, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok || fs.Traversal != nil {
+		return fmt.Errorf("%w: the left hand side is not a top level field: %T", ErrUnsupported, x.Left)
+	}
+	ve, ok := x.Right.(*expr.ValueExpr)
+	if !ok {
+		return fmt.Errorf("%w: the right hand side is not a value: %T", ErrUnsupported, x.Right)
+	}
+
+	var op string
+	switch x.Comparator {
+	case expr.EQ:
+		op = "="
+	case expr.NE:
+		op = "<>"
+	case expr.LT:
+		op = "<"
+	case expr.LE:
+		op = "<="
+	case expr.GT:
+		op = ">"
+	case expr.GE:
+		op = ">="
+	default:
+		return fmt.Errorf("%w: comparator %s", ErrUnsupported, x.Comparator)
+	}
+
+	v, err := sqlValue(ve.Value)
+	if err != nil {
+		return err
+	}
+	sb.WriteString(quoteIdent(string(fs.Field)))
+	sb.WriteByte(' ')
+	sb.WriteString(op)
+	sb.WriteString(" ?")
+	*args = append(*args, v)
+	return nil
+}
+
+// sqlValue converts the value of the expression or the message field into the SQLite value.
+func sqlValue(v any) (any, error) {
+	switch vt := v.(type) {
+	case bool:
+		if vt {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case int64, float64, string:
+		return vt, nil
+	case int32:
+		return int64(vt), nil
+	case uint32:
+		return int64(vt), nil
+	case float32:
+		return float64(vt), nil
+	case uint64:
+		if vt > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: uint64 value %d overflows the SQLite integer", ErrUnsupported, vt)
+		}
+		return int64(vt), nil
+	case protoreflect.EnumNumber:
+		return int64(vt), nil
+	}
+	return nil, fmt.Errorf("%w: value of %T type", ErrUnsupported, v)
+}
+
+// quoteIdent quotes the SQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
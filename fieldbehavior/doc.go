@@ -0,0 +1,24 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fieldbehavior provides the utilities of the request messages driven by the google.api.field_behavior
+// annotations (AIP-203), i.e. of the create, update, delete (AIP-135) and custom (AIP-136) methods:
+//
+//   - ValidateRequired reports the REQUIRED fields which are not set,
+//   - ClearOutputOnly clears the OUTPUT_ONLY fields, which the service needs to ignore on input,
+//   - CensorImmutable removes the IMMUTABLE fields from the update, along with their update mask paths.
+//
+// The annotations are read the same way as by the filtering and fieldmask packages,
+// including the descriptors loaded at runtime.
+package fieldbehavior
@@ -0,0 +1,223 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldbehavior
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// ErrRequiredField is matched by the errors of the REQUIRED fields which are not set.
+var ErrRequiredField = errors.New("required field is not set")
+
+// RequiredFieldsError is an error returned by the ValidateRequired, with the paths of all the REQUIRED fields not set.
+type RequiredFieldsError struct {
+	// Paths are the paths of the fields not set, i.e.: "book.title" or "items[1].sku".
+	Paths []string
+}
+
+// Error returns the string representation of the error.
+func (e *RequiredFieldsError) Error() string {
+	return "required fields are not set: " + strings.Join(e.Paths, ", ")
+}
+
+// Unwrap returns the ErrRequiredField.
+func (e *RequiredFieldsError) Unwrap() error {
+	return ErrRequiredField
+}
+
+// Has checks if the field is annotated with the field behavior.
+func Has(fd protoreflect.FieldDescriptor, behavior annotations.FieldBehavior) bool {
+	fb, ok := info.FieldBehaviors(fd)
+	if !ok {
+		return false
+	}
+	for _, b := range fb {
+		if b == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRequired checks if all the REQUIRED fields of the message are set, including the fields of the nested messages.
+// The scalar fields are set if they have a non-zero value, the repeated and map fields if they are not empty.
+// It returns the *RequiredFieldsError with the paths of all the fields which are not set.
+func ValidateRequired(msg proto.Message) error {
+	var paths []string
+	validateRequired(msg.ProtoReflect(), "", &paths)
+	if len(paths) > 0 {
+		return &RequiredFieldsError{Paths: paths}
+	}
+	return nil
+}
+
+func validateRequired(m protoreflect.Message, prefix string, paths *[]string) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := fieldPath(prefix, fd)
+		if !m.Has(fd) {
+			if Has(fd, annotations.FieldBehavior_REQUIRED) {
+				*paths = append(*paths, path)
+			}
+			continue
+		}
+		rangeMessages(m, fd, path, func(nm protoreflect.Message, np string) {
+			validateRequired(nm, np, paths)
+		})
+	}
+}
+
+// ClearOutputOnly clears the OUTPUT_ONLY fields of the message, including the fields of the nested messages.
+// The service should ignore these fields on input, i.e. of the resource within the create or update request.
+func ClearOutputOnly(msg proto.Message) {
+	clearBehavior(msg.ProtoReflect(), annotations.FieldBehavior_OUTPUT_ONLY, true)
+}
+
+// clearBehavior clears the fields annotated with the behavior. If deep is true, it clears the fields
+// of the messages within the repeated and map fields as well, otherwise only of the singular messages.
+func clearBehavior(m protoreflect.Message, behavior annotations.FieldBehavior, deep bool) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		if Has(fd, behavior) {
+			m.Clear(fd)
+			continue
+		}
+		if !deep && (fd.IsList() || fd.IsMap()) {
+			continue
+		}
+		rangeMessages(m, fd, "", func(nm protoreflect.Message, _ string) {
+			clearBehavior(nm, behavior, deep)
+		})
+	}
+}
+
+// CensorImmutable removes the IMMUTABLE fields from the update of the message with the mask.
+// It clears the immutable fields selected by the mask paths, and returns the mask without these paths.
+// The '*' full replacement mask is expanded into the paths of the message fields, which are not immutable.
+// With an empty mask, all the immutable fields of the message and its singular nested messages are cleared.
+// The immutable fields nested in the messages replaced as a whole by a path are not censored,
+// so that the update does not reset them; use the fieldmask.Parser Diff to compare such messages.
+// The input mask is not modified. An error is returned if a mask path does not select a message field.
+func CensorImmutable(msg proto.Message, mask *fieldmaskpb.FieldMask) (*fieldmaskpb.FieldMask, error) {
+	m := msg.ProtoReflect()
+	if len(mask.GetPaths()) == 0 {
+		clearBehavior(m, annotations.FieldBehavior_IMMUTABLE, false)
+		return mask, nil
+	}
+
+	censored := &fieldmaskpb.FieldMask{}
+	for _, path := range mask.GetPaths() {
+		if path == "*" {
+			fields := m.Descriptor().Fields()
+			for i := 0; i < fields.Len(); i++ {
+				fd := fields.Get(i)
+				if Has(fd, annotations.FieldBehavior_IMMUTABLE) {
+					m.Clear(fd)
+					continue
+				}
+				censored.Paths = append(censored.Paths, string(fd.Name()))
+			}
+			continue
+		}
+
+		immutable, err := censorPath(m, path)
+		if err != nil {
+			return nil, err
+		}
+		if !immutable {
+			censored.Paths = append(censored.Paths, path)
+		}
+	}
+	return censored, nil
+}
+
+// censorPath checks if the path selects an immutable field, or a field nested in one.
+// If so, the immutable field is cleared.
+func censorPath(m protoreflect.Message, path string) (bool, error) {
+	md := m.Descriptor()
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return false, fmt.Errorf("field mask path %q: field %q not found in the message %s", path, name, md.FullName())
+		}
+		if Has(fd, annotations.FieldBehavior_IMMUTABLE) {
+			if m != nil && m.Has(fd) {
+				m.Clear(fd)
+			}
+			return true, nil
+		}
+		if i == len(names)-1 || fd.IsMap() {
+			// The remaining segment of a map field is its key.
+			return false, nil
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() {
+			return false, fmt.Errorf("field mask path %q: field %q cannot be traversed", path, name)
+		}
+		md = fd.Message()
+		if m != nil && m.Has(fd) {
+			m = m.Get(fd).Message()
+		} else {
+			m = nil
+		}
+	}
+	return false, nil
+}
+
+// rangeMessages calls fn for each message value of the set field, i.e. the singular message,
+// the elements of the repeated message field and the values of the message map field.
+func rangeMessages(m protoreflect.Message, fd protoreflect.FieldDescriptor, path string, fn func(protoreflect.Message, string)) {
+	switch {
+	case fd.IsMap():
+		if fd.MapValue().Kind() != protoreflect.MessageKind {
+			return
+		}
+		m.Get(fd).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			fn(v.Message(), fmt.Sprintf("%s[%q]", path, k.String()))
+			return true
+		})
+	case fd.IsList():
+		if fd.Kind() != protoreflect.MessageKind {
+			return
+		}
+		l := m.Get(fd).List()
+		for i := 0; i < l.Len(); i++ {
+			fn(l.Get(i).Message(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case fd.Kind() == protoreflect.MessageKind:
+		fn(m.Get(fd).Message(), path)
+	}
+}
+
+func fieldPath(prefix string, fd protoreflect.FieldDescriptor) string {
+	if prefix == "" {
+		return string(fd.Name())
+	}
+	return prefix + "." + string(fd.Name())
+}
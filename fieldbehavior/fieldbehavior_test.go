@@ -0,0 +1,209 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldbehavior
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestValidateRequired(t *testing.T) {
+	md := testBookMessage(t)
+
+	b := newBook(md, "t1", "ct", "isbn")
+	author := b.Mutable(md.Fields().ByName("author")).Message()
+	author.Set(md.Fields().ByName("create_time"), protoreflect.ValueOfString("ct"))
+	editions := b.Mutable(md.Fields().ByName("editions")).List()
+	editions.Append(protoreflect.ValueOfMessage(newBook(md, "t2", "", "")))
+	editions.Append(protoreflect.ValueOfMessage(newBook(md, "", "", "")))
+
+	err := ValidateRequired(b.Interface())
+	if !errors.Is(err, ErrRequiredField) {
+		t.Fatalf("expected error %v but got %v", ErrRequiredField, err)
+	}
+	var rfe *RequiredFieldsError
+	if !errors.As(err, &rfe) {
+		t.Fatalf("expected required fields error but got %T", err)
+	}
+	want := []string{"author.title", "editions[1].title"}
+	if !reflect.DeepEqual(rfe.Paths, want) {
+		t.Fatalf("expected paths %v but got %v", want, rfe.Paths)
+	}
+
+	if err = ValidateRequired(newBook(md, "t1", "", "").Interface()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestClearOutputOnly(t *testing.T) {
+	md := testBookMessage(t)
+	createTime := md.Fields().ByName("create_time")
+
+	b := newBook(md, "t1", "ct", "isbn")
+	b.Set(md.Fields().ByName("author"), protoreflect.ValueOfMessage(newBook(md, "t2", "ct", "")))
+	b.Mutable(md.Fields().ByName("editions")).List().Append(protoreflect.ValueOfMessage(newBook(md, "t3", "ct", "")))
+
+	ClearOutputOnly(b.Interface())
+
+	if b.Has(createTime) {
+		t.Errorf("expected create_time to be cleared")
+	}
+	if b.Get(md.Fields().ByName("author")).Message().Has(createTime) {
+		t.Errorf("expected author.create_time to be cleared")
+	}
+	if b.Get(md.Fields().ByName("editions")).List().Get(0).Message().Has(createTime) {
+		t.Errorf("expected editions[0].create_time to be cleared")
+	}
+	if b.Get(md.Fields().ByName("title")).String() != "t1" || b.Get(md.Fields().ByName("isbn")).String() != "isbn" {
+		t.Errorf("expected other fields to be kept")
+	}
+}
+
+func TestCensorImmutable(t *testing.T) {
+	md := testBookMessage(t)
+	isbn := md.Fields().ByName("isbn")
+
+	tc := []struct {
+		name  string
+		mask  []string
+		want  []string
+		isErr bool
+	}{
+		{name: "immutable path", mask: []string{"title", "isbn"}, want: []string{"title"}},
+		{name: "nested immutable path", mask: []string{"author.isbn", "author.title"}, want: []string{"author.title"}},
+		{name: "full replacement", mask: []string{"*"}, want: []string{"title", "create_time", "author", "editions"}},
+		{name: "unknown path", mask: []string{"unknown"}, isErr: true},
+		{name: "repeated path", mask: []string{"editions.title"}, isErr: true},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBook(md, "t1", "", "isbn")
+			b.Set(md.Fields().ByName("author"), protoreflect.ValueOfMessage(newBook(md, "t2", "", "isbn2")))
+
+			mask := &fieldmaskpb.FieldMask{Paths: tt.mask}
+			got, err := CensorImmutable(b.Interface(), mask)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.GetPaths(), tt.want) {
+				t.Fatalf("expected paths %v but got %v", tt.want, got.GetPaths())
+			}
+			if !reflect.DeepEqual(mask.GetPaths(), tt.mask) {
+				t.Fatalf("expected input mask not to be modified")
+			}
+			for _, p := range tt.mask {
+				switch p {
+				case "isbn", "*":
+					if b.Has(isbn) {
+						t.Errorf("expected isbn to be cleared")
+					}
+				case "author.isbn":
+					if b.Get(md.Fields().ByName("author")).Message().Has(isbn) {
+						t.Errorf("expected author.isbn to be cleared")
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("empty mask", func(t *testing.T) {
+		b := newBook(md, "t1", "", "isbn")
+		b.Set(md.Fields().ByName("author"), protoreflect.ValueOfMessage(newBook(md, "t2", "", "isbn2")))
+
+		if _, err := CensorImmutable(b.Interface(), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.Has(isbn) || b.Get(md.Fields().ByName("author")).Message().Has(isbn) {
+			t.Fatalf("expected isbn fields to be cleared")
+		}
+	})
+}
+
+func newBook(md protoreflect.MessageDescriptor, title, createTime, isbn string) *dynamicpb.Message {
+	b := dynamicpb.NewMessage(md)
+	for name, v := range map[protoreflect.Name]string{"title": title, "create_time": createTime, "isbn": isbn} {
+		if v != "" {
+			b.Set(md.Fields().ByName(name), protoreflect.ValueOfString(v))
+		}
+	}
+	return b
+}
+
+func testBookMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	behavior := func(b ...annotations.FieldBehavior) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, annotations.E_FieldBehavior, b)
+		return opts
+	}
+	field := func(name string, num int32, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+			Options:  opts,
+		}
+	}
+	book := func(name string, num int32, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".testfieldbehavior.Book"),
+			JsonName: proto.String(name),
+		}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testfieldbehavior/book.proto"),
+		Package:    proto.String("testfieldbehavior"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/api/field_behavior.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("title", 1, behavior(annotations.FieldBehavior_REQUIRED)),
+				field("create_time", 2, behavior(annotations.FieldBehavior_OUTPUT_ONLY)),
+				field("isbn", 3, behavior(annotations.FieldBehavior_IMMUTABLE)),
+				book("author", 4, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				book("editions", 5, descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
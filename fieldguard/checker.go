@@ -0,0 +1,345 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldguard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ErrViolation is the error matched by each of the governance violations.
+var ErrViolation = errors.New("field governance violation")
+
+// Rule is a governance rule broken by a Violation.
+type Rule int
+
+const (
+	// RuleInputOnlyFiltered is broken by a filter that references an INPUT_ONLY field.
+	RuleInputOnlyFiltered Rule = iota
+	// RuleFilteringForbidden is broken by a filter that references a FORBID_FILTERING field.
+	RuleFilteringForbidden
+	// RuleOutputOnlyUpdated is broken by an update mask that touches an OUTPUT_ONLY field.
+	RuleOutputOnlyUpdated
+	// RuleImmutableUpdated is broken by an update mask that touches an IMMUTABLE field.
+	RuleImmutableUpdated
+)
+
+var _RuleStrings = [...]string{
+	RuleInputOnlyFiltered:  "INPUT_ONLY_FILTERED",
+	RuleFilteringForbidden: "FILTERING_FORBIDDEN",
+	RuleOutputOnlyUpdated:  "OUTPUT_ONLY_UPDATED",
+	RuleImmutableUpdated:   "IMMUTABLE_UPDATED",
+}
+
+// String returns the string representation of the rule.
+func (r Rule) String() string {
+	if r < 0 || int(r) >= len(_RuleStrings) {
+		return fmt.Sprintf("Rule(%d)", r)
+	}
+	return _RuleStrings[r]
+}
+
+// Violation is a single reference of a governed field.
+type Violation struct {
+	// Rule is the broken rule.
+	Rule Rule
+
+	// Path is the field path within the filter or the update mask, i.e.: "sub.secret".
+	Path string
+
+	// Field is the full name of the governed field.
+	Field protoreflect.FullName
+}
+
+// Error returns the string representation of the violation.
+func (v *Violation) Error() string {
+	switch v.Rule {
+	case RuleInputOnlyFiltered:
+		return fmt.Sprintf("field %q is input only and cannot be filtered", v.Path)
+	case RuleFilteringForbidden:
+		return fmt.Sprintf("field %q forbids filtering", v.Path)
+	case RuleOutputOnlyUpdated:
+		return fmt.Sprintf("field %q is output only and cannot be updated", v.Path)
+	case RuleImmutableUpdated:
+		return fmt.Sprintf("field %q is immutable and cannot be updated", v.Path)
+	}
+	return fmt.Sprintf("field %q breaks the rule %s", v.Path, v.Rule)
+}
+
+// Unwrap returns the ErrViolation.
+func (v *Violation) Unwrap() error {
+	return ErrViolation
+}
+
+// Error is an error returned by the Checker, which contains all the violations found.
+type Error struct {
+	Violations []*Violation
+}
+
+// Error returns the string representation of all the violations.
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString("field governance violated: ")
+	for i, v := range e.Violations {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(v.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the violations as the errors.
+func (e *Error) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// Checker checks the filters and update masks of a message against the field governance rules.
+// A Checker is safe for concurrent use.
+type Checker struct {
+	desc    protoreflect.MessageDescriptor
+	msgInfo info.MessagesInfo
+}
+
+// NewChecker creates a new Checker of the message desc.
+func NewChecker(desc protoreflect.MessageDescriptor) (*Checker, error) {
+	if desc == nil {
+		return nil, errors.New("message descriptor is not set")
+	}
+	return &Checker{desc: desc, msgInfo: info.MapMsgInfo(desc)}, nil
+}
+
+// CheckFilter checks that the filter expression doesn't reference any INPUT_ONLY or FORBID_FILTERING field,
+// including the fields within the any element expressions and the function call arguments.
+// A nil expression is valid.
+// The fields not found in the message are skipped, as their validation is the concern of the filtering.ValidateExpr.
+func (c *Checker) CheckFilter(x expr.FilterExpr) error {
+	return errorOf(c.filterViolations(nil, c.desc, "", x))
+}
+
+// CheckUpdateMask checks that the update mask doesn't touch any OUTPUT_ONLY or IMMUTABLE field.
+// The full replacement "*" path is valid, as it ignores the OUTPUT_ONLY fields by the AIP-134.
+// The invalid paths are skipped, as their validation is the concern of the fieldmask.Validate.
+func (c *Checker) CheckUpdateMask(mask *fieldmaskpb.FieldMask) error {
+	var vs []*Violation
+	for _, path := range mask.GetPaths() {
+		vs = c.maskViolations(vs, path)
+	}
+	return errorOf(vs)
+}
+
+// CheckUpdate checks both the filter and the update mask of a conditional update request,
+// and returns all their violations within a single *Error.
+func (c *Checker) CheckUpdate(x expr.FilterExpr, mask *fieldmaskpb.FieldMask) error {
+	vs := c.filterViolations(nil, c.desc, "", x)
+	for _, path := range mask.GetPaths() {
+		vs = c.maskViolations(vs, path)
+	}
+	return errorOf(vs)
+}
+
+func errorOf(vs []*Violation) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return &Error{Violations: vs}
+}
+
+func (c *Checker) filterViolations(vs []*Violation, md protoreflect.MessageDescriptor, prefix string, x expr.FilterExpr) []*Violation {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		for _, e := range xt.Expr {
+			vs = c.filterViolations(vs, md, prefix, e)
+		}
+	case *expr.SequenceExpr:
+		for _, e := range xt.Factors {
+			vs = c.filterViolations(vs, md, prefix, e)
+		}
+	case *expr.OrExpr:
+		for _, e := range xt.Expr {
+			vs = c.filterViolations(vs, md, prefix, e)
+		}
+	case *expr.NotExpr:
+		vs = c.filterViolations(vs, md, prefix, xt.Expr)
+	case *expr.CompositeExpr:
+		vs = c.filterViolations(vs, md, prefix, xt.Expr)
+	case *expr.CompareExpr:
+		var fd protoreflect.FieldDescriptor
+		vs, fd = c.selectorViolations(vs, md, prefix, xt.Left)
+		switch rt := xt.Right.(type) {
+		case *expr.AnyElementExpr:
+			if fd != nil && fd.Kind() == protoreflect.MessageKind && rt.Filter != nil {
+				vs = c.filterViolations(vs, fd.Message(), prefix+selectorPath(xt.Left)+".", rt.Filter)
+			}
+		default:
+			vs, _ = c.selectorViolations(vs, md, prefix, xt.Right)
+		}
+	case *expr.SearchExpr:
+		vs = c.filterViolations(vs, md, prefix, xt.Expr)
+	case *expr.RegexMatchExpr:
+		vs, _ = c.selectorViolations(vs, md, prefix, xt.Left)
+	case *expr.PresenceExpr:
+		vs, _ = c.selectorViolations(vs, md, prefix, xt.Field)
+	case *expr.FieldSelectorExpr:
+		vs, _ = c.selectorViolations(vs, md, prefix, xt)
+	case *expr.FunctionCallExpr:
+		for _, a := range xt.Arguments {
+			vs = c.filterViolations(vs, md, prefix, a)
+		}
+	case *expr.ArrayExpr:
+		for _, e := range xt.Elements {
+			vs = c.filterViolations(vs, md, prefix, e)
+		}
+	case *expr.BinaryExpr:
+		vs = c.filterViolations(vs, md, prefix, xt.Left)
+		vs = c.filterViolations(vs, md, prefix, xt.Right)
+	}
+	return vs
+}
+
+// selectorViolations checks each field traversed by the x field selector,
+// and returns the descriptor of the last selected field, if the x is a valid selector.
+func (c *Checker) selectorViolations(vs []*Violation, md protoreflect.MessageDescriptor, prefix string, x expr.Expr) ([]*Violation, protoreflect.FieldDescriptor) {
+	switch xt := x.(type) {
+	case *expr.FieldSelectorExpr:
+	case expr.FilterExpr:
+		// Function calls and arrays may contain the selectors as well.
+		return c.filterViolations(vs, md, prefix, xt), nil
+	default:
+		return vs, nil
+	}
+
+	var (
+		fd   protoreflect.FieldDescriptor
+		path = prefix
+	)
+	for cur := x; cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if fd != nil {
+				if fd.IsMap() {
+					fd = fd.MapValue()
+				}
+				if fd.Kind() != protoreflect.MessageKind {
+					return vs, nil
+				}
+				md = fd.Message()
+				path += "."
+			}
+			fd = md.Fields().ByName(ct.Field)
+			if fd == nil {
+				return vs, nil
+			}
+			path += string(ct.Field)
+
+			fi := c.msgInfo.GetFieldInfo(fd)
+			if fi.InputOnly {
+				vs = append(vs, &Violation{Rule: RuleInputOnlyFiltered, Path: path, Field: fd.FullName()})
+			}
+			if fi.FilteringForbidden {
+				vs = append(vs, &Violation{Rule: RuleFilteringForbidden, Path: path, Field: fd.FullName()})
+			}
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			if kv, ok := ct.Key.(*expr.ValueExpr); ok {
+				path += "." + fmt.Sprint(kv.Value)
+			}
+			cur = ct.Traversal
+		default:
+			return vs, nil
+		}
+	}
+	return vs, fd
+}
+
+// selectorPath returns the path of the field selector, without the map keys.
+func selectorPath(x expr.Expr) string {
+	var sb strings.Builder
+	for cur := x; cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			cur = ct.Traversal
+		default:
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+func (c *Checker) maskViolations(vs []*Violation, path string) []*Violation {
+	if path == "*" {
+		return vs
+	}
+
+	var s scanner.Scanner
+	s.Reset(path, nil)
+	md := c.desc
+	for {
+		_, tok, lit := s.Scan()
+		if !tok.IsIdent() {
+			return vs
+		}
+		fd := md.Fields().ByName(protoreflect.Name(lit))
+		if fd == nil {
+			return vs
+		}
+
+		fi := c.msgInfo.GetFieldInfo(fd)
+		if fi.OutputOnly {
+			vs = append(vs, &Violation{Rule: RuleOutputOnlyUpdated, Path: path, Field: fd.FullName()})
+		}
+		if fi.Immutable {
+			vs = append(vs, &Violation{Rule: RuleImmutableUpdated, Path: path, Field: fd.FullName()})
+		}
+
+		if _, tok, _ = s.Scan(); tok != token.PERIOD {
+			return vs
+		}
+		switch {
+		case fd.IsMap(), fd.IsList():
+			// Skip the map key or the wildcard of the repeated field.
+			s.Scan()
+			if _, tok, _ = s.Scan(); tok != token.PERIOD {
+				return vs
+			}
+			if fd.IsMap() {
+				fd = fd.MapValue()
+			}
+		}
+		if fd.Kind() != protoreflect.MessageKind {
+			return vs
+		}
+		md = fd.Message()
+	}
+}
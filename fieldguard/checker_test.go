@@ -0,0 +1,277 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldguard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestChecker_CheckFilter(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	c, err := NewChecker(md)
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+
+	tc := []struct {
+		name string
+		x    func() expr.FilterExpr
+		want []string
+	}{
+		{name: "nil"},
+		{
+			name: "allowed",
+			x:    func() expr.FilterExpr { return compare(selector("str"), value("foo")) },
+		},
+		{
+			name: "input only",
+			x:    func() expr.FilterExpr { return compare(selector("input_only_str"), value("secret")) },
+			want: []string{"INPUT_ONLY_FILTERED input_only_str"},
+		},
+		{
+			name: "regex match",
+			x: func() expr.FilterExpr {
+				rm := expr.AcquireRegexMatchExpr()
+				rm.Left = selector("input_only_str")
+				rm.Pattern = "^foo"
+				return rm
+			},
+			want: []string{"INPUT_ONLY_FILTERED input_only_str"},
+		},
+		{
+			name: "nested and forbidden",
+			x: func() expr.FilterExpr {
+				and := expr.AcquireAndExpr()
+				and.Expr = append(and.Expr,
+					compare(selector("sub", "input_only_str"), value("secret")),
+					compare(selector("no_filter"), selector("str")),
+				)
+				return and
+			},
+			want: []string{"INPUT_ONLY_FILTERED sub.input_only_str", "FILTERING_FORBIDDEN no_filter"},
+		},
+		{
+			name: "right hand side selector",
+			x:    func() expr.FilterExpr { return compare(selector("str"), selector("input_only_str")) },
+			want: []string{"INPUT_ONLY_FILTERED input_only_str"},
+		},
+		{
+			name: "any element",
+			x: func() expr.FilterExpr {
+				ae := expr.AcquireAnyElementExpr()
+				ae.Message = md.FullName()
+				ae.Filter = compare(selector("input_only_str"), value("secret"))
+				x := compare(selector("rp_sub"), ae)
+				x.Comparator = expr.HAS
+				return x
+			},
+			want: []string{"INPUT_ONLY_FILTERED rp_sub.input_only_str"},
+		},
+		{
+			name: "function argument",
+			x: func() expr.FilterExpr {
+				fn := expr.AcquireFunctionCallExpr()
+				fn.Name = "fn"
+				fn.Arguments = append(fn.Arguments, selector("no_filter_msg", "str"))
+				return compare(fn, value(true))
+			},
+			want: []string{"FILTERING_FORBIDDEN no_filter_msg"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var x expr.FilterExpr
+			if tt.x != nil {
+				x = tt.x()
+				defer x.Free()
+			}
+			checkViolations(t, c.CheckFilter(x), tt.want)
+		})
+	}
+}
+
+func TestChecker_CheckUpdateMask(t *testing.T) {
+	c, err := NewChecker(testGovernedMessage(t))
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+
+	tc := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{name: "allowed", paths: []string{"title", "author.title", "editions.*.title"}},
+		{name: "full replacement", paths: []string{"*"}},
+		{name: "output only", paths: []string{"create_time"}, want: []string{"OUTPUT_ONLY_UPDATED create_time"}},
+		{
+			name:  "nested",
+			paths: []string{"author.isbn", "editions.*.create_time"},
+			want:  []string{"IMMUTABLE_UPDATED author.isbn", "OUTPUT_ONLY_UPDATED editions.*.create_time"},
+		},
+		{name: "unknown field", paths: []string{"unknown.isbn", "title.isbn"}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			checkViolations(t, c.CheckUpdateMask(&fieldmaskpb.FieldMask{Paths: tt.paths}), tt.want)
+		})
+	}
+}
+
+func TestChecker_CheckUpdate(t *testing.T) {
+	md := testGovernedMessage(t)
+	c, err := NewChecker(md)
+	if err != nil {
+		t.Fatalf("failed to create checker: %v", err)
+	}
+
+	x := compare(selectorOf(md, "secret"), value("foo"))
+	defer x.Free()
+
+	err = c.CheckUpdate(x, &fieldmaskpb.FieldMask{Paths: []string{"title", "isbn"}})
+	checkViolations(t, err, []string{"INPUT_ONLY_FILTERED secret", "IMMUTABLE_UPDATED isbn"})
+	if !errors.Is(err, ErrViolation) {
+		t.Errorf("expected error to match ErrViolation")
+	}
+	var ge *Error
+	if !errors.As(err, &ge) || ge.Violations[0].Field != "testguard.Book.secret" {
+		t.Errorf("expected the violation of the field testguard.Book.secret but got %v", err)
+	}
+}
+
+func checkViolations(t *testing.T, err error, want []string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	var ge *Error
+	if !errors.As(err, &ge) {
+		t.Fatalf("expected *Error but got %v", err)
+	}
+	var got []string
+	for _, v := range ge.Violations {
+		got = append(got, v.Rule.String()+" "+v.Path)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected violations %v but got %v", want, got)
+	}
+}
+
+func selector(fields ...protoreflect.Name) *expr.FieldSelectorExpr {
+	return selectorOf(new(testpb.Message).ProtoReflect().Descriptor(), fields...)
+}
+
+func selectorOf(md protoreflect.MessageDescriptor, fields ...protoreflect.Name) *expr.FieldSelectorExpr {
+	var head, last *expr.FieldSelectorExpr
+	for _, f := range fields {
+		fs := expr.AcquireFieldSelectorExpr()
+		fs.Message = md.FullName()
+		fs.Field = f
+		if head == nil {
+			head = fs
+		} else {
+			last.Traversal = fs
+		}
+		last = fs
+		if fd := md.Fields().ByName(f); fd != nil && fd.Message() != nil {
+			md = fd.Message()
+		}
+	}
+	return head
+}
+
+func value(v any) *expr.ValueExpr {
+	ve := expr.AcquireValueExpr()
+	ve.Value = v
+	return ve
+}
+
+func compare(left, right expr.FilterExpr) *expr.CompareExpr {
+	x := expr.AcquireCompareExpr()
+	x.Left = left
+	x.Comparator = expr.EQ
+	x.Right = right
+	return x
+}
+
+func testGovernedMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	behavior := func(b ...annotations.FieldBehavior) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, annotations.E_FieldBehavior, b)
+		return opts
+	}
+	field := func(name string, num int32, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+			Options:  opts,
+		}
+	}
+	book := func(name string, num int32, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".testguard.Book"),
+			JsonName: proto.String(name),
+		}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testguard/book.proto"),
+		Package:    proto.String("testguard"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/api/field_behavior.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("title", 1, nil),
+				field("create_time", 2, behavior(annotations.FieldBehavior_OUTPUT_ONLY)),
+				field("isbn", 3, behavior(annotations.FieldBehavior_IMMUTABLE)),
+				field("secret", 4, behavior(annotations.FieldBehavior_INPUT_ONLY)),
+				book("author", 5, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				book("editions", 6, descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
@@ -0,0 +1,24 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fieldguard enforces the field governance of a message consistently across the filters and the update masks.
+// The rules are driven by the google.api.field_behavior and blocky.api.query_opt annotations:
+//   - the INPUT_ONLY fields, i.e. the secrets, cannot be referenced by a filter, as it would leak their values,
+//   - the FORBID_FILTERING fields cannot be referenced by a filter,
+//   - the OUTPUT_ONLY fields, computed by the service, cannot be touched by an update mask,
+//   - the IMMUTABLE fields cannot be touched by an update mask.
+//
+// Contrary to the filtering.ValidateExpr and the fieldmask.Validate, the Checker verifies only the governance rules,
+// and collects all the violations, so that the service could report them at once.
+package fieldguard
@@ -0,0 +1,162 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// Diff compares the current and the updated message, and returns the field mask with the paths
+// of the fields which values differ, following the field behavior annotations of the AIP-203:
+//   - OUTPUT_ONLY fields are never included in the mask, as these are set by the service,
+//   - IMMUTABLE fields are never included in the mask, and setting them to a different value
+//     in the updated message results in a violation within the *ValidationError.
+//
+// The singular message fields set in both messages are compared field by field, and result in the paths of their sub fields.
+// The repeated, map and well-known type fields, like google.protobuf.Timestamp, are compared as a whole.
+func (p *Parser) Diff(current, updated proto.Message) (*fieldmaskpb.FieldMask, error) {
+	cm, um := current.ProtoReflect(), updated.ProtoReflect()
+	if cm.Descriptor().FullName() != um.Descriptor().FullName() {
+		if p.errHandler != nil {
+			p.errHandler(0, "compared messages have different descriptors")
+		}
+		return nil, ErrInternalError
+	}
+	if p.desc == nil {
+		p.desc = cm.Descriptor()
+		p.msgInfo = info.MapMsgInfo(p.desc)
+	}
+	if cm.Descriptor().FullName() != p.desc.FullName() {
+		if p.errHandler != nil {
+			p.errHandler(0, "invalid message descriptor")
+		}
+		return nil, ErrInternalError
+	}
+
+	var (
+		fm fieldmaskpb.FieldMask
+		ve ValidationError
+	)
+	p.diffMessage(&fm, &ve, "", cm, um)
+	if len(ve.Violations) > 0 {
+		return nil, &ve
+	}
+	return &fm, nil
+}
+
+// ParseDiffUpdateExpr computes the Diff of the current and the updated message,
+// and parses the resulting field mask into the update expression with the values of the updated message.
+// The result is ready to apply by the AIP-134 Update handlers, that don't receive the update mask.
+func (p *Parser) ParseDiffUpdateExpr(current, updated proto.Message) (*expr.UpdateExpr, error) {
+	fm, err := p.Diff(current, updated)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseUpdateExpr(updated, fm)
+}
+
+func (p *Parser) diffMessage(fm *fieldmaskpb.FieldMask, ve *ValidationError, prefix string, cm, um protoreflect.Message) {
+	mi := p.msgInfo.MessageInfo(cm.Descriptor())
+	for _, fi := range mi.Fields {
+		if fi.OutputOnly {
+			continue
+		}
+
+		path := string(fi.Desc.Name())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		fd := fi.Desc
+		ch, uh := cm.Has(fd), um.Has(fd)
+		if !ch && !uh {
+			continue
+		}
+
+		if fi.Immutable {
+			// An immutable field not set in the updated message is not changed.
+			if uh && (!ch || !equalFieldValue(fd, cm.Get(fd), um.Get(fd))) {
+				ve.Violations = append(ve.Violations, &Violation{
+					Path: path,
+					Msg:  fmt.Sprintf("immutable field %q value cannot be changed", fd.Name()),
+					Err:  ErrInvalidField,
+				})
+			}
+			continue
+		}
+
+		if ch && uh && fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() &&
+			!fi.IsTimestamp && !fi.IsDuration && !fi.IsStructpb {
+			p.diffMessage(fm, ve, path, cm.Get(fd).Message(), um.Get(fd).Message())
+			continue
+		}
+
+		if ch != uh || !equalFieldValue(fd, cm.Get(fd), um.Get(fd)) {
+			fm.Paths = append(fm.Paths, path)
+		}
+	}
+}
+
+// equalFieldValue checks if the values of the fd field are equal.
+func equalFieldValue(fd protoreflect.FieldDescriptor, a, b protoreflect.Value) bool {
+	switch {
+	case fd.IsList():
+		al, bl := a.List(), b.List()
+		if al.Len() != bl.Len() {
+			return false
+		}
+		for i := 0; i < al.Len(); i++ {
+			if !equalSingularValue(fd, al.Get(i), bl.Get(i)) {
+				return false
+			}
+		}
+		return true
+	case fd.IsMap():
+		am, bm := a.Map(), b.Map()
+		if am.Len() != bm.Len() {
+			return false
+		}
+		equal := true
+		am.Range(func(k protoreflect.MapKey, av protoreflect.Value) bool {
+			bv := bm.Get(k)
+			equal = bv.IsValid() && equalSingularValue(fd.MapValue(), av, bv)
+			return equal
+		})
+		return equal
+	}
+	return equalSingularValue(fd, a, b)
+}
+
+func equalSingularValue(fd protoreflect.FieldDescriptor, a, b protoreflect.Value) bool {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return proto.Equal(a.Message().Interface(), b.Message().Interface())
+	case protoreflect.BytesKind:
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// NaN values are considered equal, so that an unchanged NaN doesn't end in the mask.
+		af, bf := a.Float(), b.Float()
+		return af == bf || (af != af && bf != bf)
+	}
+	return a.Interface() == b.Interface()
+}
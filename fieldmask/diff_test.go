@@ -0,0 +1,158 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestParser_Diff(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *testpb.Message
+		updated *testpb.Message
+		want    []string
+	}{
+		{
+			name:    "equal",
+			current: &testpb.Message{Str: "a", Sub: &testpb.Message{Name: "n"}},
+			updated: &testpb.Message{Str: "a", Sub: &testpb.Message{Name: "n"}},
+		},
+		{
+			name:    "scalar",
+			current: &testpb.Message{Str: "a", I32: 1},
+			updated: &testpb.Message{Str: "b", I32: 1},
+			want:    []string{"str"},
+		},
+		{
+			name:    "cleared scalar",
+			current: &testpb.Message{Str: "a"},
+			updated: &testpb.Message{},
+			want:    []string{"str"},
+		},
+		{
+			name:    "nested field",
+			current: &testpb.Message{Sub: &testpb.Message{Name: "n", I32: 1}},
+			updated: &testpb.Message{Sub: &testpb.Message{Name: "m", I32: 1}},
+			want:    []string{"sub.name"},
+		},
+		{
+			name:    "set message",
+			current: &testpb.Message{},
+			updated: &testpb.Message{Sub: &testpb.Message{Name: "n"}},
+			want:    []string{"sub"},
+		},
+		{
+			name:    "well known type",
+			current: &testpb.Message{Timestamp: &timestamppb.Timestamp{Seconds: 1}},
+			updated: &testpb.Message{Timestamp: &timestamppb.Timestamp{Seconds: 2}},
+			want:    []string{"timestamp"},
+		},
+		{
+			name:    "repeated and map",
+			current: &testpb.Message{RpStr: []string{"a"}, MapStrStr: map[string]string{"k": "v"}},
+			updated: &testpb.Message{RpStr: []string{"a", "b"}, MapStrStr: map[string]string{"k": "w"}},
+			want:    []string{"rp_str", "map_str_str"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if err := p.Reset(new(testpb.Message)); err != nil {
+				t.Fatalf("Reset() error = %v", err)
+			}
+
+			got, err := p.Diff(tt.current, tt.updated)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if len(got.Paths) != len(tt.want) {
+				t.Fatalf("Diff() = %v, want %v", got.Paths, tt.want)
+			}
+			for i := range tt.want {
+				if got.Paths[i] != tt.want[i] {
+					t.Errorf("Diff() = %v, want %v", got.Paths, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_DiffFieldBehavior(t *testing.T) {
+	md := testBehaviorMessage(t)
+	book := func(title, createTime, isbn string) *dynamicpb.Message {
+		m := dynamicpb.NewMessage(md)
+		for name, v := range map[protoreflect.Name]string{"title": title, "create_time": createTime, "isbn": isbn} {
+			if v != "" {
+				m.Set(md.Fields().ByName(name), protoreflect.ValueOfString(v))
+			}
+		}
+		return m
+	}
+
+	var p Parser
+	if err := p.Reset(dynamicpb.NewMessage(md)); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+
+	t.Run("output only and unchanged immutable", func(t *testing.T) {
+		ue, err := p.ParseDiffUpdateExpr(book("a", "t1", "isbn"), book("b", "t2", "isbn"))
+		if err != nil {
+			t.Fatalf("ParseDiffUpdateExpr() error = %v", err)
+		}
+		defer ue.Free()
+
+		if len(ue.Elements) != 1 {
+			t.Fatalf("len(ue.Elements) = %d, want 1", len(ue.Elements))
+		}
+		el := ue.Elements[0]
+		if el.Field.Field != "title" {
+			t.Errorf("el.Field.Field = %v, want title", el.Field.Field)
+		}
+		if v, ok := el.Value.(*expr.ValueExpr); !ok || v.Value != "b" {
+			t.Errorf("el.Value = %v, want b", el.Value)
+		}
+	})
+
+	t.Run("omitted immutable", func(t *testing.T) {
+		fm, err := p.Diff(book("a", "", "isbn"), book("a", "", ""))
+		if err != nil {
+			t.Fatalf("Diff() error = %v", err)
+		}
+		if len(fm.Paths) != 0 {
+			t.Errorf("Diff() = %v, want no paths", fm.Paths)
+		}
+	})
+
+	t.Run("changed immutable", func(t *testing.T) {
+		_, err := p.Diff(book("a", "", "isbn"), book("b", "", "other"))
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Diff() error = %v, want *ValidationError", err)
+		}
+		if len(ve.Violations) != 1 || ve.Violations[0].Path != "isbn" || !errors.Is(err, ErrInvalidField) {
+			t.Errorf("Diff() violations = %v, want isbn", ve.Violations)
+		}
+	})
+}
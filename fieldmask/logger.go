@@ -0,0 +1,104 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// LoggerOption is an option function that sets the structured logger of the parser,
+// so that no ErrHandlerOption adapter is needed. The ParseUpdateExpr logs:
+//   - the mask path that fails to parse, with the position and the message of the error, at the info level,
+//   - the non-updatable paths discarded with the IgnoreNonUpdatableOption, with the field, at the debug level.
+func LoggerOption(l *slog.Logger) OptionFn {
+	return func(p *Parser) error {
+		if l == nil {
+			return errors.New("logger is not set")
+		}
+		p.logger = l
+		return nil
+	}
+}
+
+// pathLog is the state of the logged mask path.
+type pathLog struct {
+	path string
+	// pos and msg are the first error reported while parsing the path.
+	pos    token.Position
+	msg    string
+	hasErr bool
+}
+
+// withPathLog returns the copy of the parser, which records the errors of the path into the pl.
+// The parser is shared by the concurrent calls, thus it is not modified itself.
+func (p *Parser) withPathLog(pl *pathLog) *Parser {
+	lp := *p
+	lp.pathLog = pl
+	lp.errHandler = func(pos token.Position, msg string) {
+		if !pl.hasErr {
+			pl.pos, pl.msg, pl.hasErr = pos, msg, true
+		}
+		if p.errHandler != nil {
+			p.errHandler(pos, msg)
+		}
+	}
+	return &lp
+}
+
+// logPathError logs the error of parsing the mask path.
+func (p *Parser) logPathError(pl *pathLog, err error) {
+	level := slog.LevelInfo
+	if errors.Is(err, ErrInternalError) {
+		level = slog.LevelError
+	}
+	ctx := context.Background()
+	if !p.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs,
+		slog.String("message", string(p.desc.FullName())),
+		slog.String("path", pl.path),
+	)
+	if pl.hasErr {
+		attrs = append(attrs, slog.Int("error_position", int(pl.pos)), slog.String("error_message", pl.msg))
+	}
+	attrs = append(attrs, slog.String("error", err.Error()))
+	p.logger.LogAttrs(ctx, level, "field mask path parse failed", attrs...)
+}
+
+// logDiscarded logs the non-updatable field, which path is discarded.
+func (p *Parser) logDiscarded(fi info.FieldInfo, pos token.Position) {
+	if p.pathLog == nil {
+		return
+	}
+	reason := "immutable"
+	if fi.OutputOnly {
+		reason = "output_only"
+	}
+	p.logger.LogAttrs(context.Background(), slog.LevelDebug, "non-updatable field mask path discarded",
+		slog.String("message", string(p.desc.FullName())),
+		slog.String("path", p.pathLog.path),
+		slog.String("field", string(fi.Desc.FullName())),
+		slog.Int("position", int(pos)),
+		slog.String("reason", reason),
+	)
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestParser_LoggerOption(t *testing.T) {
+	md := testBehaviorMessage(t)
+
+	tc := []struct {
+		name  string
+		paths []string
+		opts  []OptionFn
+		isErr bool
+		want  []map[string]any
+	}{
+		{
+			name:  "valid",
+			paths: []string{"title"},
+		},
+		{
+			name:  "discarded",
+			paths: []string{"title", "create_time", "isbn"},
+			opts:  []OptionFn{IgnoreNonUpdatableOption},
+			want: []map[string]any{
+				{
+					"level":    "DEBUG",
+					"msg":      "non-updatable field mask path discarded",
+					"message":  "testvalidate.Book",
+					"path":     "create_time",
+					"field":    "testvalidate.Book.create_time",
+					"position": float64(0),
+					"reason":   "output_only",
+				},
+				{
+					"path":   "isbn",
+					"field":  "testvalidate.Book.isbn",
+					"reason": "immutable",
+				},
+			},
+		},
+		{
+			name:  "not a message",
+			paths: []string{"title", "title.unknown"},
+			isErr: true,
+			want: []map[string]any{{
+				"level":          "INFO",
+				"msg":            "field mask path parse failed",
+				"message":        "testvalidate.Book",
+				"path":           "title.unknown",
+				"error_position": float64(0),
+				"error_message":  "expected message field in sub path",
+				"error":          ErrInvalidField.Error(),
+			}},
+		},
+		{
+			name:  "non-updatable",
+			paths: []string{"isbn"},
+			isErr: true,
+			want: []map[string]any{{
+				"level":         "INFO",
+				"path":          "isbn",
+				"error_message": "immutable or output only field in sub path cannot be updated",
+			}},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			var handled int
+			opts := append([]OptionFn{
+				LoggerOption(l),
+				ErrHandlerOption(func(token.Position, string) { handled++ }),
+			}, tt.opts...)
+
+			msg := dynamicpb.NewMessage(md)
+			var p Parser
+			if err := p.Reset(msg, opts...); err != nil {
+				t.Fatalf("failed to reset parser: %v", err)
+			}
+
+			ue, err := p.ParseUpdateExpr(msg, &fieldmaskpb.FieldMask{Paths: tt.paths})
+			if tt.isErr {
+				if err == nil {
+					ue.Free()
+					t.Fatal("expected error")
+				}
+				if handled == 0 {
+					t.Error("expected the error handler to be called along with the logger")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				ue.Free()
+			}
+
+			var lines []string
+			if s := strings.TrimSpace(buf.String()); s != "" {
+				lines = strings.Split(s, "\n")
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("expected %d log entries but got %d: %s", len(tt.want), len(lines), buf.String())
+			}
+			for j, want := range tt.want {
+				var got map[string]any
+				if err = json.Unmarshal([]byte(lines[j]), &got); err != nil {
+					t.Fatalf("failed to decode log entry: %v", err)
+				}
+				for k, v := range want {
+					if got[k] != v {
+						t.Errorf("entry %d: expected %s=%v but got %v", j, k, v, got[k])
+					}
+				}
+			}
+
+			if p.pathLog != nil || handled > 1 {
+				t.Errorf("expected the parser not to be modified by the logged parse, and a single error handled, got %d", handled)
+			}
+		})
+	}
+
+	if err := LoggerOption(nil)(&Parser{}); err == nil {
+		t.Error("expected error for nil logger")
+	}
+}
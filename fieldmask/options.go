@@ -0,0 +1,72 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"log/slog"
+
+	"github.com/blockysource/blocky-aip/scanner"
+)
+
+// FieldmaskOptions is a plain structure alternative to the OptionFn functions of the Parser.
+// It could be decoded from the configuration files or environment, and compared across the services.
+type FieldmaskOptions struct {
+	// IgnoreNonUpdatable skips the non-updatable fields instead of failing, see IgnoreNonUpdatableOption.
+	IgnoreNonUpdatable bool `json:"ignore_non_updatable,omitempty"`
+
+	// EmptyStringAsNull makes the empty nullable string values update the fields to null, see EmptyStringAsNullOption.
+	EmptyStringAsNull bool `json:"empty_string_as_null,omitempty"`
+
+	// ErrHandler is the error handler of the parser, see ErrHandlerOption.
+	ErrHandler scanner.ErrorHandler `json:"-"`
+
+	// Logger logs the failed and discarded paths, see LoggerOption.
+	Logger *slog.Logger `json:"-"`
+}
+
+// Option returns the option function that applies all the non-zero fields of the options.
+func (o FieldmaskOptions) Option() OptionFn {
+	return func(p *Parser) error {
+		if o.IgnoreNonUpdatable {
+			if err := IgnoreNonUpdatableOption(p); err != nil {
+				return err
+			}
+		}
+		if o.EmptyStringAsNull {
+			if err := EmptyStringAsNullOption(p); err != nil {
+				return err
+			}
+		}
+		if o.ErrHandler != nil {
+			if err := ErrHandlerOption(o.ErrHandler)(p); err != nil {
+				return err
+			}
+		}
+		if o.Logger != nil {
+			return LoggerOption(o.Logger)(p)
+		}
+		return nil
+	}
+}
+
+// Options returns the options the parser is currently configured with.
+func (p *Parser) Options() FieldmaskOptions {
+	return FieldmaskOptions{
+		IgnoreNonUpdatable: p.ignoreNonUpdatable,
+		EmptyStringAsNull:  p.emptyStringAsNull,
+		ErrHandler:         p.errHandler,
+		Logger:             p.logger,
+	}
+}
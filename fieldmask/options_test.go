@@ -0,0 +1,33 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestFieldmaskOptions(t *testing.T) {
+	o := FieldmaskOptions{IgnoreNonUpdatable: true, EmptyStringAsNull: true}
+
+	var p Parser
+	if err := p.Reset(new(testpb.Message), o.Option()); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if got := p.Options(); got.IgnoreNonUpdatable != o.IgnoreNonUpdatable || got.EmptyStringAsNull != o.EmptyStringAsNull || got.ErrHandler != nil {
+		t.Errorf("Options() = %+v, want %+v", got, o)
+	}
+}
@@ -16,6 +16,8 @@ package fieldmask
 
 import (
 	"errors"
+	"log/slog"
+	"math"
 	"time"
 
 	"google.golang.org/protobuf/proto"
@@ -35,6 +37,10 @@ var (
 	// ErrInvalidSyntax is an error returned by the parser when the field mask
 	// has invalid syntax.
 	ErrInvalidSyntax = errors.New("invalid syntax")
+
+	// ErrSortingForbidden is an error returned by the parser when the sorting
+	// by a field is forbidden.
+	ErrSortingForbidden = errors.New("sorting forbidden")
 )
 
 // Parser is a field mask to expression parser.
@@ -45,7 +51,13 @@ type Parser struct {
 	errHandler scanner.ErrorHandler
 
 	ignoreNonUpdatable bool
+	emptyStringAsNull  bool
 	msgInfo            info.MessagesInfo
+
+	// logger logs the failed and discarded paths, see LoggerOption.
+	logger *slog.Logger
+	// pathLog is the state of the logged path, set only in the copy of the parser made by the withPathLog.
+	pathLog *pathLog
 }
 
 // OptionFn is an option function for the Parser.
@@ -65,6 +77,15 @@ func IgnoreNonUpdatableOption(p *Parser) error {
 	return nil
 }
 
+// EmptyStringAsNullOption is an option function that makes the empty string values of the nullable string fields
+// update the fields to null, instead of an empty string.
+// A nullable string field is a field with the OPTIONAL field behavior annotation.
+// It matches the filtering.EmptyStringAsNull mode of the filters.
+func EmptyStringAsNullOption(p *Parser) error {
+	p.emptyStringAsNull = true
+	return nil
+}
+
 // Reset the parser.
 func (p *Parser) Reset(msg proto.Message, opts ...OptionFn) error {
 	for _, opt := range opts {
@@ -90,7 +111,8 @@ func (p *Parser) extractTimeValue(msg protoreflect.Message) time.Time {
 		}
 		return true
 	})
-	return time.Unix(seconds, nanos)
+	// The timestamp is always in UTC, keep it that way so that it renders in the canonical RFC 3339 form.
+	return time.Unix(seconds, nanos).UTC()
 }
 
 func (p *Parser) extractDurationValue(msg protoreflect.Message) time.Duration {
@@ -105,5 +127,27 @@ func (p *Parser) extractDurationValue(msg protoreflect.Message) time.Duration {
 		}
 		return true
 	})
-	return time.Duration(seconds)*time.Second + time.Duration(nanos)*time.Nanosecond
+	return durationOf(seconds, nanos)
+}
+
+// durationOf returns the duration of given seconds and nanos, without losing the nanosecond precision.
+// The durations out of the time.Duration range are saturated to its minimum or maximum value.
+func durationOf(seconds, nanos int64) time.Duration {
+	const maxSeconds = math.MaxInt64 / int64(time.Second)
+	switch {
+	case seconds > maxSeconds:
+		return math.MaxInt64
+	case seconds < -maxSeconds:
+		return math.MinInt64
+	}
+	d := time.Duration(seconds) * time.Second
+	n := time.Duration(nanos)
+	switch sum := d + n; {
+	case n > 0 && sum < d:
+		return math.MaxInt64
+	case n < 0 && sum > d:
+		return math.MinInt64
+	default:
+		return sum
+	}
 }
@@ -0,0 +1,197 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ParseSortMask parses the field mask paths into an order by expression.
+// Each path is a field selector optionally followed by the direction, i.e.: "sub.name desc" or "create_time asc".
+// If the direction is not specified, the field is sorted in the ascending order.
+// The paths are validated against the message descriptor, a field that is not found,
+// is input only or is marked with the FORBID_SORTING query option results in an error.
+// Repeated and map fields cannot be used for sorting nor traversed through.
+// Output only fields are allowed, as these are set by the service and are the common sorting keys.
+func (p *Parser) ParseSortMask(fm *fieldmaskpb.FieldMask) (*expr.OrderByExpr, error) {
+	if p.desc == nil {
+		if p.errHandler != nil {
+			p.errHandler(0, "message descriptor is not set")
+		}
+		return nil, ErrInternalError
+	}
+
+	if len(fm.GetPaths()) == 0 {
+		return nil, nil
+	}
+
+	oe := expr.AcquireOrderByExpr()
+	for _, path := range fm.GetPaths() {
+		var s scanner.Scanner
+		s.Reset(path, p.errHandler)
+
+		fe, err := p.parseSortMaskPath(&s)
+		if err != nil {
+			oe.Free()
+			return nil, err
+		}
+
+		// Verify if the field was not already used for sorting.
+		for _, prev := range oe.Fields {
+			if prev.Field.Equals(fe.Field) {
+				if p.errHandler != nil {
+					p.errHandler(0, fmt.Sprintf("duplicated sort path: %q", path))
+				}
+				fe.Free()
+				oe.Free()
+				return nil, ErrInvalidField
+			}
+		}
+		oe.Fields = append(oe.Fields, fe)
+	}
+	return oe, nil
+}
+
+func (p *Parser) parseSortMaskPath(s *scanner.Scanner) (*expr.OrderByFieldExpr, error) {
+	s.SkipWhitespace()
+
+	md := p.desc
+	var root, last *expr.FieldSelectorExpr
+	freeRoot := func() {
+		if root != nil {
+			root.Free()
+		}
+	}
+	for {
+		pos, tok, lit := s.Scan()
+		if !tok.IsIdent() {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("expected field name but got %q", lit))
+			}
+			freeRoot()
+			return nil, ErrInvalidSyntax
+		}
+
+		fi, ok := p.msgInfo.MessageInfo(md).FieldByName(protoreflect.Name(lit))
+		if !ok {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("field %q not found", lit))
+			}
+			freeRoot()
+			return nil, ErrInvalidField
+		}
+
+		if fi.OrderingForbidden {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("sorting by field %q is forbidden", lit))
+			}
+			freeRoot()
+			return nil, ErrSortingForbidden
+		}
+
+		if fi.InputOnly {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("field %q is marked as input only", lit))
+			}
+			freeRoot()
+			return nil, ErrInvalidField
+		}
+
+		if fi.Desc.IsList() || fi.Desc.IsMap() {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("cannot sort by a repeated or map field: %q", lit))
+			}
+			freeRoot()
+			return nil, ErrInvalidField
+		}
+
+		fs := expr.AcquireFieldSelectorExpr()
+		fs.Message = md.FullName()
+		fs.Field = fi.Desc.Name()
+		fs.FieldComplexity = fi.Complexity
+		if last == nil {
+			root = fs
+		} else {
+			last.Traversal = fs
+		}
+		last = fs
+
+		var isPeriod bool
+		s.Peek(func(_ token.Position, t token.Token, _ string) bool {
+			isPeriod = t == token.PERIOD
+			return isPeriod
+		})
+		if isPeriod {
+			if fi.Desc.Kind() != protoreflect.MessageKind || fi.IsTimestamp || fi.IsDuration || fi.IsStructpb || fi.NonTraversal {
+				if p.errHandler != nil {
+					p.errHandler(pos, fmt.Sprintf("cannot traverse through field %q", lit))
+				}
+				freeRoot()
+				return nil, ErrInvalidField
+			}
+			md = fi.Desc.Message()
+			continue
+		}
+
+		// The last field of the path needs to be sortable by its value.
+		if fi.Desc.Kind() == protoreflect.MessageKind && !fi.IsTimestamp && !fi.IsDuration {
+			if p.errHandler != nil {
+				p.errHandler(pos, fmt.Sprintf("cannot sort by a message field: %q", lit))
+			}
+			freeRoot()
+			return nil, ErrInvalidField
+		}
+		break
+	}
+
+	fe := expr.AcquireOrderByFieldExpr()
+	fe.Field = root
+	fe.Order = expr.ASC
+
+	s.SkipWhitespace()
+
+	pos, tok, lit := s.Scan()
+	switch tok {
+	case token.EOF:
+		return fe, nil
+	case token.ASC:
+		fe.Order = expr.ASC
+	case token.DESC:
+		fe.Order = expr.DESC
+	default:
+		if p.errHandler != nil {
+			p.errHandler(pos, fmt.Sprintf("expected sort direction or end of path but got %q", lit))
+		}
+		fe.Free()
+		return nil, ErrInvalidSyntax
+	}
+
+	s.SkipWhitespace()
+	if pos, tok, lit = s.Scan(); tok != token.EOF {
+		if p.errHandler != nil {
+			p.errHandler(pos, fmt.Sprintf("expected end of path but got %q", lit))
+		}
+		fe.Free()
+		return nil, ErrInvalidSyntax
+	}
+	return fe, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestParser_ParseSortMask(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		check func(t *testing.T, x *expr.OrderByExpr)
+		isErr bool
+		err   error
+	}{
+		{
+			name:  "single field",
+			paths: []string{"name"},
+			check: func(t *testing.T, x *expr.OrderByExpr) {
+				if len(x.Fields) != 1 {
+					t.Fatalf("unexpected number of fields: %d", len(x.Fields))
+				}
+				f := x.Fields[0]
+				if f.Field.Field != "name" {
+					t.Fatalf("unexpected field name: %s", f.Field.Field)
+				}
+				if f.Order != expr.ASC {
+					t.Fatalf("unexpected order: %s", f.Order)
+				}
+			},
+		},
+		{
+			name:  "multiple fields with directions",
+			paths: []string{"i32 desc", "timestamp asc", "str"},
+			check: func(t *testing.T, x *expr.OrderByExpr) {
+				if len(x.Fields) != 3 {
+					t.Fatalf("unexpected number of fields: %d", len(x.Fields))
+				}
+				expected := []struct {
+					field string
+					order expr.Order
+				}{
+					{"i32", expr.DESC},
+					{"timestamp", expr.ASC},
+					{"str", expr.ASC},
+				}
+				for i, e := range expected {
+					f := x.Fields[i]
+					if string(f.Field.Field) != e.field {
+						t.Fatalf("unexpected field name: %s, expected: %s", f.Field.Field, e.field)
+					}
+					if f.Order != e.order {
+						t.Fatalf("unexpected order: %s, expected: %s", f.Order, e.order)
+					}
+				}
+			},
+		},
+		{
+			name:  "traversal field",
+			paths: []string{"sub.name desc"},
+			check: func(t *testing.T, x *expr.OrderByExpr) {
+				if len(x.Fields) != 1 {
+					t.Fatalf("unexpected number of fields: %d", len(x.Fields))
+				}
+				f := x.Fields[0]
+				if f.Field.Field != "sub" {
+					t.Fatalf("unexpected field name: %s", f.Field.Field)
+				}
+				sub, ok := f.Field.Traversal.(*expr.FieldSelectorExpr)
+				if !ok {
+					t.Fatalf("unexpected traversal: %T", f.Field.Traversal)
+				}
+				if sub.Field != "name" {
+					t.Fatalf("unexpected traversal field name: %s", sub.Field)
+				}
+				if f.Order != expr.DESC {
+					t.Fatalf("unexpected order: %s", f.Order)
+				}
+			},
+		},
+		{
+			name:  "field not found",
+			paths: []string{"unknown"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "sorting forbidden",
+			paths: []string{"no_filter"},
+			isErr: true,
+			err:   ErrSortingForbidden,
+		},
+		{
+			name:  "input only",
+			paths: []string{"input_only_str"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "repeated field",
+			paths: []string{"rp_str"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "map field",
+			paths: []string{"map_str_str"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "message field",
+			paths: []string{"sub"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "traversal through non message",
+			paths: []string{"name.sub"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "duplicated path",
+			paths: []string{"name", "name desc"},
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "invalid direction",
+			paths: []string{"name up"},
+			isErr: true,
+			err:   ErrInvalidSyntax,
+		},
+		{
+			name:  "trailing token",
+			paths: []string{"name desc asc"},
+			isErr: true,
+			err:   ErrInvalidSyntax,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Parser{}
+			if err := p.Reset(&testpb.Message{}, ErrHandlerOption(testErrorHandler(t, tc.isErr))); err != nil {
+				t.Fatalf("failed to reset parser: %v", err)
+			}
+
+			x, err := p.ParseSortMask(&fieldmaskpb.FieldMask{Paths: tc.paths})
+			if err != nil {
+				if !tc.isErr {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tc.err != err {
+					t.Fatalf("unexpected error: %v, expected: %v", err, tc.err)
+				}
+				return
+			}
+			defer x.Free()
+
+			if tc.isErr {
+				t.Fatalf("expected error: %v", tc.err)
+			}
+
+			tc.check(t, x)
+		})
+	}
+}
@@ -16,6 +16,7 @@ package fieldmask
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -32,8 +33,14 @@ import (
 // ParseUpdateExpr parses a field mask, and extracts field values to update
 // from the given message.
 // Parsed field mask Update expressions, can be used to update selected fields of a message.
-// If selected field is a map with a wildstar selector i.e: path: "map_field.*.field_name"
-// This will evaluate to all keys in the mapi_field value
+// If selected field is a map with a wildcard selector i.e: path: "map_field.*" or "map_field.*.field_name"
+// this evaluates to all the keys existing in the map_field value, as if each of them was selected explicitly.
+// A wildcard of an empty map matches no keys, and adds no update expressions for the path.
+// A repeated message field could be traversed with a wildcard selector i.e.: path: "list_field.*.sub_field",
+// which results in an expr.ArrayUpdateExpr with the sub_field update expression for each element of the list.
+// The order of the resulting elements is deterministic: the elements follow the order of the mask paths,
+// the wildcard and map value entries are ordered by the ascending map keys, and the fields of a whole message value
+// are ordered by the field number.
 func (p *Parser) ParseUpdateExpr(msg proto.Message, mask *fieldmaskpb.FieldMask) (*expr.UpdateExpr, error) {
 	if p.desc == nil {
 		p.desc = msg.ProtoReflect().Descriptor()
@@ -46,7 +53,7 @@ func (p *Parser) ParseUpdateExpr(msg proto.Message, mask *fieldmaskpb.FieldMask)
 
 	pm := msg.ProtoReflect()
 	for _, path := range mask.Paths {
-		err := p.buildPathUpdateExpr(ue, pm, path)
+		err := p.buildLoggedPathUpdateExpr(ue, pm, path)
 		if err != nil {
 			ue.Free()
 			return nil, err
@@ -55,12 +62,23 @@ func (p *Parser) ParseUpdateExpr(msg proto.Message, mask *fieldmaskpb.FieldMask)
 	return ue, nil
 }
 
-func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.Message, path string) (err error) {
+// buildLoggedPathUpdateExpr builds the update expression of the path, logging it with the logger, if set.
+func (p *Parser) buildLoggedPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.Message, path string) error {
+	if p.logger == nil {
+		return p.buildPathUpdateExpr(ue, msgValue, path)
+	}
+	pl := pathLog{path: path}
+	err := p.withPathLog(&pl).buildPathUpdateExpr(ue, msgValue, path)
+	if err != nil {
+		p.logPathError(&pl, err)
+	}
+	return err
+}
+
+func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.Message, path string) error {
 	var s scanner.Scanner
 	s.Reset(path, p.errHandler)
 
-	curMsg := msgValue
-
 	if msgValue.Descriptor().FullName() != p.desc.FullName() {
 		if p.errHandler != nil {
 			p.errHandler(0, "invalid message descriptor")
@@ -68,27 +86,27 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 		return ErrInternalError
 	}
 
-	md := p.desc
-
 	root := expr.AcquireFieldSelectorExpr()
+	root.Message = msgValue.Descriptor().FullName()
+	return p.buildSubPathUpdateExpr(ue, &s, p.desc, msgValue, root, root)
+}
+
+// buildSubPathUpdateExpr builds the update expression for the rest of the path scanned by s.
+// The next path element is resolved in the md message descriptor of the curMsg value,
+// and its name is set in the fs, which is the last field selector of the root.
+// It takes the ownership of the root, which is either added to the ue or freed.
+func (p *Parser) buildSubPathUpdateExpr(ue *expr.UpdateExpr, s *scanner.Scanner, md protoreflect.MessageDescriptor, curMsg protoreflect.Message, root, fs *expr.FieldSelectorExpr) (err error) {
 	defer func() {
 		if err != nil {
 			root.Free()
 		}
 	}()
 
-	fs := root
-	fs.Message = msgValue.Descriptor().FullName()
 	var fi info.FieldInfo
 	for {
 		pos, tok, lit := s.Scan()
 		if tok == token.EOF {
-			err = p.handleLastPathElem(ue, curMsg, fi, root, fs, pos)
-			if err != nil {
-				root.Free()
-				return err
-			}
-			return nil
+			return p.handleLastPathElem(ue, curMsg, fi, root, fs, pos)
 		}
 		if tok == token.PERIOD {
 			// This means it is an extra period.
@@ -96,7 +114,6 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 			if p.errHandler != nil {
 				p.errHandler(pos, "unexpected period")
 			}
-			root.Free()
 			return ErrInvalidField
 		}
 
@@ -108,13 +125,13 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 			if p.errHandler != nil {
 				p.errHandler(pos, "unknown field name")
 			}
-			root.Free()
 			return ErrInvalidField
 		}
 
 		// Ensure that the field is not immutable or output only.
 		if fi.Immutable || fi.OutputOnly {
 			if p.ignoreNonUpdatable {
+				p.logDiscarded(fi, pos)
 				// Finish up this path without any expressions.
 				root.Free()
 				return nil
@@ -122,12 +139,12 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 			if p.errHandler != nil {
 				p.errHandler(pos, "immutable or output only field in sub path cannot be updated")
 			}
-			root.Free()
 			return ErrInvalidField
 		}
 
 		// Make the next field selector expression.
 		fs.Field = protoreflect.Name(lit)
+		fs.FieldComplexity = fi.Complexity
 
 		// Check if the next is a period.
 		var isPeriod bool
@@ -143,10 +160,21 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 				if p.errHandler != nil {
 					p.errHandler(pos, "expected message field in sub path")
 				}
-				root.Free()
 				return ErrInvalidField
 			}
 
+			if fi.Desc.IsList() {
+				// A repeated message field can only be traversed with a wildcard, i.e.: list_field.*.sub_field.
+				pos, tok, _ = s.Scan()
+				if tok != token.ASTERISK {
+					if p.errHandler != nil {
+						p.errHandler(pos, "expected wildcard selector for the repeated field")
+					}
+					return ErrInvalidField
+				}
+				return p.handleListWildcard(ue, s, curMsg, fi, root, fs, pos)
+			}
+
 			if fi.Desc.IsMap() {
 				// Scan the map key value.
 				pos, tok, lit = s.Scan()
@@ -161,7 +189,7 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 				case token.ASTERISK:
 					// An asterisk is a wildcard selector.
 					// This means we need to add all the values of the map keys recursively.
-					// TODO: Implement this.
+					return p.handleMapWildcard(ue, s, curMsg, fi, root, fs, pos)
 				}
 
 				// Search for the next period to check whether the selector is a map key or it has subsequent elements.
@@ -184,6 +212,7 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 
 				// This is a valid map key selector now.
 				mke := expr.AcquireMapKeyExpr()
+				fs.Traversal = mke
 				var mkv *expr.ValueExpr
 				switch mk := fi.Desc.MapKey(); mk.Kind() {
 				case protoreflect.BoolKind:
@@ -264,8 +293,12 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 
 				// If it does change current context message value.
 				curMsg = mv.Message()
-				// Set the traversal of the last field selector to be the map key expression.
-				fs.Traversal = mke
+
+				// The next field selector is the traversal of the map key expression.
+				nf := expr.AcquireFieldSelectorExpr()
+				nf.Message = md.FullName()
+				mke.Traversal = nf
+				fs = nf
 				continue
 			}
 
@@ -275,15 +308,7 @@ func (p *Parser) buildPathUpdateExpr(ue *expr.UpdateExpr, msgValue protoreflect.
 			nf := expr.AcquireFieldSelectorExpr()
 			nf.Message = md.FullName()
 
-			// Ensure if the last field selector has already a MapKey traversal.
-			switch ft := fs.Traversal.(type) {
-			case nil:
-				// If the traversal is not already set, then set it to the new field selector.
-				fs.Traversal = nf
-			case *expr.MapKeyExpr:
-				// If the traversal is a map key, then set the map key traversal to the new field selector.
-				ft.Traversal = nf
-			}
+			fs.Traversal = nf
 
 			// Change current context field selector to the new one.
 			fs = nf
@@ -309,7 +334,7 @@ func (p *Parser) handleLastPathElem(ue *expr.UpdateExpr, curMsg protoreflect.Mes
 				mk := fi.Desc.MapKey()
 				mv := fi.Desc.MapValue()
 				var err error
-				fvm.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				rangeSortedMap(fvm, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 					var mkv *expr.ValueExpr
 					switch mk.Kind() {
 					case protoreflect.BoolKind:
@@ -408,9 +433,11 @@ func (p *Parser) handleLastPathElem(ue *expr.UpdateExpr, curMsg protoreflect.Mes
 
 				subUe := expr.AcquireUpdateExpr()
 				if err = p.addMsgAllFieldsExpr(subUe, elem.Message()); err != nil {
+					subUe.Free()
 					ae.Free()
 					return err
 				}
+				ae.Elements = append(ae.Elements, subUe)
 			}
 			ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 				Field: root,
@@ -504,11 +531,9 @@ func (p *Parser) handleLastPathElem(ue *expr.UpdateExpr, curMsg protoreflect.Mes
 				Value: ae,
 			})
 		} else {
-			ve := expr.AcquireValueExpr()
-			ve.Value = fv.String()
 			ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 				Field: root,
-				Value: ve,
+				Value: p.stringValueExpr(fi, fv.String()),
 			})
 		}
 	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
@@ -627,14 +652,11 @@ func (p *Parser) handleLastPathElem(ue *expr.UpdateExpr, curMsg protoreflect.Mes
 }
 
 func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldSelectorExpr, fi info.FieldInfo, mp protoreflect.Map, tok token.Token, pos token.Position, lit string) error {
-	var (
-		mkv protoreflect.MapKey
-		mvv protoreflect.Value
-	)
+	var mkv protoreflect.MapKey
 
-	ke := expr.AcquireMapKeyExpr()
 	mk := fi.Desc.MapKey()
 	fs.Field = fi.Desc.Name()
+	fs.FieldComplexity = fi.Complexity
 	// A map key can only be a string, Int, Uint, Bool.
 	// It cannot be a float, double, message, bytes or enum.
 	switch mk.Kind() {
@@ -646,12 +668,7 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 
 			return ErrInvalidField
 		}
-		v := lit == "true"
-		ve := expr.AcquireValueExpr()
-		ve.Value = v
-		ke.Key = ve
-		mkv = protoreflect.ValueOf(v).MapKey()
-		mvv = mp.Get(mkv)
+		mkv = protoreflect.ValueOfBool(lit == "true").MapKey()
 	case protoreflect.StringKind:
 		if tok != token.STRING && !tok.IsIdent() {
 			if p.errHandler != nil {
@@ -659,11 +676,7 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 			}
 			return ErrInvalidField
 		}
-		ve := expr.AcquireValueExpr()
-		ve.Value = lit
-		ke.Key = ve
-		mkv = protoreflect.ValueOf(lit).MapKey()
-		mvv = mp.Get(mkv)
+		mkv = protoreflect.ValueOfString(lit).MapKey()
 	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
 		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
 		if !tok.IsInteger() {
@@ -679,11 +692,7 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 			}
 			return ErrInvalidField
 		}
-		ve := expr.AcquireValueExpr()
-		ve.Value = iv
-		ke.Key = ve
-		mkv = protoreflect.ValueOf(iv).MapKey()
-		mvv = mp.Get(mkv)
+		mkv = protoreflect.ValueOfInt64(iv).MapKey()
 	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
 		if !tok.IsInteger() {
 			if p.errHandler != nil {
@@ -698,19 +707,15 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 			}
 			return ErrInvalidField
 		}
-		ve := expr.AcquireValueExpr()
-		ve.Value = iv
-		ke.Key = ve
-		mkv = protoreflect.ValueOf(iv).MapKey()
-		mvv = mp.Get(mkv)
+		mkv = protoreflect.ValueOfUint64(iv).MapKey()
 	default:
 		if p.errHandler != nil {
 			p.errHandler(pos, fmt.Sprintf("unsupported map key type: %s", mk.Kind()))
 		}
 		return ErrInvalidField
 	}
-	fs.Traversal = ke
 
+	mvv := mp.Get(mkv)
 	if !mvv.IsValid() {
 		if p.errHandler != nil {
 			p.errHandler(pos, "map key not found in the input message")
@@ -718,38 +723,27 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 		return ErrInvalidField
 	}
 
+	return p.addMapEntryUpdateExpr(ue, root, fs, fi, mkv, mvv, pos)
+}
+
+// addMapEntryUpdateExpr adds the update of the map entry value to the ue.
+// The fs is the map field selector of the root, which gets the map key traversal.
+func (p *Parser) addMapEntryUpdateExpr(ue *expr.UpdateExpr, root, fs *expr.FieldSelectorExpr, fi info.FieldInfo, mkv protoreflect.MapKey, mvv protoreflect.Value, pos token.Position) error {
+	mke := expr.AcquireMapKeyExpr()
+	mke.Key = mapKeyValueExpr(fi.Desc.MapKey(), mkv)
+	fs.Traversal = mke
+
 	var fv expr.UpdateValueExpr
 	switch fi.Desc.MapValue().Kind() {
 	case protoreflect.MessageKind:
 		// This is a special case where the field traversal contains a map key, and each message field is a different
 		// update expression.
-		mke := expr.AcquireMapKeyExpr()
-		kve := expr.AcquireValueExpr()
-		switch mk.Kind() {
-		case protoreflect.BoolKind:
-			kve.Value = mkv.Bool()
-		case protoreflect.StringKind:
-			kve.Value = mkv.String()
-		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
-			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
-			kve.Value = mkv.Int()
-		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
-			kve.Value = mkv.Uint()
-		}
-		mke.Key = kve
-		fs.Traversal = mke
-
 		subUe := expr.AcquireUpdateExpr()
 		if err := p.addMsgAllFieldsExpr(subUe, mvv.Message()); err != nil {
 			subUe.Free()
 			return err
 		}
-
-		// Add the sub update expression to the update expression.
-		ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
-			Field: root,
-			Value: subUe,
-		})
+		fv = subUe
 	case protoreflect.BoolKind:
 		ve := expr.AcquireValueExpr()
 		ve.Value = mvv.Bool()
@@ -793,11 +787,217 @@ func (p *Parser) handleLastMapKeyElem(ue *expr.UpdateExpr, root, fs *expr.FieldS
 	return nil
 }
 
+// handleMapWildcard handles the wildcard map key selector, i.e.: map_field.* or map_field.*.field_name.
+// The wildcard evaluates to all the keys existing in the input message map, and results in the same expressions
+// as if each key was selected explicitly, ordered by the key values.
+// A wildcard of an empty map matches no keys, thus it adds no update expressions for the path.
+func (p *Parser) handleMapWildcard(ue *expr.UpdateExpr, s *scanner.Scanner, curMsg protoreflect.Message, fi info.FieldInfo, root, fs *expr.FieldSelectorExpr, pos token.Position) error {
+	var isPeriod bool
+	s.Peek(func(_ token.Position, t token.Token, _ string) bool {
+		isPeriod = t == token.PERIOD
+		return isPeriod
+	})
+
+	mv := fi.Desc.MapValue()
+	if !isPeriod {
+		// The wildcard needs to be the last element of the path.
+		if npos, tok, lit := s.Scan(); tok != token.EOF {
+			if p.errHandler != nil {
+				p.errHandler(npos, fmt.Sprintf("expected period or end of path after wildcard but got %q", lit))
+			}
+			return ErrInvalidField
+		}
+	} else if mv.Kind() != protoreflect.MessageKind {
+		if p.errHandler != nil {
+			p.errHandler(pos, fmt.Sprintf("field: %q map value is not a message", fi.Desc.Name()))
+		}
+		return ErrInvalidField
+	}
+
+	mp := curMsg.Get(fi.Desc).Map()
+	for _, k := range sortedMapKeys(mp) {
+		// Each key gets its own copy of the path selected so far.
+		clone := root.Clone().(*expr.FieldSelectorExpr)
+		last := lastFieldSelector(clone)
+		if !isPeriod {
+			if err := p.addMapEntryUpdateExpr(ue, clone, last, fi, k, mp.Get(k), pos); err != nil {
+				clone.Free()
+				return err
+			}
+			continue
+		}
+
+		mke := expr.AcquireMapKeyExpr()
+		mke.Key = mapKeyValueExpr(fi.Desc.MapKey(), k)
+		last.Traversal = mke
+
+		nf := expr.AcquireFieldSelectorExpr()
+		nf.Message = mv.Message().FullName()
+		mke.Traversal = nf
+
+		// The rest of the path is scanned separately for each key.
+		sc := *s
+		if err := p.buildSubPathUpdateExpr(ue, &sc, mv.Message(), mp.Get(k).Message(), clone, nf); err != nil {
+			return err
+		}
+	}
+
+	// The root selector was replaced with its clones.
+	root.Free()
+	return nil
+}
+
+// handleListWildcard handles the wildcard selector of the repeated message field, i.e.: list_field.* or list_field.*.sub_field.
+// The list_field.* path is equivalent to the list_field path.
+// The list_field.*.sub_field results in an ArrayUpdateExpr with an update expression of the sub_field for each list element.
+// The field selectors of the element expressions are relative to the element message.
+func (p *Parser) handleListWildcard(ue *expr.UpdateExpr, s *scanner.Scanner, curMsg protoreflect.Message, fi info.FieldInfo, root, fs *expr.FieldSelectorExpr, pos token.Position) error {
+	npos, tok, lit := s.Scan()
+	switch tok {
+	case token.EOF:
+		return p.handleLastPathElem(ue, curMsg, fi, root, fs, pos)
+	case token.PERIOD:
+	default:
+		if p.errHandler != nil {
+			p.errHandler(npos, fmt.Sprintf("expected period or end of path after wildcard but got %q", lit))
+		}
+		return ErrInvalidField
+	}
+
+	md := fi.Desc.Message()
+	aue := expr.AcquireArrayUpdateExpr()
+	ls := curMsg.Get(fi.Desc).List()
+	for i := 0; i < ls.Len(); i++ {
+		elem := ls.Get(i)
+		if !elem.IsValid() {
+			aue.Elements = append(aue.Elements, nil)
+			continue
+		}
+
+		elemRoot := expr.AcquireFieldSelectorExpr()
+		elemRoot.Message = md.FullName()
+
+		// The rest of the path is scanned separately for each element.
+		sc := *s
+		subUe := expr.AcquireUpdateExpr()
+		if err := p.buildSubPathUpdateExpr(subUe, &sc, md, elem.Message(), elemRoot, elemRoot); err != nil {
+			subUe.Free()
+			aue.Free()
+			return err
+		}
+		aue.Elements = append(aue.Elements, subUe)
+	}
+
+	ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
+		Field: root,
+		Value: aue,
+	})
+	return nil
+}
+
+// lastFieldSelector returns the last field selector of the fs traversal chain.
+func lastFieldSelector(fs *expr.FieldSelectorExpr) *expr.FieldSelectorExpr {
+	for {
+		switch t := fs.Traversal.(type) {
+		case *expr.FieldSelectorExpr:
+			fs = t
+		case *expr.MapKeyExpr:
+			next, ok := t.Traversal.(*expr.FieldSelectorExpr)
+			if !ok {
+				return fs
+			}
+			fs = next
+		default:
+			return fs
+		}
+	}
+}
+
+// stringValueExpr returns the update value expression of the string field value.
+// The empty string of a nullable field is a null value if the EmptyStringAsNullOption is set.
+func (p *Parser) stringValueExpr(fi info.FieldInfo, v string) *expr.ValueExpr {
+	ve := expr.AcquireValueExpr()
+	if v != "" || !p.emptyStringAsNull || !fi.Nullable {
+		ve.Value = v
+	}
+	return ve
+}
+
+// mapKeyValueExpr creates a value expression of the map key.
+func mapKeyValueExpr(mk protoreflect.FieldDescriptor, k protoreflect.MapKey) *expr.ValueExpr {
+	ve := expr.AcquireValueExpr()
+	switch mk.Kind() {
+	case protoreflect.BoolKind:
+		ve.Value = k.Bool()
+	case protoreflect.StringKind:
+		ve.Value = k.String()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		ve.Value = k.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		ve.Value = k.Uint()
+	}
+	return ve
+}
+
+// sortedMapKeys returns the keys of the map in ascending order, so that the wildcard expressions are deterministic.
+func sortedMapKeys(mp protoreflect.Map) []protoreflect.MapKey {
+	keys := make([]protoreflect.MapKey, 0, mp.Len())
+	mp.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		switch ki := keys[i].Interface().(type) {
+		case bool:
+			return !ki && keys[j].Bool()
+		case string:
+			return ki < keys[j].String()
+		case int32, int64:
+			return keys[i].Int() < keys[j].Int()
+		case uint32, uint64:
+			return keys[i].Uint() < keys[j].Uint()
+		}
+		return false
+	})
+	return keys
+}
+
+// rangeSortedMap calls fn for each entry of the map in ascending key order, until fn returns false.
+func rangeSortedMap(mp protoreflect.Map, fn func(k protoreflect.MapKey, v protoreflect.Value) bool) {
+	for _, k := range sortedMapKeys(mp) {
+		if !fn(k, mp.Get(k)) {
+			return
+		}
+	}
+}
+
+// fieldsByNumber returns the fields of the message in ascending field number order,
+// so that the expressions of the whole message updates are deterministic.
+// The oneof fields, listed twice in the message info, are returned once with their oneof field info.
+func fieldsByNumber(mi *info.MessageInfo) []info.FieldInfo {
+	fields := make([]info.FieldInfo, len(mi.Fields))
+	copy(fields, mi.Fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Desc.Number() < fields[j].Desc.Number()
+	})
+
+	out := fields[:0]
+	for _, fi := range fields {
+		if n := len(out); n > 0 && out[n-1].Desc.Number() == fi.Desc.Number() {
+			out[n-1] = fi
+			continue
+		}
+		out = append(out, fi)
+	}
+	return out
+}
+
 func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Message) error {
 	msg := subV.Descriptor()
 
 	mi := p.msgInfo.MessageInfo(msg)
-	for _, fi := range mi.Fields {
+	for _, fi := range fieldsByNumber(mi) {
 		// We don't want to update immutable or output only fields.
 		if fi.Immutable || fi.OutputOnly {
 			continue
@@ -823,6 +1023,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 				fs := expr.AcquireFieldSelectorExpr()
 				fs.Message = msg.FullName()
 				fs.Field = fi.Desc.Name()
+				fs.FieldComplexity = fi.Complexity
 				ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 					Field: fs,
 					Value: expr.AcquireValueExpr(),
@@ -861,6 +1062,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 					fs := expr.AcquireFieldSelectorExpr()
 					fs.Message = msg.FullName()
 					fs.Field = fi.Desc.Name()
+					fs.FieldComplexity = fi.Complexity
 					ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 						Field: fs,
 						Value: ae,
@@ -873,6 +1075,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 						fs := expr.AcquireFieldSelectorExpr()
 						fs.Message = msg.FullName()
 						fs.Field = fi.Desc.Name()
+						fs.FieldComplexity = fi.Complexity
 						ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 							Field: fs,
 							Value: expr.AcquireValueExpr(),
@@ -902,6 +1105,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 					fs := expr.AcquireFieldSelectorExpr()
 					fs.Message = msg.FullName()
 					fs.Field = fi.Desc.Name()
+					fs.FieldComplexity = fi.Complexity
 
 					ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 						Field: fs,
@@ -914,7 +1118,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 					}
 					mp := v.Map()
 					var err error
-					mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+					rangeSortedMap(mp, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 						var mkv *expr.ValueExpr
 						switch mk := fi.Desc.MapKey(); mk.Kind() {
 						case protoreflect.BoolKind:
@@ -939,6 +1143,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 						fs := expr.AcquireFieldSelectorExpr()
 						fs.Message = msg.FullName()
 						fs.Field = fi.Desc.Name()
+						fs.FieldComplexity = fi.Complexity
 
 						mke := expr.AcquireMapKeyExpr()
 						mke.Key = mkv
@@ -959,6 +1164,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 								ve.Value = p.extractDurationValue(v.Message())
 								ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 									Field: fs,
+									Value: ve,
 								})
 							default:
 								subUe := expr.AcquireUpdateExpr()
@@ -1103,6 +1309,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 			fs := expr.AcquireFieldSelectorExpr()
 			fs.Message = msg.FullName()
 			fs.Field = fi.Desc.Name()
+			fs.FieldComplexity = fi.Complexity
 			ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 				Field: fs,
 				Value: uv,
@@ -1154,6 +1361,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 						fs := expr.AcquireFieldSelectorExpr()
 						fs.Message = msg.FullName()
 						fs.Field = fi.Desc.Name()
+						fs.FieldComplexity = fi.Complexity
 						ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 							Field: fs,
 							Value: expr.AcquireValueExpr(),
@@ -1173,9 +1381,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 			ve.Value = v.Bool()
 			uv = ve
 		case protoreflect.StringKind:
-			ve := expr.AcquireValueExpr()
-			ve.Value = v.String()
-			uv = ve
+			uv = p.stringValueExpr(fi, v.String())
 		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
 			protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
 			ve := expr.AcquireValueExpr()
@@ -1208,6 +1414,7 @@ func (p *Parser) addMsgAllFieldsExpr(ue *expr.UpdateExpr, subV protoreflect.Mess
 		rc := expr.AcquireFieldSelectorExpr()
 		rc.Message = msg.FullName()
 		rc.Field = fi.Desc.Name()
+		rc.FieldComplexity = fi.Complexity
 
 		ue.Elements = append(ue.Elements, expr.UpdateFieldValue{
 			Field: rc,
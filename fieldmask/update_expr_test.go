@@ -16,6 +16,8 @@ package fieldmask
 
 import (
 	"math"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -745,6 +747,226 @@ func TestParseUpdateExpr(t *testing.T) {
 	}
 }
 
+func TestParseUpdateExpr_Wildcard(t *testing.T) {
+	// mapKeys returns the keys of the map key selectors of the update elements.
+	mapKeys := func(t *testing.T, x *expr.UpdateExpr, field protoreflect.Name) []any {
+		var keys []any
+		for _, el := range x.Elements {
+			if el.Field.Field != field {
+				t.Fatalf("el.Field.Field = %v, want %v", el.Field.Field, field)
+			}
+			mk, ok := el.Field.Traversal.(*expr.MapKeyExpr)
+			if !ok {
+				t.Fatalf("el.Field.Traversal is not a MapKeyExpr but %T", el.Field.Traversal)
+			}
+			kv, ok := mk.Key.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("map key is not a ValueExpr but %T", mk.Key)
+			}
+			keys = append(keys, kv.Value)
+		}
+		return keys
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		msg     *testpb.Message
+		wantErr bool
+		check   func(t *testing.T, x *expr.UpdateExpr)
+	}{
+		{
+			name:  "map scalar wildcard",
+			paths: []string{"map_str_str.*"},
+			msg: &testpb.Message{
+				MapStrStr: map[string]string{"b": "2", "a": "1"},
+			},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 2 {
+					t.Fatalf("len(expr.Elements) = %v, want 2", len(x.Elements))
+				}
+				keys := mapKeys(t, x, "map_str_str")
+				if keys[0] != "a" || keys[1] != "b" {
+					t.Errorf("keys = %v, want [a b]", keys)
+				}
+				for i, want := range []string{"1", "2"} {
+					ev, ok := x.Elements[i].Value.(*expr.ValueExpr)
+					if !ok {
+						t.Fatalf("el.Value is not a ValueExpr but %T", x.Elements[i].Value)
+					}
+					if ev.Value != want {
+						t.Errorf("el.Value = %v, want %v", ev.Value, want)
+					}
+				}
+			},
+		},
+		{
+			name:  "map message wildcard sub field",
+			paths: []string{"map_str_msg.*.name"},
+			msg: &testpb.Message{
+				MapStrMsg: map[string]*testpb.Message{
+					"second": {Name: "n2"},
+					"first":  {Name: "n1"},
+				},
+			},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 2 {
+					t.Fatalf("len(expr.Elements) = %v, want 2", len(x.Elements))
+				}
+				keys := mapKeys(t, x, "map_str_msg")
+				if keys[0] != "first" || keys[1] != "second" {
+					t.Errorf("keys = %v, want [first second]", keys)
+				}
+				for i, want := range []string{"n1", "n2"} {
+					el := x.Elements[i]
+					mk := el.Field.Traversal.(*expr.MapKeyExpr)
+					nf, ok := mk.Traversal.(*expr.FieldSelectorExpr)
+					if !ok {
+						t.Fatalf("map key traversal is not a FieldSelectorExpr but %T", mk.Traversal)
+					}
+					if nf.Field != "name" || nf.Message != "testpb.Message" {
+						t.Errorf("map key traversal = %s.%s, want testpb.Message.name", nf.Message, nf.Field)
+					}
+					ev, ok := el.Value.(*expr.ValueExpr)
+					if !ok {
+						t.Fatalf("el.Value is not a ValueExpr but %T", el.Value)
+					}
+					if ev.Value != want {
+						t.Errorf("el.Value = %v, want %v", ev.Value, want)
+					}
+				}
+			},
+		},
+		{
+			name:  "map key sub field",
+			paths: []string{"map_str_msg.key.name"},
+			msg: &testpb.Message{
+				MapStrMsg: map[string]*testpb.Message{"key": {Name: "n"}},
+			},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 1 {
+					t.Fatalf("len(expr.Elements) = %v, want 1", len(x.Elements))
+				}
+				keys := mapKeys(t, x, "map_str_msg")
+				if keys[0] != "key" {
+					t.Errorf("keys = %v, want [key]", keys)
+				}
+				mk := x.Elements[0].Field.Traversal.(*expr.MapKeyExpr)
+				nf, ok := mk.Traversal.(*expr.FieldSelectorExpr)
+				if !ok || nf.Field != "name" {
+					t.Fatalf("map key traversal is not the name field selector: %v", mk.Traversal)
+				}
+			},
+		},
+		{
+			name:  "empty map wildcard",
+			paths: []string{"map_str_msg.*.name"},
+			msg:   &testpb.Message{},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 0 {
+					t.Errorf("len(expr.Elements) = %v, want 0", len(x.Elements))
+				}
+			},
+		},
+		{
+			name:  "list wildcard sub field",
+			paths: []string{"rp_sub.*.name"},
+			msg: &testpb.Message{
+				RpSub: []*testpb.Message{{Name: "e1"}, {Name: "e2"}},
+			},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 1 {
+					t.Fatalf("len(expr.Elements) = %v, want 1", len(x.Elements))
+				}
+				el := x.Elements[0]
+				if el.Field.Field != "rp_sub" {
+					t.Errorf("el.Field.Field = %v, want rp_sub", el.Field.Field)
+				}
+				aue, ok := el.Value.(*expr.ArrayUpdateExpr)
+				if !ok {
+					t.Fatalf("el.Value is not an ArrayUpdateExpr but %T", el.Value)
+				}
+				if len(aue.Elements) != 2 {
+					t.Fatalf("len(aue.Elements) = %v, want 2", len(aue.Elements))
+				}
+				for i, want := range []string{"e1", "e2"} {
+					sub := aue.Elements[i]
+					if len(sub.Elements) != 1 {
+						t.Fatalf("len(sub.Elements) = %v, want 1", len(sub.Elements))
+					}
+					if sub.Elements[0].Field.Field != "name" {
+						t.Errorf("sub field = %v, want name", sub.Elements[0].Field.Field)
+					}
+					ev, ok := sub.Elements[0].Value.(*expr.ValueExpr)
+					if !ok {
+						t.Fatalf("sub value is not a ValueExpr but %T", sub.Elements[0].Value)
+					}
+					if ev.Value != want {
+						t.Errorf("sub value = %v, want %v", ev.Value, want)
+					}
+				}
+			},
+		},
+		{
+			name:  "list wildcard",
+			paths: []string{"rp_sub.*"},
+			msg: &testpb.Message{
+				RpSub: []*testpb.Message{{Name: "e1"}},
+			},
+			check: func(t *testing.T, x *expr.UpdateExpr) {
+				if len(x.Elements) != 1 {
+					t.Fatalf("len(expr.Elements) = %v, want 1", len(x.Elements))
+				}
+				aue, ok := x.Elements[0].Value.(*expr.ArrayUpdateExpr)
+				if !ok {
+					t.Fatalf("el.Value is not an ArrayUpdateExpr but %T", x.Elements[0].Value)
+				}
+				if len(aue.Elements) != 1 || len(aue.Elements[0].Elements) == 0 {
+					t.Errorf("expected a single element with the message fields")
+				}
+			},
+		},
+		{
+			name:    "list without wildcard",
+			paths:   []string{"rp_sub.name"},
+			msg:     &testpb.Message{RpSub: []*testpb.Message{{Name: "e1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "scalar map wildcard sub field",
+			paths:   []string{"map_str_str.*.name"},
+			msg:     &testpb.Message{MapStrStr: map[string]string{"a": "1"}},
+			wantErr: true,
+		},
+		{
+			name:    "list wildcard unknown sub field",
+			paths:   []string{"rp_sub.*.unknown"},
+			msg:     &testpb.Message{RpSub: []*testpb.Message{{Name: "e1"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if err := p.Reset(new(testpb.Message)); err != nil {
+				t.Fatalf("Reset() error = %v", err)
+			}
+
+			got, err := p.ParseUpdateExpr(tt.msg, &fieldmaskpb.FieldMask{Paths: tt.paths})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUpdateExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer got.Free()
+
+			tt.check(t, got)
+		})
+	}
+}
+
 func TestProtoReflectMessageFields(t *testing.T) {
 	msg := testpb.Message{
 		Name: "test",
@@ -761,3 +983,251 @@ func TestProtoReflectMessageFields(t *testing.T) {
 		return true
 	})
 }
+
+func TestParseUpdateExpr_Metrics(t *testing.T) {
+	var p Parser
+	if err := p.Reset(new(testpb.Message)); err != nil {
+		t.Fatalf("failed to reset parser: %v", err)
+	}
+	msg := &testpb.Message{
+		I32Complexity: 1,
+		Sub:           &testpb.Message{Name: "sub"},
+		RpStr:         []string{"a", "b", "c"},
+		MapStrStr:     map[string]string{"a": "1"},
+	}
+	x, err := p.ParseUpdateExpr(msg, &fieldmaskpb.FieldMask{Paths: []string{"i32_complexity", "sub.name", "rp_str", "map_str_str.a"}})
+	if err != nil {
+		t.Fatalf("failed to parse update expression: %v", err)
+	}
+	defer x.Free()
+
+	m := x.Metrics()
+	want := []expr.UpdatePathSize{
+		{Path: "i32_complexity", UpdateSize: expr.UpdateSize{Fields: 1, Depth: 1, Values: 1, Complexity: 45}},
+		{Path: "sub.name", UpdateSize: expr.UpdateSize{Fields: 1, Depth: 2, Values: 1, Complexity: 3}},
+		{Path: "rp_str", UpdateSize: expr.UpdateSize{Fields: 1, Depth: 1, Values: 3, MaxLen: 3, Complexity: 5}},
+		{Path: "map_str_str.a", UpdateSize: expr.UpdateSize{Fields: 1, Depth: 2, Values: 1, Complexity: 3}},
+	}
+	if len(m.Paths) != len(want) {
+		t.Fatalf("len(m.Paths) = %v, want %v", len(m.Paths), len(want))
+	}
+	for i := range want {
+		if m.Paths[i] != want[i] {
+			t.Errorf("m.Paths[%d] = %+v, want %+v", i, m.Paths[i], want[i])
+		}
+	}
+	total := expr.UpdateSize{Fields: 4, Depth: 2, Values: 6, MaxLen: 3, Complexity: 56}
+	if m.UpdateSize != total {
+		t.Errorf("m.UpdateSize = %+v, want %+v", m.UpdateSize, total)
+	}
+	if c := x.Complexity(); c != total.Complexity {
+		t.Errorf("x.Complexity() = %v, want %v", c, total.Complexity)
+	}
+}
+
+func TestParseUpdateExpr_TimeFidelity(t *testing.T) {
+	var p Parser
+	if err := p.Reset(new(testpb.Message)); err != nil {
+		t.Fatalf("failed to reset parser: %v", err)
+	}
+	msg := &testpb.Message{
+		Timestamp: &timestamppb.Timestamp{Seconds: 1700000000, Nanos: 999999999},
+		Duration:  &durationpb.Duration{Seconds: 315360000, Nanos: 1},
+		Sub: &testpb.Message{
+			MapStrDuration: map[string]*durationpb.Duration{"a": {Seconds: -1, Nanos: -1}},
+		},
+	}
+	x, err := p.ParseUpdateExpr(msg, &fieldmaskpb.FieldMask{Paths: []string{"timestamp", "duration", "sub"}})
+	if err != nil {
+		t.Fatalf("failed to parse update expression: %v", err)
+	}
+	defer x.Free()
+
+	if len(x.Elements) != 3 {
+		t.Fatalf("len(expr.Elements) = %v, want 3", len(x.Elements))
+	}
+
+	tv := x.Elements[0].Value.(*expr.ValueExpr).Value.(time.Time)
+	if want := msg.Timestamp.AsTime(); !tv.Equal(want) || tv.Location() != time.UTC {
+		t.Errorf("timestamp = %v, want %v", tv, want)
+	}
+	if got := tv.Format(time.RFC3339Nano); got != "2023-11-14T22:13:20.999999999Z" {
+		t.Errorf("timestamp = %s, want 2023-11-14T22:13:20.999999999Z", got)
+	}
+
+	dv := x.Elements[1].Value.(*expr.ValueExpr).Value.(time.Duration)
+	if want := 315360000*time.Second + time.Nanosecond; dv != want {
+		t.Errorf("duration = %v, want %v", dv, want)
+	}
+
+	sub, ok := x.Elements[2].Value.(*expr.UpdateExpr)
+	if !ok {
+		t.Fatalf("sub value is not an UpdateExpr but %T", x.Elements[2].Value)
+	}
+	var found bool
+	for _, el := range sub.Elements {
+		if el.Field.Field != "map_str_duration" {
+			continue
+		}
+		found = true
+		ve, ok := el.Value.(*expr.ValueExpr)
+		if !ok {
+			t.Fatalf("map duration value is not a ValueExpr but %T", el.Value)
+		}
+		if want := -time.Second - time.Nanosecond; ve.Value != want {
+			t.Errorf("map duration = %v, want %v", ve.Value, want)
+		}
+	}
+	if !found {
+		t.Errorf("map_str_duration element not found")
+	}
+}
+
+func TestParseUpdateExpr_EmptyStringAsNull(t *testing.T) {
+	tc := []struct {
+		name string
+		opts []OptionFn
+		msg  *testpb.Message
+		want []any
+	}{
+		{name: "distinct", msg: &testpb.Message{}, want: []any{"", ""}},
+		{name: "empty as null", opts: []OptionFn{EmptyStringAsNullOption}, msg: &testpb.Message{}, want: []any{nil, ""}},
+		{name: "non empty", opts: []OptionFn{EmptyStringAsNullOption}, msg: &testpb.Message{StrOptional: "a", Str: "b"}, want: []any{"a", "b"}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if err := p.Reset(new(testpb.Message), tt.opts...); err != nil {
+				t.Fatalf("failed to reset parser: %v", err)
+			}
+			x, err := p.ParseUpdateExpr(tt.msg, &fieldmaskpb.FieldMask{Paths: []string{"str_optional", "str"}})
+			if err != nil {
+				t.Fatalf("failed to parse update expression: %v", err)
+			}
+			defer x.Free()
+
+			if len(x.Elements) != len(tt.want) {
+				t.Fatalf("len(expr.Elements) = %v, want %v", len(x.Elements), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := x.Elements[i].Value.(*expr.ValueExpr).Value; got != want {
+					t.Errorf("%s = %#v, want %#v", x.Elements[i].Field.Field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUpdateExpr_Order(t *testing.T) {
+	msg := &testpb.Message{
+		Str: "foo",
+		I32: 1,
+		Sub: &testpb.Message{
+			Str:       "bar",
+			MapStrI32: map[string]int32{"c": 3, "a": 1, "b": 2},
+			Oneof:     &testpb.Message_OneofStr{OneofStr: "baz"},
+		},
+	}
+	md := msg.ProtoReflect().Descriptor()
+
+	// The result must not depend on the map iteration order.
+	for n := 0; n < 10; n++ {
+		var p Parser
+		if err := p.Reset(msg); err != nil {
+			t.Fatalf("failed to reset parser: %v", err)
+		}
+		x, err := p.ParseUpdateExpr(msg, &fieldmaskpb.FieldMask{Paths: []string{"sub", "str", "i32"}})
+		if err != nil {
+			t.Fatalf("failed to parse update expression: %v", err)
+		}
+
+		var paths []string
+		for _, e := range x.Elements {
+			paths = append(paths, string(e.Field.Field))
+		}
+		if want := []string{"sub", "str", "i32"}; !slices.Equal(paths, want) {
+			t.Fatalf("paths = %v, want %v", paths, want)
+		}
+
+		var (
+			last   protoreflect.FieldNumber
+			keys   []string
+			oneofs int
+		)
+		for _, e := range x.Elements[0].Value.(*expr.UpdateExpr).Elements {
+			num := md.Fields().ByName(e.Field.Field).Number()
+			if num < last {
+				t.Fatalf("field %s is out of the field number order", e.Field.Field)
+			}
+			last = num
+
+			switch e.Field.Field {
+			case "map_str_i32":
+				keys = append(keys, e.Field.Traversal.(*expr.MapKeyExpr).Key.(*expr.ValueExpr).Value.(string))
+			case "oneof_str":
+				oneofs++
+			}
+		}
+		if want := []string{"a", "b", "c"}; !slices.Equal(keys, want) {
+			t.Errorf("map keys = %v, want %v", keys, want)
+		}
+		if oneofs != 1 {
+			t.Errorf("oneof_str occurrences = %d, want 1", oneofs)
+		}
+		x.Free()
+	}
+}
+
+func TestDurationOf(t *testing.T) {
+	tests := []struct {
+		seconds, nanos int64
+		want           time.Duration
+	}{
+		{seconds: 1, nanos: 1, want: time.Second + time.Nanosecond},
+		{seconds: -1, nanos: -500000000, want: -1500 * time.Millisecond},
+		{seconds: 315576000000, want: math.MaxInt64},
+		{seconds: -315576000000, want: math.MinInt64},
+		{seconds: math.MaxInt64 / int64(time.Second), nanos: 999999999, want: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		if got := durationOf(tt.seconds, tt.nanos); got != tt.want {
+			t.Errorf("durationOf(%d, %d) = %v, want %v", tt.seconds, tt.nanos, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkParser_ParseUpdateExpr(b *testing.B) {
+	msg := &testpb.Message{
+		Str:       "foo",
+		I32:       1,
+		Sub:       &testpb.Message{Name: "bar", I64: 2},
+		RpStr:     []string{"a", "b"},
+		MapStrI32: map[string]int32{"a": 1},
+	}
+	singular := []string{"str", "i32", "i64", "bool", "sub.name", "sub.i64", "rp_str", "map_str_i32.a"}
+
+	for _, n := range []int{len(singular), 256} {
+		paths := make([]string, 0, n)
+		for len(paths) < n {
+			paths = append(paths, singular...)
+		}
+		mask := &fieldmaskpb.FieldMask{Paths: paths[:n]}
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var p Parser
+			if err := p.Reset(msg); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				x, err := p.ParseUpdateExpr(msg, mask)
+				if err != nil {
+					b.Fatal(err)
+				}
+				x.Free()
+			}
+		})
+	}
+}
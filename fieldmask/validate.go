@@ -0,0 +1,215 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// Violation is a single violation of the field mask path found by the Validate.
+type Violation struct {
+	// Path is the field mask path that contains the violation.
+	Path string
+
+	// Pos is the position of the violation within the path.
+	Pos token.Position
+
+	// Msg is a human-readable description of the violation.
+	Msg string
+
+	// Err is the cause of the violation, i.e.: ErrInvalidField or ErrInvalidSyntax.
+	Err error
+}
+
+// Error returns the string representation of the violation.
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%q:%d: %s", v.Path, v.Pos, v.Msg)
+}
+
+// Unwrap returns the cause of the violation.
+func (v *Violation) Unwrap() error {
+	return v.Err
+}
+
+// ValidationError is an error returned by the Validate, which contains all the violations of the field mask.
+// It matches each of its violation causes with the errors.Is, i.e.: errors.Is(err, ErrInvalidField).
+type ValidationError struct {
+	Violations []*Violation
+}
+
+// Error returns the string representation of all the violations.
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("invalid field mask: ")
+	for i, v := range e.Violations {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(v.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the violations as the errors.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// Validate checks the field mask against the message descriptor, without the need of the message instance,
+// following the update mask semantics of the AIP-134.
+// It verifies the syntax of each path, whether the selected fields exist, and whether they are not marked
+// as OUTPUT_ONLY or IMMUTABLE, thus the mask could be rejected before loading the resource.
+// A single "*" path denotes the full replacement and is always valid.
+// The map fields could be selected by the key or a wildcard, i.e.: "labels.key" or "map_field.*.sub_field",
+// and the repeated message fields could only be traversed with a wildcard, i.e.: "list_field.*.sub_field".
+// All the violations are returned within the *ValidationError.
+func Validate(mask *fieldmaskpb.FieldMask, desc protoreflect.MessageDescriptor) error {
+	if desc == nil {
+		return ErrInternalError
+	}
+
+	var ve ValidationError
+	mi := info.MapMsgInfo(desc)
+	for _, path := range mask.GetPaths() {
+		ve.Violations = validatePath(ve.Violations, mi, desc, path)
+	}
+	if len(ve.Violations) == 0 {
+		return nil
+	}
+	return &ve
+}
+
+func validatePath(vs []*Violation, mi info.MessagesInfo, md protoreflect.MessageDescriptor, path string) []*Violation {
+	add := func(pos token.Position, err error, msg string) {
+		vs = append(vs, &Violation{Path: path, Pos: pos, Msg: msg, Err: err})
+	}
+
+	if path == "" {
+		add(0, ErrInvalidSyntax, "empty path")
+		return vs
+	}
+	if path == "*" {
+		return vs
+	}
+
+	var s scanner.Scanner
+	s.Reset(path, nil)
+	for {
+		pos, tok, lit := s.Scan()
+		if !tok.IsIdent() {
+			add(pos, ErrInvalidSyntax, fmt.Sprintf("expected field name but got %q", lit))
+			return vs
+		}
+
+		fi, ok := mi.MessageInfo(md).FieldByName(protoreflect.Name(lit))
+		if !ok {
+			add(pos, ErrInvalidField, fmt.Sprintf("field %q not found in the message %s", lit, md.FullName()))
+			return vs
+		}
+		if fi.OutputOnly {
+			add(pos, ErrInvalidField, fmt.Sprintf("field %q is output only", lit))
+		}
+		if fi.Immutable {
+			add(pos, ErrInvalidField, fmt.Sprintf("field %q is immutable", lit))
+		}
+
+		if !expectPeriod(&s, add) {
+			return vs
+		}
+
+		switch {
+		case fi.Desc.IsMap():
+			pos, tok, lit = s.Scan()
+			if tok != token.ASTERISK && !validMapKey(fi.Desc.MapKey(), tok, lit) {
+				add(pos, ErrInvalidField, fmt.Sprintf("invalid %s map key of the field %q: %q", fi.Desc.MapKey().Kind(), fi.Desc.Name(), lit))
+				return vs
+			}
+			if !expectPeriod(&s, add) {
+				return vs
+			}
+			if fi.Desc.MapValue().Kind() != protoreflect.MessageKind {
+				add(pos, ErrInvalidField, fmt.Sprintf("field %q map value is not a message", fi.Desc.Name()))
+				return vs
+			}
+			md = fi.Desc.MapValue().Message()
+		case fi.Desc.Kind() != protoreflect.MessageKind:
+			add(pos, ErrInvalidField, fmt.Sprintf("field %q is not a message, cannot select its sub fields", lit))
+			return vs
+		case fi.Desc.IsList():
+			// A repeated message field can only be traversed with a wildcard.
+			pos, tok, lit = s.Scan()
+			if tok != token.ASTERISK {
+				add(pos, ErrInvalidField, fmt.Sprintf("expected wildcard selector of the repeated field %q but got %q", fi.Desc.Name(), lit))
+				return vs
+			}
+			if !expectPeriod(&s, add) {
+				return vs
+			}
+			md = fi.Desc.Message()
+		default:
+			md = fi.Desc.Message()
+		}
+	}
+}
+
+// expectPeriod scans the token following a path element.
+// It returns true if the path continues with a period, and false on the end of the path or a syntax violation.
+func expectPeriod(s *scanner.Scanner, add func(pos token.Position, err error, msg string)) bool {
+	pos, tok, lit := s.Scan()
+	switch tok {
+	case token.PERIOD:
+		return true
+	case token.EOF:
+	default:
+		add(pos, ErrInvalidSyntax, fmt.Sprintf("expected period or end of path but got %q", lit))
+	}
+	return false
+}
+
+// validMapKey checks if the literal is a valid value of the map key.
+func validMapKey(mk protoreflect.FieldDescriptor, tok token.Token, lit string) bool {
+	switch mk.Kind() {
+	case protoreflect.BoolKind:
+		return tok.IsBoolean()
+	case protoreflect.StringKind:
+		return tok == token.STRING || tok.IsIdent()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err := strconv.ParseInt(lit, 10, 32)
+		return tok.IsInteger() && err == nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		_, err := strconv.ParseInt(lit, 10, 64)
+		return tok.IsInteger() && err == nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err := strconv.ParseUint(lit, 10, 32)
+		return tok.IsInteger() && err == nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		_, err := strconv.ParseUint(lit, 10, 64)
+		return tok.IsInteger() && err == nil
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fieldmask
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestValidate(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tests := []struct {
+		name  string
+		paths []string
+		errs  []error
+	}{
+		{name: "no paths"},
+		{name: "full replacement", paths: []string{"*"}},
+		{name: "fields", paths: []string{"str", "sub.name", "timestamp"}},
+		{name: "map key", paths: []string{"map_str_str.key", "sub.map_i32_str.653"}},
+		{name: "map wildcard", paths: []string{"map_str_msg.*.name", "map_str_str.*"}},
+		{name: "list wildcard", paths: []string{"rp_sub.*.name", "rp_sub.*"}},
+		{name: "unknown field", paths: []string{"unknown"}, errs: []error{ErrInvalidField}},
+		{name: "unknown sub field", paths: []string{"sub.unknown"}, errs: []error{ErrInvalidField}},
+		{name: "empty path", paths: []string{""}, errs: []error{ErrInvalidSyntax}},
+		{name: "trailing period", paths: []string{"sub."}, errs: []error{ErrInvalidSyntax}},
+		{name: "double period", paths: []string{"sub..name"}, errs: []error{ErrInvalidSyntax}},
+		{name: "scalar traversal", paths: []string{"str.name"}, errs: []error{ErrInvalidField}},
+		{name: "invalid map key", paths: []string{"sub.map_i32_str.abc"}, errs: []error{ErrInvalidField}},
+		{name: "scalar map value traversal", paths: []string{"map_str_str.key.name"}, errs: []error{ErrInvalidField}},
+		{name: "list without wildcard", paths: []string{"rp_sub.name"}, errs: []error{ErrInvalidField}},
+		{
+			name:  "multiple violations",
+			paths: []string{"unknown", "str", "sub..name"},
+			errs:  []error{ErrInvalidField, ErrInvalidSyntax},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&fieldmaskpb.FieldMask{Paths: tt.paths}, md)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			if len(ve.Violations) != len(tt.errs) {
+				t.Fatalf("Validate() violations = %v, want %d", ve.Violations, len(tt.errs))
+			}
+			for i, want := range tt.errs {
+				if !errors.Is(ve.Violations[i], want) {
+					t.Errorf("violation %d = %v, want %v", i, ve.Violations[i], want)
+				}
+				if !errors.Is(err, want) {
+					t.Errorf("Validate() error = %v, want match %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_FieldBehavior(t *testing.T) {
+	md := testBehaviorMessage(t)
+
+	err := Validate(&fieldmaskpb.FieldMask{Paths: []string{"title", "create_time", "isbn"}}, md)
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+
+	want := []string{"create_time", "isbn"}
+	if len(ve.Violations) != len(want) {
+		t.Fatalf("Validate() violations = %v, want %d", ve.Violations, len(want))
+	}
+	for i, w := range want {
+		v := ve.Violations[i]
+		if v.Path != w || v.Pos != token.Position(0) || !errors.Is(v, ErrInvalidField) {
+			t.Errorf("violation %d = %v, want path %q at 0", i, v, w)
+		}
+	}
+}
+
+// testBehaviorMessage builds a message with the title, the OUTPUT_ONLY create_time and the IMMUTABLE isbn string fields.
+func testBehaviorMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	behavior := func(b ...annotations.FieldBehavior) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, annotations.E_FieldBehavior, b)
+		return opts
+	}
+	field := func(name string, num int32, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+			Options:  opts,
+		}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testvalidate/book.proto"),
+		Package:    proto.String("testvalidate"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/api/field_behavior.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("title", 1, nil),
+				field("create_time", 2, behavior(annotations.FieldBehavior_OUTPUT_ONLY)),
+				field("isbn", 3, behavior(annotations.FieldBehavior_IMMUTABLE)),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
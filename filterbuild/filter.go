@@ -0,0 +1,215 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filterbuild
+
+import (
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// Value is a type constraint of the field values supported by the typed builders.
+// The generated enum types match the ~int32 and are converted into their protoreflect.EnumNumber.
+type Value interface {
+	~bool | ~string | ~[]byte |
+		~int32 | ~int64 | ~uint32 | ~uint64 | ~float32 | ~float64 |
+		time.Time
+}
+
+// Filter is a composed filter expression.
+// Combining the filter into another one takes over its ownership, thus each Filter value should be used only once.
+type Filter struct {
+	x expr.FilterExpr
+}
+
+// Expr returns the composed filter expression, which needs to be released by the caller with its Free method.
+func (f Filter) Expr() expr.FilterExpr {
+	return f.x
+}
+
+// And returns the conjunction of the filter with the others, i.e.: a AND b.
+func (f Filter) And(others ...Filter) Filter {
+	return And(append([]Filter{f}, others...)...)
+}
+
+// Or returns the disjunction of the filter with the others, i.e.: a OR b.
+func (f Filter) Or(others ...Filter) Filter {
+	return Or(append([]Filter{f}, others...)...)
+}
+
+// Not returns the negation of the filter, i.e.: NOT a.
+func (f Filter) Not() Filter {
+	return Not(f)
+}
+
+// And returns the conjunction of the filters.
+func And(fs ...Filter) Filter {
+	return Filter{x: expr.And(filterExprs(fs)...)}
+}
+
+// Or returns the disjunction of the filters.
+func Or(fs ...Filter) Filter {
+	return Filter{x: expr.Or(filterExprs(fs)...)}
+}
+
+// Not returns the negation of the filter.
+func Not(f Filter) Filter {
+	if f.x == nil {
+		return f
+	}
+	ne := expr.AcquireNotExpr()
+	ne.Expr = f.x
+	return Filter{x: ne}
+}
+
+func filterExprs(fs []Filter) []expr.FilterExpr {
+	xs := make([]expr.FilterExpr, len(fs))
+	for i, f := range fs {
+		xs[i] = f.x
+	}
+	return xs
+}
+
+// Field is a typed selector of a singular field with the values of type T.
+type Field[T Value] struct {
+	sel *expr.FieldSelectorExpr
+}
+
+// NewField creates the typed selector of the field at the dot separated path of the message md,
+// i.e.: "driver.name". It panics if the path is invalid, thus it is meant to be used by the generated code.
+func NewField[T Value](md protoreflect.MessageDescriptor, path string) Field[T] {
+	c := expr.Composer{Desc: md}
+	return Field[T]{sel: c.MustSelect(path)}
+}
+
+// Eq returns the filter of the field equal to v, i.e.: field = v.
+func (f Field[T]) Eq(v T) Filter { return f.compare(expr.EQ, v) }
+
+// Ne returns the filter of the field not equal to v, i.e.: field != v.
+func (f Field[T]) Ne(v T) Filter { return f.compare(expr.NE, v) }
+
+// Lt returns the filter of the field less than v, i.e.: field < v.
+func (f Field[T]) Lt(v T) Filter { return f.compare(expr.LT, v) }
+
+// Le returns the filter of the field less than or equal to v, i.e.: field <= v.
+func (f Field[T]) Le(v T) Filter { return f.compare(expr.LE, v) }
+
+// Gt returns the filter of the field greater than v, i.e.: field > v.
+func (f Field[T]) Gt(v T) Filter { return f.compare(expr.GT, v) }
+
+// Ge returns the filter of the field greater than or equal to v, i.e.: field >= v.
+func (f Field[T]) Ge(v T) Filter { return f.compare(expr.GE, v) }
+
+// In returns the filter of the field equal to one of the values, i.e.: field IN [v1, v2].
+func (f Field[T]) In(vs ...T) Filter {
+	ae := expr.AcquireArrayExpr()
+	for _, v := range vs {
+		ae.Elements = append(ae.Elements, valueExpr(v))
+	}
+	return compareExpr(f.sel, expr.IN, ae)
+}
+
+// Has returns the filter of the field presence, i.e.: field:*.
+func (f Field[T]) Has() Filter {
+	return presenceExpr(f.sel)
+}
+
+func (f Field[T]) compare(cmp expr.Comparator, v T) Filter {
+	return compareExpr(f.sel, cmp, valueExpr(v))
+}
+
+// RepeatedField is a typed selector of a repeated field with the element values of type T.
+type RepeatedField[T Value] struct {
+	sel *expr.FieldSelectorExpr
+}
+
+// NewRepeatedField creates the typed selector of the repeated field at the dot separated path of the message md.
+// It panics if the path is invalid, thus it is meant to be used by the generated code.
+func NewRepeatedField[T Value](md protoreflect.MessageDescriptor, path string) RepeatedField[T] {
+	c := expr.Composer{Desc: md}
+	return RepeatedField[T]{sel: c.MustSelect(path)}
+}
+
+// Contains returns the filter of the field that contains the element v, i.e.: field:v.
+func (f RepeatedField[T]) Contains(v T) Filter {
+	return compareExpr(f.sel, expr.HAS, valueExpr(v))
+}
+
+// Has returns the filter of the field with any element, i.e.: field:*.
+func (f RepeatedField[T]) Has() Filter {
+	return presenceExpr(f.sel)
+}
+
+// MessageField is a selector of a singular message field, which could only be tested for presence.
+type MessageField struct {
+	sel *expr.FieldSelectorExpr
+}
+
+// NewMessageField creates the selector of the message field at the dot separated path of the message md.
+// It panics if the path is invalid, thus it is meant to be used by the generated code.
+func NewMessageField(md protoreflect.MessageDescriptor, path string) MessageField {
+	c := expr.Composer{Desc: md}
+	return MessageField{sel: c.MustSelect(path)}
+}
+
+// Has returns the filter of the message field presence, i.e.: field:*.
+func (f MessageField) Has() Filter {
+	return presenceExpr(f.sel)
+}
+
+func compareExpr(sel *expr.FieldSelectorExpr, cmp expr.Comparator, right expr.FilterExpr) Filter {
+	ce := expr.AcquireCompareExpr()
+	ce.Left = sel.Clone().(*expr.FieldSelectorExpr)
+	ce.Comparator = cmp
+	ce.Right = right
+	return Filter{x: ce}
+}
+
+func presenceExpr(sel *expr.FieldSelectorExpr) Filter {
+	pe := expr.AcquirePresenceExpr()
+	pe.Field = sel.Clone().(*expr.FieldSelectorExpr)
+	return Filter{x: pe}
+}
+
+// valueExpr returns the value expression of v, with the value types matching the filtering.Interpreter results.
+func valueExpr[T Value](v T) *expr.ValueExpr {
+	ve := expr.AcquireValueExpr()
+	switch vt := any(v).(type) {
+	case protoreflect.Enum:
+		ve.Value = vt.Number()
+	case time.Time, time.Duration:
+		ve.Value = vt
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Bool:
+			ve.Value = rv.Bool()
+		case reflect.String:
+			ve.Value = rv.String()
+		case reflect.Slice:
+			ve.Value = rv.Bytes()
+		case reflect.Int32, reflect.Int64:
+			ve.Value = rv.Int()
+		case reflect.Uint32, reflect.Uint64:
+			ve.Value = rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			ve.Value = rv.Float()
+		}
+	}
+	return ve
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filterbuild
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestFilter(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	interpreter, err := filtering.NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	var (
+		str         = NewField[string](md, "str")
+		i32         = NewField[int32](md, "i32")
+		u64         = NewField[uint64](md, "u64")
+		enum        = NewField[testpb.Enum](md, "enum")
+		subName     = NewField[string](md, "sub.name")
+		timestamp   = NewField[time.Time](md, "timestamp")
+		rpStr       = NewRepeatedField[string](md, "rp_str")
+		strOptional = NewField[string](md, "str_optional")
+		sub         = NewMessageField(md, "sub")
+	)
+
+	tc := []struct {
+		name   string
+		filter string
+		build  func() Filter
+	}{
+		{name: "equal", filter: `str = "foo"`, build: func() Filter { return str.Eq("foo") }},
+		{name: "and", filter: `str != "foo" AND i32 >= 1`, build: func() Filter { return str.Ne("foo").And(i32.Ge(1)) }},
+		{name: "or", filter: `i32 < 1 OR u64 > 10`, build: func() Filter { return i32.Lt(1).Or(u64.Gt(10)) }},
+		{name: "not", filter: `NOT sub.name <= "foo"`, build: func() Filter { return subName.Le("foo").Not() }},
+		{name: "in", filter: `str IN ["a", "b"]`, build: func() Filter { return str.In("a", "b") }},
+		{name: "enum", filter: `enum = "ONE"`, build: func() Filter { return enum.Eq(testpb.Enum_ONE) }},
+		{
+			name:   "timestamp",
+			filter: `timestamp > 2023-01-01T00:00:00Z`,
+			build:  func() Filter { return timestamp.Gt(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) },
+		},
+		{name: "contains", filter: `rp_str:"foo"`, build: func() Filter { return rpStr.Contains("foo") }},
+		{name: "presence", filter: `str_optional:*`, build: func() Filter { return strOptional.Has() }},
+		{name: "message presence", filter: `sub:*`, build: func() Filter { return sub.Has() }},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := interpreter.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer want.Free()
+
+			got := tt.build().Expr()
+			defer got.Free()
+
+			if !expr.Equal(got, want) {
+				gs, _ := expr.String(got)
+				ws, _ := expr.String(want)
+				t.Errorf("expected %s but got %s", ws, gs)
+			}
+		})
+	}
+}
+
+func TestFilter_Empty(t *testing.T) {
+	if x := And().Or(Filter{}).Not().Expr(); x != nil {
+		t.Errorf("expected nil expression, got %v", x)
+	}
+}
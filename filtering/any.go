@@ -0,0 +1,190 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+const (
+	// anyFullName is the full name of the google.protobuf.Any message.
+	anyFullName protoreflect.FullName = "google.protobuf.Any"
+	// anyTypeSelector is the JSON name of the google.protobuf.Any type url, i.e.: payload.@type.
+	anyTypeSelector = "@type"
+	// anyUnpackFunction is the name of the type assertion function of the google.protobuf.Any fields.
+	anyUnpackFunction = "any.Unpack"
+)
+
+// AnyTypesOpt is an option that registers the message types, to which the google.protobuf.Any fields could be
+// unpacked in a filter with the `any.Unpack(field, type)` function, i.e.:
+//
+//	any.Unpack(payload, my.pkg.Order).status = "PAID"
+//
+// The unpacked field selector expression traverses from the Any field into the fields of the message type,
+// so that its nested field selector has the message type name set as the Message.
+// Regardless of this option, the type url of an Any field can be selected with its JSON name,
+// i.e. `payload.@type = "type.googleapis.com/my.pkg.Order"`, which resolves to the 'type_url' field.
+func AnyTypesOpt(types ...protoreflect.MessageDescriptor) Option {
+	return func(i *Interpreter) error {
+		for _, md := range types {
+			if md == nil {
+				return errors.New("any type message descriptor is nil")
+			}
+			if i.findAnyType(md.FullName()) != nil {
+				continue
+			}
+			i.anyTypes = append(i.anyTypes, md)
+			i.msgInfo.MapMessage(md)
+		}
+		return nil
+	}
+}
+
+// findAnyType finds the registered Any message type by its full name, or returns nil if it is not registered.
+func (b *Interpreter) findAnyType(name protoreflect.FullName) protoreflect.MessageDescriptor {
+	for _, md := range b.anyTypes {
+		if md.FullName() == name {
+			return md
+		}
+	}
+	return nil
+}
+
+// isAnyUnpackCall returns true if the function call is the any.Unpack type assertion of the registered Any types.
+func (b *Interpreter) isAnyUnpackCall(x *ast.FunctionCall) bool {
+	return len(b.anyTypes) > 0 && x.JoinedNameEquals(anyUnpackFunction)
+}
+
+// tryParseAnyUnpack parses the any.Unpack function call with the fields selected from the unpacked message,
+// into a field selector expression which traverses from the Any field into the fields of the unpacked message.
+func (b *Interpreter) tryParseAnyUnpack(ctx *ParseContext, x *ast.FunctionCall) (TryParseValueResult, error) {
+	if x.ArgList == nil || len(x.ArgList.Args) != 2 {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Lparen
+			res.ErrMsg = fmt.Sprintf("function: %s requires the Any field and the message type arguments", anyUnpackFunction)
+		}
+		return res, ErrInvalidValue
+	}
+
+	field, ok := x.ArgList.Args[0].(*ast.MemberExpr)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.ArgList.Args[0].Position()
+			res.ErrMsg = fmt.Sprintf("function: %s first argument is not a field selector", anyUnpackFunction)
+		}
+		return res, ErrInvalidValue
+	}
+
+	tn, ok := x.ArgList.Args[1].(*ast.MemberExpr)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.ArgList.Args[1].Position()
+			res.ErrMsg = fmt.Sprintf("function: %s second argument is not a message type name", anyUnpackFunction)
+		}
+		return res, ErrInvalidValue
+	}
+	md := b.findAnyType(protoreflect.FullName(tn.JoinedName(true)))
+	if md == nil {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = tn.Position()
+			res.ErrMsg = fmt.Sprintf("function: %s type %q is not a registered Any type", anyUnpackFunction, tn.JoinedName(true))
+		}
+		return res, ErrInvalidValue
+	}
+
+	if len(x.Fields) == 0 {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Rparen
+			res.ErrMsg = fmt.Sprintf("function: %s requires a field of the unpacked message", anyUnpackFunction)
+		}
+		return res, ErrInvalidValue
+	}
+
+	res, err := b.tryParseSelectorExpr(ctx, field.Value, field.Fields...)
+	if err != nil {
+		return res, err
+	}
+	root := res.Expr
+
+	last, mk, fd, ok := b.traverseLastFieldExpr(root)
+	if ok && mk != nil {
+		fd = fd.MapValue()
+	}
+	if !ok || fd.Kind() != protoreflect.MessageKind || fd.Message().FullName() != anyFullName || (mk == nil && fd.IsList()) {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = field.Position()
+			res.ErrMsg = fmt.Sprintf("function: %s first argument %q is not a singular %s field", anyUnpackFunction, selectorPath(root), anyFullName)
+		}
+		root.Free()
+		return res, ErrInvalidValue
+	}
+
+	value, ok := x.Fields[0].(ast.ValueExpr)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Fields[0].Position()
+			res.ErrMsg = "invalid ast"
+		}
+		root.Free()
+		return res, ErrInvalidAST
+	}
+
+	// The fields that follow the call are resolved within the unpacked message.
+	parent := ctx.Message
+	ctx.Message = md
+	sub, err := b.tryParseSelectorExpr(ctx, value, x.Fields[1:]...)
+	ctx.Message = parent
+	if err != nil {
+		root.Free()
+		return sub, err
+	}
+
+	if mk != nil {
+		mk.Traversal = sub.Expr
+	} else {
+		last.Traversal = sub.Expr
+	}
+
+	res = TryParseValueResult{Expr: root}
+	if ctx.fieldAccess == nil {
+		return res, nil
+	}
+	return b.checkFieldAccess(ctx, x.Pos, res)
+}
+
+// findFallbackField finds the field of the md message, which is not its regular field,
+// i.e. the type url of the google.protobuf.Any or a registered extension field.
+// It returns nil if no such field matches the name.
+func (b *Interpreter) findFallbackField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if name == anyTypeSelector && md.FullName() == anyFullName {
+		return md.Fields().ByName("type_url")
+	}
+	if len(b.extensions) == 0 {
+		return nil
+	}
+	return b.findExtensionField(md, name)
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// TryParseAnyElementExpr tries to parse a struct pattern of the repeated message field fd.
+// Each field of the pattern results in an equality comparison of the element field,
+// and all the comparisons are joined into a single expr.AnyElementExpr filter.
+// Fields not defined in the pattern are not compared.
+func (b *Interpreter) TryParseAnyElementExpr(ctx *ParseContext, fd protoreflect.FieldDescriptor, st *ast.StructExpr) (TryParseValueResult, error) {
+	desc := fd.Message()
+
+	ae := expr.AcquireAndExpr()
+	for _, field := range st.Elements {
+		if len(field.Name) != 1 {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = field.Position()
+				res.ErrMsg = fmt.Sprintf("struct pattern of the field %s cannot select nested fields: '%s'", fd.Name(), joinedName(field.Name[0]))
+			}
+			ae.Free()
+			return res, ErrInvalidValue
+		}
+
+		name := protoreflect.Name(field.Name[0].UnquotedString())
+		df := desc.Fields().ByName(name)
+		if df == nil {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = field.Position()
+				res.ErrMsg = fmt.Sprintf("field: %s not found in the message: %s", name, desc.Name())
+			}
+			ae.Free()
+			return res, ErrFieldNotFound
+		}
+
+		for _, prev := range ae.Expr {
+			if prev.(*expr.CompareExpr).Left.(*expr.FieldSelectorExpr).Field == name {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = field.Position()
+					res.ErrMsg = fmt.Sprintf("field %s is duplicated", name)
+				}
+				ae.Free()
+				return res, ErrInvalidValue
+			}
+		}
+
+		fi := b.msgInfo.GetFieldInfo(df)
+		if fi.FilteringForbidden || fi.InputOnly {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = field.Position()
+				res.ErrMsg = fmt.Sprintf("field: %q cannot be used in a filter", name)
+			}
+			ae.Free()
+			return res, ErrInvalidField
+		}
+
+		v, err := b.TryParseValue(ctx, TryParseValueInput{
+			Field:      df,
+			Value:      field.Value,
+			IsOptional: fi.Nullable,
+			Complexity: fi.Complexity,
+		})
+		if err != nil {
+			ae.Free()
+			return v, err
+		}
+
+		sel := expr.AcquireFieldSelectorExpr()
+		sel.Message = desc.FullName()
+		sel.Field = df.Name()
+		sel.FieldComplexity = fi.Complexity
+
+		ce := expr.AcquireCompareExpr()
+		ce.Left = sel
+		ce.Comparator = expr.EQ
+		ce.Right = v.Expr
+		ae.Expr = append(ae.Expr, ce)
+	}
+
+	ax := expr.AcquireAnyElementExpr()
+	ax.Message = desc.FullName()
+	switch len(ae.Expr) {
+	case 0:
+		ae.Free()
+	case 1:
+		ax.Filter = ae.Expr[0]
+		ae.Expr = ae.Expr[:0]
+		ae.Free()
+	default:
+		ax.Filter = ae
+	}
+	return TryParseValueResult{Expr: ax}, nil
+}
+
+// isRepeatedMessage checks if the field is a repeated message field, that is neither a map nor
+// a well-known type represented as a single value.
+func isRepeatedMessage(fd protoreflect.FieldDescriptor, fi info.FieldInfo) bool {
+	return fd.Kind() == protoreflect.MessageKind && fd.Cardinality() == protoreflect.Repeated && !fd.IsMap() &&
+		!fi.IsTimestamp && !fi.IsDuration && !fi.IsStructpb
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_AnyElement(t *testing.T) {
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("single field pattern", func(t *testing.T) {
+		x, err := i.Parse(`rp_sub:{name: "abc"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ce, ok := x.(*expr.CompareExpr)
+		if !ok {
+			t.Fatalf("expected compare expression but got %T", x)
+		}
+		if ce.Comparator != expr.HAS {
+			t.Fatalf("expected comparator %s but got %s", expr.HAS, ce.Comparator)
+		}
+		left, ok := ce.Left.(*expr.FieldSelectorExpr)
+		if !ok || left.Field != "rp_sub" {
+			t.Fatalf("expected rp_sub field selector but got %v", ce.Left)
+		}
+		ae, ok := ce.Right.(*expr.AnyElementExpr)
+		if !ok {
+			t.Fatalf("expected any element expression but got %T", ce.Right)
+		}
+		if ae.Message != md.FullName() {
+			t.Fatalf("expected element message %s but got %s", md.FullName(), ae.Message)
+		}
+		ec, ok := ae.Filter.(*expr.CompareExpr)
+		if !ok {
+			t.Fatalf("expected compare expression element filter but got %T", ae.Filter)
+		}
+		if ec.Comparator != expr.EQ {
+			t.Fatalf("expected comparator %s but got %s", expr.EQ, ec.Comparator)
+		}
+		if ef := ec.Left.(*expr.FieldSelectorExpr); ef.Field != "name" {
+			t.Fatalf("expected field 'name' but got %s", ef.Field)
+		}
+		if ev := ec.Right.(*expr.ValueExpr); ev.Value != "abc" {
+			t.Fatalf("expected value 'abc' but got %v", ev.Value)
+		}
+
+		if err = ValidateExpr(md, x); err != nil {
+			t.Fatalf("expected valid expression but got: %v", err)
+		}
+	})
+
+	t.Run("multiple fields pattern", func(t *testing.T) {
+		x, err := i.Parse(`rp_sub:{name: "abc", i32: 10}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ae := x.(*expr.CompareExpr).Right.(*expr.AnyElementExpr)
+		and, ok := ae.Filter.(*expr.AndExpr)
+		if !ok {
+			t.Fatalf("expected and expression element filter but got %T", ae.Filter)
+		}
+		if len(and.Expr) != 2 {
+			t.Fatalf("expected 2 element comparisons but got %d", len(and.Expr))
+		}
+	})
+
+	t.Run("named struct is an exact match", func(t *testing.T) {
+		x, err := i.Parse(`rp_sub:testpb.Message{name: "abc"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		if _, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr); !ok {
+			t.Fatalf("expected value expression but got %T", x.(*expr.CompareExpr).Right)
+		}
+	})
+
+	errs := []struct {
+		filter string
+		err    error
+	}{
+		{filter: `rp_sub:{unknown: "abc"}`, err: ErrFieldNotFound},
+		{filter: `rp_sub:{name: 1}`, err: ErrInvalidValue},
+		{filter: `rp_sub:{name: "abc", name: "def"}`, err: ErrInvalidValue},
+		{filter: `rp_sub:{no_filter: "abc"}`, err: ErrInvalidField},
+	}
+	for _, tt := range errs {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := i.Parse(tt.filter)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v but got %v", tt.err, err)
+			}
+		})
+	}
+}
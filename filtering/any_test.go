@@ -0,0 +1,198 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_AnyFields(t *testing.T) {
+	files, event, order, _ := testAnyMessage(t)
+
+	i, err := NewInterpreter(event, AnyTypesOpt(order))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   string
+		err    error
+	}{
+		{filter: `payload.@type = "type.googleapis.com/testany.Order"`, want: `payload.type_url = "type.googleapis.com/testany.Order"`},
+		{filter: `any.Unpack(payload, testany.Order).status = "PAID"`, want: `any.Unpack(payload, testany.Order).status = "PAID"`},
+		{filter: `any.Unpack(payload, testany.Order).total > 10 AND name = "a"`, want: `any.Unpack(payload, testany.Order).total > 10 AND name = "a"`},
+		{filter: `any.Unpack(payload, testany.Order).missing = "PAID"`, err: ErrFieldNotFound},
+		{filter: `any.Unpack(payload, testany.Other).status = "PAID"`, err: ErrInvalidValue},
+		{filter: `any.Unpack(name, testany.Order).status = "PAID"`, err: ErrInvalidValue},
+		{filter: `any.Unpack(payload).status = "PAID"`, err: ErrInvalidValue},
+		{filter: `any.Unpack(payload, testany.Order) = "PAID"`, err: ErrInvalidValue},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.StringWithFiles(x, files)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+
+			y, err := i.Parse(got)
+			if err != nil {
+				t.Fatalf("failed to parse rendered filter: %v", err)
+			}
+			defer y.Free()
+			if !expr.Equal(x, y) {
+				t.Errorf("rendered filter parses into a different expression")
+			}
+		})
+	}
+}
+
+func TestInterpreter_AnyUnpackSelector(t *testing.T) {
+	_, event, order, _ := testAnyMessage(t)
+
+	i, err := NewInterpreter(event, AnyTypesOpt(order))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := i.Parse(`any.Unpack(payload, testany.Order).status = "PAID"`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	ce, ok := x.(*expr.CompareExpr)
+	if !ok {
+		t.Fatalf("expected compare expression but got %T", x)
+	}
+	fs := ce.Left.(*expr.FieldSelectorExpr)
+	if fs.Message != "testany.Event" || fs.Field != "payload" {
+		t.Errorf("expected testany.Event.payload selector but got %s.%s", fs.Message, fs.Field)
+	}
+	nested, ok := fs.Traversal.(*expr.FieldSelectorExpr)
+	if !ok {
+		t.Fatalf("expected nested field selector but got %T", fs.Traversal)
+	}
+	if nested.Message != "testany.Order" || nested.Field != "status" {
+		t.Errorf("expected testany.Order.status selector but got %s.%s", nested.Message, nested.Field)
+	}
+}
+
+func TestInterpreter_AnyUnpackUndefined(t *testing.T) {
+	_, event, _, _ := testAnyMessage(t)
+
+	i, err := NewInterpreter(event)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	if _, err = i.Parse(`any.Unpack(payload, testany.Order).status = "PAID"`); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected error %v but got %v", ErrInvalidValue, err)
+	}
+}
+
+// testAnyMessage builds the proto2 testany.Event message with the google.protobuf.Any payload field
+// and its 'priority' and 'order' extensions, along with the testany.Order message.
+func testAnyMessage(t *testing.T) (*protoregistry.Files, protoreflect.MessageDescriptor, protoreflect.MessageDescriptor, []protoreflect.ExtensionType) {
+	t.Helper()
+
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			JsonName: proto.String(name),
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	extension := func(fd *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fd.Extendee = proto.String(".testany.Event")
+		return fd
+	}
+
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(anypb.File_google_protobuf_any_proto); err != nil {
+		t.Fatalf("failed to register any file: %v", err)
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testany/event.proto"),
+		Package:    proto.String("testany"),
+		Syntax:     proto.String("proto2"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Event"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("payload", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Any"),
+				},
+				ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{
+					{Start: proto.Int32(100), End: proto.Int32(200)},
+				},
+			},
+			{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("status", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("total", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				},
+			},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			extension(field("priority", 100, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")),
+			extension(field("order", 101, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".testany.Order")),
+		},
+	}, files)
+	if err != nil {
+		t.Fatalf("failed to build message file: %v", err)
+	}
+	if err = files.RegisterFile(fd); err != nil {
+		t.Fatalf("failed to register message file: %v", err)
+	}
+
+	var xts []protoreflect.ExtensionType
+	for j := 0; j < fd.Extensions().Len(); j++ {
+		xts = append(xts, dynamicpb.NewExtensionType(fd.Extensions().Get(j)))
+	}
+	return files, fd.Messages().ByName("Event"), fd.Messages().ByName("Order"), xts
+}
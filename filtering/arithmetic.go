@@ -0,0 +1,462 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+var (
+	// durationOperandDesc is the descriptor of the duration operands, i.e. of the timestamp additions.
+	durationOperandDesc = &FunctionCallReturningDeclaration{FieldKind: protoreflect.MessageKind, MessageDescriptor: durationMsgDesc}
+
+	// int64OperandDesc is the descriptor of the integer operands, i.e. of the duration multiplications.
+	int64OperandDesc = &FunctionCallReturningDeclaration{FieldKind: protoreflect.Int64Kind}
+)
+
+// TryParseArithmeticExpr tries to parse the arithmetic expression x, resulting in a value of the field fd,
+// i.e. the create_time + duration("24h") of the timestamp field, into the expr.BinaryExpr.
+// The operands are either the values, field selectors or function calls of the types matching the operator:
+//   - the numeric fields support all the operators on the operands of the same kind,
+//   - the timestamp fields support adding and subtracting a duration from a timestamp,
+//   - the duration fields support adding and subtracting the durations, and multiplying or dividing a duration by an integer.
+//
+// The operation on the literal values, i.e. now() - 1h, is folded into the expr.ValueExpr,
+// and results in the ErrInvalidValue if it overflows the type of the field.
+func (b *Interpreter) TryParseArithmeticExpr(ctx *ParseContext, fd FieldDescriptor, x *ast.BinaryExpr) (TryParseValueResult, error) {
+	op := arithmeticOperator(x.Op)
+	lfd, rfd, ok := arithmeticOperands(fd, op)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.OpPos
+			res.ErrMsg = fmt.Sprintf("operator %s is not supported for the value of %s type", x.Op, valueTypeName(fd))
+		}
+		return res, ErrInvalidValue
+	}
+
+	left, err := b.tryParseArithmeticOperand(ctx, lfd, x.Left)
+	if err != nil {
+		return left, err
+	}
+
+	right, err := b.tryParseArithmeticOperand(ctx, rfd, x.Right)
+	if err != nil {
+		left.Expr.Free()
+		return right, err
+	}
+
+	if op == expr.DIV && isZeroValue(right.Expr) {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Right.Position()
+			res.ErrMsg = "division by zero"
+		}
+		left.Expr.Free()
+		right.Expr.Free()
+		return res, ErrInvalidValue
+	}
+
+	// The operation on the literal values is folded into the resulting value.
+	if lv, ok := left.Expr.(*expr.ValueExpr); ok {
+		if rv, ok := right.Expr.(*expr.ValueExpr); ok {
+			v, ok := foldArithmetic(fd, op, lv.Value, rv.Value)
+			left.Expr.Free()
+			right.Expr.Free()
+			if !ok {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = x.OpPos
+					res.ErrMsg = fmt.Sprintf("arithmetic operation %s overflows the %s value", x.Op, valueTypeName(fd))
+				}
+				return res, ErrInvalidValue
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = v
+			return TryParseValueResult{Expr: ve}, nil
+		}
+	}
+
+	be := expr.AcquireBinaryExpr()
+	be.Left = left.Expr
+	be.Op = op
+	be.Right = right.Expr
+	return TryParseValueResult{Expr: be, IsIndirect: left.IsIndirect || right.IsIndirect}, nil
+}
+
+// tryParseArithmeticOperand parses the operand x of the arithmetic expression, of the type of the field fd.
+// The operand is either a value, a function call, a field selector or a nested arithmetic expression.
+func (b *Interpreter) tryParseArithmeticOperand(ctx *ParseContext, fd FieldDescriptor, x ast.ArgExpr) (TryParseValueResult, error) {
+	switch xt := x.(type) {
+	case *ast.BinaryExpr:
+		return b.TryParseArithmeticExpr(ctx, fd, xt)
+	case *ast.FunctionCall:
+		return b.tryParseArithmeticFunctionCall(ctx, fd, xt)
+	case *ast.MemberExpr:
+	default:
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = fmt.Sprintf("arithmetic operand is not a valid %s value: %s", valueTypeName(fd), x.String())
+		}
+		return res, ErrInvalidValue
+	}
+
+	res, err := b.TryParseValue(ctx, TryParseValueInput{
+		Field:         fd,
+		Value:         x,
+		AllowIndirect: true,
+		Complexity:    1,
+	})
+	if err == nil {
+		if vt, ok := res.Expr.(*expr.ValueExpr); ok && vt.Value != nil {
+			return res, nil
+		}
+		res.Expr.Free()
+		var out TryParseValueResult
+		if ctx.ErrHandler != nil {
+			out.ErrPos = x.Position()
+			out.ErrMsg = fmt.Sprintf("arithmetic operand is not a valid %s value: %s", valueTypeName(fd), x.String())
+		}
+		return out, ErrInvalidValue
+	}
+
+	me, ok := x.(*ast.MemberExpr)
+	if !ok || isParamRef(ctx, x) {
+		return res, err
+	}
+
+	// The operand is not a value, try parsing it as a field selector.
+	sel, err2 := b.TryParseSelectorExpr(ctx, me.Value, me.Fields...)
+	if err2 != nil {
+		// Neither a value nor a selector, return the value error.
+		return res, err
+	}
+
+	_, mk, sfd, ok := b.traverseLastFieldExpr(sel.Expr)
+	if !ok {
+		var out TryParseValueResult
+		if ctx.ErrHandler != nil {
+			out.ErrPos = x.Position()
+			out.ErrMsg = "internal error: arithmetic operand is not a field selector expression"
+		}
+		sel.Expr.Free()
+		return out, ErrInternal
+	}
+	if mk != nil {
+		sfd = sfd.MapValue()
+	}
+
+	if (mk == nil && (sfd.IsList() || sfd.IsMap())) || !isSameValueType(b.IsKindComparable, fd, sfd) {
+		var out TryParseValueResult
+		if ctx.ErrHandler != nil {
+			out.ErrPos = x.Position()
+			out.ErrMsg = fmt.Sprintf("arithmetic operand: %s is not of %s type", x.String(), valueTypeName(fd))
+		}
+		sel.Expr.Free()
+		return out, ErrInvalidValue
+	}
+	return TryParseValueResult{Expr: sel.Expr, IsIndirect: true}, nil
+}
+
+// tryParseArithmeticFunctionCall parses the function call operand x of the arithmetic expression,
+// which needs to return a single value of the type of the field fd, i.e. the now() of the timestamp operand.
+func (b *Interpreter) tryParseArithmeticFunctionCall(ctx *ParseContext, fd FieldDescriptor, x *ast.FunctionCall) (TryParseValueResult, error) {
+	fn, ok := b.getFunctionDeclaration(ctx, x)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = fmt.Sprintf("function call %s not found", x.JoinedName())
+		}
+		return res, ErrInvalidValue
+	}
+
+	if fn.ServiceCall() || fn.Returning.IsRepeated || fn.Returning.IsMap() || !isSameValueType(b.IsKindComparable, fd, fn.Returning) {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = fmt.Sprintf("function call %s does not return a %s value", x.JoinedName(), valueTypeName(fd))
+		}
+		return res, ErrInvalidValue
+	}
+
+	res, err := b.tryParseAndCallFunction(ctx, x, fn, true)
+	if err != nil {
+		return res, err
+	}
+	switch vt := res.Expr.(type) {
+	case *expr.ValueExpr:
+		if vt.Value != nil {
+			return res, nil
+		}
+	case *expr.FunctionCallExpr:
+		return res, nil
+	}
+	res.Expr.Free()
+	var out TryParseValueResult
+	if ctx.ErrHandler != nil {
+		out.ErrPos = x.Position()
+		out.ErrMsg = fmt.Sprintf("arithmetic operand is not a valid %s value: %s", valueTypeName(fd), x.String())
+	}
+	return out, ErrInvalidValue
+}
+
+// handleArithmeticRestriction handles the restriction of the left hand side value of the field fd,
+// with the arithmetic expression argument, i.e. 'expire_time < create_time + 24h'.
+func (b *Interpreter) handleArithmeticRestriction(ctx *ParseContext, x *ast.RestrictionExpr, cmp expr.Comparator, left expr.FilterExpr, fd FieldDescriptor, be *ast.BinaryExpr) (TryParseValueResult, error) {
+	if cmp == expr.HAS || cmp == expr.IN {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Comparator.Position()
+			res.ErrMsg = fmt.Sprintf("cannot compare an arithmetic expression with a comparator: %s", x.Comparator.String())
+		}
+		left.Free()
+		return res, ErrInvalidValue
+	}
+	if fd.Cardinality() == protoreflect.Repeated {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = "cannot compare a repeated field with an arithmetic expression"
+		}
+		left.Free()
+		return res, ErrInvalidValue
+	}
+
+	right, err := b.TryParseArithmeticExpr(ctx, fd, be)
+	if err != nil {
+		left.Free()
+		return right, err
+	}
+
+	ce := expr.AcquireCompareExpr()
+	ce.Left = left
+	ce.Comparator = cmp
+	ce.Right = right.Expr
+	return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+}
+
+// arithmeticOperands returns the descriptors of the left and right operands of the operator op,
+// which results in the value of the field fd, or false if the operation is not defined for the field.
+func arithmeticOperands(fd FieldDescriptor, op expr.Operator) (FieldDescriptor, FieldDescriptor, bool) {
+	if op == 0 || fd.IsMap() {
+		return nil, nil, false
+	}
+	switch k := fd.Kind(); {
+	case isIntegerKind(k), isFloatKind(k):
+		return fd, fd, true
+	case k == protoreflect.MessageKind && fd.Message() != nil:
+		switch fd.Message().FullName() {
+		case timestampMsgDesc.FullName():
+			if op == expr.ADD || op == expr.SUB {
+				return fd, durationOperandDesc, true
+			}
+		case durationMsgDesc.FullName():
+			if op == expr.ADD || op == expr.SUB {
+				return fd, fd, true
+			}
+			return fd, int64OperandDesc, true
+		}
+	}
+	return nil, nil, false
+}
+
+// arithmeticOperator returns the expression operator of the ast operator.
+func arithmeticOperator(op ast.OperatorType) expr.Operator {
+	switch op {
+	case ast.ADD:
+		return expr.ADD
+	case ast.SUB:
+		return expr.SUB
+	case ast.MUL:
+		return expr.MUL
+	case ast.DIV:
+		return expr.DIV
+	}
+	return 0
+}
+
+// valueTypeName returns the name of the type of the field values, i.e. int64 or google.protobuf.Timestamp.
+func valueTypeName(fd FieldDescriptor) string {
+	if fd.Kind() == protoreflect.MessageKind && fd.Message() != nil {
+		return string(fd.Message().FullName())
+	}
+	return fd.Kind().String()
+}
+
+// isZeroValue checks if x is the zero numeric value.
+func isZeroValue(x expr.FilterExpr) bool {
+	ve, ok := x.(*expr.ValueExpr)
+	if !ok {
+		return false
+	}
+	switch v := ve.Value.(type) {
+	case int64:
+		return v == 0
+	case int32:
+		return v == 0
+	case uint64:
+		return v == 0
+	case uint32:
+		return v == 0
+	case float64:
+		return v == 0
+	case float32:
+		return v == 0
+	}
+	return false
+}
+
+// foldArithmetic returns the result of the operation op on the literal values l and r, of the type of the field fd,
+// or false if the result overflows the type.
+func foldArithmetic(fd FieldDescriptor, op expr.Operator, l, r any) (any, bool) {
+	switch lv := l.(type) {
+	case int64:
+		rv, ok := r.(int64)
+		if !ok {
+			return nil, false
+		}
+		v, ok := foldInt64(op, lv, rv)
+		if !ok {
+			return nil, false
+		}
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, false
+			}
+		}
+		return v, true
+	case uint64:
+		rv, ok := r.(uint64)
+		if !ok {
+			return nil, false
+		}
+		v, ok := foldUint64(op, lv, rv)
+		if !ok {
+			return nil, false
+		}
+		switch fd.Kind() {
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			if v > math.MaxUint32 {
+				return nil, false
+			}
+		}
+		return v, true
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return nil, false
+		}
+		var v float64
+		switch op {
+		case expr.ADD:
+			v = lv + rv
+		case expr.SUB:
+			v = lv - rv
+		case expr.MUL:
+			v = lv * rv
+		case expr.DIV:
+			v = lv / rv
+		}
+		limit := math.MaxFloat64
+		if fd.Kind() == protoreflect.FloatKind {
+			limit = math.MaxFloat32
+		}
+		if math.IsNaN(v) || math.Abs(v) > limit {
+			return nil, false
+		}
+		return v, true
+	case time.Time:
+		rv, ok := r.(time.Duration)
+		if !ok {
+			return nil, false
+		}
+		if op == expr.SUB {
+			if rv == math.MinInt64 {
+				return nil, false
+			}
+			rv = -rv
+		}
+		v := lv.Add(rv)
+		if (rv > 0 && v.Before(lv)) || (rv < 0 && v.After(lv)) || !timestamppb.New(v).IsValid() {
+			return nil, false
+		}
+		return v, true
+	case time.Duration:
+		var rv int64
+		switch rt := r.(type) {
+		case time.Duration:
+			rv = int64(rt)
+		case int64:
+			rv = rt
+		default:
+			return nil, false
+		}
+		v, ok := foldInt64(op, int64(lv), rv)
+		return time.Duration(v), ok
+	}
+	return nil, false
+}
+
+// foldInt64 returns the result of the operation op on the l and r, or false if it overflows.
+func foldInt64(op expr.Operator, l, r int64) (int64, bool) {
+	switch op {
+	case expr.ADD:
+		v := l + r
+		return v, (v > l) == (r > 0)
+	case expr.SUB:
+		v := l - r
+		return v, (v < l) == (r > 0)
+	case expr.MUL:
+		if l == 0 || r == 0 {
+			return 0, true
+		}
+		v := l * r
+		return v, v/r == l && !(l == -1 && r == math.MinInt64) && !(r == -1 && l == math.MinInt64)
+	case expr.DIV:
+		return l / r, !(l == math.MinInt64 && r == -1)
+	}
+	return 0, false
+}
+
+// foldUint64 returns the result of the operation op on the l and r, or false if it overflows.
+func foldUint64(op expr.Operator, l, r uint64) (uint64, bool) {
+	switch op {
+	case expr.ADD:
+		v := l + r
+		return v, v >= l
+	case expr.SUB:
+		return l - r, l >= r
+	case expr.MUL:
+		if l == 0 || r == 0 {
+			return 0, true
+		}
+		v := l * r
+		return v, v/r == l
+	case expr.DIV:
+		return l / r, true
+	}
+	return 0, false
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_Arithmetic(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	i, err := NewInterpreter(md, RelativeTimeOpt(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   string
+		err    error
+	}{
+		{name: "timestamp plus duration", filter: `timestamp < timestamp_optional + 24h`, want: `timestamp < timestamp_optional + 86400s`},
+		{name: "timestamp minus duration field", filter: `timestamp > 2023-01-01T00:00:00Z - duration`, want: `timestamp > 2023-01-01T00:00:00Z - duration`},
+		{name: "integer precedence", filter: `i64 > i32 + 2 * 3`, want: `i64 > i32 + 6`},
+		{name: "left associative", filter: `i32 = i64 - 1 - 2`, want: `i32 = i64 - 1 - 2`},
+		{name: "float division", filter: `double <= float / 2.5`, want: `double <= float / 2.5`},
+		{name: "duration multiplied", filter: `duration >= duration_optional * 2`, want: `duration >= duration_optional * 2`},
+		{name: "map value", filter: `map_str_i32."foo" > i32 + 1`, want: `map_str_i32.foo > i32 + 1`},
+		{name: "now minus duration", filter: `timestamp < now() - 1h`, want: `timestamp < 2023-05-31T23:00:00Z`},
+		{name: "now plus duration", filter: `timestamp > now() + 24h`, want: `timestamp > 2023-06-02T00:00:00Z`},
+		{name: "now minus zero", filter: `timestamp > now() - 0s`, want: `timestamp > 2023-06-01T00:00:00Z`},
+		{name: "field plus now", filter: `duration > duration_optional + now()`, err: ErrInvalidValue},
+		{name: "folded literals", filter: `i64 = 1 + 2 * 3 - 4 / 2`, want: `i64 = 5`},
+		{name: "folded durations", filter: `duration = 1h + 30m * 2`, want: `duration = 7200s`},
+		{name: "int64 overflow", filter: `i64 = 9223372036854775807 + 1`, err: ErrInvalidValue},
+		{name: "int64 multiplication overflow", filter: `i64 = 4611686018427387904 * 2`, err: ErrInvalidValue},
+		{name: "int32 overflow", filter: `i32 = 2147483647 + 1`, err: ErrInvalidValue},
+		{name: "uint64 underflow", filter: `u64 = 1 - 2`, err: ErrInvalidValue},
+		{name: "uint32 overflow", filter: `u32 = 4294967295 + 1`, err: ErrInvalidValue},
+		{name: "float overflow", filter: `float = 300000000000000000000000000000000000000.0 * 2.0`, err: ErrInvalidValue},
+		{name: "timestamp overflow", filter: `timestamp < 9999-12-31T23:00:00Z + 2h`, err: ErrInvalidValue},
+		{name: "timestamp multiplied", filter: `timestamp < timestamp_optional * 2`, err: ErrInvalidValue},
+		{name: "timestamp plus timestamp", filter: `timestamp < timestamp_optional + timestamp`, err: ErrInvalidValue},
+		{name: "string concatenation", filter: `str = name + "foo"`, err: ErrInvalidValue},
+		{name: "operand type mismatch", filter: `i32 > str + 1`, err: ErrInvalidValue},
+		{name: "repeated operand", filter: `i32 > rp_i32 + 1`, err: ErrInvalidValue},
+		{name: "division by zero", filter: `i32 > i64 / 0`, err: ErrInvalidValue},
+		{name: "has comparator", filter: `rp_i32:i32 + 1`, err: ErrInvalidValue},
+		{name: "repeated field", filter: `rp_i32 = i32 + 1`, err: ErrInvalidValue},
+		{name: "array operand", filter: `i32 = [1, 2] + 1`, err: ErrInvalidValue},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to unparse: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("failed to validate: %v", err)
+			}
+		})
+	}
+}
@@ -20,7 +20,8 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-// ArgExpr is either a Comparable or Composite
+// ArgExpr is either a Comparable or Composite.
+// The argument of a function call could also be a Restriction, i.e. any(items, sku = "abc").
 //
 // EBNF:
 //
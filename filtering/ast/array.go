@@ -28,6 +28,8 @@ var (
 // ArrayExpr is an extended expression not defined by the standard EBNF.
 // It is used to represent an array of expressions.
 // Its values are separated by a comma (,), and surrounded by square brackets ([]).
+// The argument of the IN comparator could also be surrounded by parentheses, i.e.: a IN ("b", "c"),
+// which results in the same ArrayExpr, rendered with the square brackets.
 // The values are either a member expression or a function call (ComparableExpr).
 // By default, the parser is not aware of the array expression.
 // It needs to be set as an extension to the parser.
@@ -0,0 +1,137 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// Compile-time checks to ensure type implements desired interfaces.
+var (
+	_ ArgExpr = (*BinaryExpr)(nil)
+)
+
+// BinaryExpr is an extended expression not defined by the standard EBNF.
+// It is an arithmetic operation on the restriction argument, i.e.: a < b + duration("24h") or a > b * 2.
+// The operators need to be surrounded by the whitespaces, thus 'a -1' is not a subtraction.
+// The multiplicative operators (*, /) bind tighter than the additive ones (+, -),
+// and the operators of the same precedence are left associative, thus the Right operand
+// is never a BinaryExpr of the same or lower precedence.
+//
+// EBNF:
+//
+// arithmetic
+//
+//	: comparable {WS operator WS comparable}
+//	;
+//
+// operator
+//
+//	: PLUS     # +
+//	| MINUS    # -
+//	| ASTERISK # *
+//	| SLASH    # /
+//	;
+type BinaryExpr struct {
+	// Left is the left operand, either a ComparableExpr or a BinaryExpr.
+	Left ArgExpr
+
+	// OpPos is the position of the operator.
+	OpPos token.Position
+
+	// Op is the arithmetic operator.
+	Op OperatorType
+
+	// Right is the right operand, either a ComparableExpr or a BinaryExpr of a higher precedence.
+	Right ArgExpr
+}
+
+// Position returns the position of the left operand.
+func (b *BinaryExpr) Position() token.Position { return b.Left.Position() }
+
+// String returns the string representation of the arithmetic expression.
+func (b *BinaryExpr) String() string {
+	var sb strings.Builder
+	b.WriteStringTo(&sb, false)
+	return sb.String()
+}
+
+// UnquotedString returns the unquoted string.
+func (b *BinaryExpr) UnquotedString() string {
+	var sb strings.Builder
+	b.WriteStringTo(&sb, true)
+	return sb.String()
+}
+
+// WriteStringTo writes the string representation of the value to the builder.
+// If unquoted argument is set to true, the StringLiterals do not write its string
+// representation surrounded with quotes.
+func (b *BinaryExpr) WriteStringTo(sb *strings.Builder, unquoted bool) {
+	b.Left.WriteStringTo(sb, unquoted)
+	sb.WriteByte(' ')
+	sb.WriteString(b.Op.String())
+	sb.WriteByte(' ')
+	b.Right.WriteStringTo(sb, unquoted)
+}
+
+// isAstExpr is a marker method for the interface.
+func (*BinaryExpr) isAstExpr() {}
+
+// isArgExpr is a marker method for the interface.
+func (*BinaryExpr) isArgExpr() {}
+
+var _OperatorTypeStrings = [...]string{
+	ADD: "+",
+	SUB: "-",
+	MUL: "*",
+	DIV: "/",
+}
+
+// OperatorType is a defined type for the arithmetic operators.
+type OperatorType int
+
+// String returns the string representation of the operator.
+func (o OperatorType) String() string {
+	if o < 0 || o > OperatorType(len(_OperatorTypeStrings)-1) {
+		return fmt.Sprintf("OperatorType(%d)", o)
+	}
+	return _OperatorTypeStrings[o]
+}
+
+// Precedence returns the precedence of the operator, the higher binds tighter.
+func (o OperatorType) Precedence() int {
+	switch o {
+	case MUL, DIV:
+		return 2
+	case ADD, SUB:
+		return 1
+	}
+	return 0
+}
+
+const (
+	_ OperatorType = iota
+	// ADD is the addition operator.
+	ADD
+	// SUB is the subtraction operator.
+	SUB
+	// MUL is the multiplication operator.
+	MUL
+	// DIV is the division operator.
+	DIV
+)
@@ -35,37 +35,53 @@ import (
 //	| EQUALS           # =
 //	| HAS              # :
 //	| IN 			     # IN (extension to the standard)
+//	| MATCH            # =~ (extension to the standard)
+//	| NOT_IN           # NOT IN (extension to the standard)
+//	| NOT_HAS          # !: (extension to the standard)
+//	| CUSTOM           # registered custom comparator, i.e. ~ (extension to the standard)
 //	;
 type ComparatorLiteral struct {
 	Pos  token.Position
 	Type ComparatorType
+
+	// Symbol is the symbol of the CUSTOM comparator, i.e. "~".
+	Symbol string
 }
 
 // Position returns the position of the comparator.
 func (c *ComparatorLiteral) Position() token.Position { return c.Pos }
 
 // String returns the string representation of the comparator.
-func (c *ComparatorLiteral) String() string { return c.Type.String() }
+func (c *ComparatorLiteral) String() string {
+	if c.Type == CUSTOM {
+		return c.Symbol
+	}
+	return c.Type.String()
+}
 
 // UnquotedString returns the unquoted string.
-func (c *ComparatorLiteral) UnquotedString() string { return c.Type.String() }
+func (c *ComparatorLiteral) UnquotedString() string { return c.String() }
 
 // WriteStringTo writes the string representation of the comparator to the builder.
 func (c *ComparatorLiteral) WriteStringTo(sb *strings.Builder, unquoted bool) {
-	sb.WriteString(c.Type.String())
+	sb.WriteString(c.String())
 }
 
 func (*ComparatorLiteral) isAstExpr() {}
 
 var _ComparatorTypeStrings = [...]string{
-	LE:  "<=",
-	LT:  "<",
-	GE:  ">=",
-	GT:  ">",
-	NE:  "!=",
-	EQ:  "=",
-	HAS: ":",
-	IN:  "IN",
+	LE:      "<=",
+	LT:      "<",
+	GE:      ">=",
+	GT:      ">",
+	NE:      "!=",
+	EQ:      "=",
+	HAS:     ":",
+	IN:      "IN",
+	MATCH:   "=~",
+	NOT_IN:  "NOT IN",
+	NOT_HAS: "!:",
+	CUSTOM:  "CUSTOM",
 }
 
 // ComparatorType is a defined type for comparators.
@@ -98,4 +114,28 @@ const (
 	// IN is the in comparator that checks if a value is in a list of values.
 	// NOTE: This is an extension to the standard.
 	IN
+	// MATCH is the regular expression match comparator.
+	// NOTE: This is an extension to the standard.
+	MATCH
+	// NOT_IN is the negated in comparator, i.e.: a NOT IN [b, c], equivalent to NOT a IN [b, c].
+	// NOTE: This is an extension to the standard.
+	NOT_IN
+	// NOT_HAS is the negated has comparator, i.e.: a !: b, equivalent to NOT a:b.
+	// NOTE: This is an extension to the standard.
+	NOT_HAS
+	// CUSTOM is a custom comparator registered with the parser, whose symbol is in the ComparatorLiteral.
+	// NOTE: This is an extension to the standard.
+	CUSTOM
 )
+
+// Negated returns the positive comparator of the negated NOT_IN and NOT_HAS comparators, and true.
+// Other comparators are returned as they are, with false.
+func (c ComparatorType) Negated() (ComparatorType, bool) {
+	switch c {
+	case NOT_IN:
+		return IN, true
+	case NOT_HAS:
+		return HAS, true
+	}
+	return c, false
+}
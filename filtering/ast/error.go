@@ -0,0 +1,50 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"strings"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// Compile-time check that *ErrorExpr implements SimpleExpr.
+var _ SimpleExpr = (*ErrorExpr)(nil)
+
+// ErrorExpr is a part of the source that could not be parsed.
+// It is produced only by the parser recovering from the syntax errors,
+// in place of the invalid term, so that the rest of the filter is still parsed.
+type ErrorExpr struct {
+	// Pos is the position of the first character of the invalid source.
+	Pos token.Position
+
+	// Text is the invalid source text, as it was skipped by the parser.
+	Text string
+}
+
+// UnquotedString returns the invalid source text.
+func (e *ErrorExpr) UnquotedString() string { return e.Text }
+
+// String returns the invalid source text.
+func (e *ErrorExpr) String() string { return e.Text }
+
+// WriteStringTo writes the invalid source text to the builder.
+func (e *ErrorExpr) WriteStringTo(sb *strings.Builder, _ bool) { sb.WriteString(e.Text) }
+
+// Position returns the position of the invalid source.
+func (e *ErrorExpr) Position() token.Position { return e.Pos }
+
+func (*ErrorExpr) isSimpleExpr() {}
+func (*ErrorExpr) isAstExpr()    {}
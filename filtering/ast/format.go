@@ -0,0 +1,236 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// FormatOption changes the formatting of the Format function.
+type FormatOption func(f *formatter)
+
+// ParenthesizeOrOption makes the Format function surround the OR factors with the parentheses,
+// whenever they are combined with the other factors, i.e.: 'a OR b AND c' is formatted as '(a OR b) AND c'.
+// The OR operator has a higher precedence than the AND, thus the parentheses do not change the meaning of the filter.
+func ParenthesizeOrOption() FormatOption {
+	return func(f *formatter) {
+		f.parenthesizeOr = true
+	}
+}
+
+// MinusNegationOption makes the Format function write the negations with the minus operator, i.e.: '-a = 1'.
+// By default, the negations are written with the NOT keyword.
+func MinusNegationOption() FormatOption {
+	return func(f *formatter) {
+		f.minusNegation = true
+	}
+}
+
+// SpacedHasOption makes the Format function surround the HAS comparator with the whitespaces, i.e.: 'a : b'.
+// By default, the HAS comparator is written without the whitespaces, i.e.: 'a:b'.
+func SpacedHasOption() FormatOption {
+	return func(f *formatter) {
+		f.spacedHas = true
+	}
+}
+
+// Format returns the normalized filter string of the expression.
+// The keywords are uppercase, the operators, comparators and arguments are separated with a single whitespace
+// and the composite expressions do not contain leading or trailing whitespaces.
+// A nil expression is formatted as an empty string.
+func Format(e *Expr, opts ...FormatOption) string {
+	if e == nil {
+		return ""
+	}
+	var f formatter
+	for _, opt := range opts {
+		opt(&f)
+	}
+	f.expr(e)
+	return f.sb.String()
+}
+
+type formatter struct {
+	sb             strings.Builder
+	parenthesizeOr bool
+	minusNegation  bool
+	spacedHas      bool
+}
+
+func (f *formatter) expr(e *Expr) {
+	single := len(e.Sequences) == 1 && len(e.Sequences[0].Factors) == 1
+	for i, seq := range e.Sequences {
+		if i > 0 {
+			f.sb.WriteString(" AND ")
+		}
+		for j, fe := range seq.Factors {
+			if j > 0 {
+				f.sb.WriteByte(' ')
+			}
+			f.factor(fe, !single)
+		}
+	}
+}
+
+func (f *formatter) factor(fe *FactorExpr, combined bool) {
+	paren := combined && f.parenthesizeOr && len(fe.Terms) > 1
+	if paren {
+		f.sb.WriteByte('(')
+	}
+	for i, t := range fe.Terms {
+		if i > 0 {
+			f.sb.WriteString(" OR ")
+		}
+		f.term(t)
+	}
+	if paren {
+		f.sb.WriteByte(')')
+	}
+}
+
+func (f *formatter) term(t *TermExpr) {
+	if t.HasNegation() {
+		if f.minusNegation {
+			f.sb.WriteByte('-')
+		} else {
+			f.sb.WriteString("NOT ")
+		}
+	}
+	switch st := t.Expr.(type) {
+	case *CompositeExpr:
+		f.composite(st)
+	case *RestrictionExpr:
+		f.restriction(st)
+	default:
+		st.WriteStringTo(&f.sb, false)
+	}
+}
+
+func (f *formatter) composite(c *CompositeExpr) {
+	f.sb.WriteByte('(')
+	if c.Expr != nil {
+		f.expr(c.Expr)
+	}
+	f.sb.WriteByte(')')
+}
+
+func (f *formatter) restriction(r *RestrictionExpr) {
+	f.arg(r.Comparable)
+	if r.IsGlobal() {
+		return
+	}
+	if r.Comparator.Type == HAS && !f.spacedHas {
+		f.sb.WriteByte(':')
+	} else {
+		f.sb.WriteByte(' ')
+		r.Comparator.WriteStringTo(&f.sb, false)
+		f.sb.WriteByte(' ')
+	}
+	f.arg(r.Arg)
+}
+
+func (f *formatter) arg(a AnyExpr) {
+	switch at := a.(type) {
+	case *CompositeExpr:
+		f.composite(at)
+	case *RestrictionExpr:
+		f.restriction(at)
+	case *ArgListExpr:
+		for i, arg := range at.Args {
+			if i > 0 {
+				f.sb.WriteString(", ")
+			}
+			f.arg(arg)
+		}
+	case *ArrayExpr:
+		f.sb.WriteByte('[')
+		for i, elem := range at.Elements {
+			if i > 0 {
+				f.sb.WriteString(", ")
+			}
+			f.arg(elem)
+		}
+		f.sb.WriteByte(']')
+	case *BinaryExpr:
+		f.arg(at.Left)
+		f.sb.WriteByte(' ')
+		f.sb.WriteString(at.Op.String())
+		f.sb.WriteByte(' ')
+		f.arg(at.Right)
+	case *MemberExpr:
+		f.arg(at.Value)
+		for _, fe := range at.Fields {
+			f.sb.WriteByte('.')
+			f.arg(fe)
+		}
+	case *TextLiteral:
+		f.text(at)
+	case *FunctionCall:
+		for i, n := range at.Name {
+			if i > 0 {
+				f.sb.WriteByte('.')
+			}
+			f.arg(n)
+		}
+		f.sb.WriteByte('(')
+		if at.ArgList != nil {
+			f.arg(at.ArgList)
+		}
+		f.sb.WriteByte(')')
+		for _, fe := range at.Fields {
+			f.sb.WriteByte('.')
+			f.arg(fe)
+		}
+	default:
+		at.WriteStringTo(&f.sb, false)
+	}
+}
+
+// text writes the text literal, the identifiers which would be scanned as a keyword or are not a plain text,
+// are surrounded with the backticks, i.e.: `AND`.
+func (f *formatter) text(t *TextLiteral) {
+	if t.Token != token.IDENT || isPlainText(t.Value) {
+		f.sb.WriteString(t.Value)
+		return
+	}
+	f.sb.WriteByte('`')
+	for _, r := range t.Value {
+		if r == '`' || r == '\\' {
+			f.sb.WriteByte('\\')
+		}
+		f.sb.WriteRune(r)
+	}
+	f.sb.WriteByte('`')
+}
+
+// isPlainText checks if the identifier is scanned as a text, without the backticks.
+func isPlainText(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	switch s {
+	case "AND", "OR", "NOT", "IN", "true", "false", "null":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+)
+
+func TestFormat(t *testing.T) {
+	tc := []struct {
+		name   string
+		filter string
+		opts   []ast.FormatOption
+		want   string
+	}{
+		{name: "empty", filter: ``, want: ``},
+		{name: "spacing", filter: `a=1   AND b   >=  2`, want: `a = 1 AND b >= 2`},
+		{name: "has", filter: `a : "b"`, want: `a:"b"`},
+		{name: "spaced has", filter: `a:"b"`, opts: []ast.FormatOption{ast.SpacedHasOption()}, want: `a : "b"`},
+		{name: "negation", filter: `-a = 1 OR NOT   b`, want: `NOT a = 1 OR NOT b`},
+		{name: "minus negation", filter: `NOT a = 1`, opts: []ast.FormatOption{ast.MinusNegationOption()}, want: `-a = 1`},
+		{name: "composite", filter: `(a = 1  OR   b = 2)`, want: `(a = 1 OR b = 2)`},
+		{name: "sequence", filter: `a   b AND c`, want: `a b AND c`},
+		{name: "function", filter: `a = fn( 1,2 )`, want: `a = fn(1, 2)`},
+		{name: "keyword field", filter: "`AND`  =  1 AND sub.`IN`:`x`", want: "`AND` = 1 AND sub.`IN`:x"},
+		{name: "or", filter: `a OR b AND c`, want: `a OR b AND c`},
+		{name: "parenthesize or", filter: `a OR b AND c`, opts: []ast.FormatOption{ast.ParenthesizeOrOption()}, want: `(a OR b) AND c`},
+		{name: "parenthesize single or", filter: `a OR b`, opts: []ast.FormatOption{ast.ParenthesizeOrOption()}, want: `a OR b`},
+		{name: "parenthesize nested or", filter: `(a OR b c)`, opts: []ast.FormatOption{ast.ParenthesizeOrOption()}, want: `((a OR b) c)`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := parser.NewParser(tt.filter).Parse()
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer pf.Free()
+
+			got := ast.Format(pf.Expr, tt.opts...)
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+
+			// The formatted filter is stable.
+			pf2, err := parser.NewParser(got).Parse()
+			if err != nil {
+				t.Fatalf("failed to parse formatted filter: %v", err)
+			}
+			defer pf2.Free()
+			if again := ast.Format(pf2.Expr, tt.opts...); again != got {
+				t.Errorf("expected stable format %s but got %s", got, again)
+			}
+		})
+	}
+}
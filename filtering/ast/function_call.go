@@ -27,10 +27,11 @@ var (
 
 // FunctionCall is a function call expression
 // which mau use simple or qualified names with zero or more arguments.
+// The fields of the function result might follow the call, i.e.: any.Unpack(payload, my.Type).name.
 //
 // function
 //
-//	: name {DOT name} LPAREN [argList] RPAREN
+//	: name {DOT name} LPAREN [argList] RPAREN {DOT field}
 //	;
 //
 // FunctionCall implements ComparableExpr.
@@ -49,6 +50,9 @@ type FunctionCall struct {
 
 	// Rparen is the right parenthesis position.
 	Rparen token.Position
+
+	// Fields is a list of field expressions selected from the function result, DOT separated.
+	Fields []FieldExpr
 }
 
 // JoinedPkgName returns the joined package name of the function.
@@ -142,6 +146,10 @@ func (f *FunctionCall) UnquotedString() string {
 		sb.WriteString(f.ArgList.UnquotedString())
 	}
 	sb.WriteRune(')')
+	for _, fe := range f.Fields {
+		sb.WriteRune('.')
+		sb.WriteString(fe.UnquotedString())
+	}
 	return sb.String()
 }
 
@@ -158,6 +166,10 @@ func (f *FunctionCall) String() string {
 		sb.WriteString(f.ArgList.String())
 	}
 	sb.WriteRune(')')
+	for _, fe := range f.Fields {
+		sb.WriteRune('.')
+		sb.WriteString(fe.String())
+	}
 	return sb.String()
 }
 
@@ -176,6 +188,10 @@ func (f *FunctionCall) WriteStringTo(sb *strings.Builder, unquoted bool) {
 		f.ArgList.WriteStringTo(sb, unquoted)
 	}
 	sb.WriteRune(')')
+	for _, fe := range f.Fields {
+		sb.WriteRune('.')
+		fe.WriteStringTo(sb, unquoted)
+	}
 }
 
 func (f *FunctionCall) Position() token.Position { return f.Pos }
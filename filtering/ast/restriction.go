@@ -21,9 +21,10 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-// Compile-time check that *RestrictionExpr implements SimpleExpr.
+// Compile-time check that *RestrictionExpr implements SimpleExpr and ArgExpr.
 var (
 	_ SimpleExpr = (*RestrictionExpr)(nil)
+	_ ArgExpr    = (*RestrictionExpr)(nil)
 )
 
 // RestrictionExpr express a relationship between a comparable value and a
@@ -87,4 +88,5 @@ func (r *RestrictionExpr) WriteStringTo(sb *strings.Builder, unquoted bool) {
 // Position returns the position of the restriction.
 func (r *RestrictionExpr) Position() token.Position { return r.Pos }
 func (*RestrictionExpr) isSimpleExpr()              {}
+func (*RestrictionExpr) isArgExpr()                 {}
 func (*RestrictionExpr) isAstExpr()                 {}
@@ -38,6 +38,9 @@ type StringLiteral struct {
 
 	// Value is the raw string value without quotes.
 	Value string
+
+	// Bytes is true if the string is a b prefixed bytes literal, i.e. b"aGVsbG8=".
+	Bytes bool
 }
 
 // WriteStringTo writes the string representation of the value to the builder.
@@ -49,6 +52,9 @@ func (s *StringLiteral) WriteStringTo(sb *strings.Builder, unquoted bool) {
 		return
 	}
 
+	if s.Bytes {
+		sb.WriteRune('b')
+	}
 	sb.WriteRune('"')
 	sb.WriteString(s.Value)
 	sb.WriteRune('"')
@@ -61,6 +67,9 @@ func (s *StringLiteral) GetStringValue() string {
 
 // Position returns the position of the string literal.
 func (s *StringLiteral) String() string {
+	if s.Bytes {
+		return fmt.Sprintf("b%q", s.Value)
+	}
 	return fmt.Sprintf("%q", s.Value)
 }
 
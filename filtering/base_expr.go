@@ -0,0 +1,45 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// BaseExprOpt is an option that combines each parsed filter with the base expression x using the AND operator,
+// i.e. the 'tenant_id = X' access scope of the service, so that the scope is applied consistently before the
+// translation. The base expression is the first operand, and an empty filter results in the base expression only.
+// The interpreter clones the base expression for each parse, thus it must not be freed while the interpreter is in use.
+// The base expression is not validated against the field annotations, and the limits of the interpreter
+// apply to the filter only.
+func BaseExprOpt(x expr.FilterExpr) Option {
+	return func(i *Interpreter) error {
+		if expr.IsMatchAll(x) {
+			return errors.New("base expression is not set")
+		}
+		i.baseExpr = x
+		return nil
+	}
+}
+
+// withBaseExpr combines the parsed expression with the base expression, if it is set.
+func (b *Interpreter) withBaseExpr(x expr.FilterExpr, err error) (expr.FilterExpr, error) {
+	if err != nil || b.baseExpr == nil {
+		return x, err
+	}
+	return expr.And(b.baseExpr.Clone().(expr.FilterExpr), x), nil
+}
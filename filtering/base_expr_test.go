@@ -0,0 +1,72 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestBaseExprOpt(t *testing.T) {
+	expr.LeakCheck(t)
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	c := expr.Composer{Desc: md}
+	base := c.Compare(c.MustSelect("str"), expr.EQ, c.Value("tenant"))
+	defer base.Free()
+
+	i, err := NewInterpreter(md, BaseExprOpt(base), MatchAllOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		params map[string]any
+		want   string
+	}{
+		{name: "empty", filter: ``, want: `str = "tenant"`},
+		{name: "restriction", filter: `i32 = 1`, want: `str = "tenant" AND i32 = 1`},
+		{name: "conjunction", filter: `i32 = 1 AND bool = true`, want: `str = "tenant" AND i32 = 1 AND bool = true`},
+		{name: "disjunction", filter: `i32 = 1 OR bool = true`, want: `str = "tenant" AND i32 = 1 OR bool = true`},
+		{name: "params", filter: `i32 = @v`, params: map[string]any{"v": 2}, want: `str = "tenant" AND i32 = 2`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.ParseWithParams(tt.filter, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to render expression: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err = i.Parse(`unknown = 1`); err == nil {
+		t.Errorf("expected error for an invalid filter")
+	}
+	if _, err = NewInterpreter(md, BaseExprOpt(nil)); err == nil {
+		t.Errorf("expected error for a nil base expression")
+	}
+}
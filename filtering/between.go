@@ -0,0 +1,184 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// BetweenFunctionName is the name of the range macro enabled by the BetweenOpt.
+var BetweenFunctionName = FunctionName{PkgName: "range", Name: "Between"}
+
+// BetweenOpt is an option that enables the range macro, i.e.: range.Between(create_time, 2023-01-01T00:00:00Z, 2023-02-01T00:00:00Z).
+// The macro takes a field selector, the lower and the upper bound, and it expands into the inclusive range restriction
+// of the expr.AndExpr with the GE and LE expr.CompareExpr, which is equivalent to: field >= lo AND field <= hi.
+// The field must be a singular numeric, google.protobuf.Timestamp or google.protobuf.Duration field,
+// and the bounds are parsed as the values of the field, where the lower bound must not be greater than the upper one.
+// The macro takes precedence over a registered function with the same name.
+func BetweenOpt() Option {
+	return func(i *Interpreter) error {
+		i.between = true
+		return nil
+	}
+}
+
+// isBetweenCall checks if the function call is the range macro, and the macro is enabled.
+func (b *Interpreter) isBetweenCall(x *ast.FunctionCall) bool {
+	return b.between && x.JoinedNameEquals(BetweenFunctionName.String())
+}
+
+// handleBetween expands the range macro function call into the inclusive range restriction.
+func (b *Interpreter) handleBetween(ctx *ParseContext, x *ast.RestrictionExpr, fc *ast.FunctionCall) (TryParseValueResult, error) {
+	if x.Comparator != nil || x.Arg != nil || len(fc.Fields) > 0 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Pos, ErrMsg: fmt.Sprintf("function: %s cannot be compared", BetweenFunctionName)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	var args []ast.ArgExpr
+	if fc.ArgList != nil {
+		args = fc.ArgList.Args
+	}
+	if len(args) != 3 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Lparen, ErrMsg: fmt.Sprintf("function: %s expects 3 arguments: field, lower and upper bound, but got %d", BetweenFunctionName, len(args))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	me, ok := args[0].(*ast.MemberExpr)
+	if !ok {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: args[0].Position(), ErrMsg: fmt.Sprintf("function: %s first argument must be a field, but got: %s", BetweenFunctionName, args[0].String())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	res, err := b.TryParseSelectorExpr(ctx, me.Value, me.Fields...)
+	if err != nil {
+		return res, err
+	}
+	left := res.Expr
+
+	_, mk, fd, ok := b.traverseLastFieldExpr(left)
+	if !ok {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: me.Position(), ErrMsg: "internal error: range field is not a field selector expression"}, ErrInternal
+		}
+		return TryParseValueResult{}, ErrInternal
+	}
+	fi := b.msgInfo.GetFieldInfo(fd)
+	if mk != nil {
+		fd = fd.MapValue()
+	}
+	if !isRangeField(fd) {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: me.Position(), ErrMsg: fmt.Sprintf("function: %s is not allowed for the field: '%s'", BetweenFunctionName, fd.Name())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	var bounds [2]expr.FilterExpr
+	for i, arg := range args[1:] {
+		vr, err := b.TryParseValue(ctx, TryParseValueInput{
+			Field:      fd,
+			Value:      arg,
+			Complexity: fi.Complexity,
+		})
+		if err != nil {
+			left.Free()
+			if bounds[0] != nil {
+				bounds[0].Free()
+			}
+			return vr, err
+		}
+		bounds[i] = vr.Expr
+	}
+
+	if lo, ok := bounds[0].(*expr.ValueExpr); ok {
+		if hi, ok := bounds[1].(*expr.ValueExpr); ok && rangeGreater(lo.Value, hi.Value) {
+			left.Free()
+			bounds[0].Free()
+			bounds[1].Free()
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: args[1].Position(), ErrMsg: fmt.Sprintf("function: %s lower bound is greater than the upper bound", BetweenFunctionName)}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+	}
+
+	ge := expr.AcquireCompareExpr()
+	ge.Left = left
+	ge.Comparator = expr.GE
+	ge.Right = bounds[0]
+
+	le := expr.AcquireCompareExpr()
+	le.Left = left.Clone().(expr.FilterExpr)
+	le.Comparator = expr.LE
+	le.Right = bounds[1]
+
+	and := expr.AcquireAndExpr()
+	and.Expr = append(and.Expr, ge, le)
+	return TryParseValueResult{Expr: and, IsIndirect: true}, nil
+}
+
+// isRangeField checks if the field values are ordered, so that they could be a subject of the range.
+func isRangeField(fd protoreflect.FieldDescriptor) bool {
+	if fd.IsList() || fd.IsMap() {
+		return false
+	}
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return true
+	case protoreflect.MessageKind:
+		name := fd.Message().FullName()
+		return name == timestampMsgDesc.FullName() || name == durationMsgDesc.FullName()
+	}
+	return false
+}
+
+// rangeGreater checks if the lo value is greater than the hi value, for the values of the same ordered type.
+func rangeGreater(lo, hi any) bool {
+	switch lt := lo.(type) {
+	case int64:
+		ht, ok := hi.(int64)
+		return ok && lt > ht
+	case uint64:
+		ht, ok := hi.(uint64)
+		return ok && lt > ht
+	case float64:
+		ht, ok := hi.(float64)
+		return ok && lt > ht
+	case time.Time:
+		ht, ok := hi.(time.Time)
+		return ok && lt.After(ht)
+	case time.Duration:
+		ht, ok := hi.(time.Duration)
+		return ok && lt > ht
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_Between(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tc := []struct {
+		name   string
+		opts   []Option
+		filter string
+		lo, hi any
+		err    error
+	}{
+		{name: "int", opts: []Option{BetweenOpt()}, filter: `range.Between(i32, 1, 10)`, lo: int64(1), hi: int64(10)},
+		{name: "nested", opts: []Option{BetweenOpt()}, filter: `range.Between(sub.i64, -5, 5)`, lo: int64(-5), hi: int64(5)},
+		{
+			name:   "timestamp",
+			opts:   []Option{BetweenOpt()},
+			filter: `range.Between(timestamp, 2023-01-01T00:00:00Z, 2023-02-01T00:00:00Z)`,
+			lo:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			hi:     time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "duration", opts: []Option{BetweenOpt()}, filter: `range.Between(duration, 1m, 1h)`, lo: time.Minute, hi: time.Hour},
+		{name: "disabled", filter: `range.Between(i32, 1, 10)`, err: ErrInvalidValue},
+		{name: "inverted", opts: []Option{BetweenOpt()}, filter: `range.Between(i32, 10, 1)`, err: ErrInvalidValue},
+		{name: "string field", opts: []Option{BetweenOpt()}, filter: `range.Between(str, "a", "b")`, err: ErrInvalidValue},
+		{name: "invalid bound", opts: []Option{BetweenOpt()}, filter: `range.Between(i32, 1, "b")`, err: ErrInvalidValue},
+		{name: "arguments", opts: []Option{BetweenOpt()}, filter: `range.Between(i32, 1)`, err: ErrInvalidValue},
+		{name: "compared", opts: []Option{BetweenOpt()}, filter: `range.Between(i32, 1, 2) = true`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			and, ok := x.(*expr.AndExpr)
+			if !ok || len(and.Expr) != 2 {
+				t.Fatalf("expected AndExpr of two comparisons but got %T", x)
+			}
+			ge, le := and.Expr[0].(*expr.CompareExpr), and.Expr[1].(*expr.CompareExpr)
+			if ge.Comparator != expr.GE || le.Comparator != expr.LE {
+				t.Errorf("expected GE and LE comparators but got %s and %s", ge.Comparator, le.Comparator)
+			}
+			if !ge.Left.Equals(le.Left) || ge.Left == le.Left {
+				t.Errorf("expected equal and distinct field selectors")
+			}
+			if got := ge.Right.(*expr.ValueExpr).Value; got != tt.lo {
+				t.Errorf("expected lower bound %v but got %v", tt.lo, got)
+			}
+			if got := le.Right.(*expr.ValueExpr).Value; got != tt.hi {
+				t.Errorf("expected upper bound %v but got %v", tt.hi, got)
+			}
+		})
+	}
+}
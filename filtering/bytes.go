@@ -17,7 +17,11 @@ package filtering
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
@@ -26,6 +30,8 @@ import (
 
 // TryParseBytesField tries to parse a bytes field.
 // It can be a single bytes value or a repeated bytes value.
+// The value is either a hexadecimal literal, i.e. 0xdeadbeef, or a quoted base64 string,
+// optionally prefixed with 'b', i.e. "aGVsbG8=" or b"aGVsbG8=".
 func (b *Interpreter) TryParseBytesField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
 	// Check if no more Fields are present in the input *x.MemberExpr.
 	// If there are, then return an error.
@@ -51,7 +57,7 @@ func (b *Interpreter) TryParseBytesField(ctx *ParseContext, in TryParseValueInpu
 			bt, err := hex.DecodeString(vt.Value[2:])
 			if err != nil {
 				if ctx.ErrHandler != nil {
-					return TryParseValueResult{ErrPos: vt.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Kind(), vt.Value)}, ErrInvalidValue
+					return TryParseValueResult{ErrPos: vt.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a valid hexadecimal literal: '%s': %v", in.Field.Kind(), vt.Value, err)}, ErrInvalidValue
 				}
 				return TryParseValueResult{}, ErrInvalidValue
 			}
@@ -80,11 +86,13 @@ func (b *Interpreter) TryParseBytesField(ctx *ParseContext, in TryParseValueInpu
 				Complexity:    in.Complexity,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ve.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
@@ -113,10 +121,10 @@ func (b *Interpreter) TryParseBytesField(ctx *ParseContext, in TryParseValueInpu
 		ve.Value = nil
 		return TryParseValueResult{Expr: ve}, nil
 	}
-	dec, err := base64.StdEncoding.DecodeString(value)
+	dec, err := decodeBase64(value)
 	if err != nil {
 		if ctx.ErrHandler != nil {
-			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Kind(), value)}, ErrInvalidValue
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a valid base64 string: '%s': %v", in.Field.Kind(), value, err)}, ErrInvalidValue
 		}
 		return TryParseValueResult{}, ErrInvalidValue
 	}
@@ -126,3 +134,51 @@ func (b *Interpreter) TryParseBytesField(ctx *ParseContext, in TryParseValueInpu
 
 	return TryParseValueResult{Expr: ve}, nil
 }
+
+// decodeBase64 decodes the padded base64 encoded value, of either the standard or the URL-safe alphabet.
+// The value mixing both alphabets, with the line breaks, or with the non-zero trailing bits is invalid.
+func decodeBase64(value string) ([]byte, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, errors.New("line breaks are not allowed")
+	}
+	enc := base64.StdEncoding
+	if strings.ContainsAny(value, "-_") {
+		if strings.ContainsAny(value, "+/") {
+			return nil, errors.New("standard and URL-safe alphabets are mixed")
+		}
+		enc = base64.URLEncoding
+	}
+	return enc.Strict().DecodeString(value)
+}
+
+// acceptsBytesLiteral checks if the field accepts the b prefixed bytes literal,
+// i.e. it is the bytes or the google.protobuf.BytesValue field.
+func acceptsBytesLiteral(fd FieldDescriptor) bool {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return true
+	case protoreflect.MessageKind:
+		return fd.Message() != nil && fd.Message().FullName() == "google.protobuf.BytesValue"
+	}
+	return false
+}
+
+// isPrefixedBytesLiteral checks if the member expression is a sole b prefixed bytes literal, i.e. b"aGVsbG8=".
+func isPrefixedBytesLiteral(x *ast.MemberExpr) bool {
+	sl, ok := x.Value.(*ast.StringLiteral)
+	return ok && sl.Bytes && len(x.Fields) == 0
+}
+
+// isBytesLiteral checks if the member expression is a sole quoted string or hexadecimal literal.
+func isBytesLiteral(x *ast.MemberExpr) bool {
+	if len(x.Fields) > 0 {
+		return false
+	}
+	switch vt := x.Value.(type) {
+	case *ast.StringLiteral:
+		return true
+	case *ast.TextLiteral:
+		return vt.Token == token.HEX
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestInterpreter_BytesLiterals(t *testing.T) {
+	var msg string
+	i, err := NewInterpreter(new(testpb.Message).ProtoReflect().Descriptor(), ErrHandlerOpt(func(pos token.Position, m string) {
+		msg = m
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   []byte
+		err    error
+		msg    string
+	}{
+		{filter: `bytes = 0xdeadbeef`, want: []byte{0xde, 0xad, 0xbe, 0xef}},
+		{filter: `bytes = 0xDEADBEEF`, want: []byte{0xde, 0xad, 0xbe, 0xef}},
+		{filter: `bytes = "aGVsbG8="`, want: []byte("hello")},
+		{filter: `bytes = 'aGVsbG8='`, want: []byte("hello")},
+		{filter: `bytes = b"aGVsbG8="`, want: []byte("hello")},
+		{filter: `bytes = b'aGVsbG8='`, want: []byte("hello")},
+		{filter: `bytes = "_-8="`, want: []byte{0xff, 0xef}},
+		{filter: `bytes = "+/8="`, want: []byte{0xfb, 0xff}},
+		{filter: `bytes = 0xabc`, err: ErrInvalidValue, msg: "not a valid hexadecimal literal"},
+		{filter: `bytes = "!!"`, err: ErrInvalidValue, msg: "not a valid base64 string"},
+		{filter: `bytes = b"a"`, err: ErrInvalidValue, msg: "not a valid base64 string"},
+		{filter: `bytes = "aGVsbG8"`, err: ErrInvalidValue, msg: "not a valid base64 string"},
+		{filter: `bytes = b'aGVsbG8'`, err: ErrInvalidValue, msg: "not a valid base64 string"},
+		{filter: `bytes = "_+8="`, err: ErrInvalidValue, msg: "alphabets are mixed"},
+		{filter: `bytes = "aGVsbG9="`, err: ErrInvalidValue, msg: "not a valid base64 string"},
+		{filter: "bytes = \"aGVs\nbG8=\"", err: ErrInvalidValue, msg: "line breaks are not allowed"},
+		{filter: `str = b"aGVsbG8="`, err: ErrInvalidValue, msg: "provided value is a bytes literal"},
+		{filter: `i32 = b"AQ=="`, err: ErrInvalidValue, msg: "provided value is a bytes literal"},
+		{filter: `bytes = b"aGVsbG8=".foo`, err: parser.ErrInvalidFilterSyntax},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			msg = ""
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				if !strings.Contains(msg, tt.msg) {
+					t.Errorf("expected error message containing %q but got %q", tt.msg, msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			ve, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			bt, ok := ve.Value.([]byte)
+			if !ok {
+				t.Fatalf("expected bytes value but got %T", ve.Value)
+			}
+			if !bytes.Equal(bt, tt.want) {
+				t.Errorf("expected value %x but got %x", tt.want, bt)
+			}
+		})
+	}
+}
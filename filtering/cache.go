@@ -0,0 +1,205 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/cache"
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// CacheOpt is an option that caches the expressions parsed by the Parse in the cache c for the ttl.
+// The expressions are stored gob encoded, keyed by the message full name, the fingerprint of the interpreter options
+// and the filter, thus the interpreters of different messages or options could share the cache.
+// The function valued options, i.e. the KindPolicyOpt or the RestrictionHookOpt, are fingerprinted only
+// by their presence, thus the interpreters differing only in these should not share the cache.
+// The cache failures and the expressions that cannot be encoded, i.e. holding the message values,
+// fall back to parsing the filter, and are logged by the LoggerOpt logger,
+// while the invalid filters are not cached at all.
+// Each call of the Parse returns a new expression, which is owned by the caller.
+// As the relative timestamps are resolved at parse time, see RelativeTimeOpt, the ttl should not exceed their precision.
+func CacheOpt(c cache.Cache, ttl time.Duration) Option {
+	return func(i *Interpreter) error {
+		if c == nil {
+			return errors.New("cache is not set")
+		}
+		i.cache = c
+		i.cacheTTL = ttl
+		return nil
+	}
+}
+
+// ExprCacheOpt is an option that caches the expressions parsed by the Parse in the in-memory LRU cache c for the ttl.
+// Unlike the CacheOpt, the expressions are not encoded, but kept as the immutable trees owned by the cache,
+// thus a cache hit costs only the expr.Clone of the cached expression, which is less than parsing the filter.
+// Each call of the Parse returns a new expression, which is owned by the caller.
+// The expressions evicted from the cache are not freed, as these could be cloned concurrently,
+// and are left to the garbage collector instead.
+// The expressions are keyed as in the CacheOpt, thus the cache could be shared by the interpreters as well.
+// It could be combined with the CacheOpt, in which case the in-memory cache is checked first.
+// As the relative timestamps are resolved at parse time, see RelativeTimeOpt, the ttl should not exceed their precision.
+func ExprCacheOpt(c *cache.LRU[expr.FilterExpr], ttl time.Duration) Option {
+	return func(i *Interpreter) error {
+		if c == nil {
+			return errors.New("cache is not set")
+		}
+		i.exprCache = c
+		i.exprCacheTTL = ttl
+		return nil
+	}
+}
+
+// cachedExpr is the gob encoded cache entry.
+type cachedExpr struct {
+	Expr expr.FilterExpr
+}
+
+// parseCached parses the filter, with the expression taken from the caches if set.
+func (b *Interpreter) parseCached(filter string) (expr.FilterExpr, error) {
+	if (b.cache == nil && b.exprCache == nil) || filter == "" {
+		return b.parse(filter, nil, nil)
+	}
+
+	ctx := context.Background()
+	key := b.cacheKeyPrefix + filter
+	if b.exprCache != nil {
+		if x, ok, _ := b.exprCache.Get(ctx, key); ok {
+			return expr.Clone(x), nil
+		}
+	}
+
+	x, ok := b.getEncoded(ctx, filter, key)
+	if !ok {
+		var err error
+		x, err = b.parse(filter, nil, nil)
+		if err != nil || x == nil {
+			return x, err
+		}
+		b.setEncoded(ctx, filter, key, x)
+	}
+
+	if b.exprCache != nil {
+		_ = b.exprCache.Set(ctx, key, expr.Clone(x), b.exprCacheTTL)
+	}
+	return x, nil
+}
+
+// getEncoded returns the expression decoded from the cache set by the CacheOpt.
+func (b *Interpreter) getEncoded(ctx context.Context, filter, key string) (expr.FilterExpr, bool) {
+	if b.cache == nil {
+		return nil, false
+	}
+	data, ok, err := b.cache.Get(ctx, key)
+	if err != nil {
+		b.logCacheError(filter, fmt.Errorf("getting cached expression failed: %w", err))
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	var ce cachedExpr
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&ce); err != nil {
+		b.logCacheError(filter, fmt.Errorf("decoding cached expression failed: %w", err))
+		return nil, false
+	}
+	return ce.Expr, ce.Expr != nil
+}
+
+// setEncoded stores the expression in the cache set by the CacheOpt.
+func (b *Interpreter) setEncoded(ctx context.Context, filter, key string, x expr.FilterExpr) {
+	if b.cache == nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cachedExpr{Expr: x}); err != nil {
+		b.logCacheError(filter, fmt.Errorf("encoding expression failed: %w", err))
+		return
+	}
+	if err := b.cache.Set(ctx, key, buf.Bytes(), b.cacheTTL); err != nil {
+		b.logCacheError(filter, fmt.Errorf("caching expression failed: %w", err))
+	}
+}
+
+// cacheFingerprint are the options affecting the parsed expressions, which are not serialized
+// within the InterpreterOptions.
+type cacheFingerprint struct {
+	Options          InterpreterOptions
+	Functions        []string
+	ValueDecoders    []protoreflect.FullName
+	DisplayName      protoreflect.FullName
+	Extensions       []protoreflect.FullName
+	AnyTypes         []protoreflect.FullName
+	BaseExpr         string
+	KindPolicy       bool
+	RestrictionHooks int
+	GlobalSearch     bool
+	SequenceWeight   bool
+}
+
+// newCacheKeyPrefix returns the prefix of the cache keys, composed of the message full name
+// and the hash of the options affecting the parsed expressions.
+func (b *Interpreter) newCacheKeyPrefix() (string, error) {
+	o := b.Options()
+	fp := cacheFingerprint{
+		Options:          o,
+		KindPolicy:       o.KindPolicy != nil,
+		RestrictionHooks: len(o.RestrictionHooks),
+		GlobalSearch:     o.GlobalSearchHandler != nil,
+		SequenceWeight:   o.SequenceWeight != nil,
+	}
+	for _, fn := range o.Functions {
+		fp.Functions = append(fp.Functions, fn.Name.String())
+	}
+	for name := range o.ValueDecoders {
+		fp.ValueDecoders = append(fp.ValueDecoders, name)
+	}
+	sort.Slice(fp.ValueDecoders, func(i, j int) bool { return fp.ValueDecoders[i] < fp.ValueDecoders[j] })
+	if o.DisplayNameExtension != nil {
+		fp.DisplayName = o.DisplayNameExtension.TypeDescriptor().FullName()
+	}
+	for _, xt := range o.ExtensionFields {
+		fp.Extensions = append(fp.Extensions, xt.TypeDescriptor().FullName())
+	}
+	for _, md := range o.AnyTypes {
+		fp.AnyTypes = append(fp.AnyTypes, md.FullName())
+	}
+	if o.BaseExpr != nil {
+		s, err := expr.String(o.BaseExpr)
+		if err != nil {
+			return "", fmt.Errorf("failed to fingerprint the base expression: %w", err)
+		}
+		fp.BaseExpr = s
+	}
+
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint the interpreter options: %w", err)
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return "filtering:" + string(b.msg.FullName()) + ":" + strconv.FormatUint(h.Sum64(), 16) + ":", nil
+}
@@ -0,0 +1,367 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/cache"
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+// countingCache counts the cache hits.
+type countingCache struct {
+	cache.Cache
+	hits int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, ok, err := c.Cache.Get(ctx, key)
+	if ok {
+		c.hits++
+	}
+	return v, ok, err
+}
+
+func TestInterpreter_Cache(t *testing.T) {
+	mem, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	c := &countingCache{Cache: mem}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, CacheOpt(c, time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	const filter = `str = "foo" AND i32 > 1 OR NOT rp_str:"bar"`
+	first, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer first.Free()
+
+	second, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer second.Free()
+
+	if c.hits != 1 {
+		t.Errorf("expected a single cache hit but got %d", c.hits)
+	}
+	if !first.Equals(second) {
+		t.Errorf("expected the cached expression %v to equal %v", second, first)
+	}
+
+	if _, err = i.Parse(`str = 1`); err == nil {
+		t.Error("expected error")
+	}
+	if mem.Len() != 1 {
+		t.Errorf("expected the invalid filter not to be cached, but got %d entries", mem.Len())
+	}
+}
+
+func TestInterpreter_CacheValues(t *testing.T) {
+	mem, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	c := &countingCache{Cache: mem}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, CacheOpt(c, 0))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	filters := []string{
+		`duration > 1s`,
+		`timestamp > 2023-01-01T00:00:00Z`,
+		`enum = "ONE"`,
+		`bytes = "YQ=="`,
+	}
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			hits := c.hits
+			first, err := i.Parse(filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer first.Free()
+
+			second, err := i.Parse(filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer second.Free()
+
+			if c.hits != hits+1 {
+				t.Errorf("expected the filter to be cached")
+			}
+			if !expr.Equal(first, second) {
+				t.Errorf("expected the cached expression %v to equal %v", second, first)
+			}
+		})
+	}
+}
+
+// keysCache records the keys of the cached values.
+type keysCache struct {
+	cache.Noop
+	keys []string
+}
+
+func (c *keysCache) Set(_ context.Context, key string, _ []byte, _ time.Duration) error {
+	c.keys = append(c.keys, key)
+	return nil
+}
+
+func TestInterpreter_CacheKey(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	key := func(t *testing.T, opts ...Option) string {
+		t.Helper()
+		c := new(keysCache)
+		i, err := NewInterpreter(md, append(opts, CacheOpt(c, 0))...)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		x, err := i.Parse(`str = "foo"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+		if len(c.keys) != 1 {
+			t.Fatalf("expected a single cached expression but got %d", len(c.keys))
+		}
+		return c.keys[0]
+	}
+
+	base := key(t)
+	if !strings.HasPrefix(base, "filtering:testpb.Message:") || !strings.HasSuffix(base, `:str = "foo"`) {
+		t.Errorf("unexpected key %q", base)
+	}
+	if k := key(t); k != base {
+		t.Errorf("expected the same options to result in the same key %q, but got %q", base, k)
+	}
+
+	tc := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "case insensitive", opts: []Option{CaseInsensitiveOpt()}},
+		{name: "lenient enums", opts: []Option{LenientEnumsOpt()}},
+		{name: "kind policy", opts: []Option{KindPolicyOpt(DefaultKindPolicy)}},
+		{name: "value decoder", opts: []Option{RegisterValueDecoder("testpb.Message", ValueDecoderFunc(func(protoreflect.MessageDescriptor, string) (any, error) {
+			return nil, nil
+		}))}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if k := key(t, tt.opts...); k == base {
+				t.Errorf("expected the options to change the key %q", k)
+			}
+		})
+	}
+}
+
+func TestInterpreter_CacheEncodeError(t *testing.T) {
+	mem, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	var buf bytes.Buffer
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md,
+		CacheOpt(mem, 0),
+		LoggerOpt(slog.New(slog.NewTextHandler(&buf, nil))),
+		// The message values cannot be gob encoded.
+		RegisterValueDecoder("testpb.Message", ValueDecoderFunc(func(_ protoreflect.MessageDescriptor, literal string) (any, error) {
+			return &testpb.Message{Name: literal}, nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`sub = "foo"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+
+	if mem.Len() != 0 {
+		t.Errorf("expected the expression not to be cached, but got %d entries", mem.Len())
+	}
+	if out := buf.String(); !strings.Contains(out, "filter cache failed") || !strings.Contains(out, "encoding expression failed") {
+		t.Errorf("expected the encode failure to be logged, but got %q", out)
+	}
+}
+
+func TestInterpreter_ExprCache(t *testing.T) {
+	lru, err := cache.NewLRU[expr.FilterExpr](2)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, ExprCacheOpt(lru, 0))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	const filter = `str = "foo" AND i32 > 1 OR NOT rp_str:"bar"`
+	first, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := expr.Clone(first)
+	defer want.Free()
+	// Freeing the returned expression must not affect the cached one.
+	first.Free()
+
+	second, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer second.Free()
+	third, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer third.Free()
+
+	if !expr.Equal(want, second) || !expr.Equal(want, third) {
+		t.Errorf("expected the cached expressions %v and %v to equal %v", second, third, want)
+	}
+	if second == third {
+		t.Error("expected each call to return a distinct expression")
+	}
+
+	for _, f := range []string{`i32 = 1`, `i32 = 2`} {
+		x, err := i.Parse(f)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	}
+	if lru.Len() != 2 {
+		t.Errorf("expected the cache limited to 2 filters but got %d", lru.Len())
+	}
+}
+
+func TestInterpreter_ExprCacheWithCache(t *testing.T) {
+	mem, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	c := &countingCache{Cache: mem}
+	lru, err := cache.NewLRU[expr.FilterExpr](2)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, CacheOpt(c, 0), ExprCacheOpt(lru, 0))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	const filter = `str = "foo" AND i32 > 1`
+	for n := 0; n < 3; n++ {
+		x, err := i.Parse(filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	}
+	if c.hits != 0 {
+		t.Errorf("expected the in-memory cache to be checked first, but got %d hits of the encoded cache", c.hits)
+	}
+
+	// Another interpreter sharing only the encoded cache fills its in-memory cache from it.
+	lru2, err := cache.NewLRU[expr.FilterExpr](2)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	i2, err := NewInterpreter(md, CacheOpt(c, 0), ExprCacheOpt(lru2, 0))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	for n := 0; n < 2; n++ {
+		x, err := i2.Parse(filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	}
+	if c.hits != 1 || lru2.Len() != 1 {
+		t.Errorf("expected a single hit of the encoded cache and 1 in-memory entry, but got %d and %d", c.hits, lru2.Len())
+	}
+}
+
+func BenchmarkInterpreter_ParseCached(b *testing.B) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md)
+	if err != nil {
+		b.Fatalf("failed to create interpreter: %v", err)
+	}
+	exprLRU, err := cache.NewLRU[expr.FilterExpr](1024)
+	if err != nil {
+		b.Fatalf("failed to create cache: %v", err)
+	}
+	ei, err := NewInterpreter(md, ExprCacheOpt(exprLRU, 0))
+	if err != nil {
+		b.Fatalf("failed to create interpreter: %v", err)
+	}
+	encodedLRU, err := cache.NewLRU[[]byte](1024)
+	if err != nil {
+		b.Fatalf("failed to create cache: %v", err)
+	}
+	ci, err := NewInterpreter(md, CacheOpt(encodedLRU, 0))
+	if err != nil {
+		b.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	const filter = `str = "foo" AND i32 > 1 OR NOT rp_str:"bar"`
+	for _, bc := range []struct {
+		name string
+		in   *Interpreter
+	}{
+		{name: "Interpreter", in: i},
+		{name: "ExprCache", in: ei},
+		{name: "Cache", in: ci},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				x, err := bc.in.Parse(filter)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				x.Free()
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// FilterSupport is the level of support of a single filter by a backend capability.
+type FilterSupport struct {
+	// Filter is the checked filter.
+	Filter string
+	// Level is the level of support of the filter.
+	Level expr.SupportLevel
+	// Reasons describe the unsupported parts of the filter.
+	Reasons []string
+	// Err is the error of the filter interpretation, the filter is then Unsupported.
+	Err error
+}
+
+// CapabilityReport checks the support of each of the stored filters by the backend capability c,
+// i.e.: when migrating a service into a new storage backend.
+// The filters which cannot be interpreted are reported as unsupported, with the interpretation error.
+// The report is in the order of the filters.
+func (b *Interpreter) CapabilityReport(filters []string, c expr.Capability) []FilterSupport {
+	out := make([]FilterSupport, len(filters))
+	for i, filter := range filters {
+		out[i].Filter = filter
+		x, err := b.Parse(filter)
+		if err != nil {
+			out[i].Level = expr.Unsupported
+			out[i].Err = err
+			continue
+		}
+		out[i].Level, out[i].Reasons = c.Check(x)
+		if x != nil {
+			x.Free()
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_CapabilityReport(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, RegexMatchOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	c := expr.Capability{
+		Comparators:  []expr.Comparator{expr.EQ, expr.NE, expr.LT, expr.LE, expr.GT, expr.GE, expr.HAS, expr.IN},
+		StringSearch: true,
+	}
+	filters := []string{
+		`i32 = 1 AND str = "foo*"`,
+		`i32 = 1 AND str =~ "^fo+$"`,
+		`i32 = i64 OR str =~ "^fo+$"`,
+		`unknown = 1`,
+	}
+	want := []expr.SupportLevel{expr.FullySupported, expr.NeedsResidual, expr.Unsupported, expr.Unsupported}
+
+	report := i.CapabilityReport(filters, c)
+	if len(report) != len(filters) {
+		t.Fatalf("expected %d results but got %d", len(filters), len(report))
+	}
+	for j, r := range report {
+		if r.Filter != filters[j] {
+			t.Errorf("expected filter %q but got %q", filters[j], r.Filter)
+		}
+		if r.Level != want[j] {
+			t.Errorf("%q: expected %s but got %s, reasons: %v", r.Filter, want[j], r.Level, r.Reasons)
+		}
+	}
+	if report[0].Reasons != nil {
+		t.Errorf("expected no reasons but got %v", report[0].Reasons)
+	}
+	if got := report[2].Reasons; len(got) != 2 {
+		t.Errorf("expected 2 reasons but got %v", got)
+	}
+	if report[3].Err == nil {
+		t.Error("expected an error of the unknown field")
+	}
+}
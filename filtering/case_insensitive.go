@@ -0,0 +1,129 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// CaseInsensitiveOpt is an option that marks all the string comparisons as case-insensitive.
+// The resulting expr.CompareExpr and expr.StringSearchExpr have the CaseInsensitive flag set,
+// so that the backends could compare the values with i.e. the LOWER() or ILIKE.
+func CaseInsensitiveOpt() Option {
+	return func(i *Interpreter) error {
+		i.caseInsensitive = true
+		return nil
+	}
+}
+
+// CaseInsensitiveFieldsOpt is an option that marks the comparisons of given string fields as case-insensitive.
+// The fields are the string fields, repeated string fields or the maps with the string values.
+// See CaseInsensitiveOpt for details.
+func CaseInsensitiveFieldsOpt(fields ...protoreflect.FullName) Option {
+	return func(i *Interpreter) error {
+		if i.caseInsensitiveFields == nil {
+			i.caseInsensitiveFields = make(map[protoreflect.FullName]struct{}, len(fields))
+		}
+		for _, name := range fields {
+			fd, ok := i.findField(name)
+			if !ok {
+				return fmt.Errorf("field %q not found", name)
+			}
+			if fd.IsMap() {
+				fd = fd.MapValue()
+			}
+			if fd.Kind() != protoreflect.StringKind {
+				return fmt.Errorf("field %q is not a string field", name)
+			}
+			i.caseInsensitiveFields[name] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// markCaseInsensitive sets the CaseInsensitive flag of the string comparisons within the x restriction.
+func (b *Interpreter) markCaseInsensitive(x expr.FilterExpr) {
+	ce, ok := x.(*expr.CompareExpr)
+	if !ok {
+		return
+	}
+	if ae, ok := ce.Right.(*expr.AnyElementExpr); ok {
+		switch ft := ae.Filter.(type) {
+		case *expr.AndExpr:
+			for _, e := range ft.Expr {
+				b.markCaseInsensitive(e)
+			}
+		default:
+			b.markCaseInsensitive(ft)
+		}
+		return
+	}
+	if !b.isCaseInsensitiveSelector(ce.Left) {
+		return
+	}
+	ce.CaseInsensitive = true
+	if ss, ok := ce.Right.(*expr.StringSearchExpr); ok {
+		ss.CaseInsensitive = true
+	}
+}
+
+// isCaseInsensitiveSelector checks if the x selects a string value, which comparisons are case-insensitive.
+func (b *Interpreter) isCaseInsensitiveSelector(x expr.FilterExpr) bool {
+	var (
+		last     *expr.FieldSelectorExpr
+		isMapKey bool
+	)
+	for cur := expr.Expr(x); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			last, isMapKey = ct, false
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			isMapKey = true
+			cur = ct.Traversal
+		default:
+			return false
+		}
+	}
+	if last == nil {
+		return false
+	}
+
+	name := last.Message.Append(last.Field)
+	fd, ok := b.findField(name)
+	if !ok {
+		return false
+	}
+	if fd.IsMap() {
+		if !isMapKey {
+			// The HAS comparison of a map selects its keys.
+			fd = fd.MapKey()
+		} else {
+			fd = fd.MapValue()
+		}
+	}
+	if fd.Kind() != protoreflect.StringKind {
+		return false
+	}
+	if b.caseInsensitive {
+		return true
+	}
+	_, ok = b.caseInsensitiveFields[name]
+	return ok
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_CaseInsensitive(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tc := []struct {
+		name   string
+		opts   []Option
+		filter string
+		want   []bool
+	}{
+		{name: "disabled", filter: `str = "Foo"`, want: []bool{false}},
+		{name: "global", opts: []Option{CaseInsensitiveOpt()}, filter: `str = "Foo" AND i32 = 1`, want: []bool{true, false}},
+		{name: "field", opts: []Option{CaseInsensitiveFieldsOpt("testpb.Message.str")}, filter: `str = "Foo" AND name = "Foo"`, want: []bool{true, false}},
+		{name: "nested field", opts: []Option{CaseInsensitiveFieldsOpt("testpb.Message.str")}, filter: `sub.str != "Foo"`, want: []bool{true}},
+		{name: "string search", opts: []Option{CaseInsensitiveOpt()}, filter: `str = "Foo*"`, want: []bool{true}},
+		{name: "in", opts: []Option{CaseInsensitiveOpt()}, filter: `str IN ["a", "B"]`, want: []bool{true}},
+		{name: "repeated", opts: []Option{CaseInsensitiveFieldsOpt("testpb.Message.rp_str")}, filter: `rp_str:"Foo"`, want: []bool{true}},
+		{name: "map field", opts: []Option{CaseInsensitiveFieldsOpt("testpb.Message.map_str_str")}, filter: `map_str_str:"Key"`, want: []bool{true}},
+		{name: "map key presence", opts: []Option{CaseInsensitiveOpt()}, filter: `map_str_i32:"Key"`, want: []bool{true}},
+		{name: "any element", opts: []Option{CaseInsensitiveOpt()}, filter: `rp_sub:{str: "Foo", i32: 1}`, want: []bool{true, false}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+			x, err := i.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			var got []bool
+			var collect func(x expr.FilterExpr)
+			collect = func(x expr.FilterExpr) {
+				switch xt := x.(type) {
+				case *expr.AndExpr:
+					for _, e := range xt.Expr {
+						collect(e)
+					}
+				case *expr.CompareExpr:
+					if ae, ok := xt.Right.(*expr.AnyElementExpr); ok {
+						collect(ae.Filter)
+						return
+					}
+					got = append(got, xt.CaseInsensitive)
+					if ss, ok := xt.Right.(*expr.StringSearchExpr); ok && ss.CaseInsensitive != xt.CaseInsensitive {
+						t.Errorf("expected string search flag to match the comparison")
+					}
+				}
+			}
+			collect(x)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d comparisons but got %d", len(tt.want), len(got))
+			}
+			for j := range got {
+				if got[j] != tt.want[j] {
+					t.Errorf("comparison %d: expected case insensitive %v but got %v", j, tt.want[j], got[j])
+				}
+			}
+		})
+	}
+
+	t.Run("invalid field", func(t *testing.T) {
+		if _, err := NewInterpreter(md, CaseInsensitiveFieldsOpt("testpb.Message.i32")); err == nil {
+			t.Error("expected error for non string field")
+		}
+		if _, err := NewInterpreter(md, CaseInsensitiveFieldsOpt("testpb.Message.unknown")); err == nil {
+			t.Error("expected error for unknown field")
+		}
+	})
+}
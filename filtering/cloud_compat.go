@@ -0,0 +1,120 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CloudCompatPreset is the name of the preset reproducing the filter behaviors of the Google Cloud list APIs.
+const CloudCompatPreset = "cloud-compat"
+
+// CloudCompatOpt is an option that reproduces the observed filter behaviors of the major Google Cloud list APIs.
+// It enables:
+//   - the substring HAS comparisons of the string fields, see SubstringHasOpt,
+//   - the lenient enum names, see LenientEnumsOpt,
+//   - the camelCase field names, along with the proto names, see SelectorNamesOpt,
+//   - the date-only timestamps, see DateOnlyTimestampsOpt.
+func CloudCompatOpt() Option {
+	return func(i *Interpreter) error {
+		opts := []Option{
+			SubstringHasOpt(),
+			LenientEnumsOpt(),
+			SelectorNamesOpt(ProtoSelectorName, JSONSelectorName),
+			DateOnlyTimestampsOpt(),
+		}
+		for _, opt := range opts {
+			if err := opt(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// PresetOpt is an option that applies the preset configuration with given name, i.e.: CloudCompatPreset.
+// It allows selecting the preset by its name in the configuration files.
+func PresetOpt(name string) Option {
+	return func(i *Interpreter) error {
+		switch name {
+		case CloudCompatPreset:
+			if err := CloudCompatOpt()(i); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown preset: %q", name)
+		}
+		i.preset = name
+		return nil
+	}
+}
+
+// SubstringHasOpt is an option that makes the HAS comparator of a singular string field match its substring,
+// i.e.: display_name:"foo" is equivalent to display_name = "*foo*".
+// The restriction results in the expr.CompareExpr with the EQ comparator and the expr.StringSearchExpr
+// having both wildcards set.
+func SubstringHasOpt() Option {
+	return func(i *Interpreter) error {
+		i.substringHas = true
+		return nil
+	}
+}
+
+// LenientEnumsOpt is an option that accepts the unquoted enum value names, and matches them regardless of their case,
+// i.e.: state = active matches the ACTIVE value.
+// An exact match of the name takes precedence over a case-insensitive one.
+func LenientEnumsOpt() Option {
+	return func(i *Interpreter) error {
+		i.lenientEnums = true
+		return nil
+	}
+}
+
+// DateOnlyTimestampsOpt is an option that accepts the date-only values of the timestamp fields,
+// i.e.: create_time > 2023-01-01, which is the start of the day in UTC.
+func DateOnlyTimestampsOpt() Option {
+	return func(i *Interpreter) error {
+		i.dateOnlyTimestamps = true
+		return nil
+	}
+}
+
+// isSubstringHas checks if the HAS comparison of the field is a substring match.
+func (b *Interpreter) isSubstringHas(fd protoreflect.FieldDescriptor) bool {
+	return b.substringHas && fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated
+}
+
+// findEnumValue finds the enum value by its name, ignoring the case if the lenient enums are enabled.
+func (b *Interpreter) findEnumValue(ed protoreflect.EnumDescriptor, name string) protoreflect.EnumValueDescriptor {
+	if ev := ed.Values().ByName(protoreflect.Name(name)); ev != nil || !b.lenientEnums {
+		return ev
+	}
+	values := ed.Values()
+	for j := 0; j < values.Len(); j++ {
+		if strings.EqualFold(string(values.Get(j).Name()), name) {
+			return values.Get(j)
+		}
+	}
+	return nil
+}
+
+// parseDateOnly parses the date-only timestamp value at the start of the day in UTC.
+func parseDateOnly(v string) (time.Time, error) {
+	return time.Parse(time.DateOnly, v)
+}
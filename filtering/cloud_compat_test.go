@@ -0,0 +1,137 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_CloudCompat(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	compat, err := NewInterpreter(md, PresetOpt(CloudCompatPreset))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	plain, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		check  func(t *testing.T, ce *expr.CompareExpr)
+		plain  error
+	}{
+		{
+			name:   "substring has",
+			filter: `str:"foo"`,
+			check: func(t *testing.T, ce *expr.CompareExpr) {
+				ss, ok := ce.Right.(*expr.StringSearchExpr)
+				if !ok || ce.Comparator != expr.EQ {
+					t.Fatalf("expected EQ string search but got %s %T", ce.Comparator, ce.Right)
+				}
+				if ss.Value != "foo" || !ss.PrefixWildcard || !ss.SuffixWildcard {
+					t.Errorf("expected substring search of 'foo' but got %+v", ss)
+				}
+			},
+		},
+		{
+			name:   "repeated has",
+			filter: `rp_str:"foo"`,
+			check: func(t *testing.T, ce *expr.CompareExpr) {
+				if ce.Comparator != expr.HAS {
+					t.Errorf("expected HAS comparator but got %s", ce.Comparator)
+				}
+			},
+		},
+		{
+			name:   "lenient enum",
+			filter: `enum = two`,
+			check:  checkCompareValue(testpb.Enum_TWO.Number()),
+			plain:  ErrInvalidValue,
+		},
+		{
+			name:   "enum name case",
+			filter: `enum = "Three"`,
+			check:  checkCompareValue(testpb.Enum_THREE.Number()),
+			plain:  ErrInvalidValue,
+		},
+		{
+			name:   "camel case",
+			filter: `mapStrI32:"key"`,
+			check:  func(t *testing.T, ce *expr.CompareExpr) {},
+			plain:  ErrFieldNotFound,
+		},
+		{
+			name:   "date only timestamp",
+			filter: `timestamp > 2023-01-01`,
+			check:  checkCompareValue(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+			plain:  ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := compat.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			tt.check(t, ce)
+
+			if tt.plain != nil {
+				if _, err = plain.Parse(tt.filter); !errors.Is(err, tt.plain) {
+					t.Errorf("expected error %v without the preset but got %v", tt.plain, err)
+				}
+			}
+		})
+	}
+
+	if got := compat.Options(); got.Preset != CloudCompatPreset || !got.SubstringHas || !got.LenientEnums || !got.DateOnlyTimestamps {
+		t.Errorf("expected cloud compat options but got %+v", got)
+	}
+	if _, err = NewInterpreter(md, PresetOpt("unknown")); err == nil {
+		t.Error("expected unknown preset error")
+	}
+}
+
+func checkCompareValue(want any) func(t *testing.T, ce *expr.CompareExpr) {
+	return func(t *testing.T, ce *expr.CompareExpr) {
+		ve, ok := ce.Right.(*expr.ValueExpr)
+		if !ok {
+			t.Fatalf("expected value expression but got %T", ce.Right)
+		}
+		if tv, ok := want.(time.Time); ok {
+			if got, ok := ve.Value.(time.Time); !ok || !got.Equal(tv) {
+				t.Errorf("expected %v but got %v", want, ve.Value)
+			}
+			return
+		}
+		if ve.Value != want {
+			t.Errorf("expected %v (%T) but got %v (%T)", want, want, ve.Value, ve.Value)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+var (
+	// SizeFunctionName is the name of the collection size function enabled by the CollectionFunctionsOpt.
+	SizeFunctionName = FunctionName{Name: "size"}
+
+	// AnyFunctionName is the name of the existential collection function enabled by the CollectionFunctionsOpt.
+	AnyFunctionName = FunctionName{Name: "any"}
+
+	// AllFunctionName is the name of the universal collection function enabled by the CollectionFunctionsOpt.
+	AllFunctionName = FunctionName{Name: "all"}
+)
+
+// sizeReturning is the declaration of the value returned by the size function.
+var sizeReturning = &FunctionCallReturningDeclaration{FieldKind: protoreflect.Int64Kind}
+
+// CollectionFunctionsOpt is an option that enables the functions of the repeated and map fields:
+//
+//   - size(field) compares the number of the elements, i.e.: size(items) > 2.
+//     It results in the expr.CompareExpr of the expr.FunctionCallExpr named 'size', with the field selector argument.
+//   - any(field, predicate) matches if any element of a repeated message field satisfies the predicate,
+//     i.e.: any(items, quantity > 2 AND sku = "abc"). Without the predicate, it matches a non-empty field.
+//   - all(field, predicate) matches if every element of a repeated message field satisfies the predicate,
+//     i.e.: all(items, quantity > 0).
+//
+// The any and all functions result in the expr.CompareExpr with the HAS comparator and the expr.AnyElementExpr,
+// where the All flag is the quantifier. The field selectors of the predicate are relative to the element message.
+// The functions take precedence over registered functions with the same names.
+func CollectionFunctionsOpt() Option {
+	return func(i *Interpreter) error {
+		i.collectionFunctions = true
+		return nil
+	}
+}
+
+// isCollectionCall checks if the function call is one of the collection functions, and these are enabled.
+func (b *Interpreter) isCollectionCall(x *ast.FunctionCall) bool {
+	if !b.collectionFunctions {
+		return false
+	}
+	return x.JoinedNameEquals(SizeFunctionName.String()) ||
+		x.JoinedNameEquals(AnyFunctionName.String()) ||
+		x.JoinedNameEquals(AllFunctionName.String())
+}
+
+// handleCollectionCall handles the restriction of the size, any or all collection function call.
+func (b *Interpreter) handleCollectionCall(ctx *ParseContext, x *ast.RestrictionExpr, fc *ast.FunctionCall) (TryParseValueResult, error) {
+	name := fc.JoinedName()
+	if len(fc.Fields) > 0 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Pos, ErrMsg: fmt.Sprintf("function: %s result has no fields", name)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	var args []ast.ArgExpr
+	if fc.ArgList != nil {
+		args = fc.ArgList.Args
+	}
+
+	isSize := name == SizeFunctionName.String()
+	isAll := name == AllFunctionName.String()
+	switch {
+	case isSize && len(args) != 1:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Lparen, ErrMsg: fmt.Sprintf("function: %s expects 1 argument: field, but got %d", name, len(args))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	case isAll && len(args) != 2:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Lparen, ErrMsg: fmt.Sprintf("function: %s expects 2 arguments: field and predicate, but got %d", name, len(args))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	case len(args) == 0 || len(args) > 2:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Lparen, ErrMsg: fmt.Sprintf("function: %s expects a field and an optional predicate, but got %d arguments", name, len(args))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	me, ok := args[0].(*ast.MemberExpr)
+	if !ok {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: args[0].Position(), ErrMsg: fmt.Sprintf("function: %s first argument must be a field, but got: %s", name, args[0].String())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	res, err := b.TryParseSelectorExpr(ctx, me.Value, me.Fields...)
+	if err != nil {
+		return res, err
+	}
+	left := res.Expr
+
+	_, mk, fd, ok := b.traverseLastFieldExpr(left)
+	if !ok {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: me.Position(), ErrMsg: "internal error: collection field is not a field selector expression"}, ErrInternal
+		}
+		return TryParseValueResult{}, ErrInternal
+	}
+
+	if isSize {
+		if mk != nil || !(fd.IsList() || fd.IsMap()) {
+			left.Free()
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: me.Position(), ErrMsg: fmt.Sprintf("function: %s field: '%s' is neither repeated nor a map", name, fd.Name())}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+		return b.handleSizeCall(ctx, x, fc, left)
+	}
+
+	if mk != nil || !isRepeatedMessage(fd, b.msgInfo.GetFieldInfo(fd)) {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: me.Position(), ErrMsg: fmt.Sprintf("function: %s field: '%s' is not a repeated message", name, fd.Name())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	if x.Comparator != nil || x.Arg != nil {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: fmt.Sprintf("function: %s cannot be compared", name)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	ae := expr.AcquireAnyElementExpr()
+	ae.Message = fd.Message().FullName()
+	ae.All = isAll
+
+	if len(args) == 2 {
+		// The predicate is resolved within the element message.
+		parent := ctx.Message
+		ctx.Message = fd.Message()
+		var pred TryParseValueResult
+		switch at := args[1].(type) {
+		case *ast.RestrictionExpr:
+			pred, err = b.HandleRestrictionExpr(ctx, at)
+		case *ast.CompositeExpr:
+			pred, err = b.HandleCompositeExpr(ctx, at)
+		default:
+			if ctx.ErrHandler != nil {
+				pred = TryParseValueResult{ErrPos: at.Position(), ErrMsg: fmt.Sprintf("function: %s second argument must be a restriction of the element, but got: %s", name, at.String())}
+			}
+			err = ErrInvalidValue
+		}
+		ctx.Message = parent
+		if err != nil {
+			left.Free()
+			ae.Free()
+			return pred, err
+		}
+		ae.Filter = pred.Expr
+	}
+
+	ce := expr.AcquireCompareExpr()
+	ce.Left = left
+	ce.Comparator = expr.HAS
+	ce.Right = ae
+	return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+}
+
+// handleSizeCall handles the comparison of the size function call with the selector of the field.
+func (b *Interpreter) handleSizeCall(ctx *ParseContext, x *ast.RestrictionExpr, fc *ast.FunctionCall, left expr.FilterExpr) (TryParseValueResult, error) {
+	if x.Comparator == nil || x.Arg == nil {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: fc.Pos, ErrMsg: fmt.Sprintf("function: %s needs to be compared, i.e.: size(items) > 0", SizeFunctionName)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	cmp, ok := parseComparator(x.Comparator)
+	if !ok || cmp == expr.HAS || cmp == expr.IN {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: fmt.Sprintf("function: %s cannot be compared with the comparator: %s", SizeFunctionName, x.Comparator.String())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	right, err := b.TryParseValue(ctx, TryParseValueInput{
+		Field:         sizeReturning,
+		Value:         x.Arg,
+		AllowIndirect: true,
+		Complexity:    1,
+	})
+	if err != nil {
+		left.Free()
+		return right, err
+	}
+
+	if v, ok := right.Expr.(*expr.ValueExpr); ok {
+		if n, ok := v.Value.(int64); ok && n < 0 {
+			left.Free()
+			right.Expr.Free()
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: x.Arg.Position(), ErrMsg: fmt.Sprintf("function: %s cannot be compared with a negative value", SizeFunctionName)}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+	}
+
+	size := expr.AcquireFunctionCallExpr()
+	size.Name = SizeFunctionName.Name
+	size.Arguments = append(size.Arguments, left)
+	size.CallComplexity = 1
+
+	ce := expr.AcquireCompareExpr()
+	ce.Left = size
+	ce.Comparator = cmp
+	ce.Right = right.Expr
+	return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+}
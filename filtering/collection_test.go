@@ -0,0 +1,190 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestInterpreter_CollectionFunctions(t *testing.T) {
+	var msg string
+	i, err := NewInterpreter(md, CollectionFunctionsOpt(), ErrHandlerOpt(func(pos token.Position, m string) {
+		msg = m
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("size", func(t *testing.T) {
+		for _, filter := range []string{`size(rp_i32) > 2`, `size(map_str_str) = 0`, `size(rp_sub) <= 10`} {
+			x, err := i.Parse(filter)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", filter, err)
+			}
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("%s: expected compare expression but got %T", filter, x)
+			}
+			fc, ok := ce.Left.(*expr.FunctionCallExpr)
+			if !ok || fc.Name != "size" || fc.PkgName != "" || len(fc.Arguments) != 1 {
+				t.Fatalf("%s: expected size function call but got %v", filter, ce.Left)
+			}
+			if _, ok = fc.Arguments[0].(*expr.FieldSelectorExpr); !ok {
+				t.Fatalf("%s: expected field selector argument but got %T", filter, fc.Arguments[0])
+			}
+			if _, ok = ce.Right.(*expr.ValueExpr).Value.(int64); !ok {
+				t.Fatalf("%s: expected int64 value but got %T", filter, ce.Right.(*expr.ValueExpr).Value)
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Fatalf("%s: expected valid expression but got: %v", filter, err)
+			}
+			if s, err := expr.String(x); err != nil || s != filter {
+				t.Fatalf("%s: expected rendered filter but got: %q, %v", filter, s, err)
+			}
+			x.Free()
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		x, err := i.Parse(`any(rp_sub, (i32 > 2 AND name = "abc"))`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ce, ok := x.(*expr.CompareExpr)
+		if !ok || ce.Comparator != expr.HAS {
+			t.Fatalf("expected HAS compare expression but got %v", x)
+		}
+		if left, ok := ce.Left.(*expr.FieldSelectorExpr); !ok || left.Field != "rp_sub" {
+			t.Fatalf("expected rp_sub field selector but got %v", ce.Left)
+		}
+		ae, ok := ce.Right.(*expr.AnyElementExpr)
+		if !ok {
+			t.Fatalf("expected any element expression but got %T", ce.Right)
+		}
+		if ae.All || ae.Message != md.FullName() {
+			t.Fatalf("expected existential any element expression of %s but got %v", md.FullName(), ae)
+		}
+		cps, ok := ae.Filter.(*expr.CompositeExpr)
+		if !ok {
+			t.Fatalf("expected composite element filter but got %T", ae.Filter)
+		}
+		and, ok := cps.Expr.(*expr.AndExpr)
+		if !ok || len(and.Expr) != 2 {
+			t.Fatalf("expected two element restrictions but got %v", cps.Expr)
+		}
+		if ec := and.Expr[0].(*expr.CompareExpr); ec.Comparator != expr.GT || ec.Left.(*expr.FieldSelectorExpr).Field != "i32" {
+			t.Fatalf("expected i32 > 2 element restriction but got %v", ec)
+		}
+
+		if err = ValidateExpr(md, x); err != nil {
+			t.Fatalf("expected valid expression but got: %v", err)
+		}
+		if s, err := expr.String(x); err != nil || s != `any(rp_sub, (i32 > 2 AND name = "abc"))` {
+			t.Fatalf("unexpected rendered filter: %q, %v", s, err)
+		}
+	})
+
+	t.Run("any without predicate", func(t *testing.T) {
+		x, err := i.Parse(`any(rp_sub)`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ae, ok := x.(*expr.CompareExpr).Right.(*expr.AnyElementExpr)
+		if !ok || ae.Filter != nil || ae.All {
+			t.Fatalf("expected any element expression without a filter but got %v", x.(*expr.CompareExpr).Right)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		x, err := i.Parse(`all(rp_sub, i64 >= 0)`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ae, ok := x.(*expr.CompareExpr).Right.(*expr.AnyElementExpr)
+		if !ok || !ae.All {
+			t.Fatalf("expected universal any element expression but got %v", x.(*expr.CompareExpr).Right)
+		}
+		if ec, ok := ae.Filter.(*expr.CompareExpr); !ok || ec.Comparator != expr.GE {
+			t.Fatalf("expected i64 >= 0 element restriction but got %v", ae.Filter)
+		}
+		if s, err := expr.String(x); err != nil || s != `all(rp_sub, i64 >= 0)` {
+			t.Fatalf("unexpected rendered filter: %q, %v", s, err)
+		}
+		c := expr.Capability{FieldComparisons: true, AnyElement: true}
+		if level, _ := c.Check(x); level != expr.Unsupported {
+			t.Fatalf("expected the all elements quantifier to be unsupported")
+		}
+	})
+
+	t.Run("negated", func(t *testing.T) {
+		x, err := i.Parse(`NOT any(rp_sub, name = "abc")`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		if _, ok := x.(*expr.NotExpr); !ok {
+			t.Fatalf("expected not expression but got %T", x)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			filter string
+			err    error
+		}{
+			{filter: `size(rp_i32)`, err: ErrInvalidValue},
+			{filter: `size(i32) > 1`, err: ErrInvalidValue},
+			{filter: `size(rp_i32) > -1`, err: ErrInvalidValue},
+			{filter: `size(rp_i32) > "abc"`, err: ErrInvalidValue},
+			{filter: `size(rp_i32, rp_i64) > 1`, err: ErrInvalidValue},
+			{filter: `any(rp_i32, i32 > 1)`, err: ErrInvalidValue},
+			{filter: `any(rp_sub, i32 > 1) = true`, err: ErrInvalidValue},
+			{filter: `any(rp_sub, unknown > 1)`, err: ErrFieldNotFound},
+			{filter: `any(rp_sub, "abc")`, err: ErrInvalidValue},
+			{filter: `all(rp_sub)`, err: ErrInvalidValue},
+		} {
+			msg = ""
+			_, err := i.Parse(tc.filter)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected error %v but got %v", tc.filter, tc.err, err)
+			}
+			if msg == "" {
+				t.Errorf("%s: expected error message", tc.filter)
+			}
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		d, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = d.Parse(`size(rp_i32) > 2`); err == nil {
+			t.Fatalf("expected error but got nil")
+		}
+	})
+}
@@ -0,0 +1,448 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package complete provides the completion candidates for a partially typed filter.
+// Given the filter, the cursor offset and the message descriptor, the Completer suggests
+// the field names (including nested paths), the comparators valid for the field type,
+// the enum value names, the registered function names and the logical keywords.
+// It is meant to power the query builders and the filter editors of the user interfaces.
+package complete
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ErrInvalidCursor is returned when the cursor is out of the filter bounds.
+var ErrInvalidCursor = errors.New("complete: invalid cursor")
+
+// Kind is the kind of the completion candidate.
+type Kind int
+
+// The kinds of the completion candidates.
+const (
+	FieldKind Kind = iota + 1
+	ComparatorKind
+	EnumValueKind
+	ValueKind
+	FunctionKind
+	KeywordKind
+)
+
+var kindNames = [...]string{
+	FieldKind:      "field",
+	ComparatorKind: "comparator",
+	EnumValueKind:  "enum_value",
+	ValueKind:      "value",
+	FunctionKind:   "function",
+	KeywordKind:    "keyword",
+}
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	if k <= 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Candidate is a single completion candidate.
+type Candidate struct {
+	// Text is the text that replaces the filter in range [Result.Start, cursor).
+	Text string
+
+	// Kind is the kind of the candidate.
+	Kind Kind
+
+	// Detail is a short human-readable description of the candidate,
+	// i.e. the type of the field or the full name of the enum.
+	Detail string
+}
+
+// Result is the result of the completion.
+type Result struct {
+	// Start is the byte offset where the replaced text begins.
+	// The candidates replace the filter in range [Start, cursor).
+	Start int
+
+	// Candidates are the matching completion candidates.
+	Candidates []Candidate
+}
+
+// Option is an option of the Completer.
+type Option func(*Completer) error
+
+// FunctionsOpt sets the function declarations suggested by the Completer.
+// The declarations of the interpreter could be obtained from its options,
+// i.e. interpreter.Options().Functions.
+func FunctionsOpt(decls ...*filtering.FunctionCallDeclaration) Option {
+	return func(c *Completer) error {
+		for _, d := range decls {
+			if d == nil {
+				return errors.New("complete: nil function declaration")
+			}
+			c.functions = append(c.functions, d.Name.String())
+		}
+		return nil
+	}
+}
+
+// RegexMatchOpt enables the suggestion of the regex match comparator '=~' for string fields.
+// It should be set if the interpreter has the filtering.RegexMatchOpt set.
+func RegexMatchOpt() Option {
+	return func(c *Completer) error {
+		c.regexMatch = true
+		return nil
+	}
+}
+
+// Completer provides the completion candidates of the filters for a message.
+// It is safe for concurrent use.
+type Completer struct {
+	md         protoreflect.MessageDescriptor
+	msgInfo    info.MessagesInfo
+	functions  []string
+	regexMatch bool
+}
+
+// New creates a new Completer for the input message descriptor.
+func New(md protoreflect.MessageDescriptor, opts ...Option) (*Completer, error) {
+	if md == nil {
+		return nil, errors.New("complete: nil message descriptor")
+	}
+	c := &Completer{md: md, msgInfo: info.MapMsgInfo(md)}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(c.functions)
+	return c, nil
+}
+
+// Complete returns the completion candidates for the filter with the cursor at the given byte offset.
+// Only the part of the filter before the cursor is taken into account.
+// The candidates are matched case-insensitively with the text typed just before the cursor.
+func (c *Completer) Complete(filter string, cursor int) (Result, error) {
+	if cursor < 0 || cursor > len(filter) {
+		return Result{}, ErrInvalidCursor
+	}
+	src := filter[:cursor]
+
+	// The errors are ignored on purpose, as the partial filter is usually invalid,
+	// i.e. it may contain an unterminated string.
+	toks, _ := scanner.Tokenize(src)
+	if n := len(toks); n > 0 && toks[n-1].Tok == token.EOF {
+		toks = toks[:n-1]
+	}
+
+	// Find the word that is being typed, just before the cursor.
+	i := len(toks)
+	start := cursor
+	if i > 0 && isWord(toks[i-1].Tok) && int(toks[i-1].End) == cursor {
+		i--
+		start = int(toks[i].Pos)
+	}
+
+	// Find the field path preceding the word, i.e. 'sub.sub.'.
+	var path []string
+	for i >= 2 && toks[i-1].Tok == token.PERIOD && toks[i-2].Tok.IsIdent() {
+		path = append([]string{toks[i-2].Lit}, path...)
+		i -= 2
+		start = int(toks[i].Pos)
+	}
+	prefix := src[start:]
+	typing := start != cursor
+
+	// Find the token preceding the typed text.
+	j := i
+	hasWS := false
+	for j > 0 && toks[j-1].Tok == token.WS {
+		hasWS = true
+		j--
+	}
+
+	res := Result{Start: start}
+	var prev token.Token
+	if j > 0 {
+		prev = toks[j-1].Tok
+	}
+	switch {
+	case j == 0, prev.IsLogical(), prev == token.LPAREN, prev == token.COMMA:
+		// The beginning of a term.
+		c.termCandidates(&res, path, prefix)
+	case prev.IsComparator():
+		// The value of the restriction.
+		c.valueCandidates(&res, toks[:j-1], path, prefix)
+	case !hasWS && !typing:
+		// The cursor is glued to a previous token, i.e. 'i32<cursor>'.
+	case typing:
+		// A word typed after a complete expression, i.e. 'a = 1 AN'.
+		c.keywordCandidates(&res, prefix)
+	case c.isRestrictionStart(toks[:j]):
+		// The field followed by the whitespace, i.e. 'i32 '.
+		if fi, ok := c.resolvePath(c.md, trailingPath(toks[:j])); ok {
+			c.comparatorCandidates(&res, fi.Desc)
+		}
+	default:
+		// A complete restriction, i.e. 'a = 1 '.
+		c.keywordCandidates(&res, prefix)
+	}
+	return res, nil
+}
+
+// termCandidates adds the candidates for the beginning of the term: fields, functions and the NOT keyword.
+func (c *Completer) termCandidates(res *Result, path []string, prefix string) {
+	c.fieldCandidates(res, path, prefix)
+	c.functionCandidates(res, prefix)
+	if len(path) == 0 {
+		res.addIfMatch(prefix, Candidate{Text: "NOT", Kind: KeywordKind})
+	}
+}
+
+// valueCandidates adds the candidates for the restriction value.
+func (c *Completer) valueCandidates(res *Result, before []scanner.Token, path []string, prefix string) {
+	if len(path) == 0 {
+		for len(before) > 0 && before[len(before)-1].Tok == token.WS {
+			before = before[:len(before)-1]
+		}
+		if fi, ok := c.resolvePath(c.md, trailingPath(before)); ok {
+			fd := fi.Desc
+			if fd.IsMap() {
+				fd = fd.MapValue()
+			}
+			switch fd.Kind() {
+			case protoreflect.EnumKind:
+				// The enum values are string literals, the candidates keep the quote already typed.
+				quote, name := `"`, prefix
+				if len(prefix) > 0 && (prefix[0] == '"' || prefix[0] == '\'') {
+					quote, name = prefix[:1], prefix[1:]
+				}
+				ed := fd.Enum()
+				vals := ed.Values()
+				for k := 0; k < vals.Len(); k++ {
+					cand := Candidate{
+						Text:   quote + string(vals.Get(k).Name()) + quote,
+						Kind:   EnumValueKind,
+						Detail: string(ed.FullName()),
+					}
+					if hasPrefixFold(string(vals.Get(k).Name()), name) {
+						res.Candidates = append(res.Candidates, cand)
+					}
+				}
+			case protoreflect.BoolKind:
+				res.addIfMatch(prefix, Candidate{Text: "true", Kind: ValueKind, Detail: "bool"})
+				res.addIfMatch(prefix, Candidate{Text: "false", Kind: ValueKind, Detail: "bool"})
+			}
+			if fi.Nullable {
+				res.addIfMatch(prefix, Candidate{Text: "null", Kind: ValueKind, Detail: "null"})
+			}
+		}
+	}
+	// The value might also be a function call.
+	c.functionCandidates(res, prefix)
+}
+
+// keywordCandidates adds the logical keywords that follow a complete expression.
+func (c *Completer) keywordCandidates(res *Result, prefix string) {
+	res.addIfMatch(prefix, Candidate{Text: "AND", Kind: KeywordKind})
+	res.addIfMatch(prefix, Candidate{Text: "OR", Kind: KeywordKind})
+}
+
+// fieldCandidates adds the filterable fields of the message at the given path.
+func (c *Completer) fieldCandidates(res *Result, path []string, prefix string) {
+	md := c.md
+	if len(path) > 0 {
+		fi, ok := c.resolvePath(md, path)
+		if !ok || !traversable(fi) {
+			return
+		}
+		md = fi.Desc.Message()
+	}
+	mi := c.msgInfo.MessageInfo(md)
+	if mi == nil {
+		return
+	}
+	pathPrefix := ""
+	if len(path) > 0 {
+		pathPrefix = strings.Join(path, ".") + "."
+	}
+	for _, fi := range mi.Fields {
+		if fi.FilteringForbidden {
+			continue
+		}
+		res.addIfMatch(prefix, Candidate{
+			Text:   pathPrefix + string(fi.Desc.Name()),
+			Kind:   FieldKind,
+			Detail: fieldType(fi.Desc),
+		})
+	}
+}
+
+// functionCandidates adds the registered functions.
+func (c *Completer) functionCandidates(res *Result, prefix string) {
+	for _, fn := range c.functions {
+		res.addIfMatch(prefix, Candidate{Text: fn, Kind: FunctionKind, Detail: "function"})
+	}
+}
+
+// comparatorCandidates adds the comparators valid for the field.
+func (c *Completer) comparatorCandidates(res *Result, fd protoreflect.FieldDescriptor) {
+	for _, cmp := range c.comparators(fd) {
+		res.Candidates = append(res.Candidates, Candidate{Text: cmp, Kind: ComparatorKind})
+	}
+}
+
+// comparators returns the comparators valid for the field.
+func (c *Completer) comparators(fd protoreflect.FieldDescriptor) []string {
+	if fd.IsList() || fd.IsMap() {
+		return []string{":"}
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind, protoreflect.BytesKind:
+		return []string{"=", "!="}
+	case protoreflect.EnumKind:
+		return []string{"=", "!=", "IN"}
+	case protoreflect.StringKind:
+		cmps := []string{"=", "!=", "<", "<=", ">", ">=", ":", "IN"}
+		if c.regexMatch {
+			cmps = append(cmps, "=~")
+		}
+		return cmps
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp", "google.protobuf.Duration":
+			return []string{"=", "!=", "<", "<=", ">", ">="}
+		}
+		return []string{"=", "!=", ":"}
+	default:
+		return []string{"=", "!=", "<", "<=", ">", ">=", "IN"}
+	}
+}
+
+// isRestrictionStart checks if the tokens end with a field path that starts a restriction,
+// i.e. the path is preceded by the beginning of the filter, a logical operator or a parenthesis.
+func (c *Completer) isRestrictionStart(toks []scanner.Token) bool {
+	n := len(toks)
+	if n == 0 || !toks[n-1].Tok.IsIdent() || toks[n-1].Tok.IsKeyword() {
+		return false
+	}
+	n--
+	for n >= 2 && toks[n-1].Tok == token.PERIOD && toks[n-2].Tok.IsIdent() {
+		n -= 2
+	}
+	for n > 0 && toks[n-1].Tok == token.WS {
+		n--
+	}
+	if n == 0 {
+		return true
+	}
+	prev := toks[n-1].Tok
+	return prev.IsLogical() || prev == token.LPAREN
+}
+
+// resolvePath resolves the field at the given path, starting from the message descriptor.
+func (c *Completer) resolvePath(md protoreflect.MessageDescriptor, path []string) (info.FieldInfo, bool) {
+	var fi info.FieldInfo
+	if len(path) == 0 {
+		return fi, false
+	}
+	for k, name := range path {
+		if k > 0 {
+			if !traversable(fi) {
+				return fi, false
+			}
+			md = fi.Desc.Message()
+		}
+		mi := c.msgInfo.MessageInfo(md)
+		if mi == nil {
+			return fi, false
+		}
+		var ok bool
+		fi, ok = mi.FieldByName(protoreflect.Name(name))
+		if !ok || fi.FilteringForbidden {
+			return fi, false
+		}
+	}
+	return fi, true
+}
+
+// traversable checks if the nested fields of the field could be suggested.
+func traversable(fi info.FieldInfo) bool {
+	fd := fi.Desc
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() || fi.NonTraversal {
+		return false
+	}
+	return !fi.IsTimestamp && !fi.IsDuration && !fi.IsStructpb
+}
+
+// trailingPath returns the field path the tokens end with, i.e. 'sub.name'.
+func trailingPath(toks []scanner.Token) []string {
+	n := len(toks)
+	if n == 0 || !toks[n-1].Tok.IsIdent() {
+		return nil
+	}
+	path := []string{toks[n-1].Lit}
+	n--
+	for n >= 2 && toks[n-1].Tok == token.PERIOD && toks[n-2].Tok.IsIdent() {
+		path = append([]string{toks[n-2].Lit}, path...)
+		n -= 2
+	}
+	return path
+}
+
+// fieldType returns the human-readable type of the field.
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	elem := func(fd protoreflect.FieldDescriptor) string {
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			return string(fd.Message().FullName())
+		case protoreflect.EnumKind:
+			return string(fd.Enum().FullName())
+		}
+		return fd.Kind().String()
+	}
+	switch {
+	case fd.IsMap():
+		return "map<" + elem(fd.MapKey()) + ", " + elem(fd.MapValue()) + ">"
+	case fd.IsList():
+		return "repeated " + elem(fd)
+	}
+	return elem(fd)
+}
+
+// isWord checks if the token could be a part of a typed word.
+func isWord(tok token.Token) bool {
+	return tok.IsIdent() || tok.IsLiteral()
+}
+
+// addIfMatch adds the candidate if it matches the prefix case-insensitively.
+func (r *Result) addIfMatch(prefix string, cand Candidate) {
+	if hasPrefixFold(cand.Text, prefix) {
+		r.Candidates = append(r.Candidates, cand)
+	}
+}
+
+// hasPrefixFold checks if the s begins with the prefix, ignoring the case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(prefix) <= len(s) && strings.EqualFold(prefix, s[:len(prefix)])
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package complete
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestCompleter_Complete(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	c, err := New(md, RegexMatchOpt(), FunctionsOpt(&filtering.FunctionCallDeclaration{
+		Name: filtering.FunctionName{PkgName: "time", Name: "now"},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create completer: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		cursor int // -1 means the end of the filter
+		start  int
+		want   []string
+	}{
+		{name: "field prefix", filter: `i3`, cursor: -1, start: 0, want: []string{"i32", "i32_complexity", "i32_optional"}},
+		{name: "case insensitive", filter: `MAP_STR_S`, cursor: -1, start: 0, want: []string{"map_str_str", "map_str_s32", "map_str_s64", "map_str_sf32", "map_str_sf64"}},
+		{name: "forbidden field", filter: `no_f`, cursor: -1, start: 0, want: nil},
+		{name: "function", filter: `ti`, cursor: -1, start: 0, want: []string{"timestamp", "timestamp_optional", "time.now"}},
+		// The test message has a field named NOT.
+		{name: "not keyword", filter: `a = 1 AND NOT`, cursor: -1, start: 10, want: []string{"NOT", "NOT"}},
+		{name: "nested field", filter: `sub.na`, cursor: -1, start: 0, want: []string{"sub.name"}},
+		{name: "nested path", filter: `sub.sub.i6`, cursor: -1, start: 0, want: []string{"sub.sub.i64", "sub.sub.i64_optional"}},
+		{name: "nested in composite", filter: `i32 = 1 AND (sub.rp_i6`, cursor: -1, start: 13, want: []string{"sub.rp_i64"}},
+		{name: "timestamp not traversed", filter: `timestamp.`, cursor: -1, start: 0, want: nil},
+		{name: "repeated not traversed", filter: `rp_sub.`, cursor: -1, start: 0, want: nil},
+		{name: "string comparators", filter: `str `, cursor: -1, start: 4, want: []string{"=", "!=", "<", "<=", ">", ">=", ":", "IN", "=~"}},
+		{name: "nested bool comparators", filter: `a = 1 OR sub.bool `, cursor: -1, start: 18, want: []string{"=", "!="}},
+		{name: "repeated comparators", filter: `rp_str `, cursor: -1, start: 7, want: []string{":"}},
+		{name: "timestamp comparators", filter: `timestamp `, cursor: -1, start: 10, want: []string{"=", "!=", "<", "<=", ">", ">="}},
+		{name: "enum values", filter: `enum = `, cursor: -1, start: 7, want: []string{`"UNKNOWN"`, `"ONE"`, `"TWO"`, `"THREE"`, "time.now"}},
+		{name: "enum value prefix", filter: `enum = "T`, cursor: -1, start: 7, want: []string{`"TWO"`, `"THREE"`}},
+		{name: "enum value single quoted", filter: `sub.enum != 'o`, cursor: -1, start: 12, want: []string{`'ONE'`}},
+		{name: "bool values", filter: `bool = f`, cursor: -1, start: 7, want: []string{"false"}},
+		{name: "nullable value", filter: `i32_optional = nu`, cursor: -1, start: 15, want: []string{"null"}},
+		{name: "logical keywords", filter: `i32 = 1 `, cursor: -1, start: 8, want: []string{"AND", "OR"}},
+		{name: "logical keyword prefix", filter: `i32 = 1 O`, cursor: -1, start: 8, want: []string{"OR"}},
+		{name: "cursor in the middle", filter: `str AND i32 = 1`, cursor: 4, start: 4, want: []string{"=", "!=", "<", "<=", ">", ">=", ":", "IN", "=~"}},
+		{name: "glued to parenthesis", filter: `(i32 = 1)`, cursor: -1, start: 9, want: nil},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := tt.cursor
+			if cursor < 0 {
+				cursor = len(tt.filter)
+			}
+			res, err := c.Complete(tt.filter, cursor)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Start != tt.start {
+				t.Errorf("expected start %d but got %d", tt.start, res.Start)
+			}
+			var got []string
+			for _, cand := range res.Candidates {
+				got = append(got, cand.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompleter_Detail(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	c, err := New(md)
+	if err != nil {
+		t.Fatalf("failed to create completer: %v", err)
+	}
+	res, err := c.Complete(`map_str_enum AND rp_sub`, 23)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Candidate{{Text: "rp_sub", Kind: FieldKind, Detail: "repeated testpb.Message"}}
+	if !reflect.DeepEqual(res.Candidates, want) {
+		t.Errorf("expected %v but got %v", want, res.Candidates)
+	}
+
+	res, err = c.Complete(`map_str_enum`, 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []Candidate{{Text: "map_str_enum", Kind: FieldKind, Detail: "map<string, testpb.Enum>"}}
+	if !reflect.DeepEqual(res.Candidates, want) {
+		t.Errorf("expected %v but got %v", want, res.Candidates)
+	}
+}
+
+func TestCompleter_InvalidCursor(t *testing.T) {
+	c, err := New(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create completer: %v", err)
+	}
+	for _, cursor := range []int{-1, 4} {
+		if _, err = c.Complete(`abc`, cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected error %v for cursor %d but got %v", ErrInvalidCursor, cursor, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/scanner"
+)
+
+// ComparatorDeclaration is a declaration of the custom comparator, i.e. "~" for the LIKE match,
+// or "^=" for the prefix match.
+type ComparatorDeclaration struct {
+	// Symbol is the symbol of the comparator in the filter, see the scanner.IsValidComparator.
+	Symbol string `json:"symbol"`
+
+	// Comparator is the comparator of the resulting expr.CompareExpr.
+	// It needs to be a custom comparator, greater than the expr.IN, and it is registered with the expr.RegisterComparator.
+	Comparator expr.Comparator `json:"comparator"`
+
+	// Kinds are the kinds of the fields the comparator is allowed for.
+	// The message kind allows any message field, including the well-known types like the google.protobuf.Timestamp.
+	// An empty list allows all the kinds.
+	Kinds []protoreflect.Kind `json:"kinds,omitempty"`
+}
+
+// allowsKind checks if the comparator is allowed for the field kind.
+func (d *ComparatorDeclaration) allowsKind(k protoreflect.Kind) bool {
+	if len(d.Kinds) == 0 {
+		return true
+	}
+	for _, dk := range d.Kinds {
+		if dk == k {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomComparatorOpt is an option that registers the custom comparators, i.e.:
+//
+//	CustomComparatorOpt(ComparatorDeclaration{Symbol: "~", Comparator: Like, Kinds: []protoreflect.Kind{protoreflect.StringKind}})
+//
+// A restriction with the custom comparator results in the expr.CompareExpr with the declared Comparator,
+// and its right hand side must be a value of the field, i.e.: title ~ "%go%".
+// The comparator is allowed only on the singular fields and the map values of the declared kinds.
+// The backends need to handle the custom comparators on their own, as the translators reject them as unsupported.
+func CustomComparatorOpt(decls ...ComparatorDeclaration) Option {
+	return func(i *Interpreter) error {
+		for _, decl := range decls {
+			if !scanner.IsValidComparator(decl.Symbol) {
+				return fmt.Errorf("invalid custom comparator symbol %q", decl.Symbol)
+			}
+			if _, ok := i.customComparators[decl.Symbol]; ok {
+				return fmt.Errorf("custom comparator %q is already registered", decl.Symbol)
+			}
+			for _, cd := range i.customComparators {
+				if cd.Comparator == decl.Comparator {
+					return fmt.Errorf("comparator %d is already registered as %q", decl.Comparator, cd.Symbol)
+				}
+			}
+			if err := expr.RegisterComparator(decl.Comparator, decl.Symbol); err != nil {
+				return err
+			}
+
+			if i.customComparators == nil {
+				i.customComparators = make(map[string]*ComparatorDeclaration)
+			}
+			d := decl
+			d.Kinds = append([]protoreflect.Kind(nil), decl.Kinds...)
+			i.customComparators[d.Symbol] = &d
+		}
+		return nil
+	}
+}
+
+// customComparatorsOption returns the parser option of the custom comparators, if any.
+func (b *Interpreter) customComparatorsOption() parser.ParserOption {
+	if len(b.customComparators) == 0 {
+		return nil
+	}
+	symbols := make([]string, 0, len(b.customComparators))
+	for symbol := range b.customComparators {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return parser.CustomComparatorsOption(symbols...)
+}
+
+// handleCustomComparator handles the restriction with the custom comparator.
+// The left expression is owned by the function, and it is freed on failure.
+func (b *Interpreter) handleCustomComparator(ctx *ParseContext, x *ast.RestrictionExpr, left expr.FilterExpr) (TryParseValueResult, error) {
+	decl, ok := b.customComparators[x.Comparator.Symbol]
+	if !ok {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: fmt.Sprintf("comparator '%s' is not enabled", x.Comparator.Symbol)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	_, mk, fd, ok := b.traverseLastFieldExpr(left)
+	if !ok {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparable.Position(), ErrMsg: "internal error: left hand side of restriction expression is not a field selector expression"}, ErrInternal
+		}
+		return TryParseValueResult{}, ErrInternal
+	}
+	fi := b.msgInfo.GetFieldInfo(fd)
+
+	vd := fd
+	if mk != nil {
+		vd = fd.MapValue()
+	}
+	if !decl.allowsKind(vd.Kind()) || !isSingularField(fd, mk != nil) {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: fmt.Sprintf("comparator '%s' is not allowed for the field: '%s'", decl.Symbol, fd.Name())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	ve, err := b.TryParseValue(ctx, TryParseValueInput{
+		Field:      vd,
+		Value:      x.Arg,
+		IsOptional: fi.Nullable,
+		Complexity: fi.Complexity,
+		IsLiteral:  b.isLiteralStringField(vd),
+	})
+	if err != nil {
+		left.Free()
+		return ve, err
+	}
+	if _, ok = ve.Expr.(*expr.ValueExpr); !ok {
+		// The string search and the array values have no meaning for the custom comparator.
+		left.Free()
+		ve.Expr.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Arg.Position(), ErrMsg: fmt.Sprintf("comparator '%s' requires a single value, but got: %s", decl.Symbol, x.Arg.String())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	ce := expr.AcquireCompareExpr()
+	ce.Left = left
+	ce.Comparator = decl.Comparator
+	ce.Right = ve.Expr
+	return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+}
+
+// isSingularField checks if the field has a single value to compare with, which is the map value
+// if the field is selected by the map key, thus neither a repeated nor a map field.
+func isSingularField(fd protoreflect.FieldDescriptor, isMapKey bool) bool {
+	return isMapKey || (!fd.IsList() && !fd.IsMap())
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+const (
+	testLikeComparator   = expr.Comparator(100)
+	testPrefixComparator = expr.Comparator(101)
+)
+
+func TestInterpreter_CustomComparators(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, CustomComparatorOpt(
+		ComparatorDeclaration{Symbol: "~", Comparator: testLikeComparator, Kinds: []protoreflect.Kind{protoreflect.StringKind}},
+		ComparatorDeclaration{Symbol: "^=", Comparator: testPrefixComparator},
+	))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		cmp    expr.Comparator
+		want   string
+		err    error
+	}{
+		{filter: `str ~ "%foo%"`, cmp: testLikeComparator, want: `str ~ "%foo%"`},
+		{filter: `sub.name~"a_c"`, cmp: testLikeComparator, want: `sub.name ~ "a_c"`},
+		{filter: `map_str_str."key" ~ "%x"`, cmp: testLikeComparator, want: `map_str_str.key ~ "%x"`},
+		{filter: `i32 ^= 12`, cmp: testPrefixComparator, want: `i32 ^= 12`},
+		{filter: `i32 ~ "1"`, err: ErrInvalidValue},
+		{filter: `rp_str ~ "foo"`, err: ErrInvalidValue},
+		{filter: `str ~ "foo*"`, err: ErrInvalidValue},
+		{filter: `i32 ^= "abc"`, err: ErrInvalidValue},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected CompareExpr but got %T", x)
+			}
+			if ce.Comparator != tt.cmp {
+				t.Errorf("expected comparator %s but got %s", tt.cmp, ce.Comparator)
+			}
+			got, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to unparse expression: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		d, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if x, err := d.Parse(`str ~ "foo"`); err == nil {
+			x.Free()
+			t.Fatalf("expected error of the disabled comparator")
+		}
+	})
+}
+
+func TestCustomComparatorOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tc := []struct {
+		name  string
+		decls []ComparatorDeclaration
+	}{
+		{name: "invalid symbol", decls: []ComparatorDeclaration{{Symbol: "==", Comparator: testLikeComparator}}},
+		{name: "standard comparator", decls: []ComparatorDeclaration{{Symbol: "~", Comparator: expr.EQ}}},
+		{name: "duplicate symbol", decls: []ComparatorDeclaration{
+			{Symbol: "~", Comparator: testLikeComparator},
+			{Symbol: "~", Comparator: testPrefixComparator},
+		}},
+		{name: "conflicting symbol", decls: []ComparatorDeclaration{{Symbol: "@>", Comparator: testLikeComparator}}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInterpreter(md, CustomComparatorOpt(tt.decls...)); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
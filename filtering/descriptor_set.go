@@ -0,0 +1,130 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// NewInterpreterFromDescriptorSet returns a new interpreter for the message with given full name,
+// resolved from the file descriptor set, i.e. obtained by the gRPC server reflection or a schema registry.
+// It allows to validate the filters of arbitrary services without compiling their proto files.
+// The dependencies missing in the set, like the well-known types, are resolved from the protoregistry.GlobalFiles.
+func NewInterpreterFromDescriptorSet(fds *descriptorpb.FileDescriptorSet, name protoreflect.FullName, opts ...Option) (*Interpreter, error) {
+	if fds == nil {
+		return nil, errors.New("file descriptor set is not set")
+	}
+	files, err := newDescriptorSetFiles(fds)
+	if err != nil {
+		return nil, err
+	}
+	return NewInterpreterFromName(files, name, opts...)
+}
+
+// NewInterpreterFromName returns a new interpreter for the message with given full name, resolved from the files registry.
+// The message may be known only at runtime, without the generated Go types, i.e. when a gateway proxies arbitrary services.
+// The values of such messages are represented with the dynamicpb.Message, and the blocky and google.api annotations
+// are resolved from the descriptor options, even if these were unmarshaled without the annotation types.
+// If the files are nil, the message is resolved from the protoregistry.GlobalFiles.
+func NewInterpreterFromName(files *protoregistry.Files, name protoreflect.FullName, opts ...Option) (*Interpreter, error) {
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+	d, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("message %q not found in the files registry: %w", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor %q is not a message", name)
+	}
+	return NewInterpreter(md, opts...)
+}
+
+// newDescriptorSetFiles creates the files registry of the file descriptor set.
+// The files are registered in the dependency order, regardless of their order in the set.
+func newDescriptorSetFiles(fds *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	r := descriptorSetResolver{
+		files:  new(protoregistry.Files),
+		protos: make(map[string]*descriptorpb.FileDescriptorProto, len(fds.GetFile())),
+	}
+	for _, fdp := range fds.GetFile() {
+		if _, ok := r.protos[fdp.GetName()]; ok {
+			return nil, fmt.Errorf("file %q is duplicated in the file descriptor set", fdp.GetName())
+		}
+		r.protos[fdp.GetName()] = fdp
+	}
+	for _, fdp := range fds.GetFile() {
+		if err := r.register(fdp, nil); err != nil {
+			return nil, err
+		}
+	}
+	return r.files, nil
+}
+
+// descriptorSetResolver is a protodesc.Resolver of the file descriptor set files,
+// which falls back to the protoregistry.GlobalFiles for the files not included in the set.
+type descriptorSetResolver struct {
+	files  *protoregistry.Files
+	protos map[string]*descriptorpb.FileDescriptorProto
+}
+
+func (r *descriptorSetResolver) register(fdp *descriptorpb.FileDescriptorProto, path []string) error {
+	if _, err := r.files.FindFileByPath(fdp.GetName()); err == nil {
+		return nil
+	}
+	for _, p := range path {
+		if p == fdp.GetName() {
+			return fmt.Errorf("file %q has an import cycle", fdp.GetName())
+		}
+	}
+	path = append(path, fdp.GetName())
+
+	for _, dep := range fdp.GetDependency() {
+		if dp, ok := r.protos[dep]; ok {
+			if err := r.register(dp, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	fd, err := protodesc.NewFile(fdp, r)
+	if err != nil {
+		return fmt.Errorf("invalid file %q: %w", fdp.GetName(), err)
+	}
+	return r.files.RegisterFile(fd)
+}
+
+// FindFileByPath implements the protodesc.Resolver interface.
+func (r *descriptorSetResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+// FindDescriptorByName implements the protodesc.Resolver interface.
+func (r *descriptorSetResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestNewInterpreterFromDescriptorSet(t *testing.T) {
+	testFile := protodesc.ToFileDescriptorProto(testpb.File_internal_testpb_message_proto)
+	tsFile := protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto)
+
+	t.Run("valid", func(t *testing.T) {
+		tc := []struct {
+			name string
+			fds  *descriptorpb.FileDescriptorSet
+		}{
+			{name: "global dependencies", fds: &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile}}},
+			{name: "unordered dependencies", fds: &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile, tsFile}}},
+		}
+		for _, tt := range tc {
+			t.Run(tt.name, func(t *testing.T) {
+				i, err := NewInterpreterFromDescriptorSet(tt.fds, "testpb.Message")
+				if err != nil {
+					t.Fatalf("failed to create interpreter: %v", err)
+				}
+				x, err := i.Parse(`name = "foo" AND timestamp > 2021-01-01T00:00:00Z AND sub = testpb.Message{i32: 1}`)
+				if err != nil {
+					t.Fatalf("failed to parse filter: %v", err)
+				}
+				x.Free()
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tc := []struct {
+			name    string
+			fds     *descriptorpb.FileDescriptorSet
+			message protoreflect.FullName
+		}{
+			{name: "nil set", message: "testpb.Message"},
+			{name: "unknown message", fds: &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile}}, message: "testpb.Unknown"},
+			{name: "not a message", fds: &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile}}, message: "testpb.Enum"},
+			{name: "duplicated file", fds: &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testFile, testFile}}, message: "testpb.Message"},
+		}
+		for _, tt := range tc {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := NewInterpreterFromDescriptorSet(tt.fds, tt.message); err == nil {
+					t.Fatal("expected error")
+				}
+			})
+		}
+	})
+}
+
+func TestNewInterpreterFromName(t *testing.T) {
+	t.Run("global files", func(t *testing.T) {
+		i, err := NewInterpreterFromName(nil, "testpb.Message")
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		x, err := i.Parse(`name = "foo"`)
+		if err != nil {
+			t.Fatalf("failed to parse filter: %v", err)
+		}
+		x.Free()
+	})
+
+	t.Run("unresolved annotations", func(t *testing.T) {
+		// The options unmarshaled without the annotation types keep the annotations as the unknown fields.
+		fdp := protodesc.ToFileDescriptorProto(testpb.File_internal_testpb_message_proto)
+		for _, mdp := range fdp.GetMessageType() {
+			for _, field := range mdp.GetField() {
+				if field.Options == nil {
+					continue
+				}
+				b, err := proto.Marshal(field.Options)
+				if err != nil {
+					t.Fatalf("failed to marshal field options: %v", err)
+				}
+				field.Options = new(descriptorpb.FieldOptions)
+				if err = (proto.UnmarshalOptions{Resolver: new(protoregistry.Types)}).Unmarshal(b, field.Options); err != nil {
+					t.Fatalf("failed to unmarshal field options: %v", err)
+				}
+			}
+		}
+		fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+		if err != nil {
+			t.Fatalf("failed to build test file: %v", err)
+		}
+		files := new(protoregistry.Files)
+		if err = files.RegisterFile(fd); err != nil {
+			t.Fatalf("failed to register test file: %v", err)
+		}
+
+		i, err := NewInterpreterFromName(files, "testpb.Message")
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = i.Parse(`no_filter = "test"`); !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("expected forbidden filtering error but got: %v", err)
+		}
+		x, err := i.Parse(`str_optional = null AND sub = testpb.Message{i32: 1}`)
+		if err != nil {
+			t.Fatalf("failed to parse filter: %v", err)
+		}
+		x.Free()
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewInterpreterFromName(nil, "testpb.Unknown"); err == nil {
+			t.Fatal("expected error")
+		}
+		if _, err := NewInterpreterFromName(nil, "testpb.Enum"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -17,6 +17,7 @@
 // Read more at https://google.aip.dev/160.
 //
 // An Interpreter is used to parse a filter expression and return an implementation of the expr.Expr interface.
+// Once initialized, an Interpreter is safe for concurrent use, and could be shared across multiple goroutines.
 // This implementation supports custom function calls, that can be either direct or abstract call.
 // An abstract call results in returning of expr.FunctionCall expression, that can be handled by the caller.
 // A direct call works like a macro that converts input arguments to a concrete expression.
@@ -26,4 +27,6 @@
 //     pkg.MyType{field1: value1, field2: value2} or
 //     map{key1: value1, key2: value2}
 //   - Array value expression - allows to use repeated value expression like: [1, 2, 3]
+//   - Struct pattern on repeated message fields - matches if any element matches the pattern like:
+//     items:{sku: "abc"}
 package filtering
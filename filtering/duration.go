@@ -29,6 +29,25 @@ import (
 
 var durationMsgDesc = new(durationpb.Duration).ProtoReflect().Descriptor()
 
+// DurationDaysOpt is an option that accepts the day 'd' and week 'w' units of the duration literals,
+// on top of the time.ParseDuration ones, i.e.: ttl > 1w2d12h.
+// A day is always 24 hours long, regardless of the daylight saving time changes,
+// which is why it is not enabled by default.
+func DurationDaysOpt() Option {
+	return func(i *Interpreter) error {
+		i.durationDays = true
+		return nil
+	}
+}
+
+// parseDuration parses the duration literal, with the day and week units if these are enabled.
+func (b *Interpreter) parseDuration(lit string) (time.Duration, error) {
+	if b.durationDays {
+		return ParseDuration(lit)
+	}
+	return time.ParseDuration(lit)
+}
+
 // TryParseDurationField tries to parse the provided value as a duration.
 // It returns an error if the value is not a valid duration.
 func (b *Interpreter) TryParseDurationField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
@@ -77,7 +96,7 @@ func (b *Interpreter) TryParseDurationField(ctx *ParseContext, in TryParseValueI
 
 		// The duration probably don't have fractal part.
 		// Try parsing it as an integer with unit.
-		d, err := time.ParseDuration(ft.Value)
+		d, err := b.parseDuration(ft.Value)
 		if err != nil {
 			if ctx.ErrHandler != nil {
 				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Kind(), ft.Value)}, ErrInvalidValue
@@ -101,15 +120,16 @@ func (b *Interpreter) TryParseDurationField(ctx *ParseContext, in TryParseValueI
 				Complexity:    in.Complexity,
 			})
 			if err != nil {
+				ae.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ae.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
-				ae.Free()
 				return TryParseValueResult{}, ErrInternal
 			}
 
@@ -249,6 +269,19 @@ func (b *Interpreter) TryParseDurationField(ctx *ParseContext, in TryParseValueI
 		ve := expr.AcquireValueExpr()
 		ve.Value = d.AsDuration()
 		return TryParseValueResult{Expr: ve}, nil
+	case *ast.FunctionCall:
+		// A function call needs to return a duration value.
+		fn, ok := b.getFunctionDeclaration(ctx, ft)
+		if !ok || fn.ServiceCall() || fn.Returning.Message() == nil ||
+			fn.Returning.Message().FullName() != durationMsgDesc.FullName() {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = ft.Position()
+				res.ErrMsg = fmt.Sprintf("function call %s does not return a %s value", ft.JoinedName(), durationMsgDesc.FullName())
+			}
+			return res, ErrInvalidValue
+		}
+		return b.tryParseAndCallFunction(ctx, ft, fn, in.AllowIndirect)
 	default:
 		// This is invalid AST node, return an error.
 		if ctx.ErrHandler != nil {
@@ -15,10 +15,12 @@
 package filtering
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
 )
 
 const tstDurationFieldEQDirect = `duration = 1s`
@@ -332,3 +334,59 @@ func testRepeatedDurationHasDirect(t *testing.T, x expr.FilterExpr) {
 		t.Fatalf("expected value 1s but got %d", dv)
 	}
 }
+
+func TestInterpreter_DurationDays(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	days, err := NewInterpreter(md, DurationDaysOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	plain, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   time.Duration
+		plain  error
+	}{
+		{filter: `duration = 5m`, want: 5 * time.Minute},
+		{filter: `duration = 2h30m`, want: 150 * time.Minute},
+		{filter: `duration = 1d`, want: 24 * time.Hour, plain: ErrInvalidValue},
+		{filter: `duration > 1w2d12h`, want: 9*24*time.Hour + 12*time.Hour, plain: ErrInvalidValue},
+		{filter: `duration < -1.5d`, want: -36 * time.Hour, plain: ErrInvalidValue},
+		{filter: `duration > 0d`, want: time.Duration(0), plain: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := days.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			ve, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if ve.Value != tt.want {
+				t.Errorf("expected value %s but got %v", tt.want, ve.Value)
+			}
+
+			_, err = plain.Parse(tt.filter)
+			if !errors.Is(err, tt.plain) {
+				t.Errorf("expected error %v without the option but got %v", tt.plain, err)
+			}
+		})
+	}
+
+	if _, err = days.Parse(`timestamp < timestamp_optional + 1d`); err != nil {
+		t.Errorf("failed to parse arithmetic with the day unit: %v", err)
+	}
+}
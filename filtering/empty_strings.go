@@ -0,0 +1,103 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// EmptyStringMode defines how the empty string and the null values of the string fields relate to each other.
+type EmptyStringMode int
+
+const (
+	// EmptyStringDistinct keeps the empty string and the null values distinct.
+	// The `field = ""` compares the field with an empty string, whereas the `field = null` is allowed only
+	// for the nullable string fields.
+	// This is the default mode.
+	EmptyStringDistinct EmptyStringMode = iota
+	// EmptyStringAsNull makes the empty string value of a nullable string field a null value,
+	// so that the `field = ""` is equivalent to the `field = null`.
+	// The empty string values of the non-nullable string fields are left intact.
+	EmptyStringAsNull
+	// NullAsEmptyString makes the null value of a string field an empty string value,
+	// so that the `field = null` is equivalent to the `field = ""`, regardless of the field nullability.
+	NullAsEmptyString
+)
+
+var _EmptyStringModeStrings = [...]string{
+	EmptyStringDistinct: "DISTINCT",
+	EmptyStringAsNull:   "EMPTY_AS_NULL",
+	NullAsEmptyString:   "NULL_AS_EMPTY",
+}
+
+// String returns the string representation of the empty string mode.
+func (m EmptyStringMode) String() string {
+	if m < 0 || int(m) >= len(_EmptyStringModeStrings) {
+		return fmt.Sprintf("EmptyStringMode(%d)", m)
+	}
+	return _EmptyStringModeStrings[m]
+}
+
+// EmptyStringModeOpt is an option that sets the relation between the empty string and the null values
+// of the string fields. By default, the EmptyStringDistinct mode is used.
+// A nullable string field is a field with the OPTIONAL field behavior annotation.
+// The null value results in the expr.ValueExpr with a nil Value, whereas the empty string results in
+// the expr.ValueExpr with an empty string Value, thus the translators could keep the same distinction.
+func EmptyStringModeOpt(mode EmptyStringMode) Option {
+	return func(i *Interpreter) error {
+		if mode < EmptyStringDistinct || mode > NullAsEmptyString {
+			return fmt.Errorf("invalid empty string mode: %s", mode)
+		}
+		i.emptyStringMode = mode
+		return nil
+	}
+}
+
+// emptyStringValue returns the value expression of an empty string for the string field.
+func (b *Interpreter) emptyStringValue(nullable bool) *expr.ValueExpr {
+	ve := expr.AcquireValueExpr()
+	if !nullable || b.emptyStringMode != EmptyStringAsNull {
+		ve.Value = ""
+	}
+	return ve
+}
+
+// nullStringValue returns the value expression of a null for the string field.
+// It returns false if the null value is not expressible for the field.
+func (b *Interpreter) nullStringValue(nullable bool) (*expr.ValueExpr, bool) {
+	switch {
+	case b.emptyStringMode == NullAsEmptyString:
+		ve := expr.AcquireValueExpr()
+		ve.Value = ""
+		return ve, true
+	case nullable:
+		return expr.AcquireValueExpr(), true
+	default:
+		return nil, false
+	}
+}
+
+// isNullLiteral checks if the member expression is a sole null keyword.
+func isNullLiteral(x *ast.MemberExpr) bool {
+	if len(x.Fields) > 0 {
+		return false
+	}
+	tl, ok := x.Value.(*ast.TextLiteral)
+	return ok && tl.Token == token.NULL
+}
@@ -17,8 +17,6 @@ package filtering
 import (
 	"fmt"
 
-	"google.golang.org/protobuf/reflect/protoreflect"
-
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
 	"github.com/blockysource/blocky-aip/token"
@@ -42,16 +40,24 @@ func (b *Interpreter) TryParseEnumField(ctx *ParseContext, in TryParseValueInput
 		return TryParseValueResult{}, ErrInvalidValue
 	}
 
-	var sl *ast.StringLiteral
+	var (
+		name string
+		pos  token.Position
+	)
 	switch ft := in.Value.(type) {
 	case *ast.StringLiteral:
-		sl = ft
+		name, pos = ft.Value, ft.Pos
 	case *ast.TextLiteral:
 		if in.IsOptional && ft.Token == token.NULL {
 			ve := expr.AcquireValueExpr()
 			ve.Value = nil
 			return TryParseValueResult{Expr: ve}, nil
 		}
+		if ft.Token == token.IDENT && (b.lenientEnums || in.Field.Enum().FullName() == "google.type.DayOfWeek") {
+			// The day of week names are unambiguous, thus are accepted unquoted, i.e.: MONDAY.
+			name, pos = ft.Value, ft.Pos
+			break
+		}
 		if ctx.ErrHandler != nil {
 			return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a valid value: '%s'. String literal required", in.Field.Enum().FullName(), ft.Value)}, ErrInvalidValue
 		}
@@ -69,11 +75,13 @@ func (b *Interpreter) TryParseEnumField(ctx *ParseContext, in TryParseValueInput
 				Complexity:    in.Complexity,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ve.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
@@ -108,10 +116,10 @@ func (b *Interpreter) TryParseEnumField(ctx *ParseContext, in TryParseValueInput
 		return TryParseValueResult{}, ErrInvalidAST
 	}
 
-	enumValue := in.Field.Enum().Values().ByName(protoreflect.Name(sl.Value))
+	enumValue := b.findEnumValue(in.Field.Enum(), name)
 	if enumValue == nil {
 		if ctx.ErrHandler != nil {
-			return TryParseValueResult{ErrPos: sl.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Enum().FullName(), sl.Value)}, ErrInvalidValue
+			return TryParseValueResult{ErrPos: pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Enum().FullName(), name)}, ErrInvalidValue
 		}
 		return TryParseValueResult{}, ErrInvalidValue
 	}
@@ -0,0 +1,192 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ErrorCode is a code that classifies the FilterError.
+type ErrorCode int
+
+const (
+	// ErrorCodeUnknown is a code of an error that doesn't match any of the standard errors.
+	ErrorCodeUnknown ErrorCode = iota
+	// ErrorCodeInvalidSyntax is a code of an error for a filter with invalid syntax.
+	ErrorCodeInvalidSyntax
+	// ErrorCodeInvalidField is a code of the ErrInvalidField error.
+	ErrorCodeInvalidField
+	// ErrorCodeFieldNotFound is a code of the ErrFieldNotFound error.
+	ErrorCodeFieldNotFound
+	// ErrorCodeAmbiguousField is a code of the ErrAmbiguousField error.
+	ErrorCodeAmbiguousField
+	// ErrorCodeInvalidValue is a code of the ErrInvalidValue error.
+	ErrorCodeInvalidValue
+	// ErrorCodeNoHandlerFound is a code of the ErrNoHandlerFound error.
+	ErrorCodeNoHandlerFound
+	// ErrorCodeInvalidAST is a code of the ErrInvalidAST error.
+	ErrorCodeInvalidAST
+	// ErrorCodeInternal is a code of the ErrInternal error.
+	ErrorCodeInternal
+	// ErrorCodeLimitExceeded is a code of the ErrLimitExceeded error.
+	ErrorCodeLimitExceeded
+	// ErrorCodeUnsupported is a code of the ErrUnsupported error.
+	ErrorCodeUnsupported
+)
+
+var _ErrorCodeStrings = [...]string{
+	ErrorCodeUnknown:        "UNKNOWN",
+	ErrorCodeInvalidSyntax:  "INVALID_SYNTAX",
+	ErrorCodeInvalidField:   "INVALID_FIELD",
+	ErrorCodeFieldNotFound:  "FIELD_NOT_FOUND",
+	ErrorCodeAmbiguousField: "AMBIGUOUS_FIELD",
+	ErrorCodeInvalidValue:   "INVALID_VALUE",
+	ErrorCodeNoHandlerFound: "NO_HANDLER_FOUND",
+	ErrorCodeInvalidAST:     "INVALID_AST",
+	ErrorCodeInternal:       "INTERNAL",
+	ErrorCodeLimitExceeded:  "LIMIT_EXCEEDED",
+	ErrorCodeUnsupported:    "UNSUPPORTED",
+}
+
+// String returns the string representation of the error code.
+func (c ErrorCode) String() string {
+	if c < 0 || int(c) >= len(_ErrorCodeStrings) {
+		return fmt.Sprintf("ErrorCode(%d)", c)
+	}
+	return _ErrorCodeStrings[c]
+}
+
+// ParseErrorCode returns the error code of given string representation, i.e. 'FIELD_NOT_FOUND'.
+func ParseErrorCode(s string) (ErrorCode, bool) {
+	for c, str := range _ErrorCodeStrings {
+		if str == s {
+			return ErrorCode(c), true
+		}
+	}
+	return ErrorCodeUnknown, false
+}
+
+// FilterError is an error returned by the Interpreter when the filter could not be parsed.
+// It carries the position and the offending snippet of the filter, and wraps one of the standard errors
+// (i.e. ErrInvalidValue), so that it can still be checked with errors.Is.
+type FilterError struct {
+	// Code is the classification of the error.
+	Code ErrorCode
+
+	// Pos is the position in the filter where the error occurred.
+	Pos token.Position
+
+	// Snippet is the offending part of the filter, found at the Pos.
+	Snippet string
+
+	// Msg is a detailed error message.
+	Msg string
+
+	// Err is the wrapped standard error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FilterError) Error() string {
+	if e.Msg == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s at position %d", e.Err, e.Msg, e.Pos)
+}
+
+// Unwrap returns the wrapped standard error.
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the gRPC status representation of the error.
+// Errors caused by the filter input are returned with the codes.InvalidArgument code
+// and errdetails.BadRequest details, where the violation field is 'filter'.
+func (e *FilterError) GRPCStatus() *status.Status {
+	if e.Code == ErrorCodeInternal || e.Code == ErrorCodeUnknown {
+		return status.New(codes.Internal, e.Error())
+	}
+
+	st := status.New(codes.InvalidArgument, e.Error())
+	ds, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "filter", Description: e.Error()},
+		},
+	})
+	if err != nil {
+		return st
+	}
+	return ds
+}
+
+// newFilterError creates a new FilterError for the input src filter.
+func newFilterError(src string, pos token.Position, msg string, err error) *FilterError {
+	return &FilterError{
+		Code:    errorCodeOf(err),
+		Pos:     pos,
+		Snippet: snippetAt(src, pos),
+		Msg:     msg,
+		Err:     err,
+	}
+}
+
+func errorCodeOf(err error) ErrorCode {
+	switch {
+	case errors.Is(err, parser.ErrInvalidFilterSyntax):
+		return ErrorCodeInvalidSyntax
+	case errors.Is(err, ErrInvalidField):
+		return ErrorCodeInvalidField
+	case errors.Is(err, ErrFieldNotFound):
+		return ErrorCodeFieldNotFound
+	case errors.Is(err, ErrAmbiguousField):
+		return ErrorCodeAmbiguousField
+	case errors.Is(err, ErrInvalidValue):
+		return ErrorCodeInvalidValue
+	case errors.Is(err, ErrNoHandlerFound):
+		return ErrorCodeNoHandlerFound
+	case errors.Is(err, ErrInvalidAST):
+		return ErrorCodeInvalidAST
+	case errors.Is(err, ErrInternal):
+		return ErrorCodeInternal
+	case errors.Is(err, ErrLimitExceeded):
+		return ErrorCodeLimitExceeded
+	case errors.Is(err, ErrUnsupported):
+		return ErrorCodeUnsupported
+	default:
+		return ErrorCodeUnknown
+	}
+}
+
+// snippetAt returns the whitespace delimited part of the src, that starts at the pos.
+func snippetAt(src string, pos token.Position) string {
+	if pos < 0 || int(pos) >= len(src) {
+		return ""
+	}
+	s := src[pos:]
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestFilterError(t *testing.T) {
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name    string
+		filter  string
+		err     error
+		code    ErrorCode
+		pos     token.Position
+		snippet string
+	}{
+		{
+			name:    "invalid value",
+			filter:  `name = 123`,
+			err:     ErrInvalidValue,
+			code:    ErrorCodeInvalidValue,
+			pos:     7,
+			snippet: "123",
+		},
+		{
+			name:    "field not found",
+			filter:  `i32 = 1 AND unknown = 1`,
+			err:     ErrFieldNotFound,
+			code:    ErrorCodeFieldNotFound,
+			pos:     12,
+			snippet: "unknown",
+		},
+		{
+			name:    "invalid syntax",
+			filter:  `name = (`,
+			err:     parser.ErrInvalidFilterSyntax,
+			code:    ErrorCodeInvalidSyntax,
+			pos:     7,
+			snippet: "(",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := i.Parse(tt.filter)
+			if err == nil {
+				t.Fatalf("expected error but got nil")
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v but got %v", tt.err, err)
+			}
+
+			var fe *FilterError
+			if !errors.As(err, &fe) {
+				t.Fatalf("expected FilterError but got %T", err)
+			}
+			if fe.Code != tt.code {
+				t.Errorf("expected code %s but got %s", tt.code, fe.Code)
+			}
+			if fe.Pos != tt.pos {
+				t.Errorf("expected position %d but got %d", tt.pos, fe.Pos)
+			}
+			if fe.Snippet != tt.snippet {
+				t.Errorf("expected snippet %q but got %q", tt.snippet, fe.Snippet)
+			}
+			if fe.Msg == "" {
+				t.Errorf("expected error message to be set")
+			}
+
+			st, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("expected error to be convertible to status")
+			}
+			if st.Code() != codes.InvalidArgument {
+				t.Errorf("expected status code %s but got %s", codes.InvalidArgument, st.Code())
+			}
+			if len(st.Details()) != 1 {
+				t.Fatalf("expected single status detail but got %d", len(st.Details()))
+			}
+			br, ok := st.Details()[0].(*errdetails.BadRequest)
+			if !ok {
+				t.Fatalf("expected BadRequest detail but got %T", st.Details()[0])
+			}
+			if br.FieldViolations[0].Field != "filter" {
+				t.Errorf("expected field violation for 'filter' but got %q", br.FieldViolations[0].Field)
+			}
+		})
+	}
+}
+
+func TestFilterError_SelectorPath(t *testing.T) {
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	_, err = i.Parse(`sub.rp_str.name = "foo"`)
+	var fe *FilterError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FilterError but got %v", err)
+	}
+	if want := `field: "sub.rp_str" is a repeated field`; !strings.Contains(fe.Msg, want) {
+		t.Errorf("expected message to contain %q but got %q", want, fe.Msg)
+	}
+}
+
+func TestFilterError_FunctionCall(t *testing.T) {
+	callErr := errors.New("invalid CIDR address: 10.0.0.0/33")
+	decl := func(err error) *FunctionCallDeclaration {
+		return &FunctionCallDeclaration{
+			Name: FunctionName{PkgName: "test", Name: "CIDR"},
+			Arguments: []*FunctionCallArgumentDeclaration{
+				{ArgName: "cidr", FieldKind: protoreflect.StringKind},
+			},
+			Returning: &FunctionCallReturningDeclaration{FieldKind: protoreflect.StringKind},
+			CallFn: func(args ...expr.FilterExpr) (FunctionCallArgument, error) {
+				return FunctionCallArgument{}, err
+			},
+		}
+	}
+
+	tc := []struct {
+		name   string
+		callFn error
+		err    error
+		code   ErrorCode
+		status codes.Code
+	}{
+		{
+			name:   "invalid argument",
+			callFn: callErr,
+			err:    ErrInvalidValue,
+			code:   ErrorCodeInvalidValue,
+			status: codes.InvalidArgument,
+		},
+		{
+			name:   "internal",
+			callFn: fmt.Errorf("%w: backend unavailable", ErrInternal),
+			err:    ErrInternal,
+			code:   ErrorCodeInternal,
+			status: codes.Internal,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, RegisterFunction(decl(tt.callFn)))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			_, err = i.Parse(`str = test.CIDR("10.0.0.0/33")`)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v but got %v", tt.err, err)
+			}
+
+			var fe *FilterError
+			if !errors.As(err, &fe) {
+				t.Fatalf("expected FilterError but got %T", err)
+			}
+			if fe.Code != tt.code {
+				t.Errorf("expected code %s but got %s", tt.code, fe.Code)
+			}
+			if fe.Pos != 6 {
+				t.Errorf("expected position 6 but got %d", fe.Pos)
+			}
+			if !strings.Contains(fe.Msg, tt.callFn.Error()) {
+				t.Errorf("expected the message %q to contain the function error", fe.Msg)
+			}
+			if n := strings.Count(err.Error(), tt.callFn.Error()); n != 1 {
+				t.Errorf("expected the function error once in %q but got %d", err.Error(), n)
+			}
+			if st, _ := status.FromError(err); st.Code() != tt.status {
+				t.Errorf("expected status code %s but got %s", tt.status, st.Code())
+			}
+		})
+	}
+}
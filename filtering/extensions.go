@@ -0,0 +1,75 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ExtensionFieldsOpt is an option that allows filtering on given extension fields.
+// An extension field is selected by its name within the extended message, just like a regular field,
+// i.e. `priority > 1` for the 'priority' extension of the filtered message, or `parent.priority > 1` for the nested one.
+// The extended message needs to be the filtered message or one of its nested messages,
+// and the extension name cannot collide with a field of the extended message.
+func ExtensionFieldsOpt(xts ...protoreflect.ExtensionType) Option {
+	return func(i *Interpreter) error {
+		for _, xt := range xts {
+			if xt == nil {
+				return errors.New("extension type is nil")
+			}
+			xd := xt.TypeDescriptor()
+			md := xd.ContainingMessage()
+			if !i.isMessageMapped(md) {
+				return fmt.Errorf("extension %q extends %q, which is not the filtered message nor its nested message", xd.FullName(), md.FullName())
+			}
+			if md.Fields().ByName(xd.Name()) != nil {
+				return fmt.Errorf("extension %q name collides with a field of %q", xd.FullName(), md.FullName())
+			}
+			if prev := i.findExtensionField(md, string(xd.Name())); prev != nil {
+				if prev.FullName() == xd.FullName() {
+					continue
+				}
+				return fmt.Errorf("extension %q name collides with the extension %q", xd.FullName(), prev.FullName())
+			}
+			i.extensions = append(i.extensions, xt)
+			i.msgInfo.MapExtension(xd)
+		}
+		return nil
+	}
+}
+
+// findExtensionField finds the registered extension field of the md message by its name.
+// It returns nil if no extension matches the name.
+func (b *Interpreter) findExtensionField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	for _, xt := range b.extensions {
+		if xd := xt.TypeDescriptor(); xd.ContainingMessage() == md && string(xd.Name()) == name {
+			return xd
+		}
+	}
+	return nil
+}
+
+// isMessageMapped returns true if the message descriptor is known to the interpreter.
+func (b *Interpreter) isMessageMapped(md protoreflect.MessageDescriptor) bool {
+	for _, mi := range b.msgInfo {
+		if mi.Desc == md {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_ExtensionFields(t *testing.T) {
+	files, event, _, xts := testAnyMessage(t)
+
+	i, err := NewInterpreter(event, ExtensionFieldsOpt(xts...))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   string
+		err    error
+	}{
+		{filter: `priority > 1`, want: `priority > 1`},
+		{filter: `order.status = "PAID" OR priority = 2`, want: `order.status = "PAID" OR priority = 2`},
+		{filter: `priority = "high"`, err: ErrInvalidValue},
+		{filter: `order.missing = "PAID"`, err: ErrFieldNotFound},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.StringWithFiles(x, files)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("not allowed", func(t *testing.T) {
+		i, err := NewInterpreter(event)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = i.Parse(`priority > 1`); !errors.Is(err, ErrFieldNotFound) {
+			t.Errorf("expected error %v but got %v", ErrFieldNotFound, err)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		opts := i.Options()
+		if len(opts.ExtensionFields) != len(xts) {
+			t.Fatalf("expected %d extension fields but got %d", len(xts), len(opts.ExtensionFields))
+		}
+		j, err := NewInterpreter(event, opts.Opt())
+		if err != nil {
+			t.Fatalf("failed to create interpreter from options: %v", err)
+		}
+		x, err := j.Parse(`priority > 1`)
+		if err != nil {
+			t.Fatalf("failed to parse filter: %v", err)
+		}
+		x.Free()
+	})
+}
+
+func TestExtensionFieldsOpt_Invalid(t *testing.T) {
+	_, event, _, xts := testAnyMessage(t)
+
+	tc := []struct {
+		name string
+		md   protoreflect.MessageDescriptor
+		xts  []protoreflect.ExtensionType
+	}{
+		{name: "nil", md: event, xts: []protoreflect.ExtensionType{nil}},
+		{name: "unrelated message", md: new(testpb.Message).ProtoReflect().Descriptor(), xts: xts},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInterpreter(tt.md, ExtensionFieldsOpt(tt.xts...)); err == nil {
+				t.Errorf("expected error but got nil")
+			}
+		})
+	}
+}
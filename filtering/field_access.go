@@ -0,0 +1,167 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ParseOption is an option of a single call of the Parse.
+// Contrary to the Option, it doesn't change the interpreter, so that the same interpreter
+// could parse the filters of the callers with different permissions.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	allowedFields []string
+	deniedFields  []string
+}
+
+// AllowedFieldsOpt is a parse option that restricts the filter to the fields at given paths, along with their nested fields.
+// The paths are dot separated proto field names, i.e. 'sub.name'.
+// Multiple options are combined, so that the fields of any of the paths are allowed.
+// With no paths, none of the fields is allowed.
+// The fields of the free-text search query are not restricted.
+func AllowedFieldsOpt(paths ...string) ParseOption {
+	return func(o *parseOptions) {
+		if o.allowedFields == nil {
+			o.allowedFields = make([]string, 0, len(paths))
+		}
+		o.allowedFields = append(o.allowedFields, paths...)
+	}
+}
+
+// DeniedFieldsOpt is a parse option that rejects the fields at given paths, along with their nested fields,
+// i.e. the internal fields that must not be filtered by the tenants.
+// The paths are dot separated proto field names, i.e. 'sub.name'. A selector of the parent message of a denied field,
+// i.e. 'sub = testpb.Message{...}' or 'sub:*', is rejected as well, as it could reveal the value of the denied field.
+// The denied fields take precedence over the allowed ones.
+func DeniedFieldsOpt(paths ...string) ParseOption {
+	return func(o *parseOptions) {
+		o.deniedFields = append(o.deniedFields, paths...)
+	}
+}
+
+// fieldAccess is the field restriction of a single parse, nil if the fields are not restricted.
+type fieldAccess struct {
+	// allowed is nil if all the fields are allowed.
+	allowed []string
+	denied  []string
+}
+
+// fieldAccess applies the parse options, and verifies the field paths exist in the interpreter message.
+func (b *Interpreter) fieldAccess(opts []ParseOption) (*fieldAccess, error) {
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.allowedFields == nil && o.deniedFields == nil {
+		return nil, nil
+	}
+	for _, path := range append(o.allowedFields, o.deniedFields...) {
+		if err := b.verifyFieldPath(path); err != nil {
+			return nil, err
+		}
+	}
+	return &fieldAccess{allowed: o.allowedFields, denied: o.deniedFields}, nil
+}
+
+// verifyFieldPath checks if the dot separated path of proto field names exists in the interpreter message.
+func (b *Interpreter) verifyFieldPath(path string) error {
+	md := b.msg
+	for i, name := range strings.Split(path, ".") {
+		if md == nil {
+			return fmt.Errorf("%w: field path %q is not a message at %d element", ErrFieldNotFound, path, i)
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("%w: field path %q not found in the message: %s", ErrFieldNotFound, path, b.msg.FullName())
+		}
+		md = fd.Message()
+		if fd.IsMap() {
+			md = fd.MapValue().Message()
+		}
+	}
+	return nil
+}
+
+// permits checks if the field at given path could be selected.
+func (fa *fieldAccess) permits(path string) bool {
+	for _, d := range fa.denied {
+		if isPathWithin(path, d) || isPathWithin(d, path) {
+			return false
+		}
+	}
+	if fa.allowed == nil {
+		return true
+	}
+	for _, a := range fa.allowed {
+		if isPathWithin(path, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPathWithin checks if the path is equal to, or nested within the parent path.
+func isPathWithin(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent) && path[len(parent)] == '.'
+}
+
+// checkFieldAccess verifies the selector resolved at given position is permitted by the parse field access.
+// The selector expression is freed if it is not.
+func (b *Interpreter) checkFieldAccess(ctx *ParseContext, pos token.Position, res TryParseValueResult) (TryParseValueResult, error) {
+	path := selectorFieldPath(res.Expr)
+	if ctx.fieldAccess.permits(path) {
+		return res, nil
+	}
+	res.Expr.Free()
+
+	var out TryParseValueResult
+	if ctx.ErrHandler != nil {
+		out.ErrPos = pos
+		out.ErrMsg = fmt.Sprintf("field: %q cannot be used in a filter", path)
+	}
+	return out, ErrInvalidField
+}
+
+// selectorFieldPath returns the dot separated field names of the selector expression,
+// the map keys are not part of the path.
+func selectorFieldPath(x expr.FilterExpr) string {
+	var sb strings.Builder
+	for cur := expr.Expr(x); cur != nil; {
+		switch ct := cur.(type) {
+		case *expr.FieldSelectorExpr:
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(string(ct.Field))
+			cur = ct.Traversal
+		case *expr.MapKeyExpr:
+			cur = ct.Traversal
+		default:
+			cur = nil
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/cache"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_Parse_FieldAccess(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		opts   []ParseOption
+		err    error
+	}{
+		{name: "no options", filter: `str = "foo" AND sub.name = "bar"`},
+		{name: "allowed", filter: `str = "foo"`, opts: []ParseOption{AllowedFieldsOpt("str", "i32")}},
+		{name: "allowed nested", filter: `sub.name = "foo"`, opts: []ParseOption{AllowedFieldsOpt("sub")}},
+		{name: "not allowed", filter: `str = "foo" AND i64 = 1`, opts: []ParseOption{AllowedFieldsOpt("str")}, err: ErrInvalidField},
+		{name: "allowed combined", filter: `str = "foo" AND i64 = 1`, opts: []ParseOption{AllowedFieldsOpt("str"), AllowedFieldsOpt("i64")}},
+		{name: "allowed none", filter: `str = "foo"`, opts: []ParseOption{AllowedFieldsOpt()}, err: ErrInvalidField},
+		{name: "allowed parent", filter: `sub = testpb.Message{name: "foo"}`, opts: []ParseOption{AllowedFieldsOpt("sub.name")}, err: ErrInvalidField},
+		{name: "denied", filter: `i32 = 1 OR str = "foo"`, opts: []ParseOption{DeniedFieldsOpt("str")}, err: ErrInvalidField},
+		{name: "denied other", filter: `i32 = 1`, opts: []ParseOption{DeniedFieldsOpt("str")}},
+		{name: "denied nested", filter: `sub.sub.name = "foo"`, opts: []ParseOption{DeniedFieldsOpt("sub.sub")}, err: ErrInvalidField},
+		{name: "denied sibling", filter: `sub.str = "foo"`, opts: []ParseOption{DeniedFieldsOpt("sub.name")}},
+		{name: "denied parent", filter: `rp_sub:{str: "foo"}`, opts: []ParseOption{DeniedFieldsOpt("rp_sub.name")}, err: ErrInvalidField},
+		{name: "denied field comparison", filter: `i32 = i64`, opts: []ParseOption{DeniedFieldsOpt("i64")}, err: ErrInvalidValue},
+		{name: "denied map", filter: `map_str_i32:"key"`, opts: []ParseOption{DeniedFieldsOpt("map_str_i32")}, err: ErrInvalidField},
+		{name: "denied over allowed", filter: `sub.name = "foo"`, opts: []ParseOption{AllowedFieldsOpt("sub"), DeniedFieldsOpt("sub.name")}, err: ErrInvalidField},
+		{name: "unknown path", filter: `i32 = 1`, opts: []ParseOption{DeniedFieldsOpt("sub.unknown")}, err: ErrFieldNotFound},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter, tt.opts...)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			x.Free()
+		})
+	}
+}
+
+func TestInterpreter_Parse_FieldAccessCache(t *testing.T) {
+	mem, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, CacheOpt(mem, time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`str = "foo"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x.Free()
+
+	// The cached expression must not bypass the field restrictions.
+	if _, err = i.Parse(`str = "foo"`, DeniedFieldsOpt("str")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected error %v but got %v", ErrInvalidField, err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// TryParseFieldMaskField tries parsing a google.protobuf.FieldMask field value.
+// The field mask is a string of the comma separated field paths, i.e.: update_mask = "name,sub.title",
+// and is decoded into the *fieldmaskpb.FieldMask value.
+// If the field has the google.api.resource_reference annotation, and the message of the referenced resource type
+// is defined in the field file or its imports, the paths are validated against the fields of that message.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseFieldMaskField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	if len(in.Args) > 0 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Message().FullName(), joinedName(in.Value, in.Args...))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	switch ft := in.Value.(type) {
+	case *ast.StringLiteral:
+		fm, err := parseFieldMask(ft.Value, fieldMaskTarget(in.Field))
+		if err != nil {
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s': %v", in.Field.Message().FullName(), ft.Value, err)}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+		ve := expr.AcquireValueExpr()
+		ve.Value = fm
+		return TryParseValueResult{Expr: ve}, nil
+	case *ast.TextLiteral:
+		if in.IsOptional && ft.Token == token.NULL {
+			ve := expr.AcquireValueExpr()
+			ve.Value = nil
+			return TryParseValueResult{Expr: ve}, nil
+		}
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a quoted list of paths: '%s'", in.Field.Message().FullName(), ft.Value)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	case *ast.StructExpr:
+		// The message struct, i.e.: google.protobuf.FieldMask{paths: ["name"]}.
+		return b.TryParseMessageStructField(ctx, in)
+	case *ast.ArrayExpr:
+		ae := expr.AcquireArrayExpr()
+		for _, elem := range ft.Elements {
+			res, err := b.TryParseValue(ctx, TryParseValueInput{
+				Field:      in.Field,
+				IsOptional: in.IsOptional,
+				Value:      elem,
+				Complexity: in.Complexity,
+			})
+			if err != nil {
+				ae.Free()
+				return res, err
+			}
+			ae.Elements = append(ae.Elements, res.Expr)
+		}
+		return TryParseValueResult{Expr: ae}, nil
+	default:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: "invalid AST node"}, ErrInvalidAST
+		}
+		return TryParseValueResult{}, ErrInvalidAST
+	}
+}
+
+// parseFieldMask parses the comma separated field paths.
+// If the target is not nil, each path needs to select its field.
+func parseFieldMask(s string, target protoreflect.MessageDescriptor) (*fieldmaskpb.FieldMask, error) {
+	fm := &fieldmaskpb.FieldMask{}
+	if strings.TrimSpace(s) == "" {
+		return fm, nil
+	}
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, fmt.Errorf("empty path")
+		}
+		if target != nil {
+			if err := validateMaskPath(target, path); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, name := range strings.Split(path, ".") {
+				if !protoreflect.Name(name).IsValid() {
+					return nil, fmt.Errorf("invalid path: %q", path)
+				}
+			}
+		}
+		fm.Paths = append(fm.Paths, path)
+	}
+	return fm, nil
+}
+
+// validateMaskPath checks if the dot separated path selects a field of the message md.
+// Only the singular message fields could be traversed.
+func validateMaskPath(md protoreflect.MessageDescriptor, path string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("field: %q not found in the message: %s", name, md.FullName())
+		}
+		if i == len(names)-1 {
+			return nil
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("field: %q of the path: %q cannot be traversed", name, path)
+		}
+		md = fd.Message()
+	}
+	return nil
+}
+
+// fieldMaskTarget returns the message of the resource referenced by the google.api.resource_reference
+// annotation of the field mask field, or nil if there is no such annotation or the message is not found.
+func fieldMaskTarget(fd FieldDescriptor) protoreflect.MessageDescriptor {
+	pfd, ok := fd.(protoreflect.FieldDescriptor)
+	if !ok {
+		return nil
+	}
+	ref, ok := info.GetExtension(pfd.Options(), annotations.E_ResourceReference).(*annotations.ResourceReference)
+	if !ok || ref.GetType() == "" {
+		return nil
+	}
+	return findResourceMessage(pfd.ParentFile(), ref.GetType(), make(map[string]struct{}))
+}
+
+// findResourceMessage finds the message with the google.api.resource annotation of given type,
+// within the file and its imports.
+func findResourceMessage(f protoreflect.FileDescriptor, typ string, visited map[string]struct{}) protoreflect.MessageDescriptor {
+	if f == nil {
+		return nil
+	}
+	if _, ok := visited[f.Path()]; ok {
+		return nil
+	}
+	visited[f.Path()] = struct{}{}
+
+	if md := findResourceIn(f.Messages(), typ); md != nil {
+		return md
+	}
+	imports := f.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if md := findResourceMessage(imports.Get(i).FileDescriptor, typ, visited); md != nil {
+			return md
+		}
+	}
+	return nil
+}
+
+func findResourceIn(mds protoreflect.MessageDescriptors, typ string) protoreflect.MessageDescriptor {
+	for i := 0; i < mds.Len(); i++ {
+		md := mds.Get(i)
+		if rd, ok := info.GetExtension(md.Options(), annotations.E_Resource).(*annotations.ResourceDescriptor); ok && rd.GetType() == typ {
+			return md
+		}
+		if nested := findResourceIn(md.Messages(), typ); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
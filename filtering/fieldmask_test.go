@@ -0,0 +1,171 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_FieldMaskFields(t *testing.T) {
+	md := testFieldMaskMessage(t)
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   []string
+		err    error
+	}{
+		{filter: `mask = "name,author.title"`, want: []string{"name", "author.title"}},
+		{filter: `mask = " name , tags "`, want: []string{"name", "tags"}},
+		{filter: `mask = ""`, want: nil},
+		{filter: `free_mask = "any.path,other"`, want: []string{"any.path", "other"}},
+		{filter: `mask = "unknown"`, err: ErrInvalidValue},
+		{filter: `mask = "tags.title"`, err: ErrInvalidValue},
+		{filter: `mask = "name,,tags"`, err: ErrInvalidValue},
+		{filter: `free_mask = "a..b"`, err: ErrInvalidValue},
+		{filter: `mask = name`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ve, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", x.(*expr.CompareExpr).Right)
+			}
+			fm, ok := ve.Value.(*fieldmaskpb.FieldMask)
+			if !ok {
+				t.Fatalf("expected field mask value but got %T", ve.Value)
+			}
+			if !reflect.DeepEqual(fm.GetPaths(), tt.want) {
+				t.Fatalf("expected paths %v but got %v", tt.want, fm.GetPaths())
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Fatalf("expected valid expression but got: %v", err)
+			}
+		})
+	}
+
+	t.Run("unparse", func(t *testing.T) {
+		x, err := i.Parse(`mask = "name, author.title"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		if s, err := expr.String(x); err != nil || s != `mask = "name,author.title"` {
+			t.Fatalf("unexpected rendered filter: %q, %v", s, err)
+		}
+	})
+}
+
+// testFieldMaskMessage builds a message with the field mask fields, where the 'mask' field
+// references the testfieldmask.Book resource.
+func testFieldMaskMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	bookOpts := new(descriptorpb.MessageOptions)
+	proto.SetExtension(bookOpts, annotations.E_Resource, &annotations.ResourceDescriptor{
+		Type:    "testfieldmask.example.com/Book",
+		Pattern: []string{"books/{book}"},
+	})
+	maskOpts := new(descriptorpb.FieldOptions)
+	proto.SetExtension(maskOpts, annotations.E_ResourceReference, &annotations.ResourceReference{
+		Type: "testfieldmask.example.com/Book",
+	})
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			JsonName: proto.String(name),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	tags := field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	mask := field("mask", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.FieldMask")
+	mask.Options = maskOpts
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("testfieldmask/fieldmask.proto"),
+		Package: proto.String("testfieldmask"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			fieldmaskpb.File_google_protobuf_field_mask_proto.Path(),
+			annotations.File_google_api_resource_proto.Path(),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Masks"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					mask,
+					field("free_mask", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.FieldMask"),
+				},
+			},
+			{
+				Name:    proto.String("Book"),
+				Options: bookOpts,
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("author", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".testfieldmask.Author"),
+					tags,
+				},
+			},
+			{
+				Name: proto.String("Author"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("title", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build test file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+const (
+	asOfPkgName = "system"
+	asOfName    = "AsOf"
+)
+
+// AsOf is a protofiltering service call declaration of the snapshot reads, i.e.: `system.AsOf(2023-01-01T00:00:00Z)`.
+// It takes a direct google.protobuf.Timestamp value, and results in the expr.FunctionCallExpr with the
+// time.Time value argument, which is meant to be extracted from the filter by the service with the ExtractAsOf.
+func AsOf() *filtering.FunctionCallDeclaration {
+	return &asOfFunc
+}
+
+var asOfFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: asOfPkgName,
+		Name:    asOfName,
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			ArgName:           "timestamp",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: timestampDesc,
+		},
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 1 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for as of function: %v", len(args))
+		}
+
+		ve, ok := args[0].(*expr.ValueExpr)
+		if !ok {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid timestamp value expression: %T", args[0])
+		}
+		if _, ok = ve.Value.(time.Time); !ok {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid timestamp value expression: %T", ve.Value)
+		}
+
+		fc := expr.AcquireFunctionCallExpr()
+		fc.PkgName = asOfPkgName
+		fc.Name = asOfName
+		fc.Arguments = append(fc.Arguments, ve)
+		fc.CallComplexity = 1
+		return filtering.FunctionCallArgument{Expr: fc}, nil
+	},
+}
+
+// ExtractAsOf extracts the system.AsOf call from the top level conjunctions of the filter expression x,
+// i.e.: `system.AsOf(2023-01-01T00:00:00Z) AND state = "ACTIVE"`, and returns its timestamp along with the
+// residual filter, which is nil if nothing remains. It takes over the ownership of the x.
+// If the filter has no system.AsOf call, the zero time and the x are returned.
+// The system.AsOf nested in the disjunctions or negations, or used more than once is an error,
+// which wraps the filtering.ErrInvalidValue, and then the x is left intact.
+func ExtractAsOf(x expr.FilterExpr) (time.Time, expr.FilterExpr, error) {
+	var conj []expr.FilterExpr
+	ae, isAnd := x.(*expr.AndExpr)
+	if isAnd {
+		conj = ae.Expr
+	} else if x != nil {
+		conj = []expr.FilterExpr{x}
+	}
+
+	found := -1
+	for i, cx := range conj {
+		if isAsOfCall(cx) {
+			if found >= 0 {
+				return time.Time{}, x, fmt.Errorf("%w: %s.%s is used more than once", filtering.ErrInvalidValue, asOfPkgName, asOfName)
+			}
+			found = i
+			continue
+		}
+		if containsAsOf(cx) {
+			return time.Time{}, x, fmt.Errorf("%w: %s.%s must be a top level conjunction", filtering.ErrInvalidValue, asOfPkgName, asOfName)
+		}
+	}
+	if found < 0 {
+		return time.Time{}, x, nil
+	}
+
+	fc := conj[found].(*expr.FunctionCallExpr)
+	asOf := fc.Arguments[0].(*expr.ValueExpr).Value.(time.Time)
+	fc.Free()
+	if !isAnd {
+		return asOf, nil, nil
+	}
+
+	rest := make([]expr.FilterExpr, 0, len(ae.Expr)-1)
+	rest = append(rest, ae.Expr[:found]...)
+	rest = append(rest, ae.Expr[found+1:]...)
+	clear(ae.Expr)
+	ae.Expr = ae.Expr[:0]
+	ae.Free()
+	return asOf, expr.And(rest...), nil
+}
+
+// ValidateAsOf checks if the snapshot time asOf is not in the future of the now,
+// and is within the retention period, if it is greater than zero.
+// The returned error wraps the filtering.ErrInvalidValue.
+func ValidateAsOf(asOf, now time.Time, retention time.Duration) error {
+	if asOf.After(now) {
+		return fmt.Errorf("%w: %s.%s timestamp %s is in the future", filtering.ErrInvalidValue, asOfPkgName, asOfName, asOf.Format(time.RFC3339))
+	}
+	if retention > 0 && now.Sub(asOf) > retention {
+		return fmt.Errorf("%w: %s.%s timestamp %s exceeds the retention period of %s", filtering.ErrInvalidValue, asOfPkgName, asOfName, asOf.Format(time.RFC3339), retention)
+	}
+	return nil
+}
+
+// isAsOfCall checks if the x is the system.AsOf call with a valid timestamp argument.
+func isAsOfCall(x expr.FilterExpr) bool {
+	fc, ok := x.(*expr.FunctionCallExpr)
+	if !ok || fc.PkgName != asOfPkgName || fc.Name != asOfName || len(fc.Arguments) != 1 {
+		return false
+	}
+	ve, ok := fc.Arguments[0].(*expr.ValueExpr)
+	if !ok {
+		return false
+	}
+	_, ok = ve.Value.(time.Time)
+	return ok
+}
+
+// containsAsOf checks if the system.AsOf call is nested within the x.
+func containsAsOf(x expr.FilterExpr) bool {
+	switch xt := x.(type) {
+	case *expr.FunctionCallExpr:
+		return xt.PkgName == asOfPkgName && xt.Name == asOfName
+	case *expr.AndExpr:
+		return anyContainsAsOf(xt.Expr)
+	case *expr.OrExpr:
+		return anyContainsAsOf(xt.Expr)
+	case *expr.SequenceExpr:
+		return anyContainsAsOf(xt.Factors)
+	case *expr.NotExpr:
+		return containsAsOf(xt.Expr)
+	case *expr.CompositeExpr:
+		return containsAsOf(xt.Expr)
+	}
+	return false
+}
+
+func anyContainsAsOf(xs []expr.FilterExpr) bool {
+	for _, x := range xs {
+		if containsAsOf(x) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+func TestAsOf(t *testing.T) {
+	i, err := filtering.NewInterpreter(msgDesc, filtering.RegisterFunction(AsOf()))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	snapshot := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		filter   string
+		asOf     time.Time
+		residual string
+		err      error
+	}{
+		{name: "only", filter: `system.AsOf(2023-01-01T00:00:00Z)`, asOf: snapshot},
+		{name: "conjunction", filter: `i32 = 1 AND system.AsOf(2023-01-01T00:00:00Z) AND str = "foo"`, asOf: snapshot, residual: `i32 = 1 AND str = "foo"`},
+		{name: "none", filter: `i32 = 1`, residual: `i32 = 1`},
+		{name: "disjunction", filter: `i32 = 1 OR system.AsOf(2023-01-01T00:00:00Z)`, err: filtering.ErrInvalidValue},
+		{name: "negation", filter: `NOT system.AsOf(2023-01-01T00:00:00Z)`, err: filtering.ErrInvalidValue},
+		{name: "twice", filter: `system.AsOf(2023-01-01T00:00:00Z) AND system.AsOf(2023-01-02T00:00:00Z)`, err: filtering.ErrInvalidValue},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			x, err := i.Parse(tc.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+
+			asOf, residual, err := ExtractAsOf(x)
+			if residual != nil {
+				defer residual.Free()
+			}
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error %v but got %v", tc.err, err)
+				}
+				if residual != x {
+					t.Errorf("expected the filter to be left intact")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !asOf.Equal(tc.asOf) {
+				t.Errorf("expected as of %s but got %s", tc.asOf, asOf)
+			}
+			got, err := expr.String(residual)
+			if err != nil {
+				t.Fatalf("failed to render residual: %v", err)
+			}
+			if got != tc.residual {
+				t.Errorf("expected residual %q but got %q", tc.residual, got)
+			}
+		})
+	}
+
+	if _, err = i.Parse(`timestamp = system.AsOf(2023-01-01T00:00:00Z)`); err == nil {
+		t.Errorf("expected error for the service call used as a value")
+	}
+}
+
+func TestValidateAsOf(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	if err := ValidateAsOf(now.Add(-time.Hour), now, 24*time.Hour); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateAsOf(now.Add(time.Hour), now, 0); !errors.Is(err, filtering.ErrInvalidValue) {
+		t.Errorf("expected future timestamp error but got %v", err)
+	}
+	if err := ValidateAsOf(now.Add(-48*time.Hour), now, 24*time.Hour); !errors.Is(err, filtering.ErrInvalidValue) {
+		t.Errorf("expected retention error but got %v", err)
+	}
+}
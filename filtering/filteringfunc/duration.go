@@ -0,0 +1,133 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+var durationDesc = new(durationpb.Duration).ProtoReflect().Descriptor()
+
+// Duration is a protofiltering function call declaration,
+// that converts an input string value, i.e. "15m" or "1h30m", into a valid google.protobuf.Duration - (time.Duration ValueExpression).
+// The input string is parsed with the filtering.ParseDuration function, which accepts the day 'd' and week 'w' units,
+// i.e. duration("7d"), and it takes only direct values.
+func Duration() *filtering.FunctionCallDeclaration {
+	return &durationFunc
+}
+
+var durationFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		Name: "duration",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			ArgName:   "value",
+			FieldKind: protoreflect.StringKind,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind:         protoreflect.MessageKind,
+		MessageDescriptor: durationDesc,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 1 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for duration function: %v", len(args))
+		}
+
+		ve, ok := args[0].(*expr.ValueExpr)
+		if !ok {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid string value expression: %T", args[0])
+		}
+
+		s, ok := ve.Value.(string)
+		if !ok {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid string value expression: %T", ve.Value)
+		}
+
+		d, err := filtering.ParseDuration(s)
+		if err != nil {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid duration: %w", err)
+		}
+
+		res := expr.AcquireValueExpr()
+		res.Value = d
+		return filtering.FunctionCallArgument{Expr: res}, nil
+	},
+}
+
+// TimeSince is a protofiltering function call declaration,
+// that represents the duration elapsed since the input google.protobuf.Timestamp.
+// The result depends on the time of the evaluation, thus it always results in the
+// time.Since expr.FunctionCallExpr, which is of google.protobuf.Duration type and needs
+// to be evaluated by the service, i.e.: time.Since(last_seen) > duration("15m").
+func TimeSince() *filtering.FunctionCallDeclaration {
+	return &sinceFunc
+}
+
+var sinceFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: "time",
+		Name:    "Since",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:          true,
+			ArgName:           "ts",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: timestampDesc,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind:         protoreflect.MessageKind,
+		MessageDescriptor: durationDesc,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 1 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for since function: %v", len(args))
+		}
+
+		var isIndirect bool
+		switch ve := args[0].(type) {
+		case *expr.ValueExpr:
+			if _, ok := ve.Value.(time.Time); !ok {
+				return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid timestamp value expression: %T", ve.Value)
+			}
+		case *expr.FieldSelectorExpr, *expr.MapKeyExpr, *expr.FunctionCallExpr:
+			isIndirect = true
+		default:
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid timestamp value expression: %T", args[0])
+		}
+
+		fc := expr.AcquireFunctionCallExpr()
+		fc.PkgName = "time"
+		fc.Name = "Since"
+		fc.Arguments = append(fc.Arguments, args[0])
+		fc.CallComplexity = 1
+		return filtering.FunctionCallArgument{
+			Expr:       fc,
+			IsIndirect: isIndirect,
+		}, nil
+	},
+}
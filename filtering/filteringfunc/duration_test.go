@@ -0,0 +1,286 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+func TestDurationFunctionCall(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	checkRightTime := func(want time.Time) func(t *testing.T, x expr.FilterExpr) {
+		return func(t *testing.T, x expr.FilterExpr) {
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			right, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if ts, ok := right.Value.(time.Time); !ok || !ts.Equal(want) {
+				t.Fatalf("expected value %s but got %v", want, right.Value)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		filter  string
+		isErr   bool
+		err     error
+		checkFn func(t *testing.T, x expr.FilterExpr)
+	}{
+		{
+			name:   "since timestamp GT duration",
+			filter: `time.Since(timestamp) > duration("15m")`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				if ce.Comparator != expr.GT {
+					t.Fatalf("expected comparator %s but got %s", expr.GT, ce.Comparator)
+				}
+
+				left, ok := ce.Left.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", ce.Left)
+				}
+				if left.FullName() != "time.Since" {
+					t.Fatalf("expected function 'time.Since' but got %s", left.FullName())
+				}
+				if len(left.Arguments) != 1 {
+					t.Fatalf("expected 1 argument but got %d", len(left.Arguments))
+				}
+				arg, ok := left.Arguments[0].(*expr.FieldSelectorExpr)
+				if !ok {
+					t.Fatalf("expected field selector expression but got %T", left.Arguments[0])
+				}
+				if arg.Field != msgDesc.Fields().ByName("timestamp").Name() {
+					t.Fatalf("expected field 'timestamp' but got %s", arg.Field)
+				}
+
+				right, ok := ce.Right.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", ce.Right)
+				}
+				if d, ok := right.Value.(time.Duration); !ok || d != 15*time.Minute {
+					t.Fatalf("expected value %s but got %v", 15*time.Minute, right.Value)
+				}
+			},
+		},
+		{
+			name:   "since timestamp GT duration literal",
+			filter: `time.Since(timestamp) > 15m`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				right, ok := ce.Right.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", ce.Right)
+				}
+				if d, ok := right.Value.(time.Duration); !ok || d != 15*time.Minute {
+					t.Fatalf("expected value %s but got %v", 15*time.Minute, right.Value)
+				}
+			},
+		},
+		{
+			name:   "since timestamp GT duration field",
+			filter: `time.Since(timestamp) > duration`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				if _, ok = ce.Left.(*expr.FunctionCallExpr); !ok {
+					t.Fatalf("expected function call expression but got %T", ce.Left)
+				}
+				right, ok := ce.Right.(*expr.FieldSelectorExpr)
+				if !ok {
+					t.Fatalf("expected field selector expression but got %T", ce.Right)
+				}
+				if right.Field != msgDesc.Fields().ByName("duration").Name() {
+					t.Fatalf("expected field 'duration' but got %s", right.Field)
+				}
+			},
+		},
+		{
+			name:   "duration field LT since timestamp",
+			filter: `duration < time.Since(timestamp)`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				if ce.Comparator != expr.LT {
+					t.Fatalf("expected comparator %s but got %s", expr.LT, ce.Comparator)
+				}
+				if _, ok = ce.Left.(*expr.FieldSelectorExpr); !ok {
+					t.Fatalf("expected field selector expression but got %T", ce.Left)
+				}
+				right, ok := ce.Right.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", ce.Right)
+				}
+				if right.FullName() != "time.Since" {
+					t.Fatalf("expected function 'time.Since' but got %s", right.FullName())
+				}
+			},
+		},
+		{
+			name:   "duration field GT duration field",
+			filter: `duration > duration_optional`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				if _, ok = ce.Left.(*expr.FieldSelectorExpr); !ok {
+					t.Fatalf("expected field selector expression but got %T", ce.Left)
+				}
+				right, ok := ce.Right.(*expr.FieldSelectorExpr)
+				if !ok {
+					t.Fatalf("expected field selector expression but got %T", ce.Right)
+				}
+				if right.Field != msgDesc.Fields().ByName("duration_optional").Name() {
+					t.Fatalf("expected field 'duration_optional' but got %s", right.Field)
+				}
+			},
+		},
+		{
+			name:   "duration field LT duration function",
+			filter: `duration < duration("1h")`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				right, ok := ce.Right.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", ce.Right)
+				}
+				if d, ok := right.Value.(time.Duration); !ok || d != time.Hour {
+					t.Fatalf("expected value %s but got %v", time.Hour, right.Value)
+				}
+			},
+		},
+		{
+			name:   "duration function with days",
+			filter: `duration < duration("7d")`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				right, ok := ce.Right.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", ce.Right)
+				}
+				if d, ok := right.Value.(time.Duration); !ok || d != 7*24*time.Hour {
+					t.Fatalf("expected value %s but got %v", 7*24*time.Hour, right.Value)
+				}
+			},
+		},
+		{
+			name:    "now plus duration function",
+			filter:  `timestamp < now() + duration("1h")`,
+			checkFn: checkRightTime(now.Add(time.Hour)),
+		},
+		{
+			name:    "now minus duration function with days",
+			filter:  `timestamp > now() - duration("7d")`,
+			checkFn: checkRightTime(now.AddDate(0, 0, -7)),
+		},
+		{
+			name:    "now minus duration literal",
+			filter:  `timestamp < now() - 1h`,
+			checkFn: checkRightTime(now.Add(-time.Hour)),
+		},
+		{
+			name:   "now plus non duration function",
+			filter: `timestamp < now() + now()`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+		{
+			name:   "since non timestamp field",
+			filter: `time.Since(i64) > 15m`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+		{
+			name:   "since timestamp GT integer",
+			filter: `time.Since(timestamp) > 15`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+		{
+			name:   "invalid duration string",
+			filter: `duration < duration("15 minutes")`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+		{
+			name:   "duration string with mixed signs",
+			filter: `duration < duration("-1d-2h")`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+		{
+			name:   "duration string out of range",
+			filter: `duration < duration("106752d")`,
+			isErr:  true,
+			err:    filtering.ErrInvalidValue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it, err := filtering.NewInterpreter(msgDesc,
+				filtering.RegisterFunction(Duration()),
+				filtering.RegisterFunction(TimeSince()),
+				filtering.RelativeTimeOpt(func() time.Time { return now }),
+				filtering.ErrHandlerOpt(errHandler(t, tc.filter, tc.isErr)),
+			)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %s", err)
+			}
+
+			x, err := it.Parse(tc.filter)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+				if tc.err != nil && !errors.Is(err, tc.err) {
+					t.Fatalf("expected error %s but got %s", tc.err, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected no error but got %s", err)
+				}
+				defer x.Free()
+				tc.checkFn(t, x)
+			}
+		})
+	}
+}
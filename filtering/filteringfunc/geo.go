@@ -0,0 +1,312 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+const geoPkgName = "geo"
+
+// EarthRadius is the mean radius of the Earth in meters, used by the geo.Distance haversine formula.
+const EarthRadius = 6371008.8
+
+var latLngDesc = new(latlng.LatLng).ProtoReflect().Descriptor()
+
+// GeoPoint is a protofiltering function call declaration,
+// that creates a google.type.LatLng point of the latitude and longitude in degrees, i.e.: `geo.Point(52.23, 21.01)`.
+// The direct values result in the *latlng.LatLng ValueExpr, with the latitude within [-90, 90],
+// and the longitude within [-180, 180] range, whereas the field selectors result in the indirect geo.Point expr.FunctionCallExpr.
+func GeoPoint() *filtering.FunctionCallDeclaration {
+	return &geoPointFunc
+}
+
+var geoPointFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: geoPkgName,
+		Name:    "Point",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:  true,
+			ArgName:   "latitude",
+			FieldKind: protoreflect.DoubleKind,
+		},
+		{
+			Indirect:  true,
+			ArgName:   "longitude",
+			FieldKind: protoreflect.DoubleKind,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind:         protoreflect.MessageKind,
+		MessageDescriptor: latLngDesc,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 2 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for point function: %v", len(args))
+		}
+
+		var latV, lngV float64
+		lat, latDirect := args[0].(*expr.ValueExpr)
+		if latDirect {
+			var ok bool
+			if latV, ok = floatValue(lat.Value); !ok || latV < -90 || latV > 90 {
+				return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid latitude: %v", lat.Value)
+			}
+		}
+		lng, lngDirect := args[1].(*expr.ValueExpr)
+		if lngDirect {
+			var ok bool
+			if lngV, ok = floatValue(lng.Value); !ok || lngV < -180 || lngV > 180 {
+				return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid longitude: %v", lng.Value)
+			}
+		}
+		if !latDirect || !lngDirect {
+			return geoFunctionCall("Point", args...)
+		}
+
+		// The argument value expressions are no longer needed, reuse the first one as the result.
+		lng.Free()
+		lat.Value = &latlng.LatLng{Latitude: latV, Longitude: lngV}
+		return filtering.FunctionCallArgument{Expr: lat}, nil
+	},
+}
+
+// GeoDistance is a protofiltering function call declaration,
+// that computes the great-circle distance in meters between two google.type.LatLng points,
+// i.e.: `geo.Distance(location, geo.Point(52.23, 21.01)) < 1000`.
+// If both points are direct values, the distance is evaluated with the haversine formula into the float64 ValueExpr,
+// thus it could only be the compared value, i.e. `radius < geo.Distance(geo.Point(52.23, 21.01), geo.Point(50.06, 19.94))`,
+// as the restriction of the literals only doesn't depend on the filtered message and is rejected.
+// Otherwise it is the indirect geo.Distance expr.FunctionCallExpr, which needs to be evaluated by the service.
+func GeoDistance() *filtering.FunctionCallDeclaration {
+	return &geoDistanceFunc
+}
+
+var geoDistanceFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: geoPkgName,
+		Name:    "Distance",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:          true,
+			ArgName:           "from",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: latLngDesc,
+		},
+		{
+			Indirect:          true,
+			ArgName:           "to",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: latLngDesc,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind: protoreflect.DoubleKind,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 2 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for distance function: %v", len(args))
+		}
+
+		pts, direct, err := latLngArgs(args)
+		if err != nil {
+			return filtering.FunctionCallArgument{}, err
+		}
+		if !direct {
+			return geoFunctionCall("Distance", args...)
+		}
+
+		args[1].Free()
+		ve := args[0].(*expr.ValueExpr)
+		ve.Value = Haversine(pts[0], pts[1])
+		return filtering.FunctionCallArgument{Expr: ve}, nil
+	},
+}
+
+// GeoInBBox is a protofiltering function call declaration,
+// that checks if the google.type.LatLng point is within the bounding box of its south-west and north-east corners,
+// i.e.: `geo.InBBox(location, geo.Point(52.1, 20.8), geo.Point(52.4, 21.3))`.
+// The box, which west longitude is greater than its east longitude, crosses the antimeridian.
+// If all the points are direct values, the result is evaluated into the bool ValueExpr, which could only be the compared value,
+// otherwise it is the indirect geo.InBBox expr.FunctionCallExpr, which needs to be evaluated by the service.
+func GeoInBBox() *filtering.FunctionCallDeclaration {
+	return &geoInBBoxFunc
+}
+
+var geoInBBoxFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: geoPkgName,
+		Name:    "InBBox",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:          true,
+			ArgName:           "point",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: latLngDesc,
+		},
+		{
+			Indirect:          true,
+			ArgName:           "south_west",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: latLngDesc,
+		},
+		{
+			Indirect:          true,
+			ArgName:           "north_east",
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: latLngDesc,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind: protoreflect.BoolKind,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 3 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for in bbox function: %v", len(args))
+		}
+
+		pts, direct, err := latLngArgs(args)
+		if err != nil {
+			return filtering.FunctionCallArgument{}, err
+		}
+		if pts[1] != nil && pts[2] != nil && pts[1].Latitude > pts[2].Latitude {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("south west latitude %v is greater than the north east latitude %v", pts[1].Latitude, pts[2].Latitude)
+		}
+		if !direct {
+			return geoFunctionCall("InBBox", args...)
+		}
+
+		args[1].Free()
+		args[2].Free()
+		ve := args[0].(*expr.ValueExpr)
+		ve.Value = InBBox(pts[0], pts[1], pts[2])
+		return filtering.FunctionCallArgument{Expr: ve}, nil
+	},
+}
+
+// Haversine returns the great-circle distance in meters between the points a and b on the sphere of the EarthRadius.
+func Haversine(a, b *latlng.LatLng) float64 {
+	const rad = math.Pi / 180
+	lat1, lat2 := a.GetLatitude()*rad, b.GetLatitude()*rad
+	dLat := lat2 - lat1
+	dLng := (b.GetLongitude() - a.GetLongitude()) * rad
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InBBox checks if the point p is within the bounding box of the south-west sw and north-east ne corners.
+func InBBox(p, sw, ne *latlng.LatLng) bool {
+	if p.GetLatitude() < sw.GetLatitude() || p.GetLatitude() > ne.GetLatitude() {
+		return false
+	}
+	if sw.GetLongitude() <= ne.GetLongitude() {
+		return p.GetLongitude() >= sw.GetLongitude() && p.GetLongitude() <= ne.GetLongitude()
+	}
+	// The box crosses the antimeridian.
+	return p.GetLongitude() >= sw.GetLongitude() || p.GetLongitude() <= ne.GetLongitude()
+}
+
+// geoFunctionCall results in the indirect geo function call of the given name and arguments.
+func geoFunctionCall(name string, args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *expr.ValueExpr, *expr.FieldSelectorExpr, *expr.MapKeyExpr, *expr.FunctionCallExpr:
+		default:
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid %s.%s argument: %T", geoPkgName, name, arg)
+		}
+	}
+
+	fc := expr.AcquireFunctionCallExpr()
+	fc.PkgName = geoPkgName
+	fc.Name = name
+	fc.Arguments = append(fc.Arguments, args...)
+	fc.CallComplexity = 1
+	return filtering.FunctionCallArgument{
+		Expr:       fc,
+		IsIndirect: true,
+	}, nil
+}
+
+// latLngArgs returns the points of the direct arguments, and reports whether all the arguments are direct.
+// The points of the indirect arguments are nil.
+func latLngArgs(args []expr.FilterExpr) ([]*latlng.LatLng, bool, error) {
+	pts := make([]*latlng.LatLng, len(args))
+	direct := true
+	for i, arg := range args {
+		ve, ok := arg.(*expr.ValueExpr)
+		if !ok {
+			direct = false
+			continue
+		}
+		pt, ok := latLngValue(ve.Value)
+		if !ok {
+			return nil, false, fmt.Errorf("input value is not a valid google.type.LatLng value expression: %T", ve.Value)
+		}
+		pts[i] = pt
+	}
+	return pts, direct, nil
+}
+
+// latLngValue converts the value of the google.type.LatLng message into the *latlng.LatLng.
+func latLngValue(v any) (*latlng.LatLng, bool) {
+	var m protoreflect.Message
+	switch vt := v.(type) {
+	case *latlng.LatLng:
+		return vt, vt != nil
+	case protoreflect.Message:
+		m = vt
+	case proto.Message:
+		m = vt.ProtoReflect()
+	default:
+		return nil, false
+	}
+	if m.Descriptor().FullName() != latLngDesc.FullName() {
+		return nil, false
+	}
+	fields := m.Descriptor().Fields()
+	return &latlng.LatLng{
+		Latitude:  m.Get(fields.ByName("latitude")).Float(),
+		Longitude: m.Get(fields.ByName("longitude")).Float(),
+	}, true
+}
+
+// floatValue converts the numeric value into the float64.
+func floatValue(v any) (float64, bool) {
+	switch vt := v.(type) {
+	case float64:
+		return vt, true
+	case int64:
+		return float64(vt), true
+	case uint64:
+		return float64(vt), true
+	}
+	return 0, false
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"math"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+func TestGeoFunctionCalls(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  string
+		isErr   bool
+		checkFn func(t *testing.T, x expr.FilterExpr)
+	}{
+		{
+			name:   "distance direct",
+			filter: `double < geo.Distance(geo.Point(52.2297, 21.0122), geo.Point(50.0647, 19.945))`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				ve, ok := ce.Right.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", ce.Right)
+				}
+				d, ok := ve.Value.(float64)
+				if !ok {
+					t.Fatalf("expected float64 value but got %T", ve.Value)
+				}
+				// The distance between Warsaw and Krakow is about 252 km.
+				if math.Abs(d-252_000) > 2_000 {
+					t.Fatalf("expected distance of about 252km but got %fm", d)
+				}
+			},
+		},
+		{
+			name:   "distance indirect",
+			filter: `geo.Distance(geo.Point(double, double), geo.Point(52.2297, 21.0122)) < 1000`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				fc, ok := ce.Left.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", ce.Left)
+				}
+				if fc.PkgName != "geo" || fc.Name != "Distance" || len(fc.Arguments) != 2 {
+					t.Fatalf("expected geo.Distance function call with 2 arguments but got %s.%s", fc.PkgName, fc.Name)
+				}
+				if _, ok = fc.Arguments[0].(*expr.FunctionCallExpr); !ok {
+					t.Fatalf("expected indirect geo.Point argument but got %T", fc.Arguments[0])
+				}
+				ve, ok := fc.Arguments[1].(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value argument but got %T", fc.Arguments[1])
+				}
+				if _, ok = ve.Value.(*latlng.LatLng); !ok {
+					t.Fatalf("expected LatLng value but got %T", ve.Value)
+				}
+			},
+		},
+		{
+			name:    "in bbox direct",
+			filter:  `bool = geo.InBBox(geo.Point(52.2297, 21.0122), geo.Point(52, 20.5), geo.Point(52.5, 21.5))`,
+			checkFn: checkRightValue(true),
+		},
+		{
+			name:    "in bbox across antimeridian",
+			filter:  `bool = geo.InBBox(geo.Point(0, -179.5), geo.Point(-1, 179), geo.Point(1, -179))`,
+			checkFn: checkRightValue(true),
+		},
+		{
+			name:    "outside bbox",
+			filter:  `bool = geo.InBBox(geo.Point(50.0647, 19.945), geo.Point(52, 20.5), geo.Point(52.5, 21.5))`,
+			checkFn: checkRightValue(false),
+		},
+		{
+			name:   "in bbox indirect",
+			filter: `geo.InBBox(geo.Point(double, double), geo.Point(52, 20.5), geo.Point(52.5, 21.5))`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				fc, ok := x.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", x)
+				}
+				if fc.PkgName != "geo" || fc.Name != "InBBox" || len(fc.Arguments) != 3 {
+					t.Fatalf("expected geo.InBBox function call with 3 arguments but got %s.%s", fc.PkgName, fc.Name)
+				}
+			},
+		},
+		{
+			name:   "invalid latitude",
+			filter: `double < geo.Distance(geo.Point(91, 0), geo.Point(0, 0))`,
+			isErr:  true,
+		},
+		{
+			name:   "invalid longitude of indirect point",
+			filter: `geo.Distance(geo.Point(double, 181), geo.Point(0, 0)) < 10`,
+			isErr:  true,
+		},
+		{
+			name:   "distance of literals only",
+			filter: `geo.Distance(geo.Point(52.2297, 21.0122), geo.Point(50.0647, 19.945)) < 10`,
+			isErr:  true,
+		},
+		{
+			name:   "in bbox of literals only",
+			filter: `geo.InBBox(geo.Point(52.2297, 21.0122), geo.Point(52, 20.5), geo.Point(52.5, 21.5))`,
+			isErr:  true,
+		},
+		{
+			name:   "inverted bbox",
+			filter: `geo.InBBox(geo.Point(double, double), geo.Point(53, 20.5), geo.Point(52, 21.5))`,
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it, err := filtering.NewInterpreter(msgDesc,
+				filtering.RegisterFunction(GeoPoint()),
+				filtering.RegisterFunction(GeoDistance()),
+				filtering.RegisterFunction(GeoInBBox()),
+				filtering.ErrHandlerOpt(errHandler(t, tc.filter, tc.isErr)),
+			)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %s", err)
+			}
+
+			x, err := it.Parse(tc.filter)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got %s", err)
+			}
+			defer x.Free()
+			tc.checkFn(t, x)
+		})
+	}
+}
+
+func TestHaversine(t *testing.T) {
+	a := &latlng.LatLng{Latitude: 0, Longitude: 0}
+	b := &latlng.LatLng{Latitude: 0, Longitude: 180}
+	if d, want := Haversine(a, b), math.Pi*EarthRadius; math.Abs(d-want) > 1e-6 {
+		t.Errorf("expected half of the circumference %f but got %f", want, d)
+	}
+	if d := Haversine(a, a); d != 0 {
+		t.Errorf("expected zero distance but got %f", d)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"fmt"
+	"net/netip"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+const netPkgName = "net"
+
+// NetIP is a protofiltering function call declaration,
+// that validates and normalizes the input IPv4 or IPv6 address string, i.e.: `ip = net.IP("2001:DB8::1")`.
+// A direct value results in the string ValueExpr of the address in its canonical form, i.e. "2001:db8::1",
+// whereas a field selector results in the indirect net.IP expr.FunctionCallExpr.
+func NetIP() *filtering.FunctionCallDeclaration {
+	return &netIPFunc
+}
+
+var netIPFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: netPkgName,
+		Name:    "IP",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:  true,
+			ArgName:   "ip",
+			FieldKind: protoreflect.StringKind,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind: protoreflect.StringKind,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 1 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for ip function: %v", len(args))
+		}
+		return callNetFunc("IP", args, func(ve *expr.ValueExpr) (any, error) {
+			addr, err := netAddrValue(ve)
+			if err != nil {
+				return nil, err
+			}
+			return addr.String(), nil
+		})
+	},
+}
+
+// NetCIDR is a protofiltering function call declaration,
+// that validates and normalizes the input CIDR notation of the IP range, i.e.: `subnet = net.CIDR("10.1.0.0/16")`.
+// A direct value results in the string ValueExpr of the range in its canonical form, with the host bits cleared,
+// i.e. "10.1.2.3/16" becomes "10.1.0.0/16", whereas a field selector results in the indirect net.CIDR expr.FunctionCallExpr.
+func NetCIDR() *filtering.FunctionCallDeclaration {
+	return &netCIDRFunc
+}
+
+var netCIDRFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: netPkgName,
+		Name:    "CIDR",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:  true,
+			ArgName:   "cidr",
+			FieldKind: protoreflect.StringKind,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind: protoreflect.StringKind,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 1 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for cidr function: %v", len(args))
+		}
+		return callNetFunc("CIDR", args, func(ve *expr.ValueExpr) (any, error) {
+			p, err := netPrefixValue(ve)
+			if err != nil {
+				return nil, err
+			}
+			return p.String(), nil
+		})
+	},
+}
+
+// NetContains is a protofiltering function call declaration,
+// that checks if the IP range in the CIDR notation contains the IP address, i.e.: `net.Contains("10.0.0.0/8", ip)`.
+// If both arguments are direct values, the result is evaluated into the bool ValueExpr, thus it could only be
+// the compared value, i.e. `bool = net.Contains("10.0.0.0/8", "10.1.2.3")`, as the restriction of the literals only
+// doesn't depend on the filtered message and is rejected.
+// Otherwise it is the indirect net.Contains expr.FunctionCallExpr, which needs to be evaluated by the service.
+// The direct arguments are validated and normalized in both cases.
+func NetContains() *filtering.FunctionCallDeclaration {
+	return &netContainsFunc
+}
+
+var netContainsFunc = filtering.FunctionCallDeclaration{
+	Name: filtering.FunctionName{
+		PkgName: netPkgName,
+		Name:    "Contains",
+	},
+	Arguments: []*filtering.FunctionCallArgumentDeclaration{
+		{
+			Indirect:  true,
+			ArgName:   "cidr",
+			FieldKind: protoreflect.StringKind,
+		},
+		{
+			Indirect:  true,
+			ArgName:   "ip",
+			FieldKind: protoreflect.StringKind,
+		},
+	},
+	Returning: &filtering.FunctionCallReturningDeclaration{
+		FieldKind: protoreflect.BoolKind,
+	},
+	CallFn: func(args ...expr.FilterExpr) (filtering.FunctionCallArgument, error) {
+		if len(args) != 2 {
+			// This is internal error.
+			return filtering.FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for contains function: %v", len(args))
+		}
+
+		pv, pDirect := args[0].(*expr.ValueExpr)
+		av, aDirect := args[1].(*expr.ValueExpr)
+
+		var (
+			p    netip.Prefix
+			addr netip.Addr
+			err  error
+		)
+		if pDirect {
+			if p, err = netPrefixValue(pv); err != nil {
+				return filtering.FunctionCallArgument{}, err
+			}
+		} else if !isIndirectArg(args[0]) {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid cidr value expression: %T", args[0])
+		}
+		if aDirect {
+			if addr, err = netAddrValue(av); err != nil {
+				return filtering.FunctionCallArgument{}, err
+			}
+		} else if !isIndirectArg(args[1]) {
+			return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid ip value expression: %T", args[1])
+		}
+
+		if pDirect && aDirect {
+			// The argument value expressions are no longer needed, reuse the first one as the result.
+			av.Free()
+			pv.Value = p.Contains(addr)
+			return filtering.FunctionCallArgument{Expr: pv}, nil
+		}
+
+		if pDirect {
+			pv.Value = p.String()
+		}
+		if aDirect {
+			av.Value = addr.String()
+		}
+		fc := expr.AcquireFunctionCallExpr()
+		fc.PkgName = netPkgName
+		fc.Name = "Contains"
+		fc.Arguments = append(fc.Arguments, args...)
+		fc.CallComplexity = 1
+		return filtering.FunctionCallArgument{
+			Expr:       fc,
+			IsIndirect: true,
+		}, nil
+	},
+}
+
+// callNetFunc calls the single argument net function, which either normalizes the direct value with the fn,
+// or results in the indirect expr.FunctionCallExpr of the field selector.
+func callNetFunc(name string, args []expr.FilterExpr, fn func(ve *expr.ValueExpr) (any, error)) (filtering.FunctionCallArgument, error) {
+	if ve, ok := args[0].(*expr.ValueExpr); ok {
+		v, err := fn(ve)
+		if err != nil {
+			return filtering.FunctionCallArgument{}, err
+		}
+		ve.Value = v
+		return filtering.FunctionCallArgument{Expr: ve}, nil
+	}
+	if !isIndirectArg(args[0]) {
+		return filtering.FunctionCallArgument{}, fmt.Errorf("input value is not a valid string value expression: %T", args[0])
+	}
+
+	fc := expr.AcquireFunctionCallExpr()
+	fc.PkgName = netPkgName
+	fc.Name = name
+	fc.Arguments = append(fc.Arguments, args[0])
+	fc.CallComplexity = 1
+	return filtering.FunctionCallArgument{
+		Expr:       fc,
+		IsIndirect: true,
+	}, nil
+}
+
+// isIndirectArg checks if the argument is a field selector or a function call.
+func isIndirectArg(x expr.FilterExpr) bool {
+	switch x.(type) {
+	case *expr.FieldSelectorExpr, *expr.MapKeyExpr, *expr.FunctionCallExpr:
+		return true
+	}
+	return false
+}
+
+// netAddrValue parses the IP address of the string value expression.
+func netAddrValue(ve *expr.ValueExpr) (netip.Addr, error) {
+	s, ok := ve.Value.(string)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("input value is not a valid string value expression: %T", ve.Value)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("input value is not a valid ip address: %w", err)
+	}
+	return addr, nil
+}
+
+// netPrefixValue parses the masked CIDR range of the string value expression.
+func netPrefixValue(ve *expr.ValueExpr) (netip.Prefix, error) {
+	s, ok := ve.Value.(string)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("input value is not a valid string value expression: %T", ve.Value)
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("input value is not a valid cidr: %w", err)
+	}
+	return p.Masked(), nil
+}
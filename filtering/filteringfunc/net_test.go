@@ -0,0 +1,160 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringfunc
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+func TestNetFunctionCalls(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  string
+		isErr   bool
+		checkFn func(t *testing.T, x expr.FilterExpr)
+	}{
+		{
+			name:    "ip direct normalized",
+			filter:  `str = net.IP("2001:DB8:0:0::1")`,
+			checkFn: checkRightValue("2001:db8::1"),
+		},
+		{
+			name:    "cidr direct masked",
+			filter:  `str = net.CIDR("10.1.2.3/16")`,
+			checkFn: checkRightValue("10.1.0.0/16"),
+		},
+		{
+			name:    "contains direct",
+			filter:  `bool = net.Contains("10.0.0.0/8", "10.1.2.3")`,
+			checkFn: checkRightValue(true),
+		},
+		{
+			name:    "contains direct outside",
+			filter:  `bool = net.Contains("10.0.0.0/8", "192.168.0.1")`,
+			checkFn: checkRightValue(false),
+		},
+		{
+			name:   "contains indirect",
+			filter: `net.Contains("10.1.2.3/8", str)`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				fc, ok := x.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", x)
+				}
+				if fc.PkgName != "net" || fc.Name != "Contains" {
+					t.Fatalf("expected net.Contains function call but got %s.%s", fc.PkgName, fc.Name)
+				}
+				if len(fc.Arguments) != 2 {
+					t.Fatalf("expected 2 arguments but got %d", len(fc.Arguments))
+				}
+				cidr, ok := fc.Arguments[0].(*expr.ValueExpr)
+				if !ok || cidr.Value != "10.0.0.0/8" {
+					t.Fatalf("expected normalized cidr argument but got %v", fc.Arguments[0])
+				}
+				if _, ok = fc.Arguments[1].(*expr.FieldSelectorExpr); !ok {
+					t.Fatalf("expected field selector argument but got %T", fc.Arguments[1])
+				}
+			},
+		},
+		{
+			name:   "ip indirect",
+			filter: `name = net.IP(str)`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				fc, ok := ce.Right.(*expr.FunctionCallExpr)
+				if !ok {
+					t.Fatalf("expected function call expression but got %T", ce.Right)
+				}
+				if fc.PkgName != "net" || fc.Name != "IP" {
+					t.Fatalf("expected net.IP function call but got %s.%s", fc.PkgName, fc.Name)
+				}
+			},
+		},
+		{
+			name:   "invalid ip",
+			filter: `str = net.IP("10.0.0.256")`,
+			isErr:  true,
+		},
+		{
+			name:   "invalid cidr",
+			filter: `net.Contains("10.0.0.0/33", str)`,
+			isErr:  true,
+		},
+		{
+			name:    "contains of nested literals",
+			filter:  `bool = net.Contains(net.CIDR("10.1.2.3/8"), net.IP("10.1.1.1"))`,
+			checkFn: checkRightValue(true),
+		},
+		{
+			name:   "contains of literals only",
+			filter: `net.Contains("10.0.0.0/8", "10.1.2.3")`,
+			isErr:  true,
+		},
+		{
+			name:   "contains of nested literals only",
+			filter: `net.Contains(net.CIDR("10.0.0.0/8"), net.IP("10.1.1.1"))`,
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			it, err := filtering.NewInterpreter(msgDesc,
+				filtering.RegisterFunction(NetIP()),
+				filtering.RegisterFunction(NetCIDR()),
+				filtering.RegisterFunction(NetContains()),
+				filtering.ErrHandlerOpt(errHandler(t, tc.filter, tc.isErr)),
+			)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %s", err)
+			}
+
+			x, err := it.Parse(tc.filter)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got %s", err)
+			}
+			defer x.Free()
+			tc.checkFn(t, x)
+		})
+	}
+}
+
+func checkRightValue(want any) func(t *testing.T, x expr.FilterExpr) {
+	return func(t *testing.T, x expr.FilterExpr) {
+		ce, ok := x.(*expr.CompareExpr)
+		if !ok {
+			t.Fatalf("expected compare expression but got %T", x)
+		}
+		ve, ok := ce.Right.(*expr.ValueExpr)
+		if !ok {
+			t.Fatalf("expected value expression but got %T", ce.Right)
+		}
+		if ve.Value != want {
+			t.Fatalf("expected value %v but got %v", want, ve.Value)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filteringlog provides the structured logging of the filtering interpreter parses with the log/slog.
+// Each parse is logged with the redacted filter, its complexity, duration and outcome.
+// The zap users could pass the *slog.Logger backed by the zap core, i.e. with the go.uber.org/zap/exp/zapslog handler.
+package filteringlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+// The outcomes of the parse, logged as the "outcome" attribute.
+const (
+	OutcomeOK            = "ok"
+	OutcomeInvalid       = "invalid"
+	OutcomeLimitExceeded = "limit_exceeded"
+	OutcomeError         = "error"
+)
+
+// DefaultMessage is the default message of the parse log records.
+const DefaultMessage = "filter parsed"
+
+// RedactFn is a function that redacts the filter before it is logged.
+type RedactFn func(filter string) string
+
+// Option is an option of the Logger.
+type Option func(*Logger) error
+
+// SuccessLevelOpt is an option that sets the level of the successful parses, slog.LevelDebug by default.
+func SuccessLevelOpt(level slog.Level) Option {
+	return func(l *Logger) error {
+		l.successLevel = level
+		return nil
+	}
+}
+
+// FailureLevelOpt is an option that sets the level of the parses rejected due to the invalid filter,
+// or the exceeded limits, slog.LevelInfo by default.
+// The internal errors are always logged with the slog.LevelError.
+func FailureLevelOpt(level slog.Level) Option {
+	return func(l *Logger) error {
+		l.failureLevel = level
+		return nil
+	}
+}
+
+// RedactOpt is an option that sets the function that redacts the filter, filtering.RedactFilter by default.
+// The filter is logged as is when the function returns its input.
+func RedactOpt(fn RedactFn) Option {
+	return func(l *Logger) error {
+		if fn == nil {
+			return errors.New("redact function is nil")
+		}
+		l.redact = fn
+		return nil
+	}
+}
+
+// MessageOpt is an option that sets the message of the log records, DefaultMessage by default.
+func MessageOpt(msg string) Option {
+	return func(l *Logger) error {
+		if msg == "" {
+			return errors.New("message is empty")
+		}
+		l.msg = msg
+		return nil
+	}
+}
+
+// Logger logs the parses of the filtering interpreters.
+// A Logger is safe for concurrent use, and could be shared across the interpreters.
+type Logger struct {
+	logger       *slog.Logger
+	successLevel slog.Level
+	failureLevel slog.Level
+	redact       RedactFn
+	msg          string
+}
+
+// New creates a new Logger writing into the logger.
+func New(logger *slog.Logger, opts ...Option) (*Logger, error) {
+	if logger == nil {
+		return nil, errors.New("logger is not set")
+	}
+	l := Logger{
+		logger:       logger,
+		successLevel: slog.LevelDebug,
+		failureLevel: slog.LevelInfo,
+		redact:       filtering.RedactFilter,
+		msg:          DefaultMessage,
+	}
+	for _, opt := range opts {
+		if err := opt(&l); err != nil {
+			return nil, err
+		}
+	}
+	return &l, nil
+}
+
+// Opt returns the interpreter option that logs each of its parses, i.e.:
+//
+//	filtering.NewInterpreter(md, l.Opt())
+func (l *Logger) Opt() filtering.Option {
+	return filtering.ParseHookOpt(l.LogParse)
+}
+
+// LogParse logs a single parse of the interpreter.
+// It implements the filtering.ParseHookFn.
+func (l *Logger) LogParse(info filtering.ParseInfo) {
+	level, outcome := l.successLevel, OutcomeOK
+	switch {
+	case info.Err == nil:
+	case errors.Is(info.Err, filtering.ErrLimitExceeded):
+		level, outcome = l.failureLevel, OutcomeLimitExceeded
+	case errors.Is(info.Err, filtering.ErrInternal):
+		level, outcome = slog.LevelError, OutcomeError
+	default:
+		level, outcome = l.failureLevel, OutcomeInvalid
+	}
+
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 8)
+	attrs = append(attrs,
+		slog.String("message", string(info.Message)),
+		slog.String("filter", l.redact(info.Filter)),
+		slog.Int64("complexity", info.Complexity),
+		slog.Duration("duration", info.Duration),
+		slog.String("outcome", outcome),
+	)
+	if info.Err != nil {
+		var fe *filtering.FilterError
+		if errors.As(info.Err, &fe) {
+			attrs = append(attrs, slog.String("error_code", fe.Code.String()), slog.Int("error_position", int(fe.Pos)))
+		}
+		attrs = append(attrs, slog.String("error", info.Err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, l.msg, attrs...)
+}
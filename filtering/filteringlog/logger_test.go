@@ -0,0 +1,142 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestLogger_LogParse(t *testing.T) {
+	tc := []struct {
+		name    string
+		filter  string
+		opts    []Option
+		want    map[string]any
+		noEntry bool
+	}{
+		{
+			name:   "success",
+			filter: `str = "secret" AND i32 > 5`,
+			want: map[string]any{
+				"level":   "DEBUG",
+				"msg":     DefaultMessage,
+				"message": "testpb.Message",
+				"filter":  `str = "***" AND i32 > ***`,
+				"outcome": OutcomeOK,
+			},
+		},
+		{
+			name:   "invalid",
+			filter: `i32 = "foo"`,
+			want: map[string]any{
+				"level":      "INFO",
+				"filter":     `i32 = "***"`,
+				"outcome":    OutcomeInvalid,
+				"error_code": "INVALID_VALUE",
+			},
+		},
+		{
+			name:   "limit exceeded",
+			filter: `str = "a" OR str = "b" OR str = "c" OR str = "d"`,
+			opts:   []Option{FailureLevelOpt(slog.LevelWarn)},
+			want: map[string]any{
+				"level":      "WARN",
+				"outcome":    OutcomeLimitExceeded,
+				"error_code": "LIMIT_EXCEEDED",
+			},
+		},
+		{
+			name:   "no redaction",
+			filter: `str = "foo"`,
+			opts: []Option{
+				SuccessLevelOpt(slog.LevelInfo),
+				MessageOpt("filter"),
+				RedactOpt(func(filter string) string { return filter }),
+			},
+			want: map[string]any{
+				"level":  "INFO",
+				"msg":    "filter",
+				"filter": `str = "foo"`,
+			},
+		},
+		{
+			name:    "disabled level",
+			filter:  `str = "foo"`,
+			opts:    []Option{SuccessLevelOpt(slog.LevelDebug - 1)},
+			noEntry: true,
+		},
+	}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := New(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create logger: %v", err)
+			}
+
+			i, err := filtering.NewInterpreter(md, l.Opt(), filtering.MaxFilterLengthOpt(40))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+			x, _ := i.Parse(tt.filter)
+			if x != nil {
+				x.Free()
+			}
+
+			if tt.noEntry {
+				if buf.Len() != 0 {
+					t.Errorf("expected no log entry but got: %s", buf.String())
+				}
+				return
+			}
+
+			var got map[string]any
+			if err = json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode log entry %q: %v", buf.String(), err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("expected %s to be %v but got %v", k, v, got[k])
+				}
+			}
+			for _, k := range []string{"complexity", "duration"} {
+				if _, ok := got[k]; !ok {
+					t.Errorf("expected %s attribute in the log entry", k)
+				}
+			}
+			if strings.Contains(buf.String(), "secret") {
+				t.Errorf("expected the filter values to be redacted: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for nil logger")
+	}
+	if _, err := New(slog.Default(), RedactOpt(nil)); err == nil {
+		t.Error("expected error for nil redact function")
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filteringquota provides the admission control of the filters, based on the filter complexity quota per caller.
+// Each caller, identified by the key extracted from the context, has a budget of the aggregate complexity
+// of the filters parsed within a sliding time window. The filters exceeding the remaining budget are rejected,
+// so that the expensive ad-hoc querying of a single caller doesn't degrade the service for the others.
+package filteringquota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+// ErrQuotaExceeded is the error of the filters rejected due to the exceeded quota of the caller.
+// It wraps the filtering.ErrLimitExceeded.
+var ErrQuotaExceeded = fmt.Errorf("%w: filter quota exceeded", filtering.ErrLimitExceeded)
+
+// KeyFn extracts the key of the caller from the context.
+// It returns false if the context has no caller.
+type KeyFn func(ctx context.Context) (string, bool)
+
+// Option is an option of the Quota.
+type Option func(*Quota) error
+
+// ClockOpt is an option that sets the clock of the sliding window, time.Now by default.
+func ClockOpt(fn func() time.Time) Option {
+	return func(q *Quota) error {
+		if fn == nil {
+			return errors.New("clock function is nil")
+		}
+		q.clock = fn
+		return nil
+	}
+}
+
+// AnonymousKeyOpt is an option that sets the key of the callers not identified by the KeyFn.
+// By default, all such callers share the empty key and its budget.
+func AnonymousKeyOpt(key string) Option {
+	return func(q *Quota) error {
+		q.anonymousKey = key
+		return nil
+	}
+}
+
+// CostModelOpt is an option that charges the cost of the filters estimated with the cost model m,
+// see the expr.EstimateCost, instead of their complexity. The budget is then expressed in the cost units.
+func CostModelOpt(m expr.CostModel) Option {
+	return func(q *Quota) error {
+		q.costModel = &m
+		return nil
+	}
+}
+
+// Quota tracks the aggregate complexity of the filters parsed by each caller within a sliding time window.
+// A Quota is safe for concurrent use, and could be shared across the interpreters.
+type Quota struct {
+	budget       int64
+	window       time.Duration
+	key          KeyFn
+	clock        func() time.Time
+	anonymousKey string
+	costModel    *expr.CostModel
+
+	mu        sync.Mutex
+	callers   map[string]*usage
+	lastSweep time.Time
+}
+
+// usage is the complexity of the filters admitted for a single caller within the window.
+type usage struct {
+	events []event
+	total  int64
+}
+
+// event is a single admitted filter.
+type event struct {
+	at         time.Time
+	complexity int64
+}
+
+// New creates a new Quota with the complexity budget of each caller within the window.
+func New(budget int64, window time.Duration, key KeyFn, opts ...Option) (*Quota, error) {
+	if budget <= 0 {
+		return nil, errors.New("quota budget must be positive")
+	}
+	if window <= 0 {
+		return nil, errors.New("quota window must be positive")
+	}
+	if key == nil {
+		return nil, errors.New("key function is nil")
+	}
+	q := Quota{
+		budget:  budget,
+		window:  window,
+		key:     key,
+		clock:   time.Now,
+		callers: make(map[string]*usage),
+	}
+	for _, opt := range opts {
+		if err := opt(&q); err != nil {
+			return nil, err
+		}
+	}
+	return &q, nil
+}
+
+// Parse parses the filter with the interpreter on behalf of the caller of the ctx, and admits its complexity.
+// If the quota of the caller is exceeded, the parsed expression is freed and the *QuotaError is returned.
+func (q *Quota) Parse(ctx context.Context, i *filtering.Interpreter, filter string, opts ...filtering.ParseOption) (expr.FilterExpr, error) {
+	x, err := i.Parse(filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = q.Admit(ctx, x); err != nil {
+		if x != nil {
+			x.Free()
+		}
+		return nil, err
+	}
+	return x, nil
+}
+
+// Admit charges the complexity of the parsed x expression to the quota of the caller of the ctx.
+// It could be used for the expressions parsed in other ways than the Parse, i.e. with the ParseWithSearch.
+// If the complexity exceeds the remaining budget of the caller, nothing is charged and the *QuotaError is returned.
+// The nil expression is always admitted.
+func (q *Quota) Admit(ctx context.Context, x expr.FilterExpr) error {
+	if x == nil {
+		return nil
+	}
+	key := q.callerKey(ctx)
+	complexity := q.complexity(x)
+
+	now := q.clock()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sweep(now)
+	u := q.callers[key]
+	if u == nil {
+		u = &usage{}
+		q.callers[key] = u
+	}
+	u.expire(now.Add(-q.window))
+
+	if u.total+complexity > q.budget {
+		return &QuotaError{
+			Caller:     key,
+			Used:       u.total,
+			Complexity: complexity,
+			Budget:     q.budget,
+			RetryAfter: q.retryAfter(u, complexity, now),
+		}
+	}
+	u.events = append(u.events, event{at: now, complexity: complexity})
+	u.total += complexity
+	return nil
+}
+
+// complexity returns the complexity of the expression charged to the quota.
+func (q *Quota) complexity(x expr.FilterExpr) int64 {
+	if q.costModel != nil {
+		return expr.EstimateCost(x, *q.costModel)
+	}
+	return x.Complexity()
+}
+
+// Usage returns the complexity of the filters admitted for the caller of the ctx within the current window.
+func (q *Quota) Usage(ctx context.Context) int64 {
+	key := q.callerKey(ctx)
+	now := q.clock()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u := q.callers[key]
+	if u == nil {
+		return 0
+	}
+	u.expire(now.Add(-q.window))
+	return u.total
+}
+
+func (q *Quota) callerKey(ctx context.Context) string {
+	if key, ok := q.key(ctx); ok {
+		return key
+	}
+	return q.anonymousKey
+}
+
+// sweep removes the callers without any event within the window, at most once per window.
+func (q *Quota) sweep(now time.Time) {
+	if now.Sub(q.lastSweep) < q.window {
+		return
+	}
+	q.lastSweep = now
+	since := now.Add(-q.window)
+	for key, u := range q.callers {
+		if u.expire(since); len(u.events) == 0 {
+			delete(q.callers, key)
+		}
+	}
+}
+
+// retryAfter returns the time after which the complexity fits the budget of the u caller,
+// or zero if it never fits.
+func (q *Quota) retryAfter(u *usage, complexity int64, now time.Time) time.Duration {
+	if complexity > q.budget {
+		return 0
+	}
+	total := u.total
+	for _, ev := range u.events {
+		total -= ev.complexity
+		if total+complexity <= q.budget {
+			return ev.at.Add(q.window).Sub(now)
+		}
+	}
+	return 0
+}
+
+// expire removes the events that happened before the since time.
+func (u *usage) expire(since time.Time) {
+	var n int
+	for n < len(u.events) && !u.events[n].at.After(since) {
+		u.total -= u.events[n].complexity
+		n++
+	}
+	if n > 0 {
+		u.events = append(u.events[:0], u.events[n:]...)
+	}
+}
+
+// QuotaError is an error returned for the filters rejected due to the exceeded quota of the caller.
+// It wraps the ErrQuotaExceeded.
+type QuotaError struct {
+	// Caller is the key of the caller.
+	Caller string
+
+	// Used is the complexity already used by the caller within the window.
+	Used int64
+
+	// Complexity is the complexity of the rejected filter.
+	Complexity int64
+
+	// Budget is the complexity budget of the caller within the window.
+	Budget int64
+
+	// RetryAfter is the time after which the filter would fit the budget, zero if it never fits.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: filter complexity %d exceeds the remaining budget %d of %d", ErrQuotaExceeded, e.Complexity, e.Budget-e.Used, e.Budget)
+}
+
+// Unwrap returns the ErrQuotaExceeded.
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// GRPCStatus returns the gRPC status representation of the error.
+// The error is returned with the codes.ResourceExhausted code and the errdetails.QuotaFailure details,
+// along with the errdetails.RetryInfo if the filter could be retried.
+func (e *QuotaError) GRPCStatus() *status.Status {
+	st := status.New(codes.ResourceExhausted, e.Error())
+	qf := &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: e.Caller, Description: e.Error()},
+		},
+	}
+	var (
+		ds  *status.Status
+		err error
+	)
+	if e.RetryAfter > 0 {
+		ds, err = st.WithDetails(qf, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	} else {
+		ds, err = st.WithDetails(qf)
+	}
+	if err != nil {
+		return st
+	}
+	return ds
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringquota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+type callerKey struct{}
+
+func withCaller(caller string) context.Context {
+	return context.WithValue(context.Background(), callerKey{}, caller)
+}
+
+func testKey(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey{}).(string)
+	return caller, ok
+}
+
+func TestQuota_Parse(t *testing.T) {
+	i, err := filtering.NewInterpreter(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	const filter = `i32 = 1`
+	x, err := i.Parse(filter)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	complexity := x.Complexity()
+	x.Free()
+
+	// The budget fits three filters within the window.
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	q, err := New(3*complexity, time.Minute, testKey, ClockOpt(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to create quota: %v", err)
+	}
+
+	alice, bob := withCaller("alice"), withCaller("bob")
+	for j := 0; j < 3; j++ {
+		x, err = q.Parse(alice, i, filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+		now = now.Add(10 * time.Second)
+	}
+
+	_, err = q.Parse(alice, i, filter)
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.Is(err, filtering.ErrLimitExceeded) {
+		t.Fatalf("expected quota exceeded error but got %v", err)
+	}
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("expected *QuotaError but got %T", err)
+	}
+	if qe.Caller != "alice" || qe.Used != 3*complexity || qe.Budget != 3*complexity || qe.RetryAfter != 30*time.Second {
+		t.Errorf("unexpected quota error: %+v", qe)
+	}
+
+	// Other callers are not affected.
+	if x, err = q.Parse(bob, i, filter); err != nil {
+		t.Fatalf("unexpected error for other caller: %v", err)
+	}
+	x.Free()
+
+	// The oldest filter leaves the window.
+	now = now.Add(qe.RetryAfter)
+	if got := q.Usage(alice); got != 2*complexity {
+		t.Errorf("expected usage %d but got %d", 2*complexity, got)
+	}
+	if x, err = q.Parse(alice, i, filter); err != nil {
+		t.Fatalf("unexpected error after the window: %v", err)
+	}
+	x.Free()
+
+	// Invalid filters are not charged.
+	if _, err = q.Parse(bob, i, `i32 = "foo"`); !errors.Is(err, filtering.ErrInvalidValue) {
+		t.Fatalf("expected invalid value error but got %v", err)
+	}
+	if got := q.Usage(bob); got != complexity {
+		t.Errorf("expected usage %d but got %d", complexity, got)
+	}
+}
+
+func TestQuota_Admit(t *testing.T) {
+	i, err := filtering.NewInterpreter(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	q, err := New(2, time.Minute, testKey, AnonymousKeyOpt("anonymous"))
+	if err != nil {
+		t.Fatalf("failed to create quota: %v", err)
+	}
+
+	x, err := i.Parse(`i32 = 1 AND i64 = 2 AND str = "a"`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	err = q.Admit(context.Background(), x)
+	var qe *QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("expected *QuotaError but got %v", err)
+	}
+	if qe.Caller != "anonymous" || qe.RetryAfter != 0 {
+		t.Errorf("unexpected quota error: %+v", qe)
+	}
+	if err = q.Admit(context.Background(), nil); err != nil {
+		t.Errorf("unexpected error for nil expression: %v", err)
+	}
+
+	st := status.Convert(qe)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected code %s but got %s", codes.ResourceExhausted, st.Code())
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("expected single detail but got %v", st.Details())
+	}
+	if _, ok := st.Details()[0].(*errdetails.QuotaFailure); !ok {
+		t.Errorf("expected quota failure detail but got %T", st.Details()[0])
+	}
+}
+
+func TestQuota_CostModel(t *testing.T) {
+	i, err := filtering.NewInterpreter(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	q, err := New(100, time.Minute, testKey, CostModelOpt(expr.CostModel{Compare: 1, StringSearch: 60}))
+	if err != nil {
+		t.Fatalf("failed to create quota: %v", err)
+	}
+
+	ctx := withCaller("alice")
+	x, err := q.Parse(ctx, i, `str = "foo*"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x.Free()
+	if used := q.Usage(ctx); used != 61 {
+		t.Fatalf("expected usage 61 but got %d", used)
+	}
+
+	// The cheap comparisons still fit the budget, but another string search does not.
+	if x, err = q.Parse(ctx, i, `i32 = 1`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x.Free()
+	if _, err = q.Parse(ctx, i, `str = "bar*"`); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded error but got %v", err)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New(0, time.Minute, testKey); err == nil {
+		t.Error("expected error for non-positive budget")
+	}
+	if _, err := New(1, 0, testKey); err == nil {
+		t.Error("expected error for non-positive window")
+	}
+	if _, err := New(1, time.Minute, nil); err == nil {
+		t.Error("expected error for nil key function")
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filteringreflect provides the filtering interpreters of the messages resolved by the gRPC server reflection.
+// It allows to build a standalone filter validation proxy of the services, which protos are not compiled in.
+package filteringreflect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/filtering"
+)
+
+// DefaultTTL is the default time for which the interpreters are cached.
+const DefaultTTL = 10 * time.Minute
+
+// Option is an option of the Factory.
+type Option func(*Factory) error
+
+// TTLOpt is an option that sets the time for which the created interpreters are cached.
+// After the TTL the descriptors are fetched again, so that the changes of the target service schema are applied.
+func TTLOpt(ttl time.Duration) Option {
+	return func(f *Factory) error {
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		f.ttl = ttl
+		return nil
+	}
+}
+
+// InterpreterOpts is an option that sets the options of each created interpreter.
+func InterpreterOpts(opts ...filtering.Option) Option {
+	return func(f *Factory) error {
+		f.interpreterOpts = append(f.interpreterOpts, opts...)
+		return nil
+	}
+}
+
+// Factory creates the filtering interpreters of the messages of the target service on demand,
+// with the descriptors fetched from its gRPC server reflection endpoint.
+// The created interpreters are cached for the TTL.
+// A Factory is safe for concurrent use.
+type Factory struct {
+	client          rpb.ServerReflectionClient
+	ttl             time.Duration
+	interpreterOpts []filtering.Option
+	now             func() time.Time
+
+	mu    sync.Mutex
+	cache map[protoreflect.FullName]*cacheEntry
+}
+
+// cacheEntry is a cached interpreter, the done channel is closed once the interpreter is created.
+type cacheEntry struct {
+	done        chan struct{}
+	interpreter *filtering.Interpreter
+	err         error
+	expires     time.Time
+}
+
+// NewFactory creates a new factory of the interpreters of the service, connected with the cc client connection.
+func NewFactory(cc grpc.ClientConnInterface, opts ...Option) (*Factory, error) {
+	if cc == nil {
+		return nil, errors.New("client connection is not set")
+	}
+	f := Factory{
+		client: rpb.NewServerReflectionClient(cc),
+		ttl:    DefaultTTL,
+		now:    time.Now,
+		cache:  make(map[protoreflect.FullName]*cacheEntry),
+	}
+	for _, opt := range opts {
+		if err := opt(&f); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// Interpreter returns the interpreter of the message with given full name, i.e.: "library.v1.Book".
+// The interpreter is taken from the cache, or created with the descriptors fetched by the server reflection.
+// The concurrent calls for the same message wait for a single reflection request.
+// The failures are not cached.
+func (f *Factory) Interpreter(ctx context.Context, name protoreflect.FullName) (*filtering.Interpreter, error) {
+	f.mu.Lock()
+	e, ok := f.cache[name]
+	if ok {
+		select {
+		case <-e.done:
+			if f.now().After(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &cacheEntry{done: make(chan struct{})}
+		f.cache[name] = e
+		f.mu.Unlock()
+
+		e.interpreter, e.err = f.newInterpreter(ctx, name)
+		e.expires = f.now().Add(f.ttl)
+		if e.err != nil {
+			f.mu.Lock()
+			if f.cache[name] == e {
+				delete(f.cache, name)
+			}
+			f.mu.Unlock()
+		}
+		close(e.done)
+		return e.interpreter, e.err
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.interpreter, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Invalidate removes the cached interpreter of the message with given full name.
+func (f *Factory) Invalidate(name protoreflect.FullName) {
+	f.mu.Lock()
+	delete(f.cache, name)
+	f.mu.Unlock()
+}
+
+func (f *Factory) newInterpreter(ctx context.Context, name protoreflect.FullName) (*filtering.Interpreter, error) {
+	fds, err := f.fetchDescriptorSet(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return filtering.NewInterpreterFromDescriptorSet(fds, name, f.interpreterOpts...)
+}
+
+// fetchDescriptorSet fetches the file containing the symbol, along with all its dependencies,
+// that are not registered in the protoregistry.GlobalFiles.
+func (f *Factory) fetchDescriptorSet(ctx context.Context, symbol protoreflect.FullName) (*descriptorpb.FileDescriptorSet, error) {
+	stream, err := f.client.ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open server reflection stream: %w", err)
+	}
+	defer stream.CloseSend()
+
+	var (
+		fds       descriptorpb.FileDescriptorSet
+		files     = map[string]struct{}{}
+		requested = map[string]struct{}{}
+	)
+	req := &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: string(symbol)},
+	}
+	for req != nil {
+		if err = stream.Send(req); err != nil {
+			return nil, fmt.Errorf("failed to send server reflection request: %w", err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive server reflection response: %w", err)
+		}
+		switch rt := res.MessageResponse.(type) {
+		case *rpb.ServerReflectionResponse_FileDescriptorResponse:
+			for _, b := range rt.FileDescriptorResponse.FileDescriptorProto {
+				var fdp descriptorpb.FileDescriptorProto
+				if err = proto.Unmarshal(b, &fdp); err != nil {
+					return nil, fmt.Errorf("invalid file descriptor of the symbol %q: %w", symbol, err)
+				}
+				if _, ok := files[fdp.GetName()]; ok {
+					continue
+				}
+				files[fdp.GetName()] = struct{}{}
+				fds.File = append(fds.File, &fdp)
+			}
+		case *rpb.ServerReflectionResponse_ErrorResponse:
+			return nil, fmt.Errorf("server reflection of the symbol %q failed: %s", symbol, rt.ErrorResponse.ErrorMessage)
+		default:
+			return nil, fmt.Errorf("unexpected server reflection response: %T", res.MessageResponse)
+		}
+
+		// The server may skip the files already sent, thus request the missing dependencies one by one.
+		req = nil
+		for _, fdp := range fds.File {
+			for _, dep := range fdp.GetDependency() {
+				if _, ok := files[dep]; ok {
+					continue
+				}
+				if _, err = protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+					continue
+				}
+				if _, ok := requested[dep]; ok {
+					return nil, fmt.Errorf("server reflection did not return the dependency %q of the symbol %q", dep, symbol)
+				}
+				requested[dep] = struct{}{}
+				req = &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				}
+				break
+			}
+			if req != nil {
+				break
+			}
+		}
+	}
+	return &fds, nil
+}
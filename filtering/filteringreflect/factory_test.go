@@ -0,0 +1,100 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filteringreflect
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+
+	_ "github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	reflection.Register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestFactory_Interpreter(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewFactory(newTestConn(t), TTLOpt(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create factory: %v", err)
+	}
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	i, err := f.Interpreter(ctx, "testpb.Message")
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := i.Parse(`name = "foo" AND timestamp > 2021-01-01T00:00:00Z`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	x.Free()
+
+	t.Run("cached", func(t *testing.T) {
+		cached, err := f.Interpreter(ctx, "testpb.Message")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cached != i {
+			t.Error("expected cached interpreter")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		renewed, err := f.Interpreter(ctx, "testpb.Message")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if renewed == i {
+			t.Error("expected new interpreter after the TTL")
+		}
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		if _, err := f.Interpreter(ctx, "testpb.Unknown"); err == nil {
+			t.Fatal("expected error")
+		}
+		f.mu.Lock()
+		_, cached := f.cache["testpb.Unknown"]
+		f.mu.Unlock()
+		if cached {
+			t.Error("expected failure not to be cached")
+		}
+	})
+}
@@ -17,11 +17,13 @@ package filtering
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
 )
 
 type (
@@ -108,13 +110,16 @@ type (
 		// an expr.FunctionCall expression.
 		Indirect bool
 
-		// ArgName is the name of the field.
+		// ArgName is the name of the argument.
+		// It is used to refer to the argument in the error messages, which fall back to its index if the name is empty.
 		ArgName string
 
 		// IsRepeated is true if the argument is a repeated field.
 		IsRepeated bool
 
 		// IsNullable is true if the argument is a nullable field.
+		// The null literal of a nullable argument results in the expr.ValueExpr with nil Value,
+		// and the NullKind of the argument FieldKind.
 		IsNullable bool
 
 		// AllowedServiceCallFuncs is a list of function names that can be used as indirect argument.
@@ -175,6 +180,9 @@ type (
 )
 
 func (n FunctionName) String() string {
+	if n.PkgName == "" {
+		return n.Name
+	}
 	return fmt.Sprintf("%s.%s", n.PkgName, n.Name)
 }
 
@@ -419,10 +427,27 @@ func (b *Interpreter) TryParseFunctionCall(ctx *ParseContext, in TryParseValueIn
 }
 
 func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.FunctionCall, fn *FunctionCallDeclaration, allowIndirect bool) (TryParseValueResult, error) {
+	if b.traceHook != nil {
+		return b.traceCallFunction(ctx, x, fn, allowIndirect)
+	}
+	return b.callFunction(ctx, x, fn, allowIndirect)
+}
+
+func (b *Interpreter) callFunction(ctx *ParseContext, x *ast.FunctionCall, fn *FunctionCallDeclaration, allowIndirect bool) (TryParseValueResult, error) {
 	// We have a function call handler.
 	// Parse the argument fields and check if they match the function call declaration.
 	// If they do, then we can call the function call handler.
 	// Otherwise we return an error.
+	if len(x.Fields) > 0 {
+		// Only the fields of the any.Unpack result could be selected.
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Fields[0].Position()
+			res.ErrMsg = fmt.Sprintf("function: %s result fields cannot be selected", x.JoinedName())
+		}
+		return res, ErrInvalidValue
+	}
+
 	if (x.ArgList == nil || len(x.ArgList.Args) == 0) && len(fn.Arguments) > 0 {
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
@@ -447,12 +472,13 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 		// No arguments, so we can call the function call handler.
 		ex, err := fn.CallFn()
 		if err != nil {
+			b.logFunctionError(ctx, x, err)
 			var res TryParseValueResult
 			if ctx.ErrHandler != nil {
 				res.ErrPos = x.Position()
 				res.ErrMsg = fmt.Sprintf("function call %s failed: %v", x.JoinedName(), err)
 			}
-			return TryParseValueResult{}, err
+			return res, callFnError(err)
 		}
 		return TryParseValueResult{Expr: ex.Expr, IsIndirect: ex.IsIndirect}, nil
 	}
@@ -491,7 +517,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument is not of a boolean type, thus composite expression is not a valid argument", x.JoinedName())
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of a boolean type, thus composite expression is not a valid argument", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -506,6 +532,26 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 			isIndirect = isIndirect || right.IsIndirect
 			args = append(args, right.Expr)
 			continue
+		case *ast.RestrictionExpr:
+			// Similarly to the composite, a restriction is a boolean expression.
+			if ad.FieldKind != protoreflect.BoolKind || ad.Cardinality() == protoreflect.Repeated {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = at.Position()
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of a boolean type, thus restriction is not a valid argument", x.JoinedName(), argumentName(i, ad))
+				}
+				clearArgs()
+				return res, ErrInvalidValue
+			}
+
+			right, err := b.HandleRestrictionExpr(ctx, at)
+			if err != nil {
+				clearArgs()
+				return right, err
+			}
+			isIndirect = isIndirect || right.IsIndirect
+			args = append(args, right.Expr)
+			continue
 		case *ast.MemberExpr:
 			// A member expression is either a selector or a value expression.
 			// If the direct argument is a repeated field, then it is not a valid argument.
@@ -514,7 +560,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument %d must be an array", x.JoinedName(), i)
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s must be an array", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -537,7 +583,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not a valid field selector", x.JoinedName(), i)
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not a valid field selector", x.JoinedName(), argumentName(i, ad))
 						}
 						clearArgs()
 						return res, ErrInternal
@@ -548,11 +594,11 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					}
 
 					// Check if the field type matches.
-					if !isKindComparable(fd.Kind(), ad.Kind()) {
+					if !b.IsKindComparable(fd.Kind(), ad.Kind()) {
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, ad.FieldKind)
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), ad.FieldKind)
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -564,7 +610,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not repeated", x.JoinedName(), i)
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not repeated", x.JoinedName(), argumentName(i, ad))
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -575,7 +621,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s input argument %d is repeated but it should not be", x.JoinedName(), i)
+							res.ErrMsg = fmt.Sprintf("function call %s input argument %s is repeated but it should not be", x.JoinedName(), argumentName(i, ad))
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -586,7 +632,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not a map", x.JoinedName(), i)
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not a map", x.JoinedName(), argumentName(i, ad))
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -596,7 +642,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is a map", x.JoinedName(), i)
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is a map", x.JoinedName(), argumentName(i, ad))
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -606,7 +652,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, ad.Message().FullName())
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), ad.Message().FullName())
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -616,7 +662,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, ad.Enum().FullName())
+							res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), ad.Enum().FullName())
 						}
 						clearArgs()
 						return res, ErrInvalidValue
@@ -633,13 +679,24 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d must be an array", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s must be an array", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					return res, ErrInvalidValue
 				}
 			}
 
+			// The null literal is checked up front, so that the error names the argument.
+			if tl, ok := at.Value.(*ast.TextLiteral); ok && tl.Token == token.NULL && len(at.Fields) == 0 && !ad.IsNullable {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = tl.Pos
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is not nullable", x.JoinedName(), argumentName(i, ad))
+				}
+				clearArgs()
+				return res, ErrInvalidValue
+			}
+
 			// In case of indirect argument, the selector was not a valid field selector.
 			// Try to parse the member expression as a value expression.
 			res, err = b.TryParseValue(ctx, TryParseValueInput{
@@ -647,6 +704,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				Value:         at.Value,
 				Args:          at.Fields,
 				AllowIndirect: ad.Indirect,
+				IsOptional:    ad.IsNullable,
 				Complexity:    1,
 			})
 			if err != nil {
@@ -663,7 +721,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not repeated", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not repeated", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -674,18 +732,22 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is repeated", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is repeated", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
 					return res, ErrInvalidValue
 				}
+				// The null value of a nullable argument is typed with its declared kind.
+				if et.Value == nil && ad.IsNullable {
+					et.NullKind = ad.FieldKind
+				}
 				// Check if the argument accepts null values and the value is null.
 				if !ad.IsNullable && et.Value == nil {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not nullable", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not nullable", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -696,7 +758,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is repeated", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is repeated", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -707,7 +769,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not indirect", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not indirect", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -718,7 +780,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is repeated", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is repeated", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -729,7 +791,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not a map", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not a map", x.JoinedName(), argumentName(i, ad))
 					}
 					clearArgs()
 					res.Expr.Free()
@@ -760,7 +822,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument %d not found", x.JoinedName(), i)
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s not found", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -782,7 +844,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d does not allow to use indirect function call %s.%s", x.JoinedName(), i, argFn.Name.PkgName, argFn.Name.Name)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s does not allow to use indirect function call %s.%s", x.JoinedName(), argumentName(i, ad), argFn.Name.PkgName, argFn.Name.Name)
 					}
 					return res, ErrInvalidValue
 				}
@@ -794,7 +856,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, rt.FieldKind)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), rt.FieldKind)
 					}
 					return res, ErrInvalidValue
 				}
@@ -803,7 +865,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, rt.EnumDescriptor.FullName())
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), rt.EnumDescriptor.FullName())
 					}
 					return res, ErrInvalidValue
 				}
@@ -812,7 +874,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d does not return a map value", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s does not return a map value", x.JoinedName(), argumentName(i, ad))
 					}
 					return res, ErrInvalidValue
 				}
@@ -821,7 +883,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is not of type %s", x.JoinedName(), i, rt.Message().FullName())
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of type %s", x.JoinedName(), argumentName(i, ad), rt.Message().FullName())
 					}
 					return res, ErrInvalidValue
 				}
@@ -830,7 +892,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s argument %d is repeated", x.JoinedName(), i)
+						res.ErrMsg = fmt.Sprintf("function call %s argument %s is repeated", x.JoinedName(), argumentName(i, ad))
 					}
 					return res, ErrInvalidValue
 				}
@@ -855,7 +917,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument is not of a message type, thus struct expression is not a valid argument", x.JoinedName())
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is not of a message type, thus struct expression is not a valid argument", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -866,7 +928,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument %d must be a map", x.JoinedName(), i)
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s must be a map", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -877,7 +939,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument %d is a message not a map", x.JoinedName(), i)
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is a message not a map", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -904,7 +966,7 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Position()
-					res.ErrMsg = fmt.Sprintf("function call %s argument is not repeated, thus array expression is not a valid argument", x.JoinedName())
+					res.ErrMsg = fmt.Sprintf("function call %s argument %s is not repeated, thus array expression is not a valid argument", x.JoinedName(), argumentName(i, ad))
 				}
 				clearArgs()
 				return res, ErrInvalidValue
@@ -931,18 +993,28 @@ func (b *Interpreter) tryParseAndCallFunction(ctx *ParseContext, x *ast.Function
 	// We can call the function call handler.
 	ex, err := fn.CallFn(args...)
 	if err != nil {
+		b.logFunctionError(ctx, x, err)
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
 			res.ErrPos = x.Position()
 			res.ErrMsg = fmt.Sprintf("function call %s failed: %v", x.JoinedName(), err)
 		}
 		clearArgs()
-		return res, err
+		return res, callFnError(err)
 	}
 
 	return TryParseValueResult{Expr: ex.Expr, IsIndirect: ex.IsIndirect || isIndirect}, nil
 }
 
+// argumentName returns the name of the ith function call argument used in the error messages,
+// or its index if the declaration has no name.
+func argumentName(i int, ad *FunctionCallArgumentDeclaration) string {
+	if ad.ArgName == "" {
+		return strconv.Itoa(i)
+	}
+	return "'" + ad.ArgName + "'"
+}
+
 func (b *Interpreter) getFunctionDeclaration(ctx *ParseContext, x *ast.FunctionCall) (*FunctionCallDeclaration, bool) {
 	fn, ok := b.functionCallDeclarations[x.JoinedName()]
 	if !ok {
@@ -950,3 +1022,13 @@ func (b *Interpreter) getFunctionDeclaration(ctx *ParseContext, x *ast.FunctionC
 	}
 	return fn, true
 }
+
+// callFnError returns the standard error of the failed function call.
+// The function calls fail on the arguments provided by the filter, thus these are returned as the ErrInvalidValue,
+// unless the function failed with the internal error. The details of the error are set in the error message.
+func callFnError(err error) error {
+	if errors.Is(err, ErrInternal) {
+		return ErrInternal
+	}
+	return ErrInvalidValue
+}
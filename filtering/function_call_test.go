@@ -0,0 +1,102 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_FunctionCallNullArguments(t *testing.T) {
+	var got []*expr.ValueExpr
+	coalesce := &FunctionCallDeclaration{
+		Name: FunctionName{PkgName: "test", Name: "Coalesce"},
+		Arguments: []*FunctionCallArgumentDeclaration{
+			{ArgName: "value", FieldKind: protoreflect.StringKind, IsNullable: true},
+			{ArgName: "fallback", FieldKind: protoreflect.StringKind},
+		},
+		Returning: &FunctionCallReturningDeclaration{FieldKind: protoreflect.StringKind},
+		CallFn: func(args ...expr.FilterExpr) (FunctionCallArgument, error) {
+			got = got[:0]
+			for _, arg := range args {
+				got = append(got, arg.(*expr.ValueExpr))
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = got[1].Value
+			if got[0].Value != nil {
+				ve.Value = got[0].Value
+			}
+			return FunctionCallArgument{Expr: ve}, nil
+		},
+	}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, RegisterFunction(coalesce))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("typed null", func(t *testing.T) {
+		x, err := i.Parse(`str = test.Coalesce(null, "foo")`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		if len(got) != 2 {
+			t.Fatalf("expected 2 arguments but got %d", len(got))
+		}
+		if got[0].Value != nil || got[0].NullKind != protoreflect.StringKind {
+			t.Errorf("expected typed string null but got %v of kind %s", got[0].Value, got[0].NullKind)
+		}
+		if got[1].NullKind != 0 {
+			t.Errorf("expected no null kind for the non-null value, got %s", got[1].NullKind)
+		}
+		if v := x.(*expr.CompareExpr).Right.(*expr.ValueExpr).Value; v != "foo" {
+			t.Errorf("expected the fallback value but got %v", v)
+		}
+	})
+
+	t.Run("not nullable", func(t *testing.T) {
+		_, err := i.Parse(`str = test.Coalesce("foo", null)`)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("expected invalid value error but got %v", err)
+		}
+		var fe *FilterError
+		if !errors.As(err, &fe) {
+			t.Fatalf("expected FilterError but got %T", err)
+		}
+		if !strings.Contains(fe.Msg, "argument 'fallback' is not nullable") {
+			t.Errorf("expected the argument name in the error message, got: %s", fe.Msg)
+		}
+	})
+
+	t.Run("argument name", func(t *testing.T) {
+		_, err := i.Parse(`str = test.Coalesce("foo", [1])`)
+		var fe *FilterError
+		if !errors.As(err, &fe) {
+			t.Fatalf("expected FilterError but got %v", err)
+		}
+		if !strings.Contains(fe.Msg, "argument 'fallback'") {
+			t.Errorf("expected the argument name in the error message, got: %s", fe.Msg)
+		}
+	})
+}
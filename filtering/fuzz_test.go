@@ -0,0 +1,56 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func FuzzInterpreter_Parse(f *testing.F) {
+	for _, seed := range []string{
+		``,
+		`str = "foo" AND i32 > 10`,
+		`sub.name:* OR NOT enum = ONE`,
+		`map_str_str."key" = "value" AND rp_str:"abc*"`,
+		`timestamp > 2023-01-01T00:00:00Z AND double <= 1.5e3`,
+		`i32 IN [1, 2, 3] AND str =~ "^f.*"`,
+		`size(rp_str) > 2 AND any(rp_sub, name = "x")`,
+		`(i32 = 1 OR (i64 = 2 AND (bool = true)))`,
+		strings.Repeat("(", 200),
+	} {
+		f.Add(seed)
+	}
+
+	i, err := NewInterpreter(md, CollectionFunctionsOpt(), RegexMatchOpt(), BetweenOpt(), MaxDepthOpt(16))
+	if err != nil {
+		f.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, filter string) {
+		x, err := i.Parse(filter)
+		if err != nil {
+			var fe *FilterError
+			if !errors.As(err, &fe) {
+				t.Fatalf("expected FilterError for %q but got %T: %v", filter, err, err)
+			}
+			return
+		}
+		if x != nil {
+			x.Free()
+		}
+	})
+}
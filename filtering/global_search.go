@@ -0,0 +1,106 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// GlobalSearchHandler returns the expression of a global restriction, which is a bare term of the filter
+// without a comparator, i.e.: "New York" and hotel of the filter `"New York" hotel`.
+// The term is the unquoted value of the string literal or the text of the restriction.
+// The returned error should wrap one of the standard errors, i.e. ErrInvalidValue or ErrUnsupported.
+type GlobalSearchHandler func(term string) (expr.FilterExpr, error)
+
+// GlobalSearchHandlerOpt is an option that enables the global restrictions, handled by the handler.
+// The sequences of the terms result in the expr.AndExpr of the handler expressions, as for any other fuzzy AND.
+// Without the option, a restriction without a comparator is rejected.
+func GlobalSearchHandlerOpt(h GlobalSearchHandler) Option {
+	return func(i *Interpreter) error {
+		if h == nil {
+			return fmt.Errorf("global search handler is nil")
+		}
+		i.globalSearch = h
+		i.globalSearchFields = false
+		return nil
+	}
+}
+
+// GlobalSearchOpt is an option that enables the global restrictions, matched against the searchable fields
+// set by the SearchableFieldsOpt. Each term results in the same case-insensitive expr.StringSearchExpr
+// comparisons as the terms of the ParseWithSearch query, i.e.: `hotel` matches "Hotel Paris" in any
+// of the searchable fields.
+func GlobalSearchOpt() Option {
+	return func(i *Interpreter) error {
+		i.globalSearch = i.globalSearchFieldsExpr
+		i.globalSearchFields = true
+		return nil
+	}
+}
+
+// globalSearchFieldsExpr is the GlobalSearchHandler of the GlobalSearchOpt.
+func (b *Interpreter) globalSearchFieldsExpr(term string) (expr.FilterExpr, error) {
+	if len(b.searchFields) == 0 {
+		return nil, fmt.Errorf("%w: global restrictions require searchable fields", ErrUnsupported)
+	}
+	return b.searchTermExpr(term), nil
+}
+
+// globalSearchTerm returns the term of the global restriction member expression.
+func globalSearchTerm(x *ast.MemberExpr) (string, bool) {
+	if len(x.Fields) > 0 {
+		return "", false
+	}
+	switch vt := x.Value.(type) {
+	case *ast.StringLiteral:
+		return vt.Value, true
+	case *ast.TextLiteral:
+		return vt.Value, true
+	}
+	return "", false
+}
+
+// handleGlobalSearch handles the global restriction term of the member expression x.
+func (b *Interpreter) handleGlobalSearch(ctx *ParseContext, x *ast.MemberExpr, term string) (TryParseValueResult, error) {
+	var res TryParseValueResult
+	if strings.TrimSpace(term) == "" {
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = "global restriction term is empty"
+		}
+		return res, ErrInvalidValue
+	}
+
+	gx, err := b.globalSearch(term)
+	if err != nil {
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = err.Error()
+		}
+		return res, err
+	}
+	if gx == nil {
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Position()
+			res.ErrMsg = "global search handler returned no expression"
+		}
+		return res, ErrInternal
+	}
+	return TryParseValueResult{Expr: gx}, nil
+}
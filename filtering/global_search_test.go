@@ -0,0 +1,134 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestGlobalSearchOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, SearchableFieldsOpt("name", "rp_str"), GlobalSearchOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		x, err := i.Parse(`"New York" hotel`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		and, ok := x.(*expr.AndExpr)
+		if !ok || len(and.Expr) != 2 {
+			t.Fatalf("expected AndExpr of the terms but got %T", x)
+		}
+		for j, want := range []string{"New York", "hotel"} {
+			or, ok := and.Expr[j].(*expr.OrExpr)
+			if !ok || len(or.Expr) != 2 {
+				t.Fatalf("expected OrExpr of the searchable fields but got %T", and.Expr[j])
+			}
+			ss := or.Expr[0].(*expr.CompareExpr).Right.(*expr.StringSearchExpr)
+			if ss.Value != want || !ss.CaseInsensitive {
+				t.Errorf("expected case-insensitive search of %q but got %+v", want, ss)
+			}
+			if c := or.Expr[1].(*expr.CompareExpr).Comparator; c != expr.HAS {
+				t.Errorf("expected HAS comparator for the repeated field, got %s", c)
+			}
+		}
+	})
+
+	t.Run("combined with restriction", func(t *testing.T) {
+		x, err := i.Parse(`hotel AND i32 = 1`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+		if and, ok := x.(*expr.AndExpr); !ok || len(and.Expr) != 2 {
+			t.Errorf("expected AndExpr but got %T", x)
+		}
+	})
+
+	t.Run("empty term", func(t *testing.T) {
+		if _, err := i.Parse(`""`); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("expected invalid value error, got %v", err)
+		}
+	})
+
+	t.Run("no searchable fields", func(t *testing.T) {
+		ni, err := NewInterpreter(md, GlobalSearchOpt())
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = ni.Parse(`hotel`); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("expected unsupported error, got %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ni, err := NewInterpreter(md, SearchableFieldsOpt("name"))
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = ni.Parse(`"New York"`); err == nil {
+			t.Errorf("expected error without the global search")
+		}
+	})
+}
+
+func TestGlobalSearchHandlerOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	var terms []string
+	h := func(term string) (expr.FilterExpr, error) {
+		if term == "forbidden" {
+			return nil, fmt.Errorf("%w: term %q is not allowed", ErrInvalidValue, term)
+		}
+		terms = append(terms, term)
+		c := expr.Composer{Desc: md}
+		return c.Compare(c.MustSelect("name"), expr.EQ, c.Value(term)), nil
+	}
+	i, err := NewInterpreter(md, GlobalSearchHandlerOpt(h))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`foo OR "bar baz"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+	if or, ok := x.(*expr.OrExpr); !ok || len(or.Expr) != 2 {
+		t.Fatalf("expected OrExpr but got %T", x)
+	}
+	if len(terms) != 2 || terms[0] != "foo" || terms[1] != "bar baz" {
+		t.Errorf("unexpected handled terms: %v", terms)
+	}
+
+	if _, err = i.Parse(`forbidden`); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected handler error, got %v", err)
+	}
+	if _, err = i.Parse(`i32 = 1`); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err = NewInterpreter(md, GlobalSearchHandlerOpt(nil)); err == nil {
+		t.Errorf("expected nil handler error")
+	}
+}
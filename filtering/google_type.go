@@ -0,0 +1,183 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/date"
+	"google.golang.org/genproto/googleapis/type/timeofday"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// TryParseDateField tries parsing a google.type.Date field value.
+// The date is either a DATE literal or a string, in the 'YYYY-MM-DD' format, i.e.: 2024-05-01 or "2024-05-01",
+// and is decoded into the *date.Date value.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseDateField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	return b.tryParseGoogleTypeField(ctx, in, func(s string) (any, error) {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, errors.New("date must be in the 'YYYY-MM-DD' format")
+		}
+		return &date.Date{Year: int32(t.Year()), Month: int32(t.Month()), Day: int32(t.Day())}, nil
+	}, token.DATE)
+}
+
+// TryParseTimeOfDayField tries parsing a google.type.TimeOfDay field value.
+// The time is either a TIME_OF_DAY literal or a string, in the 'HH:MM:SS[.fffffffff]' format,
+// i.e.: 13:45:00 or "13:45:00.5", and is decoded into the *timeofday.TimeOfDay value.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseTimeOfDayField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	return b.tryParseGoogleTypeField(ctx, in, parseTimeOfDay, token.TIME_OF_DAY)
+}
+
+// TryParseDecimalField tries parsing a google.type.Decimal field value.
+// The decimal is either a numeric literal or a string, i.e.: 10.25 or "-123456789012345678901234567890.5",
+// and is decoded into the *big.Rat value, so that it never loses the precision of the float64.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseDecimalField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	return b.tryParseGoogleTypeField(ctx, in, parseDecimal, token.INT, token.NUMERIC)
+}
+
+func (b *Interpreter) tryParseGoogleTypeField(ctx *ParseContext, in TryParseValueInput, decode func(string) (any, error), toks ...token.Token) (TryParseValueResult, error) {
+	if len(in.Args) > 0 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Message().FullName(), joinedName(in.Value, in.Args...))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	var (
+		lit string
+		pos token.Position
+	)
+	switch ft := in.Value.(type) {
+	case *ast.StringLiteral:
+		lit, pos = ft.Value, ft.Pos
+	case *ast.TextLiteral:
+		if in.IsOptional && ft.Token == token.NULL {
+			ve := expr.AcquireValueExpr()
+			ve.Value = nil
+			return TryParseValueResult{Expr: ve}, nil
+		}
+		if !slices.Contains(toks, ft.Token) {
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Message().FullName(), ft.Value)}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+		lit, pos = ft.Value, ft.Pos
+	case *ast.StructExpr:
+		// The message struct, i.e.: google.type.Date{year: 2024, month: 5, day: 1}.
+		return b.TryParseMessageStructField(ctx, in)
+	case *ast.ArrayExpr:
+		ae := expr.AcquireArrayExpr()
+		for _, elem := range ft.Elements {
+			res, err := b.TryParseValue(ctx, TryParseValueInput{
+				Field:      in.Field,
+				IsOptional: in.IsOptional,
+				Value:      elem,
+				Complexity: in.Complexity,
+			})
+			if err != nil {
+				ae.Free()
+				return res, err
+			}
+			ae.Elements = append(ae.Elements, res.Expr)
+		}
+		return TryParseValueResult{Expr: ae}, nil
+	default:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: "invalid AST node"}, ErrInvalidAST
+		}
+		return TryParseValueResult{}, ErrInvalidAST
+	}
+
+	v, err := decode(lit)
+	if err != nil {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s': %v", in.Field.Message().FullName(), lit, err)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+	ve := expr.AcquireValueExpr()
+	ve.Value = v
+	return TryParseValueResult{Expr: ve}, nil
+}
+
+// parseTimeOfDay parses the time of day in the 'HH:MM:SS[.fffffffff]' format.
+// As in the google.type.TimeOfDay, the 24:00:00 is allowed as the end of the day, and 60 seconds as a leap second.
+func parseTimeOfDay(s string) (any, error) {
+	errFormat := errors.New("time of day must be in the 'HH:MM:SS[.fffffffff]' format")
+	if len(s) < len("15:04:05") || s[2] != ':' || s[5] != ':' {
+		return nil, errFormat
+	}
+	var parts [3]int32
+	for i := range parts {
+		v, err := strconv.ParseUint(s[i*3:i*3+2], 10, 8)
+		if err != nil {
+			return nil, errFormat
+		}
+		parts[i] = int32(v)
+	}
+
+	var nanos int32
+	if frac := s[8:]; frac != "" {
+		if frac[0] != '.' || len(frac) < 2 || len(frac) > 10 {
+			return nil, errFormat
+		}
+		digits := frac[1:] + strings.Repeat("0", 10-len(frac))
+		v, err := strconv.ParseUint(digits, 10, 32)
+		if err != nil {
+			return nil, errFormat
+		}
+		nanos = int32(v)
+	}
+
+	hh, mm, ss := parts[0], parts[1], parts[2]
+	switch {
+	case hh == 24 && (mm != 0 || ss != 0 || nanos != 0), hh > 24:
+		return nil, errors.New("hours must be in the range 0 to 23")
+	case mm > 59:
+		return nil, errors.New("minutes must be in the range 0 to 59")
+	case ss > 60:
+		return nil, errors.New("seconds must be in the range 0 to 60")
+	}
+	return &timeofday.TimeOfDay{Hours: hh, Minutes: mm, Seconds: ss, Nanos: nanos}, nil
+}
+
+// parseDecimal parses the decimal number with an optional exponent, i.e.: -10.25 or 1.5e-3.
+// Contrary to the big.Rat.SetString, the fractions 'a/b' and the base prefixed numbers are not accepted.
+func parseDecimal(s string) (any, error) {
+	errFormat := errors.New("decimal must be a base 10 number, optionally with the exponent")
+	if strings.ContainsAny(s, "/_xXoObB") {
+		return nil, errFormat
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errFormat
+	}
+	return r, nil
+}
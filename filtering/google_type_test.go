@@ -0,0 +1,179 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/type/date"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+	"google.golang.org/genproto/googleapis/type/decimal"
+	"google.golang.org/genproto/googleapis/type/timeofday"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_GoogleTypes(t *testing.T) {
+	md := testGoogleTypeMessage(t)
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   []any
+		err    error
+	}{
+		{name: "date", filter: `date = 2024-05-01`, want: []any{&date.Date{Year: 2024, Month: 5, Day: 1}}},
+		{name: "date string", filter: `date >= "2024-02-29"`, want: []any{&date.Date{Year: 2024, Month: 2, Day: 29}}},
+		{name: "date and", filter: `date > 2024-05-01 AND day = MONDAY`},
+		{
+			name:   "date array",
+			filter: `date IN [2024-05-01, 2024-05-02]`,
+			want:   []any{&date.Date{Year: 2024, Month: 5, Day: 1}, &date.Date{Year: 2024, Month: 5, Day: 2}},
+		},
+		{name: "invalid date", filter: `date = 2023-02-29`, err: ErrInvalidValue},
+		{name: "timestamp for date", filter: `date = 2024-05-01T00:00:00Z`, err: ErrInvalidValue},
+		{name: "time of day", filter: `time < 13:45:00`, want: []any{&timeofday.TimeOfDay{Hours: 13, Minutes: 45}}},
+		{name: "time of day nanos", filter: `time = 13:45:01.25`, want: []any{&timeofday.TimeOfDay{Hours: 13, Minutes: 45, Seconds: 1, Nanos: 250000000}}},
+		{name: "end of day", filter: `time = "24:00:00"`, want: []any{&timeofday.TimeOfDay{Hours: 24}}},
+		{name: "invalid time of day", filter: `time = 13:61:00`, err: ErrInvalidValue},
+		{name: "day of week", filter: `day = MONDAY`, want: []any{dayofweek.DayOfWeek_MONDAY.Number()}},
+		{name: "quoted day of week", filter: `day = "FRIDAY"`, want: []any{dayofweek.DayOfWeek_FRIDAY.Number()}},
+		{name: "invalid day of week", filter: `day = MONDAYS`, err: ErrInvalidValue},
+		{name: "decimal", filter: `decimal = 10.25`, want: []any{big.NewRat(41, 4)}},
+		{name: "negative decimal", filter: `decimal > -0.5`, want: []any{big.NewRat(-1, 2)}},
+		{
+			name:   "big decimal",
+			filter: `decimal < 123456789012345678901234567890.1`,
+			want:   []any{new(big.Rat).SetFrac(bigInt(t, "1234567890123456789012345678901"), big.NewInt(10))},
+		},
+		{name: "decimal string", filter: `decimal = "9007199254740993"`, want: []any{new(big.Rat).SetInt64(9007199254740993)}},
+		{name: "decimal array", filter: `decimal IN [1, 2.5]`, want: []any{big.NewRat(1, 1), big.NewRat(5, 2)}},
+		{name: "fraction decimal", filter: `decimal = "1/3"`, err: ErrInvalidValue},
+		{name: "hex decimal", filter: `decimal = 0x10`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected valid expression but got: %v", err)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var values []expr.FilterExpr
+			switch rt := x.(*expr.CompareExpr).Right.(type) {
+			case *expr.ArrayExpr:
+				values = rt.Elements
+			default:
+				values = []expr.FilterExpr{rt}
+			}
+			if len(values) != len(tt.want) {
+				t.Fatalf("expected %d values but got %d", len(tt.want), len(values))
+			}
+			for j, v := range values {
+				got := v.(*expr.ValueExpr).Value
+				switch wt := tt.want[j].(type) {
+				case *big.Rat:
+					if gr, ok := got.(*big.Rat); !ok || gr.Cmp(wt) != 0 {
+						t.Errorf("expected value %v but got %v", wt, got)
+					}
+				case proto.Message:
+					if gm, ok := got.(proto.Message); !ok || !proto.Equal(gm, wt) {
+						t.Errorf("expected value %v but got %v", wt, got)
+					}
+				default:
+					if got != wt {
+						t.Errorf("expected value %v but got %v", wt, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func bigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid big integer: %s", s)
+	}
+	return v
+}
+
+// testGoogleTypeMessage builds a message with the google.type.Date, google.type.TimeOfDay, google.type.DayOfWeek
+// and google.type.Decimal fields.
+func testGoogleTypeMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			TypeName: proto.String(typeName),
+			JsonName: proto.String(name),
+		}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("testgoogletype/schedule.proto"),
+		Package: proto.String("testgoogletype"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			date.File_google_type_date_proto.Path(),
+			timeofday.File_google_type_timeofday_proto.Path(),
+			dayofweek.File_google_type_dayofweek_proto.Path(),
+			decimal.File_google_type_decimal_proto.Path(),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Schedule"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("date", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.type.Date"),
+				field("time", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.type.TimeOfDay"),
+				field("day", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".google.type.DayOfWeek"),
+				field("decimal", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.type.Decimal"),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build test file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// DisallowIndirectComparisonsOpt is an option that forbids the restrictions which compare a field
+// with another field, i.e. `i32 = i64` or `time.Since(create_time) > ttl`.
+// It is useful for the backends that cannot compare two columns, so that such filters
+// fail with the ErrUnsupported error while parsing.
+func DisallowIndirectComparisonsOpt() Option {
+	return func(i *Interpreter) error {
+		i.disallowIndirectComparisons = true
+		return nil
+	}
+}
+
+// checkIndirectComparison verifies if the restriction result doesn't compare a field with another field.
+func (b *Interpreter) checkIndirectComparison(ctx *ParseContext, x *ast.RestrictionExpr, res TryParseValueResult) (TryParseValueResult, error) {
+	ce, ok := res.Expr.(*expr.CompareExpr)
+	if !ok {
+		return res, nil
+	}
+
+	if !referencesField(ce.Left) || !referencesField(ce.Right) {
+		return res, nil
+	}
+
+	var out TryParseValueResult
+	if ctx.ErrHandler != nil {
+		out.ErrPos = x.Arg.Position()
+		out.ErrMsg = fmt.Sprintf("comparing a field with another field is not supported: %s", x.Arg.String())
+	}
+	res.Expr.Free()
+	return out, ErrUnsupported
+}
+
+// referencesField checks if the expression value depends on a message field.
+func referencesField(x expr.FilterExpr) bool {
+	switch xt := x.(type) {
+	case *expr.FieldSelectorExpr:
+		return true
+	case *expr.FunctionCallExpr:
+		for _, arg := range xt.Arguments {
+			if referencesField(arg) {
+				return true
+			}
+		}
+	case *expr.ArrayExpr:
+		for _, elem := range xt.Elements {
+			if referencesField(elem) {
+				return true
+			}
+		}
+	case *expr.BinaryExpr:
+		return referencesField(xt.Left) || referencesField(xt.Right)
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInterpreter_DisallowIndirectComparisons(t *testing.T) {
+	tc := []struct {
+		name   string
+		filter string
+		isErr  bool
+	}{
+		{
+			name:   "direct value",
+			filter: `i32 = 1`,
+		},
+		{
+			name:   "direct array",
+			filter: `i64 IN [1, 2, 3]`,
+		},
+		{
+			name:   "map key presence",
+			filter: `map_str_i32:"key"`,
+		},
+		{
+			name:   "field to field",
+			filter: `i32 = i64`,
+			isErr:  true,
+		},
+		{
+			name:   "traversal field to field",
+			filter: `sub.name = str`,
+			isErr:  true,
+		},
+		{
+			name:   "field to field within composite",
+			filter: `name = "foo" AND (i32 > 1 OR i32 = i64)`,
+			isErr:  true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, DisallowIndirectComparisonsOpt())
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.isErr {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("expected error %v but got %v", ErrUnsupported, err)
+				}
+				var fe *FilterError
+				if !errors.As(err, &fe) || fe.Code != ErrorCodeUnsupported {
+					t.Fatalf("expected filter error with code %s but got %v", ErrorCodeUnsupported, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			x.Free()
+		})
+	}
+}
@@ -17,15 +17,19 @@ package filtering
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
+	"github.com/blockysource/blocky-aip/cache"
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/parser"
 	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/resourcename"
 	"github.com/blockysource/blocky-aip/scanner"
 	"github.com/blockysource/blocky-aip/token"
 )
@@ -51,9 +55,19 @@ var (
 
 	// ErrAmbiguousField is an error that is returned when a field is ambiguous.
 	ErrAmbiguousField = errors.New("ambiguous field selector")
+
+	// ErrLimitExceeded is an error that is returned when a filter exceeds one of the interpreter limits.
+	ErrLimitExceeded = errors.New("filter limit exceeded")
+
+	// ErrUnsupported is an error that is returned when a filter uses a capability disabled in the interpreter.
+	ErrUnsupported = errors.New("unsupported filter capability")
 )
 
 // Interpreter is an interpreter that can parse a query string and return an expression.
+// Once initialized, the Interpreter is safe for concurrent use by multiple goroutines,
+// i.e. a single interpreter could be shared across all the gRPC handlers of a service.
+// The error handler function, set by the ErrHandlerOpt, is called concurrently and needs to be thread-safe as well.
+// The Reset method must not be called concurrently with the Parse.
 type Interpreter struct {
 	// msg is a message descriptor which is used to resolve field names.
 	msg protoreflect.MessageDescriptor
@@ -63,6 +77,130 @@ type Interpreter struct {
 
 	functionCallDeclarations map[string]*FunctionCallDeclaration
 
+	// valueDecoders are the decoders of the message field values, by the message full name.
+	valueDecoders map[protoreflect.FullName]ValueDecoder
+
+	// literalStringFields are the string fields which unquoted values are not split by the dots.
+	literalStringFields map[protoreflect.FullName]struct{}
+
+	// limits of the parsed filter, zero value means no limit.
+	maxComplexity   int64
+	maxDepth        int
+	maxFilterLength int
+
+	// caseInsensitive marks all the string comparisons as case-insensitive.
+	caseInsensitive bool
+	// caseInsensitiveFields are the string fields which comparisons are case-insensitive.
+	caseInsensitiveFields map[protoreflect.FullName]struct{}
+
+	// uuidFields are the string fields which values are UUIDs.
+	uuidFields map[protoreflect.FullName]struct{}
+
+	// resourceNames are the patterns of the resource name string fields, enabled by the ResourceNamesOpt.
+	resourceNames map[protoreflect.FullName]resourcename.Patterns
+
+	// searchFields are the fields matched by the free-text search query.
+	searchFields []searchField
+
+	// between enables the range macro.
+	between bool
+
+	// collectionFunctions enables the size, any and all functions of the repeated fields.
+	collectionFunctions bool
+
+	// regexMatch enables the regular expression match comparator.
+	regexMatch bool
+
+	// customComparators are the declarations of the custom comparators by their symbols.
+	customComparators map[string]*ComparatorDeclaration
+
+	// cache stores the parsed expressions for the cacheTTL.
+	cache    cache.Cache
+	cacheTTL time.Duration
+
+	// exprCache stores the parsed expressions in memory for the exprCacheTTL.
+	exprCache    *cache.LRU[expr.FilterExpr]
+	exprCacheTTL time.Duration
+	// cacheKeyPrefix is the prefix of the cache keys, with the fingerprint of the options.
+	cacheKeyPrefix string
+
+	// macros are the registered macros by their normalized names.
+	macros map[string]*macro
+
+	// preset is the name of the applied preset configuration.
+	preset string
+
+	// substringHas makes the HAS comparator of singular string fields a substring match.
+	substringHas bool
+	// lenientEnums accepts unquoted and case-insensitive enum value names.
+	lenientEnums bool
+	// dateOnlyTimestamps accepts date-only values of the timestamp fields.
+	dateOnlyTimestamps bool
+	// durationDays accepts the day and week units of the duration literals.
+	durationDays bool
+
+	// kindPolicy decides which field kinds are comparable, nil means the DefaultKindPolicy.
+	kindPolicy KindPolicy
+
+	// disallowIndirectComparisons forbids comparing a field with another field.
+	disallowIndirectComparisons bool
+
+	// matchAll makes the empty filter result in the expr.MatchAllExpr.
+	matchAll bool
+
+	// baseExpr is combined with each parsed filter using the AND operator.
+	baseExpr expr.FilterExpr
+
+	// reservedKeywords are the keywords rejected by the parser, along with their hints.
+	reservedKeywords scanner.ReservedWords
+
+	// traceFn records the selector resolution steps.
+	traceFn SelectorTraceFn
+
+	// traceHook receives the parse trace events.
+	traceHook parser.TraceHookFn
+
+	// parseHooks are called after each Parse.
+	parseHooks []ParseHookFn
+
+	// logger logs the parse and function call failures, see LoggerOpt.
+	logger *slog.Logger
+
+	// restrictionHooks are called with each restriction expression.
+	restrictionHooks []RestrictionHookFn
+
+	// clock resolves the relative timestamps, nil if these are disabled.
+	clock func() time.Time
+	// nowFn is the now() function declaration registered along with the clock.
+	nowFn *FunctionCallDeclaration
+
+	// selectorNames are the kinds of the field names used to resolve the selectors, in the priority order.
+	selectorNames []SelectorName
+
+	// fieldAliases are the fields by their alternative names.
+	fieldAliases map[string]protoreflect.FieldDescriptor
+
+	// displayNameExt is the field option extension with the field display name.
+	displayNameExt protoreflect.ExtensionType
+
+	// extensions are the extension fields allowed in the filters.
+	extensions []protoreflect.ExtensionType
+	// anyTypes are the message types the google.protobuf.Any fields could be unpacked to.
+	anyTypes []protoreflect.MessageDescriptor
+
+	// sequence makes the sequences result in the expr.SequenceExpr.
+	sequence bool
+	// sequenceWeight sets the relevance weights of the sequence factors.
+	sequenceWeight SequenceWeightFn
+
+	// globalSearch handles the global restrictions, nil if these are disabled.
+	globalSearch GlobalSearchHandler
+	// globalSearchFields is set if the globalSearch matches the searchable fields.
+	globalSearchFields bool
+
+	// emptyStringMode defines the relation between the empty string and the null values of the string fields.
+	emptyStringMode EmptyStringMode
+
 	msgInfo info.MessagesInfo
 }
 
@@ -103,6 +241,30 @@ func RegisterFunction(fn *FunctionCallDeclaration) Option {
 	}
 }
 
+// LiteralStringFieldsOpt is an option that marks string fields, which unquoted values are taken
+// as a single literal, instead of being split by the dots into a field selector.
+// It is useful for the fields that commonly contain dots like domains, versions or file paths,
+// so that a filter like `domain = example.com` is equivalent to `domain = "example.com"`.
+// Note that the unquoted values of these fields can no longer be used as field selectors.
+func LiteralStringFieldsOpt(fields ...protoreflect.FullName) Option {
+	return func(i *Interpreter) error {
+		if i.literalStringFields == nil {
+			i.literalStringFields = make(map[protoreflect.FullName]struct{}, len(fields))
+		}
+		for _, name := range fields {
+			fd, ok := i.findField(name)
+			if !ok {
+				return fmt.Errorf("field %q not found", name)
+			}
+			if fd.Kind() != protoreflect.StringKind {
+				return fmt.Errorf("field %q is not a string field", name)
+			}
+			i.literalStringFields[name] = struct{}{}
+		}
+		return nil
+	}
+}
+
 // NewInterpreter returns a new interpreter.
 func NewInterpreter(msg protoreflect.MessageDescriptor, opts ...Option) (*Interpreter, error) {
 	b := Interpreter{
@@ -115,9 +277,14 @@ func NewInterpreter(msg protoreflect.MessageDescriptor, opts ...Option) (*Interp
 	return &b, nil
 }
 
+// Reset resets the interpreter with the new message descriptor and options.
+// It is not safe to call Reset concurrently with the Parse method.
 func (b *Interpreter) Reset(msg protoreflect.MessageDescriptor, opts ...Option) error {
 	b.msg = msg
 	b.msgInfo = info.MapMsgInfo(msg)
+	// The extensions and Any types are mapped within the message info, thus need to be registered again.
+	b.extensions = nil
+	b.anyTypes = nil
 
 	if b.msg == nil {
 		return errors.New("message descriptor is not set")
@@ -128,34 +295,119 @@ func (b *Interpreter) Reset(msg protoreflect.MessageDescriptor, opts ...Option)
 			return err
 		}
 	}
+
+	if b.cache != nil || b.exprCache != nil {
+		prefix, err := b.newCacheKeyPrefix()
+		if err != nil {
+			return err
+		}
+		b.cacheKeyPrefix = prefix
+	}
 	return nil
 }
 
+// findField finds the field descriptor by its full name within the messages known to the interpreter.
+func (b *Interpreter) findField(name protoreflect.FullName) (protoreflect.FieldDescriptor, bool) {
+	for _, mi := range b.msgInfo {
+		if mi.Desc.FullName() != name.Parent() {
+			continue
+		}
+		if fi, ok := mi.FieldByName(name.Name()); ok {
+			return fi.Desc, true
+		}
+	}
+	return nil, false
+}
+
+func (b *Interpreter) isLiteralStringField(fd protoreflect.FieldDescriptor) bool {
+	if len(b.literalStringFields) == 0 {
+		return false
+	}
+	_, ok := b.literalStringFields[fd.FullName()]
+	return ok
+}
+
 // Parse input filter into an expression.
 // Implements filtering.Interpreter interface.
-// By default, interpreter is returning a non-precise error if the parsing fails.
-// For detailed error handling, provide an error handler function during initialization of the interpreter.
-func (b *Interpreter) Parse(filter string) (expr.FilterExpr, error) {
-	var p parser.Parser
-
+// If the parsing fails, the returned error is a *FilterError, which wraps one of the standard errors,
+// i.e. ErrInvalidValue, and describes the position and the offending snippet of the filter.
+// For collecting all the diagnostics, provide an error handler function during initialization of the interpreter.
+// The parse options, i.e. the DeniedFieldsOpt, apply only to this call. The filters parsed with the field
+// restrictions are not cached.
+func (b *Interpreter) Parse(filter string, opts ...ParseOption) (expr.FilterExpr, error) {
 	if b.msg == nil {
 		panic("message descriptor is not set")
 	}
 
+	fa, err := b.fieldAccess(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.parseHooks) == 0 && b.logger == nil {
+		return b.withBaseExpr(b.parseWithAccess(filter, fa))
+	}
+
+	start := time.Now()
+	x, err := b.withBaseExpr(b.parseWithAccess(filter, fa))
+	b.callParseHooks(filter, start, x, err)
+	return x, err
+}
+
+func (b *Interpreter) parseWithAccess(filter string, fa *fieldAccess) (expr.FilterExpr, error) {
+	if fa != nil {
+		return b.parse(filter, nil, fa)
+	}
+	return b.parseCached(filter)
+}
+
+// ParseInArena parses input filter into an expression owned by the request-scoped arena.
+// The expression is released with the arena, and must not be freed directly.
+func (b *Interpreter) ParseInArena(a *expr.Arena, filter string, opts ...ParseOption) (expr.FilterExpr, error) {
+	x, err := b.Parse(filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if x != nil {
+		a.Own(x)
+	}
+	return x, nil
+}
+
+func (b *Interpreter) parse(filter string, params map[string]any, fa *fieldAccess) (expr.FilterExpr, error) {
+	var p parser.Parser
+
 	if filter == "" {
+		if b.matchAll {
+			return expr.AcquireMatchAllExpr(), nil
+		}
 		return nil, nil
 	}
 
-	var errHandler parser.ParserOption
-	if b.errHandlerFn != nil {
-		errHandler = parser.ErrorHandlerOption(b.errHandlerFn)
+	if b.maxFilterLength > 0 && len(filter) > b.maxFilterLength {
+		return nil, b.limitExceeded(filter, token.Position(b.maxFilterLength), fmt.Sprintf("filter length exceeds the limit of %d", b.maxFilterLength))
 	}
 
-	p.Reset(filter, errHandler)
+	// Capture the first syntax error, so that it could be returned as a FilterError.
+	var (
+		synPos token.Position
+		synMsg string
+	)
+	p.Reset(filter, parser.ErrorHandlerOption(func(pos token.Position, msg string) {
+		if synMsg == "" {
+			synPos, synMsg = pos, msg
+		}
+		if b.errHandlerFn != nil {
+			b.errHandlerFn(pos, msg)
+		}
+	}), b.reservedKeywordsOption(), b.customComparatorsOption(), b.traceHookOption(), b.parserLimitsOption())
 
 	pf, err := p.Parse()
 	if err != nil {
-		return nil, err
+		if errors.Is(err, parser.ErrLimitExceeded) {
+			err = ErrLimitExceeded
+		}
+		return nil, newFilterError(filter, synPos, synMsg, err)
 	}
 	defer pf.Free()
 
@@ -163,23 +415,52 @@ func (b *Interpreter) Parse(filter string) (expr.FilterExpr, error) {
 		return nil, status.Error(codes.Internal, "parsing filter failed")
 	}
 
+	if b.maxDepth > 0 {
+		if pos, ok := exceedsDepth(pf.Expr, 1, b.maxDepth); ok {
+			return nil, b.limitExceeded(filter, pos, fmt.Sprintf("filter depth exceeds the limit of %d", b.maxDepth))
+		}
+	}
+
 	ctx := contextPool.Get().(*ParseContext)
 	defer ctx.Free()
 
 	ctx.Message = b.msg
+	ctx.filter = filter
 	ctx.ErrHandler = b.errHandlerFn
+	if ctx.ErrHandler == nil {
+		// The detailed error messages are required to compose the FilterError.
+		ctx.ErrHandler = noopErrHandler
+	}
 	ctx.Interpreter = b
+	ctx.Params = params
+	ctx.fieldAccess = fa
 
 	he, err := b.HandleExpr(ctx, pf.Expr)
 	if err != nil {
 		if b.errHandlerFn != nil {
 			b.errHandlerFn(he.ErrPos, he.ErrMsg)
 		}
-		return nil, err
+		return nil, newFilterError(filter, he.ErrPos, he.ErrMsg, err)
+	}
+
+	if b.maxComplexity > 0 && he.Expr != nil {
+		if c := he.Expr.Complexity(); c > b.maxComplexity {
+			he.Expr.Free()
+			return nil, b.limitExceeded(filter, 0, fmt.Sprintf("filter complexity %d exceeds the limit of %d", c, b.maxComplexity))
+		}
 	}
 	return he.Expr, nil
 }
 
+func (b *Interpreter) limitExceeded(filter string, pos token.Position, msg string) error {
+	if b.errHandlerFn != nil {
+		b.errHandlerFn(pos, msg)
+	}
+	return newFilterError(filter, pos, msg, ErrLimitExceeded)
+}
+
+func noopErrHandler(token.Position, string) {}
+
 // HandledExpr is a struct that contains an expression and a flag that indicates if the expression was consumed.
 type HandledExpr struct {
 	Expr     expr.FilterExpr
@@ -188,7 +469,7 @@ type HandledExpr struct {
 
 var contextPool = sync.Pool{
 	New: func() any {
-		return &ParseContext{}
+		return &ParseContext{isAcquired: true}
 	},
 }
 
@@ -204,6 +485,19 @@ type ParseContext struct {
 	// It can be used by custom handlers to reuse standard handlers for sub-expressions.
 	Interpreter *Interpreter
 
+	// Params are the named parameter values bound by the ParseWithParams.
+	// Nil if the filter is parsed without parameters.
+	Params map[string]any
+
+	// macros are the names of the macros being expanded.
+	macros []string
+
+	// fieldAccess restricts the fields of the selectors, nil if these are not restricted.
+	fieldAccess *fieldAccess
+
+	// filter is the input filter being parsed.
+	filter string
+
 	isAcquired bool
 }
 
@@ -219,4 +513,9 @@ func (c *ParseContext) Free() {
 	c.Message = nil
 	c.ErrHandler = nil
 	c.Interpreter = nil
+	c.Params = nil
+	c.macros = c.macros[:0]
+	c.fieldAccess = nil
+	c.filter = ""
+	contextPool.Put(c)
 }
@@ -15,6 +15,8 @@
 package filtering
 
 import (
+	"errors"
+	"sync"
 	"testing"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -70,6 +72,19 @@ func TestInterpreter_Parse(t *testing.T) {
 			isErr:  true,
 			err:    ErrInvalidValue,
 		},
+		{
+			name:   "map value EQ field selector",
+			filter: `map_str_str."key" = str`,
+			checkFn: func(t *testing.T, x expr.FilterExpr) {
+				ce, ok := x.(*expr.CompareExpr)
+				if !ok {
+					t.Fatalf("expected compare expression but got %T", x)
+				}
+				if fs, ok := ce.Right.(*expr.FieldSelectorExpr); !ok || fs.Field != "str" {
+					t.Fatalf("expected str field selector but got %v", ce.Right)
+				}
+			},
+		},
 		{
 			name:    "repeated string EQ direct",
 			filter:  tstRepeatedStringFieldEqDirect,
@@ -751,7 +766,7 @@ func TestInterpreter_Parse(t *testing.T) {
 				if err == nil {
 					t.Fatal("expected error but got none")
 				}
-				if tt.err != nil && tt.err != err {
+				if tt.err != nil && !errors.Is(err, tt.err) {
 					t.Fatalf("expected error %s but got %s", tt.err, err)
 				}
 			} else {
@@ -822,6 +837,40 @@ func BenchmarkInterpreter_Parse(b *testing.B) {
 	}
 }
 
+func TestInterpreter_ParseConcurrent(t *testing.T) {
+	filters := []string{
+		`name = "foo" AND i32 > 10`,
+		`rp_str:"foo" OR (i64 IN [1, 2, 3] AND NOT bool = true)`,
+		`timestamp > 2021-01-01T00:00:00Z AND duration = 10s`,
+		`sub.name = "foo" AND i32 = i64`,
+		`name = 1 AND`,
+		`unknown_field = 1`,
+	}
+
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				for _, f := range filters {
+					x, err := i.Parse(f)
+					if err != nil {
+						continue
+					}
+					x.Free()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func testIndirectFields(f1, f2 string) func(t *testing.T, x expr.FilterExpr) {
 	return func(t *testing.T, x expr.FilterExpr) {
 		ce, ok := x.(*expr.CompareExpr)
@@ -850,3 +899,25 @@ func testIndirectFields(f1, f2 string) func(t *testing.T, x expr.FilterExpr) {
 		}
 	}
 }
+
+func TestInterpreter_ParseInArena(t *testing.T) {
+	i, err := NewInterpreter(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	a := expr.NewArena()
+	defer a.Release()
+
+	for _, filter := range []string{`i32 = 1`, `str = "foo" AND i64 > 2`, ``} {
+		if _, err = i.ParseInArena(a, filter); err != nil {
+			t.Fatalf("failed to parse filter %q: %v", filter, err)
+		}
+	}
+	if _, err = i.ParseInArena(a, `unknown = 1`); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+	if got := a.Len(); got != 2 {
+		t.Errorf("expected 2 expressions owned by the arena, got %d", got)
+	}
+}
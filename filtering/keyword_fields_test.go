@@ -0,0 +1,165 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_KeywordFields(t *testing.T) {
+	kmd := testKeywordMessage(t)
+	i, err := NewInterpreter(kmd)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{name: "lower case", filter: `and = "a" AND or = "b" OR not = "c" AND in = "d"`, want: []string{"and", "or", "not", "in"}},
+		{name: "quoted", filter: "`AND` = \"a\"", want: []string{"AND"}},
+		{name: "quoted all", filter: "`AND` = \"a\" AND `OR` = \"b\" AND `NOT` = \"c\" AND `IN` = \"d\"", want: []string{"AND", "OR", "NOT", "IN"}},
+		{name: "quoted negated", filter: "NOT `IN` = \"a\"", want: []string{"IN"}},
+		{name: "quoted minus negated", filter: "-`NOT` = \"a\"", want: []string{"NOT"}},
+		{name: "quoted nested", filter: "sub.`AND` = \"a\"", want: []string{"sub.AND"}},
+		{name: "quoted message", filter: "`sub`.`OR` = \"a\"", want: []string{"sub.OR"}},
+		{name: "quoted deeply nested", filter: "sub.sub.`IN` = \"a\"", want: []string{"sub.sub.IN"}},
+		{name: "quoted has", filter: "`OR`:\"a\"", want: []string{"OR"}},
+		{name: "quoted in", filter: "`IN` IN [\"a\", \"b\"]", want: []string{"IN"}},
+		{name: "quoted right hand side", filter: "`AND` = `OR`", want: []string{"AND", "OR"}},
+		{name: "quoted any element", filter: "items:{`NOT`: \"a\", in: \"b\"}", want: []string{"items", "NOT", "in"}},
+		{name: "quoted composite", filter: "(`AND` = \"a\" OR `OR` = \"b\")", want: []string{"AND", "OR"}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			got := collectSelectors(x, nil)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected selectors %v but got %v", tt.want, got)
+			}
+
+			// The keyword named fields are rendered with the backticks, so that the filter is parsed back.
+			s, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to render expression: %v", err)
+			}
+			y, err := i.Parse(s)
+			if err != nil {
+				t.Fatalf("failed to parse rendered filter %s: %v", s, err)
+			}
+			defer y.Free()
+			if !x.Equals(y) {
+				t.Errorf("expected rendered filter %s to be equal", s)
+			}
+		})
+	}
+}
+
+// collectSelectors collects the dot separated paths of the field selectors of the expression.
+func collectSelectors(x expr.FilterExpr, out []string) []string {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		for _, e := range xt.Expr {
+			out = collectSelectors(e, out)
+		}
+	case *expr.OrExpr:
+		for _, e := range xt.Expr {
+			out = collectSelectors(e, out)
+		}
+	case *expr.NotExpr:
+		out = collectSelectors(xt.Expr, out)
+	case *expr.CompositeExpr:
+		out = collectSelectors(xt.Expr, out)
+	case *expr.CompareExpr:
+		out = collectSelectors(xt.Left, out)
+		out = collectSelectors(xt.Right, out)
+	case *expr.AnyElementExpr:
+		out = collectSelectors(xt.Filter, out)
+	case *expr.FieldSelectorExpr:
+		var path []string
+		for fs := xt; fs != nil; fs, _ = fs.Traversal.(*expr.FieldSelectorExpr) {
+			path = append(path, string(fs.Field))
+		}
+		out = append(out, strings.Join(path, "."))
+	}
+	return out
+}
+
+// testKeywordMessage builds a message which fields are named with the filtering keywords.
+func testKeywordMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, num int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+		}
+	}
+	msg := func(name string, num int32, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".testkeyword.Keywords"),
+			JsonName: proto.String(name),
+		}
+	}
+
+	// The proto2 syntax allows the field names which differ only in case.
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("testkeyword/keywords.proto"),
+		Package: proto.String("testkeyword"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Keywords"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("and", 1),
+				field("or", 2),
+				field("not", 3),
+				field("in", 4),
+				field("AND", 5),
+				field("OR", 6),
+				field("NOT", 7),
+				field("IN", 8),
+				msg("sub", 9, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL),
+				msg("items", 10, descriptorpb.FieldDescriptorProto_LABEL_REPEATED),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build message file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
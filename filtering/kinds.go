@@ -0,0 +1,143 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// KindPolicy decides whether the values of two protobuf field kinds can be compared with each other.
+// It is used by the restrictions comparing a field with another field, by the function call selector arguments,
+// and by the ValidateExprWithKindPolicy. The enum and message kinds are additionally checked to have matching
+// descriptors, thus the policy needs only to decide on the kinds.
+type KindPolicy func(k1, k2 protoreflect.Kind) bool
+
+// DefaultKindPolicy is the kind policy used by default.
+// The equal kinds are comparable, all the integer kinds are comparable with each other, regardless of their sign,
+// and so are the float and double kinds.
+func DefaultKindPolicy(k1, k2 protoreflect.Kind) bool {
+	if k1 == k2 {
+		return true
+	}
+	switch {
+	case isIntegerKind(k1):
+		return isIntegerKind(k2)
+	case isFloatKind(k1):
+		return isFloatKind(k2)
+	default:
+		return false
+	}
+}
+
+// StrictKindPolicy is a kind policy which, contrary to the DefaultKindPolicy, doesn't allow comparing
+// the signed integer kinds with the unsigned ones.
+func StrictKindPolicy(k1, k2 protoreflect.Kind) bool {
+	if k1 == k2 {
+		return true
+	}
+	switch {
+	case isSignedKind(k1):
+		return isSignedKind(k2)
+	case isUnsignedKind(k1):
+		return isUnsignedKind(k2)
+	case isFloatKind(k1):
+		return isFloatKind(k2)
+	default:
+		return false
+	}
+}
+
+// KindPolicyOpt is an option that sets the kind policy of the interpreter.
+// By default, the DefaultKindPolicy is used.
+func KindPolicyOpt(policy KindPolicy) Option {
+	return func(i *Interpreter) error {
+		if policy == nil {
+			return errors.New("kind policy is not set")
+		}
+		i.kindPolicy = policy
+		return nil
+	}
+}
+
+// IsKindComparable checks if the values of given kinds are comparable with the interpreter kind policy.
+// External converters could use it to share the compatibility rules of the interpreter.
+func (b *Interpreter) IsKindComparable(k1, k2 protoreflect.Kind) bool {
+	if b.kindPolicy == nil {
+		return DefaultKindPolicy(k1, k2)
+	}
+	return b.kindPolicy(k1, k2)
+}
+
+// ValidateExprWithKindPolicy verifies the filter expression x the same way as the ValidateExpr,
+// but checks the kinds of the compared fields with given policy.
+// A nil policy is the DefaultKindPolicy.
+func ValidateExprWithKindPolicy(desc protoreflect.MessageDescriptor, x expr.FilterExpr, policy KindPolicy) error {
+	if desc == nil {
+		return errors.New("message descriptor is not set")
+	}
+	if x == nil {
+		return nil
+	}
+	if policy == nil {
+		policy = DefaultKindPolicy
+	}
+	v := exprValidator{desc: desc, msgInfo: info.MapMsgInfo(desc), kindPolicy: policy}
+	return v.validateFilter(x)
+}
+
+// isSameValueType checks if the values of the field sfd are of the fd type, with the kind policy.
+// The enum and message fields need to have matching descriptors as well.
+// It is the single check of the compared fields, shared by the Interpreter and the ValidateExpr.
+func isSameValueType(policy KindPolicy, fd, sfd FieldDescriptor) bool {
+	if !policy(fd.Kind(), sfd.Kind()) {
+		return false
+	}
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return fd.Enum() != nil && sfd.Enum() != nil && fd.Enum().FullName() == sfd.Enum().FullName()
+	case protoreflect.MessageKind:
+		return fd.Message() != nil && sfd.Message() != nil && fd.Message().FullName() == sfd.Message().FullName()
+	}
+	return true
+}
+
+func isIntegerKind(k protoreflect.Kind) bool {
+	return isSignedKind(k) || isUnsignedKind(k)
+}
+
+func isSignedKind(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return true
+	}
+	return false
+}
+
+func isUnsignedKind(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return true
+	}
+	return false
+}
+
+func isFloatKind(k protoreflect.Kind) bool {
+	return k == protoreflect.FloatKind || k == protoreflect.DoubleKind
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestKindPolicy(t *testing.T) {
+	tc := []struct {
+		k1, k2      protoreflect.Kind
+		def, strict bool
+	}{
+		{k1: protoreflect.StringKind, k2: protoreflect.StringKind, def: true, strict: true},
+		{k1: protoreflect.Int32Kind, k2: protoreflect.Sfixed64Kind, def: true, strict: true},
+		{k1: protoreflect.Int64Kind, k2: protoreflect.Uint64Kind, def: true, strict: false},
+		{k1: protoreflect.Fixed32Kind, k2: protoreflect.Uint64Kind, def: true, strict: true},
+		{k1: protoreflect.FloatKind, k2: protoreflect.DoubleKind, def: true, strict: true},
+		{k1: protoreflect.Int64Kind, k2: protoreflect.DoubleKind, def: false, strict: false},
+		{k1: protoreflect.StringKind, k2: protoreflect.BytesKind, def: false, strict: false},
+	}
+	for _, tt := range tc {
+		if got := DefaultKindPolicy(tt.k1, tt.k2); got != tt.def {
+			t.Errorf("DefaultKindPolicy(%s, %s) = %v, want %v", tt.k1, tt.k2, got, tt.def)
+		}
+		if got := StrictKindPolicy(tt.k1, tt.k2); got != tt.strict {
+			t.Errorf("StrictKindPolicy(%s, %s) = %v, want %v", tt.k1, tt.k2, got, tt.strict)
+		}
+	}
+}
+
+func TestInterpreter_KindPolicy(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	def, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := def.Parse(`i64 = u64`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+
+	if err = ValidateExpr(md, x); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if err = ValidateExprWithKindPolicy(md, x, StrictKindPolicy); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected error %v but got %v", ErrInvalidValue, err)
+	}
+
+	strict, err := NewInterpreter(md, KindPolicyOpt(StrictKindPolicy))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	if strict.IsKindComparable(protoreflect.Int64Kind, protoreflect.Uint64Kind) {
+		t.Error("expected signed and unsigned kinds not to be comparable")
+	}
+	if _, err = strict.Parse(`i64 = u64`); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("expected error %v but got %v", ErrInvalidValue, err)
+	}
+	if _, err = NewInterpreter(md, KindPolicyOpt(nil)); err == nil {
+		t.Error("expected error for nil kind policy")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_NoLeaksOnError(t *testing.T) {
+	if !expr.LeakDetection {
+		t.Skip("the leak detection requires the exprleak build tag")
+	}
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	filters := []string{
+		`i32 IN [1, "a"]`,
+		`u32 IN [1, -1]`,
+		`str IN ["a", 1.5]`,
+		`bool IN [true, 3]`,
+		`bytes IN ["YQ==", 1]`,
+		`enum IN [ONE, FIVE]`,
+		`timestamp IN ["2021-01-01T00:00:00Z", 1]`,
+		`duration IN [1s, "x"]`,
+		`i32 = fn(1)`,
+		`fn(1) = 1`,
+		`i32 = 1 AND str = 2`,
+		`i32 = 1 OR (str = 1 AND i32 = 2)`,
+		`rp_sub:{name: "a", i32: "x"}`,
+	}
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			expr.ResetLeaks()
+			x, err := i.Parse(filter)
+			if err == nil {
+				x.Free()
+				t.Fatalf("expected an error")
+			}
+			for _, l := range expr.Leaks() {
+				t.Errorf("leaked %T acquired at:\n%s", l.Expr, l.Stack)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// MaxComplexityOpt is an option that limits the total complexity of the parsed filter expression.
+// The complexity of an expression is a sum of the complexities of its fields, as defined
+// by the blocky.api.complexity annotation, and of the operators used in the filter.
+// A filter that exceeds the limit fails with the ErrLimitExceeded error.
+func MaxComplexityOpt(n int64) Option {
+	return func(i *Interpreter) error {
+		if n <= 0 {
+			return errors.New("max complexity must be positive")
+		}
+		i.maxComplexity = n
+		return nil
+	}
+}
+
+// MaxDepthOpt is an option that limits the nesting depth of the filter.
+// Each parenthesized expression, function call, array and struct increases the depth by one,
+// i.e. the filter `a = 1 AND (b = 2 OR c = fn(3))` has the depth of 3.
+// A filter that exceeds the limit fails with the ErrLimitExceeded error while it is parsed, before it is interpreted.
+func MaxDepthOpt(n int) Option {
+	return func(i *Interpreter) error {
+		if n <= 0 {
+			return errors.New("max depth must be positive")
+		}
+		i.maxDepth = n
+		return nil
+	}
+}
+
+// MaxFilterLengthOpt is an option that limits the length in bytes of the input filter.
+// A filter that exceeds the limit fails with the ErrLimitExceeded error, before it is parsed.
+func MaxFilterLengthOpt(n int) Option {
+	return func(i *Interpreter) error {
+		if n <= 0 {
+			return errors.New("max filter length must be positive")
+		}
+		i.maxFilterLength = n
+		return nil
+	}
+}
+
+// parserLimitsOption returns the parser option of the depth and length limits, if any,
+// so that the deeply nested filters are rejected while parsing, before they exhaust the stack.
+// Without the limits, the parser applies its defaults, see the parser.MaxDepthOption.
+func (b *Interpreter) parserLimitsOption() parser.ParserOption {
+	if b.maxDepth == 0 && b.maxFilterLength == 0 {
+		return nil
+	}
+	return parser.ParserOptions{MaxDepth: b.maxDepth, MaxLength: b.maxFilterLength}.Option()
+}
+
+// exceedsDepth checks if the nesting depth of the expression x exceeds the max.
+// If so, it returns the position of the first expression that exceeds the limit.
+func exceedsDepth(x *ast.Expr, depth, max int) (token.Position, bool) {
+	if depth > max {
+		return x.Pos, true
+	}
+	for _, seq := range x.Sequences {
+		for _, f := range seq.Factors {
+			for _, t := range f.Terms {
+				switch st := t.Expr.(type) {
+				case *ast.CompositeExpr:
+					if pos, ok := exceedsDepth(st.Expr, depth+1, max); ok {
+						return pos, true
+					}
+				case *ast.RestrictionExpr:
+					if pos, ok := argExceedsDepth(st.Comparable, depth, max); ok {
+						return pos, true
+					}
+					if st.Arg != nil {
+						if pos, ok := argExceedsDepth(st.Arg, depth, max); ok {
+							return pos, true
+						}
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func argExceedsDepth(x ast.ArgExpr, depth, max int) (token.Position, bool) {
+	switch at := x.(type) {
+	case *ast.CompositeExpr:
+		return exceedsDepth(at.Expr, depth+1, max)
+	case *ast.RestrictionExpr:
+		if pos, ok := argExceedsDepth(at.Comparable, depth, max); ok {
+			return pos, true
+		}
+		if at.Arg != nil {
+			return argExceedsDepth(at.Arg, depth, max)
+		}
+	case *ast.FunctionCall:
+		if depth+1 > max {
+			return at.Pos, true
+		}
+		if at.ArgList == nil {
+			return 0, false
+		}
+		for _, arg := range at.ArgList.Args {
+			if pos, ok := argExceedsDepth(arg, depth+1, max); ok {
+				return pos, true
+			}
+		}
+	case *ast.ArrayExpr:
+		if depth+1 > max {
+			return at.LBracket, true
+		}
+		for _, elem := range at.Elements {
+			if pos, ok := argExceedsDepth(elem, depth+1, max); ok {
+				return pos, true
+			}
+		}
+	case *ast.BinaryExpr:
+		if pos, ok := argExceedsDepth(at.Left, depth, max); ok {
+			return pos, true
+		}
+		return argExceedsDepth(at.Right, depth, max)
+	case *ast.StructExpr:
+		if depth+1 > max {
+			return at.LBrace, true
+		}
+		for _, elem := range at.Elements {
+			if pos, ok := argExceedsDepth(elem.Value, depth+1, max); ok {
+				return pos, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterpreter_Limits(t *testing.T) {
+	tc := []struct {
+		name   string
+		opt    Option
+		filter string
+		isErr  bool
+	}{
+		{
+			name:   "filter length within limit",
+			opt:    MaxFilterLengthOpt(10),
+			filter: `i32 = 1`,
+		},
+		{
+			name:   "filter length exceeded",
+			opt:    MaxFilterLengthOpt(10),
+			filter: `i32 = 1 AND i64 = 2`,
+			isErr:  true,
+		},
+		{
+			name:   "depth within limit",
+			opt:    MaxDepthOpt(2),
+			filter: `i32 = 1 AND (i64 = 2 OR u32 = 3)`,
+		},
+		{
+			name:   "depth exceeded",
+			opt:    MaxDepthOpt(2),
+			filter: `i32 = 1 AND (i64 = 2 OR (u32 = 3 AND u64 = 4))`,
+			isErr:  true,
+		},
+		{
+			name:   "depth exceeded by array",
+			opt:    MaxDepthOpt(1),
+			filter: `i32 IN [1, 2]`,
+			isErr:  true,
+		},
+		{
+			name:   "depth exceeded while parsing",
+			opt:    MaxDepthOpt(2),
+			filter: strings.Repeat("(", 100000),
+			isErr:  true,
+		},
+		{
+			name:   "complexity within limit",
+			opt:    MaxComplexityOpt(10),
+			filter: `i32 = 1`,
+		},
+		{
+			name:   "complexity exceeded",
+			opt:    MaxComplexityOpt(10),
+			filter: `i32_complexity = 1`,
+			isErr:  true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, tt.opt)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.isErr {
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Fatalf("expected error %v but got %v", ErrLimitExceeded, err)
+				}
+				var fe *FilterError
+				if !errors.As(err, &fe) || fe.Code != ErrorCodeLimitExceeded {
+					t.Fatalf("expected FilterError with code %s but got %v", ErrorCodeLimitExceeded, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			x.Free()
+		})
+	}
+
+	if _, err := NewInterpreter(md, MaxDepthOpt(0)); err == nil {
+		t.Fatalf("expected error for non positive limit")
+	}
+}
@@ -0,0 +1,472 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint provides the static analysis of the filters.
+// The Linter inspects the parsed filter against the message descriptor and reports the issues
+// of the valid, but suspicious filters, i.e. the contradictory restrictions which never match,
+// the duplicated restrictions, the comparisons with the undefined enum values or the leading wildcards.
+// Each issue has its lint code and the position in the filter.
+// It is meant for the CI pipelines validating the stored filters, i.e. the saved searches.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// Code is the code of the lint issue.
+type Code string
+
+// The codes of the lint issues.
+const (
+	// AlwaysTrue is the code of the expression which matches everything, i.e.: a = 1 OR NOT a = 1.
+	AlwaysTrue Code = "always-true"
+	// AlwaysFalse is the code of the expression which never matches, i.e.: a = 1 AND a = 2.
+	AlwaysFalse Code = "always-false"
+	// Duplicate is the code of the restriction repeated within the same conjunction or disjunction.
+	Duplicate Code = "duplicate"
+	// UnknownEnumValue is the code of the comparison of an enum field with the value which is not defined.
+	UnknownEnumValue Code = "unknown-enum-value"
+	// LeadingWildcard is the code of the non-selective string search with the leading wildcard, i.e.: name = "*foo".
+	LeadingWildcard Code = "leading-wildcard"
+)
+
+// Issue is a single issue of the filter.
+type Issue struct {
+	// Code is the lint code of the issue.
+	Code Code
+
+	// Pos is the position of the issue in the filter.
+	Pos token.Position
+
+	// Message is a human-readable description of the issue.
+	Message string
+}
+
+// String returns the string representation of the issue, i.e.: "12: always-false: i32 = 2 contradicts i32 = 1".
+func (i Issue) String() string {
+	return fmt.Sprintf("%d: %s: %s", i.Pos, i.Code, i.Message)
+}
+
+// Option is an option of the Linter.
+type Option func(*Linter) error
+
+// DisableOpt disables reporting the issues with the codes.
+func DisableOpt(codes ...Code) Option {
+	return func(l *Linter) error {
+		if l.disabled == nil {
+			l.disabled = make(map[Code]bool)
+		}
+		for _, c := range codes {
+			l.disabled[c] = true
+		}
+		return nil
+	}
+}
+
+// ParserOptionsOpt sets the options of the parser used by the Lint, i.e. the parser.CustomComparatorsOption,
+// which should match the options of the interpreter.
+func ParserOptionsOpt(opts ...parser.ParserOption) Option {
+	return func(l *Linter) error {
+		l.parserOpts = append(l.parserOpts, opts...)
+		return nil
+	}
+}
+
+// Linter reports the issues of the filters for a message.
+// It is safe for concurrent use.
+type Linter struct {
+	md         protoreflect.MessageDescriptor
+	disabled   map[Code]bool
+	parserOpts []parser.ParserOption
+}
+
+// New creates a new Linter for the input message descriptor.
+func New(md protoreflect.MessageDescriptor, opts ...Option) (*Linter, error) {
+	if md == nil {
+		return nil, errors.New("lint: nil message descriptor")
+	}
+	l := &Linter{md: md}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Lint parses the filter and returns its issues.
+// The syntax errors of the filter are returned as the error, wrapping the parser.ErrInvalidFilterSyntax.
+func (l *Linter) Lint(filter string) ([]Issue, error) {
+	pf, err := parser.NewParser(filter, l.parserOpts...).Parse()
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Free()
+	return l.LintExpr(pf.Expr), nil
+}
+
+// LintExpr returns the issues of the parsed filter expression.
+// A nil expression has no issues.
+func (l *Linter) LintExpr(x *ast.Expr) []Issue {
+	if x == nil {
+		return nil
+	}
+	var issues []Issue
+	l.lintExpr(x, &issues)
+	return issues
+}
+
+func (l *Linter) report(issues *[]Issue, code Code, pos token.Position, format string, args ...any) {
+	if l.disabled[code] {
+		return
+	}
+	*issues = append(*issues, Issue{Code: code, Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// lintExpr lints the expression, which sequences and factors form a new conjunction.
+func (l *Linter) lintExpr(x *ast.Expr, issues *[]Issue) {
+	c := conjunction{terms: make(map[string]bool), fields: make(map[string]*fieldRange)}
+	l.lintConjunction(x, &c, issues)
+}
+
+// lintConjunction lints the factors of the expression as the members of the conjunction c.
+// The factors of the composite expressions are flattened into the same conjunction, i.e.: a = 1 AND (b = 2 AND a = 1).
+func (l *Linter) lintConjunction(x *ast.Expr, c *conjunction, issues *[]Issue) {
+	for _, seq := range x.Sequences {
+		for _, f := range seq.Factors {
+			if len(f.Terms) != 1 {
+				l.lintDisjunction(f, issues)
+				continue
+			}
+			t := f.Terms[0]
+			if ce, ok := t.Expr.(*ast.CompositeExpr); ok && !t.HasNegation() && ce.Expr != nil {
+				l.lintConjunction(ce.Expr, c, issues)
+				continue
+			}
+			l.lintTerm(t, issues)
+			l.addConjunct(c, t, issues)
+		}
+	}
+}
+
+// lintDisjunction lints the terms of the factor joined with the OR operator.
+func (l *Linter) lintDisjunction(f *ast.FactorExpr, issues *[]Issue) {
+	terms := make(map[string]bool, len(f.Terms))
+	for _, t := range f.Terms {
+		l.lintTerm(t, issues)
+
+		key, neg := t.Expr.String(), t.HasNegation()
+		if seen, ok := terms[key]; ok {
+			if seen == neg {
+				l.report(issues, Duplicate, t.Pos, "%s is repeated in the disjunction", t.String())
+			} else {
+				l.report(issues, AlwaysTrue, t.Pos, "%s is joined with its negation, thus the disjunction is always true", t.String())
+			}
+			continue
+		}
+		terms[key] = neg
+	}
+}
+
+// lintTerm lints the inner expression of the term.
+func (l *Linter) lintTerm(t *ast.TermExpr, issues *[]Issue) {
+	switch xt := t.Expr.(type) {
+	case *ast.RestrictionExpr:
+		l.lintRestriction(xt, issues)
+	case *ast.CompositeExpr:
+		if xt.Expr != nil {
+			l.lintExpr(xt.Expr, issues)
+		}
+	}
+}
+
+// lintRestriction checks the values of the restriction.
+func (l *Linter) lintRestriction(r *ast.RestrictionExpr, issues *[]Issue) {
+	if r.Comparator == nil || r.Arg == nil {
+		return
+	}
+	vd := l.resolveField(r.Comparable)
+	if vd == nil {
+		return
+	}
+
+	switch vd.Kind() {
+	case protoreflect.StringKind:
+		sl, ok := literal(r.Arg).(*ast.StringLiteral)
+		if !ok || len(sl.Value) < 2 || !strings.HasPrefix(sl.Value, "*") {
+			return
+		}
+		switch r.Comparator.Type {
+		case ast.EQ, ast.NE, ast.HAS, ast.NOT_HAS:
+			l.report(issues, LeadingWildcard, sl.Pos, "%s searches with the leading wildcard, which cannot use an index", r.String())
+		}
+	case protoreflect.EnumKind:
+		values := []ast.ValueExpr{literal(r.Arg)}
+		if ae, ok := r.Arg.(*ast.ArrayExpr); ok {
+			values = values[:0]
+			for _, e := range ae.Elements {
+				values = append(values, literal(e))
+			}
+		}
+		for _, v := range values {
+			if v == nil || v.UnquotedString() == "null" {
+				continue
+			}
+			if _, ok := enumNumber(vd.Enum(), v.UnquotedString()); !ok {
+				l.report(issues, UnknownEnumValue, v.Position(), "%s is not a value of the enum %s", v.String(), vd.Enum().FullName())
+			}
+		}
+	}
+}
+
+// conjunction tracks the members of a conjunction.
+type conjunction struct {
+	// terms are the negation flags of the terms by their string representation.
+	terms map[string]bool
+	// fields are the ranges of the singular fields restricted in the conjunction, by their selectors.
+	fields map[string]*fieldRange
+}
+
+// fieldRange is the range of the values allowed by the restrictions of the field.
+type fieldRange struct {
+	// eq is the normalized value of the first equality restriction of a non-numeric field, and eqText is its text.
+	eq, eqText string
+
+	// lower and upper are the bounds of a numeric field, along with the texts of the restrictions which set them.
+	lower, upper         *float64
+	lowerIncl, upperIncl bool
+	lowerText, upperText string
+
+	// contradicted is set after the first contradiction of the field is reported.
+	contradicted bool
+}
+
+// addConjunct adds the term to the conjunction and reports the duplicates and contradictions.
+func (l *Linter) addConjunct(c *conjunction, t *ast.TermExpr, issues *[]Issue) {
+	key, neg := t.Expr.String(), t.HasNegation()
+	if seen, ok := c.terms[key]; ok {
+		if seen == neg {
+			l.report(issues, Duplicate, t.Pos, "%s is repeated in the conjunction", t.String())
+		} else {
+			l.report(issues, AlwaysFalse, t.Pos, "%s is joined with its negation, thus the conjunction is always false", t.String())
+		}
+		return
+	}
+	c.terms[key] = neg
+
+	r, ok := t.Expr.(*ast.RestrictionExpr)
+	if !ok || neg || r.Comparator == nil {
+		return
+	}
+	vd := l.resolveField(r.Comparable)
+	if vd == nil || vd.IsList() || vd.IsMap() {
+		return
+	}
+	v := literal(r.Arg)
+	if v == nil {
+		return
+	}
+
+	field := r.Comparable.String()
+	fr := c.fields[field]
+	if fr == nil {
+		fr = &fieldRange{}
+		c.fields[field] = fr
+	}
+	if fr.contradicted {
+		return
+	}
+
+	if isNumeric(vd.Kind()) {
+		n, err := strconv.ParseFloat(v.UnquotedString(), 64)
+		if err != nil {
+			return
+		}
+		if msg, ok := fr.restrict(r.Comparator.Type, n, r.String()); !ok {
+			fr.contradicted = true
+			l.report(issues, AlwaysFalse, r.Pos, "%s, thus the conjunction is always false", msg)
+		}
+		return
+	}
+
+	if r.Comparator.Type != ast.EQ {
+		return
+	}
+	eq, ok := normalizedValue(vd, v)
+	if !ok {
+		return
+	}
+	if fr.eqText == "" {
+		fr.eq, fr.eqText = eq, r.String()
+		return
+	}
+	if fr.eq != eq {
+		fr.contradicted = true
+		l.report(issues, AlwaysFalse, r.Pos, "%s contradicts %s, thus the conjunction is always false", r.String(), fr.eqText)
+	}
+}
+
+// restrict narrows the range with the comparison, and returns false with the message if the range becomes empty.
+func (fr *fieldRange) restrict(cmp ast.ComparatorType, n float64, text string) (string, bool) {
+	switch cmp {
+	case ast.EQ:
+		fr.setLower(n, true, text)
+		fr.setUpper(n, true, text)
+	case ast.GT:
+		fr.setLower(n, false, text)
+	case ast.GE:
+		fr.setLower(n, true, text)
+	case ast.LT:
+		fr.setUpper(n, false, text)
+	case ast.LE:
+		fr.setUpper(n, true, text)
+	default:
+		return "", true
+	}
+	if fr.lower == nil || fr.upper == nil {
+		return "", true
+	}
+	if *fr.lower < *fr.upper || (*fr.lower == *fr.upper && fr.lowerIncl && fr.upperIncl) {
+		return "", true
+	}
+	other := fr.lowerText
+	if other == text {
+		other = fr.upperText
+	}
+	return fmt.Sprintf("%s contradicts %s", text, other), false
+}
+
+func (fr *fieldRange) setLower(n float64, incl bool, text string) {
+	if fr.lower == nil || n > *fr.lower || (n == *fr.lower && !incl) {
+		fr.lower, fr.lowerIncl, fr.lowerText = &n, incl, text
+	}
+}
+
+func (fr *fieldRange) setUpper(n float64, incl bool, text string) {
+	if fr.upper == nil || n < *fr.upper || (n == *fr.upper && !incl) {
+		fr.upper, fr.upperIncl, fr.upperText = &n, incl, text
+	}
+}
+
+// normalizedValue returns the value of the equality restriction of the field, comparable with other values.
+// The values which equality cannot be decided statically, i.e. the string searches, are not normalized.
+func normalizedValue(fd protoreflect.FieldDescriptor, v ast.ValueExpr) (string, bool) {
+	s := v.UnquotedString()
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatBool(b), true
+	case protoreflect.EnumKind:
+		n, ok := enumNumber(fd.Enum(), s)
+		if !ok {
+			return "", false
+		}
+		return strconv.FormatInt(int64(n), 10), true
+	case protoreflect.StringKind:
+		if strings.Contains(s, "*") || s == "null" {
+			return "", false
+		}
+		// The case-insensitive comparisons of the interpreter would match the values of different cases.
+		return strings.ToLower(s), true
+	}
+	return "", false
+}
+
+// enumNumber returns the number of the enum value by its name or number.
+// The names are matched case-insensitively, as the interpreter could accept such with the lenient enums.
+func enumNumber(ed protoreflect.EnumDescriptor, s string) (protoreflect.EnumNumber, bool) {
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return protoreflect.EnumNumber(n), ed.Values().ByNumber(protoreflect.EnumNumber(n)) != nil
+	}
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		if strings.EqualFold(string(values.Get(i).Name()), s) {
+			return values.Get(i).Number(), true
+		}
+	}
+	return 0, false
+}
+
+// literal returns the value of the argument, if it is a single literal.
+func literal(arg ast.AnyExpr) ast.ValueExpr {
+	me, ok := arg.(*ast.MemberExpr)
+	if !ok || len(me.Fields) > 0 {
+		return nil
+	}
+	return me.Value
+}
+
+// resolveField returns the descriptor of the field value selected by the comparable,
+// i.e. the map value descriptor of the map key selector, or nil if it is not a known field.
+func (l *Linter) resolveField(c ast.ComparableExpr) protoreflect.FieldDescriptor {
+	me, ok := c.(*ast.MemberExpr)
+	if !ok || me.Value == nil {
+		return nil
+	}
+	names := make([]string, 0, len(me.Fields)+1)
+	names = append(names, me.Value.UnquotedString())
+	for _, f := range me.Fields {
+		names = append(names, f.UnquotedString())
+	}
+
+	md := l.md
+	for i := 0; i < len(names); i++ {
+		fields := md.Fields()
+		fd := fields.ByName(protoreflect.Name(names[i]))
+		if fd == nil {
+			fd = fields.ByJSONName(names[i])
+		}
+		last := i == len(names)-1
+		switch {
+		case fd == nil:
+			return nil
+		case last:
+			return fd
+		case fd.IsMap():
+			// The next name is the map key.
+			if i+1 == len(names)-1 {
+				return fd.MapValue()
+			}
+			return nil
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsList():
+			md = fd.Message()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isNumeric(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	l, err := New(new(testpb.Message).ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("failed to create linter: %v", err)
+	}
+
+	type issue struct {
+		code Code
+		pos  token.Position
+	}
+	tc := []struct {
+		name   string
+		filter string
+		want   []issue
+	}{
+		{name: "valid", filter: `i32 > 1 AND i32 < 10 AND str = "foo*" AND enum = ONE`},
+		{name: "empty", filter: ``},
+		{name: "conflicting equality", filter: `str = "a" AND str = "b"`, want: []issue{{AlwaysFalse, 14}}},
+		{name: "case-insensitive equality", filter: `str = "a" AND str = "A"`},
+		{name: "conflicting enum", filter: `enum = ONE AND enum = 2`, want: []issue{{AlwaysFalse, 15}}},
+		{name: "same enum", filter: `enum = ONE AND enum = 1`},
+		{name: "empty range", filter: `i32 > 10 AND i32 < 5`, want: []issue{{AlwaysFalse, 13}}},
+		{name: "exclusive bound", filter: `i64 >= 5 i64 < 5`, want: []issue{{AlwaysFalse, 9}}},
+		{name: "equality out of range", filter: `double = 1.5 AND double > 2`, want: []issue{{AlwaysFalse, 17}}},
+		{name: "nested conjunction", filter: `i32 = 1 AND (str = "a" AND i32 = 1)`, want: []issue{{Duplicate, 27}}},
+		{name: "negation in conjunction", filter: `bool = true AND NOT bool = true`, want: []issue{{AlwaysFalse, 16}}},
+		{name: "negation in disjunction", filter: `i32 = 1 OR -i32 = 1`, want: []issue{{AlwaysTrue, 11}}},
+		{name: "duplicate in disjunction", filter: `i32 = 1 OR i32 = 2 OR i32 = 1`, want: []issue{{Duplicate, 22}}},
+		{name: "unknown enum", filter: `enum = FOUR`, want: []issue{{UnknownEnumValue, 7}}},
+		{name: "unknown enum number", filter: `sub.enum = 9`, want: []issue{{UnknownEnumValue, 11}}},
+		{name: "unknown enum in list", filter: `enum IN [ONE, "five"]`, want: []issue{{UnknownEnumValue, 14}}},
+		{name: "lenient enum", filter: `enum = "one"`},
+		{name: "leading wildcard", filter: `str = "*foo"`, want: []issue{{LeadingWildcard, 6}}},
+		{name: "nested leading wildcard", filter: `i32 = 1 OR (sub.name != "*foo*")`, want: []issue{{LeadingWildcard, 24}}},
+		{name: "map value", filter: `map_str_str."k" = "a" AND map_str_str."k" = "b"`, want: []issue{{AlwaysFalse, 26}}},
+		{name: "different map keys", filter: `map_str_str."k" = "a" AND map_str_str."l" = "b"`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := l.Lint(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []issue
+			for _, is := range issues {
+				got = append(got, issue{is.Code, is.Pos})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected issues %v but got %v", tt.want, issues)
+			}
+		})
+	}
+}
+
+func TestLinter_Options(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	l, err := New(md, DisableOpt(Duplicate))
+	if err != nil {
+		t.Fatalf("failed to create linter: %v", err)
+	}
+	issues, err := l.Lint(`i32 = 1 AND i32 = 1 AND i32 = 2`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Code != AlwaysFalse {
+		t.Fatalf("expected single always-false issue but got %v", issues)
+	}
+	if want := "24: always-false: i32 = 2 contradicts i32 = 1, thus the conjunction is always false"; issues[0].String() != want {
+		t.Errorf("expected %q but got %q", want, issues[0].String())
+	}
+
+	if _, err = l.Lint(`i32 = (`); !errors.Is(err, parser.ErrInvalidFilterSyntax) {
+		t.Errorf("expected syntax error but got %v", err)
+	}
+
+	l, err = New(md, ParserOptionsOpt(parser.CustomComparatorsOption("~")))
+	if err != nil {
+		t.Fatalf("failed to create linter: %v", err)
+	}
+	if issues, err = l.Lint(`str ~ "a%" AND str ~ "a%"`); err != nil || len(issues) != 1 || issues[0].Code != Duplicate {
+		t.Errorf("expected duplicate issue but got %v, %v", issues, err)
+	}
+
+	if _, err = New(nil); err == nil {
+		t.Errorf("expected error of the nil message descriptor")
+	}
+}
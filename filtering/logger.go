@@ -0,0 +1,105 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// LoggerOpt is an option that logs the failures of the interpreter with the structured logger l,
+// so that no ErrHandlerOpt adapter is needed. It logs:
+//   - the filters that fail to parse, with the message name, the redacted filter, and the error code, position and message,
+//   - the errors returned by the declared functions, with the function name and position,
+//   - the failures of the cache set by the CacheOpt, i.e. the expressions that could not be encoded.
+//
+// The failures caused by the invalid filters are logged at the info level, the cache failures at the warn level,
+// and the internal errors at the error level.
+// The filters are redacted with the RedactFilter, as they may contain sensitive values.
+// For the logging of each parsed filter, along with its complexity and duration, see the filteringlog package.
+func LoggerOpt(l *slog.Logger) Option {
+	return func(i *Interpreter) error {
+		if l == nil {
+			return errors.New("logger is not set")
+		}
+		i.logger = l
+		return nil
+	}
+}
+
+// logParseError logs the error of parsing the filter.
+func (b *Interpreter) logParseError(filter string, err error) {
+	level := slog.LevelInfo
+	if errors.Is(err, ErrInternal) {
+		level = slog.LevelError
+	}
+	ctx := context.Background()
+	if !b.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs,
+		slog.String("message", string(b.msg.FullName())),
+		slog.String("filter", RedactFilter(filter)),
+	)
+	var fe *FilterError
+	if errors.As(err, &fe) {
+		attrs = append(attrs, slog.String("error_code", fe.Code.String()), slog.Int("error_position", int(fe.Pos)))
+	}
+	attrs = append(attrs, slog.String("error", err.Error()))
+	b.logger.LogAttrs(ctx, level, "filter parse failed", attrs...)
+}
+
+// logCacheError logs the error of storing or loading the expression of the filter in the cache set by the CacheOpt.
+func (b *Interpreter) logCacheError(filter string, err error) {
+	if b.logger == nil {
+		return
+	}
+	ctx := context.Background()
+	if !b.logger.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	b.logger.LogAttrs(ctx, slog.LevelWarn, "filter cache failed",
+		slog.String("message", string(b.msg.FullName())),
+		slog.String("filter", RedactFilter(filter)),
+		slog.String("error", err.Error()),
+	)
+}
+
+// logFunctionError logs the error returned by the function called in the filter.
+func (b *Interpreter) logFunctionError(pctx *ParseContext, x *ast.FunctionCall, err error) {
+	if b.logger == nil {
+		return
+	}
+	level := slog.LevelInfo
+	if errors.Is(err, ErrInternal) {
+		level = slog.LevelError
+	}
+	ctx := context.Background()
+	if !b.logger.Enabled(ctx, level) {
+		return
+	}
+	b.logger.LogAttrs(ctx, level, "filter function call failed",
+		slog.String("message", string(b.msg.FullName())),
+		slog.String("filter", RedactFilter(pctx.filter)),
+		slog.String("function", x.JoinedName()),
+		slog.Int("position", int(x.Position())),
+		slog.String("error", err.Error()),
+	)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestLoggerOpt(t *testing.T) {
+	failing := &FunctionCallDeclaration{
+		Name: FunctionName{PkgName: "test", Name: "Lookup"},
+		Arguments: []*FunctionCallArgumentDeclaration{
+			{ArgName: "key", FieldKind: protoreflect.StringKind},
+		},
+		Returning: &FunctionCallReturningDeclaration{FieldKind: protoreflect.StringKind},
+		CallFn: func(args ...expr.FilterExpr) (FunctionCallArgument, error) {
+			return FunctionCallArgument{}, errors.New("lookup unavailable")
+		},
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   []map[string]any
+	}{
+		{
+			name:   "valid",
+			filter: `str = "secret"`,
+		},
+		{
+			name:   "invalid value",
+			filter: `i32 = "secret"`,
+			want: []map[string]any{{
+				"level":          "INFO",
+				"msg":            "filter parse failed",
+				"message":        "testpb.Message",
+				"filter":         `i32 = "***"`,
+				"error_code":     "INVALID_VALUE",
+				"error_position": float64(6),
+			}},
+		},
+		{
+			name:   "function call error",
+			filter: `str = test.Lookup("secret")`,
+			want: []map[string]any{
+				{
+					"level":    "INFO",
+					"msg":      "filter function call failed",
+					"filter":   `str = test.Lookup("***")`,
+					"function": "test.Lookup",
+					"position": float64(6),
+					"error":    "lookup unavailable",
+				},
+				{
+					"msg": "filter parse failed",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			i, err := NewInterpreter(md, LoggerOpt(l), RegisterFunction(failing))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if err == nil {
+				x.Free()
+			}
+
+			var lines []string
+			if s := strings.TrimSpace(buf.String()); s != "" {
+				lines = strings.Split(s, "\n")
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("expected %d log entries but got %d: %s", len(tt.want), len(lines), buf.String())
+			}
+			for j, want := range tt.want {
+				var got map[string]any
+				if err = json.Unmarshal([]byte(lines[j]), &got); err != nil {
+					t.Fatalf("failed to decode log entry: %v", err)
+				}
+				for k, v := range want {
+					if got[k] != v {
+						t.Errorf("entry %d: expected %s=%v but got %v", j, k, v, got[k])
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := NewInterpreter(md, LoggerOpt(nil)); err == nil {
+		t.Error("expected error for nil logger")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// macro is a named restriction registered with the MacroOpt.
+type macro struct {
+	// name is the restriction as registered, i.e.: is:active.
+	name string
+	// expansion is the filter the restriction expands into.
+	expansion string
+	// expr is the parsed expansion, shared by all the parses.
+	expr *ast.Expr
+	// grouped is true if the expansion is not a single restriction, and needs to be grouped as a composite.
+	grouped bool
+}
+
+// MacroOpt is an option that registers a named macro, a restriction which expands into the given filter
+// before it is interpreted, i.e.: MacroOpt("is:active", "state = \"ACTIVE\" AND delete_time = null"),
+// or MacroOpt("mine()", "owner = \"users/me\"").
+// The name is any single restriction, matched by its normalized form, thus 'is:active' matches 'is : active'.
+// An expansion of multiple restrictions is grouped, as if it was surrounded by the parentheses.
+// The expansion may contain other macros, which are expanded recursively, where a cyclic expansion is an error.
+// The errors of the expansion are reported at the position of the macro in the original filter.
+// A macro takes precedence over the function and field of the same name.
+func MacroOpt(name, expansion string) Option {
+	return func(i *Interpreter) error {
+		key, err := macroKey(name)
+		if err != nil {
+			return err
+		}
+		if _, ok := i.macros[key]; ok {
+			return fmt.Errorf("macro %q is already registered", name)
+		}
+
+		var p parser.Parser
+		p.Reset(expansion)
+		pf, err := p.Parse()
+		if err != nil {
+			return fmt.Errorf("invalid expansion of the macro %q: %w", name, err)
+		}
+		if pf.Expr == nil {
+			return fmt.Errorf("macro %q has an empty expansion", name)
+		}
+
+		if i.macros == nil {
+			i.macros = make(map[string]*macro)
+		}
+		_, single := singleTerm(pf.Expr)
+		i.macros[key] = &macro{name: name, expansion: expansion, expr: pf.Expr, grouped: !single}
+		return nil
+	}
+}
+
+// macroKey returns the normalized form of the macro name.
+func macroKey(name string) (string, error) {
+	var p parser.Parser
+	p.Reset(name)
+	pf, err := p.Parse()
+	if err != nil {
+		return "", fmt.Errorf("invalid macro name %q: %w", name, err)
+	}
+	defer pf.Free()
+
+	term, ok := singleTerm(pf.Expr)
+	if !ok {
+		return "", fmt.Errorf("invalid macro name %q: %w", name, errors.New("name must be a single restriction"))
+	}
+	r, ok := term.Expr.(*ast.RestrictionExpr)
+	if !ok || term.UnaryOp != "" {
+		return "", fmt.Errorf("invalid macro name %q: %w", name, errors.New("name must be a single restriction"))
+	}
+	return r.String(), nil
+}
+
+// singleTerm returns the only term of the expression, if it consists of a single term.
+func singleTerm(x *ast.Expr) (*ast.TermExpr, bool) {
+	if x == nil || len(x.Sequences) != 1 || len(x.Sequences[0].Factors) != 1 || len(x.Sequences[0].Factors[0].Terms) != 1 {
+		return nil, false
+	}
+	return x.Sequences[0].Factors[0].Terms[0], true
+}
+
+// findMacro finds the macro matching the restriction.
+func (b *Interpreter) findMacro(x *ast.RestrictionExpr) (*macro, bool) {
+	if len(b.macros) == 0 {
+		return nil, false
+	}
+	m, ok := b.macros[x.String()]
+	return m, ok
+}
+
+// expandMacro interprets the expansion of the macro in place of the restriction.
+func (b *Interpreter) expandMacro(ctx *ParseContext, x *ast.RestrictionExpr, m *macro) (TryParseValueResult, error) {
+	for j, name := range ctx.macros {
+		if name != m.name {
+			continue
+		}
+		// The macro is already being expanded.
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Pos
+			res.ErrMsg = fmt.Sprintf("macro cycle: %s -> %s", strings.Join(ctx.macros[j:], " -> "), m.name)
+		}
+		return res, ErrInvalidValue
+	}
+	ctx.macros = append(ctx.macros, m.name)
+	defer func() { ctx.macros = ctx.macros[:len(ctx.macros)-1] }()
+
+	// The positions of the expansion are relative to the macro filter, map them to the macro restriction.
+	errHandler := ctx.ErrHandler
+	if errHandler != nil {
+		ctx.ErrHandler = func(_ token.Position, msg string) {
+			errHandler(x.Pos, fmt.Sprintf("macro %s: %s", m.name, msg))
+		}
+		defer func() { ctx.ErrHandler = errHandler }()
+	}
+
+	res, err := b.HandleExpr(ctx, m.expr)
+	if err != nil {
+		if errHandler != nil {
+			res.ErrPos = x.Pos
+			res.ErrMsg = fmt.Sprintf("macro %s: %s", m.name, res.ErrMsg)
+		}
+		return res, err
+	}
+	if !m.grouped {
+		return res, nil
+	}
+
+	// The expansion is grouped as if it was surrounded by the parentheses.
+	cps := expr.AcquireCompositeExpr()
+	cps.Expr = res.Expr
+	return TryParseValueResult{Expr: cps, IsIndirect: res.IsIndirect}, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_Macros(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md,
+		MacroOpt("is:active", `bool = true AND i32 > 0`),
+		MacroOpt("mine()", `name = "users/me"`),
+		MacroOpt("is:mine_active", `mine() AND is:active`),
+		MacroOpt("is:broken", `i32 = "foo"`),
+		MacroOpt("is:cycle", `str = "a" OR is:loop`),
+		MacroOpt("is:loop", `is:cycle`),
+	)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   string
+		err    error
+		msg    string
+	}{
+		{name: "restriction", filter: `is:active`, want: `is:active`},
+		{name: "spaced", filter: `is : active`, want: `is:active`},
+		{name: "function", filter: `mine()`, want: `mine()`},
+		{name: "negated", filter: `NOT is:active`, want: `NOT is:active`},
+		{name: "nested", filter: `is:mine_active`, want: `is:mine_active`},
+		{name: "invalid expansion", filter: `str = "x" AND is:broken`, err: ErrInvalidValue, msg: "macro is:broken:"},
+		{name: "cycle", filter: `is:cycle`, err: ErrInvalidValue, msg: "macro cycle: is:cycle -> is:loop -> is:cycle"},
+	}
+
+	expanded := map[string]string{
+		`is:active`:      `(bool = true AND i32 > 0)`,
+		`mine()`:         `name = "users/me"`,
+		`NOT is:active`:  `NOT (bool = true AND i32 > 0)`,
+		`is:mine_active`: `(name = "users/me" AND (bool = true AND i32 > 0))`,
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				var fe *FilterError
+				if !errors.As(err, &fe) || !strings.Contains(fe.Msg, tt.msg) {
+					t.Fatalf("expected error message containing %q but got %v", tt.msg, err)
+				}
+				if !strings.HasPrefix(tt.filter[fe.Pos:], "is:") {
+					t.Errorf("expected error position at the macro but got %d", fe.Pos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			want, err := i.Parse(expanded[tt.want])
+			if err != nil {
+				t.Fatalf("failed to parse the expected filter: %v", err)
+			}
+			defer want.Free()
+
+			if !x.Equals(want) {
+				t.Errorf("expected %v but got %v", want, x)
+			}
+		})
+	}
+}
+
+func TestMacroOpt_Invalid(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	tc := []struct {
+		name      string
+		macroName string
+		expansion string
+	}{
+		{name: "empty expansion", macroName: "is:active", expansion: ""},
+		{name: "invalid expansion", macroName: "is:active", expansion: "bool = ("},
+		{name: "compound name", macroName: "is:active AND is:on", expansion: "bool = true"},
+		{name: "negated name", macroName: "NOT is:active", expansion: "bool = true"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInterpreter(md, MacroOpt(tt.macroName, tt.expansion)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := NewInterpreter(md, MacroOpt("is:on", "bool = true"), MacroOpt("is : on", "bool = false")); err == nil {
+		t.Error("expected duplicated macro error")
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+// MatchAllOpt is an option that makes the empty filter result in the expr.MatchAllExpr,
+// instead of the nil expression, so that the callers and translators handle all the filters uniformly.
+// The ParseWithSearch with an empty filter and a non-empty query results in the search expression only.
+func MatchAllOpt() Option {
+	return func(i *Interpreter) error {
+		i.matchAll = true
+		return nil
+	}
+}
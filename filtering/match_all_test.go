@@ -0,0 +1,65 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestMatchAllOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, MatchAllOpt(), SearchableFieldsOpt("name"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(``)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := x.(*expr.MatchAllExpr); !ok {
+		t.Fatalf("expected MatchAllExpr but got %T", x)
+	}
+	x.Free()
+
+	x, err = i.ParseWithSearch(``, `foo`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := x.(*expr.SearchExpr); !ok {
+		t.Errorf("expected SearchExpr but got %T", x)
+	}
+	x.Free()
+
+	x, err = i.Parse(`i32 = 1`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expr.IsMatchAll(x) {
+		t.Error("expected non-empty filter not to match all")
+	}
+	x.Free()
+
+	i, err = NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	if x, err = i.Parse(``); err != nil || x != nil {
+		t.Errorf("expected nil expression without the option but got %v, %v", x, err)
+	}
+}
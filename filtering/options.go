@@ -0,0 +1,453 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/cache"
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/scanner"
+)
+
+// InterpreterOptions is a plain structure alternative to the functional options of the Interpreter.
+// It could be decoded from the configuration files or environment, and compared across the services.
+// The zero value of each field leaves the related option unset.
+// The function and descriptor valued fields cannot be encoded, and need to be set programmatically.
+type InterpreterOptions struct {
+	// Preset is the name of the preset configuration applied before the other options, see PresetOpt.
+	Preset string `json:"preset,omitempty"`
+
+	// LiteralStringFields are the string fields which unquoted values are not split by the dots, see LiteralStringFieldsOpt.
+	LiteralStringFields []protoreflect.FullName `json:"literal_string_fields,omitempty"`
+
+	// MaxComplexity is the maximum complexity of the filter, see MaxComplexityOpt.
+	MaxComplexity int64 `json:"max_complexity,omitempty"`
+
+	// MaxDepth is the maximum nesting depth of the filter, see MaxDepthOpt.
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// MaxFilterLength is the maximum length of the filter string, see MaxFilterLengthOpt.
+	MaxFilterLength int `json:"max_filter_length,omitempty"`
+
+	// DisallowIndirectComparisons forbids comparing a field with another field, see DisallowIndirectComparisonsOpt.
+	DisallowIndirectComparisons bool `json:"disallow_indirect_comparisons,omitempty"`
+
+	// MatchAll makes the empty filter result in the expr.MatchAllExpr, see MatchAllOpt.
+	MatchAll bool `json:"match_all,omitempty"`
+
+	// ReservedKeywords are the keywords rejected by the parser, along with their hints, see ReservedKeywordsOpt.
+	ReservedKeywords scanner.ReservedWords `json:"reserved_keywords,omitempty"`
+
+	// CaseInsensitive marks all the string comparisons as case-insensitive, see CaseInsensitiveOpt.
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+
+	// CaseInsensitiveFields are the string fields which comparisons are case-insensitive, see CaseInsensitiveFieldsOpt.
+	CaseInsensitiveFields []protoreflect.FullName `json:"case_insensitive_fields,omitempty"`
+
+	// UUIDFields are the string fields which values are UUIDs, see UUIDFieldsOpt.
+	UUIDFields []protoreflect.FullName `json:"uuid_fields,omitempty"`
+
+	// ResourceNames enables the validation of the resource name fields, see ResourceNamesOpt.
+	ResourceNames bool `json:"resource_names,omitempty"`
+
+	// RegexMatch enables the regular expression match comparator, see RegexMatchOpt.
+	RegexMatch bool `json:"regex_match,omitempty"`
+
+	// CustomComparators are the declarations of the custom comparators, see CustomComparatorOpt.
+	CustomComparators []ComparatorDeclaration `json:"custom_comparators,omitempty"`
+
+	// SearchableFields are the paths of the fields matched by the search query, see SearchableFieldsOpt.
+	SearchableFields []string `json:"searchable_fields,omitempty"`
+
+	// GlobalSearch enables the global restrictions matched against the searchable fields, see GlobalSearchOpt.
+	GlobalSearch bool `json:"global_search,omitempty"`
+
+	// Sequence makes the sequences result in the expr.SequenceExpr, see SequenceOpt.
+	Sequence bool `json:"sequence,omitempty"`
+
+	// Between enables the range macro, see BetweenOpt.
+	Between bool `json:"between,omitempty"`
+
+	// CollectionFunctions enables the size, any and all functions of the repeated fields, see CollectionFunctionsOpt.
+	CollectionFunctions bool `json:"collection_functions,omitempty"`
+
+	// Macros are the macro expansions by their names, see MacroOpt.
+	Macros map[string]string `json:"macros,omitempty"`
+
+	// SubstringHas makes the HAS comparator of singular string fields a substring match, see SubstringHasOpt.
+	SubstringHas bool `json:"substring_has,omitempty"`
+
+	// LenientEnums accepts unquoted and case-insensitive enum value names, see LenientEnumsOpt.
+	LenientEnums bool `json:"lenient_enums,omitempty"`
+
+	// DateOnlyTimestamps accepts date-only values of the timestamp fields, see DateOnlyTimestampsOpt.
+	DateOnlyTimestamps bool `json:"date_only_timestamps,omitempty"`
+	// DurationDays accepts the day and week units of the duration literals, see DurationDaysOpt.
+	DurationDays bool `json:"duration_days,omitempty"`
+
+	// RelativeTime enables the relative timestamps, see RelativeTimeOpt.
+	RelativeTime bool `json:"relative_time,omitempty"`
+
+	// EmptyStringMode is the relation between the empty string and the null values, see EmptyStringModeOpt.
+	EmptyStringMode EmptyStringMode `json:"empty_string_mode,omitempty"`
+
+	// SelectorNames are the kinds of the field names used to resolve the selectors, see SelectorNamesOpt.
+	SelectorNames []SelectorName `json:"selector_names,omitempty"`
+
+	// FieldAliases are the alternative names of the fields, see FieldAliasesOpt.
+	FieldAliases map[string]protoreflect.FullName `json:"field_aliases,omitempty"`
+
+	// ErrHandler is the error handler of the interpreter, see ErrHandlerOpt.
+	ErrHandler scanner.ErrorHandler `json:"-"`
+
+	// Functions are the function call declarations registered in the interpreter, see RegisterFunction.
+	Functions []*FunctionCallDeclaration `json:"-"`
+
+	// ValueDecoders are the decoders of the message field values by the message full name, see RegisterValueDecoder.
+	ValueDecoders map[protoreflect.FullName]ValueDecoder `json:"-"`
+
+	// SelectorTrace is the selector resolution trace function, see SelectorTraceOpt.
+	SelectorTrace SelectorTraceFn `json:"-"`
+
+	// TraceHook is the parse trace function, see TraceHookOpt.
+	TraceHook parser.TraceHookFn `json:"-"`
+
+	// ParseHooks are the functions called after each Parse, see ParseHookOpt.
+	ParseHooks []ParseHookFn `json:"-"`
+
+	// Logger logs the parse and function call failures, see LoggerOpt.
+	Logger *slog.Logger `json:"-"`
+
+	// RestrictionHooks are the functions called with each restriction expression, see RestrictionHookOpt.
+	RestrictionHooks []RestrictionHookFn `json:"-"`
+
+	// Clock is the clock of the relative timestamps, see RelativeTimeOpt.
+	// It is used only if the RelativeTime is set.
+	Clock func() time.Time `json:"-"`
+
+	// DisplayNameExtension is the field display name extension, see DisplayNameExtensionOpt.
+	DisplayNameExtension protoreflect.ExtensionType `json:"-"`
+
+	// ExtensionFields are the extension fields allowed in the filters, see ExtensionFieldsOpt.
+	ExtensionFields []protoreflect.ExtensionType `json:"-"`
+
+	// AnyTypes are the message types of the any.Unpack function, see AnyTypesOpt.
+	AnyTypes []protoreflect.MessageDescriptor `json:"-"`
+
+	// Cache stores the parsed expressions for the CacheTTL, see CacheOpt.
+	Cache cache.Cache `json:"-"`
+
+	// CacheTTL is the time for which the parsed expressions are cached, see CacheOpt.
+	// It is used only if the Cache is set.
+	CacheTTL time.Duration `json:"-"`
+
+	// ExprCache stores the parsed expressions in memory for the ExprCacheTTL, see ExprCacheOpt.
+	ExprCache *cache.LRU[expr.FilterExpr] `json:"-"`
+
+	// ExprCacheTTL is the time for which the parsed expressions are cached in memory, see ExprCacheOpt.
+	// It is used only if the ExprCache is set.
+	ExprCacheTTL time.Duration `json:"-"`
+
+	// KindPolicy decides which field kinds are comparable, see KindPolicyOpt.
+	KindPolicy KindPolicy `json:"-"`
+
+	// GlobalSearchHandler handles the global restrictions, see GlobalSearchHandlerOpt.
+	// It is used only if the GlobalSearch is not set.
+	GlobalSearchHandler GlobalSearchHandler `json:"-"`
+
+	// SequenceWeight sets the relevance weights of the sequence factors, see SequenceOpt.
+	// It is used only if the Sequence is set.
+	SequenceWeight SequenceWeightFn `json:"-"`
+
+	// BaseExpr is combined with each parsed filter using the AND operator, see BaseExprOpt.
+	BaseExpr expr.FilterExpr `json:"-"`
+}
+
+// Opt returns the option that applies all the non-zero fields of the options.
+// It could be combined with the other functional options, i.e.:
+//
+//	filtering.NewInterpreter(md, opts.Opt(), filtering.ErrHandlerOpt(fn))
+func (o InterpreterOptions) Opt() Option {
+	return func(i *Interpreter) error {
+		var opts []Option
+		if o.Preset != "" {
+			opts = append(opts, PresetOpt(o.Preset))
+		}
+		if o.ErrHandler != nil {
+			opts = append(opts, ErrHandlerOpt(o.ErrHandler))
+		}
+		for _, fn := range o.Functions {
+			opts = append(opts, RegisterFunction(fn))
+		}
+		for name, dec := range o.ValueDecoders {
+			opts = append(opts, RegisterValueDecoder(name, dec))
+		}
+		if len(o.LiteralStringFields) > 0 {
+			opts = append(opts, LiteralStringFieldsOpt(o.LiteralStringFields...))
+		}
+		if o.MaxComplexity != 0 {
+			opts = append(opts, MaxComplexityOpt(o.MaxComplexity))
+		}
+		if o.MaxDepth != 0 {
+			opts = append(opts, MaxDepthOpt(o.MaxDepth))
+		}
+		if o.MaxFilterLength != 0 {
+			opts = append(opts, MaxFilterLengthOpt(o.MaxFilterLength))
+		}
+		if o.DisallowIndirectComparisons {
+			opts = append(opts, DisallowIndirectComparisonsOpt())
+		}
+		if o.MatchAll {
+			opts = append(opts, MatchAllOpt())
+		}
+		if len(o.ReservedKeywords) > 0 {
+			opts = append(opts, ReservedKeywordsOpt(o.ReservedKeywords))
+		}
+		if o.SelectorTrace != nil {
+			opts = append(opts, SelectorTraceOpt(o.SelectorTrace))
+		}
+		if o.TraceHook != nil {
+			opts = append(opts, TraceHookOpt(o.TraceHook))
+		}
+		for _, fn := range o.ParseHooks {
+			opts = append(opts, ParseHookOpt(fn))
+		}
+		if o.Logger != nil {
+			opts = append(opts, LoggerOpt(o.Logger))
+		}
+		for _, fn := range o.RestrictionHooks {
+			opts = append(opts, RestrictionHookOpt(fn))
+		}
+		if o.DisplayNameExtension != nil {
+			opts = append(opts, DisplayNameExtensionOpt(o.DisplayNameExtension))
+		}
+		if len(o.ExtensionFields) > 0 {
+			opts = append(opts, ExtensionFieldsOpt(o.ExtensionFields...))
+		}
+		if len(o.AnyTypes) > 0 {
+			opts = append(opts, AnyTypesOpt(o.AnyTypes...))
+		}
+		if o.Cache != nil {
+			opts = append(opts, CacheOpt(o.Cache, o.CacheTTL))
+		}
+		if o.ExprCache != nil {
+			opts = append(opts, ExprCacheOpt(o.ExprCache, o.ExprCacheTTL))
+		}
+		if o.KindPolicy != nil {
+			opts = append(opts, KindPolicyOpt(o.KindPolicy))
+		}
+		if o.CaseInsensitive {
+			opts = append(opts, CaseInsensitiveOpt())
+		}
+		if len(o.CaseInsensitiveFields) > 0 {
+			opts = append(opts, CaseInsensitiveFieldsOpt(o.CaseInsensitiveFields...))
+		}
+		if len(o.UUIDFields) > 0 {
+			opts = append(opts, UUIDFieldsOpt(o.UUIDFields...))
+		}
+		if o.ResourceNames {
+			opts = append(opts, ResourceNamesOpt())
+		}
+		if o.RegexMatch {
+			opts = append(opts, RegexMatchOpt())
+		}
+		if len(o.CustomComparators) > 0 {
+			opts = append(opts, CustomComparatorOpt(o.CustomComparators...))
+		}
+		if len(o.SearchableFields) > 0 {
+			opts = append(opts, SearchableFieldsOpt(o.SearchableFields...))
+		}
+		switch {
+		case o.GlobalSearch:
+			opts = append(opts, GlobalSearchOpt())
+		case o.GlobalSearchHandler != nil:
+			opts = append(opts, GlobalSearchHandlerOpt(o.GlobalSearchHandler))
+		}
+		if o.Sequence {
+			opts = append(opts, SequenceOpt(o.SequenceWeight))
+		}
+		if o.Between {
+			opts = append(opts, BetweenOpt())
+		}
+		if o.CollectionFunctions {
+			opts = append(opts, CollectionFunctionsOpt())
+		}
+		if len(o.Macros) > 0 {
+			names := make([]string, 0, len(o.Macros))
+			for name := range o.Macros {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				opts = append(opts, MacroOpt(name, o.Macros[name]))
+			}
+		}
+		if o.SubstringHas {
+			opts = append(opts, SubstringHasOpt())
+		}
+		if o.LenientEnums {
+			opts = append(opts, LenientEnumsOpt())
+		}
+		if o.DateOnlyTimestamps {
+			opts = append(opts, DateOnlyTimestampsOpt())
+		}
+		if o.DurationDays {
+			opts = append(opts, DurationDaysOpt())
+		}
+		if o.RelativeTime {
+			opts = append(opts, RelativeTimeOpt(o.Clock))
+		}
+		if o.EmptyStringMode != EmptyStringDistinct {
+			opts = append(opts, EmptyStringModeOpt(o.EmptyStringMode))
+		}
+		if len(o.SelectorNames) > 0 {
+			opts = append(opts, SelectorNamesOpt(o.SelectorNames...))
+		}
+		if len(o.FieldAliases) > 0 {
+			opts = append(opts, FieldAliasesOpt(o.FieldAliases))
+		}
+		if o.BaseExpr != nil {
+			opts = append(opts, BaseExprOpt(o.BaseExpr))
+		}
+		for _, opt := range opts {
+			if err := opt(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Options returns the options the interpreter is currently configured with.
+func (b *Interpreter) Options() InterpreterOptions {
+	o := InterpreterOptions{
+		Preset:                      b.preset,
+		MaxComplexity:               b.maxComplexity,
+		MaxDepth:                    b.maxDepth,
+		MaxFilterLength:             b.maxFilterLength,
+		DisallowIndirectComparisons: b.disallowIndirectComparisons,
+		MatchAll:                    b.matchAll,
+		ReservedKeywords:            b.reservedKeywords,
+		SelectorNames:               append([]SelectorName(nil), b.selectorNames...),
+		ErrHandler:                  b.errHandlerFn,
+		SelectorTrace:               b.traceFn,
+		TraceHook:                   b.traceHook,
+		ParseHooks:                  append([]ParseHookFn(nil), b.parseHooks...),
+		Logger:                      b.logger,
+		RestrictionHooks:            append([]RestrictionHookFn(nil), b.restrictionHooks...),
+		CaseInsensitive:             b.caseInsensitive,
+		RegexMatch:                  b.regexMatch,
+		Between:                     b.between,
+		CollectionFunctions:         b.collectionFunctions,
+		ResourceNames:               b.resourceNames != nil,
+		Sequence:                    b.sequence,
+		SequenceWeight:              b.sequenceWeight,
+		SubstringHas:                b.substringHas,
+		LenientEnums:                b.lenientEnums,
+		DateOnlyTimestamps:          b.dateOnlyTimestamps,
+		DurationDays:                b.durationDays,
+		RelativeTime:                b.clock != nil,
+		EmptyStringMode:             b.emptyStringMode,
+		Clock:                       b.clock,
+		DisplayNameExtension:        b.displayNameExt,
+		ExtensionFields:             append([]protoreflect.ExtensionType(nil), b.extensions...),
+		AnyTypes:                    append([]protoreflect.MessageDescriptor(nil), b.anyTypes...),
+		KindPolicy:                  b.kindPolicy,
+		BaseExpr:                    b.baseExpr,
+		Cache:                       b.cache,
+		CacheTTL:                    b.cacheTTL,
+		ExprCache:                   b.exprCache,
+		ExprCacheTTL:                b.exprCacheTTL,
+	}
+	if len(b.macros) > 0 {
+		o.Macros = make(map[string]string, len(b.macros))
+		for _, m := range b.macros {
+			o.Macros[m.name] = m.expansion
+		}
+	}
+	if len(b.fieldAliases) > 0 {
+		o.FieldAliases = make(map[string]protoreflect.FullName, len(b.fieldAliases))
+		for alias, fd := range b.fieldAliases {
+			o.FieldAliases[alias] = fd.FullName()
+		}
+	}
+	if b.globalSearchFields {
+		o.GlobalSearch = true
+	} else {
+		o.GlobalSearchHandler = b.globalSearch
+	}
+	for _, sf := range b.searchFields {
+		o.SearchableFields = append(o.SearchableFields, sf.path)
+	}
+	for name := range b.literalStringFields {
+		o.LiteralStringFields = append(o.LiteralStringFields, name)
+	}
+	sort.Slice(o.LiteralStringFields, func(i, j int) bool { return o.LiteralStringFields[i] < o.LiteralStringFields[j] })
+	for name := range b.caseInsensitiveFields {
+		o.CaseInsensitiveFields = append(o.CaseInsensitiveFields, name)
+	}
+	sort.Slice(o.CaseInsensitiveFields, func(i, j int) bool { return o.CaseInsensitiveFields[i] < o.CaseInsensitiveFields[j] })
+	for _, cd := range b.customComparators {
+		d := *cd
+		d.Kinds = append([]protoreflect.Kind(nil), cd.Kinds...)
+		o.CustomComparators = append(o.CustomComparators, d)
+	}
+	sort.Slice(o.CustomComparators, func(i, j int) bool { return o.CustomComparators[i].Symbol < o.CustomComparators[j].Symbol })
+	for name := range b.uuidFields {
+		o.UUIDFields = append(o.UUIDFields, name)
+	}
+	sort.Slice(o.UUIDFields, func(i, j int) bool { return o.UUIDFields[i] < o.UUIDFields[j] })
+	for _, fn := range b.functionCallDeclarations {
+		if fn == b.nowFn {
+			// The now() function is registered by the RelativeTime.
+			continue
+		}
+		o.Functions = append(o.Functions, fn)
+	}
+	sort.Slice(o.Functions, func(i, j int) bool { return o.Functions[i].Name.String() < o.Functions[j].Name.String() })
+	if len(b.valueDecoders) > 0 {
+		o.ValueDecoders = make(map[protoreflect.FullName]ValueDecoder, len(b.valueDecoders))
+		for name, dec := range b.valueDecoders {
+			o.ValueDecoders[name] = dec
+		}
+	}
+	return o
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (n SelectorName) MarshalText() ([]byte, error) {
+	if n < ProtoSelectorName || n > DisplaySelectorName {
+		return nil, fmt.Errorf("invalid selector name: %d", n)
+	}
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (n *SelectorName) UnmarshalText(text []byte) error {
+	for i, s := range _SelectorNameStrings {
+		if s == string(text) {
+			*n = SelectorName(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid selector name: %q", text)
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreterOptions(t *testing.T) {
+	const config = `{
+		"max_depth": 1,
+		"disallow_indirect_comparisons": true,
+		"match_all": true,
+		"reserved_keywords": {"BETWEEN": "use range comparisons"},
+		"selector_names": ["PROTO_NAME", "JSON_NAME"],
+		"literal_string_fields": ["testpb.Message.str"],
+		"case_insensitive_fields": ["testpb.Message.map_str_str"],
+		"uuid_fields": ["testpb.Message.name"],
+		"resource_names": true,
+		"regex_match": true,
+		"custom_comparators": [{"symbol": "^=", "comparator": 101, "kinds": [9]}],
+		"searchable_fields": ["name", "sub.str"],
+		"global_search": true,
+		"between": true,
+		"collection_functions": true,
+		"lenient_enums": true,
+		"macros": {"is:on": "bool = true"},
+		"field_aliases": {"created": "testpb.Message.timestamp"}
+	}`
+
+	var o InterpreterOptions
+	if err := json.Unmarshal([]byte(config), &o); err != nil {
+		t.Fatalf("failed to decode options: %v", err)
+	}
+
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, o.Opt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("applied", func(t *testing.T) {
+		if _, err = i.Parse(`mapStrStr:"key"`); err != nil {
+			t.Errorf("json selector name not applied: %v", err)
+		}
+		if _, err = i.Parse(`created > 2023-01-01T00:00:00Z`); err != nil {
+			t.Errorf("field aliases not applied: %v", err)
+		}
+		if _, err = i.Parse(`"New York" hotel`); err != nil {
+			t.Errorf("global search not applied: %v", err)
+		}
+		if _, err = i.Parse(`str ^= "abc"`); err != nil {
+			t.Errorf("custom comparators not applied: %v", err)
+		}
+		if _, err = i.Parse(`i32 = i64`); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("indirect comparisons not disallowed: %v", err)
+		}
+		if _, err = i.Parse(`i32 IN [1, 2]`); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("max depth not applied: %v", err)
+		}
+		if _, err = i.Parse(`i32 BETWEEN 1`); !errors.Is(err, parser.ErrInvalidFilterSyntax) {
+			t.Errorf("reserved keywords not applied: %v", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		if got := i.Options(); !reflect.DeepEqual(got, o) {
+			t.Errorf("expected options %+v but got %+v", o, got)
+		}
+		data, err := json.Marshal(i.Options())
+		if err != nil {
+			t.Fatalf("failed to encode options: %v", err)
+		}
+		var decoded InterpreterOptions
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("failed to decode options: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, o) {
+			t.Errorf("expected options %+v but got %+v", o, decoded)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if err := json.Unmarshal([]byte(`{"selector_names": ["UNKNOWN"]}`), new(InterpreterOptions)); err == nil {
+			t.Error("expected invalid selector name error")
+		}
+		if _, err := NewInterpreter(md, InterpreterOptions{MaxDepth: -1}.Opt()); err == nil {
+			t.Error("expected invalid max depth error")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// ParseWithParams parses input filter with the named parameter placeholders, i.e.:
+// 'create_time > @since AND author = @user', binding the params values at parse time.
+// Each value is validated against the descriptor of the field it is compared with.
+// Supported param values are the Go equivalents of the field kinds, i.e.:
+// string, signed and unsigned integers, floats, bool, []byte, protoreflect.EnumNumber or enum value name,
+// time.Time or *timestamppb.Timestamp, time.Duration or *durationpb.Duration, and proto.Message of the field message type.
+// A nil value is bound only to the nullable fields.
+// If params is nil, the filter is parsed the same way as with Parse.
+func (b *Interpreter) ParseWithParams(filter string, params map[string]any, opts ...ParseOption) (expr.FilterExpr, error) {
+	if b.msg == nil {
+		panic("message descriptor is not set")
+	}
+
+	fa, err := b.fieldAccess(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.parseHooks) == 0 && b.logger == nil {
+		return b.withBaseExpr(b.parse(filter, params, fa))
+	}
+
+	start := time.Now()
+	x, err := b.withBaseExpr(b.parse(filter, params, fa))
+	b.callParseHooks(filter, start, x, err)
+	return x, err
+}
+
+// paramName returns the name of the parameter placeholder referenced by the value.
+func paramName(ctx *ParseContext, in TryParseValueInput) (string, bool) {
+	if ctx.Params == nil || len(in.Args) > 0 {
+		return "", false
+	}
+	tl, ok := in.Value.(*ast.TextLiteral)
+	if !ok || len(tl.Value) < 2 || !strings.HasPrefix(tl.Value, "@") {
+		return "", false
+	}
+	return tl.Value[1:], true
+}
+
+// isParamRef checks if the ast value is a parameter placeholder.
+func isParamRef(ctx *ParseContext, v ast.AnyExpr) bool {
+	in := TryParseValueInput{Value: v}
+	if me, ok := v.(*ast.MemberExpr); ok {
+		in.Value, in.Args = me.Value, me.Fields
+	}
+	_, ok := paramName(ctx, in)
+	return ok
+}
+
+// tryParseParam binds the value of the named parameter to the field of the input.
+func (b *Interpreter) tryParseParam(ctx *ParseContext, in TryParseValueInput, name string) (TryParseValueResult, error) {
+	pv, ok := ctx.Params[name]
+	if !ok {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("parameter @%s is not bound", name)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	v, err := paramValue(in.Field, pv, in.IsOptional)
+	if err != nil {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("parameter @%s: %v", name, err)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	ve := expr.AcquireValueExpr()
+	ve.Value = v
+	if v == nil {
+		ve.NullKind = in.Field.Kind()
+	}
+	return TryParseValueResult{Expr: ve}, nil
+}
+
+// paramValue converts the parameter value into the value expression representation of the field.
+func paramValue(fd FieldDescriptor, v any, nullable bool) (any, error) {
+	if v == nil {
+		if !nullable {
+			return nil, errors.New("field is not nullable")
+		}
+		return nil, nil
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case protoreflect.BoolKind:
+		if bv, ok := v.(bool); ok {
+			return bv, nil
+		}
+	case protoreflect.BytesKind:
+		switch bt := v.(type) {
+		case []byte:
+			return bt, nil
+		case string:
+			return []byte(bt), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if i, ok := paramInt(v); ok {
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return nil, fmt.Errorf("value %d overflows the %s field", i, fd.Kind())
+			}
+			return i, nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if i, ok := paramInt(v); ok {
+			return i, nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if u, ok := paramUint(v); ok {
+			if u > math.MaxUint32 {
+				return nil, fmt.Errorf("value %d overflows the %s field", u, fd.Kind())
+			}
+			return u, nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if u, ok := paramUint(v); ok {
+			return u, nil
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch f := v.(type) {
+		case float64:
+			return f, nil
+		case float32:
+			return float64(f), nil
+		}
+		if i, ok := paramInt(v); ok {
+			return float64(i), nil
+		}
+	case protoreflect.EnumKind:
+		switch e := v.(type) {
+		case protoreflect.EnumNumber:
+			if fd.Enum().Values().ByNumber(e) != nil {
+				return e, nil
+			}
+			return nil, fmt.Errorf("value %d is not a valid %s enum number", e, fd.Enum().FullName())
+		case protoreflect.Enum:
+			if e.Descriptor().FullName() == fd.Enum().FullName() {
+				return e.Number(), nil
+			}
+		case string:
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(e)); ev != nil {
+				return ev.Number(), nil
+			}
+			return nil, fmt.Errorf("value %q is not a valid %s enum value", e, fd.Enum().FullName())
+		}
+	case protoreflect.MessageKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			switch t := v.(type) {
+			case time.Time:
+				return t, nil
+			case *timestamppb.Timestamp:
+				if err := t.CheckValid(); err != nil {
+					return nil, err
+				}
+				return t.AsTime(), nil
+			}
+		case "google.protobuf.Duration":
+			switch d := v.(type) {
+			case time.Duration:
+				return d, nil
+			case *durationpb.Duration:
+				if err := d.CheckValid(); err != nil {
+					return nil, err
+				}
+				return d.AsDuration(), nil
+			}
+		default:
+			if m, ok := v.(proto.Message); ok && m.ProtoReflect().Descriptor().FullName() == fd.Message().FullName() {
+				return m.ProtoReflect(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("value of type %T cannot be used for the %s field", v, paramKindName(fd))
+}
+
+func paramKindName(fd FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		return string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fd.Enum().FullName())
+	}
+	return fd.Kind().String()
+}
+
+func paramInt(v any) (int64, bool) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int8:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	case uint8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case uint32:
+		return int64(i), true
+	}
+	return 0, false
+}
+
+func paramUint(v any) (uint64, bool) {
+	switch u := v.(type) {
+	case uint:
+		return uint64(u), true
+	case uint8:
+		return uint64(u), true
+	case uint16:
+		return uint64(u), true
+	case uint32:
+		return uint64(u), true
+	case uint64:
+		return u, true
+	}
+	if i, ok := paramInt(v); ok && i >= 0 {
+		return uint64(i), true
+	}
+	return 0, false
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_ParseWithParams(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		name   string
+		filter string
+		params map[string]any
+		want   any
+		err    error
+	}{
+		{name: "string", filter: `str = @user`, params: map[string]any{"user": "john"}, want: "john"},
+		{name: "int", filter: `i32 > @n`, params: map[string]any{"n": 5}, want: int64(5)},
+		{name: "float from int", filter: `double < @n`, params: map[string]any{"n": 2}, want: float64(2)},
+		{name: "time", filter: `timestamp > @since`, params: map[string]any{"since": since}, want: since},
+		{name: "timestamppb", filter: `timestamp > @since`, params: map[string]any{"since": timestamppb.New(since)}, want: since},
+		{name: "duration", filter: `duration >= @d`, params: map[string]any{"d": time.Hour}, want: time.Hour},
+		{name: "enum name", filter: `enum = @e`, params: map[string]any{"e": "TWO"}, want: testpb.Enum_TWO.Number()},
+		{name: "enum", filter: `enum = @e`, params: map[string]any{"e": testpb.Enum_ONE}, want: testpb.Enum_ONE.Number()},
+		{name: "not bound", filter: `str = @user`, params: map[string]any{}, err: ErrInvalidValue},
+		{name: "type mismatch", filter: `i32 = @n`, params: map[string]any{"n": "5"}, err: ErrInvalidValue},
+		{name: "overflow", filter: `i32 = @n`, params: map[string]any{"n": int64(math.MaxInt32) + 1}, err: ErrInvalidValue},
+		{name: "invalid enum", filter: `enum = @e`, params: map[string]any{"e": "FOUR"}, err: ErrInvalidValue},
+		{name: "not nullable", filter: `i32 = @n`, params: map[string]any{"n": nil}, err: ErrInvalidValue},
+		{name: "without params", filter: `i32 = @n`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.ParseWithParams(tt.filter, tt.params)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			ve, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if tv, ok := tt.want.(time.Time); ok {
+				if !tv.Equal(ve.Value.(time.Time)) {
+					t.Errorf("expected %v but got %v", tt.want, ve.Value)
+				}
+				return
+			}
+			if ve.Value != tt.want {
+				t.Errorf("expected %v (%T) but got %v (%T)", tt.want, tt.want, ve.Value, ve.Value)
+			}
+		})
+	}
+}
+
+func TestInterpreter_ParseWithParamsCombined(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	params := map[string]any{"since": time.Now(), "user": "john"}
+	x, err := i.ParseWithParams(`timestamp > @since AND str = @user`, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+
+	ae, ok := x.(*expr.AndExpr)
+	if !ok || len(ae.Expr) != 2 {
+		t.Fatalf("expected and expression with two restrictions but got %v", x)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+// ParseInfo describes a single call of the Interpreter.Parse, passed to the ParseHookFn.
+type ParseInfo struct {
+	// Message is the full name of the message the filter was parsed for.
+	Message protoreflect.FullName
+
+	// Filter is the raw input filter.
+	// It may contain sensitive values, thus it should be redacted, i.e. with the RedactFilter, before being logged.
+	Filter string
+
+	// Expr is the resulting expression, nil if the parsing failed or the filter is empty.
+	// The hook must not free nor retain the expression, as it is owned by the caller of the Parse.
+	Expr expr.FilterExpr
+
+	// Complexity is the complexity of the resulting expression, zero if the parsing failed.
+	Complexity int64
+
+	// Duration is the time spent on parsing the filter.
+	Duration time.Duration
+
+	// Err is the error returned by the Parse, nil on success.
+	Err error
+}
+
+// ParseHookFn is a function called after each Parse of the interpreter.
+type ParseHookFn func(info ParseInfo)
+
+// ParseHookOpt is an option that registers a function called after each Parse,
+// with the outcome, complexity and duration of the parsing.
+// It is meant for the observability of the filtering, i.e. the structured logging or metrics.
+// Multiple hooks are called in the registration order.
+// The hooks are called synchronously within the Parse, from each goroutine that uses the Interpreter.
+func ParseHookOpt(fn ParseHookFn) Option {
+	return func(i *Interpreter) error {
+		if fn == nil {
+			return errors.New("parse hook function is nil")
+		}
+		i.parseHooks = append(i.parseHooks, fn)
+		return nil
+	}
+}
+
+// callParseHooks calls the registered parse hooks with the result of the parsing started at the start time,
+// and logs the error with the logger, if set.
+func (b *Interpreter) callParseHooks(filter string, start time.Time, x expr.FilterExpr, err error) {
+	if err != nil && b.logger != nil {
+		b.logParseError(filter, err)
+	}
+	if len(b.parseHooks) == 0 {
+		return
+	}
+	info := ParseInfo{
+		Message:  b.msg.FullName(),
+		Filter:   filter,
+		Expr:     x,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if x != nil {
+		info.Complexity = x.Complexity()
+	}
+	for _, fn := range b.parseHooks {
+		fn(info)
+	}
+}
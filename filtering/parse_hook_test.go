@@ -0,0 +1,60 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestParseHookOpt(t *testing.T) {
+	var infos []ParseInfo
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, ParseHookOpt(func(info ParseInfo) {
+		infos = append(infos, info)
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`i32 = 1`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+
+	if _, err = i.Parse(`i32 = "foo"`); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 hook calls but got %d", len(infos))
+	}
+	if infos[0].Message != md.FullName() || infos[0].Filter != `i32 = 1` || infos[0].Expr != x || infos[0].Err != nil {
+		t.Errorf("unexpected success info: %+v", infos[0])
+	}
+	if infos[0].Complexity != x.Complexity() {
+		t.Errorf("expected complexity %d but got %d", x.Complexity(), infos[0].Complexity)
+	}
+	if !errors.Is(infos[1].Err, ErrInvalidValue) || infos[1].Expr != nil || infos[1].Complexity != 0 {
+		t.Errorf("unexpected failure info: %+v", infos[1])
+	}
+
+	if _, err = NewInterpreter(md, ParseHookOpt(nil)); err == nil {
+		t.Error("expected error for nil hook")
+	}
+}
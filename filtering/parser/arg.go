@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseArgExpr() (ast.ArgExpr, error) {
+func (p *Parser) parseArgExpr() (_ ast.ArgExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleArg), &err)
+	}
+
 	// Peek for the composite LPAREN token.
 	var isComposite bool
 	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
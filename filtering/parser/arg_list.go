@@ -55,7 +55,7 @@ func (p *Parser) parseArgListExpr() (*ast.ArgListExpr, error) {
 		}
 
 		// Parse the argument.
-		arg, err := p.parseArgExpr()
+		arg, err := p.parseFunctionArgExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -63,3 +63,28 @@ func (p *Parser) parseArgListExpr() (*ast.ArgListExpr, error) {
 		i++
 	}
 }
+
+// parseFunctionArgExpr parses the function call argument, which in addition to the arg could be a restriction,
+// i.e. 'any(items, sku = "abc")'. A restriction without a comparator results in its sole comparable.
+func (p *Parser) parseFunctionArgExpr() (ast.ArgExpr, error) {
+	var isComposite bool
+	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
+		isComposite = tok == token.LPAREN
+		return false
+	})
+	if isComposite {
+		return p.parseCompositeExpr()
+	}
+
+	re, err := p.parseRestrictionExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !re.IsGlobal() {
+		return re, nil
+	}
+	comp := re.Comparable
+	re.Comparable = nil
+	putRestrictionExpr(re)
+	return comp, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"sync"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+var binaryExprPool = &sync.Pool{
+	New: func() any { return &ast.BinaryExpr{} },
+}
+
+func getBinaryExpr() *ast.BinaryExpr {
+	return getPooled[*ast.BinaryExpr](binaryExprPool)
+}
+
+func putBinaryExpr(b *ast.BinaryExpr) {
+	if b == nil {
+		return
+	}
+	putArgExpr(b.Left)
+	putArgExpr(b.Right)
+	*b = ast.BinaryExpr{}
+	putPooled(binaryExprPool, b)
+}
+
+// parseArithmeticExpr parses the arithmetic operations following the restriction argument x, i.e. 'a < b + 1h'.
+// Only the operators of at least minPrec precedence are parsed, the operators of a higher precedence
+// are folded into the right operand, thus 'a + b * c' results in 'a + (b * c)'.
+// The operator needs to be surrounded by the whitespaces, otherwise the scanner is restored and x is returned as is,
+// thus i.e. 'a = b -c' is still a sequence with the negated 'c' term.
+func (p *Parser) parseArithmeticExpr(x ast.ArgExpr, minPrec int) (ast.ArgExpr, error) {
+	for {
+		bp := p.scanner.Breakpoint()
+		if p.scanner.SkipWhitespace() == 0 {
+			p.scanner.Restore(bp)
+			return x, nil
+		}
+
+		var (
+			op    ast.OperatorType
+			opPos token.Position
+		)
+		p.scanner.Peek(func(pos token.Position, tok token.Token, _ string) bool {
+			op, opPos = operatorType(tok), pos
+			return op != 0 && op.Precedence() >= minPrec
+		})
+		if op == 0 || op.Precedence() < minPrec {
+			p.scanner.Restore(bp)
+			return x, nil
+		}
+
+		if p.scanner.SkipWhitespace() == 0 {
+			p.scanner.Restore(bp)
+			return x, nil
+		}
+
+		right, err := p.parseComparableExpr()
+		if err != nil {
+			putArgExpr(x)
+			return nil, err
+		}
+
+		// Fold the operations of a higher precedence into the right operand.
+		ra, err := p.parseArithmeticExpr(right, op.Precedence()+1)
+		if err != nil {
+			putArgExpr(x)
+			return nil, err
+		}
+
+		be := getBinaryExpr()
+		be.Left = x
+		be.OpPos = opPos
+		be.Op = op
+		be.Right = ra
+		x = be
+	}
+}
+
+// operatorType returns the arithmetic operator of the token, or zero if the token is not an operator.
+func operatorType(tok token.Token) ast.OperatorType {
+	switch tok {
+	case token.PLUS:
+		return ast.ADD
+	case token.MINUS:
+		return ast.SUB
+	case token.ASTERISK:
+		return ast.MUL
+	case token.SLASH:
+		return ast.DIV
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+func TestParser_Arithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "add", src: `a < b + 1h`, want: `(b + 1h)`},
+		{name: "function operand", src: `a < b + duration("24h")`, want: `(b + duration("24h"))`},
+		{name: "precedence", src: `a > b + c * 2`, want: `(b + (c * 2))`},
+		{name: "left associative", src: `a = b - c - d`, want: `((b - c) - d)`},
+		{name: "multiplicative first", src: `a = b / 2 + c * 3 - d`, want: `(((b / 2) + (c * 3)) - d)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := NewParser(tt.src).Parse()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer pf.Free()
+
+			rest := seqRestriction(t, pf.Expr.Sequences[0])
+			if _, ok := rest.Arg.(*ast.BinaryExpr); !ok {
+				t.Fatalf("expected binary expression but got: %T", rest.Arg)
+			}
+			var sb strings.Builder
+			writeGrouped(&sb, rest.Arg)
+			if got := sb.String(); got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+			if got := pf.Expr.String(); got != tt.src {
+				t.Errorf("expected %s but got %s", tt.src, got)
+			}
+		})
+	}
+
+	// The operator not followed by a whitespace is not arithmetic, i.e. the negation of the next term.
+	pf, err := NewParser(`a = b -c`).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pf.Free()
+	if n := len(pf.Expr.Sequences[0].Factors); n != 2 {
+		t.Errorf("expected two factors of 'a = b -c' but got %d", n)
+	}
+
+	if _, err = NewParser(`a = b + `).Parse(); err == nil {
+		t.Error("expected error of the missing operand")
+	}
+}
+
+// writeGrouped writes the arithmetic expression with each operation surrounded by the parentheses.
+func writeGrouped(sb *strings.Builder, x ast.ArgExpr) {
+	be, ok := x.(*ast.BinaryExpr)
+	if !ok {
+		x.WriteStringTo(sb, false)
+		return
+	}
+	sb.WriteByte('(')
+	writeGrouped(sb, be.Left)
+	sb.WriteString(" " + be.Op.String() + " ")
+	writeGrouped(sb, be.Right)
+	sb.WriteByte(')')
+}
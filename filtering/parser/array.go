@@ -30,7 +30,7 @@ var arrayExprPool = &sync.Pool{
 }
 
 func getArrayExpr() *ast.ArrayExpr {
-	return arrayExprPool.Get().(*ast.ArrayExpr)
+	return getPooled[*ast.ArrayExpr](arrayExprPool)
 }
 
 func putArrayExpr(a *ast.ArrayExpr) {
@@ -42,20 +42,41 @@ func putArrayExpr(a *ast.ArrayExpr) {
 		putComparableExpr(a.Elements[i])
 	}
 	a.Elements = a.Elements[:0]
-	arrayExprPool.Put(a)
+	putPooled(arrayExprPool, a)
 }
 
 func (p *Parser) parseArrayExpr(pos token.Position) (*ast.ArrayExpr, error) {
+	return p.parseDelimitedArrayExpr(pos, token.BRACKET_OPEN, token.BRACKET_CLOSE)
+}
+
+// parseInListExpr parses the parenthesized list argument of the IN comparator, i.e. 'field IN ("a", "b")'.
+// It results in the same ArrayExpr as the bracketed array, which is the canonical form of its String.
+func (p *Parser) parseInListExpr(pos token.Position) (*ast.ArrayExpr, error) {
+	return p.parseDelimitedArrayExpr(pos, token.LPAREN, token.RPAREN)
+}
+
+// parseDelimitedArrayExpr parses the comma separated array elements, surrounded by the open and closing tokens.
+func (p *Parser) parseDelimitedArrayExpr(pos token.Position, open, closing token.Token) (_ *ast.ArrayExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleArray), &err)
+	}
+
 	a := getArrayExpr()
 	a.LBracket = pos
 
 	pos, tok, lit := p.scanner.Scan()
-	if tok != token.BRACKET_OPEN {
+	if tok != open {
 		if p.err != nil {
-			p.err(pos, "array: '[' expected but got: "+lit)
+			p.err(pos, "array: '"+open.String()+"' expected but got: "+lit)
 		}
+		putArrayExpr(a)
 		return nil, ErrInvalidFilterSyntax
 	}
+	if err = p.enterNesting(pos); err != nil {
+		putArrayExpr(a)
+		return nil, err
+	}
+	defer p.exitNesting()
 
 	i := 0
 	for {
@@ -70,7 +91,7 @@ func (p *Parser) parseArrayExpr(pos token.Position) (*ast.ArrayExpr, error) {
 			return false
 		})
 
-		if (i > 0 && pt != token.COMMA) || (i == 0 && pt == token.BRACKET_CLOSE) {
+		if (i > 0 && pt != token.COMMA) || (i == 0 && pt == closing) {
 			break
 		}
 		p.scanner.SkipWhitespace()
@@ -86,9 +107,9 @@ func (p *Parser) parseArrayExpr(pos token.Position) (*ast.ArrayExpr, error) {
 	}
 
 	pos, tok, lit = p.scanner.Scan()
-	if tok != token.BRACKET_CLOSE {
+	if tok != closing {
 		if p.err != nil {
-			p.err(pos, "array: ']' expected but got: "+lit)
+			p.err(pos, "array: '"+closing.String()+"' expected but got: "+lit)
 		}
 		putArrayExpr(a)
 		return nil, ErrInvalidFilterSyntax
@@ -0,0 +1,134 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// checkChainedComparison checks if the restriction is followed by another comparator,
+// i.e. '1 < x < 10', which is a common syntax of the Python-like languages.
+// Such a comparison of a field between two literals is reported with the message suggesting the equivalent
+// conjunction, i.e. 'x > 1 AND x < 10', while any other chained comparison is reported with a generic message.
+func (p *Parser) checkChainedComparison(re *ast.RestrictionExpr) error {
+	bp := p.scanner.Breakpoint()
+	p.scanner.SkipWhitespace()
+
+	var isComparator bool
+	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
+		isComparator = tok.IsComparator()
+		return false
+	})
+	if !isComparator {
+		p.scanner.Restore(bp)
+		return nil
+	}
+
+	cmp, err := p.parseComparator()
+	if err != nil {
+		return err
+	}
+	defer putComparatorLiteral(cmp)
+
+	// Parse the chained argument silently, it is only used to suggest the valid filter.
+	p.scanner.SkipWhitespace()
+	errHandler := p.err
+	p.err = nil
+	arg, err := p.parseArgExpr()
+	p.err = errHandler
+
+	if p.err != nil {
+		msg := "restriction: chained comparisons are not supported, join the comparisons with the AND operator"
+		if err == nil {
+			if suggestion, ok := chainedSuggestion(re, cmp, arg); ok {
+				msg = fmt.Sprintf("restriction: chained comparisons are not supported, use '%s' instead", suggestion)
+			}
+		}
+		p.err(cmp.Pos, msg)
+	}
+	if arg != nil {
+		putArgExpr(arg)
+	}
+	if err != nil || !p.recoverable() {
+		return ErrInvalidFilterSyntax
+	}
+	return nil
+}
+
+// chainedSuggestion returns the conjunction equivalent to the chained comparison 'a op1 b op2 c',
+// i.e. 'b op1' a AND b op2 c', where op1' is the op1 with swapped operands.
+// The conjunction is suggested only if the a and c are literals and the b is a field,
+// as otherwise the intent of the chained comparison, i.e. 'x > 1 < 2', is unclear.
+func chainedSuggestion(re *ast.RestrictionExpr, cmp *ast.ComparatorLiteral, c ast.ArgExpr) (string, bool) {
+	if !isLiteralArg(re.Comparable) || !isFieldArg(re.Arg) || !isLiteralArg(c) {
+		return "", false
+	}
+	swapped, ok := swapComparator(re.Comparator.Type)
+	if !ok {
+		return "", false
+	}
+
+	a, b := re.Comparable.String(), re.Arg.String()
+	var sb strings.Builder
+	sb.WriteString(b + " " + swapped.String() + " " + a)
+	sb.WriteString(" AND " + b + " " + cmp.Type.String() + " " + c.String())
+	return sb.String(), true
+}
+
+// isLiteralArg checks if the argument is a single literal value, i.e. a string, number or timestamp.
+func isLiteralArg(arg ast.ArgExpr) bool {
+	me, ok := arg.(*ast.MemberExpr)
+	if !ok || len(me.Fields) > 0 {
+		return false
+	}
+	switch vt := me.Value.(type) {
+	case *ast.StringLiteral:
+		return true
+	case *ast.TextLiteral:
+		return vt.Token.IsLiteral() && vt.Token != token.IDENT
+	}
+	return false
+}
+
+// isFieldArg checks if the argument is a field selector, i.e. 'x' or 'a.b'.
+func isFieldArg(arg ast.ArgExpr) bool {
+	me, ok := arg.(*ast.MemberExpr)
+	if !ok {
+		return false
+	}
+	tl, ok := me.Value.(*ast.TextLiteral)
+	return ok && tl.Token == token.IDENT
+}
+
+// swapComparator returns the comparator equivalent to the input one with the swapped operands.
+func swapComparator(c ast.ComparatorType) (ast.ComparatorType, bool) {
+	switch c {
+	case ast.EQ, ast.NE:
+		return c, true
+	case ast.LT:
+		return ast.GT, true
+	case ast.LE:
+		return ast.GE, true
+	case ast.GT:
+		return ast.LT, true
+	case ast.GE:
+		return ast.LE, true
+	}
+	return c, false
+}
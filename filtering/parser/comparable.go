@@ -35,7 +35,11 @@ func putComparableExpr(e ast.ComparableExpr) {
 	}
 }
 
-func (p *Parser) parseComparableExpr() (ast.ComparableExpr, error) {
+func (p *Parser) parseComparableExpr() (_ ast.ComparableExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleComparable), &err)
+	}
+
 	var (
 		pos token.Position
 		tok token.Token
@@ -56,6 +60,8 @@ func (p *Parser) parseComparableExpr() (ast.ComparableExpr, error) {
 	case tok == token.BRACKET_OPEN:
 		// This is returned from scanner only when arrays are enabled.
 		return p.parseArrayExpr(pos)
+	case tok == token.RESERVED:
+		return nil, p.reservedKeywordError(pos, lit)
 	default:
 		if p.err != nil {
 			p.err(pos, "comparable: STRING, TEXT or Keyword expected but got: '"+lit+"'")
@@ -76,6 +82,9 @@ func (p *Parser) parseComparableExpr() (ast.ComparableExpr, error) {
 			switch {
 			case tok == token.STRING:
 			case tok.IsNonStringLit() || tok.IsKeyword():
+			case tok == token.RESERVED:
+				putNameParts(np)
+				return nil, p.reservedKeywordError(pos, lit)
 			default:
 				if !tok.IsKeyword() {
 					if p.err != nil {
@@ -19,7 +19,20 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseComparator() (*ast.ComparatorLiteral, error) {
+// CustomComparatorsOption makes the parser recognize the custom comparators, i.e. "~" or "^=",
+// which result in the ast.ComparatorLiteral of the ast.CUSTOM type, with the Symbol of the comparator.
+// The symbols need to be valid, see the scanner.IsValidComparator.
+func CustomComparatorsOption(symbols ...string) ParserOption {
+	return func(p *Parser) {
+		p.comparators = symbols
+	}
+}
+
+func (p *Parser) parseComparator() (_ *ast.ComparatorLiteral, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleComparator), &err)
+	}
+
 	// Parse the restriction operator.
 	pos, tok, lit := p.scanner.Scan()
 	switch {
@@ -50,6 +63,13 @@ func (p *Parser) parseComparator() (*ast.ComparatorLiteral, error) {
 		cl.Type = ast.HAS
 	case token.IN:
 		cl.Type = ast.IN
+	case token.MATCH:
+		cl.Type = ast.MATCH
+	case token.NHAS:
+		cl.Type = ast.NOT_HAS
+	case token.CUSTOM:
+		cl.Type = ast.CUSTOM
+		cl.Symbol = lit
 	default:
 		if p.err != nil {
 			p.err(pos, "restriction: unknown comparator: "+lit)
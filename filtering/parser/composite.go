@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseCompositeExpr() (*ast.CompositeExpr, error) {
+func (p *Parser) parseCompositeExpr() (_ *ast.CompositeExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleComposite), &err)
+	}
+
 	pos, tok, lit := p.scanner.Scan()
 	if tok != token.LPAREN {
 		if p.err != nil {
@@ -28,6 +32,11 @@ func (p *Parser) parseCompositeExpr() (*ast.CompositeExpr, error) {
 		return nil, ErrInvalidFilterSyntax
 	}
 
+	if err = p.enterNesting(pos); err != nil {
+		return nil, err
+	}
+	defer p.exitNesting()
+
 	cl := getCompositeExpr()
 	cl.Lparen = pos
 
@@ -35,7 +44,9 @@ func (p *Parser) parseCompositeExpr() (*ast.CompositeExpr, error) {
 	_ = p.scanner.SkipWhitespace()
 
 	// Parse the expression element.
+	p.nesting++
 	expr, err := p.parseExpr()
+	p.nesting--
 	if err != nil {
 		return nil, err
 	}
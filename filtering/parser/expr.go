@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseExpr() (*ast.Expr, error) {
+func (p *Parser) parseExpr() (_ *ast.Expr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleExpr), &err)
+	}
+
 	// Expression is a single or 'AND' separated sequences.
 	// Parse the first sequence.
 	p.scanner.SkipWhitespace()
@@ -44,7 +48,9 @@ func (p *Parser) parseExpr() (*ast.Expr, error) {
 			if p.err != nil {
 				p.err(p.scanner.Pos(), "expr: only one WS is allowed between sequence and AND operator")
 			}
-			return nil, ErrInvalidFilterSyntax
+			if !p.recoverable() {
+				return nil, ErrInvalidFilterSyntax
+			}
 		}
 
 		// Parse the AND operator.
@@ -68,15 +74,20 @@ func (p *Parser) parseExpr() (*ast.Expr, error) {
 				if p.err != nil {
 					p.err(p.scanner.Pos(), "expr: WS expected after AND operator")
 				}
-				return nil, ErrInvalidFilterSyntax
+				if !p.recoverable() {
+					return nil, ErrInvalidFilterSyntax
+				}
 			}
 			if p.strictWhiteSpaces && n > 1 {
 				if p.err != nil {
 					p.err(p.scanner.Pos(), "expr: only one WS is allowed between AND operator and sequence")
 				}
-				return nil, ErrInvalidFilterSyntax
+				if !p.recoverable() {
+					return nil, ErrInvalidFilterSyntax
+				}
 			}
-		case token.EOF:
+		case token.EOF, token.RPAREN:
+			// The closing parenthesis is handled by the composite expression, or reported as unexpected.
 			return expr, nil
 		default:
 			if p.err != nil {
@@ -16,6 +16,7 @@ package parser
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/blockysource/blocky-aip/filtering/ast"
 	"github.com/blockysource/blocky-aip/token"
@@ -108,20 +109,55 @@ var (
 	}
 )
 
+// poolingDisabled disables the pools of the AST nodes, see SetPoolingEnabled.
+var poolingDisabled atomic.Bool
+
+// SetPoolingEnabled enables or disables the pooling of the AST nodes, which is enabled by default.
+// With the pooling enabled, the nodes of the ParsedFilter are reused by the following Parse calls once it is freed,
+// so that the parsing of a simple filter does not allocate.
+// With the pooling disabled, each Parse allocates new nodes, and the ParsedFilter.Free does nothing,
+// so that the AST could be kept after Free, i.e. when it is hard to track its lifetime.
+// It only affects the nodes allocated after the call, and is safe for concurrent use.
+func SetPoolingEnabled(enabled bool) {
+	poolingDisabled.Store(!enabled)
+}
+
+// PoolingEnabled returns true if the AST nodes are pooled.
+func PoolingEnabled() bool {
+	return !poolingDisabled.Load()
+}
+
+// getPooled takes a node from the pool, or allocates a new one if the pooling is disabled.
+func getPooled[T any](pool *sync.Pool) T {
+	if poolingDisabled.Load() {
+		return pool.New().(T)
+	}
+	return pool.Get().(T)
+}
+
+// putPooled puts a reset node back to the pool, unless the pooling is disabled.
+func putPooled[T any](pool *sync.Pool, x T) {
+	if poolingDisabled.Load() {
+		return
+	}
+	pool.Put(x)
+}
+
 func getParsedFilter() *ParsedFilter {
-	return parsedFilterPool.Get().(*ParsedFilter)
+	return getPooled[*ParsedFilter](&parsedFilterPool)
 }
 
 func putParsedFilter(f *ParsedFilter) {
-	if f == nil {
+	if f == nil || poolingDisabled.Load() {
 		return
 	}
 	putExpr(f.Expr)
 	f.Expr = nil
+	putPooled(&parsedFilterPool, f)
 }
 
 func getExpr() *ast.Expr {
-	return exprPool.Get().(*ast.Expr)
+	return getPooled[*ast.Expr](&exprPool)
 }
 
 func putExpr(e *ast.Expr) {
@@ -133,7 +169,7 @@ func putExpr(e *ast.Expr) {
 		putSequenceExpr(v)
 	}
 	e.Sequences = e.Sequences[:0]
-	exprPool.Put(e)
+	putPooled(&exprPool, e)
 }
 
 func putSequenceExpr(e *ast.SequenceExpr) {
@@ -145,11 +181,11 @@ func putSequenceExpr(e *ast.SequenceExpr) {
 		putFactorExpr(v)
 	}
 	e.Factors = e.Factors[:0]
-	sequenceExprPool.Put(e)
+	putPooled(&sequenceExprPool, e)
 }
 
 func getSequenceExpr() *ast.SequenceExpr {
-	return sequenceExprPool.Get().(*ast.SequenceExpr)
+	return getPooled[*ast.SequenceExpr](&sequenceExprPool)
 }
 
 func putFactorExpr(e *ast.FactorExpr) {
@@ -161,11 +197,11 @@ func putFactorExpr(e *ast.FactorExpr) {
 	}
 	e.Terms = e.Terms[:0]
 	e.Pos = 0
-	factorExprPool.Put(e)
+	putPooled(&factorExprPool, e)
 }
 
 func getFactorExpr() *ast.FactorExpr {
-	return factorExprPool.Get().(*ast.FactorExpr)
+	return getPooled[*ast.FactorExpr](&factorExprPool)
 }
 
 func putTermExpr(e *ast.TermExpr) {
@@ -176,11 +212,11 @@ func putTermExpr(e *ast.TermExpr) {
 	e.Expr = nil
 	e.UnaryOp = ""
 	e.Pos = 0
-	termExprPool.Put(e)
+	putPooled(&termExprPool, e)
 }
 
 func getTermExpr() *ast.TermExpr {
-	return termExprPool.Get().(*ast.TermExpr)
+	return getPooled[*ast.TermExpr](&termExprPool)
 }
 
 func putSimpleExpr(e ast.SimpleExpr) {
@@ -206,10 +242,11 @@ func putRestrictionExpr(e *ast.RestrictionExpr) {
 	e.Comparator = nil
 	putArgExpr(e.Arg)
 	e.Arg = nil
+	putPooled(&restrictionExprPool, e)
 }
 
 func getRestrictionExpr() *ast.RestrictionExpr {
-	return restrictionExprPool.Get().(*ast.RestrictionExpr)
+	return getPooled[*ast.RestrictionExpr](&restrictionExprPool)
 }
 
 func putMemberLiteral(e *ast.MemberExpr) {
@@ -224,11 +261,11 @@ func putMemberLiteral(e *ast.MemberExpr) {
 		putFieldExpr(v)
 	}
 	e.Fields = e.Fields[:0]
-	memberLiteralPool.Put(e)
+	putPooled(&memberLiteralPool, e)
 }
 
 func getMemberExpr() *ast.MemberExpr {
-	return memberLiteralPool.Get().(*ast.MemberExpr)
+	return getPooled[*ast.MemberExpr](&memberLiteralPool)
 }
 
 func putFunctionLiteral(e *ast.FunctionCall) {
@@ -245,11 +282,15 @@ func putFunctionLiteral(e *ast.FunctionCall) {
 	putArgListExpr(e.ArgList)
 	e.ArgList = nil
 	e.Rparen = 0
-	funcCallPool.Put(e)
+	for _, v := range e.Fields {
+		putFieldExpr(v)
+	}
+	e.Fields = e.Fields[:0]
+	putPooled(&funcCallPool, e)
 }
 
 func getFunctionCall() *ast.FunctionCall {
-	return funcCallPool.Get().(*ast.FunctionCall)
+	return getPooled[*ast.FunctionCall](&funcCallPool)
 }
 
 func putComparatorLiteral(e *ast.ComparatorLiteral) {
@@ -258,11 +299,12 @@ func putComparatorLiteral(e *ast.ComparatorLiteral) {
 	}
 	e.Pos = 0
 	e.Type = 0
-	comparatorLiteralPool.Put(e)
+	e.Symbol = ""
+	putPooled(&comparatorLiteralPool, e)
 }
 
 func getComparatorLiteral() *ast.ComparatorLiteral {
-	return comparatorLiteralPool.Get().(*ast.ComparatorLiteral)
+	return getPooled[*ast.ComparatorLiteral](&comparatorLiteralPool)
 }
 
 func putCompositeLiteral(e *ast.CompositeExpr) {
@@ -274,10 +316,11 @@ func putCompositeLiteral(e *ast.CompositeExpr) {
 
 	putExpr(e.Expr)
 	e.Expr = nil
+	putPooled(&compositeExprPool, e)
 }
 
 func getCompositeExpr() *ast.CompositeExpr {
-	return compositeExprPool.Get().(*ast.CompositeExpr)
+	return getPooled[*ast.CompositeExpr](&compositeExprPool)
 }
 
 func putValueExpr(e ast.ValueExpr) {
@@ -296,11 +339,11 @@ func putTextLiteral(e *ast.TextLiteral) {
 	e.Pos = 0
 	e.Value = ""
 	e.Token = token.ILLEGAL
-	textLiteralPool.Put(e)
+	putPooled(&textLiteralPool, e)
 }
 
 func getTextLiteral() *ast.TextLiteral {
-	return textLiteralPool.Get().(*ast.TextLiteral)
+	return getPooled[*ast.TextLiteral](&textLiteralPool)
 }
 
 func putStringLiteral(e *ast.StringLiteral) {
@@ -309,11 +352,12 @@ func putStringLiteral(e *ast.StringLiteral) {
 	}
 	e.Pos = 0
 	e.Value = ""
-	stringLiteralPool.Put(e)
+	e.Bytes = false
+	putPooled(&stringLiteralPool, e)
 }
 
 func getStringLiteral() *ast.StringLiteral {
-	return stringLiteralPool.Get().(*ast.StringLiteral)
+	return getPooled[*ast.StringLiteral](&stringLiteralPool)
 }
 
 func putArgExpr(e ast.ArgExpr) {
@@ -324,6 +368,10 @@ func putArgExpr(e ast.ArgExpr) {
 	switch vt := e.(type) {
 	case *ast.CompositeExpr:
 		putCompositeLiteral(vt)
+	case *ast.RestrictionExpr:
+		putRestrictionExpr(vt)
+	case *ast.BinaryExpr:
+		putBinaryExpr(vt)
 	case ast.ComparableExpr:
 		putComparableExpr(vt)
 	}
@@ -337,11 +385,11 @@ func putArgListExpr(e *ast.ArgListExpr) {
 		putArgExpr(v)
 	}
 	e.Args = e.Args[:0]
-	argListExprPool.Put(e)
+	putPooled(&argListExprPool, e)
 }
 
 func getArgListExpr() *ast.ArgListExpr {
-	return argListExprPool.Get().(*ast.ArgListExpr)
+	return getPooled[*ast.ArgListExpr](&argListExprPool)
 }
 
 func putNameExpr(e ast.NameExpr) {
@@ -19,11 +19,15 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseFactorExpr() (*ast.FactorExpr, error) {
+func (p *Parser) parseFactorExpr() (_ *ast.FactorExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleFactor), &err)
+	}
+
 	factor := getFactorExpr()
 
 	// Parse the first term.
-	term, err := p.parseTermExpr()
+	term, err := p.parseTermOrRecover()
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +47,9 @@ func (p *Parser) parseFactorExpr() (*ast.FactorExpr, error) {
 			if p.err != nil {
 				p.err(p.scanner.Pos(), "factor: only one WS is allowed between term and OR operator")
 			}
-			return nil, ErrInvalidFilterSyntax
+			if !p.recoverable() {
+				return nil, ErrInvalidFilterSyntax
+			}
 		}
 
 		// Parse the NOT operator.
@@ -77,17 +83,21 @@ func (p *Parser) parseFactorExpr() (*ast.FactorExpr, error) {
 			if p.err != nil {
 				p.err(p.scanner.Pos(), "factor: WS expected after OR operator")
 			}
-			return nil, ErrInvalidFilterSyntax
+			if !p.recoverable() {
+				return nil, ErrInvalidFilterSyntax
+			}
 		}
 		if p.strictWhiteSpaces && n > 1 {
 			if p.err != nil {
 				p.err(p.scanner.Pos(), "factor: only one WS is allowed between OR operator and term")
 			}
-			return nil, ErrInvalidFilterSyntax
+			if !p.recoverable() {
+				return nil, ErrInvalidFilterSyntax
+			}
 		}
 
 		// Parse the next term.
-		term, err = p.parseTermExpr()
+		term, err = p.parseTermOrRecover()
 		if err != nil {
 			return nil, err
 		}
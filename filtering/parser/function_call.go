@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseFuncCall(nameParts *nameParts) (*ast.FunctionCall, error) {
+func (p *Parser) parseFuncCall(nameParts *nameParts) (_ *ast.FunctionCall, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleFunction), &err)
+	}
+
 	fl := getFunctionCall()
 	fl.Pos = nameParts.parts[0].pos
 
@@ -50,6 +54,11 @@ func (p *Parser) parseFuncCall(nameParts *nameParts) (*ast.FunctionCall, error)
 	}
 	fl.Lparen = pos
 
+	if err = p.enterNesting(pos); err != nil {
+		return nil, err
+	}
+	defer p.exitNesting()
+
 	var isRParen bool
 	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
 		isRParen = tok == token.RPAREN
@@ -57,7 +66,7 @@ func (p *Parser) parseFuncCall(nameParts *nameParts) (*ast.FunctionCall, error)
 		return isRParen
 	})
 	if isRParen {
-		return fl, nil
+		return p.parseFuncCallFields(fl)
 	}
 
 	// Skip possible whitespaces before the first argument.
@@ -94,5 +103,40 @@ func (p *Parser) parseFuncCall(nameParts *nameParts) (*ast.FunctionCall, error)
 	}
 	fl.Rparen = pos
 
-	return fl, nil
+	return p.parseFuncCallFields(fl)
+}
+
+// parseFuncCallFields parses the fields selected from the function call result, i.e.: any.Unpack(payload, my.Type).name.
+func (p *Parser) parseFuncCallFields(fl *ast.FunctionCall) (*ast.FunctionCall, error) {
+	for {
+		var isPeriod bool
+		p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
+			isPeriod = tok == token.PERIOD
+			return isPeriod
+		})
+		if !isPeriod {
+			return fl, nil
+		}
+
+		pos, tok, lit := p.scanner.Scan()
+		switch {
+		case tok == token.STRING:
+			sl := getStringLiteral()
+			sl.Pos = pos
+			sl.Value = lit
+			fl.Fields = append(fl.Fields, sl)
+		case tok.IsNonStringLit(), tok.IsKeyword():
+			text := getTextLiteral()
+			text.Pos = pos
+			text.Value = lit
+			text.Token = tok
+			fl.Fields = append(fl.Fields, text)
+		default:
+			if p.err != nil {
+				p.err(pos, "function: TEXT, STRING or Keyword expected after the function call period but got: "+lit)
+			}
+			putFunctionLiteral(fl)
+			return nil, ErrInvalidFilterSyntax
+		}
+	}
 }
@@ -72,6 +72,37 @@ func testFuncCallNoArg(t *testing.T, pf *ParsedFilter) {
 	}
 }
 
+const funcCallFields = `any.Unpack(payload, my.Type).sub.name = "foo"`
+
+func testFuncCallFields(t *testing.T, pf *ParsedFilter) {
+	if pf.Expr == nil {
+		t.Fatalf("expected parsed filter got: %v", pf)
+	}
+	if len(pf.Expr.Sequences) != 1 {
+		t.Fatalf("expected one sequence got: %v", pf.Expr.Sequences)
+	}
+	fnCall := seqFuncCall(t, pf.Expr.Sequences[0])
+
+	if fnCall.JoinedName() != "any.Unpack" {
+		t.Fatalf("expected 'any.Unpack' got: %v", fnCall.JoinedName())
+	}
+	if fnCall.ArgList == nil || len(fnCall.ArgList.Args) != 2 {
+		t.Fatalf("expected two arguments got: %v", fnCall.ArgList)
+	}
+	if len(fnCall.Fields) != 2 {
+		t.Fatalf("expected two fields got: %v", len(fnCall.Fields))
+	}
+	if fnCall.Fields[0].String() != "sub" || fnCall.Fields[1].String() != "name" {
+		t.Fatalf("expected 'sub.name' fields got: %v.%v", fnCall.Fields[0], fnCall.Fields[1])
+	}
+	if fnCall.Fields[1].Position() != 33 {
+		t.Fatalf("expected position 33 got: %v", fnCall.Fields[1].Position())
+	}
+	if got := fnCall.String(); got != "any.Unpack(payload, my.Type).sub.name" {
+		t.Fatalf("expected 'any.Unpack(payload, my.Type).sub.name' got: %v", got)
+	}
+}
+
 const complexFuncCall = "regex(m.key, '^.*prod.*$')"
 
 func testComplexFuncCall(t *testing.T, pf *ParsedFilter) {
@@ -213,3 +244,36 @@ func testComplexFuncCall(t *testing.T, pf *ParsedFilter) {
 		t.Fatalf("expected '^.*prod.*$' got: '%v'", sl.Value)
 	}
 }
+
+const funcCallRestrictionArg = `all(items, quantity > 0)`
+
+func testFuncCallRestrictionArg(t *testing.T, pf *ParsedFilter) {
+	if pf.Expr == nil {
+		t.Fatalf("expected parsed filter got: %v", pf)
+	}
+	if len(pf.Expr.Sequences) != 1 {
+		t.Fatalf("expected one sequence got: %v", pf.Expr.Sequences)
+	}
+	fnCall := seqFuncCall(t, pf.Expr.Sequences[0])
+	if fnCall.ArgList == nil || len(fnCall.ArgList.Args) != 2 {
+		t.Fatalf("expected two arguments got: %v", fnCall.ArgList)
+	}
+
+	if _, ok := fnCall.ArgList.Args[0].(*ast.MemberExpr); !ok {
+		t.Fatalf("expected member expression got: %T", fnCall.ArgList.Args[0])
+	}
+
+	re, ok := fnCall.ArgList.Args[1].(*ast.RestrictionExpr)
+	if !ok {
+		t.Fatalf("expected restriction expression got: %T", fnCall.ArgList.Args[1])
+	}
+	if re.Comparator == nil || re.Comparator.Type != ast.GT {
+		t.Fatalf("expected '>' comparator got: %v", re.Comparator)
+	}
+	if re.Pos != 11 {
+		t.Fatalf("expected position 11 got: %v", re.Pos)
+	}
+	if got := re.String(); got != "quantity > 0" {
+		t.Fatalf("expected 'quantity > 0' got: %v", got)
+	}
+}
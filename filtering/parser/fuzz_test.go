@@ -0,0 +1,67 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+var fuzzSeeds = []string{
+	``,
+	`a = "b"`,
+	`a.b.c:* AND NOT d < 1.5e3 OR e != -10`,
+	`map."key" = 'value' AND ts > 2023-01-01T00:00:00Z`,
+	`d >= 10s AND fn(a, [1, 2], x{y: "z"})`,
+	`a IN ("x", "y") AND b NOT IN [1] AND c =~ "^f"`,
+	`(a = 1 OR (b = 2 AND (c = 3)))`,
+	`x = 1 + 2 * (3 - y)`,
+	`NOT NOT NOT a`,
+	`a = (`,
+	strings.Repeat("(", 200),
+	strings.Repeat("f(", 200),
+}
+
+func FuzzParser(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, false)
+	}
+	f.Fuzz(func(t *testing.T, src string, recoverErrors bool) {
+		opts := []ParserOption{
+			ErrorHandlerOption(func(pos token.Position, msg string) {}),
+			CustomComparatorsOption("~"),
+			MaxDepthOption(32),
+		}
+		if recoverErrors {
+			opts = append(opts, RecoverErrorsOption())
+		}
+		p := NewParser(src, opts...)
+		pf, err := p.Parse()
+		if err != nil && !errors.Is(err, ErrInvalidFilterSyntax) && !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("unexpected error for %q: %v", src, err)
+		}
+		if pf == nil {
+			return
+		}
+		if pf.Expr != nil {
+			// Rendering of any parsed filter, including the partial one, must not panic.
+			_ = pf.Expr.String()
+		}
+		pf.Free()
+	})
+}
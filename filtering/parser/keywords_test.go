@@ -241,3 +241,88 @@ func testNotAndOr(t *testing.T, pf *ParsedFilter) {
 		t.Fatalf("expected no fields got: %v", m.Fields)
 	}
 }
+
+func TestParser_ReservedKeywords(t *testing.T) {
+	reserved := DefaultReservedKeywords()
+	reserved["ILIKE"] = ""
+
+	tests := []struct {
+		name string
+		src  string
+		pos  token.Position
+		msg  string
+	}{
+		{
+			name: "operator",
+			src:  `x BETWEEN 1 AND 10`,
+			pos:  2,
+			msg:  "restriction: BETWEEN is not supported; use range comparisons, i.e. x >= 1 AND x <= 10",
+		},
+		{
+			name: "negated operator",
+			src:  `a = 1 AND NOT name LIKE "abc%"`,
+			pos:  19,
+			msg:  `restriction: LIKE is not supported; use the equality with a wildcard, i.e. x = "abc*"`,
+		},
+		{
+			name: "field name",
+			src:  `IS = 1`,
+			pos:  0,
+			msg:  "restriction: IS is not supported; use the comparison with null, i.e. x = null",
+		},
+		{
+			name: "argument",
+			src:  `x = ILIKE`,
+			pos:  4,
+			msg:  "restriction: ILIKE is not supported",
+		},
+		{
+			name: "nested field name",
+			src:  `sub.LIKE = 1`,
+			pos:  4,
+			msg:  `restriction: LIKE is not supported; use the equality with a wildcard, i.e. x = "abc*"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				errs []token.Position
+				msgs []string
+			)
+			p := NewParser(tt.src, ReservedKeywordsOption(reserved), ErrorHandlerOption(func(pos token.Position, msg string) {
+				errs = append(errs, pos)
+				msgs = append(msgs, msg)
+			}))
+			pf, err := p.Parse()
+			if err == nil {
+				pf.Free()
+				t.Fatal("expected error")
+			}
+			if len(msgs) == 0 {
+				t.Fatal("expected error message")
+			}
+			if errs[0] != tt.pos {
+				t.Errorf("expected error at %d but got %d", tt.pos, errs[0])
+			}
+			if msgs[0] != tt.msg {
+				t.Errorf("expected message %q but got %q", tt.msg, msgs[0])
+			}
+		})
+	}
+
+	t.Run("not registered", func(t *testing.T) {
+		pf, err := NewParser(`x BETWEEN 1`).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pf.Free()
+	})
+
+	t.Run("backtick quoted", func(t *testing.T) {
+		pf, err := NewParser("`LIKE` = 1", ReservedKeywordsOption(reserved)).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pf.Free()
+	})
+}
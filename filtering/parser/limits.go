@@ -0,0 +1,85 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+const (
+	// DefaultMaxDepth is the default maximum nesting depth of the filter, see MaxDepthOption.
+	DefaultMaxDepth = 100
+
+	// DefaultMaxLength is the default maximum length in bytes of the filter, see MaxLengthOption.
+	DefaultMaxLength = 1 << 20
+)
+
+// ErrLimitExceeded is returned when the input string filter exceeds the length or the nesting depth limit.
+var ErrLimitExceeded = errors.New("filter limit exceeded")
+
+// MaxDepthOption limits the nesting depth of the filter, so that the malformed deeply nested filters,
+// i.e. '((((((...', cannot exhaust the stack of the recursive descent parser.
+// Each parenthesized expression, function call, array and struct increases the depth by one,
+// i.e. the filter 'a = 1 AND (b = 2 OR c = fn(3))' has the depth of 3.
+// A filter that exceeds the limit fails with the ErrLimitExceeded error, even with the RecoverErrorsOption.
+// A non-positive n sets the DefaultMaxDepth.
+func MaxDepthOption(n int) ParserOption {
+	return func(p *Parser) {
+		p.maxDepth = n
+	}
+}
+
+// MaxLengthOption limits the length in bytes of the filter.
+// A filter that exceeds the limit fails with the ErrLimitExceeded error, before it is scanned.
+// A non-positive n sets the DefaultMaxLength.
+func MaxLengthOption(n int) ParserOption {
+	return func(p *Parser) {
+		p.maxLength = n
+	}
+}
+
+func (p *Parser) depthLimit() int {
+	if p.maxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return p.maxDepth
+}
+
+func (p *Parser) lengthLimit() int {
+	if p.maxLength <= 0 {
+		return DefaultMaxLength
+	}
+	return p.maxLength
+}
+
+// enterNesting increases the nesting depth, unless it exceeds the limit.
+// On success, it needs to be followed by the exitNesting, once the nested expression is parsed.
+func (p *Parser) enterNesting(pos token.Position) error {
+	if limit := p.depthLimit(); p.depth >= limit {
+		if p.err != nil {
+			p.err(pos, "filter depth exceeds the limit of "+strconv.Itoa(limit))
+		}
+		return ErrLimitExceeded
+	}
+	p.depth++
+	return nil
+}
+
+func (p *Parser) exitNesting() {
+	p.depth--
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestParser_Limits(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		opts  []ParserOption
+		isErr bool
+		pos   token.Position
+	}{
+		{
+			name: "depth within limit",
+			src:  `a = 1 AND (b = 2 OR c = fn(3))`,
+			opts: []ParserOption{MaxDepthOption(3)},
+		},
+		{
+			name:  "depth exceeded by function call",
+			src:   `a = 1 AND (b = 2 OR c = fn(3))`,
+			opts:  []ParserOption{MaxDepthOption(2)},
+			isErr: true,
+			pos:   26,
+		},
+		{
+			name:  "depth exceeded by array",
+			src:   `a IN [1, [2]]`,
+			opts:  []ParserOption{MaxDepthOption(2)},
+			isErr: true,
+			pos:   9,
+		},
+		{
+			name:  "depth exceeded by struct",
+			src:   `a = x{b: y{c: 1}}`,
+			opts:  []ParserOption{MaxDepthOption(2)},
+			isErr: true,
+			pos:   10,
+		},
+		{
+			name:  "depth exceeded with recovered errors",
+			src:   `a = ( AND ((b = 1))`,
+			opts:  []ParserOption{MaxDepthOption(2), RecoverErrorsOption()},
+			isErr: true,
+			pos:   10,
+		},
+		{
+			name:  "default depth",
+			src:   strings.Repeat("(", 100000),
+			isErr: true,
+			pos:   DefaultMaxDepth - 1,
+		},
+		{
+			name:  "keyword chain",
+			src:   strings.Repeat("NOT ", 100000) + "a",
+			isErr: true,
+		},
+		{
+			name:  "length exceeded",
+			src:   `a = "abcdef"`,
+			opts:  []ParserOption{MaxLengthOption(8)},
+			isErr: true,
+			pos:   8,
+		},
+		{
+			name:  "default length",
+			src:   "a = " + strings.Repeat("1", DefaultMaxLength),
+			isErr: true,
+			pos:   DefaultMaxLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				errPos token.Position
+				errMsg string
+			)
+			opts := append([]ParserOption{ErrorHandlerOption(func(pos token.Position, msg string) {
+				errPos, errMsg = pos, msg
+			})}, tt.opts...)
+			p := NewParser(tt.src, opts...)
+
+			pf, err := p.Parse()
+			if !tt.isErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				pf.Free()
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected error %v but got %v", ErrLimitExceeded, err)
+			}
+			if !strings.Contains(errMsg, "exceeds the limit") {
+				t.Errorf("expected limit error message but got %q", errMsg)
+			}
+			if tt.pos != 0 && errPos != tt.pos {
+				t.Errorf("expected error at %d but got %d", tt.pos, errPos)
+			}
+		})
+	}
+}
@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseMemberExpr(nameParts *nameParts) (*ast.MemberExpr, error) {
+func (p *Parser) parseMemberExpr(nameParts *nameParts) (_ *ast.MemberExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleMember), &err)
+	}
+
 	member := getMemberExpr()
 	defer putNameParts(nameParts)
 
@@ -31,6 +35,12 @@ func (p *Parser) parseMemberExpr(nameParts *nameParts) (*ast.MemberExpr, error)
 				sl.Pos = np.pos
 				sl.Value = np.lit
 				member.Value = sl
+			case np.tok == token.BYTES && len(nameParts.parts) == 1:
+				sl := getStringLiteral()
+				sl.Pos = np.pos
+				sl.Value = np.lit
+				sl.Bytes = true
+				member.Value = sl
 			case np.tok.IsNonStringLit(), np.tok.IsKeyword():
 				text := getTextLiteral()
 				text.Pos = np.pos
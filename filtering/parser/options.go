@@ -0,0 +1,91 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/blockysource/blocky-aip/scanner"
+)
+
+// ParserOptions is a plain structure alternative to the ParserOption functions.
+// It could be decoded from the configuration files or environment, and compared across the services.
+type ParserOptions struct {
+	// StrictWhitespaces makes the parser fail on redundant whitespaces, see StrictWhitespacesOption.
+	StrictWhitespaces bool `json:"strict_whitespaces,omitempty"`
+
+	// RecoverErrors makes the parser recover from the syntax errors, see RecoverErrorsOption.
+	RecoverErrors bool `json:"recover_errors,omitempty"`
+
+	// ReservedKeywords are the reserved keywords along with their hints, see ReservedKeywordsOption.
+	ReservedKeywords scanner.ReservedWords `json:"reserved_keywords,omitempty"`
+
+	// CustomComparators are the symbols of the custom comparators, see CustomComparatorsOption.
+	CustomComparators []string `json:"custom_comparators,omitempty"`
+
+	// MaxDepth is the maximum nesting depth of the filter, see MaxDepthOption.
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// MaxLength is the maximum length in bytes of the filter, see MaxLengthOption.
+	MaxLength int `json:"max_length,omitempty"`
+
+	// ErrHandler is the error handler of the parser, see ErrorHandlerOption.
+	ErrHandler scanner.ErrorHandler `json:"-"`
+
+	// TraceHook is the function receiving the parsing trace events, see TraceHookOption.
+	TraceHook TraceHookFn `json:"-"`
+}
+
+// Option returns the parser option that applies all the non-zero fields of the options.
+func (o ParserOptions) Option() ParserOption {
+	return func(p *Parser) {
+		if o.StrictWhitespaces {
+			p.strictWhiteSpaces = true
+		}
+		if o.RecoverErrors {
+			p.recoverErrors = true
+		}
+		if o.ReservedKeywords != nil {
+			p.reserved = o.ReservedKeywords
+		}
+		if o.CustomComparators != nil {
+			p.comparators = o.CustomComparators
+		}
+		if o.MaxDepth != 0 {
+			p.maxDepth = o.MaxDepth
+		}
+		if o.MaxLength != 0 {
+			p.maxLength = o.MaxLength
+		}
+		if o.ErrHandler != nil {
+			p.err = o.ErrHandler
+		}
+		if o.TraceHook != nil {
+			TraceHookOption(o.TraceHook)(p)
+		}
+	}
+}
+
+// Options returns the options the parser is currently configured with.
+func (p *Parser) Options() ParserOptions {
+	return ParserOptions{
+		StrictWhitespaces: p.strictWhiteSpaces,
+		RecoverErrors:     p.recoverErrors,
+		ReservedKeywords:  p.reserved,
+		CustomComparators: p.comparators,
+		MaxDepth:          p.maxDepth,
+		MaxLength:         p.maxLength,
+		ErrHandler:        p.err,
+		TraceHook:         p.trace,
+	}
+}
@@ -16,6 +16,7 @@ package parser
 
 import (
 	"errors"
+	"strconv"
 	"sync"
 
 	"github.com/blockysource/blocky-aip/filtering/ast"
@@ -32,6 +33,26 @@ type Parser struct {
 	err scanner.ErrorHandler
 
 	strictWhiteSpaces bool
+
+	// reserved are the reserved keywords along with their hints.
+	reserved scanner.ReservedWords
+
+	// comparators are the symbols of the custom comparators.
+	comparators []string
+
+	recoverErrors bool
+	recovered     int
+	// nesting is the number of the composite expressions being parsed.
+	nesting int
+
+	// depth is the nesting depth of the expression being parsed, limited by the maxDepth.
+	depth     int
+	maxDepth  int
+	maxLength int
+
+	// trace is the trace hook, and traceDepth is the number of the traced rules being parsed.
+	trace      TraceHookFn
+	traceDepth int
 }
 
 // ParserOption changes the behavior of the parser.
@@ -51,6 +72,17 @@ func ErrorHandlerOption(err scanner.ErrorHandler) ParserOption {
 	}
 }
 
+// RecoverErrorsOption makes the parser recover from the syntax errors of the terms,
+// so that all of them are reported to the error handler, instead of only the first one.
+// Each invalid term is replaced by the ast.ErrorExpr, and the Parse returns the partial AST
+// along with the ErrInvalidFilterSyntax error.
+// It is meant for the tooling, i.e. editors or filter builders, reporting multiple errors at once.
+func RecoverErrorsOption() ParserOption {
+	return func(p *Parser) {
+		p.recoverErrors = true
+	}
+}
+
 // NewParser creates a new parser with the given options.
 func NewParser(src string, opts ...ParserOption) *Parser {
 	p := &Parser{src: src}
@@ -59,6 +91,8 @@ func NewParser(src string, opts ...ParserOption) *Parser {
 		opt(p)
 	}
 
+	p.scanner.Reserve(p.reserved)
+	p.scanner.RegisterComparators(p.comparators)
 	p.scanner.Reset(src, p.err)
 
 	return p
@@ -72,6 +106,8 @@ func (p *Parser) Reset(src string, opts ...ParserOption) {
 			opt(p)
 		}
 	}
+	p.scanner.Reserve(p.reserved)
+	p.scanner.RegisterComparators(p.comparators)
 	p.scanner.Reset(src, p.err)
 }
 
@@ -80,11 +116,23 @@ var ErrInvalidFilterSyntax = errors.New("invalid filter")
 
 // Parse parses the input string filter into an AST.
 // If the input was an empty string, the returned ParsedFilter will have a nil Expr.
+// With the RecoverErrorsOption, the ParsedFilter with the partial AST is returned
+// along with the ErrInvalidFilterSyntax error, if any of the syntax errors was recovered.
 func (p *Parser) Parse() (*ParsedFilter, error) {
-	pf := getParsedFilter()
+	if limit := p.lengthLimit(); len(p.src) > limit {
+		if p.err != nil {
+			p.err(token.Position(limit), "filter length exceeds the limit of "+strconv.Itoa(limit))
+		}
+		return nil, ErrLimitExceeded
+	}
+
 	if p.src == "" {
-		return pf, nil
+		return getParsedFilter(), nil
 	}
+	p.recovered = 0
+	p.nesting = 0
+	p.depth = 1
+	p.traceDepth = 0
 
 	expr, err := p.parseExpr()
 	if err != nil {
@@ -96,11 +144,19 @@ func (p *Parser) Parse() (*ParsedFilter, error) {
 		if p.err != nil {
 			p.err(pos, "expr: EOF expected but got: "+lit)
 		}
-		return nil, ErrInvalidFilterSyntax
+		if !p.recoverErrors {
+			putExpr(expr)
+			return nil, ErrInvalidFilterSyntax
+		}
+		expr.Sequences = append(expr.Sequences, p.recoverTrailing(pos))
 	}
 
+	pf := getParsedFilter()
 	pf.Expr = expr
 
+	if p.recovered > 0 || (p.recoverErrors && p.scanner.ErrorCount > 0) {
+		return pf, ErrInvalidFilterSyntax
+	}
 	return pf, nil
 }
 
@@ -119,6 +119,11 @@ func TestParse(t *testing.T) {
 			src:     restrictionWithFunctionArgListNoSpace,
 			checkFn: testRestrictionWithFunctionArgListNoSpace,
 		},
+		{
+			name:    "restriction with presence arg",
+			src:     restrictionWithPresenceArg,
+			checkFn: testRestrictionWithPresenceArg,
+		},
 		{
 			name:    "restriction with has arg",
 			src:     restrictionWithHasArg,
@@ -154,6 +159,11 @@ func TestParse(t *testing.T) {
 			src:     compositeExpression,
 			checkFn: testCompositeExpression,
 		},
+		{
+			name:    "func call result fields",
+			src:     funcCallFields,
+			checkFn: testFuncCallFields,
+		},
 		{
 			name:    "complex func call",
 			src:     complexFuncCall,
@@ -184,6 +194,11 @@ func TestParse(t *testing.T) {
 			src:     funcCallNoArg,
 			checkFn: testFuncCallNoArg,
 		},
+		{
+			name:    "func call restriction arg",
+			src:     funcCallRestrictionArg,
+			checkFn: testFuncCallRestrictionArg,
+		},
 		{
 			name:    "array with quote",
 			src:     arrayWithQuote,
@@ -303,30 +318,133 @@ func testErrHandler(t testing.TB) scanner.ErrorHandler {
 	}
 }
 
-func BenchmarkParse(b *testing.B) {
-	b.Run("Simple", func(b *testing.B) {
-		p := Parser{}
-		for i := 0; i < b.N; i++ {
-			p.Reset("a")
-			pf, err := p.Parse()
-			if err != nil {
-				b.Fatalf("unexpected error: %s", err)
+// benchFilters are the filters of the parsing benchmarks, from the simplest to the most complex.
+var benchFilters = []struct {
+	name   string
+	filter string
+}{
+	{name: "Simple", filter: "a"},
+	{name: "Restriction", filter: `a = "b"`},
+	{name: "Member", filter: "a.b.c > 10"},
+	{name: "Escaped", filter: `a = "b \"c\" d"`},
+	{name: "Timestamp", filter: "create_time > 2023-01-01T00:00:00Z AND duration < 1.5s"},
+	{name: "Function", filter: `fn.call(a, "b", 3) = true`},
+	{name: "Array", filter: `a IN ["x", "y", "z"]`},
+	{name: "Struct", filter: "a = geo.Point{Lat: 0.3, Lon: 15.6}"},
+	{name: "Complex", filter: "(a b) AND c OR d AND (e > f OR g < h)"},
+	{name: "Long", filter: "a = 1 AND b.c != \"d\" OR NOT e:f AND (g >= 2.5 OR -h < -3) AND i.j(k, l.m) AND n = [1, 2] AND o = p.Q{r: s}"},
+}
+
+func TestParse_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the pools drop the items at random with the race detector")
+	}
+
+	for _, bf := range benchFilters {
+		if bf.name == "Escaped" {
+			// The unescaped string is the only allocation.
+			continue
+		}
+		t.Run(bf.name, func(t *testing.T) {
+			var p Parser
+			allocs := testing.AllocsPerRun(100, func() {
+				p.Reset(bf.filter)
+				pf, err := p.Parse()
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				pf.Free()
+			})
+			if allocs != 0 {
+				t.Errorf("expected no allocations but got %v", allocs)
 			}
-			pf.Free()
+		})
+	}
+}
+
+func TestSetPoolingEnabled(t *testing.T) {
+	defer SetPoolingEnabled(true)
+
+	SetPoolingEnabled(false)
+	if PoolingEnabled() {
+		t.Fatal("expected pooling to be disabled")
+	}
+
+	pf, err := NewParser("a = b").Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	x := pf.Expr
+	pf.Free()
+
+	// Without the pooling, the AST is left intact by the Free.
+	if pf.Expr != x || len(x.Sequences) != 1 || x.String() != "a = b" {
+		t.Errorf("expected the freed filter to be intact, got: %v", x)
+	}
+
+	SetPoolingEnabled(true)
+	if !PoolingEnabled() {
+		t.Fatal("expected pooling to be enabled")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	defer SetPoolingEnabled(true)
+
+	for _, mode := range []struct {
+		name    string
+		pooling bool
+	}{
+		{name: "Pool", pooling: true},
+		{name: "NoPool", pooling: false},
+	} {
+		for _, bf := range benchFilters {
+			b.Run(mode.name+"/"+bf.name, func(b *testing.B) {
+				SetPoolingEnabled(mode.pooling)
+				p := Parser{}
+				b.SetBytes(int64(len(bf.filter)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p.Reset(bf.filter)
+					pf, err := p.Parse()
+					if err != nil {
+						b.Fatalf("unexpected error: %s", err)
+					}
+					pf.Free()
+				}
+			})
 		}
-	})
+	}
+}
 
-	b.Run("Complex", func(b *testing.B) {
-		p := Parser{}
-		for i := 0; i < b.N; i++ {
-			p.Reset("(a b) AND c OR d AND (e > f OR g < h)")
-			pf, err := p.Parse()
-			if err != nil {
-				b.Fatalf("unexpected error: %s", err)
-			}
-			pf.Free()
+// BenchmarkParse_Parallel measures the parsing of the filters by many goroutines sharing the pools,
+// as in a server parsing the filter of each request.
+func BenchmarkParse_Parallel(b *testing.B) {
+	defer SetPoolingEnabled(true)
+
+	for _, pooling := range []bool{true, false} {
+		name := "Pool"
+		if !pooling {
+			name = "NoPool"
 		}
-	})
+		b.Run(name, func(b *testing.B) {
+			SetPoolingEnabled(pooling)
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				var p Parser
+				for i := 0; pb.Next(); i++ {
+					p.Reset(benchFilters[i%len(benchFilters)].filter)
+					pf, err := p.Parse()
+					if err != nil {
+						b.Errorf("unexpected error: %s", err)
+						return
+					}
+					pf.Free()
+				}
+			})
+		})
+	}
 }
 
 func seqMember(t *testing.T, seq *ast.SequenceExpr) *ast.MemberExpr {
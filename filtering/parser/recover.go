@@ -0,0 +1,146 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// parseTermOrRecover parses the term, and if the RecoverErrorsOption is set,
+// replaces the invalid term with the ast.ErrorExpr.
+func (p *Parser) parseTermOrRecover() (*ast.TermExpr, error) {
+	bp := p.scanner.Breakpoint()
+	term, err := p.parseTermExpr()
+	if err == nil || !p.recoverErrors || errors.Is(err, ErrLimitExceeded) {
+		return term, err
+	}
+
+	// Skip the invalid term up to the next synchronization point, i.e. the whitespace
+	// followed by the AND or OR operator, the closing parenthesis of the composite expression or the EOF.
+	p.scanner.Restore(bp)
+	p.recovered++
+
+	start, end := token.Position(-1), token.Position(-1)
+	var (
+		depth   int
+		skipped bool
+	)
+	for end < 0 {
+		var (
+			pos token.Position
+			tok token.Token
+		)
+		p.scanner.Peek(func(ps token.Position, t token.Token, _ string) bool {
+			pos, tok = ps, t
+			return false
+		})
+		if start < 0 {
+			start = pos
+		}
+
+		switch tok {
+		case token.EOF:
+			// The position of the EOF token is the last character of the source.
+			end = token.Position(len(p.src))
+			if start == pos && !skipped {
+				start = end
+			}
+			continue
+		case token.WS:
+			if depth == 0 && p.isRecoveryPoint() {
+				end = pos
+				continue
+			}
+		case token.LPAREN, token.BRACKET_OPEN, token.BRACE_OPEN:
+			depth++
+		case token.RPAREN, token.BRACKET_CLOSE, token.BRACE_CLOSE:
+			switch {
+			case depth > 0:
+				depth--
+			case tok == token.RPAREN && p.nesting > 0:
+				// The closing parenthesis of the composite expression.
+				end = pos
+				continue
+			}
+			// Otherwise the unmatched bracket is a part of the invalid term.
+		}
+		p.scanner.Scan()
+		skipped = true
+	}
+	if start > end {
+		start = end
+	}
+
+	te := getTermExpr()
+	te.Pos = start
+	te.Expr = &ast.ErrorExpr{Pos: start, Text: p.src[start:end]}
+	return te, nil
+}
+
+// isRecoveryPoint checks if the whitespaces are followed by the logical operator,
+// closing parenthesis or the EOF, without consuming them.
+func (p *Parser) isRecoveryPoint() bool {
+	bp := p.scanner.Breakpoint()
+	defer p.scanner.Restore(bp)
+
+	p.scanner.SkipWhitespace()
+	var tok token.Token
+	p.scanner.Peek(func(_ token.Position, t token.Token, _ string) bool {
+		tok = t
+		return false
+	})
+	switch tok {
+	case token.AND, token.OR, token.EOF:
+		return true
+	case token.RPAREN:
+		return p.nesting > 0
+	}
+	return false
+}
+
+// recoverTrailing returns the sequence of the ast.ErrorExpr with the source
+// starting at the position, which was left after the parsed expression.
+func (p *Parser) recoverTrailing(pos token.Position) *ast.SequenceExpr {
+	p.recovered++
+
+	if pos < 0 || int(pos) > len(p.src) {
+		pos = token.Position(len(p.src))
+	}
+	te := getTermExpr()
+	te.Pos = pos
+	te.Expr = &ast.ErrorExpr{Pos: pos, Text: p.src[pos:]}
+
+	factor := getFactorExpr()
+	factor.Pos = pos
+	factor.Terms = append(factor.Terms, te)
+
+	seq := getSequenceExpr()
+	seq.Pos = pos
+	seq.Factors = append(seq.Factors, factor)
+	return seq
+}
+
+// recoverable checks if the parser continues after the reported syntax error,
+// which doesn't affect the structure of the AST, i.e. the invalid whitespaces.
+func (p *Parser) recoverable() bool {
+	if !p.recoverErrors {
+		return false
+	}
+	p.recovered++
+	return true
+}
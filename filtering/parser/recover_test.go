@@ -0,0 +1,157 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestParser_RecoverErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		opts    []ParserOption
+		errs    []token.Position
+		invalid []string
+		want    string
+	}{
+		{
+			name:    "invalid argument",
+			src:     `a = 1 AND (b = ) AND c = 3`,
+			errs:    []token.Position{15},
+			invalid: []string{"b ="},
+			want:    `a = 1 AND (b =) AND c = 3`,
+		},
+		{
+			name:    "multiple errors",
+			src:     `a = ) OR b = 2 AND c = ] AND d = 4`,
+			errs:    []token.Position{4, 23},
+			invalid: []string{"a = )", "c = ]"},
+			want:    `a = ) OR b = 2 AND c = ] AND d = 4`,
+		},
+		{
+			name:    "trailing AND",
+			src:     `a = 1 AND`,
+			errs:    []token.Position{8, 8},
+			invalid: []string{""},
+			want:    `a = 1 AND `,
+		},
+		{
+			name:    "unmatched parenthesis",
+			src:     `a = 1 ) b`,
+			errs:    []token.Position{6},
+			invalid: []string{") b"},
+			want:    `a = 1 AND ) b`,
+		},
+		{
+			name: "strict whitespaces",
+			src:  `a  =  1`,
+			opts: []ParserOption{StrictWhitespacesOption()},
+			errs: []token.Position{4, 6},
+			want: `a = 1`,
+		},
+		{
+			name: "chained comparison",
+			src:  `1 < x < 10 AND y = 2`,
+			errs: []token.Position{6},
+			want: `1 < x AND y = 2`,
+		},
+		{
+			name:    "unterminated string",
+			src:     `a = "x`,
+			errs:    []token.Position{6},
+			invalid: nil,
+			want:    `a = "x"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []token.Position
+			opts := append([]ParserOption{RecoverErrorsOption(), ErrorHandlerOption(func(pos token.Position, msg string) {
+				errs = append(errs, pos)
+			})}, tt.opts...)
+
+			pf, err := NewParser(tt.src, opts...).Parse()
+			if !errors.Is(err, ErrInvalidFilterSyntax) {
+				t.Fatalf("expected invalid filter syntax error but got: %v", err)
+			}
+			if pf == nil || pf.Expr == nil {
+				t.Fatal("expected partial AST but got nil")
+			}
+			defer pf.Free()
+
+			if len(errs) != len(tt.errs) {
+				t.Fatalf("expected errors at %v but got %v", tt.errs, errs)
+			}
+			for i := range errs {
+				if errs[i] != tt.errs[i] {
+					t.Errorf("expected error at %d but got %d", tt.errs[i], errs[i])
+				}
+			}
+
+			invalid := collectErrorExprs(pf.Expr, nil)
+			if len(invalid) != len(tt.invalid) {
+				t.Fatalf("expected invalid parts %q but got %q", tt.invalid, invalid)
+			}
+			for i := range invalid {
+				if invalid[i] != tt.invalid[i] {
+					t.Errorf("expected invalid part %q but got %q", tt.invalid[i], invalid[i])
+				}
+			}
+
+			if got := ast.Format(pf.Expr); got != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParser_RecoverErrorsValid(t *testing.T) {
+	src := `a = 1 AND (b = 2 OR c = 3 )`
+	for _, opts := range [][]ParserOption{nil, {RecoverErrorsOption()}} {
+		pf, err := NewParser(src, opts...).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ast.Format(pf.Expr); got != `a = 1 AND (b = 2 OR c = 3)` {
+			t.Errorf("unexpected filter: %s", got)
+		}
+		pf.Free()
+	}
+
+	if _, err := NewParser(`a = 1 )`).Parse(); !errors.Is(err, ErrInvalidFilterSyntax) {
+		t.Errorf("expected invalid filter syntax error without recovery but got: %v", err)
+	}
+}
+
+func collectErrorExprs(x *ast.Expr, out []string) []string {
+	for _, seq := range x.Sequences {
+		for _, f := range seq.Factors {
+			for _, term := range f.Terms {
+				switch st := term.Expr.(type) {
+				case *ast.ErrorExpr:
+					out = append(out, st.Text)
+				case *ast.CompositeExpr:
+					out = collectErrorExprs(st.Expr, out)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// DefaultReservedKeywords returns the reserved keywords of the commonly used operators of the SQL-like languages,
+// which are not supported by the filtering syntax, along with the hints of the supported alternatives.
+// The returned map could be extended with additional keywords, before it is passed to the ReservedKeywordsOption.
+func DefaultReservedKeywords() scanner.ReservedWords {
+	return scanner.ReservedWords{
+		"BETWEEN": "use range comparisons, i.e. x >= 1 AND x <= 10",
+		"LIKE":    `use the equality with a wildcard, i.e. x = "abc*"`,
+		"IS":      "use the comparison with null, i.e. x = null",
+	}
+}
+
+// ReservedKeywordsOption makes the parser recognize the words as the reserved keywords.
+// A reserved keyword is never treated as a field name, and using it results in an error
+// with a targeted message, i.e.: "BETWEEN is not supported; use range comparisons".
+// The backtick quoted identifiers are never reserved, so that i.e. `LIKE` still refers to a field named LIKE.
+func ReservedKeywordsOption(words scanner.ReservedWords) ParserOption {
+	return func(p *Parser) {
+		p.reserved = words
+	}
+}
+
+// reservedKeywordError reports the usage of the reserved keyword.
+func (p *Parser) reservedKeywordError(pos token.Position, lit string) error {
+	if p.err != nil {
+		msg := lit + " is not supported"
+		if hint := p.reserved[lit]; hint != "" {
+			msg += "; " + hint
+		}
+		p.err(pos, "restriction: "+msg)
+	}
+	return ErrInvalidFilterSyntax
+}
@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseRestrictionExpr() (*ast.RestrictionExpr, error) {
+func (p *Parser) parseRestrictionExpr() (_ *ast.RestrictionExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleRestriction), &err)
+	}
+
 	re := getRestrictionExpr()
 
 	// Parse comparable expression.
@@ -38,15 +42,32 @@ func (p *Parser) parseRestrictionExpr() (*ast.RestrictionExpr, error) {
 	// Peek if there is a comparator.
 	var (
 		isComparator bool
+		isNot        bool
 		eof          bool
+		reserved     bool
+		peekPos      token.Position
+		peekLit      string
 	)
 	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
 		isComparator = tok.IsComparator()
+		isNot = tok == token.NOT
 		eof = tok == token.EOF
+		reserved = tok == token.RESERVED
+		peekPos, peekLit = pos, lit
 		return false
 	})
 
-	if !isComparator || eof {
+	if reserved {
+		// The reserved keyword is used as an operator, i.e. 'x BETWEEN 1 AND 10'.
+		return nil, p.reservedKeywordError(peekPos, peekLit)
+	}
+
+	var compOp *ast.ComparatorLiteral
+	if isNot {
+		compOp = p.parseNotInComparator()
+	}
+
+	if compOp == nil && (!isComparator || eof) {
 		p.scanner.Restore(bp)
 		return re, nil
 	}
@@ -55,19 +76,25 @@ func (p *Parser) parseRestrictionExpr() (*ast.RestrictionExpr, error) {
 		if p.err != nil {
 			p.err(p.scanner.Pos(), "restriction: whitespace expected after comparable expression")
 		}
-		return nil, ErrInvalidFilterSyntax
+		if !p.recoverable() {
+			return nil, ErrInvalidFilterSyntax
+		}
 	}
 
 	if p.strictWhiteSpaces && n > 1 {
 		if p.err != nil {
 			p.err(p.scanner.Pos(), "restriction: only one whitespace is allowed between comparable expression and comparator")
 		}
-		return nil, ErrInvalidFilterSyntax
+		if !p.recoverable() {
+			return nil, ErrInvalidFilterSyntax
+		}
 	}
 
-	compOp, err := p.parseComparator()
-	if err != nil {
-		return nil, err
+	if compOp == nil {
+		var err error
+		if compOp, err = p.parseComparator(); err != nil {
+			return nil, err
+		}
 	}
 	re.Comparator = compOp
 
@@ -78,14 +105,44 @@ func (p *Parser) parseRestrictionExpr() (*ast.RestrictionExpr, error) {
 		if p.err != nil {
 			p.err(p.scanner.Pos(), "restriction: whitespace expected after comparator")
 		}
-		return nil, ErrInvalidFilterSyntax
+		if !p.recoverable() {
+			return nil, ErrInvalidFilterSyntax
+		}
 	}
 
 	if p.strictWhiteSpaces && n > 1 {
 		if p.err != nil {
 			p.err(p.scanner.Pos(), "restriction: only one whitespace is allowed between comparator and argument")
 		}
-		return nil, ErrInvalidFilterSyntax
+		if !p.recoverable() {
+			return nil, ErrInvalidFilterSyntax
+		}
+	}
+
+	if compOp.Type == ast.HAS || compOp.Type == ast.NOT_HAS {
+		// The presence restriction, i.e. 'field:*'.
+		if me, ok := p.parsePresenceArg(); ok {
+			re.Arg = me
+			return re, nil
+		}
+	}
+
+	if compOp.Type == ast.IN || compOp.Type == ast.NOT_IN {
+		// The parenthesized list of the IN comparator, i.e. 'field IN ("a", "b")'.
+		var isList bool
+		var listPos token.Position
+		p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
+			isList, listPos = tok == token.LPAREN, pos
+			return false
+		})
+		if isList {
+			a, err := p.parseInListExpr(listPos)
+			if err != nil {
+				return nil, err
+			}
+			re.Arg = a
+			return re, nil
+		}
 	}
 
 	// Parse the argument.
@@ -93,7 +150,67 @@ func (p *Parser) parseRestrictionExpr() (*ast.RestrictionExpr, error) {
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := arg.(ast.ComparableExpr); ok {
+		// The argument could be followed by the arithmetic operations, i.e. 'a < b + 1h'.
+		if arg, err = p.parseArithmeticExpr(arg, 1); err != nil {
+			return nil, err
+		}
+	}
 	re.Arg = arg
 
+	if err = p.checkChainedComparison(re); err != nil {
+		return nil, err
+	}
+
 	return re, nil
 }
+
+// parseNotInComparator parses the negated IN comparator, i.e. 'a NOT IN [b, c]'.
+// If the NOT keyword is not followed by the IN, the scanner is restored and the result is nil,
+// thus the NOT is parsed as the negation of the next term, i.e. 'a NOT b'.
+func (p *Parser) parseNotInComparator() *ast.ComparatorLiteral {
+	bp := p.scanner.Breakpoint()
+	pos, tok, _ := p.scanner.Scan()
+	if tok != token.NOT || p.scanner.SkipWhitespace() == 0 {
+		p.scanner.Restore(bp)
+		return nil
+	}
+
+	var isIn bool
+	p.scanner.Peek(func(_ token.Position, tok token.Token, _ string) bool {
+		isIn = tok == token.IN
+		return isIn
+	})
+	if !isIn {
+		p.scanner.Restore(bp)
+		return nil
+	}
+
+	cl := getComparatorLiteral()
+	cl.Pos = pos
+	cl.Type = ast.NOT_IN
+	return cl
+}
+
+// parsePresenceArg parses the wildcard argument of the presence restriction, i.e. 'field:*'.
+// The wildcard is returned as the member expression with the text literal of the ASTERISK token.
+func (p *Parser) parsePresenceArg() (*ast.MemberExpr, bool) {
+	var isWildcard bool
+	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
+		isWildcard = tok == token.ASTERISK
+		return false
+	})
+	if !isWildcard {
+		return nil, false
+	}
+	pos, tok, lit := p.scanner.Scan()
+
+	tl := getTextLiteral()
+	tl.Pos = pos
+	tl.Value = lit
+	tl.Token = tok
+
+	me := getMemberExpr()
+	me.Value = tl
+	return me, true
+}
@@ -393,6 +393,41 @@ func testRestrictionWithFunctionArgListNoSpace(t *testing.T, pf *ParsedFilter) {
 	}
 }
 
+const restrictionWithPresenceArg = "m.key:*"
+
+func testRestrictionWithPresenceArg(t *testing.T, pf *ParsedFilter) {
+	if pf.Expr == nil {
+		t.Fatalf("expected parsed filter")
+	}
+
+	if len(pf.Expr.Sequences) != 1 {
+		t.Fatalf("expected one sequence")
+	}
+
+	rest := seqRestriction(t, pf.Expr.Sequences[0])
+	if rest.Comparator == nil || rest.Comparator.Type != ast.HAS {
+		t.Fatalf("expected ':' got: %v", rest.Comparator)
+	}
+
+	arg, ok := rest.Arg.(*ast.MemberExpr)
+	if !ok {
+		t.Fatalf("expected member literal got: %T", rest.Arg)
+	}
+	tl, ok := arg.Value.(*ast.TextLiteral)
+	if !ok {
+		t.Fatalf("expected text literal got: %v", arg.Value)
+	}
+	if tl.Value != "*" || tl.Token != token.ASTERISK {
+		t.Fatalf("expected '*' wildcard got: %v (%v)", tl.Value, tl.Token)
+	}
+	if tl.Pos != 6 {
+		t.Fatalf("expected position 6 got: %v", tl.Pos)
+	}
+	if got := ast.Format(pf.Expr); got != "m.key:*" {
+		t.Fatalf("expected 'm.key:*' got: %v", got)
+	}
+}
+
 const restrictionWithHasArg = "m:foo"
 
 func testRestrictionWithHasArg(t *testing.T, pf *ParsedFilter) {
@@ -1666,3 +1701,222 @@ func testRestrictionWithNegativeIntOnRight(t *testing.T, pf *ParsedFilter) {
 		t.Fatalf("expected -1 got: %v", lit.Value)
 	}
 }
+
+func TestParser_ChainedComparison(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		pos  token.Position
+		msg  string
+	}{
+		{
+			name: "range",
+			src:  `1 < x < 10`,
+			pos:  6,
+			msg:  "restriction: chained comparisons are not supported, use 'x > 1 AND x < 10' instead",
+		},
+		{
+			name: "inclusive range with member",
+			src:  `1 <= a.b <= 2.5`,
+			pos:  10,
+			msg:  "restriction: chained comparisons are not supported, use 'a.b >= 1 AND a.b <= 2.5' instead",
+		},
+		{
+			name: "string range",
+			src:  `"a" < name <= "b"`,
+			pos:  12,
+			msg:  "restriction: chained comparisons are not supported, use 'name > \"a\" AND name <= \"b\"' instead",
+		},
+		{
+			name: "equality",
+			src:  `1 = x = 1`,
+			pos:  6,
+			msg:  "restriction: chained comparisons are not supported, use 'x = 1 AND x = 1' instead",
+		},
+		{
+			name: "field first",
+			src:  `i32 > 1 < 2`,
+			pos:  8,
+			msg:  "restriction: chained comparisons are not supported, join the comparisons with the AND operator",
+		},
+		{
+			name: "function call",
+			src:  `1 <= a.b <= f(2)`,
+			pos:  10,
+			msg:  "restriction: chained comparisons are not supported, join the comparisons with the AND operator",
+		},
+		{
+			name: "fields",
+			src:  `x = 1 = y`,
+			pos:  6,
+			msg:  "restriction: chained comparisons are not supported, join the comparisons with the AND operator",
+		},
+		{
+			name: "has comparator",
+			src:  `1 : x < 2`,
+			pos:  6,
+			msg:  "restriction: chained comparisons are not supported, join the comparisons with the AND operator",
+		},
+		{
+			name: "in composite",
+			src:  `a = 1 AND (1 < x < 10)`,
+			pos:  17,
+			msg:  "restriction: chained comparisons are not supported, use 'x > 1 AND x < 10' instead",
+		},
+		{
+			name: "missing argument",
+			src:  `1 < x <`,
+			pos:  6,
+			msg:  "restriction: chained comparisons are not supported, join the comparisons with the AND operator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				errs []token.Position
+				msgs []string
+			)
+			p := NewParser(tt.src, ErrorHandlerOption(func(pos token.Position, msg string) {
+				errs = append(errs, pos)
+				msgs = append(msgs, msg)
+			}))
+			pf, err := p.Parse()
+			if err == nil {
+				pf.Free()
+				t.Fatal("expected error")
+			}
+			if len(msgs) != 1 {
+				t.Fatalf("expected one error but got: %v", msgs)
+			}
+			if errs[0] != tt.pos {
+				t.Errorf("expected error at %d but got %d", tt.pos, errs[0])
+			}
+			if msgs[0] != tt.msg {
+				t.Errorf("expected message %q but got %q", tt.msg, msgs[0])
+			}
+		})
+	}
+}
+
+func TestParser_InList(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  bool
+	}{
+		{name: "parenthesized", src: `a IN ("b", c)`, want: `a IN ["b", c]`},
+		{name: "bracketed", src: `a IN ["b", c]`, want: `a IN ["b", c]`},
+		{name: "single element", src: `a IN (b)`, want: `a IN [b]`},
+		{name: "empty", src: `a IN ()`, want: `a IN []`},
+		{name: "with sequence", src: `a IN (b, c) AND d = e`, want: `a IN [b, c] AND d = e`},
+		{name: "unclosed", src: `a IN (b, c`, err: true},
+		{name: "composite of other comparator", src: `a = (b, c)`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(tt.src)
+			pf, err := p.Parse()
+			if tt.err {
+				if err == nil {
+					pf.Free()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer pf.Free()
+
+			rest := seqRestriction(t, pf.Expr.Sequences[0])
+			if _, ok := rest.Arg.(*ast.ArrayExpr); !ok {
+				t.Fatalf("expected array argument but got: %T", rest.Arg)
+			}
+			if got := pf.Expr.String(); got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParser_NegatedComparators(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		cmp  ast.ComparatorType
+		want string
+	}{
+		{name: "not in", src: `a NOT IN [b, c]`, cmp: ast.NOT_IN, want: `a NOT IN [b, c]`},
+		{name: "not in list", src: `a NOT IN (b, c)`, cmp: ast.NOT_IN, want: `a NOT IN [b, c]`},
+		{name: "not has", src: `a !: b`, cmp: ast.NOT_HAS, want: `a !: b`},
+		{name: "not has wildcard", src: `a !: *`, cmp: ast.NOT_HAS, want: `a !: *`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := NewParser(tt.src).Parse()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer pf.Free()
+
+			rest := seqRestriction(t, pf.Expr.Sequences[0])
+			if rest.Comparator == nil || rest.Comparator.Type != tt.cmp {
+				t.Fatalf("expected %s comparator but got: %v", tt.cmp, rest.Comparator)
+			}
+			if got := pf.Expr.String(); got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	// The NOT which is not followed by the IN keeps negating the next term.
+	pf, err := NewParser(`a NOT b`).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pf.Free()
+	if n := len(pf.Expr.Sequences[0].Factors); n != 2 {
+		t.Errorf("expected two factors of 'a NOT b' but got %d", n)
+	}
+}
+
+func TestParser_CustomComparators(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		symbol string
+		want   string
+	}{
+		{name: "symbol", src: `a ~ "b%"`, symbol: "~", want: `a ~ "b%"`},
+		{name: "longest symbol", src: `a^="b"`, symbol: "^=", want: `a ^= "b"`},
+		{name: "word", src: `a LIKE b`, symbol: "LIKE", want: `a LIKE b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := NewParser(tt.src, CustomComparatorsOption("~", "^", "^=", "LIKE")).Parse()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer pf.Free()
+
+			rest := seqRestriction(t, pf.Expr.Sequences[0])
+			if rest.Comparator == nil || rest.Comparator.Type != ast.CUSTOM || rest.Comparator.Symbol != tt.symbol {
+				t.Fatalf("expected custom comparator %s but got: %v", tt.symbol, rest.Comparator)
+			}
+			if got := pf.Expr.String(); got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	// Without the option the symbols are not comparators, but the text of the sequence.
+	pf, err := NewParser(`a ~ b`).Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pf.Free()
+	if n := len(pf.Expr.Sequences[0].Factors); n != 3 {
+		t.Errorf("expected three factors of 'a ~ b' but got %d", n)
+	}
+}
@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseSequenceExpr() (*ast.SequenceExpr, error) {
+func (p *Parser) parseSequenceExpr() (_ *ast.SequenceExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleSequence), &err)
+	}
+
 	seq := getSequenceExpr()
 
 	// Parse the first factor.
@@ -41,11 +45,15 @@ func (p *Parser) parseSequenceExpr() (*ast.SequenceExpr, error) {
 			if p.err != nil {
 				p.err(p.scanner.Pos(), "sequence: only one WS is allowed between factors")
 			}
-			return nil, ErrInvalidFilterSyntax
+			if !p.recoverable() {
+				return nil, ErrInvalidFilterSyntax
+			}
 		}
 		var isAND bool
 		p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
-			if tok == token.AND {
+			// The closing parenthesis and EOF end the sequence as well, so that the trailing whitespaces
+			// are not reported as a missing factor.
+			if tok == token.AND || tok == token.RPAREN || tok == token.EOF {
 				isAND = true
 			}
 			return false
@@ -40,7 +40,7 @@ var (
 )
 
 func getStructExpr() *ast.StructExpr {
-	return structExprPool.Get().(*ast.StructExpr)
+	return getPooled[*ast.StructExpr](&structExprPool)
 }
 
 func putStructExpr(expr *ast.StructExpr) {
@@ -59,11 +59,11 @@ func putStructExpr(expr *ast.StructExpr) {
 	expr.Elements = expr.Elements[:0]
 	expr.LBrace = 0
 	expr.RBrace = 0
-	structExprPool.Put(expr)
+	putPooled(&structExprPool, expr)
 }
 
 func getStructFieldExpr() *ast.StructFieldExpr {
-	return structFieldExprPool.Get().(*ast.StructFieldExpr)
+	return getPooled[*ast.StructFieldExpr](&structFieldExprPool)
 }
 
 func putStructFieldExpr(expr *ast.StructFieldExpr) {
@@ -76,10 +76,14 @@ func putStructFieldExpr(expr *ast.StructFieldExpr) {
 	}
 	expr.Name = expr.Name[:0]
 	putComparableExpr(expr.Value)
-	structFieldExprPool.Put(expr)
+	putPooled(&structFieldExprPool, expr)
 }
 
-func (p *Parser) parseStructExpr(nameParts *nameParts) (*ast.StructExpr, error) {
+func (p *Parser) parseStructExpr(nameParts *nameParts) (_ *ast.StructExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleStruct), &err)
+	}
+
 	st := getStructExpr()
 
 	if nameParts != nil {
@@ -117,6 +121,11 @@ func (p *Parser) parseStructExpr(nameParts *nameParts) (*ast.StructExpr, error)
 
 	st.LBrace = pos
 
+	if err = p.enterNesting(pos); err != nil {
+		return nil, err
+	}
+	defer p.exitNesting()
+
 	var isRBrace bool
 	p.scanner.Peek(func(pos token.Position, tok token.Token, lit string) bool {
 		isRBrace = tok == token.BRACE_CLOSE
@@ -19,7 +19,11 @@ import (
 	"github.com/blockysource/blocky-aip/token"
 )
 
-func (p *Parser) parseTermExpr() (*ast.TermExpr, error) {
+func (p *Parser) parseTermExpr() (_ *ast.TermExpr, err error) {
+	if p.trace != nil {
+		defer p.traceExit(p.traceEnter(RuleTerm), &err)
+	}
+
 	// The term is either unary or simple term.
 	// Check if the next token is unary operator.
 	var (
@@ -144,7 +148,12 @@ func (p *Parser) isKeywordMember() (bool, error) {
 	case token.RPAREN, token.BRACKET_CLOSE, token.BRACE_CLOSE, token.COMMA:
 		return true, nil
 	case token.AND, token.OR, token.NOT:
+		// The chain of the keywords is looked ahead recursively, so that its length is limited as the nesting depth.
+		if err := p.enterNesting(pos); err != nil {
+			return false, err
+		}
 		is, err := p.isKeywordMember()
+		p.exitNesting()
 		if err != nil {
 			return false, err
 		}
@@ -0,0 +1,224 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// TraceEventKind is a kind of the trace event.
+type TraceEventKind int
+
+const (
+	// TraceTokenConsumed is an event where the scanner consumed a token.
+	TraceTokenConsumed TraceEventKind = iota
+	// TraceRuleEntered is an event where the parser started parsing a grammar rule.
+	TraceRuleEntered
+	// TraceRuleExited is an event where the parser finished parsing a grammar rule, successfully or not.
+	TraceRuleExited
+	// TraceValueParsed is an event where the interpreter parsed a value of a field.
+	TraceValueParsed
+	// TraceFunctionCalled is an event where the interpreter called a filtering function.
+	TraceFunctionCalled
+)
+
+var _TraceEventKindStrings = [...]string{
+	TraceTokenConsumed:  "TOKEN_CONSUMED",
+	TraceRuleEntered:    "RULE_ENTERED",
+	TraceRuleExited:     "RULE_EXITED",
+	TraceValueParsed:    "VALUE_PARSED",
+	TraceFunctionCalled: "FUNCTION_CALLED",
+}
+
+// String returns the string representation of the trace event kind.
+func (k TraceEventKind) String() string {
+	if k < 0 || int(k) >= len(_TraceEventKindStrings) {
+		return fmt.Sprintf("TraceEventKind(%d)", k)
+	}
+	return _TraceEventKindStrings[k]
+}
+
+// Rule is a name of the filtering grammar rule.
+type Rule string
+
+// The traced grammar rules, named after the AIP-160 EBNF grammar.
+const (
+	RuleExpr        Rule = "expression"
+	RuleSequence    Rule = "sequence"
+	RuleFactor      Rule = "factor"
+	RuleTerm        Rule = "term"
+	RuleComposite   Rule = "composite"
+	RuleRestriction Rule = "restriction"
+	RuleComparable  Rule = "comparable"
+	RuleComparator  Rule = "comparator"
+	RuleArg         Rule = "arg"
+	RuleMember      Rule = "member"
+	RuleFunction    Rule = "function"
+	RuleArray       Rule = "array"
+	RuleStruct      Rule = "struct"
+)
+
+// TraceEvent is a single event of the filter parsing, emitted to the TraceHookFn.
+type TraceEvent struct {
+	// Kind is the kind of the event.
+	Kind TraceEventKind
+
+	// Pos is the position of the token, the rule start, the value or the function call in the filter.
+	Pos token.Position
+
+	// Depth is the number of the rules being parsed, when the event was emitted.
+	Depth int
+
+	// Rule is the grammar rule entered or exited.
+	Rule Rule
+
+	// Token is the consumed token.
+	Token token.Token
+
+	// Literal is the literal of the consumed token.
+	Literal string
+
+	// Text is the source of the parsed value, or the name of the called function.
+	Text string
+
+	// Detail is a human-readable description of the parsed value or the function call result.
+	Detail string
+
+	// Elapsed is the time spent in the exited rule, the value parsing or the function call.
+	Elapsed time.Duration
+
+	// Err is the error of the exited rule, the value parsing or the function call, if any.
+	Err error
+}
+
+// String returns the string representation of the event.
+func (e TraceEvent) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d: %s", e.Pos, e.Kind)
+	switch e.Kind {
+	case TraceTokenConsumed:
+		fmt.Fprintf(&sb, " %s %q", e.Token, e.Literal)
+	case TraceRuleEntered:
+		sb.WriteString(" " + string(e.Rule))
+	case TraceRuleExited:
+		fmt.Fprintf(&sb, " %s in %s", e.Rule, e.Elapsed)
+	case TraceValueParsed, TraceFunctionCalled:
+		fmt.Fprintf(&sb, " %s in %s", e.Text, e.Elapsed)
+		if e.Detail != "" {
+			sb.WriteString(": " + e.Detail)
+		}
+	}
+	if e.Err != nil {
+		sb.WriteString(": error: " + e.Err.Error())
+	}
+	return sb.String()
+}
+
+// TraceHookFn is a function that receives the parsing trace events.
+type TraceHookFn func(event TraceEvent)
+
+// TraceHookOption sets the function called with each consumed token, and on each entry and exit of a grammar rule.
+// It is meant for debugging why a filter fails to parse, and profiling the slow parses.
+// The tokens scanned again after backtracking are reported again.
+// A nil function disables the tracing, which otherwise costs nothing.
+func TraceHookOption(fn TraceHookFn) ParserOption {
+	return func(p *Parser) {
+		p.trace = fn
+		if fn == nil {
+			p.scanner.Trace(nil)
+			return
+		}
+		p.scanner.Trace(p.traceToken)
+	}
+}
+
+func (p *Parser) traceToken(pos token.Position, tok token.Token, lit string) {
+	p.trace(TraceEvent{Kind: TraceTokenConsumed, Pos: pos, Depth: p.traceDepth, Token: tok, Literal: lit})
+}
+
+// traceFrame is the traced rule being parsed.
+type traceFrame struct {
+	rule  Rule
+	pos   token.Position
+	start time.Time
+}
+
+// traceEnter emits the rule entry, and returns the frame passed to the traceExit.
+// It is used as: defer p.traceExit(p.traceEnter(RuleExpr), &err).
+// The deferred call, unlike a returned closure, does not move the err to the heap,
+// so that the parsing does not allocate when the tracing is disabled.
+func (p *Parser) traceEnter(r Rule) traceFrame {
+	pos := p.scanner.Pos()
+	p.trace(TraceEvent{Kind: TraceRuleEntered, Pos: pos, Depth: p.traceDepth, Rule: r})
+	p.traceDepth++
+	return traceFrame{rule: r, pos: pos, start: time.Now()}
+}
+
+// traceExit emits the rule exit with the error.
+func (p *Parser) traceExit(f traceFrame, err *error) {
+	p.traceDepth--
+	p.trace(TraceEvent{Kind: TraceRuleExited, Pos: f.pos, Depth: p.traceDepth, Rule: f.rule, Elapsed: time.Since(f.start), Err: *err})
+}
+
+// NewTracePrinter returns the trace hook that pretty prints the events to the writer w,
+// indenting them by the rule depth, i.e.:
+//
+//	expression
+//	  sequence
+//	    ...
+//	      IDENT "a" at 0
+//	    ...
+//	  sequence: ok in 3.1µs
+//	expression: ok in 5.2µs
+//
+// The write errors are ignored.
+func NewTracePrinter(w io.Writer) TraceHookFn {
+	return func(e TraceEvent) {
+		indent := strings.Repeat("  ", e.Depth)
+		switch e.Kind {
+		case TraceTokenConsumed:
+			fmt.Fprintf(w, "%s%s %q at %d\n", indent, e.Token, e.Literal, e.Pos)
+		case TraceRuleEntered:
+			fmt.Fprintf(w, "%s%s\n", indent, e.Rule)
+		case TraceRuleExited:
+			fmt.Fprintf(w, "%s%s: %s in %s\n", indent, e.Rule, traceResult(e.Err), e.Elapsed)
+		case TraceValueParsed:
+			fmt.Fprintf(w, "%svalue %s at %d: %s in %s\n", indent, e.Text, e.Pos, traceDetail(e), e.Elapsed)
+		case TraceFunctionCalled:
+			fmt.Fprintf(w, "%scall %s at %d: %s in %s\n", indent, e.Text, e.Pos, traceDetail(e), e.Elapsed)
+		default:
+			fmt.Fprintf(w, "%s%s\n", indent, e)
+		}
+	}
+}
+
+func traceResult(err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return "ok"
+}
+
+func traceDetail(e TraceEvent) string {
+	if e.Err != nil || e.Detail == "" {
+		return traceResult(e.Err)
+	}
+	return e.Detail
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestParser_TraceHook(t *testing.T) {
+	var events []TraceEvent
+	p := NewParser(`a = "b"`, TraceHookOption(func(e TraceEvent) {
+		events = append(events, e)
+	}))
+
+	pf, err := p.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pf.Free()
+
+	var (
+		tokens []string
+		depth  int
+		rules  = map[Rule]int{}
+	)
+	for _, e := range events {
+		switch e.Kind {
+		case TraceTokenConsumed:
+			if e.Depth == 0 && e.Token != token.EOF {
+				t.Errorf("expected token %s to be consumed within a rule", e.Token)
+			}
+			tokens = append(tokens, e.Literal)
+		case TraceRuleEntered:
+			if e.Depth != depth {
+				t.Errorf("expected rule %s entered at depth %d but got %d", e.Rule, depth, e.Depth)
+			}
+			depth++
+			rules[e.Rule]++
+		case TraceRuleExited:
+			depth--
+			if e.Depth != depth {
+				t.Errorf("expected rule %s exited at depth %d but got %d", e.Rule, depth, e.Depth)
+			}
+			if e.Err != nil {
+				t.Errorf("unexpected rule %s error: %v", e.Rule, e.Err)
+			}
+			rules[e.Rule]--
+		}
+	}
+	if depth != 0 {
+		t.Errorf("expected all the rules to be exited, but got depth %d", depth)
+	}
+	for r, n := range rules {
+		if n != 0 {
+			t.Errorf("expected rule %s entries to match the exits, but got %d", r, n)
+		}
+	}
+	if len(events) == 0 || events[0].Kind != TraceRuleEntered || events[0].Rule != RuleExpr {
+		t.Fatalf("expected the first event to enter the expression rule")
+	}
+	if got := strings.Join(tokens, "|"); got != `a|=|b|` {
+		t.Errorf("expected tokens %q but got %q", `a|=|b|`, got)
+	}
+}
+
+func TestParser_TraceHookError(t *testing.T) {
+	var exits []TraceEvent
+	p := NewParser(`a = (`, TraceHookOption(func(e TraceEvent) {
+		if e.Kind == TraceRuleExited {
+			exits = append(exits, e)
+		}
+	}))
+
+	if _, err := p.Parse(); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(exits) == 0 {
+		t.Fatalf("expected rule exits")
+	}
+	last := exits[len(exits)-1]
+	if last.Rule != RuleExpr || !errors.Is(last.Err, ErrInvalidFilterSyntax) {
+		t.Errorf("expected expression rule to exit with %v but got %s: %v", ErrInvalidFilterSyntax, last.Rule, last.Err)
+	}
+}
+
+func TestNewTracePrinter(t *testing.T) {
+	var sb strings.Builder
+	p := NewParser(`a:b`, TraceHookOption(NewTracePrinter(&sb)))
+	pf, err := p.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pf.Free()
+
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if lines[0] != "expression" {
+		t.Errorf("expected first line %q but got %q", "expression", lines[0])
+	}
+	// The EOF is scanned by the Parse, after the expression rule.
+	if !strings.HasPrefix(lines[len(lines)-2], "expression: ok in ") {
+		t.Errorf("expected the expression result to be printed but got %q", lines[len(lines)-2])
+	}
+	if !strings.Contains(sb.String(), "\n        restriction\n") {
+		t.Errorf("expected the restriction to be indented by its depth, got:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), `: ":" at 1`) {
+		t.Errorf("expected the colon token to be printed, got:\n%s", sb.String())
+	}
+}
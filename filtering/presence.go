@@ -0,0 +1,57 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// isPresenceArg checks if the argument of the restriction is the presence wildcard, i.e.: sub:*.
+func isPresenceArg(arg ast.ArgExpr) bool {
+	me, ok := arg.(*ast.MemberExpr)
+	if !ok || len(me.Fields) > 0 {
+		return false
+	}
+	tl, ok := me.Value.(*ast.TextLiteral)
+	return ok && tl.Token == token.ASTERISK
+}
+
+// handlePresence handles the presence restriction of the field selected by the left expression, i.e.: sub:*.
+// The left expression is owned by the function, and it is freed on failure.
+func (b *Interpreter) handlePresence(ctx *ParseContext, x *ast.RestrictionExpr, left expr.FilterExpr, fd protoreflect.FieldDescriptor, fi info.FieldInfo) (TryParseValueResult, error) {
+	if !tracksPresence(fd, fi) {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Arg.Position(), ErrMsg: fmt.Sprintf("field: '%s' does not track presence, only the message, nullable, repeated and map fields could be tested with ':*'", fd.Name())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	pe := expr.AcquirePresenceExpr()
+	pe.Field = left
+	return TryParseValueResult{Expr: pe, IsIndirect: true}, nil
+}
+
+// tracksPresence checks if the field distinguishes being set from having the default value.
+func tracksPresence(fd protoreflect.FieldDescriptor, fi info.FieldInfo) bool {
+	return fd.IsList() || fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.HasPresence() || fi.Nullable
+}
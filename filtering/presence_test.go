@@ -0,0 +1,105 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+)
+
+func TestInterpreter_Presence(t *testing.T) {
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter    string
+		wantField []protoreflect.Name
+		err       error
+	}{
+		{filter: `sub:*`, wantField: []protoreflect.Name{"sub"}},
+		{filter: `sub.sub:*`, wantField: []protoreflect.Name{"sub", "sub"}},
+		{filter: `str_optional:*`, wantField: []protoreflect.Name{"str_optional"}},
+		{filter: `timestamp_optional:*`, wantField: []protoreflect.Name{"timestamp_optional"}},
+		{filter: `rp_str:*`, wantField: []protoreflect.Name{"rp_str"}},
+		{filter: `map_str_i32:*`, wantField: []protoreflect.Name{"map_str_i32"}},
+		{filter: `str:*`, err: ErrInvalidValue},
+		{filter: `i32:*`, err: ErrInvalidValue},
+		{filter: `sub = *`, err: parser.ErrInvalidFilterSyntax},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			pe, ok := x.(*expr.PresenceExpr)
+			if !ok {
+				t.Fatalf("expected presence expression but got %T", x)
+			}
+
+			var got []protoreflect.Name
+			fs, _ := pe.Field.(*expr.FieldSelectorExpr)
+			for fs != nil {
+				got = append(got, fs.Field)
+				fs, _ = fs.Traversal.(*expr.FieldSelectorExpr)
+			}
+			if len(got) != len(tt.wantField) {
+				t.Fatalf("expected fields %v but got %v", tt.wantField, got)
+			}
+			for j := range got {
+				if got[j] != tt.wantField[j] {
+					t.Fatalf("expected fields %v but got %v", tt.wantField, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpreter_MapKeyPresence(t *testing.T) {
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`map_str_i32."key":*`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer x.Free()
+
+	ce, ok := x.(*expr.CompareExpr)
+	if !ok || ce.Comparator != expr.HAS {
+		t.Fatalf("expected HAS compare expression but got %v", x)
+	}
+	if ve, ok := ce.Right.(*expr.ValueExpr); !ok || ve.Value != "key" {
+		t.Fatalf("expected map key value but got %v", ce.Right)
+	}
+}
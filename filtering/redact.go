@@ -0,0 +1,139 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"strings"
+
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+const (
+	// RedactedValue is the replacement of the unquoted values in the redacted filter.
+	RedactedValue = "***"
+
+	// RedactedString is the replacement of the quoted string values in the redacted filter.
+	RedactedString = `"***"`
+)
+
+// RedactFilter returns the filter with all the literal values replaced, so that it could be safely logged.
+// The field selectors, function names, comparators and logical operators are preserved, i.e.:
+//
+//	name = "John" AND age > 30 AND domain = example.com
+//
+// is redacted into:
+//
+//	name = "***" AND age > *** AND domain = ***
+//
+// The quoted strings, numbers, timestamps and durations are redacted everywhere in the filter,
+// whereas the unquoted text values only on the right hand side of a comparator, or within an array.
+// The boolean and null keywords are preserved.
+// An invalid filter is redacted on the best effort basis.
+func RedactFilter(filter string) string {
+	var toks []redactToken
+	s := scanner.New(filter, nil)
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		toks = append(toks, redactToken{pos: pos, tok: tok})
+		if tok == token.ILLEGAL {
+			// The scanner does not recover, the rest of the filter is redacted as a whole.
+			break
+		}
+	}
+
+	var (
+		sb         strings.Builder
+		afterValue bool // whether the next unquoted text is a value.
+		brackets   int
+	)
+	sb.Grow(len(filter))
+	for i := 0; i < len(toks); i++ {
+		tp := toks[i]
+		switch {
+		case tp.tok == token.ILLEGAL:
+			sb.WriteString(RedactedValue)
+			return sb.String()
+		case tp.tok == token.WS:
+			sb.WriteString(tokenText(filter, toks, i))
+			continue
+		case tp.tok == token.STRING, tp.tok == token.BYTES:
+			sb.WriteString(RedactedString)
+			afterValue = false
+			continue
+		case tp.tok.IsNumber(), tp.tok == token.TIMESTAMP, tp.tok == token.DATE, tp.tok == token.TIME_OF_DAY, tp.tok == token.DURATION:
+			sb.WriteString(RedactedValue)
+			afterValue = false
+			continue
+		case tp.tok == token.IDENT && afterValue:
+			// The unquoted value may be split by the dots, i.e. example.com.
+			last := i
+			for last+2 < len(toks) && toks[last+1].tok == token.PERIOD && toks[last+2].tok == token.IDENT {
+				last += 2
+			}
+			// The function calls and the message types are not the values.
+			if next := nextToken(toks, last); next != token.LPAREN && next != token.BRACE_OPEN {
+				sb.WriteString(RedactedValue)
+				afterValue = false
+				i = last
+				continue
+			}
+		}
+
+		sb.WriteString(tokenText(filter, toks, i))
+		switch {
+		case tp.tok == token.BRACKET_OPEN:
+			brackets++
+			afterValue = true
+		case tp.tok == token.BRACKET_CLOSE:
+			brackets--
+			afterValue = false
+		case tp.tok == token.COMMA:
+			afterValue = brackets > 0
+		case tp.tok.IsComparator():
+			afterValue = true
+		default:
+			afterValue = false
+		}
+	}
+	return sb.String()
+}
+
+type redactToken struct {
+	pos token.Position
+	tok token.Token
+}
+
+// tokenText returns the source text of the i-th token.
+func tokenText(filter string, toks []redactToken, i int) string {
+	end := len(filter)
+	if i+1 < len(toks) {
+		end = int(toks[i+1].pos)
+	}
+	return filter[toks[i].pos:end]
+}
+
+// nextToken returns the first non-whitespace token following the i-th token.
+func nextToken(toks []redactToken, i int) token.Token {
+	for j := i + 1; j < len(toks); j++ {
+		if toks[j].tok != token.WS {
+			return toks[j].tok
+		}
+	}
+	return token.EOF
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import "testing"
+
+func TestRedactFilter(t *testing.T) {
+	tc := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{name: "empty", filter: ``, want: ``},
+		{name: "string", filter: `name = "John"`, want: `name = "***"`},
+		{name: "numbers", filter: `age > 30 AND score <= -1.5e3`, want: `age > *** AND score <= ***`},
+		{name: "dotted text", filter: `domain = example.com OR x:y`, want: `domain = *** OR x:***`},
+		{name: "selectors preserved", filter: `sub.name != "a" AND NOT bool = true`, want: `sub.name != "***" AND NOT bool = true`},
+		{name: "timestamp and duration", filter: `ts > 2021-01-01T00:00:00Z AND d < 1h30m`, want: `ts > *** AND d < ***`},
+		{name: "array", filter: `str IN ["a", b, 3]`, want: `str IN ["***", ***, ***]`},
+		{name: "function call", filter: `ts > now() AND fn.call("x", 1)`, want: `ts > now() AND fn.call("***", ***)`},
+		{name: "struct", filter: `rp_sub:{name: "foo", i32: 1}`, want: `rp_sub:{name: "***", i32: ***}`},
+		{name: "message literal", filter: `sub = testpb.Message{name: foo}`, want: `sub = testpb.Message{name: ***}`},
+		{name: "null", filter: `opt = null`, want: `opt = null`},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactFilter(tt.filter); got != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
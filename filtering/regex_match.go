@@ -0,0 +1,105 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"regexp"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// RegexMatchOpt is an option that enables the regular expression match comparator extension, i.e.: name =~ "^foo.*bar$".
+// The comparator is allowed only on the string fields and the string map values,
+// and its right hand side must be a string literal with a valid RE2 pattern.
+// The pattern is validated during the parsing, and the restriction results in the expr.RegexMatchExpr.
+// Without this option the =~ comparator is rejected as an invalid value.
+func RegexMatchOpt() Option {
+	return func(i *Interpreter) error {
+		i.regexMatch = true
+		return nil
+	}
+}
+
+// handleRegexMatch handles the restriction with the regular expression match comparator.
+// The left expression is owned by the function, and it is freed on failure.
+func (b *Interpreter) handleRegexMatch(ctx *ParseContext, x *ast.RestrictionExpr, left expr.FilterExpr) (TryParseValueResult, error) {
+	if !b.regexMatch {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: "regular expression match comparator '=~' is not enabled"}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	_, mk, fd, ok := b.traverseLastFieldExpr(left)
+	if !ok {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparable.Position(), ErrMsg: "internal error: left hand side of restriction expression is not a field selector expression"}, ErrInternal
+		}
+		return TryParseValueResult{}, ErrInternal
+	}
+	fi := b.msgInfo.GetFieldInfo(fd)
+
+	if !isRegexMatchField(fd, mk != nil) {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Comparator.Position(), ErrMsg: fmt.Sprintf("regular expression match comparator '=~' is not allowed for the field: '%s'", fd.Name())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	var sl *ast.StringLiteral
+	if me, ok := x.Arg.(*ast.MemberExpr); ok && len(me.Fields) == 0 {
+		sl, _ = me.Value.(*ast.StringLiteral)
+	}
+	if sl == nil {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: x.Arg.Position(), ErrMsg: fmt.Sprintf("regular expression pattern must be a string literal, but got: %s", x.Arg.String())}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	if _, err := regexp.Compile(sl.Value); err != nil {
+		left.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: sl.Pos, ErrMsg: fmt.Sprintf("invalid regular expression pattern: %v", err)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	rm := expr.AcquireRegexMatchExpr()
+	rm.Left = left
+	rm.Pattern = sl.Value
+	rm.MatchComplexity = fi.Complexity
+	return TryParseValueResult{Expr: rm, IsIndirect: true}, nil
+}
+
+// isRegexMatchField checks if the field could be matched with the regular expression,
+// which is a singular string field, or a string map value if the field is selected by the map key.
+func isRegexMatchField(fd protoreflect.FieldDescriptor, isMapKey bool) bool {
+	if !isSingularField(fd, isMapKey) {
+		return false
+	}
+	if isMapKey {
+		return fd.MapValue().Kind() == protoreflect.StringKind
+	}
+	return fd.Kind() == protoreflect.StringKind
+}
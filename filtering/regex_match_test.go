@@ -0,0 +1,80 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_RegexMatch(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	tc := []struct {
+		name    string
+		opts    []Option
+		filter  string
+		pattern string
+		err     error
+	}{
+		{name: "string field", opts: []Option{RegexMatchOpt()}, filter: `str =~ "^foo.*bar$"`, pattern: `^foo.*bar$`},
+		{name: "nested field", opts: []Option{RegexMatchOpt()}, filter: `sub.name =~ "\\d+\\.\\w"`, pattern: `\d+\.\w`},
+		{name: "disabled", filter: `str =~ "foo"`, err: ErrInvalidValue},
+		{name: "invalid pattern", opts: []Option{RegexMatchOpt()}, filter: `str =~ "(foo"`, err: ErrInvalidValue},
+		{name: "non string field", opts: []Option{RegexMatchOpt()}, filter: `i32 =~ "1"`, err: ErrInvalidValue},
+		{name: "repeated field", opts: []Option{RegexMatchOpt()}, filter: `rp_str =~ "foo"`, err: ErrInvalidValue},
+		{name: "non literal pattern", opts: []Option{RegexMatchOpt()}, filter: `str =~ name`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			rm, ok := x.(*expr.RegexMatchExpr)
+			if !ok {
+				t.Fatalf("expected RegexMatchExpr but got %T", x)
+			}
+			if rm.Pattern != tt.pattern {
+				t.Errorf("expected pattern %q but got %q", tt.pattern, rm.Pattern)
+			}
+			if _, ok = rm.Left.(*expr.FieldSelectorExpr); !ok {
+				t.Errorf("expected field selector but got %T", rm.Left)
+			}
+
+			clone := rm.Clone()
+			defer clone.Free()
+			if !rm.Equals(clone) {
+				t.Errorf("expected clone to be equal")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+var timestampMsgDesc = new(timestamppb.Timestamp).ProtoReflect().Descriptor()
+
+// RelativeTimeOpt is an option that enables the relative timestamps, resolved at parse time against the clock:
+//   - the now() function call, which results in the current time, i.e.: create_time > now(),
+//   - the duration literal compared with a timestamp field, which is an offset from the current time,
+//     i.e.: create_time > -7d matches the resources created within the last 7 days.
+//
+// The duration literals of the timestamp fields accept the day 'd' and week 'w' units, on top of the time.ParseDuration ones.
+// If the clock is nil, the time.Now is used.
+// As the filters are resolved at parse time, the parsed expressions should not be cached for longer than their precision.
+func RelativeTimeOpt(clock func() time.Time) Option {
+	return func(i *Interpreter) error {
+		if i.clock != nil {
+			return errors.New("relative time is already enabled")
+		}
+		if clock == nil {
+			clock = time.Now
+		}
+		i.clock = clock
+		i.nowFn = nowFunctionDeclaration(clock)
+		return RegisterFunction(i.nowFn)(i)
+	}
+}
+
+// nowFunctionDeclaration returns the declaration of the now() function, which results in the current time of the clock.
+func nowFunctionDeclaration(clock func() time.Time) *FunctionCallDeclaration {
+	return &FunctionCallDeclaration{
+		Name: FunctionName{Name: "now"},
+		Returning: &FunctionCallReturningDeclaration{
+			FieldKind:         protoreflect.MessageKind,
+			MessageDescriptor: timestampMsgDesc,
+		},
+		CallFn: func(args ...expr.FilterExpr) (FunctionCallArgument, error) {
+			if len(args) != 0 {
+				// This is internal error.
+				return FunctionCallArgument{}, fmt.Errorf("invalid number of arguments for now function: %v", len(args))
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = clock()
+			return FunctionCallArgument{Expr: ve}, nil
+		},
+	}
+}
+
+// relativeTime resolves the duration literal as an offset from the current time of the clock.
+func (b *Interpreter) relativeTime(lit string) (time.Time, error) {
+	d, err := ParseDuration(lit)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return b.clock().Add(d), nil
+}
+
+// ParseDuration parses the duration string, which on top of the time.ParseDuration units,
+// could start with the weeks 'w' and days 'd', i.e.: -1w2d12h.
+// A day is always 24 hours long, regardless of the daylight saving time.
+// The optional sign is allowed only at the start and applies to the whole duration,
+// thus the mixed signs like -1d-2h are invalid, the same as in the time.ParseDuration.
+// The duration out of the time.Duration range is invalid.
+func ParseDuration(s string) (time.Duration, error) {
+	rest := s
+	var neg bool
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		neg = rest[0] == '-'
+		rest = rest[1:]
+	}
+
+	var (
+		d        time.Duration
+		hasUnits bool
+	)
+	for _, u := range [...]struct {
+		suffix byte
+		unit   time.Duration
+	}{{'w', 7 * 24 * time.Hour}, {'d', 24 * time.Hour}} {
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		if i == 0 || i == len(rest) || rest[i] != u.suffix {
+			continue
+		}
+		f, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		// The float64 of the math.MaxInt64 rounds up to 1<<63, which is already out of range.
+		v := f * float64(u.unit)
+		if v >= math.MaxInt64 || !addDuration(&d, time.Duration(v)) {
+			return 0, fmt.Errorf("invalid duration %q: out of range", s)
+		}
+		rest = rest[i+1:]
+		hasUnits = true
+	}
+
+	// The day and week units may be the whole duration, i.e. '0d', otherwise the rest needs to be a valid duration.
+	if rest != "" || !hasUnits {
+		if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+			return 0, fmt.Errorf("invalid duration %q: sign is allowed only at the start", s)
+		}
+		rd, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		if !addDuration(&d, rd) {
+			return 0, fmt.Errorf("invalid duration %q: out of range", s)
+		}
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// addDuration adds the non-negative duration v to the d, and reports false if the sum overflows.
+func addDuration(d *time.Duration, v time.Duration) bool {
+	if *d > math.MaxInt64-v {
+		return false
+	}
+	*d += v
+	return true
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestRelativeTimeOpt(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, RelativeTimeOpt(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   time.Time
+		err    error
+	}{
+		{name: "now", filter: `timestamp > now()`, want: now},
+		{name: "days ago", filter: `timestamp > -7d`, want: now.AddDate(0, 0, -7)},
+		{name: "zero days", filter: `timestamp > -0d`, want: now},
+		{name: "weeks and hours ago", filter: `timestamp >= -1w2d12h`, want: now.Add(-9*24*time.Hour - 12*time.Hour)},
+		{name: "in the future", filter: `timestamp < 90m`, want: now.Add(90 * time.Minute)},
+		{name: "absolute", filter: `timestamp < 2024-01-01T00:00:00Z`, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "duration field", filter: `duration > -7d`, err: ErrInvalidValue},
+		{name: "now arguments", filter: `timestamp > now(1h)`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ve, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", x.(*expr.CompareExpr).Right)
+			}
+			if got, ok := ve.Value.(time.Time); !ok || !got.Equal(tt.want) {
+				t.Errorf("expected %v but got %v", tt.want, ve.Value)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		plain, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		for _, filter := range []string{`timestamp > -7d`, `timestamp > now()`} {
+			if _, err = plain.Parse(filter); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("%s: expected error %v but got %v", filter, ErrInvalidValue, err)
+			}
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		o := i.Options()
+		if !o.RelativeTime || len(o.Functions) != 0 {
+			t.Fatalf("unexpected options: %+v", o)
+		}
+		if _, err = NewInterpreter(md, o.Opt()); err != nil {
+			t.Errorf("failed to apply the options: %v", err)
+		}
+	})
+}
+
+func TestParseDuration(t *testing.T) {
+	tc := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{in: "1d", want: 24 * time.Hour},
+		{in: "-2w", want: -14 * 24 * time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "1w1d1h30m", want: 8*24*time.Hour + 90*time.Minute},
+		{in: "15m", want: 15 * time.Minute},
+		{in: "0d", want: 0},
+		{in: "-0w", want: 0},
+		{in: "0w0d", want: 0},
+		{in: "0s", want: 0},
+		{in: "+1d2h", want: 26 * time.Hour},
+		{in: "-1d2h", want: -26 * time.Hour},
+		{in: "-1d-2h", err: true},
+		{in: "1d+2h", err: true},
+		{in: "--2h", err: true},
+		{in: "-2h", want: -2 * time.Hour},
+		{in: "15250w", want: 15250 * 7 * 24 * time.Hour},
+		{in: "15251w", err: true},
+		{in: "-106751d", want: -106751 * 24 * time.Hour},
+		{in: "106752d", err: true},
+		{in: "106751d24h", err: true},
+		{in: "15250w1000d", err: true},
+		{in: "1e300d", err: true},
+		{in: "1d1w", err: true},
+		{in: "-", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tc {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: expected %v but got %v", tt.in, tt.want, got)
+		}
+	}
+}
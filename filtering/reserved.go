@@ -0,0 +1,40 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/scanner"
+)
+
+// ReservedKeywordsOpt is an option that makes the filters fail on the reserved keywords,
+// i.e. the operators of other query languages like BETWEEN, instead of treating them as the field names.
+// Each keyword is mapped to an optional hint, which is added to the error message, i.e.:
+// "BETWEEN is not supported; use range comparisons". The parser.DefaultReservedKeywords could be used as a base.
+// The backtick quoted identifiers are never reserved.
+func ReservedKeywordsOpt(words scanner.ReservedWords) Option {
+	return func(i *Interpreter) error {
+		i.reservedKeywords = words
+		return nil
+	}
+}
+
+// reservedKeywordsOption returns the parser option of the reserved keywords, if any.
+func (b *Interpreter) reservedKeywordsOption() parser.ParserOption {
+	if len(b.reservedKeywords) == 0 {
+		return nil
+	}
+	return parser.ReservedKeywordsOption(b.reservedKeywords)
+}
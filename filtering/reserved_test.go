@@ -0,0 +1,54 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestReservedKeywordsOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, ReservedKeywordsOpt(parser.DefaultReservedKeywords()))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	_, err = i.Parse(`i32 BETWEEN 1 AND 10`)
+	var fe *FilterError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected filter error but got: %v", err)
+	}
+	if !errors.Is(err, parser.ErrInvalidFilterSyntax) {
+		t.Errorf("expected syntax error but got: %v", err)
+	}
+	if fe.Pos != 4 {
+		t.Errorf("expected position 4 but got %d", fe.Pos)
+	}
+	const msg = "restriction: BETWEEN is not supported; use range comparisons, i.e. x >= 1 AND x <= 10"
+	if fe.Msg != msg {
+		t.Errorf("expected message %q but got %q", msg, fe.Msg)
+	}
+
+	// The backtick quoted identifier refers to the field.
+	x, err := i.Parse("`str` = \"abc\"")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x.Free()
+}
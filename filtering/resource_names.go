@@ -0,0 +1,96 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/resourcename"
+)
+
+// ResourceNamesOpt is an option that validates the values of the resource name fields against their AIP-122 patterns,
+// compiled from the google.api.resource annotations, i.e.: name = "projects/p1/books/b1" or parent = "projects/p1".
+// The resource name fields are:
+//
+//   - the name field of the message annotated with the google.api.resource,
+//   - the fields annotated with the google.api.resource_reference type,
+//   - the fields annotated with the google.api.resource_reference child_type, matching the parents of the child resource.
+//
+// The value remains the string, and its parsed resourcename.Name is set in the expr.ValueExpr DecodedValue,
+// so that the backends could use the identifiers directly.
+// The string values with the wildcards, i.e.: name = "projects/p1/*", are not validated.
+func ResourceNamesOpt() Option {
+	return func(i *Interpreter) error {
+		i.resourceNames = make(map[protoreflect.FullName]resourcename.Patterns)
+		for _, mi := range i.msgInfo {
+			for _, fi := range mi.Fields {
+				ps, ok, err := resourcename.FieldPatterns(fi.Desc)
+				if err != nil {
+					return fmt.Errorf("field %q: %w", fi.Desc.FullName(), err)
+				}
+				if ok {
+					i.resourceNames[fi.Desc.FullName()] = ps
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// resourceNamePatterns returns the patterns of the resource name field.
+func (b *Interpreter) resourceNamePatterns(fd FieldDescriptor) (resourcename.Patterns, bool) {
+	if len(b.resourceNames) == 0 {
+		return nil, false
+	}
+	pfd, ok := fd.(protoreflect.FieldDescriptor)
+	if !ok {
+		return nil, false
+	}
+	ps, ok := b.resourceNames[pfd.FullName()]
+	return ps, ok
+}
+
+// TryParseResourceNameField tries parsing the value of the resource name string field.
+// The value is parsed as any other string value, and then its resource name is parsed with the patterns
+// and set in the DecodedValue of the result expr.ValueExpr.
+func (b *Interpreter) TryParseResourceNameField(ctx *ParseContext, in TryParseValueInput, ps resourcename.Patterns) (TryParseValueResult, error) {
+	res, err := b.TryParseStringField(ctx, in)
+	if err != nil {
+		return res, err
+	}
+
+	ve, ok := res.Expr.(*expr.ValueExpr)
+	if !ok {
+		return res, nil
+	}
+	s, ok := ve.Value.(string)
+	if !ok || s == "" {
+		return res, nil
+	}
+
+	name, err := ps.Parse(s)
+	if err != nil {
+		ve.Free()
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is a resource name, but provided value is not valid: %v", err)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+	ve.DecodedValue = name
+	return res, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/resourcename"
+)
+
+func TestInterpreter_ResourceNames(t *testing.T) {
+	md := testResourceNameMessage(t)
+	i, err := NewInterpreter(md, ResourceNamesOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   resourcename.Name
+		err    error
+	}{
+		{filter: `name = "projects/p1/books/b1"`, want: resourcename.Name{Pattern: "projects/{project}/books/{book}", IDs: map[string]string{"project": "p1", "book": "b1"}}},
+		{filter: `name = "shelves/s1/books/b1"`, want: resourcename.Name{Pattern: "shelves/{shelf}/books/{book}", IDs: map[string]string{"shelf": "s1", "book": "b1"}}},
+		{filter: `parent = "projects/p1"`, want: resourcename.Name{Pattern: "projects/{project}", IDs: map[string]string{"project": "p1"}}},
+		{filter: `related:"projects/p1/books/b2"`, want: resourcename.Name{Pattern: "projects/{project}/books/{book}", IDs: map[string]string{"project": "p1", "book": "b2"}}},
+		{filter: `name = "projects/p1"`, err: ErrInvalidValue},
+		{filter: `name = "projects/p1/books/b1/pages/1"`, err: ErrInvalidValue},
+		{filter: `parent = "projects/p1/books/b1"`, err: ErrInvalidValue},
+		{filter: `related:"books/b2"`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ve, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", x.(*expr.CompareExpr).Right)
+			}
+			if _, ok = ve.Value.(string); !ok {
+				t.Fatalf("expected string value but got %T", ve.Value)
+			}
+			if !reflect.DeepEqual(ve.DecodedValue, tt.want) {
+				t.Fatalf("expected decoded value %v but got %v", tt.want, ve.DecodedValue)
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Fatalf("expected valid expression but got: %v", err)
+			}
+		})
+	}
+
+	t.Run("array", func(t *testing.T) {
+		if _, err := i.Parse(`name IN ["projects/p1/books/b1", "books/b2"]`); !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("expected error %v but got %v", ErrInvalidValue, err)
+		}
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		x, err := i.Parse(`name = "projects/p1/books/*"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		x, err := i.Parse(`name = "projects/p1/books/b1"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		c := x.Clone()
+		defer c.Free()
+		if c.(*expr.CompareExpr).Right.(*expr.ValueExpr).DecodedValue == nil {
+			t.Fatalf("expected decoded value to be cloned")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		d, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		x, err := d.Parse(`name = "books/b1"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	})
+}
+
+// testResourceNameMessage builds the testresourcenames.Book resource message with the name field,
+// the parent field referencing its parent and the repeated related field referencing other books.
+func testResourceNameMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	bookOpts := new(descriptorpb.MessageOptions)
+	proto.SetExtension(bookOpts, annotations.E_Resource, &annotations.ResourceDescriptor{
+		Type:    "testresourcenames.example.com/Book",
+		Pattern: []string{"projects/{project}/books/{book}", "shelves/{shelf}/books/{book}"},
+	})
+	parentOpts := new(descriptorpb.FieldOptions)
+	proto.SetExtension(parentOpts, annotations.E_ResourceReference, &annotations.ResourceReference{
+		ChildType: "testresourcenames.example.com/Book",
+	})
+	relatedOpts := new(descriptorpb.FieldOptions)
+	proto.SetExtension(relatedOpts, annotations.E_ResourceReference, &annotations.ResourceReference{
+		Type: "testresourcenames.example.com/Book",
+	})
+
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+			Options:  opts,
+		}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testresourcenames/book.proto"),
+		Package:    proto.String("testresourcenames"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{annotations.File_google_api_resource_proto.Path()},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:    proto.String("Book"),
+			Options: bookOpts,
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, nil),
+				field("parent", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, parentOpts),
+				field("related", 3, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, relatedOpts),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build test file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
@@ -21,34 +21,135 @@ import (
 
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
 )
 
 // HandleRestrictionExpr handles an ast.Restriction expression and returns resulting expr.FilterExpr.
 func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.RestrictionExpr) (TryParseValueResult, error) {
+	x, negated := positiveRestriction(x)
+	res, err := b.handleRestrictionExpr(ctx, x)
+	if err != nil {
+		return res, err
+	}
+	if b.caseInsensitive || len(b.caseInsensitiveFields) > 0 {
+		b.markCaseInsensitive(res.Expr)
+	}
+	if b.disallowIndirectComparisons {
+		if res, err = b.checkIndirectComparison(ctx, x, res); err != nil {
+			return res, err
+		}
+	}
+	if negated && res.Expr != nil {
+		ne := expr.AcquireNotExpr()
+		ne.Expr = res.Expr
+		res.Expr = ne
+	}
+	if len(b.restrictionHooks) == 0 || res.Expr == nil {
+		return res, nil
+	}
+	return b.callRestrictionHooks(ctx, x, res)
+}
+
+// positiveRestriction returns the restriction of the negated comparator x, i.e. 'a NOT IN [b]' or 'a !: b',
+// with the positive comparator, and true. Other restrictions are returned as they are.
+func positiveRestriction(x *ast.RestrictionExpr) (*ast.RestrictionExpr, bool) {
+	if x.Comparator == nil {
+		return x, false
+	}
+	ct, ok := x.Comparator.Type.Negated()
+	if !ok {
+		return x, false
+	}
+	cmp := *x.Comparator
+	cmp.Type = ct
+	px := *x
+	px.Comparator = &cmp
+	return &px, true
+}
+
+func (b *Interpreter) handleRestrictionExpr(ctx *ParseContext, x *ast.RestrictionExpr) (TryParseValueResult, error) {
 	// Try parsing the inner ComparableExpr
 	var left expr.FilterExpr
 	switch xt := x.Comparable.(type) {
 	case *ast.MemberExpr:
+		if x.Comparator == nil && b.globalSearch != nil {
+			if term, ok := globalSearchTerm(xt); ok {
+				return b.handleGlobalSearch(ctx, xt, term)
+			}
+		}
 		// Try to get the named selector from the left hand side.
 		res, err := b.TryParseSelectorExpr(ctx, xt.Value, xt.Fields...)
 		if err != nil {
 			return res, err
 		}
+		return b.handleSelectorRestriction(ctx, x, xt.Position(), res.Expr)
+	case *ast.FunctionCall:
+		if b.isAnyUnpackCall(xt) {
+			res, err := b.tryParseAnyUnpack(ctx, xt)
+			if err != nil {
+				return res, err
+			}
+			return b.handleSelectorRestriction(ctx, x, xt.Position(), res.Expr)
+		}
+		if b.isBetweenCall(xt) {
+			return b.handleBetween(ctx, x, xt)
+		}
+		if b.isCollectionCall(xt) {
+			return b.handleCollectionCall(ctx, x, xt)
+		}
+		fn, ok := b.getFunctionDeclaration(ctx, xt)
+		if !ok {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = xt.Pos
+				res.ErrMsg = fmt.Sprintf("function: %s undefined", xt.JoinedName())
+			}
+			return res, ErrInvalidValue
+		}
+
+		res, err := b.tryParseAndCallFunction(ctx, xt, fn, true)
+		if err != nil {
+			return res, err
+		}
+
 		left = res.Expr
 
-		// The left hand side is a selector expression.
-		// Check if there is a comparator.
-		if x.Comparator == nil {
+		if !fn.ServiceCall() && !res.IsIndirect {
+			// The left hand side is not an indirect form.
+			// The left hand side of the restriction needs to be indirect.
 			var res TryParseValueResult
-			// No comparator on the selector part is an error.
 			if ctx.ErrHandler != nil {
-				res.ErrPos = xt.Position()
-				res.ErrMsg = "missing comparator in restriction expression"
+				res.ErrPos = xt.Pos
+				res.ErrMsg = fmt.Sprintf("function: %s does not depend on the filtered message", xt.JoinedName())
 			}
-			left.Free() // Free the selector expression.
+			left.Free()
 			return res, ErrInvalidValue
 		}
 
+		if fn.ServiceCall() {
+			// The result should be an expr.FunctionCallExpr.
+			// Check if there is a comparator and an argument.
+			if x.Comparator != nil || x.Arg != nil {
+				// Service calls cannot have a comparator or an argument.
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = xt.Pos
+					res.ErrMsg = fmt.Sprintf("function: %s cannot have a comparator or an argument", xt.JoinedName())
+				}
+				left.Free()
+				return res, ErrInvalidValue
+			}
+			return res, nil
+		}
+
+		ad := fn.Returning
+
+		// The result should be an indirect expr.FunctionCallExpr,
+		if x.Comparator == nil || x.Arg == nil {
+			return res, nil
+		}
+
+		// Parse comparator.
 		cmp, ok := parseComparator(x.Comparator)
 		if !ok {
 			var res TryParseValueResult
@@ -60,61 +161,148 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 			return res, ErrInternal
 		}
 
-		field, mk, fd, ok := b.traverseLastFieldExpr(left)
-		if !ok {
-			// The left hand side is not a field selector expression.
-			// This is an internal error.
-			var res TryParseValueResult
-			if ctx.ErrHandler != nil {
-				res.ErrPos = xt.Position()
-				res.ErrMsg = "internal error: left hand side of restriction expression is not a field selector expression"
+		switch at := x.Arg.(type) {
+		case *ast.MemberExpr, *ast.StructExpr, *ast.ArrayExpr:
+		case *ast.BinaryExpr:
+			return b.handleArithmeticRestriction(ctx, x, cmp, left, ad, at)
+		case *ast.FunctionCall:
+			argFn, ok := b.getFunctionDeclaration(ctx, at)
+			if !ok {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = at.Pos
+					res.ErrMsg = fmt.Sprintf("function: %s undefined", at.JoinedName())
+				}
+				left.Free()
+				return res, ErrInvalidValue
 			}
-			left.Free()
-			return res, ErrInternal
-		}
 
-		// If the field is a map key and comparator is HAS, check if the right side is a wildcard TEXT literal.
-		if cmp == expr.HAS && mk != nil {
-			if me, ok := x.Arg.(*ast.MemberExpr); ok {
-				if tl, ok := me.Value.(*ast.TextLiteral); ok && len(me.Fields) == 0 && tl.Value == "*" {
-					// Modify the expression as a map field selector has a key expression.
-
-					// Extract key from the map key expression.
-					ke := mk.Key
-
-					// Clear the map key expression.
-					mk.Key = nil
-					mk.Free()
-					field.Traversal = nil
-
-					// Return a compare expression with the field selector and a key expression.
-					ce := expr.AcquireCompareExpr()
-					ce.Left = field
-					ce.Comparator = cmp
-					ce.Right = ke.(expr.FilterExpr)
-					return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+			if argFn.ServiceCall() {
+				// This is not a valid value expression.
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = at.Pos
+					res.ErrMsg = fmt.Sprintf("function: %s can't be as a comparator argument", at.JoinedName())
+				}
+				left.Free()
+				return res, ErrInvalidValue
+			} else {
+				// Try to match the kind of resulting value with the argument declaration.
+				rt := argFn.Returning
+
+				if rt.FieldKind != ad.FieldKind {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.FieldKind)
+					}
+					left.Free()
+					return res, ErrInvalidValue
+				}
+
+				if rt.EnumDescriptor != nil && rt.EnumDescriptor.FullName() != ad.EnumDescriptor.FullName() {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.EnumDescriptor.FullName())
+					}
+					left.Free()
+					return res, ErrInvalidValue
+				}
+
+				if ad.Message() != nil && ad.IsMap() && !rt.IsMap() {
+					if cmp != expr.HAS {
+						var res TryParseValueResult
+						if ctx.ErrHandler != nil {
+							res.ErrPos = x.Position()
+							res.ErrMsg = fmt.Sprintf("function call %s does not return a map value", at.JoinedName())
+						}
+						left.Free()
+						return res, ErrInvalidValue
+					}
+				}
+
+				if ad.Message() != nil && ad.Message().FullName() != rt.Message().FullName() {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.Message().FullName())
+					}
+					left.Free()
+					return res, ErrInvalidValue
+				}
+
+				if ad.Cardinality() == protoreflect.Repeated && rt.Cardinality() != protoreflect.Repeated {
+					if cmp != expr.IN {
+						var res TryParseValueResult
+						if ctx.ErrHandler != nil {
+							res.ErrPos = x.Position()
+							res.ErrMsg = fmt.Sprintf("function call %s is repeated", at.JoinedName())
+						}
+						left.Free()
+						return res, ErrInvalidValue
+					}
 				}
 			}
-		}
 
-		fi := b.msgInfo.GetFieldInfo(fd)
-		switch {
-		case mk != nil:
-			// If the left-hand side is a map key expr, set the field descriptor as map value.
-			fd = fd.MapValue()
-		case fd.Kind() == protoreflect.MessageKind && fd.IsMap() && cmp == expr.HAS:
-			fd = fd.MapKey()
+			// Call right hand side.
+			rfn, err := b.tryParseAndCallFunction(ctx, at, argFn, true)
+			if err != nil {
+				left.Free()
+				return rfn, err
+			}
+
+			ce := expr.AcquireCompareExpr()
+			ce.Left = left
+			ce.Comparator = cmp
+			ce.Right = rfn.Expr
+			return TryParseValueResult{Expr: ce, IsIndirect: res.IsIndirect || rfn.IsIndirect}, nil
+		case *ast.CompositeExpr:
+			// Handle composite if only the left hand side is a boolean expression.
+			if fn.ServiceCall() || (fn.Returning.FieldKind != protoreflect.BoolKind || fn.Returning.Cardinality() == protoreflect.Repeated) {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("function: %s cannot have a composite argument", xt.JoinedName())
+				}
+				left.Free()
+				return res, ErrInvalidValue
+			}
+
+			right, err := b.HandleCompositeExpr(ctx, at)
+			if err != nil {
+				left.Free()
+				return right, err
+			}
+
+			// The right hand side is a composite expression.
+			ce := expr.AcquireCompareExpr()
+			ce.Left = left
+			ce.Comparator = cmp
+			ce.Right = right.Expr
+			return TryParseValueResult{Expr: ce, IsIndirect: res.IsIndirect || right.IsIndirect}, nil
+		default:
+			// Not a valid type for right hand side, internal error.
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Arg.Position()
+				res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value type: %T", x.Arg)
+			}
+			left.Free()
+			return res, ErrInternal
 		}
 
-		// Try getting the value of the right hand side.
+		// Parse argument.
 		ve, err := b.TryParseValue(ctx, TryParseValueInput{
-			Field:         fd,
-			Value:         x.Arg,
+			Field:         fn.Returning,
 			AllowIndirect: true,
-			IsOptional:    fi.Nullable,
-			Complexity:    fi.Complexity,
+			Value:         x.Arg,
+			IsOptional:    fn.Returning.IsNullable,
+			Complexity:    fn.Complexity,
 		})
 		if err != nil {
+			// If the right hand side is not a value expression,
+			// try parsing it as a field selector.
 			// The right hand side is not a value expression, try parsing it as a selector.
 			switch at := x.Arg.(type) {
 			case *ast.MemberExpr:
@@ -147,58 +335,21 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 					return res, ErrInternal
 				}
 
-				lf := fd
+				lf := fn.Returning
 				rf := rd
 
-				// Check the ambiguity of the left and right hand side.
-				// This means that the left hand side ie equal to the right hand side directly.
-				// I.e.: field = field
-				if lf.FullName() == rf.FullName() && countTraversal(res.Expr) == countTraversal(right.Expr) {
-					// This is ambiguous and is not a valid filter.
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side is ambiguous: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrAmbiguousField
-				}
-
-				var leftIsMapKey bool
 				switch {
-				case mk != nil:
-					// If the left-hand side is a map key expr, set the field descriptor as map value.
-					lf = fd.MapValue()
 				case rmk != nil:
 					// If the right-hand side is a map key expr, set the field descriptor as map value.
 					rf = rd.MapValue()
-				case lf.Kind() == protoreflect.MessageKind && lf.IsMap():
-					// If the left-hand side is a map, set the field descriptor as map key.
-					leftIsMapKey = true
-					lf = lf.MapKey()
 				case rf.Kind() == protoreflect.MessageKind && rf.IsMap():
 					// If the right-hand side is a map, set the field descriptor as map key.
 					rf = rf.MapKey()
 				}
 
 				// This means that the right hand side is a value of the map.
-				// We need to check the type of the map value.
-				if !isKindComparable(lf.Kind(), rf.Kind()) {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
-				}
-
-				// If the field is an enum or a message matching descriptors.
-				if lf.Kind() == protoreflect.EnumKind && lf.Enum().FullName() != rf.Enum().FullName() {
+				// We need to check the type of the map value, and the descriptors of the enums and messages.
+				if !isSameValueType(b.IsKindComparable, lf, rf) {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						// Invalid value.
@@ -208,21 +359,10 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 					right.Expr.Free()
 					left.Free()
 					return res, ErrInvalidValue
-				} else if lf.Kind() == protoreflect.MessageKind && lf.Message().FullName() != rf.Message().FullName() {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						ctx.ErrHandler(x.Arg.Position(), fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String()))
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
 				}
 
 				// Check if the left hand side is repeated and the right is not.
-				if lf.Cardinality() == protoreflect.Repeated && rf.Cardinality() != protoreflect.Repeated {
+				if lf.IsRepeated && rf.Cardinality() != protoreflect.Repeated {
 					// If the comparator is not HAS, this is an error.
 					// I.e. array_field:value
 					if x.Comparator.Type != ast.HAS {
@@ -239,7 +379,7 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 				}
 
 				// Check if the left hand side is neither a map key nor repeated and the operator is HAS.
-				if (!leftIsMapKey || lf.Cardinality() != protoreflect.Repeated) && x.Comparator.Type == ast.HAS {
+				if !lf.IsRepeated && x.Comparator.Type == ast.HAS {
 					// If the comparator is HAS and the left hand side is not a map key, this is an error.
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
@@ -253,15 +393,14 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 				}
 
 				// Check if the right hand side is repeated and the left is not.
-				if rf.Cardinality() == protoreflect.Repeated && lf.Cardinality() != protoreflect.Repeated && !lf.IsMap() {
+				if rf.Cardinality() == protoreflect.Repeated && !lf.IsRepeated {
 					// If the comparator is different from IN, this is an error.
 					if x.Comparator.Type != ast.IN {
 						var res TryParseValueResult
 						if ctx.ErrHandler != nil {
 							// Invalid value.
-							ctx.ErrHandler(x.Comparator.Position(), fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field: %s with a comparator: %s", rf.FullName(), field.Field, x.Comparator.String()))
 							res.ErrPos = x.Comparator.Position()
-							res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field: %s with a comparator: %s", rf.FullName(), field.Field, x.Comparator.String())
+							res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field with a comparator: %s", rf.FullName(), x.Comparator.String())
 						}
 						right.Expr.Free()
 						left.Free()
@@ -271,155 +410,37 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 
 				// The selectors should be valid now.
 				ex := expr.AcquireCompareExpr()
-				ex.Left = field
+				ex.Left = left
 				ex.Comparator = cmp
 				ex.Right = right.Expr
 				return TryParseValueResult{Expr: ex, IsIndirect: true}, nil
-			case *ast.FunctionCall:
-				argFn, ok := b.getFunctionDeclaration(ctx, at)
-				if !ok {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						res.ErrPos = at.Pos
-						res.ErrMsg = fmt.Sprintf("function: %s undefined", at.JoinedName())
-					}
-					return res, ErrInvalidValue
-				}
-
-				if argFn.ServiceCall() {
-					// This is not a valid value expression.
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						res.ErrPos = at.Pos
-						res.ErrMsg = fmt.Sprintf("function: %s can't be as a comparator argument", at.JoinedName())
-					}
-					left.Free()
-					return res, ErrInvalidValue
-				} else {
-					// Try to match the kind of resulting value with the argument declaration.
-					rt := argFn.Returning
-
-					if rt.FieldKind != fd.Kind() {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.FieldKind)
-						}
-						left.Free()
-						return res, ErrInvalidValue
-					}
-
-					if rt.EnumDescriptor != nil && rt.EnumDescriptor.FullName() != fd.Enum().FullName() {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.EnumDescriptor.FullName())
-						}
-						left.Free()
-						return res, ErrInvalidValue
-					}
-
-					if fd.Message() != nil && fd.IsMap() && !rt.IsMap() {
-						if cmp != expr.HAS {
-							var res TryParseValueResult
-							if ctx.ErrHandler != nil {
-								res.ErrPos = x.Position()
-								res.ErrMsg = fmt.Sprintf("function call %s does not return a map value", at.JoinedName())
-							}
-							left.Free()
-							return res, ErrInvalidValue
-						}
-					}
-
-					if fd.Message() != nil && fd.Message().FullName() != rt.Message().FullName() {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.Message().FullName())
-						}
-						left.Free()
-						return res, ErrInvalidValue
-					}
-
-					if fd.Cardinality() == protoreflect.Repeated && rt.Cardinality() != protoreflect.Repeated {
-						if cmp != expr.IN {
-							var res TryParseValueResult
-							if ctx.ErrHandler != nil {
-								res.ErrPos = x.Position()
-								res.ErrMsg = fmt.Sprintf("function call %s is repeated", at.JoinedName())
-							}
-							left.Free()
-							return res, ErrInvalidValue
-						}
-					}
-				}
-
-				// Call right hand side.
-				rfn, err := b.tryParseAndCallFunction(ctx, at, argFn, true)
-				if err != nil {
-					left.Free()
-					return rfn, err
-				}
-
-				ce := expr.AcquireCompareExpr()
-				ce.Left = left
-				ce.Comparator = cmp
-				ce.Right = rfn.Expr
-				return TryParseValueResult{Expr: ce, IsIndirect: res.IsIndirect || rfn.IsIndirect}, nil
-			default:
-				// The right hand side is not a selector expression.
-				// Thus return an error.
-				left.Free()
-				return ve, err
-			}
-		}
+			default:
+				// The right hand side is not a selector expression.
+				// Thus return an error.
+				left.Free()
+				return ve, err
+			}
+		}
 
 		// The right hand side is a value expression.
 		// Check if the operator matches the expression type.
 		switch vt := ve.Expr.(type) {
 		case *expr.ValueExpr:
 			// The right hand side is a value expression,
-			// if the left hand side is a map key or a repeated field, the operator must be HAS.
-			if (mk != nil || fd.Cardinality() == protoreflect.Repeated) && cmp != expr.HAS {
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", field.Field, x.Comparator.String())
-				}
-				left.Free()
-				vt.Free()
-				return res, ErrInvalidValue
-			}
-		// The right hand side is a proper value expression.
-		case *expr.ArrayExpr:
-			// The right hand side is an array expression,
-			// check if the left hand side is either a repeated field or a
-			// single field with IN comparator.
-			if fd.Cardinality() != protoreflect.Repeated && cmp != expr.IN {
+			// if the left hand side is a repeated field, the operator must be IN.
+			if fn.Returning.IsRepeated && cmp != expr.IN {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", field.Field, x.Comparator.String())
-				}
-				left.Free()
-				vt.Free()
-				return res, ErrInvalidValue
-			}
-		case *expr.MapValueExpr:
-			// The right hand side is a map value expression,
-			// The left hand side must be a map field (NOT a map key).
-			if !fd.IsMap() || mk != nil {
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Arg.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a map with a non map field: %s", field.Field)
+					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field with a comparator: %s", x.Comparator.String())
 				}
 				left.Free()
 				vt.Free()
 				return res, ErrInvalidValue
 			}
-			// Comparator can only accept EQ or NEQ.
-			if cmp != expr.EQ && cmp != expr.NE {
+
+			// If the left hand side is a map return an error, as ValueExpr cannot be a map.
+			if fn.Returning.IsMap() && cmp != expr.HAS {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Comparator.Position()
@@ -429,588 +450,668 @@ func (b *Interpreter) HandleRestrictionExpr(ctx *ParseContext, x *ast.Restrictio
 				vt.Free()
 				return res, ErrInvalidValue
 			}
-		case *expr.StringSearchExpr:
-			// The right hand side is a string search expression,
-			// The comparator needs to be EQ or IN.
-			if cmp != expr.EQ && cmp != expr.IN {
+		case *expr.ArrayExpr:
+			// The right hand side is an array expression,
+			// check if the left hand side is either a repeated field or a
+			// single field with IN comparator.
+			if !fn.Returning.IsRepeated && cmp != expr.IN {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a string search expression with a comparator: %s", x.Comparator.String())
+					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field with a comparator: %s", x.Comparator.String())
 				}
 				left.Free()
 				vt.Free()
 				return res, ErrInvalidValue
 			}
-
-			// If the left hand side is repeated field than it is an error.
-			if fd.Cardinality() == protoreflect.Repeated {
+		case *expr.MapValueExpr:
+			// If the left hand side is not a map and comparator is neither EQ nor NEQ, return an error.
+			if !fn.Returning.IsMap() || (cmp != expr.EQ && cmp != expr.NE) {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", fd.FullName(), x.Comparator.String())
+					res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
 				}
 				left.Free()
 				vt.Free()
 				return res, ErrInvalidValue
 			}
-
-			if fi.NoTextSearch {
+		case *expr.FunctionCallExpr:
+			// The right hand side is a function call expression,
+			// Check if the returning value of the function call matches the left hand side.
+			vfn := b.functionCallDeclarations[vt.FullName()]
+			if fn.Returning.Kind() != vfn.Returning.Kind() {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a field: %s with a string search expression", fd.FullName())
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("resulting function call type: %s does not match the left hand side type: %s", vfn.Returning.Kind().String(), fn.Returning.Kind().String())
 				}
 				left.Free()
 				vt.Free()
 				return res, ErrInvalidValue
 			}
 
-		default:
-			// The right hand side is not a value expression.
-			// Thus return an error.
-			var res TryParseValueResult
-			if ctx.ErrHandler != nil {
-				res.ErrPos = x.Arg.Position()
-				res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value type: %T", ve.Expr)
-			}
-			left.Free()
-			vt.Free()
-			return res, ErrInternal
-		}
-
-		ce := expr.AcquireCompareExpr()
-		ce.Left = field
-		ce.Comparator = cmp
-		ce.Right = ve.Expr
-		return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
-	case *ast.FunctionCall:
-		fn, ok := b.getFunctionDeclaration(ctx, xt)
-		if !ok {
-			var res TryParseValueResult
-			if ctx.ErrHandler != nil {
-				res.ErrPos = xt.Pos
-				res.ErrMsg = fmt.Sprintf("function: %s undefined", xt.JoinedName())
-			}
-			return res, ErrInvalidValue
-		}
-
-		res, err := b.tryParseAndCallFunction(ctx, xt, fn, true)
-		if err != nil {
-			return res, err
-		}
-
-		left = res.Expr
-
-		if !fn.ServiceCall() && !res.IsIndirect {
-			// The left hand side is not an indirect form.
-			// The left hand side of the restriction needs to be indirect.
-			var res TryParseValueResult
-			if ctx.ErrHandler != nil {
-				res.ErrPos = xt.Pos
-				res.ErrMsg = fmt.Sprintf("function: %s does not depend on the filtered message", xt.JoinedName())
-			}
-			left.Free()
-			return res, ErrInvalidValue
-		}
-
-		if fn.ServiceCall() {
-			// The result should be an expr.FunctionCallExpr.
-			// Check if there is a comparator and an argument.
-			if x.Comparator != nil || x.Arg != nil {
-				// Service calls cannot have a comparator or an argument.
+			if fn.Returning.Kind() == protoreflect.EnumKind && fn.Returning.Enum().FullName() != vfn.Returning.Enum().FullName() {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = xt.Pos
-					res.ErrMsg = fmt.Sprintf("function: %s cannot have a comparator or an argument", xt.JoinedName())
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("resulting function call enum type: %s does not match the left hand side enum type: %s", vfn.Returning.Enum().FullName(), fn.Returning.Enum().FullName())
 				}
 				left.Free()
+				vt.Free()
 				return res, ErrInvalidValue
 			}
-			return res, nil
-		}
-
-		ad := fn.Returning
-
-		// The result should be an indirect expr.FunctionCallExpr,
-		if x.Comparator == nil || x.Arg == nil {
-			return res, nil
-		}
-
-		// Parse comparator.
-		cmp, ok := parseComparator(x.Comparator)
-		if !ok {
-			var res TryParseValueResult
-			if ctx.ErrHandler != nil {
-				res.ErrPos = x.Comparator.Position()
-				res.ErrMsg = fmt.Sprintf("unknown comparator: %s", x.Comparator.String())
-			}
-			left.Free()
-			return res, ErrInternal
-		}
-
-		switch at := x.Arg.(type) {
-		case *ast.MemberExpr, *ast.StructExpr, *ast.ArrayExpr:
-		case *ast.FunctionCall:
-			argFn, ok := b.getFunctionDeclaration(ctx, at)
-			if !ok {
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = at.Pos
-					res.ErrMsg = fmt.Sprintf("function: %s undefined", at.JoinedName())
-				}
-				return res, ErrInvalidValue
-			}
-
-			if argFn.ServiceCall() {
-				// This is not a valid value expression.
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = at.Pos
-					res.ErrMsg = fmt.Sprintf("function: %s can't be as a comparator argument", at.JoinedName())
-				}
-				left.Free()
-				return res, ErrInvalidValue
-			} else {
-				// Try to match the kind of resulting value with the argument declaration.
-				rt := argFn.Returning
 
-				if rt.FieldKind != ad.FieldKind {
+			if fn.Returning.Kind() == protoreflect.MessageKind {
+				if (fn.Returning.IsMap() && !vfn.Returning.IsMap()) || (!fn.Returning.IsMap() && vfn.Returning.IsMap()) {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.FieldKind)
+						res.ErrPos = x.Arg.Position()
+						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
 					}
 					left.Free()
+					vt.Free()
 					return res, ErrInvalidValue
 				}
-
-				if rt.EnumDescriptor != nil && rt.EnumDescriptor.FullName() != ad.EnumDescriptor.FullName() {
+				if fn.Returning.IsMap() && (fn.Returning.MapKey().FullName() != vfn.Returning.MapKey().FullName() ||
+					fn.Returning.MapValue().FullName() != vfn.Returning.MapValue().FullName()) {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.EnumDescriptor.FullName())
+						res.ErrPos = x.Arg.Position()
+						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
 					}
 					left.Free()
+					vt.Free()
 					return res, ErrInvalidValue
 				}
 
-				if ad.Message() != nil && ad.IsMap() && !rt.IsMap() {
-					if cmp != expr.HAS {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s does not return a map value", at.JoinedName())
-						}
-						left.Free()
-						return res, ErrInvalidValue
-					}
-				}
-
-				if ad.Message() != nil && ad.Message().FullName() != rt.Message().FullName() {
+				if fn.Returning.IsMap() && cmp != expr.EQ && cmp != expr.NE {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Position()
-						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.Message().FullName())
+						res.ErrPos = x.Comparator.Position()
+						res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
 					}
 					left.Free()
+					vt.Free()
 					return res, ErrInvalidValue
 				}
 
-				if ad.Cardinality() == protoreflect.Repeated && rt.Cardinality() != protoreflect.Repeated {
-					if cmp != expr.IN {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = x.Position()
-							res.ErrMsg = fmt.Sprintf("function call %s is repeated", at.JoinedName())
-						}
-						left.Free()
-						return res, ErrInvalidValue
+				if !fn.Returning.IsMap() && fn.Returning.Message().FullName() != vfn.Returning.Message().FullName() {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = x.Arg.Position()
+						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
 					}
+					left.Free()
+					vt.Free()
+					return res, ErrInvalidValue
 				}
 			}
-
-			// Call right hand side.
-			rfn, err := b.tryParseAndCallFunction(ctx, at, argFn, true)
-			if err != nil {
-				left.Free()
-				return rfn, err
-			}
-
-			ce := expr.AcquireCompareExpr()
-			ce.Left = left
-			ce.Comparator = cmp
-			ce.Right = rfn.Expr
-			return TryParseValueResult{Expr: ce, IsIndirect: res.IsIndirect || rfn.IsIndirect}, nil
-		case *ast.CompositeExpr:
-			// Handle composite if only the left hand side is a boolean expression.
-			if fn.ServiceCall() || (fn.Returning.FieldKind != protoreflect.BoolKind || fn.Returning.Cardinality() == protoreflect.Repeated) {
+		case *expr.StringSearchExpr:
+			// The right hand side is a string search expression,
+			// The comparator needs to be EQ or IN.
+			if cmp != expr.EQ && cmp != expr.IN {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Arg.Position()
-					res.ErrMsg = fmt.Sprintf("function: %s cannot have a composite argument", xt.JoinedName())
+					res.ErrPos = x.Comparator.Position()
+					res.ErrMsg = fmt.Sprintf("cannot compare a string search expression with a comparator: %s", x.Comparator.String())
 				}
 				left.Free()
+				vt.Free()
 				return res, ErrInvalidValue
 			}
 
-			right, err := b.HandleCompositeExpr(ctx, at)
-			if err != nil {
+			// If the left hand side is repeated field than it is an error.
+			if fn.Returning.Cardinality() == protoreflect.Repeated {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = x.Comparator.Position()
+					res.ErrMsg = fmt.Sprintf("cannot compare a repeated function result with a comparator: %s for string search", x.Comparator.String())
+				}
 				left.Free()
-				return right, err
+				vt.Free()
+				return res, ErrInvalidValue
 			}
 
-			// The right hand side is a composite expression.
-			ce := expr.AcquireCompareExpr()
-			ce.Left = left
-			ce.Comparator = cmp
-			ce.Right = right.Expr
-			return TryParseValueResult{Expr: ce, IsIndirect: res.IsIndirect || right.IsIndirect}, nil
 		default:
-			// Not a valid type for right hand side, internal error.
+			// The right hand side is not a value expression.
+			// Thus return an error.
 			var res TryParseValueResult
 			if ctx.ErrHandler != nil {
+				// This is invalid  value error ?
 				res.ErrPos = x.Arg.Position()
-				res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value type: %T", x.Arg)
+				res.ErrMsg = "invalid value for restriction expression"
 			}
 			left.Free()
-			return res, ErrInternal
+			vt.Free()
+			return res, ErrInvalidValue
 		}
 
-		// Parse argument.
-		ve, err := b.TryParseValue(ctx, TryParseValueInput{
-			Field:         fn.Returning,
-			AllowIndirect: true,
-			Value:         x.Arg,
-			IsOptional:    fn.Returning.IsNullable,
-			Complexity:    fn.Complexity,
-		})
-		if err != nil {
-			// If the right hand side is not a value expression,
-			// try parsing it as a field selector.
-			// The right hand side is not a value expression, try parsing it as a selector.
-			switch at := x.Arg.(type) {
-			case *ast.MemberExpr:
-				// Try to get the named selector from the right hand side.
-				right, err2 := b.TryParseSelectorExpr(ctx, at.Value, at.Fields...)
-				if err2 != nil {
-					// The right hand side is neither a value expression nor a selector expression.
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value: %s", x.Arg.String())
-					}
-					left.Free()
-					return res, ErrInvalidValue
-				}
+		// The selectors should be valid now.
+		ex := expr.AcquireCompareExpr()
+		ex.Left = left
+		ex.Comparator = cmp
+		ex.Right = ve.Expr
+		return TryParseValueResult{Expr: ex, IsIndirect: ve.IsIndirect}, nil
+	default:
+		// The left hand side is not a selector expression.
+		// This is invalid value error.
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = xt.Position()
+			res.ErrMsg = fmt.Sprintf("the left hand side is not a valid selector: %s", xt.String())
+		}
+		return res, ErrInvalidValue
+	}
+}
 
-				// Check if traversal of the right hand side types matches the left hand side types.
-				_, rmk, rd, ok := b.traverseLastFieldExpr(right.Expr)
-				if !ok {
-					// The right hand side is not a field selector expression.
-					// This is an internal error.
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						res.ErrPos = at.Position()
-						res.ErrMsg = "internal error: right hand side of restriction expression is not a field selector expression"
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInternal
-				}
+// handleSelectorRestriction handles the restriction expression, which left hand side is the field selector at the pos.
+func (b *Interpreter) handleSelectorRestriction(ctx *ParseContext, x *ast.RestrictionExpr, pos token.Position, left expr.FilterExpr) (TryParseValueResult, error) {
+	// The left hand side is a selector expression.
+	// Check if there is a comparator.
+	if x.Comparator == nil {
+		var res TryParseValueResult
+		// No comparator on the selector part is an error.
+		if ctx.ErrHandler != nil {
+			res.ErrPos = pos
+			res.ErrMsg = "missing comparator in restriction expression"
+		}
+		left.Free() // Free the selector expression.
+		return res, ErrInvalidValue
+	}
 
-				lf := fn.Returning
-				rf := rd
+	switch x.Comparator.Type {
+	case ast.MATCH:
+		return b.handleRegexMatch(ctx, x, left)
+	case ast.CUSTOM:
+		return b.handleCustomComparator(ctx, x, left)
+	}
 
-				switch {
-				case rmk != nil:
-					// If the right-hand side is a map key expr, set the field descriptor as map value.
-					rf = rd.MapValue()
-				case rf.Kind() == protoreflect.MessageKind && rf.IsMap():
-					// If the right-hand side is a map, set the field descriptor as map key.
-					rf = rf.MapKey()
-				}
+	cmp, ok := parseComparator(x.Comparator)
+	if !ok {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = x.Comparator.Position()
+			res.ErrMsg = fmt.Sprintf("unknown comparator: %s", x.Comparator.String())
+		}
+		left.Free()
+		return res, ErrInternal
+	}
 
-				// This means that the right hand side is a value of the map.
-				// We need to check the type of the map value.
-				if isKindComparable(lf.Kind(), rf.Kind()) {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
-				}
+	field, mk, fd, ok := b.traverseLastFieldExpr(left)
+	if !ok {
+		// The left hand side is not a field selector expression.
+		// This is an internal error.
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = pos
+			res.ErrMsg = "internal error: left hand side of restriction expression is not a field selector expression"
+		}
+		left.Free()
+		return res, ErrInternal
+	}
 
-				// If the field is an enum or a message matching descriptors.
-				if lf.Kind() == protoreflect.EnumKind && lf.Enum().FullName() != rf.Enum().FullName() {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
-				} else if lf.Kind() == protoreflect.MessageKind && lf.Message().FullName() != rf.Message().FullName() {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
-				}
+	// If the field is a map key and comparator is HAS, check if the right side is a wildcard TEXT literal.
+	if cmp == expr.HAS && mk != nil {
+		if me, ok := x.Arg.(*ast.MemberExpr); ok {
+			if tl, ok := me.Value.(*ast.TextLiteral); ok && len(me.Fields) == 0 && tl.Value == "*" {
+				// Modify the expression as a map field selector has a key expression.
 
-				// Check if the left hand side is repeated and the right is not.
-				if lf.IsRepeated && rf.Cardinality() != protoreflect.Repeated {
-					// If the comparator is not HAS, this is an error.
-					// I.e. array_field:value
-					if x.Comparator.Type != ast.HAS {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							// Invalid value.
-							res.ErrPos = x.Arg.Position()
-							res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
-						}
-						right.Expr.Free()
-						left.Free()
-						return res, ErrInvalidValue
-					}
-				}
+				// Extract key from the map key expression.
+				ke := mk.Key
 
-				// Check if the left hand side is neither a map key nor repeated and the operator is HAS.
-				if !lf.IsRepeated && x.Comparator.Type == ast.HAS {
-					// If the comparator is HAS and the left hand side is not a map key, this is an error.
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						// Invalid value.
-						res.ErrPos = x.Comparator.Position()
-						res.ErrMsg = "operator HAS (':') can only be used on map or repeated fields"
-					}
-					right.Expr.Free()
-					left.Free()
-					return res, ErrInvalidValue
-				}
+				// Clear the map key expression.
+				mk.Key = nil
+				mk.Free()
+				field.Traversal = nil
 
-				// Check if the right hand side is repeated and the left is not.
-				if rf.Cardinality() == protoreflect.Repeated && !lf.IsRepeated {
-					// If the comparator is different from IN, this is an error.
-					if x.Comparator.Type != ast.IN {
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							// Invalid value.
-							res.ErrPos = x.Comparator.Position()
-							res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field with a comparator: %s", rf.FullName(), x.Comparator.String())
-						}
-						right.Expr.Free()
-						left.Free()
-						return res, ErrInvalidValue
-					}
-				}
+				// Return a compare expression with the field selector and a key expression.
+				ce := expr.AcquireCompareExpr()
+				ce.Left = left
+				ce.Comparator = cmp
+				ce.Right = ke.(expr.FilterExpr)
+				return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+			}
+		}
+	}
 
-				// The selectors should be valid now.
-				ex := expr.AcquireCompareExpr()
-				ex.Left = left
-				ex.Comparator = cmp
-				ex.Right = right.Expr
-				return TryParseValueResult{Expr: ex, IsIndirect: true}, nil
-			default:
-				// The right hand side is not a selector expression.
-				// Thus return an error.
+	fi := b.msgInfo.GetFieldInfo(fd)
+
+	if cmp == expr.HAS && mk == nil && isPresenceArg(x.Arg) {
+		return b.handlePresence(ctx, x, left, fd, fi)
+	}
+
+	// A repeated message field with the HAS comparator and an unnamed struct pattern,
+	// matches if any of its elements matches the pattern, i.e.: items:{sku: "abc"}.
+	if st, ok := x.Arg.(*ast.StructExpr); ok && cmp == expr.HAS && mk == nil && len(st.Name) == 0 &&
+		isRepeatedMessage(fd, fi) {
+		ae, err := b.TryParseAnyElementExpr(ctx, fd, st)
+		if err != nil {
+			left.Free()
+			return ae, err
+		}
+
+		ce := expr.AcquireCompareExpr()
+		ce.Left = left
+		ce.Comparator = cmp
+		ce.Right = ae.Expr
+		return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
+	}
+
+	switch {
+	case mk != nil:
+		// If the left-hand side is a map key expr, set the field descriptor as map value.
+		fd = fd.MapValue()
+	case fd.Kind() == protoreflect.MessageKind && fd.IsMap() && cmp == expr.HAS:
+		fd = fd.MapKey()
+	}
+
+	if be, ok := x.Arg.(*ast.BinaryExpr); ok {
+		return b.handleArithmeticRestriction(ctx, x, cmp, left, fd, be)
+	}
+
+	// Try getting the value of the right hand side.
+	ve, err := b.TryParseValue(ctx, TryParseValueInput{
+		Field:         fd,
+		Value:         x.Arg,
+		AllowIndirect: true,
+		IsOptional:    fi.Nullable,
+		Complexity:    fi.Complexity,
+		IsLiteral:     b.isLiteralStringField(fd),
+	})
+	if b.traceFn != nil {
+		b.traceValue(x.Arg, fd, ve, err)
+	}
+	if err != nil && isParamRef(ctx, x.Arg) {
+		// The parameter placeholder is never a selector, return its binding error.
+		left.Free()
+		return ve, err
+	}
+	if err != nil {
+		// The right hand side is not a value expression, try parsing it as a selector.
+		switch at := x.Arg.(type) {
+		case *ast.MemberExpr:
+			// Try to get the named selector from the right hand side.
+			right, err2 := b.TryParseSelectorExpr(ctx, at.Value, at.Fields...)
+			if err2 != nil {
+				if b.isValueLiteral(fd, at) && ve.ErrMsg != "" {
+					// The null keyword, the bytes and UUID literals are never a selector, return their value error.
+					left.Free()
+					return ve, err
+				}
+				// The right hand side is neither a value expression nor a selector expression.
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					// Invalid value.
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value: %s", x.Arg.String())
+				}
 				left.Free()
-				return ve, err
+				return res, ErrInvalidValue
 			}
-		}
 
-		// The right hand side is a value expression.
-		// Check if the operator matches the expression type.
-		switch vt := ve.Expr.(type) {
-		case *expr.ValueExpr:
-			// The right hand side is a value expression,
-			// if the left hand side is a repeated field, the operator must be IN.
-			if fn.Returning.IsRepeated && cmp != expr.IN {
+			// Check if traversal of the right hand side types matches the left hand side types.
+			_, rmk, rd, ok := b.traverseLastFieldExpr(right.Expr)
+			if !ok {
+				// The right hand side is not a field selector expression.
+				// This is an internal error.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field with a comparator: %s", x.Comparator.String())
+					res.ErrPos = at.Position()
+					res.ErrMsg = "internal error: right hand side of restriction expression is not a field selector expression"
 				}
+				right.Expr.Free()
 				left.Free()
-				vt.Free()
-				return res, ErrInvalidValue
+				return res, ErrInternal
 			}
 
-			// If the left hand side is a map return an error, as ValueExpr cannot be a map.
-			if fn.Returning.IsMap() && cmp != expr.HAS {
+			lf := fd
+			rf := rd
+
+			// Check the ambiguity of the left and right hand side.
+			// This means that the left hand side ie equal to the right hand side directly.
+			// I.e.: field = field
+			if lf.FullName() == rf.FullName() && countTraversal(left) == countTraversal(right.Expr) {
+				// This is ambiguous and is not a valid filter.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
+					// Invalid value.
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("the right hand side is ambiguous: %s", x.Arg.String())
 				}
+				right.Expr.Free()
 				left.Free()
-				vt.Free()
-				return res, ErrInvalidValue
+				return res, ErrAmbiguousField
 			}
-		case *expr.ArrayExpr:
-			// The right hand side is an array expression,
-			// check if the left hand side is either a repeated field or a
-			// single field with IN comparator.
-			if !fn.Returning.IsRepeated && cmp != expr.IN {
+
+			var leftIsMapKey bool
+			switch {
+			case mk != nil:
+				// If the left-hand side is a map key expr, the field descriptor is already the map value.
+			case rmk != nil:
+				// If the right-hand side is a map key expr, set the field descriptor as map value.
+				rf = rd.MapValue()
+			case lf.Kind() == protoreflect.MessageKind && lf.IsMap():
+				// If the left-hand side is a map, set the field descriptor as map key.
+				leftIsMapKey = true
+				lf = lf.MapKey()
+			case rf.Kind() == protoreflect.MessageKind && rf.IsMap():
+				// If the right-hand side is a map, set the field descriptor as map key.
+				rf = rf.MapKey()
+			}
+
+			// This means that the right hand side is a value of the map.
+			// We need to check the type of the map value, and the descriptors of the enums and messages.
+			if !isSameValueType(b.IsKindComparable, lf, rf) {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated field with a comparator: %s", x.Comparator.String())
+					// Invalid value.
+					res.ErrPos = x.Arg.Position()
+					res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
 				}
+				right.Expr.Free()
 				left.Free()
-				vt.Free()
 				return res, ErrInvalidValue
 			}
-		case *expr.MapValueExpr:
-			// If the left hand side is not a map and comparator is neither EQ nor NEQ, return an error.
-			if !fn.Returning.IsMap() || (cmp != expr.EQ && cmp != expr.NE) {
+
+			// Check if the left hand side is repeated and the right is not.
+			if lf.Cardinality() == protoreflect.Repeated && rf.Cardinality() != protoreflect.Repeated {
+				// If the comparator is not HAS, this is an error.
+				// I.e. array_field:value
+				if x.Comparator.Type != ast.HAS {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						// Invalid value.
+						res.ErrPos = x.Arg.Position()
+						res.ErrMsg = fmt.Sprintf("the right hand side type of the restriction doesn't match the left hand side type: %s", x.Arg.String())
+					}
+					right.Expr.Free()
+					left.Free()
+					return res, ErrInvalidValue
+				}
+			}
+
+			// Check if the left hand side is neither a map key nor repeated and the operator is HAS.
+			if (!leftIsMapKey || lf.Cardinality() != protoreflect.Repeated) && x.Comparator.Type == ast.HAS {
+				// If the comparator is HAS and the left hand side is not a map key, this is an error.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
+					// Invalid value.
 					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
+					res.ErrMsg = "operator HAS (':') can only be used on map or repeated fields"
 				}
+				right.Expr.Free()
 				left.Free()
-				vt.Free()
 				return res, ErrInvalidValue
 			}
-		case *expr.FunctionCallExpr:
-			// The right hand side is a function call expression,
-			// Check if the returning value of the function call matches the left hand side.
-			vfn := b.functionCallDeclarations[vt.FullName()]
-			if fn.Returning.Kind() != vfn.Returning.Kind() {
+
+			// Check if the right hand side is repeated and the left is not.
+			if rf.Cardinality() == protoreflect.Repeated && lf.Cardinality() != protoreflect.Repeated && !lf.IsMap() {
+				// If the comparator is different from IN, this is an error.
+				if x.Comparator.Type != ast.IN {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						// Invalid value.
+						ctx.ErrHandler(x.Comparator.Position(), fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field: %s with a comparator: %s", rf.FullName(), selectorPath(left), x.Comparator.String()))
+						res.ErrPos = x.Comparator.Position()
+						res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a non-repeated field: %s with a comparator: %s", rf.FullName(), selectorPath(left), x.Comparator.String())
+					}
+					right.Expr.Free()
+					left.Free()
+					return res, ErrInvalidValue
+				}
+			}
+
+			// The selectors should be valid now.
+			ex := expr.AcquireCompareExpr()
+			ex.Left = field
+			ex.Comparator = cmp
+			ex.Right = right.Expr
+			return TryParseValueResult{Expr: ex, IsIndirect: true}, nil
+		case *ast.FunctionCall:
+			argFn, ok := b.getFunctionDeclaration(ctx, at)
+			if !ok {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Arg.Position()
-					res.ErrMsg = fmt.Sprintf("resulting function call type: %s does not match the left hand side type: %s", vfn.Returning.Kind().String(), fn.Returning.Kind().String())
+					res.ErrPos = at.Pos
+					res.ErrMsg = fmt.Sprintf("function: %s undefined", at.JoinedName())
 				}
 				left.Free()
-				vt.Free()
 				return res, ErrInvalidValue
 			}
 
-			if fn.Returning.Kind() == protoreflect.EnumKind && fn.Returning.Enum().FullName() != vfn.Returning.Enum().FullName() {
+			if argFn.ServiceCall() {
+				// This is not a valid value expression.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Arg.Position()
-					res.ErrMsg = fmt.Sprintf("resulting function call enum type: %s does not match the left hand side enum type: %s", vfn.Returning.Enum().FullName(), fn.Returning.Enum().FullName())
+					res.ErrPos = at.Pos
+					res.ErrMsg = fmt.Sprintf("function: %s can't be as a comparator argument", at.JoinedName())
 				}
 				left.Free()
-				vt.Free()
 				return res, ErrInvalidValue
-			}
+			} else {
+				// Try to match the kind of resulting value with the argument declaration.
+				rt := argFn.Returning
 
-			if fn.Returning.Kind() == protoreflect.MessageKind {
-				if (fn.Returning.IsMap() && !vfn.Returning.IsMap()) || (!fn.Returning.IsMap() && vfn.Returning.IsMap()) {
+				if rt.FieldKind != fd.Kind() {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.FieldKind)
 					}
 					left.Free()
-					vt.Free()
 					return res, ErrInvalidValue
 				}
-				if fn.Returning.IsMap() && (fn.Returning.MapKey().FullName() != vfn.Returning.MapKey().FullName() ||
-					fn.Returning.MapValue().FullName() != vfn.Returning.MapValue().FullName()) {
+
+				if rt.EnumDescriptor != nil && rt.EnumDescriptor.FullName() != fd.Enum().FullName() {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.EnumDescriptor.FullName())
 					}
 					left.Free()
-					vt.Free()
 					return res, ErrInvalidValue
 				}
 
-				if fn.Returning.IsMap() && cmp != expr.EQ && cmp != expr.NE {
-					var res TryParseValueResult
-					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Comparator.Position()
-						res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
+				if fd.Message() != nil && fd.IsMap() && !rt.IsMap() {
+					if cmp != expr.HAS {
+						var res TryParseValueResult
+						if ctx.ErrHandler != nil {
+							res.ErrPos = x.Position()
+							res.ErrMsg = fmt.Sprintf("function call %s does not return a map value", at.JoinedName())
+						}
+						left.Free()
+						return res, ErrInvalidValue
 					}
-					left.Free()
-					vt.Free()
-					return res, ErrInvalidValue
 				}
 
-				if !fn.Returning.IsMap() && fn.Returning.Message().FullName() != vfn.Returning.Message().FullName() {
+				if fd.Message() != nil && fd.Message().FullName() != rt.Message().FullName() {
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
-						res.ErrPos = x.Arg.Position()
-						res.ErrMsg = fmt.Sprintf("resulting function call message type: %s does not match the left hand side message type: %s", vfn.Returning.Message().FullName(), fn.Returning.Message().FullName())
+						res.ErrPos = x.Position()
+						res.ErrMsg = fmt.Sprintf("function call %s is not of type %s", at.JoinedName(), rt.Message().FullName())
 					}
 					left.Free()
-					vt.Free()
 					return res, ErrInvalidValue
 				}
-			}
-		case *expr.StringSearchExpr:
-			// The right hand side is a string search expression,
-			// The comparator needs to be EQ or IN.
-			if cmp != expr.EQ && cmp != expr.IN {
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a string search expression with a comparator: %s", x.Comparator.String())
+
+				if fd.Cardinality() == protoreflect.Repeated && rt.Cardinality() != protoreflect.Repeated {
+					if cmp != expr.IN {
+						var res TryParseValueResult
+						if ctx.ErrHandler != nil {
+							res.ErrPos = x.Position()
+							res.ErrMsg = fmt.Sprintf("function call %s is repeated", at.JoinedName())
+						}
+						left.Free()
+						return res, ErrInvalidValue
+					}
 				}
+			}
+
+			// Call right hand side.
+			rfn, err := b.tryParseAndCallFunction(ctx, at, argFn, true)
+			if err != nil {
 				left.Free()
-				vt.Free()
-				return res, ErrInvalidValue
+				return rfn, err
 			}
 
-			// If the left hand side is repeated field than it is an error.
-			if fn.Returning.Cardinality() == protoreflect.Repeated {
+			ce := expr.AcquireCompareExpr()
+			ce.Left = left
+			ce.Comparator = cmp
+			ce.Right = rfn.Expr
+			return TryParseValueResult{Expr: ce, IsIndirect: rfn.IsIndirect}, nil
+		default:
+			// The right hand side is not a selector expression.
+			// Thus return an error.
+			left.Free()
+			return ve, err
+		}
+	}
+
+	// The right hand side is a value expression.
+	// Check if the operator matches the expression type.
+	switch vt := ve.Expr.(type) {
+	case *expr.ValueExpr:
+		// The right hand side is a value expression,
+		// if the left hand side is a map key or a repeated field, the operator must be HAS.
+		if (mk != nil || fd.Cardinality() == protoreflect.Repeated) && cmp != expr.HAS {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", selectorPath(left), x.Comparator.String())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+		// The HAS comparison of a singular string field could be a substring match.
+		if sv, ok := vt.Value.(string); ok && cmp == expr.HAS && mk == nil && b.isSubstringHas(fd) {
+			if fi.NoTextSearch {
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = x.Comparator.Position()
-					res.ErrMsg = fmt.Sprintf("cannot compare a repeated function result with a comparator: %s for string search", x.Comparator.String())
+					res.ErrMsg = fmt.Sprintf("cannot compare a field: %s with a string search expression", fd.FullName())
 				}
 				left.Free()
 				vt.Free()
 				return res, ErrInvalidValue
 			}
-
-		default:
-			// The right hand side is not a value expression.
-			// Thus return an error.
+			ss := expr.AcquireStringSearchExpr()
+			ss.Value = sv
+			ss.Pattern = sv
+			ss.PrefixWildcard = true
+			ss.SuffixWildcard = true
+			ss.SearchComplexity = fi.Complexity
+			vt.Free()
+			ve.Expr = ss
+			cmp = expr.EQ
+		}
+	// The right hand side is a proper value expression.
+	case *expr.ArrayExpr:
+		// The right hand side is an array expression,
+		// check if the left hand side is either a repeated field or a
+		// single field with IN comparator.
+		if fd.Cardinality() != protoreflect.Repeated && cmp != expr.IN {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", selectorPath(left), x.Comparator.String())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+	case *expr.MapValueExpr:
+		// The right hand side is a map value expression,
+		// The left hand side must be a map field (NOT a map key).
+		if !fd.IsMap() || mk != nil {
 			var res TryParseValueResult
 			if ctx.ErrHandler != nil {
-				// This is invalid  value error ?
 				res.ErrPos = x.Arg.Position()
-				res.ErrMsg = "invalid value for restriction expression"
+				res.ErrMsg = fmt.Sprintf("cannot compare a map with a non map field: %s", selectorPath(left))
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+		// Comparator can only accept EQ or NEQ.
+		if cmp != expr.EQ && cmp != expr.NE {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a map with a comparator: %s", x.Comparator.String())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+	case *expr.StringSearchExpr:
+		// The right hand side is a string search expression,
+		// The comparator needs to be EQ or IN.
+		if cmp != expr.EQ && cmp != expr.IN {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a string search expression with a comparator: %s", x.Comparator.String())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+
+		// If the left hand side is repeated field than it is an error.
+		if fd.Cardinality() == protoreflect.Repeated {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", fd.FullName(), x.Comparator.String())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+
+		if fi.NoTextSearch {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a field: %s with a string search expression", fd.FullName())
+			}
+			left.Free()
+			vt.Free()
+			return res, ErrInvalidValue
+		}
+	case *expr.FunctionCallExpr:
+		// The right hand side is a function call, which returning type was verified while parsing the value.
+		if fd.Cardinality() == protoreflect.Repeated && cmp != expr.HAS {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = x.Comparator.Position()
+				res.ErrMsg = fmt.Sprintf("cannot compare a repeated field: %s with a comparator: %s", fd.FullName(), x.Comparator.String())
 			}
 			left.Free()
 			vt.Free()
 			return res, ErrInvalidValue
 		}
 
-		// The selectors should be valid now.
-		ex := expr.AcquireCompareExpr()
-		ex.Left = left
-		ex.Comparator = cmp
-		ex.Right = ve.Expr
-		return TryParseValueResult{Expr: ex, IsIndirect: ve.IsIndirect}, nil
 	default:
-		// The left hand side is not a selector expression.
-		// This is invalid value error.
+		// The right hand side is not a value expression.
+		// Thus return an error.
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
-			res.ErrPos = xt.Position()
-			res.ErrMsg = fmt.Sprintf("the left hand side is not a valid selector: %s", xt.String())
+			res.ErrPos = x.Arg.Position()
+			res.ErrMsg = fmt.Sprintf("the right hand side is not a valid value type: %T", ve.Expr)
 		}
-		return res, ErrInvalidValue
+		left.Free()
+		vt.Free()
+		return res, ErrInternal
 	}
+
+	ce := expr.AcquireCompareExpr()
+	ce.Left = left
+	ce.Comparator = cmp
+	ce.Right = ve.Expr
+	return TryParseValueResult{Expr: ce, IsIndirect: true}, nil
 }
 
 func parseComparator(in *ast.ComparatorLiteral) (expr.Comparator, bool) {
@@ -1050,6 +1151,12 @@ func (b *Interpreter) traverseLastFieldExpr(in expr.FilterExpr) (*expr.FieldSele
 		switch xt := e.(type) {
 		case *expr.FieldSelectorExpr:
 			fe = xt
+			if xt.Message != md.FullName() {
+				// The field of the message unpacked from the google.protobuf.Any field.
+				if amd := b.findAnyType(xt.Message); amd != nil {
+					md = amd
+				}
+			}
 			fd = md.Fields().ByName(xt.Field)
 			if fd == nil {
 				for i := 0; i < md.Oneofs().Len(); i++ {
@@ -1059,6 +1166,9 @@ func (b *Interpreter) traverseLastFieldExpr(in expr.FilterExpr) (*expr.FieldSele
 						break
 					}
 				}
+				if fd == nil {
+					fd = b.findExtensionField(md, string(xt.Field))
+				}
 				if fd == nil {
 					panic(fmt.Sprintf("field: %s not found in message: %s", xt.Field, md.FullName()))
 				}
@@ -1073,6 +1183,8 @@ func (b *Interpreter) traverseLastFieldExpr(in expr.FilterExpr) (*expr.FieldSele
 				return fe, xt, fd, true
 			}
 			mk = xt
+			// The map key traversal selects the fields of the map value message.
+			md = fd.MapValue().Message()
 			e = xt.Traversal.(expr.FilterExpr)
 		default:
 			return fe, mk, fd, true
@@ -1101,3 +1213,18 @@ func countTraversal(in expr.FilterExpr) int {
 		count++
 	}
 }
+
+// isValueLiteral checks if the member expression is a literal, which is never a field selector,
+// i.e. the null keyword, the b prefixed bytes literal, or the quoted string of the bytes and UUID fields.
+func (b *Interpreter) isValueLiteral(fd protoreflect.FieldDescriptor, x *ast.MemberExpr) bool {
+	switch {
+	case isNullLiteral(x), isPrefixedBytesLiteral(x):
+		return true
+	case fd.Kind() == protoreflect.BytesKind:
+		return isBytesLiteral(x)
+	case b.isUUIDField(fd):
+		_, ok := x.Value.(*ast.StringLiteral)
+		return ok && len(x.Fields) == 0
+	}
+	return false
+}
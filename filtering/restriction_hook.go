@@ -0,0 +1,73 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+)
+
+// RestrictionHookFn is a function called with each completed restriction expression, before it is attached
+// to the filter expression tree, i.e. the expr.CompareExpr of `name = "foo"` or the expr.PresenceExpr of `sub:*`.
+// It returns the expression to attach, which is either the x itself, possibly modified in place, or its replacement.
+// The hook takes over the ownership of the x, thus a hook that replaces it needs to free it or embed it
+// in the replacement, i.e. in an expr.AndExpr with a tenant scope.
+// A returned error vetoes the restriction and fails the parsing, in which case the interpreter frees the x
+// and ignores the returned expression.
+// The error should wrap one of the standard errors, i.e. ErrUnsupported or ErrInvalidField.
+type RestrictionHookFn func(ctx *ParseContext, x expr.FilterExpr) (expr.FilterExpr, error)
+
+// RestrictionHookOpt is an option that registers a function called with each restriction expression,
+// which could veto, rewrite or annotate it, i.e. to block the comparisons of the sensitive fields,
+// or to collect the telemetry of the compared fields.
+// Multiple hooks are chained in the registration order, each receiving the result of the previous one.
+// The hooks are called synchronously within the Parse, from each goroutine that uses the Interpreter.
+func RestrictionHookOpt(fn RestrictionHookFn) Option {
+	return func(i *Interpreter) error {
+		if fn == nil {
+			return errors.New("restriction hook function is nil")
+		}
+		i.restrictionHooks = append(i.restrictionHooks, fn)
+		return nil
+	}
+}
+
+// callRestrictionHooks passes the restriction expression of the res through the registered restriction hooks.
+func (b *Interpreter) callRestrictionHooks(ctx *ParseContext, x *ast.RestrictionExpr, res TryParseValueResult) (TryParseValueResult, error) {
+	for _, fn := range b.restrictionHooks {
+		rx, err := fn(ctx, res.Expr)
+		var msg string
+		switch {
+		case err != nil:
+			msg = err.Error()
+		case rx == nil:
+			msg, err = "restriction hook returned no expression", ErrInternal
+		default:
+			res.Expr = rx
+			continue
+		}
+
+		res.Expr.Free()
+		var out TryParseValueResult
+		if ctx.ErrHandler != nil {
+			out.ErrPos = x.Position()
+			out.ErrMsg = msg
+		}
+		return out, err
+	}
+	return res, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestRestrictionHookOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	// blockField vetoes the comparisons of the str field.
+	blockField := func(_ *ParseContext, x expr.FilterExpr) (expr.FilterExpr, error) {
+		if ce, ok := x.(*expr.CompareExpr); ok {
+			if fs, ok := ce.Left.(*expr.FieldSelectorExpr); ok && fs.Field == "str" {
+				return nil, fmt.Errorf("%w: field str is not filterable", ErrInvalidField)
+			}
+		}
+		return x, nil
+	}
+	// scope adds the tenant scope to each restriction.
+	scope := func(_ *ParseContext, x expr.FilterExpr) (expr.FilterExpr, error) {
+		var c expr.Composer
+		c.Reset(md)
+		return expr.And(x, c.Compare(c.MustSelect("name"), expr.EQ, c.Value("tenant"))), nil
+	}
+
+	tc := []struct {
+		name   string
+		hooks  []RestrictionHookFn
+		filter string
+		want   string
+		err    error
+	}{
+		{name: "pass", hooks: []RestrictionHookFn{blockField}, filter: `i32 = 1`, want: `i32 = 1`},
+		{name: "veto", hooks: []RestrictionHookFn{blockField}, filter: `i32 = 1 AND str = "foo"`, err: ErrInvalidField},
+		{name: "rewrite", hooks: []RestrictionHookFn{scope}, filter: `i32 = 1`, want: `i32 = 1 AND name = "tenant"`},
+		{name: "chain veto first", hooks: []RestrictionHookFn{blockField, scope}, filter: `str = "foo"`, err: ErrInvalidField},
+		{
+			name:   "no expression",
+			hooks:  []RestrictionHookFn{func(*ParseContext, expr.FilterExpr) (expr.FilterExpr, error) { return nil, nil }},
+			filter: `i32 = 1`,
+			err:    ErrInternal,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			for _, h := range tt.hooks {
+				opts = append(opts, RestrictionHookOpt(h))
+			}
+			i, err := NewInterpreter(md, opts...)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to unparse: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := NewInterpreter(md, RestrictionHookOpt(nil)); err == nil {
+		t.Error("expected error for nil hook")
+	}
+}
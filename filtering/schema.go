@@ -0,0 +1,223 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// FilterSchema describes the fields and operators accepted by the interpreter.
+// It is derived from the message descriptors, blocky annotations and the interpreter options,
+// so that the services can publish it to the clients, i.e. as JSON to build filter forms.
+type FilterSchema struct {
+	// Message is the full name of the interpreter message.
+	Message string `json:"message"`
+	// Fields are the field paths in the descriptor order, with the nested fields following their parents.
+	Fields []FieldSchema `json:"fields"`
+	// Functions are the full names of the registered functions, sorted.
+	Functions []string `json:"functions,omitempty"`
+}
+
+// Field returns the schema of the field at given path.
+func (s FilterSchema) Field(path string) (FieldSchema, bool) {
+	for _, f := range s.Fields {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+// FieldSchema describes a single field path of the FilterSchema.
+type FieldSchema struct {
+	// Path is the dot separated field path, composed of the names used by the interpreter selectors.
+	Path string `json:"path"`
+	// Aliases are the alternative names of the last field of the path, see FieldAliasesOpt.
+	Aliases []string `json:"aliases,omitempty"`
+	// Kind is the protobuf kind of the field, or one of 'timestamp', 'duration' and 'struct'
+	// for the well-known types handled by the interpreter. The kind of the map field is the kind of its values.
+	Kind string `json:"kind"`
+	// Type is the full name of the enum or message type of the field, or of the map field values.
+	Type string `json:"type,omitempty"`
+	// Repeated is true for the repeated fields.
+	Repeated bool `json:"repeated,omitempty"`
+	// Map is true for the map fields.
+	Map bool `json:"map,omitempty"`
+	// Nullable is true if the field can be compared with null.
+	Nullable bool `json:"nullable,omitempty"`
+	// CaseInsensitive is true if the string comparisons of the field are case-insensitive.
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+	// Filterable is false if the filtering by the field is forbidden.
+	Filterable bool `json:"filterable"`
+	// Sortable is true if the field can be used in the order by expression.
+	Sortable bool `json:"sortable"`
+	// Comparators are the comparators allowed for the field, empty if the field is not filterable.
+	Comparators []string `json:"comparators,omitempty"`
+	// EnumValues are the names of the enum values of the field.
+	EnumValues []string `json:"enum_values,omitempty"`
+}
+
+// Schema returns the FilterSchema of the interpreter message.
+// The message fields which recursively refer to the message types of their parents are listed,
+// but are not traversed any further.
+func (b *Interpreter) Schema() FilterSchema {
+	s := FilterSchema{Message: string(b.msg.FullName())}
+	b.schemaFields(&s, b.msg, "", map[protoreflect.FullName]struct{}{b.msg.FullName(): {}})
+
+	for name := range b.functionCallDeclarations {
+		s.Functions = append(s.Functions, name)
+	}
+	sort.Strings(s.Functions)
+	return s
+}
+
+func (b *Interpreter) schemaFields(s *FilterSchema, md protoreflect.MessageDescriptor, prefix string, visited map[protoreflect.FullName]struct{}) {
+	mi := b.msgInfo.MessageInfo(md)
+	if mi == nil {
+		return
+	}
+	for _, fi := range mi.Fields {
+		fd := fi.Desc
+		vd := fd
+		if fd.IsMap() {
+			vd = fd.MapValue()
+		}
+		fs := FieldSchema{
+			Path:       prefix + b.schemaFieldName(fd),
+			Aliases:    b.fieldAliasesOf(fd),
+			Kind:       schemaKind(vd),
+			Repeated:   fd.IsList(),
+			Map:        fd.IsMap(),
+			Nullable:   fi.Nullable,
+			Filterable: !fi.FilteringForbidden,
+			Sortable:   !fi.OrderingForbidden && isSortable(fi),
+		}
+		switch {
+		case vd.Enum() != nil:
+			fs.Type = string(vd.Enum().FullName())
+			values := vd.Enum().Values()
+			for i := 0; i < values.Len(); i++ {
+				fs.EnumValues = append(fs.EnumValues, string(values.Get(i).Name()))
+			}
+		case vd.Message() != nil:
+			fs.Type = string(vd.Message().FullName())
+		}
+		if fs.Filterable {
+			fs.Comparators = b.schemaComparators(fi)
+			fs.CaseInsensitive = fd.Kind() == protoreflect.StringKind && b.isCaseInsensitiveField(fd)
+		}
+		s.Fields = append(s.Fields, fs)
+
+		if !isTraversable(fi) {
+			continue
+		}
+		name := fd.Message().FullName()
+		if _, ok := visited[name]; ok {
+			continue
+		}
+		visited[name] = struct{}{}
+		b.schemaFields(s, fd.Message(), fs.Path+".", visited)
+		delete(visited, name)
+	}
+}
+
+// schemaFieldName returns the name of the field, of the first selector name kind it has.
+func (b *Interpreter) schemaFieldName(fd protoreflect.FieldDescriptor) string {
+	for _, kind := range b.selectorNames {
+		if name := b.selectorName(fd, kind); name != "" {
+			return name
+		}
+	}
+	return string(fd.Name())
+}
+
+// schemaComparators returns the comparators allowed for the field.
+func (b *Interpreter) schemaComparators(fi info.FieldInfo) []string {
+	fd := fi.Desc
+	if fd.IsList() || fd.IsMap() {
+		return []string{":"}
+	}
+	switch {
+	case fi.IsTimestamp, fi.IsDuration:
+		return []string{"=", "!=", "<", "<=", ">", ">="}
+	case fi.IsStructpb:
+		return []string{"=", "!=", ":"}
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind, protoreflect.BytesKind:
+		return []string{"=", "!="}
+	case protoreflect.EnumKind:
+		return []string{"=", "!=", "IN"}
+	case protoreflect.StringKind:
+		cmps := []string{"=", "!=", "<", "<=", ">", ">=", ":", "IN"}
+		if b.regexMatch {
+			cmps = append(cmps, "=~")
+		}
+		return cmps
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return []string{"=", "!=", ":"}
+	default:
+		return []string{"=", "!=", "<", "<=", ">", ">=", "IN"}
+	}
+}
+
+// isCaseInsensitiveField checks if the comparisons of the string field are case-insensitive.
+func (b *Interpreter) isCaseInsensitiveField(fd protoreflect.FieldDescriptor) bool {
+	if b.caseInsensitive {
+		return true
+	}
+	_, ok := b.caseInsensitiveFields[fd.FullName()]
+	return ok
+}
+
+// schemaKind returns the FieldSchema kind of the field.
+func schemaKind(fd protoreflect.FieldDescriptor) string {
+	if fd.Kind() == protoreflect.MessageKind {
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return "timestamp"
+		case "google.protobuf.Duration":
+			return "duration"
+		case "google.protobuf.Struct":
+			return "struct"
+		}
+	}
+	return fd.Kind().String()
+}
+
+// isSortable checks if the field holds a single value that can be ordered.
+func isSortable(fi info.FieldInfo) bool {
+	fd := fi.Desc
+	if fd.IsList() || fd.IsMap() {
+		return false
+	}
+	if fd.Message() != nil {
+		return fi.IsTimestamp || fi.IsDuration
+	}
+	return true
+}
+
+// isTraversable checks if the nested fields of the field can be selected.
+func isTraversable(fi info.FieldInfo) bool {
+	fd := fi.Desc
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() || fi.NonTraversal {
+		return false
+	}
+	return !fi.IsTimestamp && !fi.IsDuration && !fi.IsStructpb
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_Schema(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, RegexMatchOpt(), CaseInsensitiveFieldsOpt("testpb.Message.str"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	s := i.Schema()
+	if s.Message != "testpb.Message" {
+		t.Errorf("expected message testpb.Message but got %s", s.Message)
+	}
+
+	tc := []struct {
+		path string
+		want FieldSchema
+	}{
+		{
+			path: "str",
+			want: FieldSchema{
+				Path: "str", Kind: "string", CaseInsensitive: true, Filterable: true, Sortable: true,
+				Comparators: []string{"=", "!=", "<", "<=", ">", ">=", ":", "IN", "=~"},
+			},
+		},
+		{
+			path: "enum",
+			want: FieldSchema{
+				Path: "enum", Kind: "enum", Type: "testpb.Enum", Filterable: true, Sortable: true,
+				Comparators: []string{"=", "!=", "IN"},
+				EnumValues:  []string{"UNKNOWN", "ONE", "TWO", "THREE"},
+			},
+		},
+		{
+			path: "rp_i32",
+			want: FieldSchema{Path: "rp_i32", Kind: "int32", Repeated: true, Filterable: true, Comparators: []string{":"}},
+		},
+		{
+			path: "timestamp",
+			want: FieldSchema{
+				Path: "timestamp", Kind: "timestamp", Type: "google.protobuf.Timestamp", Filterable: true, Sortable: true,
+				Comparators: []string{"=", "!=", "<", "<=", ">", ">="},
+			},
+		},
+		{
+			path: "map_str_i32",
+			want: FieldSchema{Path: "map_str_i32", Kind: "int32", Map: true, Filterable: true, Comparators: []string{":"}},
+		},
+		{
+			path: "no_filter",
+			want: FieldSchema{Path: "no_filter", Kind: "string"},
+		},
+		{
+			path: "sub",
+			want: FieldSchema{Path: "sub", Kind: "message", Type: "testpb.Message", Filterable: true, Comparators: []string{"=", "!=", ":"}},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := s.Field(tt.path)
+			if !ok {
+				t.Fatalf("field %s not found", tt.path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v but got %+v", tt.want, got)
+			}
+		})
+	}
+
+	// The recursive message field is listed, but not traversed.
+	if _, ok := s.Field("sub.name"); ok {
+		t.Errorf("expected recursive field sub not to be traversed")
+	}
+
+	if _, err = json.Marshal(s); err != nil {
+		t.Errorf("failed to marshal schema: %v", err)
+	}
+}
+
+func TestInterpreter_Schema_SelectorNames(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md,
+		SelectorNamesOpt(JSONSelectorName, ProtoSelectorName),
+		FieldAliasesOpt(map[string]protoreflect.FullName{"strings": "testpb.Message.rp_str"}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	s := i.Schema()
+	if fs, ok := s.Field("rpStr"); !ok {
+		t.Errorf("expected field rpStr to be listed with its json name")
+	} else if len(fs.Aliases) != 1 || fs.Aliases[0] != "strings" {
+		t.Errorf("expected field rpStr aliases [strings] but got %v", fs.Aliases)
+	}
+	if _, ok := s.Field("rp_str"); ok {
+		t.Errorf("expected field rp_str not to be listed with its proto name")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// searchField is a searchable field resolved from its path.
+type searchField struct {
+	path   string
+	fields []protoreflect.FieldDescriptor
+}
+
+// SearchableFieldsOpt is an option that sets the fields matched by the free-text search query of the ParseWithSearch.
+// The fields are the dot separated paths of the string or repeated string fields relative to the interpreter message,
+// i.e.: "display_name" or "author.name", where each but the last field is a singular message field.
+func SearchableFieldsOpt(paths ...string) Option {
+	return func(i *Interpreter) error {
+		for _, path := range paths {
+			sf, err := i.resolveSearchField(path)
+			if err != nil {
+				return err
+			}
+			i.searchFields = append(i.searchFields, sf)
+		}
+		return nil
+	}
+}
+
+func (b *Interpreter) resolveSearchField(path string) (searchField, error) {
+	sf := searchField{path: path}
+	md := b.msg
+	for _, name := range strings.Split(path, ".") {
+		if md == nil {
+			return sf, fmt.Errorf("searchable field %q: %q is not a message field", path, sf.fields[len(sf.fields)-1].Name())
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return sf, fmt.Errorf("searchable field %q: field %q not found in %s", path, name, md.FullName())
+		}
+		sf.fields = append(sf.fields, fd)
+		md = nil
+		if fd.Kind() == protoreflect.MessageKind && fd.Cardinality() != protoreflect.Repeated {
+			md = fd.Message()
+		}
+	}
+	if last := sf.fields[len(sf.fields)-1]; last.Kind() != protoreflect.StringKind || last.IsMap() {
+		return sf, fmt.Errorf("searchable field %q is not a string field", path)
+	}
+	return sf, nil
+}
+
+// ParseWithSearch parses the filter and the free-text search query, and combines them into a single expression.
+// The search query is split into the terms by the whitespace, where the double-quoted phrases are kept together,
+// and results in the expr.SearchExpr, which matches the resources containing all the terms in any of the
+// searchable fields, regardless of the case. The searchable fields are set with the SearchableFieldsOpt.
+//
+// If both the filter and the query are set, the result is an expr.AndExpr of the filter expression
+// and the expr.SearchExpr, in that order, so that the backends could handle the search sub-tree differently.
+// Otherwise, the result is the expression of the one which is set, or nil if none.
+// The limits of the interpreter apply to the query and to the combined expression.
+// The parse options apply to the filter only.
+func (b *Interpreter) ParseWithSearch(filter, query string, opts ...ParseOption) (expr.FilterExpr, error) {
+	fx, err := b.Parse(filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sx, err := b.parseSearch(query)
+	if err != nil {
+		if fx != nil {
+			fx.Free()
+		}
+		return nil, err
+	}
+
+	switch {
+	case sx == nil:
+		return fx, nil
+	case expr.IsMatchAll(fx):
+		expr.FreeAll(fx)
+		return sx, nil
+	}
+
+	and := expr.AcquireAndExpr()
+	and.Expr = append(and.Expr, fx, sx)
+	if b.maxComplexity > 0 {
+		if c := and.Complexity(); c > b.maxComplexity {
+			and.Free()
+			return nil, b.limitExceeded(query, 0, fmt.Sprintf("filter and search complexity %d exceeds the limit of %d", c, b.maxComplexity))
+		}
+	}
+	return and, nil
+}
+
+func (b *Interpreter) parseSearch(query string) (*expr.SearchExpr, error) {
+	if b.maxFilterLength > 0 && len(query) > b.maxFilterLength {
+		return nil, b.limitExceeded(query, token.Position(b.maxFilterLength), fmt.Sprintf("search query length exceeds the limit of %d", b.maxFilterLength))
+	}
+
+	terms, pos, ok := splitSearchTerms(query)
+	if !ok {
+		return nil, b.invalidSearch(query, pos, "unterminated quoted phrase in the search query")
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if len(b.searchFields) == 0 {
+		return nil, b.invalidSearch(query, 0, "search query is not supported")
+	}
+
+	sx := expr.AcquireSearchExpr()
+	sx.Query = query
+	sx.Terms = append(sx.Terms, terms...)
+	for _, sf := range b.searchFields {
+		sx.Fields = append(sx.Fields, sf.path)
+	}
+
+	var and *expr.AndExpr
+	if len(terms) > 1 {
+		and = expr.AcquireAndExpr()
+		sx.Expr = and
+	}
+	for _, term := range terms {
+		tx := b.searchTermExpr(term)
+		if and == nil {
+			sx.Expr = tx
+			break
+		}
+		and.Expr = append(and.Expr, tx)
+	}
+
+	if b.maxComplexity > 0 {
+		if c := sx.Complexity(); c > b.maxComplexity {
+			sx.Free()
+			return nil, b.limitExceeded(query, 0, fmt.Sprintf("search complexity %d exceeds the limit of %d", c, b.maxComplexity))
+		}
+	}
+	return sx, nil
+}
+
+// searchTermExpr returns the expression matching the term in any of the searchable fields.
+func (b *Interpreter) searchTermExpr(term string) expr.FilterExpr {
+	var or *expr.OrExpr
+	if len(b.searchFields) > 1 {
+		or = expr.AcquireOrExpr()
+	}
+	for _, sf := range b.searchFields {
+		var (
+			head, tail *expr.FieldSelectorExpr
+			fc         int64
+		)
+		for _, fd := range sf.fields {
+			fc = b.msgInfo.GetFieldInfo(fd).Complexity
+			fs := expr.AcquireFieldSelectorExpr()
+			fs.Message = fd.ContainingMessage().FullName()
+			fs.Field = fd.Name()
+			fs.FieldComplexity = fc
+			if head == nil {
+				head = fs
+			} else {
+				tail.Traversal = fs
+			}
+			tail = fs
+		}
+
+		ss := expr.AcquireStringSearchExpr()
+		ss.Value = term
+		ss.Pattern = term
+		ss.PrefixWildcard = true
+		ss.SuffixWildcard = true
+		ss.SearchComplexity = fc
+		ss.CaseInsensitive = true
+
+		ce := expr.AcquireCompareExpr()
+		ce.Left = head
+		ce.Comparator = expr.EQ
+		if sf.fields[len(sf.fields)-1].IsList() {
+			ce.Comparator = expr.HAS
+		}
+		ce.Right = ss
+		ce.CaseInsensitive = true
+
+		if or == nil {
+			return ce
+		}
+		or.Expr = append(or.Expr, ce)
+	}
+	return or
+}
+
+func (b *Interpreter) invalidSearch(query string, pos token.Position, msg string) error {
+	if b.errHandlerFn != nil {
+		b.errHandlerFn(pos, msg)
+	}
+	return newFilterError(query, pos, msg, ErrInvalidValue)
+}
+
+// splitSearchTerms splits the search query into the terms by the whitespace, keeping the double-quoted phrases together.
+// If the query has an unterminated phrase, it returns false along with the position of the opening quote.
+func splitSearchTerms(query string) ([]string, token.Position, bool) {
+	var terms []string
+	for i := 0; i < len(query); {
+		r := rune(query[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, token.Position(i), false
+			}
+			if phrase := strings.TrimSpace(query[i+1 : i+1+end]); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			i += end + 2
+		default:
+			end := strings.IndexFunc(query[i:], func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(query) - i
+			}
+			terms = append(terms, query[i:i+end])
+			i += end
+		}
+	}
+	return terms, 0, true
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_ParseWithSearch(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, SearchableFieldsOpt("name", "sub.str", "rp_str"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		x, err := i.ParseWithSearch("", "  ")
+		if err != nil || x != nil {
+			t.Errorf("expected nil expression, got %v, %v", x, err)
+		}
+	})
+
+	t.Run("filter only", func(t *testing.T) {
+		x, err := i.ParseWithSearch(`i32 = 1`, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+		if _, ok := x.(*expr.CompareExpr); !ok {
+			t.Errorf("expected CompareExpr but got %T", x)
+		}
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		x, err := i.ParseWithSearch(`i32 = 1`, `foo "bar baz"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		and, ok := x.(*expr.AndExpr)
+		if !ok || len(and.Expr) != 2 {
+			t.Fatalf("expected AndExpr of filter and search but got %T", x)
+		}
+		sx, ok := and.Expr[1].(*expr.SearchExpr)
+		if !ok {
+			t.Fatalf("expected SearchExpr but got %T", and.Expr[1])
+		}
+		if want := []string{"foo", "bar baz"}; !reflect.DeepEqual(sx.Terms, want) {
+			t.Errorf("expected terms %v but got %v", want, sx.Terms)
+		}
+		if want := []string{"name", "sub.str", "rp_str"}; !reflect.DeepEqual(sx.Fields, want) {
+			t.Errorf("expected fields %v but got %v", want, sx.Fields)
+		}
+
+		terms, ok := sx.Expr.(*expr.AndExpr)
+		if !ok || len(terms.Expr) != 2 {
+			t.Fatalf("expected AndExpr of terms but got %T", sx.Expr)
+		}
+		or, ok := terms.Expr[1].(*expr.OrExpr)
+		if !ok || len(or.Expr) != 3 {
+			t.Fatalf("expected OrExpr of fields but got %T", terms.Expr[1])
+		}
+		nested := or.Expr[1].(*expr.CompareExpr)
+		if fs := nested.Left.(*expr.FieldSelectorExpr); fs.Field != "sub" || fs.Traversal.(*expr.FieldSelectorExpr).Field != "str" {
+			t.Errorf("expected sub.str selector")
+		}
+		ss := nested.Right.(*expr.StringSearchExpr)
+		if ss.Value != "bar baz" || !ss.PrefixWildcard || !ss.SuffixWildcard || !ss.CaseInsensitive || !nested.CaseInsensitive {
+			t.Errorf("unexpected string search: %+v", ss)
+		}
+		if c := or.Expr[2].(*expr.CompareExpr).Comparator; c != expr.HAS {
+			t.Errorf("expected HAS comparator for the repeated field, got %s", c)
+		}
+
+		clone := x.Clone()
+		defer clone.Free()
+		if !x.Equals(clone) {
+			t.Errorf("expected clone to be equal")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := i.ParseWithSearch("", `"foo`); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("expected invalid value error for unterminated phrase, got %v", err)
+		}
+		if _, err := i.ParseWithSearch(`unknown = 1`, "foo"); err == nil {
+			t.Errorf("expected filter error")
+		}
+
+		plain, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = plain.ParseWithSearch("", "foo"); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("expected invalid value error without searchable fields, got %v", err)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		limited, err := NewInterpreter(md, SearchableFieldsOpt("name"), MaxComplexityOpt(10))
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		if _, err = limited.ParseWithSearch(`i32 = 1`, "a b c"); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected limit exceeded error, got %v", err)
+		}
+	})
+}
+
+func TestSearchableFieldsOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	for _, path := range []string{"unknown", "i32", "sub", "rp_sub.name", "map_str_str", "name.sub"} {
+		if _, err := NewInterpreter(md, SearchableFieldsOpt(path)); err == nil {
+			t.Errorf("expected error for the searchable field %q", path)
+		}
+	}
+}
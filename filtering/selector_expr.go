@@ -17,13 +17,13 @@ package filtering
 import (
 	"fmt"
 
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	blockyannotations "github.com/blockysource/go-genproto/blocky/api/annotations"
 
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/internal/info"
 )
 
 // FieldDescriptor is an interface that describes a field.
@@ -56,7 +56,16 @@ type FieldDescriptor interface {
 }
 
 // TryParseSelectorExpr handles an ast.MemberExpr and returns an expression.
+// The resolved selector is checked against the field restrictions of the parse options.
 func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExpr, args ...ast.FieldExpr) (TryParseValueResult, error) {
+	res, err := b.tryParseSelectorExpr(ctx, value, args...)
+	if err != nil || ctx.fieldAccess == nil || res.Expr == nil {
+		return res, err
+	}
+	return b.checkFieldAccess(ctx, value.Position(), res)
+}
+
+func (b *Interpreter) tryParseSelectorExpr(ctx *ParseContext, value ast.ValueExpr, args ...ast.FieldExpr) (TryParseValueResult, error) {
 	// Check if the named expression is a MemberExpr.
 	var field protoreflect.FieldDescriptor
 	switch vt := value.(type) {
@@ -70,25 +79,27 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 		return res, ErrInvalidField
 	case *ast.TextLiteral:
 		// The text value should match the field name of the context message descriptor.
-		field = ctx.Message.Fields().ByName(protoreflect.Name(vt.Value))
+		var err error
+		field, err = b.findFieldByName(ctx.Message, vt.Value)
+		if err != nil {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = vt.Pos
+				res.ErrMsg = err.Error()
+			}
+			return res, ErrAmbiguousField
+		}
 		if field == nil {
-			// Check if the field might be in the OneOf descriptors.
-			for i := 0; i < ctx.Message.Oneofs().Len(); i++ {
-				ood := ctx.Message.Oneofs().Get(i)
-				field = ood.Fields().ByName(protoreflect.Name(vt.Value))
-				if field != nil {
-					break
-				}
+			if b.traceFn != nil {
+				b.trace(TraceFieldNotFound, vt.Pos, ctx.Message.FullName(), vt.Value, nil, "no field or oneof field with given name")
 			}
-			if field == nil {
-				// No field found with the given name, return error
-				var res TryParseValueResult
-				if ctx.ErrHandler != nil {
-					res.ErrPos = vt.Pos
-					res.ErrMsg = fmt.Sprintf("field: %s not found in the message: %s", vt.Value, ctx.Message.Name())
-				}
-				return res, ErrFieldNotFound
+			// No field found with the given name, return error
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = vt.Pos
+				res.ErrMsg = fmt.Sprintf("field: %s not found in the message: %s", vt.Value, ctx.Message.Name())
 			}
+			return res, ErrFieldNotFound
 		}
 
 	default:
@@ -112,8 +123,14 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 	}
 
 	fi := b.msgInfo.GetFieldInfo(field)
+	if b.traceFn != nil {
+		b.traceFieldResolved(value.Position(), ctx.Message.FullName(), string(field.Name()), field, fi)
+	}
 
 	if fi.FilteringForbidden {
+		if b.traceFn != nil {
+			b.trace(TraceAnnotation, value.Position(), ctx.Message.FullName(), string(field.Name()), field, "field is annotated with FORBID_FILTERING")
+		}
 		// Cannot traverse through fields that forbid filtering.
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
@@ -124,6 +141,9 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 	}
 
 	if fi.InputOnly {
+		if b.traceFn != nil {
+			b.trace(TraceAnnotation, value.Position(), ctx.Message.FullName(), string(field.Name()), field, "field is annotated with INPUT_ONLY field behavior")
+		}
 		// Cannot traverse through input only fields.
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
@@ -144,6 +164,9 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 	}
 
 	if fi.NonTraversal {
+		if b.traceFn != nil {
+			b.trace(TraceAnnotation, value.Position(), ctx.Message.FullName(), string(field.Name()), field, "field is annotated with NON_TRAVERSAL, cannot get nested field")
+		}
 		var res TryParseValueResult
 		if ctx.ErrHandler != nil {
 			res.ErrPos = value.Position()
@@ -153,11 +176,12 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 	}
 
 	root := expr.AcquireFieldSelectorExpr()
-	root.Message = field.Parent().(protoreflect.MessageDescriptor).FullName()
+	root.Message = field.ContainingMessage().FullName()
 	root.Field = field.Name()
 	root.FieldComplexity = fi.Complexity
 	parentFieldX := root
-	pmd := field.Parent().(protoreflect.MessageDescriptor)
+	// The nested fields are resolved within the message of the traversed field.
+	pmd := field.Message()
 	pfd := field
 	parent := expr.FilterExpr(root)
 
@@ -171,7 +195,7 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q is a repeated field, cannot get nested field", pt.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q is a repeated field, cannot get nested field", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidValue
@@ -180,22 +204,28 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 			pfi := b.msgInfo.GetFieldInfo(pfd)
 
 			if pfi.FilteringForbidden {
+				if b.traceFn != nil {
+					b.trace(TraceAnnotation, rel.Position(), pfd.ContainingMessage().FullName(), string(pfd.Name()), pfd, "field is annotated with FORBID_FILTERING")
+				}
 				// Cannot traverse through fields that forbid filtering.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q forbids filtering, cannot get nested field", pt.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q forbids filtering, cannot get nested field", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidValue
 			}
 
 			if pfi.InputOnly {
+				if b.traceFn != nil {
+					b.trace(TraceAnnotation, rel.Position(), pfd.ContainingMessage().FullName(), string(pfd.Name()), pfd, "field is annotated with INPUT_ONLY field behavior")
+				}
 				// Cannot traverse through input only fields.
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q is an input only field, cannot get nested field", pt.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q is an input only field, cannot get nested field", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidValue
@@ -270,6 +300,13 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 					root.Free()
 					return tvr, ErrInternal
 				}
+				if b.traceFn != nil {
+					if err != nil {
+						b.trace(TraceMapKeyCoercion, rel.Position(), pfd.ContainingMessage().FullName(), rel.String(), pfd, fmt.Sprintf("map key is not a valid %s value", mk.Kind()))
+					} else {
+						b.trace(TraceMapKeyCoercion, rel.Position(), pfd.ContainingMessage().FullName(), rel.String(), pfd, fmt.Sprintf("map key coerced to %s: %v", mk.Kind(), tvr.Expr))
+					}
+				}
 				if err != nil {
 					root.Free()
 					return tvr, err
@@ -295,26 +332,29 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 				}
 
 				// Check if the text literal value is a valid field in the message.
-				field = pmd.Fields().ByName(protoreflect.Name(tl.Value))
+				var err error
+				field, err = b.findFieldByName(pmd, tl.Value)
+				if err != nil {
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = rel.Position()
+						res.ErrMsg = err.Error()
+					}
+					root.Free()
+					return res, ErrAmbiguousField
+				}
 				if field == nil {
-					// Check if the field might be in the OneOf descriptors.
-					for i := 0; i < pmd.Oneofs().Len(); i++ {
-						ood := pmd.Oneofs().Get(i)
-						field = ood.Fields().ByName(protoreflect.Name(tl.Value))
-						if field != nil {
-							break
-						}
+					if b.traceFn != nil {
+						b.trace(TraceFieldNotFound, rel.Position(), pmd.FullName(), tl.Value, nil, "no field or oneof field with given name")
 					}
-					if field == nil {
-						// Field was not found in the message.
-						var res TryParseValueResult
-						if ctx.ErrHandler != nil {
-							res.ErrPos = rel.Position()
-							res.ErrMsg = fmt.Sprintf("field: %q not found in the message: %s", tl.Value, pt.Message.Name())
-						}
-						root.Free()
-						return res, ErrFieldNotFound
+					// Field was not found in the message.
+					var res TryParseValueResult
+					if ctx.ErrHandler != nil {
+						res.ErrPos = rel.Position()
+						res.ErrMsg = fmt.Sprintf("field: %q not found in the message: %s", tl.Value, pmd.Name())
 					}
+					root.Free()
+					return res, ErrFieldNotFound
 				}
 
 				if !field.IsMap() && i != len(args)-1 && field.Cardinality() == protoreflect.Repeated {
@@ -322,17 +362,20 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 					var res TryParseValueResult
 					if ctx.ErrHandler != nil {
 						res.ErrPos = rel.Position()
-						res.ErrMsg = fmt.Sprintf("field: %q is a repeated field, cannot get nested field", field.Name())
+						res.ErrMsg = fmt.Sprintf("field: %q is a repeated field, cannot get nested field", root.Path()+"."+string(field.Name()))
 					}
 					root.Free()
 					return res, ErrInvalidValue
 				}
 
 				fi = b.msgInfo.GetFieldInfo(field)
+				if b.traceFn != nil {
+					b.traceFieldResolved(rel.Position(), pmd.FullName(), tl.Value, field, fi)
+				}
 
 				// Create a field expression and set it as the parent.
 				fe := expr.AcquireFieldSelectorExpr()
-				fe.Message = pt.Message
+				fe.Message = pmd.FullName()
 				fe.Field = field.Name()
 				fe.FieldComplexity = fi.Complexity
 				parentFieldX.Traversal = fe
@@ -346,7 +389,7 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q is not a message type field, cannot get nested field", pt.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q is not a message type field, cannot get nested field", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidValue
@@ -360,7 +403,7 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q is not a message type field, cannot get nested field", parentFieldX.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q is not a message type field, cannot get nested field", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidValue
@@ -372,15 +415,28 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
-					res.ErrMsg = fmt.Sprintf("field: %q is a message type field, nested field traversal requires text literal", parentFieldX.Field)
+					res.ErrMsg = fmt.Sprintf("field: %q is a message type field, nested field traversal requires text literal", root.Path())
 				}
 				root.Free()
 				return res, ErrInvalidField
 			}
 
 			// Check the value of text literal in the map value message fields.
-			field = msg.Message().Fields().ByName(protoreflect.Name(tl.Value))
+			var err error
+			field, err = b.findFieldByName(msg.Message(), tl.Value)
+			if err != nil {
+				var res TryParseValueResult
+				if ctx.ErrHandler != nil {
+					res.ErrPos = rel.Position()
+					res.ErrMsg = err.Error()
+				}
+				root.Free()
+				return res, ErrAmbiguousField
+			}
 			if field == nil {
+				if b.traceFn != nil {
+					b.trace(TraceFieldNotFound, rel.Position(), msg.Message().FullName(), tl.Value, nil, "no field with given name in the map value message")
+				}
 				var res TryParseValueResult
 				if ctx.ErrHandler != nil {
 					res.ErrPos = rel.Position()
@@ -391,6 +447,9 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 			}
 
 			fi = b.msgInfo.GetFieldInfo(field)
+			if b.traceFn != nil {
+				b.traceFieldResolved(rel.Position(), msg.Message().FullName(), tl.Value, field, fi)
+			}
 
 			// Create a field expression and set it as the parent.
 			fe := expr.AcquireFieldSelectorExpr()
@@ -411,9 +470,17 @@ func (b *Interpreter) TryParseSelectorExpr(ctx *ParseContext, value ast.ValueExp
 	return TryParseValueResult{Expr: root}, nil
 }
 
+// selectorPath renders the path of the selector expression for the error messages.
+func selectorPath(x expr.FilterExpr) string {
+	if fs, ok := x.(*expr.FieldSelectorExpr); ok {
+		return fs.Path()
+	}
+	return ""
+}
+
 // IsFieldFilteringForbidden returns true if the field filtering is forbidden.
 func IsFieldFilteringForbidden(field protoreflect.FieldDescriptor) bool {
-	opts, ok := proto.GetExtension(field.Options(), blockyannotations.E_QueryOpt).([]blockyannotations.FieldQueryOption)
+	opts, ok := info.GetExtension(field.Options(), blockyannotations.E_QueryOpt).([]blockyannotations.FieldQueryOption)
 	if !ok {
 		return false
 	}
@@ -0,0 +1,225 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// SelectorName is a kind of the field name that could be used in the field selectors.
+type SelectorName int
+
+const (
+	// ProtoSelectorName is the field name as defined in the proto file, i.e.: create_time.
+	ProtoSelectorName SelectorName = iota
+	// JSONSelectorName is the field json_name, i.e.: createTime.
+	JSONSelectorName
+	// DisplaySelectorName is the field name defined by the display name annotation, set by the DisplayNameExtensionOpt.
+	DisplaySelectorName
+)
+
+var _SelectorNameStrings = [...]string{
+	ProtoSelectorName:   "PROTO_NAME",
+	JSONSelectorName:    "JSON_NAME",
+	DisplaySelectorName: "DISPLAY_NAME",
+}
+
+// String returns the string representation of the selector name kind.
+func (n SelectorName) String() string {
+	if n < 0 || int(n) >= len(_SelectorNameStrings) {
+		return fmt.Sprintf("SelectorName(%d)", n)
+	}
+	return _SelectorNameStrings[n]
+}
+
+// SelectorNamesOpt is an option that sets the field names by which the field selectors are resolved, in the priority order.
+// By default, only the ProtoSelectorName is used.
+// A selector name is matched against each kind in the given order, and the first kind with a matching field wins,
+// i.e. with SelectorNamesOpt(ProtoSelectorName, JSONSelectorName) both `create_time` and `createTime` resolve
+// to the same field, while a proto name takes precedence over a json name of another field.
+// If multiple fields of a message match the name of the same kind, the selector fails with the ErrAmbiguousField.
+// The resolved field selector expressions always contain the proto field names.
+func SelectorNamesOpt(names ...SelectorName) Option {
+	return func(i *Interpreter) error {
+		if len(names) == 0 {
+			return errors.New("no selector names provided")
+		}
+		for j, n := range names {
+			if n < ProtoSelectorName || n > DisplaySelectorName {
+				return fmt.Errorf("invalid selector name: %s", n)
+			}
+			for _, prev := range names[:j] {
+				if prev == n {
+					return fmt.Errorf("duplicated selector name: %s", n)
+				}
+			}
+		}
+		i.selectorNames = names
+		return nil
+	}
+}
+
+// DisplayNameExtensionOpt is an option that sets the field option extension which defines the field display name,
+// used to resolve the selectors with the DisplaySelectorName kind.
+// The extension needs to be a singular string extension of the google.protobuf.FieldOptions, i.e.:
+//
+//	extend google.protobuf.FieldOptions {
+//	  string display_name = 50000;
+//	}
+//
+// Fields without the display name cannot be selected by the DisplaySelectorName.
+func DisplayNameExtensionOpt(xt protoreflect.ExtensionType) Option {
+	return func(i *Interpreter) error {
+		if xt == nil {
+			return errors.New("display name extension is nil")
+		}
+		xd := xt.TypeDescriptor()
+		if xd.ContainingMessage().FullName() != (*descriptorpb.FieldOptions)(nil).ProtoReflect().Descriptor().FullName() {
+			return fmt.Errorf("display name extension %q does not extend google.protobuf.FieldOptions", xd.FullName())
+		}
+		if xd.Kind() != protoreflect.StringKind || xd.IsList() {
+			return fmt.Errorf("display name extension %q is not a singular string", xd.FullName())
+		}
+		i.displayNameExt = xt
+		return nil
+	}
+}
+
+// FieldAliasesOpt is an option that registers the alternative names of the fields, used to resolve the selectors,
+// i.e. external API surfaces might expose the 'created' name for the 'create_time' field:
+//
+//	FieldAliasesOpt(map[string]protoreflect.FullName{"created": "my.pkg.Resource.create_time"})
+//
+// The aliases are resolved within the messages containing their fields, after the names of the SelectorNamesOpt kinds,
+// thus the camelCase json names could be combined with the aliases by SelectorNamesOpt(ProtoSelectorName, JSONSelectorName).
+// An alias is registered for a single field, cannot be a name of another field within the message, nor contain a dot.
+// The resolved field selector expressions always contain the proto field names.
+func FieldAliasesOpt(aliases map[string]protoreflect.FullName) Option {
+	return func(i *Interpreter) error {
+		for alias, name := range aliases {
+			if alias == "" || strings.ContainsRune(alias, '.') {
+				return fmt.Errorf("invalid field alias: %q", alias)
+			}
+			fd, ok := i.findField(name)
+			if !ok {
+				return fmt.Errorf("field %q not found", name)
+			}
+			md := fd.ContainingMessage()
+			if other := md.Fields().ByName(protoreflect.Name(alias)); other != nil && other.FullName() != fd.FullName() {
+				return fmt.Errorf("field alias %q collides with the field %q", alias, other.FullName())
+			}
+			if prev, ok := i.fieldAliases[alias]; ok && prev.FullName() != fd.FullName() {
+				return fmt.Errorf("field alias %q is already registered for the field %q", alias, prev.FullName())
+			}
+			if i.fieldAliases == nil {
+				i.fieldAliases = make(map[string]protoreflect.FieldDescriptor, len(aliases))
+			}
+			i.fieldAliases[alias] = fd
+		}
+		return nil
+	}
+}
+
+// findAliasedField finds the field of the md message with given alias, or returns nil if there is no such alias.
+func (b *Interpreter) findAliasedField(md protoreflect.MessageDescriptor, alias string) protoreflect.FieldDescriptor {
+	fd, ok := b.fieldAliases[alias]
+	if !ok || fd.ContainingMessage().FullName() != md.FullName() {
+		return nil
+	}
+	return fd
+}
+
+// fieldAliasesOf returns the sorted aliases of the field.
+func (b *Interpreter) fieldAliasesOf(fd protoreflect.FieldDescriptor) []string {
+	var aliases []string
+	for alias, afd := range b.fieldAliases {
+		if afd.FullName() == fd.FullName() {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// findFieldByName finds the field of the md message matching the selector name.
+// It returns nil if no field matches the name.
+func (b *Interpreter) findFieldByName(md protoreflect.MessageDescriptor, name string) (protoreflect.FieldDescriptor, error) {
+	if len(b.selectorNames) == 0 {
+		field := md.Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			// Check if the field might be in the OneOf descriptors.
+			for i := 0; i < md.Oneofs().Len(); i++ {
+				field = md.Oneofs().Get(i).Fields().ByName(protoreflect.Name(name))
+				if field != nil {
+					break
+				}
+			}
+		}
+		if field == nil {
+			field = b.findAliasedField(md, name)
+		}
+		if field == nil {
+			field = b.findFallbackField(md, name)
+		}
+		return field, nil
+	}
+
+	fields := md.Fields()
+	for _, kind := range b.selectorNames {
+		var found protoreflect.FieldDescriptor
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if b.selectorName(fd, kind) != name {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%w: %q matches %s of fields %s and %s", ErrAmbiguousField, name, kind, found.Name(), fd.Name())
+			}
+			found = fd
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	if field := b.findAliasedField(md, name); field != nil {
+		return field, nil
+	}
+	return b.findFallbackField(md, name), nil
+}
+
+// selectorName returns the name of the field of given kind, or an empty string if the field has no such name.
+func (b *Interpreter) selectorName(fd protoreflect.FieldDescriptor, kind SelectorName) string {
+	switch kind {
+	case ProtoSelectorName:
+		return string(fd.Name())
+	case JSONSelectorName:
+		return fd.JSONName()
+	case DisplaySelectorName:
+		if b.displayNameExt == nil {
+			return ""
+		}
+		name, _ := info.GetExtension(fd.Options(), b.displayNameExt).(string)
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,299 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_SelectorNames(t *testing.T) {
+	tc := []struct {
+		name      string
+		opts      []Option
+		filter    string
+		wantField []protoreflect.Name
+		err       error
+	}{
+		{
+			name:      "default proto name",
+			filter:    `i32_complexity = 1`,
+			wantField: []protoreflect.Name{"i32_complexity"},
+		},
+		{
+			name:   "default json name",
+			filter: `i32Complexity = 1`,
+			err:    ErrFieldNotFound,
+		},
+		{
+			name:      "json name",
+			opts:      []Option{SelectorNamesOpt(ProtoSelectorName, JSONSelectorName)},
+			filter:    `i32Complexity = 1`,
+			wantField: []protoreflect.Name{"i32_complexity"},
+		},
+		{
+			name:      "proto name with json names",
+			opts:      []Option{SelectorNamesOpt(ProtoSelectorName, JSONSelectorName)},
+			filter:    `i32_complexity = 1`,
+			wantField: []protoreflect.Name{"i32_complexity"},
+		},
+		{
+			name:      "nested json name",
+			opts:      []Option{SelectorNamesOpt(JSONSelectorName)},
+			filter:    `sub.i32Complexity = 1`,
+			wantField: []protoreflect.Name{"sub", "i32_complexity"},
+		},
+		{
+			name:   "json name only",
+			opts:   []Option{SelectorNamesOpt(JSONSelectorName)},
+			filter: `i32_complexity = 1`,
+			err:    ErrFieldNotFound,
+		},
+		{
+			name:      "alias",
+			opts:      []Option{FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.i32"})},
+			filter:    `count = 1`,
+			wantField: []protoreflect.Name{"i32"},
+		},
+		{
+			name:      "nested alias",
+			opts:      []Option{FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.i32"})},
+			filter:    `sub.count = 1`,
+			wantField: []protoreflect.Name{"sub", "i32"},
+		},
+		{
+			name: "alias with json names",
+			opts: []Option{
+				SelectorNamesOpt(ProtoSelectorName, JSONSelectorName),
+				FieldAliasesOpt(map[string]protoreflect.FullName{"created": "testpb.Message.timestamp"}),
+			},
+			filter:    `sub.i32Complexity = 1 AND created > 2023-01-01T00:00:00Z`,
+			wantField: nil,
+		},
+		{
+			name:   "alias of other message",
+			opts:   []Option{FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.i32"})},
+			filter: `point.count = 1`,
+			err:    ErrFieldNotFound,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(md, tt.opts...)
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			if tt.wantField != nil {
+				testSelectorFields(t, x, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestInterpreter_DisplaySelectorNames(t *testing.T) {
+	xt, dmd := testDisplayNameMessage(t)
+
+	tc := []struct {
+		name      string
+		names     []SelectorName
+		filter    string
+		wantField []protoreflect.Name
+		err       error
+	}{
+		{
+			name:      "display name",
+			names:     []SelectorName{ProtoSelectorName, DisplaySelectorName},
+			filter:    `Title = "foo"`,
+			wantField: []protoreflect.Name{"title"},
+		},
+		{
+			name:      "proto name priority",
+			names:     []SelectorName{ProtoSelectorName, DisplaySelectorName},
+			filter:    `title = "foo"`,
+			wantField: []protoreflect.Name{"title"},
+		},
+		{
+			name:      "display name priority",
+			names:     []SelectorName{DisplaySelectorName, ProtoSelectorName},
+			filter:    `title = "foo"`,
+			wantField: []protoreflect.Name{"alias"},
+		},
+		{
+			name:   "ambiguous display name",
+			names:  []SelectorName{ProtoSelectorName, DisplaySelectorName},
+			filter: `Label = "foo"`,
+			err:    ErrAmbiguousField,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := NewInterpreter(dmd, SelectorNamesOpt(tt.names...), DisplayNameExtensionOpt(xt))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			testSelectorFields(t, x, tt.wantField)
+		})
+	}
+}
+
+func TestSelectorNamesOpt_Invalid(t *testing.T) {
+	if _, err := NewInterpreter(md, SelectorNamesOpt()); err == nil {
+		t.Error("expected error for no selector names")
+	}
+	if _, err := NewInterpreter(md, SelectorNamesOpt(ProtoSelectorName, ProtoSelectorName)); err == nil {
+		t.Error("expected error for duplicated selector names")
+	}
+	if _, err := NewInterpreter(md, DisplayNameExtensionOpt(nil)); err == nil {
+		t.Error("expected error for nil display name extension")
+	}
+	if _, err := NewInterpreter(md, FieldAliasesOpt(map[string]protoreflect.FullName{"i64": "testpb.Message.i32"})); err == nil {
+		t.Error("expected error for an alias colliding with a field name")
+	}
+	if _, err := NewInterpreter(md, FieldAliasesOpt(map[string]protoreflect.FullName{"a.b": "testpb.Message.i32"})); err == nil {
+		t.Error("expected error for a dotted alias")
+	}
+	if _, err := NewInterpreter(md, FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.unknown"})); err == nil {
+		t.Error("expected error for an alias of unknown field")
+	}
+	if _, err := NewInterpreter(md,
+		FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.i32"}),
+		FieldAliasesOpt(map[string]protoreflect.FullName{"count": "testpb.Message.i64"}),
+	); err == nil {
+		t.Error("expected error for a duplicated alias")
+	}
+}
+
+// testSelectorFields checks the field names of the left hand side selector of the compare expression.
+func testSelectorFields(t *testing.T, x expr.FilterExpr, want []protoreflect.Name) {
+	t.Helper()
+	ce, ok := x.(*expr.CompareExpr)
+	if !ok {
+		t.Fatalf("expected compare expression but got %T", x)
+	}
+
+	var got []protoreflect.Name
+	fs, _ := ce.Left.(*expr.FieldSelectorExpr)
+	for fs != nil {
+		got = append(got, fs.Field)
+		fs, _ = fs.Traversal.(*expr.FieldSelectorExpr)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected fields %v but got %v", want, got)
+	}
+	for j := range want {
+		if got[j] != want[j] {
+			t.Fatalf("expected fields %v but got %v", want, got)
+		}
+	}
+}
+
+// testDisplayNameMessage builds a display name extension and a message which fields are annotated with it.
+func testDisplayNameMessage(t *testing.T) (protoreflect.ExtensionType, protoreflect.MessageDescriptor) {
+	t.Helper()
+
+	xfd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testdisplay/display.proto"),
+		Package:    proto.String("testdisplay"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("display_name"),
+			Number:   proto.Int32(50000),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+			JsonName: proto.String("displayName"),
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build extension file: %v", err)
+	}
+	xt := dynamicpb.NewExtensionType(xfd.Extensions().Get(0))
+
+	field := func(name, display string, num int32) *descriptorpb.FieldDescriptorProto {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, xt, display)
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			JsonName: proto.String(name),
+			Options:  opts,
+		}
+	}
+
+	files := new(protoregistry.Files)
+	if err = files.RegisterFile(xfd); err != nil {
+		t.Fatalf("failed to register extension file: %v", err)
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("testdisplay/message.proto"),
+		Package:    proto.String("testdisplay"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"testdisplay/display.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("title", "Title", 1),
+				field("alias", "title", 2),
+				field("label", "Label", 3),
+				field("tag", "Label", 4),
+			},
+		}},
+	}, files)
+	if err != nil {
+		t.Fatalf("failed to build message file: %v", err)
+	}
+	return xt, fd.Messages().Get(0)
+}
@@ -19,16 +19,34 @@ import (
 	"github.com/blockysource/blocky-aip/filtering/ast"
 )
 
+// SequenceWeightFn returns the relevance weight of the i-th factor of a sequence of n factors.
+type SequenceWeightFn func(i, n int) float64
+
+// SequenceOpt is an option that makes the sequences of multiple factors, i.e.: `New York Giants`,
+// result in the expr.SequenceExpr instead of the expr.AndExpr, so that the search backends could rank the matches.
+// The weight function sets the relevance weights of the factors, if nil all the factors have the weight of 1.
+func SequenceOpt(weight SequenceWeightFn) Option {
+	return func(i *Interpreter) error {
+		i.sequence = true
+		i.sequenceWeight = weight
+		return nil
+	}
+}
+
 // HandleSequenceExpr handles an ast.SequenceExpr and returns resulting expression.
 // A sequence might be composed of single or multiple factors.
 // If it is composed of a single factor, the factor is handled directly.
-// If it is composed of multiple factors, they are handled as an AND expression.
+// If it is composed of multiple factors, they are handled as an AND expression,
+// or as the expr.SequenceExpr if the SequenceOpt is set.
 // This is called a 'fuzzy' AND expression, because it is not a strict AND expression.
 // Read more at https://google.aip.dev/160#literals for more information.
 func (b *Interpreter) HandleSequenceExpr(ctx *ParseContext, seq *ast.SequenceExpr) (TryParseValueResult, error) {
 	if len(seq.Factors) == 1 {
 		return b.HandleFactorExpr(ctx, seq.Factors[0])
 	}
+	if b.sequence {
+		return b.handleWeightedSequence(ctx, seq)
+	}
 
 	// Fuzzy AND expression
 	and := expr.AcquireAndExpr()
@@ -45,3 +63,23 @@ func (b *Interpreter) HandleSequenceExpr(ctx *ParseContext, seq *ast.SequenceExp
 
 	return TryParseValueResult{Expr: and, IsIndirect: isIndirect}, nil
 }
+
+// handleWeightedSequence handles the factors of the sequence as the expr.SequenceExpr.
+func (b *Interpreter) handleWeightedSequence(ctx *ParseContext, seq *ast.SequenceExpr) (TryParseValueResult, error) {
+	se := expr.AcquireSequenceExpr()
+	var isIndirect bool
+	for i, factor := range seq.Factors {
+		fe, err := b.HandleFactorExpr(ctx, factor)
+		if err != nil {
+			se.Free()
+			return fe, err
+		}
+		weight := float64(1)
+		if b.sequenceWeight != nil {
+			weight = b.sequenceWeight(i, len(seq.Factors))
+		}
+		se.Add(fe.Expr, weight)
+		isIndirect = isIndirect || fe.IsIndirect
+	}
+	return TryParseValueResult{Expr: se, IsIndirect: isIndirect}, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestSequenceOpt(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+
+	t.Run("default", func(t *testing.T) {
+		i, err := NewInterpreter(md)
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		x, err := i.Parse(`i32 = 1 str = "foo"`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+		if _, ok := x.(*expr.AndExpr); !ok {
+			t.Errorf("expected AndExpr but got %T", x)
+		}
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		i, err := NewInterpreter(md, SequenceOpt(func(i, n int) float64 {
+			return 1 / float64(i+1)
+		}))
+		if err != nil {
+			t.Fatalf("failed to create interpreter: %v", err)
+		}
+		x, err := i.Parse(`i32 = 1 str = "foo" OR str = "bar" AND bool = true`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		and, ok := x.(*expr.AndExpr)
+		if !ok || len(and.Expr) != 2 {
+			t.Fatalf("expected AndExpr of the sequence and the restriction but got %T", x)
+		}
+		se, ok := and.Expr[0].(*expr.SequenceExpr)
+		if !ok || len(se.Factors) != 2 {
+			t.Fatalf("expected SequenceExpr of two factors but got %T", and.Expr[0])
+		}
+		if _, ok = se.Factors[1].(*expr.OrExpr); !ok {
+			t.Errorf("expected OrExpr factor but got %T", se.Factors[1])
+		}
+		if se.Weight(0) != 1 || se.Weight(1) != 0.5 {
+			t.Errorf("unexpected weights: %v", se.Weights)
+		}
+		if want := se.Factors[0].Complexity() + se.Factors[1].Complexity() + 1; se.Complexity() != want {
+			t.Errorf("expected complexity %d but got %d", want, se.Complexity())
+		}
+
+		clone := x.Clone()
+		defer clone.Free()
+		if !x.Equals(clone) {
+			t.Errorf("expected clone to be equal")
+		}
+		if got := i.Options(); !got.Sequence || got.SequenceWeight == nil {
+			t.Errorf("sequence option not reported: %+v", got)
+		}
+	})
+}
@@ -65,11 +65,13 @@ func (b *Interpreter) TryParseSignedIntField(ctx *ParseContext, in TryParseValue
 				Value:         elem,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ve.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
@@ -28,6 +28,9 @@ func (b *Interpreter) HandleSimpleExpr(ctx *ParseContext, x ast.SimpleExpr) (Try
 	case *ast.CompositeExpr:
 		return b.HandleCompositeExpr(ctx, e)
 	case *ast.RestrictionExpr:
+		if m, ok := b.findMacro(e); ok {
+			return b.expandMacro(ctx, e, m)
+		}
 		return b.HandleRestrictionExpr(ctx, e)
 	default:
 		var res TryParseValueResult
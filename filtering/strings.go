@@ -16,6 +16,7 @@ package filtering
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/blockysource/blocky-aip/expr"
 	"github.com/blockysource/blocky-aip/filtering/ast"
@@ -25,6 +26,22 @@ import (
 // TryParseStringField tries to parse a string field.
 // It can be a single string value or a repeated string value.
 func (b *Interpreter) TryParseStringField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	if tl, ok := in.Value.(*ast.TextLiteral); ok && in.IsLiteral {
+		// The unquoted value of a literal field is not split by the dots.
+		isNull := len(in.Args) == 0 && tl.Token == token.NULL && (in.IsOptional || b.emptyStringMode == NullAsEmptyString)
+		if !isNull {
+			var sb strings.Builder
+			sb.WriteString(tl.Value)
+			for _, arg := range in.Args {
+				sb.WriteRune('.')
+				sb.WriteString(arg.UnquotedString())
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = sb.String()
+			return TryParseValueResult{Expr: ve, ArgsUsed: len(in.Args)}, nil
+		}
+	}
+
 	if len(in.Args) > 0 {
 		if ctx.ErrHandler != nil {
 			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is of string type, but provided value is not a valid string value: '%s'", joinedName(in.Value, in.Args...))}, ErrInvalidValue
@@ -77,17 +94,28 @@ func (b *Interpreter) TryParseStringField(ctx *ParseContext, in TryParseValueInp
 			ve.PrefixWildcard = hasPrefixWildcard
 			ve.SuffixWildcard = hasSuffixWildcard
 			ve.SearchComplexity = in.Complexity
+			ve.Pattern = ft.Value
 			return TryParseValueResult{Expr: ve, IsIndirect: true}, nil
 		}
 
+		if ft.Value == "" {
+			return TryParseValueResult{Expr: b.emptyStringValue(in.IsOptional)}, nil
+		}
+
 		ve := expr.AcquireValueExpr()
 		ve.Value = ft.Value
 		return TryParseValueResult{Expr: ve}, nil
 	case *ast.TextLiteral:
-		if in.IsOptional && ft.Token == token.NULL {
-			ve := expr.AcquireValueExpr()
-			ve.Value = nil
-			return TryParseValueResult{Expr: ve}, nil
+		if ft.Token == token.NULL {
+			if ve, ok := b.nullStringValue(in.IsOptional); ok {
+				return TryParseValueResult{Expr: ve}, nil
+			}
+
+			// The field cannot distinguish the null from an empty string.
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: `field is not nullable and cannot be compared with null, use an empty string "" instead`}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
 		}
 
 		// Text literal cannot be a string value.
@@ -106,13 +134,16 @@ func (b *Interpreter) TryParseStringField(ctx *ParseContext, in TryParseValueInp
 				IsOptional:    in.IsOptional,
 				Value:         elem,
 				Complexity:    in.Complexity,
+				IsLiteral:     in.IsLiteral,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ve.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
@@ -15,6 +15,8 @@
 package filtering
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/blockysource/blocky-aip/expr"
@@ -285,3 +287,151 @@ func testRepeatedStringFieldEqDirect(t *testing.T, x expr.FilterExpr) {
 
 
 
+
+func TestInterpreter_LiteralStringFields(t *testing.T) {
+	i, err := NewInterpreter(md, LiteralStringFieldsOpt("testpb.Message.str"))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		value  any
+		isErr  bool
+	}{
+		{filter: `str = example.com`, value: "example.com"},
+		{filter: `str = api.v1.example.com`, value: "api.v1.example.com"},
+		{filter: `str = "example.com"`, value: "example.com"},
+		{filter: `str = example`, value: "example"},
+		{filter: `name = example.com`, isErr: true},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			right, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if right.Value != tt.value {
+				t.Fatalf("expected value %q but got %v", tt.value, right.Value)
+			}
+		})
+	}
+
+	if _, err = NewInterpreter(md, LiteralStringFieldsOpt("testpb.Message.i32")); err == nil {
+		t.Fatalf("expected error for a non string field")
+	}
+	if _, err = NewInterpreter(md, LiteralStringFieldsOpt("testpb.Message.unknown")); err == nil {
+		t.Fatalf("expected error for an unknown field")
+	}
+}
+
+func TestInterpreter_StringSearchPattern(t *testing.T) {
+	i, err := NewInterpreter(md, SubstringHasOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter  string
+		pattern string
+		like    string
+	}{
+		{filter: `str = "10%*"`, pattern: `10%*`, like: `10\%%`},
+		{filter: `str = "*a_b*"`, pattern: `*a_b*`, like: `%a\_b%`},
+		{filter: `str:"foo"`, pattern: `foo`, like: `%foo%`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ss, ok := x.(*expr.CompareExpr).Right.(*expr.StringSearchExpr)
+			if !ok {
+				t.Fatalf("expected string search expression but got %T", x.(*expr.CompareExpr).Right)
+			}
+			if ss.Pattern != tt.pattern {
+				t.Errorf("expected pattern %q but got %q", tt.pattern, ss.Pattern)
+			}
+			if got := ss.LikePattern('\\'); got != tt.like {
+				t.Errorf("expected like pattern %q but got %q", tt.like, got)
+			}
+		})
+	}
+}
+
+func TestInterpreter_EmptyStringMode(t *testing.T) {
+	tc := []struct {
+		mode   EmptyStringMode
+		filter string
+		value  any
+		isErr  bool
+	}{
+		{mode: EmptyStringDistinct, filter: `str_optional = ""`, value: ""},
+		{mode: EmptyStringDistinct, filter: `str_optional = null`, value: nil},
+		{mode: EmptyStringDistinct, filter: `str = ""`, value: ""},
+		{mode: EmptyStringDistinct, filter: `str = null`, isErr: true},
+		{mode: EmptyStringAsNull, filter: `str_optional = ""`, value: nil},
+		{mode: EmptyStringAsNull, filter: `str_optional = null`, value: nil},
+		{mode: EmptyStringAsNull, filter: `str = ""`, value: ""},
+		{mode: EmptyStringAsNull, filter: `str = null`, isErr: true},
+		{mode: NullAsEmptyString, filter: `str_optional = null`, value: ""},
+		{mode: NullAsEmptyString, filter: `str = null`, value: ""},
+		{mode: NullAsEmptyString, filter: `str = ""`, value: ""},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.mode.String()+"/"+tt.filter, func(t *testing.T) {
+			i, err := NewInterpreter(md, EmptyStringModeOpt(tt.mode))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+			x, err := i.Parse(tt.filter)
+			if tt.isErr {
+				if !errors.Is(err, ErrInvalidValue) {
+					t.Fatalf("expected invalid value error but got %v", err)
+				}
+				var fe *FilterError
+				if !errors.As(err, &fe) || !strings.Contains(fe.Msg, "not nullable") {
+					t.Fatalf("expected not nullable error message but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			right, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", x.(*expr.CompareExpr).Right)
+			}
+			if right.Value != tt.value {
+				t.Fatalf("expected value %#v but got %#v", tt.value, right.Value)
+			}
+		})
+	}
+
+	if _, err := NewInterpreter(md, EmptyStringModeOpt(EmptyStringMode(10))); err == nil {
+		t.Fatalf("expected error for an invalid mode")
+	}
+}
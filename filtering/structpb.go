@@ -91,6 +91,7 @@ func (b *Interpreter) TryParseStructPb(ctx *ParseContext, in TryParseValueInput)
 				Complexity:    in.Complexity,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
@@ -52,6 +52,30 @@ func (b *Interpreter) TryParseTimestampField(ctx *ParseContext, in TryParseValue
 			ve.Value = nil
 			return TryParseValueResult{Expr: ve}, nil
 		}
+		if ft.Token == token.DURATION && b.clock != nil {
+			t, err := b.relativeTime(ft.Value)
+			if err != nil {
+				if ctx.ErrHandler != nil {
+					return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a valid relative time: '%s'", in.Field.Kind(), ft.Value)}, ErrInvalidValue
+				}
+				return TryParseValueResult{}, ErrInvalidValue
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = t
+			return TryParseValueResult{Expr: ve}, nil
+		}
+		if ft.Token == token.DATE && b.dateOnlyTimestamps {
+			t, err := parseDateOnly(ft.Value)
+			if err != nil {
+				if ctx.ErrHandler != nil {
+					return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not a valid date: '%s'", in.Field.Kind(), ft.Value)}, ErrInvalidValue
+				}
+				return TryParseValueResult{}, ErrInvalidValue
+			}
+			ve := expr.AcquireValueExpr()
+			ve.Value = t
+			return TryParseValueResult{Expr: ve}, nil
+		}
 		if ft.Token != token.TIMESTAMP {
 			if ctx.ErrHandler != nil {
 				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Kind(), ft.Value)}, ErrInvalidValue
@@ -202,11 +226,13 @@ func (b *Interpreter) TryParseTimestampField(ctx *ParseContext, in TryParseValue
 				Complexity:    in.Complexity,
 			})
 			if err != nil {
+				ve.Free()
 				return res, err
 			}
 
 			if res.Expr == nil {
 				// This is internal error, return an error.
+				ve.Free()
 				if ctx.ErrHandler != nil {
 					return TryParseValueResult{ErrPos: elem.Position(), ErrMsg: "internal error: parsed expression is nil"}, ErrInternal
 				}
@@ -0,0 +1,235 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+	"github.com/blockysource/blocky-aip/internal/info"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// SelectorTraceKind is a kind of the selector resolution step.
+type SelectorTraceKind int
+
+const (
+	// TraceFieldResolved is a step where the field name was matched with a field descriptor.
+	TraceFieldResolved SelectorTraceKind = iota
+	// TraceFieldNotFound is a step where the field name was not found in the message.
+	TraceFieldNotFound
+	// TraceAnnotation is a step where a field annotation rejected the selector.
+	TraceAnnotation
+	// TraceMapKeyCoercion is a step where the map key literal was coerced to the map key type.
+	TraceMapKeyCoercion
+	// TraceValueCoercion is a step where the right hand side value was coerced to the field type.
+	TraceValueCoercion
+	// TraceSelectorFallback is a step where the right hand side is not a valid value,
+	// and is resolved as a field selector.
+	TraceSelectorFallback
+)
+
+var _SelectorTraceKindStrings = [...]string{
+	TraceFieldResolved:    "FIELD_RESOLVED",
+	TraceFieldNotFound:    "FIELD_NOT_FOUND",
+	TraceAnnotation:       "ANNOTATION",
+	TraceMapKeyCoercion:   "MAP_KEY_COERCION",
+	TraceValueCoercion:    "VALUE_COERCION",
+	TraceSelectorFallback: "SELECTOR_FALLBACK",
+}
+
+// String returns the string representation of the trace kind.
+func (k SelectorTraceKind) String() string {
+	if k < 0 || int(k) >= len(_SelectorTraceKindStrings) {
+		return fmt.Sprintf("SelectorTraceKind(%d)", k)
+	}
+	return _SelectorTraceKindStrings[k]
+}
+
+// SelectorTraceStep is a single step of the selector resolution, recorded by the SelectorTraceOpt.
+type SelectorTraceStep struct {
+	// Kind is the kind of the step.
+	Kind SelectorTraceKind
+
+	// Pos is the position of the resolved element in the filter.
+	Pos token.Position
+
+	// Message is the full name of the message in which the name was resolved.
+	Message protoreflect.FullName
+
+	// Name is the field name, map key or the value literal that was resolved.
+	Name string
+
+	// Field is the matched field descriptor, nil if the field was not resolved.
+	Field protoreflect.FieldDescriptor
+
+	// Detail is a human-readable description of the step.
+	Detail string
+}
+
+// String returns the string representation of the step.
+func (s SelectorTraceStep) String() string {
+	return fmt.Sprintf("%d: %s %s in %s: %s", s.Pos, s.Kind, s.Name, s.Message, s.Detail)
+}
+
+// SelectorTraceFn is a function that receives the selector resolution steps.
+type SelectorTraceFn func(step SelectorTraceStep)
+
+// SelectorTraceOpt is an option that sets the function which records each step of the selector resolution,
+// i.e. the field names tried, the descriptors matched, the annotations that rejected the field
+// and the coercions of the map keys and values.
+// It is meant for debugging the 'field not found' and type mismatch errors, and should not be used in production.
+// The function is called synchronously within the Parse, from each goroutine that uses the Interpreter.
+func SelectorTraceOpt(fn SelectorTraceFn) Option {
+	return func(i *Interpreter) error {
+		if fn == nil {
+			return errors.New("selector trace function is nil")
+		}
+		i.traceFn = fn
+		return nil
+	}
+}
+
+func (b *Interpreter) trace(kind SelectorTraceKind, pos token.Position, msg protoreflect.FullName, name string, fd protoreflect.FieldDescriptor, detail string) {
+	b.traceFn(SelectorTraceStep{
+		Kind:    kind,
+		Pos:     pos,
+		Message: msg,
+		Name:    name,
+		Field:   fd,
+		Detail:  detail,
+	})
+}
+
+// traceFieldResolved records a resolved field along with the annotations that affect its filtering.
+func (b *Interpreter) traceFieldResolved(pos token.Position, msg protoreflect.FullName, name string, fd protoreflect.FieldDescriptor, fi info.FieldInfo) {
+	var sb strings.Builder
+	sb.WriteString("matched ")
+	sb.WriteString(string(fd.FullName()))
+	sb.WriteString(" of kind ")
+	sb.WriteString(fd.Kind().String())
+	if fd.IsMap() {
+		sb.WriteString(" (map)")
+	} else if fd.IsList() {
+		sb.WriteString(" (repeated)")
+	}
+	if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		sb.WriteString(", in oneof ")
+		sb.WriteString(string(od.Name()))
+	}
+	fmt.Fprintf(&sb, ", complexity %d", fi.Complexity)
+	if fi.Nullable {
+		sb.WriteString(", nullable")
+	}
+	if fi.NonTraversal {
+		sb.WriteString(", non traversal")
+	}
+	if fi.NoTextSearch {
+		sb.WriteString(", no text search")
+	}
+	b.trace(TraceFieldResolved, pos, msg, name, fd, sb.String())
+}
+
+// traceValue records the coercion of the right hand side value of the restriction to the field type.
+func (b *Interpreter) traceValue(arg ast.ArgExpr, fd protoreflect.FieldDescriptor, res TryParseValueResult, err error) {
+	var msg protoreflect.FullName
+	if md := fd.ContainingMessage(); md != nil {
+		msg = md.FullName()
+	}
+	if err != nil {
+		reason := res.ErrMsg
+		if reason == "" {
+			reason = err.Error()
+		}
+		b.trace(TraceSelectorFallback, arg.Position(), msg, arg.String(), fd, fmt.Sprintf("value is not a valid %s: %s, resolving as a field selector", fd.Kind(), reason))
+		return
+	}
+	var detail string
+	switch et := res.Expr.(type) {
+	case *expr.ValueExpr:
+		detail = fmt.Sprintf("coerced to %s as %T: %v", fd.Kind(), et.Value, et.Value)
+	default:
+		detail = fmt.Sprintf("coerced to %s as %T", fd.Kind(), res.Expr)
+	}
+	b.trace(TraceValueCoercion, arg.Position(), msg, arg.String(), fd, detail)
+}
+
+// TraceHookOpt is an option that sets the function receiving the parse trace events, i.e. the tokens consumed
+// and the grammar rules entered and exited by the parser, see the parser.TraceHookOption,
+// along with the values parsed and the declared functions called by the interpreter.
+// It is meant for debugging why a filter fails, and profiling the slow parses, i.e. with the parser.NewTracePrinter.
+// The function is called synchronously within the Parse, from each goroutine that uses the Interpreter.
+func TraceHookOpt(fn parser.TraceHookFn) Option {
+	return func(i *Interpreter) error {
+		if fn == nil {
+			return errors.New("trace hook function is nil")
+		}
+		i.traceHook = fn
+		return nil
+	}
+}
+
+// traceHookOption returns the parser option of the trace hook, if any.
+func (b *Interpreter) traceHookOption() parser.ParserOption {
+	if b.traceHook == nil {
+		return nil
+	}
+	return parser.TraceHookOption(b.traceHook)
+}
+
+// traceTryParseValue parses the value, and emits the parser.TraceValueParsed event.
+func (b *Interpreter) traceTryParseValue(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	pos, text := in.Value.Position(), in.Value.String()
+	start := time.Now()
+	res, err := b.tryParseValue(ctx, in)
+	elapsed := time.Since(start)
+
+	var detail string
+	switch {
+	case err != nil:
+		detail = res.ErrMsg
+	case in.Field == nil:
+	default:
+		if ve, ok := res.Expr.(*expr.ValueExpr); ok {
+			detail = fmt.Sprintf("%s as %T: %v", in.Field.Kind(), ve.Value, ve.Value)
+		} else {
+			detail = fmt.Sprintf("%s as %T", in.Field.Kind(), res.Expr)
+		}
+	}
+	b.traceHook(parser.TraceEvent{Kind: parser.TraceValueParsed, Pos: pos, Text: text, Detail: detail, Elapsed: elapsed, Err: err})
+	return res, err
+}
+
+// traceCallFunction calls the function, and emits the parser.TraceFunctionCalled event.
+func (b *Interpreter) traceCallFunction(ctx *ParseContext, x *ast.FunctionCall, fn *FunctionCallDeclaration, allowIndirect bool) (TryParseValueResult, error) {
+	pos, name := x.Position(), x.JoinedName()
+	start := time.Now()
+	res, err := b.callFunction(ctx, x, fn, allowIndirect)
+	elapsed := time.Since(start)
+
+	detail := res.ErrMsg
+	if err == nil {
+		detail = fmt.Sprintf("returned %T", res.Expr)
+	}
+	b.traceHook(parser.TraceEvent{Kind: parser.TraceFunctionCalled, Pos: pos, Text: name, Detail: detail, Elapsed: elapsed, Err: err})
+	return res, err
+}
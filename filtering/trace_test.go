@@ -0,0 +1,175 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/parser"
+)
+
+func TestInterpreter_SelectorTrace(t *testing.T) {
+	type step struct {
+		kind SelectorTraceKind
+		msg  protoreflect.FullName
+		name string
+	}
+	tc := []struct {
+		name   string
+		filter string
+		isErr  bool
+		steps  []step
+	}{
+		{
+			name:   "nested field",
+			filter: `point.x = 1.0`,
+			steps: []step{
+				{kind: TraceFieldResolved, msg: "testpb.Message", name: "point"},
+				{kind: TraceFieldResolved, msg: "testpb.Point", name: "x"},
+				{kind: TraceValueCoercion, msg: "testpb.Point", name: "1.0"},
+			},
+		},
+		{
+			name:   "nested field not found",
+			filter: `point.z = 1.0`,
+			isErr:  true,
+			steps: []step{
+				{kind: TraceFieldResolved, msg: "testpb.Message", name: "point"},
+				{kind: TraceFieldNotFound, msg: "testpb.Point", name: "z"},
+			},
+		},
+		{
+			name:   "forbidden field",
+			filter: `no_filter = "foo"`,
+			isErr:  true,
+			steps: []step{
+				{kind: TraceFieldResolved, msg: "testpb.Message", name: "no_filter"},
+				{kind: TraceAnnotation, msg: "testpb.Message", name: "no_filter"},
+			},
+		},
+		{
+			name:   "selector fallback",
+			filter: `i32 = i64`,
+			steps: []step{
+				{kind: TraceFieldResolved, msg: "testpb.Message", name: "i32"},
+				{kind: TraceSelectorFallback, msg: "testpb.Message", name: "i64"},
+				{kind: TraceFieldResolved, msg: "testpb.Message", name: "i64"},
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var steps []SelectorTraceStep
+			i, err := NewInterpreter(md, SelectorTraceOpt(func(s SelectorTraceStep) {
+				steps = append(steps, s)
+			}))
+			if err != nil {
+				t.Fatalf("failed to create interpreter: %v", err)
+			}
+
+			x, err := i.Parse(tt.filter)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				x.Free()
+			}
+
+			if len(steps) != len(tt.steps) {
+				t.Fatalf("expected %d steps but got %d: %v", len(tt.steps), len(steps), steps)
+			}
+			for j, s := range tt.steps {
+				got := steps[j]
+				if got.Kind != s.kind || got.Message != s.msg || got.Name != s.name {
+					t.Errorf("step %d: expected %s %s in %s but got %s", j, s.kind, s.name, s.msg, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpreter_TraceHook(t *testing.T) {
+	upper := &FunctionCallDeclaration{
+		Name: FunctionName{PkgName: "test", Name: "Upper"},
+		Arguments: []*FunctionCallArgumentDeclaration{
+			{ArgName: "value", FieldKind: protoreflect.StringKind},
+		},
+		Returning: &FunctionCallReturningDeclaration{FieldKind: protoreflect.StringKind},
+		CallFn: func(args ...expr.FilterExpr) (FunctionCallArgument, error) {
+			ve := expr.AcquireValueExpr()
+			ve.Value = strings.ToUpper(args[0].(*expr.ValueExpr).Value.(string))
+			return FunctionCallArgument{Expr: ve}, nil
+		},
+	}
+
+	var events []parser.TraceEvent
+	i, err := NewInterpreter(md, RegisterFunction(upper), TraceHookOpt(func(e parser.TraceEvent) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	x, err := i.Parse(`str = test.Upper("foo") AND i32 = 5`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x.Free()
+
+	var tokens, rules int
+	var values, calls []parser.TraceEvent
+	for _, e := range events {
+		switch e.Kind {
+		case parser.TraceTokenConsumed:
+			tokens++
+		case parser.TraceRuleEntered:
+			rules++
+		case parser.TraceValueParsed:
+			values = append(values, e)
+		case parser.TraceFunctionCalled:
+			calls = append(calls, e)
+		}
+	}
+	if tokens == 0 || rules == 0 {
+		t.Errorf("expected the parser events, but got %d tokens and %d rules", tokens, rules)
+	}
+	if len(calls) != 1 || calls[0].Text != "test.Upper" || calls[0].Err != nil {
+		t.Errorf("expected test.Upper function call event but got %v", calls)
+	}
+	var found bool
+	for _, v := range values {
+		if v.Text == "5" && v.Err == nil && strings.Contains(v.Detail, "int32") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the i32 value parsed event but got %v", values)
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		if _, err = NewInterpreter(md, TraceHookOpt(nil)); err == nil {
+			t.Fatalf("expected error for nil trace hook")
+		}
+	})
+}
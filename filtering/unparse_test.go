@@ -0,0 +1,113 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestExprString_RoundTrip(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, RegexMatchOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   string
+	}{
+		{filter: `i32 = 1`, want: `i32 = 1`},
+		{filter: `str = "a \"quoted\" \\ value"`, want: `str = "a \"quoted\" \\ value"`},
+		{filter: `str="foo" i64>=-5`, want: `str = "foo" AND i64 >= -5`},
+		{filter: `str = "a" OR str = "b" AND bool = true`, want: `str = "a" OR str = "b" AND bool = true`},
+		{filter: `(str = "a" AND i32 > 1) OR bool = false`, want: `(str = "a" AND i32 > 1) OR bool = false`},
+		{filter: `NOT (str = "a" OR str = "b")`, want: `NOT (str = "a" OR str = "b")`},
+		{filter: `-bool = true`, want: `NOT bool = true`},
+		{filter: `sub.name = "foo*"`, want: `sub.name = "foo*"`},
+		{filter: `rp_str:"foo"`, want: `rp_str:"foo"`},
+		{filter: `map_str_i32:"key"`, want: `map_str_i32:"key"`},
+		{filter: `str IN ["a", "b"]`, want: `str IN ["a", "b"]`},
+		{filter: `str IN ("a", "b")`, want: `str IN ["a", "b"]`},
+		{filter: `str NOT IN ["a", "b"]`, want: `NOT str IN ["a", "b"]`},
+		{filter: `i32 = 1 AND str NOT IN ("a")`, want: `i32 = 1 AND NOT str IN ["a"]`},
+		{filter: `rp_str !: "foo"`, want: `NOT rp_str:"foo"`},
+		{filter: `sub !: *`, want: `NOT sub:*`},
+		{filter: `enum = "TWO"`, want: `enum = "TWO"`},
+		{filter: `double > 1.5`, want: `double > 1.5`},
+		{filter: `double > 2`, want: `double > 2.0`},
+		{filter: `timestamp > 2023-01-01T10:00:00Z`, want: `timestamp > 2023-01-01T10:00:00Z`},
+		{filter: `duration < 1.5s`, want: `duration < 1.5s`},
+		{filter: `duration < 1h`, want: `duration < 3600s`},
+		{filter: `duration < 315360000.000000001s`, want: `duration < 315360000.000000001s`},
+		{filter: `timestamp > 2023-01-01T10:00:00.000000001Z`, want: `timestamp > 2023-01-01T10:00:00.000000001Z`},
+		{filter: `timestamp > 2023-01-01T10:00:00.5+02:00`, want: `timestamp > 2023-01-01T08:00:00.5Z`},
+		{filter: `i32 = i64`, want: `i32 = i64`},
+		{filter: `bytes = 0x0aff`, want: `bytes = 0x0aff`},
+		{filter: `bytes_optional = null`, want: `bytes_optional = null`},
+		{filter: `rp_sub:{name: "foo", i32: 1}`, want: `rp_sub:{name: "foo", i32: 1}`},
+		{filter: `str =~ "^a\\d+$"`, want: `str =~ "^a\\d+$"`},
+		{filter: `sub.sub:*`, want: `sub.sub:*`},
+		{filter: `NOT rp_str:*`, want: `NOT rp_str:*`},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			got, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s but got %s", tt.want, got)
+			}
+
+			y, err := i.Parse(got)
+			if err != nil {
+				t.Fatalf("failed to parse rendered filter: %v", err)
+			}
+			defer y.Free()
+			if !x.Equals(y) {
+				t.Errorf("rendered filter %s doesn't parse into an equal expression", got)
+			}
+		})
+	}
+}
+
+func TestExprString_NotRenderable(t *testing.T) {
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := i.Parse(`sub = testpb.Message{name: "foo"}`)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	if _, err = expr.String(x); !errors.Is(err, expr.ErrNotRenderable) {
+		t.Errorf("expected error %v but got %v", expr.ErrNotRenderable, err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// UUIDFieldsOpt is an option that marks the string fields, which values are UUIDs.
+// The values of these fields are validated while parsing, and decoded into the [16]byte,
+// so that i.e. the SQL backends could bind them as the native uuid types.
+// A value is accepted in its canonical form, i.e. "123e4567-e89b-12d3-a456-426614174000",
+// case-insensitive, without the dashes or surrounded by the braces, and is rendered back in its canonical lower case form.
+// The fields are the string fields or repeated string fields.
+func UUIDFieldsOpt(fields ...protoreflect.FullName) Option {
+	return func(i *Interpreter) error {
+		if i.uuidFields == nil {
+			i.uuidFields = make(map[protoreflect.FullName]struct{}, len(fields))
+		}
+		for _, name := range fields {
+			fd, ok := i.findField(name)
+			if !ok {
+				return fmt.Errorf("field %q not found", name)
+			}
+			if fd.IsMap() || fd.Kind() != protoreflect.StringKind {
+				return fmt.Errorf("field %q is not a string field", name)
+			}
+			i.uuidFields[name] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// isUUIDField checks if the field is marked as the UUID field.
+func (b *Interpreter) isUUIDField(fd FieldDescriptor) bool {
+	if len(b.uuidFields) == 0 {
+		return false
+	}
+	pfd, ok := fd.(protoreflect.FieldDescriptor)
+	if !ok {
+		return false
+	}
+	_, ok = b.uuidFields[pfd.FullName()]
+	return ok
+}
+
+// TryParseUUIDField tries to parse a UUID string field.
+// It can be a single UUID value or a repeated UUID value, decoded into the [16]byte.
+func (b *Interpreter) TryParseUUIDField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	if len(in.Args) > 0 {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: fmt.Sprintf("field is a UUID, but provided value is not a valid UUID: '%s'", joinedName(in.Value, in.Args...))}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
+
+	switch ft := in.Value.(type) {
+	case *ast.StringLiteral:
+		u, err := parseUUID(ft.Value)
+		if err != nil {
+			if ctx.ErrHandler != nil {
+				return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: fmt.Sprintf("field is a UUID, but provided value is not a valid UUID: '%s': %v", ft.Value, err)}, ErrInvalidValue
+			}
+			return TryParseValueResult{}, ErrInvalidValue
+		}
+		ve := expr.AcquireValueExpr()
+		ve.Value = u
+		return TryParseValueResult{Expr: ve}, nil
+	case *ast.TextLiteral:
+		if ft.Token == token.NULL && in.IsOptional {
+			ve := expr.AcquireValueExpr()
+			ve.Value = nil
+			return TryParseValueResult{Expr: ve}, nil
+		}
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: ft.Pos, ErrMsg: "field is a UUID, but provided value is not a quoted UUID string"}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	case *ast.ArrayExpr:
+		// An array can be parsed as a repeated field value.
+		ve := expr.AcquireArrayExpr()
+		for _, elem := range ft.Elements {
+			res, err := b.TryParseValue(ctx, TryParseValueInput{
+				Field:      in.Field,
+				IsOptional: in.IsOptional,
+				Value:      elem,
+				Complexity: in.Complexity,
+			})
+			if err != nil {
+				ve.Free()
+				return res, err
+			}
+			ve.Elements = append(ve.Elements, res.Expr)
+		}
+		return TryParseValueResult{Expr: ve}, nil
+	case *ast.StructExpr:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: ft.Position(), ErrMsg: "field cannot accept struct expression as a value"}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	default:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: "invalid AST node"}, ErrInvalidAST
+		}
+		return TryParseValueResult{}, ErrInvalidAST
+	}
+}
+
+// parseUUID parses the UUID in its canonical, braced or dashless form.
+func parseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+		s = s[1:37]
+	}
+	switch len(s) {
+	case 32:
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return u, errors.New("invalid dash positions")
+		}
+		s = strings.ReplaceAll(s, "-", "")
+		if len(s) != 32 {
+			return u, errors.New("invalid dash positions")
+		}
+	default:
+		return u, fmt.Errorf("invalid length %d", len(s))
+	}
+	if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+		return u, err
+	}
+	return u, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestInterpreter_UUIDFields(t *testing.T) {
+	var msg string
+	md := new(testpb.Message).ProtoReflect().Descriptor()
+	i, err := NewInterpreter(md, UUIDFieldsOpt("testpb.Message.str", "testpb.Message.rp_str", "testpb.Message.str_optional"), ErrHandlerOpt(func(pos token.Position, m string) {
+		msg = m
+	}))
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	want := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	tc := []struct {
+		filter string
+		err    error
+		msg    string
+	}{
+		{filter: `str = "123e4567-e89b-12d3-a456-426614174000"`},
+		{filter: `str = "123E4567-E89B-12D3-A456-426614174000"`},
+		{filter: `str = "123e4567e89b12d3a456426614174000"`},
+		{filter: `str = "{123e4567-e89b-12d3-a456-426614174000}"`},
+		{filter: `rp_str:"123e4567-e89b-12d3-a456-426614174000"`},
+		{filter: `str = "123e4567-e89b-12d3-a456"`, err: ErrInvalidValue, msg: "invalid length"},
+		{filter: `str = "123e4567e-89b-12d3-a456-426614174000"`, err: ErrInvalidValue, msg: "invalid dash positions"},
+		{filter: `str = "123e4567-e89b-12d3-a456-42661417400z"`, err: ErrInvalidValue, msg: "not a valid UUID"},
+		{filter: `str = "123e4567*"`, err: ErrInvalidValue, msg: "not a valid UUID"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			msg = ""
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				if !strings.Contains(msg, tt.msg) {
+					t.Errorf("expected error message containing %q but got %q", tt.msg, msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			ve, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if ve.Value != want {
+				t.Errorf("expected value %v but got %v", want, ve.Value)
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected valid expression but got %v", err)
+			}
+			s, err := expr.String(x)
+			if err != nil {
+				t.Fatalf("failed to render expression: %v", err)
+			}
+			if !strings.Contains(s, `"123e4567-e89b-12d3-a456-426614174000"`) {
+				t.Errorf("expected canonical UUID in %s", s)
+			}
+		})
+	}
+
+	t.Run("null", func(t *testing.T) {
+		x, err := i.Parse(`str_optional = null`)
+		if err != nil {
+			t.Fatalf("failed to parse filter: %v", err)
+		}
+		x.Free()
+	})
+
+	t.Run("in list", func(t *testing.T) {
+		x, err := i.Parse(`str IN ["123e4567-e89b-12d3-a456-426614174000", "00000000-0000-0000-0000-000000000000"]`)
+		if err != nil {
+			t.Fatalf("failed to parse filter: %v", err)
+		}
+		x.Free()
+	})
+
+	t.Run("not a string field", func(t *testing.T) {
+		if _, err := NewInterpreter(md, UUIDFieldsOpt("testpb.Message.i32")); err == nil {
+			t.Errorf("expected error for non string field")
+		}
+	})
+}
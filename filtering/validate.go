@@ -0,0 +1,514 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// ValidateExpr verifies that the filter expression x is semantically valid for the message descriptor desc.
+// It runs the same checks as the Interpreter does while parsing a filter (field existence, filtering restrictions,
+// kind compatibility and comparator rules), but over an expression tree that was built programmatically,
+// i.e. with the expr.Composer, or decoded from the wire.
+// The compared field types, and the fields allowed for the regular expression and the custom comparators,
+// are checked with the same functions the Interpreter uses. The kinds allowed by a custom comparator declaration
+// are known only to the interpreter, thus not checked.
+// A nil expression is considered valid.
+// The returned error wraps one of the package standard errors, so that it can be checked with errors.Is.
+func ValidateExpr(desc protoreflect.MessageDescriptor, x expr.FilterExpr) error {
+	return ValidateExprWithKindPolicy(desc, x, DefaultKindPolicy)
+}
+
+type exprValidator struct {
+	desc       protoreflect.MessageDescriptor
+	msgInfo    info.MessagesInfo
+	kindPolicy KindPolicy
+}
+
+// validatedSelector is the result of a field selector validation.
+type validatedSelector struct {
+	// fd is the descriptor of the last selected field.
+	// If the selector ends with a map key, it is the descriptor of the map field.
+	fd protoreflect.FieldDescriptor
+	fi info.FieldInfo
+
+	// isMapKey is true if the selector ends with a map key expression.
+	isMapKey bool
+}
+
+func (v *exprValidator) validateFilter(x expr.FilterExpr) error {
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		for _, e := range xt.Expr {
+			if err := v.validateFilter(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *expr.SequenceExpr:
+		for _, e := range xt.Factors {
+			if err := v.validateFilter(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *expr.OrExpr:
+		for _, e := range xt.Expr {
+			if err := v.validateFilter(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *expr.NotExpr:
+		if xt.Expr == nil {
+			return fmt.Errorf("%w: not expression without an operand", ErrInvalidAST)
+		}
+		return v.validateFilter(xt.Expr)
+	case *expr.CompositeExpr:
+		if xt.Expr == nil {
+			return fmt.Errorf("%w: empty composite expression", ErrInvalidAST)
+		}
+		return v.validateFilter(xt.Expr)
+	case *expr.SearchExpr:
+		if xt.Expr == nil {
+			return fmt.Errorf("%w: search expression without the matching filter", ErrInvalidAST)
+		}
+		return v.validateFilter(xt.Expr)
+	case *expr.CompareExpr:
+		return v.validateCompare(xt)
+	case *expr.PresenceExpr:
+		fs, ok := xt.Field.(*expr.FieldSelectorExpr)
+		if !ok {
+			return fmt.Errorf("%w: presence of unexpected expression type %T", ErrInvalidAST, xt.Field)
+		}
+		vs, err := v.validateSelector(fs)
+		if err != nil {
+			return err
+		}
+		if vs.isMapKey || !tracksPresence(vs.fd, vs.fi) {
+			return fmt.Errorf("%w: field: %q does not track presence", ErrInvalidField, vs.fd.Name())
+		}
+		return nil
+	case *expr.RegexMatchExpr:
+		fs, ok := xt.Left.(*expr.FieldSelectorExpr)
+		if !ok {
+			return fmt.Errorf("%w: regular expression match of unexpected expression type %T", ErrInvalidAST, xt.Left)
+		}
+		vs, err := v.validateSelector(fs)
+		if err != nil {
+			return err
+		}
+		if !isRegexMatchField(vs.fd, vs.isMapKey) {
+			return fmt.Errorf("%w: regular expression match is not allowed for the field: %q", ErrInvalidValue, vs.fd.Name())
+		}
+		if _, err = regexp.Compile(xt.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid regular expression pattern: %v", ErrInvalidValue, err)
+		}
+		return nil
+	case *expr.FieldSelectorExpr:
+		_, err := v.validateSelector(xt)
+		return err
+	case *expr.FunctionCallExpr:
+		// Function calls are resolved by the caller, their arguments are opaque for the validator.
+		return nil
+	case *expr.MatchAllExpr:
+		// The empty filter of the MatchAllOpt matches any message.
+		return nil
+	case nil:
+		return fmt.Errorf("%w: nil expression", ErrInvalidAST)
+	default:
+		return fmt.Errorf("%w: unexpected expression type %T", ErrInvalidAST, x)
+	}
+}
+
+func (v *exprValidator) validateSelector(x *expr.FieldSelectorExpr) (validatedSelector, error) {
+	md := v.desc
+	cur := x
+	for {
+		fd := md.Fields().ByName(cur.Field)
+		if fd == nil {
+			return validatedSelector{}, fmt.Errorf("%w: field: %s not found in the message: %s", ErrFieldNotFound, cur.Field, md.FullName())
+		}
+
+		fi := v.msgInfo.GetFieldInfo(fd)
+		if fi.FilteringForbidden {
+			return validatedSelector{}, fmt.Errorf("%w: field: %q forbids filtering", ErrInvalidField, fd.Name())
+		}
+		if fi.InputOnly {
+			return validatedSelector{}, fmt.Errorf("%w: field: %q is an input only field", ErrInvalidField, fd.Name())
+		}
+
+		switch tt := cur.Traversal.(type) {
+		case nil:
+			return validatedSelector{fd: fd, fi: fi}, nil
+		case *expr.FieldSelectorExpr:
+			if fd.Kind() != protoreflect.MessageKind || fd.IsMap() || fd.Cardinality() == protoreflect.Repeated {
+				return validatedSelector{}, fmt.Errorf("%w: field: %q cannot get nested field", ErrInvalidField, fd.Name())
+			}
+			if fi.NonTraversal {
+				return validatedSelector{}, fmt.Errorf("%w: field: %q is a non-traversal field, cannot get nested field", ErrInvalidField, fd.Name())
+			}
+			md = fd.Message()
+			cur = tt
+		case *expr.MapKeyExpr:
+			if !fd.IsMap() {
+				return validatedSelector{}, fmt.Errorf("%w: field: %q is not a map field", ErrInvalidField, fd.Name())
+			}
+			key, ok := tt.Key.(*expr.ValueExpr)
+			if !ok || key.Value == nil || !isValueOfKind(fd.MapKey(), key.Value) {
+				return validatedSelector{}, fmt.Errorf("%w: invalid key of the map field: %q", ErrInvalidValue, fd.Name())
+			}
+			if tt.Traversal == nil {
+				return validatedSelector{fd: fd, fi: fi, isMapKey: true}, nil
+			}
+			next, ok := tt.Traversal.(*expr.FieldSelectorExpr)
+			if !ok {
+				return validatedSelector{}, fmt.Errorf("%w: unexpected map key traversal type %T", ErrInvalidAST, tt.Traversal)
+			}
+			mv := fd.MapValue()
+			if mv.Kind() != protoreflect.MessageKind || fi.NonTraversal {
+				return validatedSelector{}, fmt.Errorf("%w: field: %q value cannot get nested field", ErrInvalidField, fd.Name())
+			}
+			md = mv.Message()
+			cur = next
+		default:
+			return validatedSelector{}, fmt.Errorf("%w: unexpected field traversal type %T", ErrInvalidAST, cur.Traversal)
+		}
+	}
+}
+
+func (v *exprValidator) validateCompare(x *expr.CompareExpr) error {
+	if _, ok := x.Comparator.CustomSymbol(); ok {
+		return v.validateCustomCompare(x)
+	}
+	if x.Comparator < expr.EQ || x.Comparator > expr.IN {
+		return fmt.Errorf("%w: unknown comparator: %s", ErrInvalidAST, x.Comparator)
+	}
+	if x.Left == nil || x.Right == nil {
+		return fmt.Errorf("%w: compare expression requires both sides", ErrInvalidAST)
+	}
+
+	var left validatedSelector
+	switch lt := x.Left.(type) {
+	case *expr.FieldSelectorExpr:
+		var err error
+		left, err = v.validateSelector(lt)
+		if err != nil {
+			return err
+		}
+	case *expr.FunctionCallExpr:
+		// The result type of a function call is only known to the interpreter that declared it.
+		return nil
+	default:
+		return fmt.Errorf("%w: the left hand side is not a valid selector: %T", ErrInvalidAST, x.Left)
+	}
+
+	cmp := x.Comparator
+	fd := left.fd
+	isRepeated := fd.Cardinality() == protoreflect.Repeated && !fd.IsMap()
+	switch {
+	case left.isMapKey:
+		fd = fd.MapValue()
+	case fd.IsMap() && cmp == expr.HAS:
+		fd = fd.MapKey()
+	}
+
+	switch rt := x.Right.(type) {
+	case *expr.ValueExpr:
+		if (left.isMapKey || isRepeated) && cmp != expr.HAS {
+			return fmt.Errorf("%w: cannot compare a repeated field: %s with a comparator: %s", ErrInvalidValue, left.fd.Name(), cmp)
+		}
+		if !left.isMapKey && !isRepeated && !left.fd.IsMap() && cmp == expr.HAS {
+			return fmt.Errorf("%w: operator HAS (':') can only be used on map or repeated fields", ErrInvalidValue)
+		}
+		if rt.Value == nil {
+			if !left.fi.Nullable {
+				return fmt.Errorf("%w: field: %s is not nullable", ErrInvalidValue, left.fd.Name())
+			}
+			return nil
+		}
+		if !isValueOfKind(fd, rt.Value) {
+			return fmt.Errorf("%w: value of type %T doesn't match the field: %s", ErrInvalidValue, rt.Value, left.fd.Name())
+		}
+	case *expr.ArrayExpr:
+		if !isRepeated && cmp != expr.IN {
+			return fmt.Errorf("%w: cannot compare a repeated field: %s with a comparator: %s", ErrInvalidValue, left.fd.Name(), cmp)
+		}
+		for _, elem := range rt.Elements {
+			ev, ok := elem.(*expr.ValueExpr)
+			if !ok {
+				return fmt.Errorf("%w: unexpected array element type %T", ErrInvalidAST, elem)
+			}
+			if ev.Value == nil || !isValueOfKind(fd, ev.Value) {
+				return fmt.Errorf("%w: array element of type %T doesn't match the field: %s", ErrInvalidValue, ev.Value, left.fd.Name())
+			}
+		}
+	case *expr.MapValueExpr:
+		if !fd.IsMap() || left.isMapKey {
+			return fmt.Errorf("%w: cannot compare a map with a non map field: %s", ErrInvalidValue, left.fd.Name())
+		}
+		if cmp != expr.EQ && cmp != expr.NE {
+			return fmt.Errorf("%w: cannot compare a map with a comparator: %s", ErrInvalidValue, cmp)
+		}
+		for _, entry := range rt.Values {
+			if entry.Key == nil || !isValueOfKind(fd.MapKey(), entry.Key.Value) {
+				return fmt.Errorf("%w: invalid key of the map field: %s", ErrInvalidValue, left.fd.Name())
+			}
+			ev, ok := entry.Value.(*expr.ValueExpr)
+			if !ok {
+				return fmt.Errorf("%w: unexpected map value type %T", ErrInvalidAST, entry.Value)
+			}
+			if ev.Value == nil || !isValueOfKind(fd.MapValue(), ev.Value) {
+				return fmt.Errorf("%w: invalid value of the map field: %s", ErrInvalidValue, left.fd.Name())
+			}
+		}
+	case *expr.StringSearchExpr:
+		if cmp != expr.EQ && cmp != expr.IN {
+			return fmt.Errorf("%w: cannot compare a string search expression with a comparator: %s", ErrInvalidValue, cmp)
+		}
+		if fd.Kind() != protoreflect.StringKind || isRepeated {
+			return fmt.Errorf("%w: cannot compare a field: %s with a string search expression", ErrInvalidValue, left.fd.Name())
+		}
+		if left.fi.NoTextSearch {
+			return fmt.Errorf("%w: cannot compare a field: %s with a string search expression", ErrInvalidValue, left.fd.Name())
+		}
+	case *expr.FieldSelectorExpr:
+		right, err := v.validateSelector(rt)
+		if err != nil {
+			return err
+		}
+		return v.validateSelectorsComparable(left, right, cmp)
+	case *expr.AnyElementExpr:
+		if cmp != expr.HAS || left.isMapKey || !isRepeatedMessage(left.fd, left.fi) {
+			return fmt.Errorf("%w: any element expression requires a repeated message field with a comparator: %s", ErrInvalidValue, expr.HAS)
+		}
+		if rt.Message != "" && rt.Message != left.fd.Message().FullName() {
+			return fmt.Errorf("%w: any element expression message: %s doesn't match the field: %s", ErrInvalidValue, rt.Message, left.fd.Name())
+		}
+		if rt.Filter == nil {
+			return nil
+		}
+		ev := exprValidator{desc: left.fd.Message(), msgInfo: v.msgInfo, kindPolicy: v.kindPolicy}
+		return ev.validateFilter(rt.Filter)
+	case *expr.FunctionCallExpr:
+		// Function call results are resolved by the caller.
+	case *expr.BinaryExpr:
+		if cmp == expr.HAS || cmp == expr.IN {
+			return fmt.Errorf("%w: cannot compare an arithmetic expression with a comparator: %s", ErrInvalidValue, cmp)
+		}
+		if isRepeated || (fd.IsMap() && !left.isMapKey) {
+			return fmt.Errorf("%w: cannot compare a repeated field: %s with an arithmetic expression", ErrInvalidValue, left.fd.Name())
+		}
+		return v.validateArithmetic(fd, rt)
+	default:
+		return fmt.Errorf("%w: the right hand side is not a valid value type: %T", ErrInvalidAST, x.Right)
+	}
+	return nil
+}
+
+// validateCustomCompare validates the comparison with a registered custom comparator, see the CustomComparatorOpt.
+// The kinds allowed by the comparator declaration are only known to the interpreter,
+// thus the field is only checked to have a single value matching the compared one.
+func (v *exprValidator) validateCustomCompare(x *expr.CompareExpr) error {
+	fs, ok := x.Left.(*expr.FieldSelectorExpr)
+	if !ok {
+		return fmt.Errorf("%w: the left hand side is not a valid selector: %T", ErrInvalidAST, x.Left)
+	}
+	left, err := v.validateSelector(fs)
+	if err != nil {
+		return err
+	}
+	if !isSingularField(left.fd, left.isMapKey) {
+		return fmt.Errorf("%w: comparator: %s is not allowed for the field: %s", ErrInvalidValue, x.Comparator, left.fd.Name())
+	}
+	ve, ok := x.Right.(*expr.ValueExpr)
+	if !ok {
+		return fmt.Errorf("%w: comparator: %s requires a single value, but got: %T", ErrInvalidValue, x.Comparator, x.Right)
+	}
+	fd := left.fd
+	if left.isMapKey {
+		fd = fd.MapValue()
+	}
+	if ve.Value == nil {
+		if !left.fi.Nullable {
+			return fmt.Errorf("%w: field: %s is not nullable", ErrInvalidValue, left.fd.Name())
+		}
+		return nil
+	}
+	if !isValueOfKind(fd, ve.Value) {
+		return fmt.Errorf("%w: value of type %T doesn't match the field: %s", ErrInvalidValue, ve.Value, left.fd.Name())
+	}
+	return nil
+}
+
+// validateArithmetic validates the operands of the arithmetic expression x, resulting in a value of the field fd.
+func (v *exprValidator) validateArithmetic(fd FieldDescriptor, x *expr.BinaryExpr) error {
+	lfd, rfd, ok := arithmeticOperands(fd, x.Op)
+	if !ok {
+		return fmt.Errorf("%w: operator %s is not supported for the value of %s type", ErrInvalidValue, x.Op, valueTypeName(fd))
+	}
+	if err := v.validateArithmeticOperand(lfd, x.Left); err != nil {
+		return err
+	}
+	return v.validateArithmeticOperand(rfd, x.Right)
+}
+
+func (v *exprValidator) validateArithmeticOperand(fd FieldDescriptor, x expr.FilterExpr) error {
+	switch ot := x.(type) {
+	case *expr.BinaryExpr:
+		return v.validateArithmetic(fd, ot)
+	case *expr.ValueExpr:
+		if ot.Value == nil || !isValueOfKind(fd, ot.Value) {
+			return fmt.Errorf("%w: arithmetic operand of type %T is not of %s type", ErrInvalidValue, ot.Value, valueTypeName(fd))
+		}
+	case *expr.FieldSelectorExpr:
+		sel, err := v.validateSelector(ot)
+		if err != nil {
+			return err
+		}
+		sfd := sel.fd
+		if sel.isMapKey {
+			sfd = sfd.MapValue()
+		}
+		if (!sel.isMapKey && (sfd.IsList() || sfd.IsMap())) || !isSameValueType(v.kindPolicy, fd, sfd) {
+			return fmt.Errorf("%w: arithmetic operand: %s is not of %s type", ErrInvalidValue, sfd.Name(), valueTypeName(fd))
+		}
+	case *expr.FunctionCallExpr:
+		// Function call results are resolved by the caller.
+	default:
+		return fmt.Errorf("%w: the arithmetic operand is not a valid value type: %T", ErrInvalidAST, x)
+	}
+	return nil
+}
+
+func (v *exprValidator) validateSelectorsComparable(left, right validatedSelector, cmp expr.Comparator) error {
+	if left.fd.FullName() == right.fd.FullName() && left.isMapKey == right.isMapKey {
+		return fmt.Errorf("%w: the right hand side is ambiguous: %s", ErrAmbiguousField, right.fd.Name())
+	}
+
+	lf, rf := left.fd, right.fd
+	var leftIsMap bool
+	switch {
+	case left.isMapKey:
+		lf = lf.MapValue()
+	case right.isMapKey:
+		rf = rf.MapValue()
+	case lf.IsMap():
+		leftIsMap = true
+		lf = lf.MapKey()
+	case rf.IsMap():
+		rf = rf.MapKey()
+	}
+
+	if !isSameValueType(v.kindPolicy, lf, rf) {
+		return fmt.Errorf("%w: the right hand side type of the restriction doesn't match the left hand side type: %s", ErrInvalidValue, right.fd.Name())
+	}
+
+	lRepeated := lf.Cardinality() == protoreflect.Repeated && !lf.IsMap()
+	rRepeated := rf.Cardinality() == protoreflect.Repeated && !rf.IsMap()
+	if cmp == expr.HAS && !leftIsMap && !lRepeated {
+		return fmt.Errorf("%w: operator HAS (':') can only be used on map or repeated fields", ErrInvalidValue)
+	}
+	if lRepeated && !rRepeated && cmp != expr.HAS {
+		return fmt.Errorf("%w: the right hand side type of the restriction doesn't match the left hand side type: %s", ErrInvalidValue, right.fd.Name())
+	}
+	if rRepeated && !lRepeated && !leftIsMap && cmp != expr.IN {
+		return fmt.Errorf("%w: cannot compare a repeated field: %s with a non-repeated field: %s with a comparator: %s", ErrInvalidValue, rf.FullName(), lf.Name(), cmp)
+	}
+	return nil
+}
+
+// isValueOfKind checks if the Go value v matches the standard value type produced by the interpreter
+// for the field fd, as described in the expr.ValueExpr documentation.
+func isValueOfKind(fd FieldDescriptor, v any) bool {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		_, ok := v.(bool)
+		return ok
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		switch v.(type) {
+		case int64, int32, int:
+			return true
+		}
+		return false
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		switch v.(type) {
+		case uint64, uint32, uint:
+			return true
+		}
+		return false
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch v.(type) {
+		case float64, float32:
+			return true
+		}
+		return false
+	case protoreflect.StringKind:
+		switch v.(type) {
+		case string, [16]byte:
+			// The [16]byte is a value of the UUID fields.
+			return true
+		}
+		return false
+	case protoreflect.BytesKind:
+		_, ok := v.([]byte)
+		return ok
+	case protoreflect.EnumKind:
+		en, ok := v.(protoreflect.EnumNumber)
+		if !ok {
+			return false
+		}
+		return fd.Enum().Values().ByNumber(en) != nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			_, ok := v.(time.Time)
+			return ok
+		case "google.protobuf.Duration":
+			_, ok := v.(time.Duration)
+			return ok
+		case "google.protobuf.Struct":
+			// Struct values may be composed of any JSON-like value.
+			return true
+		case "google.type.Decimal":
+			if _, ok := v.(*big.Rat); ok {
+				return true
+			}
+		}
+		if vd := wrapperValueField(fd.Message()); vd != nil && isValueOfKind(vd, v) {
+			// The wrapper fields are compared with the values of the wrapped kind.
+			return true
+		}
+		switch vt := v.(type) {
+		case protoreflect.Message:
+			return vt.Descriptor().FullName() == fd.Message().FullName()
+		case proto.Message:
+			return vt.ProtoReflect().Descriptor().FullName() == fd.Message().FullName()
+		}
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestValidateExpr_Parsed(t *testing.T) {
+	filters := []string{
+		`name = "foo"`,
+		`name = "foo*"`,
+		`i32 > 10 AND (u64 < 5 OR NOT bool = true)`,
+		`enum = "ONE"`,
+		`rp_str:"foo"`,
+		`map_str_i32 = map{"test": 1, "test2": 2}`,
+		`map_str_i32:"key"`,
+		`i64 IN [1, 2, 3]`,
+		`timestamp > 2021-01-01T00:00:00Z`,
+		`duration = 10s`,
+		`sub.name = "foo"`,
+		`i32 = i64`,
+		`str_optional = null`,
+		`sub:*`,
+		`str_optional:*`,
+	}
+
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	for _, f := range filters {
+		t.Run(f, func(t *testing.T) {
+			x, err := i.Parse(f)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected parsed expression to be valid, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateExpr_Composed(t *testing.T) {
+	c := expr.Composer{Desc: md}
+
+	missing := expr.AcquireFieldSelectorExpr()
+	missing.Message = md.FullName()
+	missing.Field = "missing"
+
+	tc := []struct {
+		name  string
+		x     expr.FilterExpr
+		isErr bool
+		err   error
+	}{
+		{
+			name: "valid string compare",
+			x:    c.Compare(c.MustSelect("name"), expr.EQ, c.Value("foo")),
+		},
+		{
+			name: "valid timestamp compare",
+			x:    c.Compare(c.MustSelect("timestamp"), expr.GT, c.Value(time.Now())),
+		},
+		{
+			name: "valid enum in array",
+			x: c.Compare(c.MustSelect("enum"), expr.IN, c.Array(
+				c.Value(testpb.Enum_ONE.Number()),
+				c.Value(testpb.Enum_TWO.Number()),
+			)),
+		},
+		{
+			name: "valid nested and/or",
+			x: c.And(
+				c.Compare(c.MustSelect("i32"), expr.GE, c.Value(int64(1))),
+				c.Or(
+					c.Compare(c.MustSelect("bool"), expr.EQ, c.Value(true)),
+					c.Not(c.Compare(c.MustSelect("sub.name"), expr.EQ, c.Value("bar"))),
+				),
+			),
+		},
+		{
+			name:  "field not found",
+			x:     c.Compare(missing, expr.EQ, c.Value("foo")),
+			isErr: true,
+			err:   ErrFieldNotFound,
+		},
+		{
+			name:  "value kind mismatch",
+			x:     c.Compare(c.MustSelect("name"), expr.EQ, c.Value(int64(1))),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "unknown enum value",
+			x:     c.Compare(c.MustSelect("enum"), expr.EQ, c.Value(testpb.Enum(100).Number())),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "HAS on non repeated field",
+			x:     c.Compare(c.MustSelect("name"), expr.HAS, c.Value("foo")),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "repeated field with EQ",
+			x:     c.Compare(c.MustSelect("rp_str"), expr.EQ, c.Value("foo")),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "array without IN",
+			x:     c.Compare(c.MustSelect("i64"), expr.EQ, c.Array(c.Value(int64(1)))),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "filtering forbidden",
+			x:     c.Compare(c.MustSelect("no_filter"), expr.EQ, c.Value("foo")),
+			isErr: true,
+			err:   ErrInvalidField,
+		},
+		{
+			name:  "non nullable null",
+			x:     c.Compare(c.MustSelect("i32"), expr.EQ, c.Value(nil)),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "ambiguous selectors",
+			x:     c.Compare(c.MustSelect("i32"), expr.EQ, c.MustSelect("i32")),
+			isErr: true,
+			err:   ErrAmbiguousField,
+		},
+		{
+			name:  "incomparable selectors",
+			x:     c.Compare(c.MustSelect("i32"), expr.EQ, c.MustSelect("name")),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "string search on no text search field",
+			x:     c.Compare(c.MustSelect("no_search"), expr.EQ, &expr.StringSearchExpr{Value: "foo", SuffixWildcard: true}),
+			isErr: true,
+			err:   ErrInvalidValue,
+		},
+		{
+			name:  "invalid comparator",
+			x:     c.Compare(c.MustSelect("name"), expr.Comparator(1000), c.Value("foo")),
+			isErr: true,
+			err:   ErrInvalidAST,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.x.Free()
+
+			err := ValidateExpr(md, tt.x)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Errorf("expected error: %v, got: %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateExpr_RegexMatch(t *testing.T) {
+	i, err := NewInterpreter(md, RegexMatchOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	for _, f := range []string{`str =~ "^foo.*bar$"`, `map_str_str."key" =~ "[a-z]+"`, `NOT name =~ "x"`} {
+		t.Run(f, func(t *testing.T) {
+			x, err := i.Parse(f)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected parsed expression to be valid, got: %v", err)
+			}
+		})
+	}
+
+	c := expr.Composer{Desc: md}
+	regexMatch := func(field, pattern string) *expr.RegexMatchExpr {
+		rm := expr.AcquireRegexMatchExpr()
+		rm.Left = c.MustSelect(field)
+		rm.Pattern = pattern
+		return rm
+	}
+
+	tc := []struct {
+		name string
+		x    expr.FilterExpr
+		err  error
+	}{
+		{name: "non string field", x: regexMatch("i32", "^1"), err: ErrInvalidValue},
+		{name: "repeated string field", x: regexMatch("rp_str", "^a"), err: ErrInvalidValue},
+		{name: "invalid pattern", x: regexMatch("str", "(a"), err: ErrInvalidValue},
+		{name: "filtering forbidden", x: regexMatch("no_filter", "a"), err: ErrInvalidField},
+		{name: "not a selector", x: &expr.RegexMatchExpr{Left: c.Value("a"), Pattern: "a"}, err: ErrInvalidAST},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.x.Free()
+
+			if err := ValidateExpr(md, tt.x); !errors.Is(err, tt.err) {
+				t.Errorf("expected error: %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateExpr_CustomComparator(t *testing.T) {
+	if err := expr.RegisterComparator(testLikeComparator, "~"); err != nil {
+		t.Fatalf("failed to register comparator: %v", err)
+	}
+	c := expr.Composer{Desc: md}
+
+	tc := []struct {
+		name string
+		x    expr.FilterExpr
+		err  error
+	}{
+		{name: "valid", x: c.Compare(c.MustSelect("name"), testLikeComparator, c.Value("%foo"))},
+		{name: "repeated field", x: c.Compare(c.MustSelect("rp_str"), testLikeComparator, c.Value("%foo")), err: ErrInvalidValue},
+		{name: "array value", x: c.Compare(c.MustSelect("name"), testLikeComparator, c.Array(c.Value("%foo"))), err: ErrInvalidValue},
+		{name: "value kind mismatch", x: c.Compare(c.MustSelect("name"), testLikeComparator, c.Value(int64(1))), err: ErrInvalidValue},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.x.Free()
+
+			err := ValidateExpr(md, tt.x)
+			if tt.err == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected error: %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateExpr_MatchAll(t *testing.T) {
+	i, err := NewInterpreter(md, MatchAllOpt())
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+	x, err := i.Parse("")
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+	defer x.Free()
+
+	if _, ok := x.(*expr.MatchAllExpr); !ok {
+		t.Fatalf("expected match all expression but got %T", x)
+	}
+	if err = ValidateExpr(md, x); err != nil {
+		t.Errorf("expected parsed expression to be valid, got: %v", err)
+	}
+
+	c := expr.Composer{Desc: md}
+	and := c.And(expr.AcquireMatchAllExpr(), c.Compare(c.MustSelect("name"), expr.EQ, c.Value("foo")))
+	defer and.Free()
+	if err = ValidateExpr(md, and); err != nil {
+		t.Errorf("expected composed expression to be valid, got: %v", err)
+	}
+}
+
+func TestValidateExpr_InterpreterNodes(t *testing.T) {
+	i, err := NewInterpreter(md,
+		RegexMatchOpt(),
+		BetweenOpt(),
+		CollectionFunctionsOpt(),
+		SequenceOpt(nil),
+		SearchableFieldsOpt("name", "sub.str"),
+		MacroOpt("is:active", `bool = true AND i32 > 0`),
+		CustomComparatorOpt(ComparatorDeclaration{Symbol: "~", Comparator: testLikeComparator, Kinds: []protoreflect.Kind{protoreflect.StringKind}}),
+		MatchAllOpt(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter, query string
+	}{
+		{filter: ``},
+		{filter: `name = "foo" AND (i32 > 1 OR NOT bool = true)`},
+		{filter: `i32 = 1 str = "foo"`},
+		{filter: `name = "foo*"`},
+		{filter: `i64 IN [1, 2]`},
+		{filter: `map_str_i32 = map{"a": 1}`},
+		{filter: `map_str_str."key" = str`},
+		{filter: `sub:*`},
+		{filter: `str =~ "^foo"`},
+		{filter: `str ~ "%foo"`},
+		{filter: `range.Between(i32, 1, 10)`},
+		{filter: `size(rp_i32) > 2`},
+		{filter: `any(rp_sub, i32 > 2)`},
+		{filter: `rp_sub:{name: "abc"}`},
+		{filter: `i64 > i32 + 2 * 3`},
+		{filter: `is:active`},
+		{filter: `i32 = 1`, query: `foo "bar baz"`},
+	}
+
+	seen := make(map[string]bool)
+	for _, tt := range tc {
+		t.Run(tt.filter+tt.query, func(t *testing.T) {
+			x, err := i.ParseWithSearch(tt.filter, tt.query)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %v", err)
+			}
+			defer x.Free()
+
+			collectExprTypes(x, seen)
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected parsed expression to be valid, got: %v", err)
+			}
+		})
+	}
+
+	// The node types the interpreter emits, see the expr.Acquire calls of the package.
+	emitted := []expr.Expr{
+		new(expr.AndExpr), new(expr.OrExpr), new(expr.NotExpr), new(expr.CompositeExpr), new(expr.SequenceExpr),
+		new(expr.CompareExpr), new(expr.PresenceExpr), new(expr.RegexMatchExpr), new(expr.MatchAllExpr),
+		new(expr.SearchExpr), new(expr.AnyElementExpr), new(expr.BinaryExpr), new(expr.FunctionCallExpr),
+		new(expr.ArrayExpr), new(expr.MapValueExpr), new(expr.StringSearchExpr), new(expr.FieldSelectorExpr),
+		new(expr.MapKeyExpr), new(expr.ValueExpr),
+	}
+	for _, e := range emitted {
+		if name := fmt.Sprintf("%T", e); !seen[name] {
+			t.Errorf("expected the filters to cover the %s", name)
+		}
+	}
+}
+
+// collectExprTypes marks the types of the expression x and its descendants as seen.
+func collectExprTypes(x expr.Expr, seen map[string]bool) {
+	if x == nil {
+		return
+	}
+	if rv := reflect.ValueOf(x); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return
+	}
+	seen[fmt.Sprintf("%T", x)] = true
+
+	var children []expr.Expr
+	switch xt := x.(type) {
+	case *expr.AndExpr:
+		for _, e := range xt.Expr {
+			children = append(children, e)
+		}
+	case *expr.OrExpr:
+		for _, e := range xt.Expr {
+			children = append(children, e)
+		}
+	case *expr.SequenceExpr:
+		for _, e := range xt.Factors {
+			children = append(children, e)
+		}
+	case *expr.NotExpr:
+		children = append(children, xt.Expr)
+	case *expr.CompositeExpr:
+		children = append(children, xt.Expr)
+	case *expr.SearchExpr:
+		children = append(children, xt.Expr)
+	case *expr.CompareExpr:
+		children = append(children, xt.Left, xt.Right)
+	case *expr.PresenceExpr:
+		children = append(children, xt.Field)
+	case *expr.RegexMatchExpr:
+		children = append(children, xt.Left)
+	case *expr.AnyElementExpr:
+		children = append(children, xt.Filter)
+	case *expr.BinaryExpr:
+		children = append(children, xt.Left, xt.Right)
+	case *expr.FunctionCallExpr:
+		for _, e := range xt.Arguments {
+			children = append(children, e)
+		}
+	case *expr.ArrayExpr:
+		for _, e := range xt.Elements {
+			children = append(children, e)
+		}
+	case *expr.MapValueExpr:
+		for _, e := range xt.Values {
+			children = append(children, e.Key, e.Value)
+		}
+	case *expr.FieldSelectorExpr:
+		children = append(children, xt.Traversal)
+	case *expr.MapKeyExpr:
+		children = append(children, xt.Key, xt.Traversal)
+	}
+	for _, c := range children {
+		collectExprTypes(c, seen)
+	}
+}
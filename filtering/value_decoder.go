@@ -0,0 +1,130 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// ValueDecoder decodes the literal values of the message typed fields, i.e. '2023-01-02' of the google.type.Date field,
+// into the Go values set in the expr.ValueExpr.
+// The literal is either the unquoted string literal, or the text literal joined with its dot separated fields, i.e. '10.5'.
+// The decoded value should be a proto.Message of the field message type, so that the expression passes the ValidateExpr,
+// but it could be any custom type understood by the expression consumer.
+// A returned error is reported as the ErrInvalidValue with the error message.
+type ValueDecoder interface {
+	DecodeValue(md protoreflect.MessageDescriptor, literal string) (any, error)
+}
+
+// ValueDecoderFunc is a function that implements the ValueDecoder interface.
+type ValueDecoderFunc func(md protoreflect.MessageDescriptor, literal string) (any, error)
+
+// DecodeValue implements the ValueDecoder interface.
+func (fn ValueDecoderFunc) DecodeValue(md protoreflect.MessageDescriptor, literal string) (any, error) {
+	return fn(md, literal)
+}
+
+// RegisterValueDecoder is an Option that registers the decoder of the literal values of the message with given full name,
+// i.e.: google.type.Date, google.type.Money or google.protobuf.FieldMask.
+// A registered decoder takes precedence over the standard handling of the google.protobuf.Timestamp,
+// google.protobuf.Duration and google.protobuf.Struct messages.
+// The struct values of the message, i.e.: 'google.type.Date{year: 2023}' are still parsed as the message values.
+func RegisterValueDecoder(name protoreflect.FullName, dec ValueDecoder) Option {
+	return func(i *Interpreter) error {
+		if dec == nil {
+			return errors.New("value decoder is nil")
+		}
+		if !name.IsValid() {
+			return fmt.Errorf("invalid message name: %q", name)
+		}
+		if i.valueDecoders == nil {
+			i.valueDecoders = make(map[protoreflect.FullName]ValueDecoder)
+		}
+		if _, ok := i.valueDecoders[name]; ok {
+			return fmt.Errorf("value decoder for %q is already registered", name)
+		}
+		i.valueDecoders[name] = dec
+		return nil
+	}
+}
+
+// TryParseDecodedValueField tries parsing the value of the message field with the registered ValueDecoder.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseDecodedValueField(ctx *ParseContext, in TryParseValueInput, dec ValueDecoder) (TryParseValueResult, error) {
+	var literal string
+	switch ft := in.Value.(type) {
+	case *ast.StringLiteral:
+		if len(in.Args) > 0 {
+			var res TryParseValueResult
+			if ctx.ErrHandler != nil {
+				res.ErrPos = ft.Pos
+				res.ErrMsg = fmt.Sprintf("field is of %q type, but provided value is not valid: '%s'", in.Field.Message().FullName(), joinedName(in.Value, in.Args...))
+			}
+			return res, ErrInvalidValue
+		}
+		literal = ft.Value
+	case *ast.TextLiteral:
+		if in.IsOptional && ft.Token == token.NULL && len(in.Args) == 0 {
+			ve := expr.AcquireValueExpr()
+			ve.Value = nil
+			return TryParseValueResult{Expr: ve}, nil
+		}
+		literal = joinedName(in.Value, in.Args...)
+	case *ast.StructExpr:
+		return b.TryParseMessageStructField(ctx, in)
+	case *ast.ArrayExpr:
+		ae := expr.AcquireArrayExpr()
+		for _, elem := range ft.Elements {
+			res, err := b.TryParseValue(ctx, TryParseValueInput{
+				Field:      in.Field,
+				IsOptional: in.IsOptional,
+				Value:      elem,
+				Complexity: in.Complexity,
+			})
+			if err != nil {
+				ae.Free()
+				return res, err
+			}
+			ae.Elements = append(ae.Elements, res.Expr)
+		}
+		return TryParseValueResult{Expr: ae}, nil
+	default:
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: in.Value.Position(), ErrMsg: "invalid AST node"}, ErrInvalidAST
+		}
+		return TryParseValueResult{}, ErrInvalidAST
+	}
+
+	v, err := dec.DecodeValue(in.Field.Message(), literal)
+	if err != nil {
+		var res TryParseValueResult
+		if ctx.ErrHandler != nil {
+			res.ErrPos = in.Value.Position()
+			res.ErrMsg = fmt.Sprintf("field is of %q type, but provided value is not valid: '%s': %v", in.Field.Message().FullName(), literal, err)
+		}
+		return res, ErrInvalidValue
+	}
+
+	ve := expr.AcquireValueExpr()
+	ve.Value = v
+	return TryParseValueResult{Expr: ve, ArgsUsed: len(in.Args)}, nil
+}
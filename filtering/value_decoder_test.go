@@ -0,0 +1,130 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+)
+
+func TestInterpreter_ValueDecoder(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	i, err := NewInterpreter(md,
+		RegisterValueDecoder("testpb.Message", ValueDecoderFunc(func(_ protoreflect.MessageDescriptor, literal string) (any, error) {
+			if literal == "" {
+				return nil, errors.New("empty name")
+			}
+			return &testpb.Message{Name: literal}, nil
+		})),
+		RegisterValueDecoder("google.protobuf.Timestamp", ValueDecoderFunc(func(_ protoreflect.MessageDescriptor, literal string) (any, error) {
+			if literal != "now" {
+				return nil, errors.New("only now is supported")
+			}
+			return now, nil
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		name   string
+		filter string
+		want   []any
+		err    error
+	}{
+		{name: "string literal", filter: `sub = "foo"`, want: []any{&testpb.Message{Name: "foo"}}},
+		{name: "text literal", filter: `sub = foo`, want: []any{&testpb.Message{Name: "foo"}}},
+		{name: "array", filter: `sub IN ["foo", "bar"]`, want: []any{&testpb.Message{Name: "foo"}, &testpb.Message{Name: "bar"}}},
+		{name: "standard type override", filter: `timestamp > now`, want: []any{now}},
+		{name: "decoder error", filter: `sub = ""`, err: ErrInvalidValue},
+		{name: "override decoder error", filter: `timestamp > 2021-01-01T00:00:00Z`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			var values []expr.FilterExpr
+			switch rt := ce.Right.(type) {
+			case *expr.ArrayExpr:
+				values = rt.Elements
+			default:
+				values = []expr.FilterExpr{rt}
+			}
+			if len(values) != len(tt.want) {
+				t.Fatalf("expected %d values but got %d", len(tt.want), len(values))
+			}
+			for j, v := range values {
+				ve, ok := v.(*expr.ValueExpr)
+				if !ok {
+					t.Fatalf("expected value expression but got %T", v)
+				}
+				switch wt := tt.want[j].(type) {
+				case proto.Message:
+					if got, ok := ve.Value.(proto.Message); !ok || !proto.Equal(got, wt) {
+						t.Errorf("expected value %v but got %v", wt, ve.Value)
+					}
+				default:
+					if ve.Value != wt {
+						t.Errorf("expected value %v but got %v", wt, ve.Value)
+					}
+				}
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Errorf("expected valid expression but got: %v", err)
+			}
+		})
+	}
+
+	t.Run("struct value", func(t *testing.T) {
+		x, err := i.Parse(`sub = testpb.Message{name: "foo"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+		if _, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr).Value.(protoreflect.Message); !ok {
+			t.Errorf("expected message value but got %T", x.(*expr.CompareExpr).Right.(*expr.ValueExpr).Value)
+		}
+	})
+
+	t.Run("duplicated decoder", func(t *testing.T) {
+		dec := ValueDecoderFunc(func(protoreflect.MessageDescriptor, string) (any, error) { return nil, nil })
+		if _, err := NewInterpreter(md, RegisterValueDecoder("testpb.Message", dec), RegisterValueDecoder("testpb.Message", dec)); err == nil {
+			t.Error("expected duplicated decoder error")
+		}
+	})
+}
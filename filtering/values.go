@@ -47,6 +47,10 @@ type TryParseValueInput struct {
 
 	// Complexity defines the complexity of a field.
 	Complexity int64
+
+	// IsLiteral is a flag that indicates whether an unquoted string value
+	// with its dot separated Args should be taken as a single string literal.
+	IsLiteral bool
 }
 
 // TryParseValueResult is a result of the TryParseValue function.
@@ -72,6 +76,13 @@ type TryParseValueResult struct {
 
 // TryParseValue tries to parse a value expression.
 func (b *Interpreter) TryParseValue(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	if b.traceHook != nil && in.Value != nil {
+		return b.traceTryParseValue(ctx, in)
+	}
+	return b.tryParseValue(ctx, in)
+}
+
+func (b *Interpreter) tryParseValue(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
 	if in.Field == nil {
 		// Internal error - no field is defined.
 		if ctx.ErrHandler != nil {
@@ -91,6 +102,15 @@ func (b *Interpreter) TryParseValue(ctx *ParseContext, in TryParseValueInput) (T
 		in.Value = me.Value
 		in.Args = me.Fields
 	}
+	if name, ok := paramName(ctx, in); ok {
+		return b.tryParseParam(ctx, in, name)
+	}
+	if sl, ok := in.Value.(*ast.StringLiteral); ok && sl.Bytes && !acceptsBytesLiteral(in.Field) {
+		if ctx.ErrHandler != nil {
+			return TryParseValueResult{ErrPos: sl.Pos, ErrMsg: fmt.Sprintf("field is of %q type, but provided value is a bytes literal: '%s'", in.Field.Kind(), sl)}, ErrInvalidValue
+		}
+		return TryParseValueResult{}, ErrInvalidValue
+	}
 	switch in.Field.Kind() {
 	case protoreflect.DoubleKind, protoreflect.FloatKind:
 		return b.TryParseFloatField(ctx, in)
@@ -105,6 +125,12 @@ func (b *Interpreter) TryParseValue(ctx *ParseContext, in TryParseValueInput) (T
 	case protoreflect.BoolKind:
 		return b.TryParseBooleanField(ctx, in)
 	case protoreflect.StringKind:
+		if b.isUUIDField(in.Field) {
+			return b.TryParseUUIDField(ctx, in)
+		}
+		if ps, ok := b.resourceNamePatterns(in.Field); ok {
+			return b.TryParseResourceNameField(ctx, in, ps)
+		}
 		return b.TryParseStringField(ctx, in)
 	case protoreflect.BytesKind:
 		return b.TryParseBytesField(ctx, in)
@@ -140,6 +166,14 @@ func (b *Interpreter) TryParseMessageField(ctx *ParseContext, in TryParseValueIn
 		return b.TryParseMapField(ctx, in)
 	}
 
+	if dec, ok := b.valueDecoders[in.Field.Message().FullName()]; ok {
+		return b.TryParseDecodedValueField(ctx, in, dec)
+	}
+
+	if isWrapperMessage(in.Field.Message().FullName()) {
+		return b.TryParseWrapperField(ctx, in)
+	}
+
 	switch in.Field.Message().FullName() {
 	case "google.protobuf.Timestamp":
 		return b.TryParseTimestampField(ctx, in)
@@ -147,6 +181,14 @@ func (b *Interpreter) TryParseMessageField(ctx *ParseContext, in TryParseValueIn
 		return b.TryParseDurationField(ctx, in)
 	case "google.protobuf.Struct":
 		return b.TryParseStructPb(ctx, in)
+	case "google.type.Date":
+		return b.TryParseDateField(ctx, in)
+	case "google.type.TimeOfDay":
+		return b.TryParseTimeOfDayField(ctx, in)
+	case "google.type.Decimal":
+		return b.TryParseDecimalField(ctx, in)
+	case "google.protobuf.FieldMask":
+		return b.TryParseFieldMaskField(ctx, in)
 	default:
 		return b.TryParseMessageStructField(ctx, in)
 	}
@@ -302,39 +344,3 @@ func joinedName(v ast.AnyExpr, args ...ast.FieldExpr) string {
 	}
 	return sb.String()
 }
-
-func isKindComparable(k1, k2 protoreflect.Kind) bool {
-	if k1 == k2 {
-		return true
-	}
-
-	switch k1 {
-	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
-		switch k2 {
-		case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
-			return true
-		case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
-			return true
-		default:
-			return false
-		}
-	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
-		switch k2 {
-		case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
-			return true
-		case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
-			return true
-		default:
-			return false
-		}
-	case protoreflect.FloatKind, protoreflect.DoubleKind:
-		switch k2 {
-		case protoreflect.FloatKind, protoreflect.DoubleKind:
-			return true
-		default:
-			return false
-		}
-	default:
-		return false
-	}
-}
@@ -0,0 +1,75 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/filtering/ast"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// TryParseWrapperField tries parsing a value of the well-known wrapper field, i.e. google.protobuf.Int64Value.
+// The value is a literal of the wrapped scalar, i.e.: int64_value = 5, and is decoded into the value
+// of the wrapped kind, the same as for the scalar field, i.e. the int64.
+// The null literal matches the unset wrapper field, if the field is optional.
+// The message struct, i.e.: google.protobuf.Int64Value{value: 5}, is parsed as any other message value.
+// The result depending on the input, could be a ValueExpr or ArrayExpr.
+func (b *Interpreter) TryParseWrapperField(ctx *ParseContext, in TryParseValueInput) (TryParseValueResult, error) {
+	switch vt := in.Value.(type) {
+	case *ast.StructExpr:
+		return b.TryParseMessageStructField(ctx, in)
+	case *ast.TextLiteral:
+		if in.IsOptional && vt.Token == token.NULL && len(in.Args) == 0 {
+			ve := expr.AcquireValueExpr()
+			ve.Value = nil
+			return TryParseValueResult{Expr: ve}, nil
+		}
+	}
+	vd := wrapperValueField(in.Field.Message())
+	if vd == nil {
+		return b.TryParseMessageStructField(ctx, in)
+	}
+	return b.TryParseValue(ctx, TryParseValueInput{
+		Field:         vd,
+		Value:         in.Value,
+		AllowIndirect: in.AllowIndirect,
+		IsOptional:    in.IsOptional,
+		Args:          in.Args,
+		Complexity:    in.Complexity,
+	})
+}
+
+// isWrapperMessage checks if the message is one of the well-known wrapper types.
+func isWrapperMessage(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// wrapperValueField returns the descriptor of the wrapped value field of the well-known wrapper message,
+// or nil if the md is not a wrapper.
+func wrapperValueField(md protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	if md == nil || !isWrapperMessage(md.FullName()) {
+		return nil
+	}
+	return md.Fields().ByName("value")
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtering
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/blockysource/blocky-aip/expr"
+)
+
+func TestInterpreter_WrapperFields(t *testing.T) {
+	md := testWrapperMessage(t)
+	i, err := NewInterpreter(md)
+	if err != nil {
+		t.Fatalf("failed to create interpreter: %v", err)
+	}
+
+	tc := []struct {
+		filter string
+		want   any
+		cmp    expr.Comparator
+		err    error
+	}{
+		{filter: `i64 = 5`, want: int64(5), cmp: expr.EQ},
+		{filter: `i64 > -5`, want: int64(-5), cmp: expr.GT},
+		{filter: `i32 <= 10`, want: int64(10), cmp: expr.LE},
+		{filter: `u64 != 7`, want: uint64(7), cmp: expr.NE},
+		{filter: `u32 >= 1`, want: uint64(1), cmp: expr.GE},
+		{filter: `double < 1.5`, want: 1.5, cmp: expr.LT},
+		{filter: `float = 2`, want: float64(2), cmp: expr.EQ},
+		{filter: `bool = true`, want: true, cmp: expr.EQ},
+		{filter: `str = "foo"`, want: "foo", cmp: expr.EQ},
+		{filter: `bytes = "Zm9v"`, want: []byte("foo"), cmp: expr.EQ},
+		{filter: `str = null`, want: nil, cmp: expr.EQ},
+		{filter: `i64 = null`, err: ErrInvalidValue},
+		{filter: `i64 = "abc"`, err: ErrInvalidValue},
+		{filter: `bool = 5`, err: ErrInvalidValue},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.filter, func(t *testing.T) {
+			x, err := i.Parse(tt.filter)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer x.Free()
+
+			ce, ok := x.(*expr.CompareExpr)
+			if !ok {
+				t.Fatalf("expected compare expression but got %T", x)
+			}
+			if ce.Comparator != tt.cmp {
+				t.Fatalf("expected comparator %s but got %s", tt.cmp, ce.Comparator)
+			}
+			ve, ok := ce.Right.(*expr.ValueExpr)
+			if !ok {
+				t.Fatalf("expected value expression but got %T", ce.Right)
+			}
+			if !reflect.DeepEqual(ve.Value, tt.want) {
+				t.Fatalf("expected value %v (%T) but got %v (%T)", tt.want, tt.want, ve.Value, ve.Value)
+			}
+			if err = ValidateExpr(md, x); err != nil {
+				t.Fatalf("expected valid expression but got: %v", err)
+			}
+		})
+	}
+
+	t.Run("in", func(t *testing.T) {
+		x, err := i.Parse(`str IN ["a", "b"]`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		ae, ok := x.(*expr.CompareExpr).Right.(*expr.ArrayExpr)
+		if !ok || len(ae.Elements) != 2 {
+			t.Fatalf("expected array of two values but got %v", x.(*expr.CompareExpr).Right)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		x, err := i.Parse(`i64 = google.protobuf.Int64Value{value: 5}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer x.Free()
+
+		if _, ok := x.(*expr.CompareExpr).Right.(*expr.ValueExpr).Value.(protoreflect.Message); !ok {
+			t.Fatalf("expected message value but got %T", x.(*expr.CompareExpr).Right.(*expr.ValueExpr).Value)
+		}
+	})
+
+	t.Run("value selector", func(t *testing.T) {
+		x, err := i.Parse(`i64.value = 5`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x.Free()
+	})
+}
+
+// testWrapperMessage builds a message with the fields of each of the well-known wrapper types.
+func testWrapperMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	optional := new(descriptorpb.FieldOptions)
+	proto.SetExtension(optional, annotations.E_FieldBehavior, []annotations.FieldBehavior{annotations.FieldBehavior_OPTIONAL})
+
+	var fields []*descriptorpb.FieldDescriptorProto
+	for i, f := range []struct{ name, typ string }{
+		{"double", "DoubleValue"},
+		{"float", "FloatValue"},
+		{"i64", "Int64Value"},
+		{"u64", "UInt64Value"},
+		{"i32", "Int32Value"},
+		{"u32", "UInt32Value"},
+		{"bool", "BoolValue"},
+		{"str", "StringValue"},
+		{"bytes", "BytesValue"},
+	} {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(f.name),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".google.protobuf." + f.typ),
+			JsonName: proto.String(f.name),
+		}
+		if f.name == "str" {
+			field.Options = optional
+		}
+		fields = append(fields, field)
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("testwrappers/wrappers.proto"),
+		Package:     proto.String("testwrappers"),
+		Syntax:      proto.String("proto3"),
+		Dependency:  []string{wrapperspb.File_google_protobuf_wrappers_proto.Path(), annotations.File_google_api_field_behavior_proto.Path()},
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Wrappers"), Field: fields}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build test file: %v", err)
+	}
+	return fd.Messages().Get(0)
+}
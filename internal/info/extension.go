@@ -0,0 +1,52 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package info
+
+import (
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// GetExtension gets the value of the extension field of the descriptor options.
+// The options of the descriptors loaded at runtime, i.e. from the file descriptor set unmarshaled
+// without the annotation types, keep the extensions as the unknown fields.
+// In such case the unknown fields are unmarshaled again with the extension type.
+func GetExtension(opts proto.Message, xt protoreflect.ExtensionType) any {
+	if opts == nil || !opts.ProtoReflect().IsValid() || proto.HasExtension(opts, xt) {
+		return proto.GetExtension(opts, xt)
+	}
+	unknown := opts.ProtoReflect().GetUnknown()
+	if len(unknown) == 0 {
+		return proto.GetExtension(opts, xt)
+	}
+
+	var types protoregistry.Types
+	if err := types.RegisterExtension(xt); err != nil {
+		return proto.GetExtension(opts, xt)
+	}
+	resolved := opts.ProtoReflect().New().Interface()
+	if err := (proto.UnmarshalOptions{Resolver: &types}).Unmarshal(unknown, resolved); err != nil {
+		return proto.GetExtension(opts, xt)
+	}
+	return proto.GetExtension(resolved, xt)
+}
+
+// FieldBehaviors returns the google.api.field_behavior annotations of the field.
+func FieldBehaviors(fd protoreflect.FieldDescriptor) ([]annotations.FieldBehavior, bool) {
+	fb, ok := GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	return fb, ok && len(fb) > 0
+}
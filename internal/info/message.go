@@ -16,7 +16,6 @@ package info
 
 import (
 	"google.golang.org/genproto/googleapis/api/annotations"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/blockysource/go-genproto/blocky/api/annotations"
@@ -101,7 +100,7 @@ type MessagesInfo []*MessageInfo
 // GetFieldInfo returns the field info for the given field descriptor.
 func (mi MessagesInfo) GetFieldInfo(fd protoreflect.FieldDescriptor) FieldInfo {
 	for _, m := range mi {
-		if m.Desc == fd.Parent() {
+		if m.Desc == fd.ContainingMessage() {
 			for _, f := range m.Fields {
 				if f.Desc == fd {
 					return f
@@ -146,46 +145,7 @@ func (b *mapper) mapMessage(msg protoreflect.MessageDescriptor) {
 
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
-		fi := FieldInfo{
-			Desc:               fd,
-			Complexity:         getFieldComplexity(fd),
-			FilteringForbidden: isFieldFilteringForbidden(fd),
-			OrderingForbidden:  isFieldOrderingForbidden(fd),
-			Nullable:           isFieldOptional(fd),
-			NonTraversal:       isFieldNonTraversal(fd),
-			NoTextSearch:       isFieldNoTextSearch(fd),
-		}
-
-		fb, ok := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
-		if ok {
-			for _, b := range fb {
-				switch b {
-				case annotations.FieldBehavior_INPUT_ONLY:
-					fi.InputOnly = true
-				case annotations.FieldBehavior_OUTPUT_ONLY:
-					fi.OutputOnly = true
-				case annotations.FieldBehavior_REQUIRED:
-					fi.Required = true
-				case annotations.FieldBehavior_IMMUTABLE:
-					fi.Immutable = true
-				case annotations.FieldBehavior_NON_EMPTY_DEFAULT:
-					fi.NonEmptyDefault = true
-				}
-			}
-		}
-
-		if fd.Kind() == protoreflect.MessageKind {
-			switch fd.Message().FullName() {
-			case "google.protobuf.Timestamp":
-				fi.IsTimestamp = true
-			case "google.protobuf.Duration":
-				fi.IsDuration = true
-			case "google.protobuf.Struct":
-				fi.IsStructpb = true
-			}
-		}
-
-		mi.Fields = append(mi.Fields, fi)
+		mi.Fields = append(mi.Fields, newFieldInfo(fd))
 
 		if fd.Kind() == protoreflect.MessageKind {
 			if fd.IsMap() {
@@ -225,7 +185,7 @@ func (b *mapper) mapMessage(msg protoreflect.MessageDescriptor) {
 				NoTextSearch:       isFieldNoTextSearch(fd),
 			}
 
-			fb, ok := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+			fb, ok := FieldBehaviors(fd)
 			if ok {
 				for _, b := range fb {
 					switch b {
@@ -267,8 +227,74 @@ func (b *mapper) mapMessage(msg protoreflect.MessageDescriptor) {
 	}
 }
 
+// MapMessage maps the message descriptor along with the messages of its fields, if it is not mapped yet.
+func (mi *MessagesInfo) MapMessage(md protoreflect.MessageDescriptor) {
+	b := mapper{msgInfo: *mi}
+	b.mapMessage(md)
+	*mi = b.msgInfo
+}
+
+// MapExtension maps the extension field within the info of its containing message.
+// The containing message must already be mapped.
+func (mi *MessagesInfo) MapExtension(xd protoreflect.ExtensionDescriptor) {
+	m := mi.MessageInfo(xd.ContainingMessage())
+	for _, f := range m.Fields {
+		if f.Desc == xd {
+			return
+		}
+	}
+	m.Fields = append(m.Fields, newFieldInfo(xd))
+
+	if xd.Kind() == protoreflect.MessageKind {
+		mi.MapMessage(xd.Message())
+	}
+}
+
+// newFieldInfo creates the info of the field descriptor, out of its annotations and type.
+func newFieldInfo(fd protoreflect.FieldDescriptor) FieldInfo {
+	fi := FieldInfo{
+		Desc:               fd,
+		Complexity:         getFieldComplexity(fd),
+		FilteringForbidden: isFieldFilteringForbidden(fd),
+		OrderingForbidden:  isFieldOrderingForbidden(fd),
+		Nullable:           isFieldOptional(fd),
+		NonTraversal:       isFieldNonTraversal(fd),
+		NoTextSearch:       isFieldNoTextSearch(fd),
+	}
+
+	fb, ok := FieldBehaviors(fd)
+	if ok {
+		for _, b := range fb {
+			switch b {
+			case annotations.FieldBehavior_INPUT_ONLY:
+				fi.InputOnly = true
+			case annotations.FieldBehavior_OUTPUT_ONLY:
+				fi.OutputOnly = true
+			case annotations.FieldBehavior_REQUIRED:
+				fi.Required = true
+			case annotations.FieldBehavior_IMMUTABLE:
+				fi.Immutable = true
+			case annotations.FieldBehavior_NON_EMPTY_DEFAULT:
+				fi.NonEmptyDefault = true
+			}
+		}
+	}
+
+	if fd.Kind() == protoreflect.MessageKind {
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			fi.IsTimestamp = true
+		case "google.protobuf.Duration":
+			fi.IsDuration = true
+		case "google.protobuf.Struct":
+			fi.IsStructpb = true
+		}
+	}
+	return fi
+}
+
 func getFieldComplexity(fdt protoreflect.FieldDescriptor) int64 {
-	c, ok := proto.GetExtension(fdt.Options(), annotationspb.E_Complexity).(int64)
+	c, ok := GetExtension(fdt.Options(), annotationspb.E_Complexity).(int64)
 	if !ok || c == 0 {
 		return 1
 	}
@@ -277,7 +303,7 @@ func getFieldComplexity(fdt protoreflect.FieldDescriptor) int64 {
 
 // isFieldFilteringForbidden returns true if the field filtering is forbidden.
 func isFieldFilteringForbidden(field protoreflect.FieldDescriptor) bool {
-	opts, ok := proto.GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
+	opts, ok := GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
 	if !ok {
 		return false
 	}
@@ -291,7 +317,7 @@ func isFieldFilteringForbidden(field protoreflect.FieldDescriptor) bool {
 
 // isFieldOrderingForbidden returns true if the field filtering is forbidden.
 func isFieldOrderingForbidden(field protoreflect.FieldDescriptor) bool {
-	opts, ok := proto.GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
+	opts, ok := GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
 	if !ok {
 		return false
 	}
@@ -305,7 +331,7 @@ func isFieldOrderingForbidden(field protoreflect.FieldDescriptor) bool {
 
 // isFieldNonTraversal returns true if the field is non-traversal.
 func isFieldNonTraversal(field protoreflect.FieldDescriptor) bool {
-	opts, ok := proto.GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
+	opts, ok := GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
 	if !ok {
 		return false
 	}
@@ -319,7 +345,7 @@ func isFieldNonTraversal(field protoreflect.FieldDescriptor) bool {
 
 // isFieldNoTextSearch returns true if the field is no text search.
 func isFieldNoTextSearch(field protoreflect.FieldDescriptor) bool {
-	opts, ok := proto.GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
+	opts, ok := GetExtension(field.Options(), annotationspb.E_QueryOpt).([]annotationspb.FieldQueryOption)
 	if !ok {
 		return false
 	}
@@ -333,7 +359,7 @@ func isFieldNoTextSearch(field protoreflect.FieldDescriptor) bool {
 
 // isFieldOptional checks if the input field is nullable.
 func isFieldOptional(field protoreflect.FieldDescriptor) bool {
-	fb, ok := proto.GetExtension(field.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	fb, ok := FieldBehaviors(field)
 	if !ok {
 		return false
 	}
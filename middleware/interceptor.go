@@ -0,0 +1,386 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/expr"
+	"github.com/blockysource/blocky-aip/fieldmask"
+	"github.com/blockysource/blocky-aip/filtering"
+	"github.com/blockysource/blocky-aip/ordering"
+	"github.com/blockysource/blocky-aip/pagination"
+)
+
+// Names of the standard request fields, recognized by the Interceptor.
+const (
+	filterField     protoreflect.Name = "filter"
+	orderByField    protoreflect.Name = "order_by"
+	pageTokenField  protoreflect.Name = "page_token"
+	updateMaskField protoreflect.Name = "update_mask"
+)
+
+// Parsed contains the parsed standard fields of a request, attached to the handler context by the Interceptor.
+// The expressions are released once the handler returns, thus the handler needs to Clone them to retain them longer.
+type Parsed struct {
+	// Filter is the parsed filter field of a list request, which is nil if the filter is empty.
+	Filter expr.FilterExpr
+
+	// OrderBy is the parsed order_by field of a list request, which is nil if the order_by is empty.
+	OrderBy *expr.OrderByExpr
+
+	// PageToken is the validated page_token field of a list request.
+	PageToken string
+
+	// Update is the parsed update_mask field of an update request, with the values taken from the resource field.
+	// It is nil if the update mask is not set.
+	Update *expr.UpdateExpr
+}
+
+// Free releases the parsed expressions.
+func (p *Parsed) Free() {
+	if p == nil {
+		return
+	}
+	if p.Filter != nil {
+		p.Filter.Free()
+		p.Filter = nil
+	}
+	if p.OrderBy != nil {
+		p.OrderBy.Free()
+		p.OrderBy = nil
+	}
+	if p.Update != nil {
+		p.Update.Free()
+		p.Update = nil
+	}
+}
+
+type parsedKey struct{}
+
+// NewContext returns a copy of the ctx with the parsed request fields attached.
+func NewContext(ctx context.Context, p *Parsed) context.Context {
+	return context.WithValue(ctx, parsedKey{}, p)
+}
+
+// FromContext returns the parsed request fields attached to the ctx by the Interceptor.
+func FromContext(ctx context.Context) (*Parsed, bool) {
+	p, ok := ctx.Value(parsedKey{}).(*Parsed)
+	return p, ok
+}
+
+// Option is an option function of the Interceptor.
+type Option func(i *Interceptor) error
+
+// ListRequestOpt registers the list request message req, which filter and order_by fields
+// are parsed against the resource message descriptor.
+// The filtering options opts are used to create the interpreter of the filter.
+func ListRequestOpt(req, resource protoreflect.MessageDescriptor, opts ...filtering.Option) Option {
+	return func(i *Interceptor) error {
+		if req == nil || resource == nil {
+			return errors.New("list request and resource descriptors must be set")
+		}
+		if _, ok := i.lists[req.FullName()]; ok {
+			return fmt.Errorf("list request %s is already registered", req.FullName())
+		}
+		i.lists[req.FullName()] = &listRequest{resource: resource, filteringOpts: opts}
+		return nil
+	}
+}
+
+// OrderingOpt sets the options of the order_by parsers of all the list requests.
+func OrderingOpt(opts ...ordering.ParserOpt) Option {
+	return func(i *Interceptor) error {
+		i.orderingOpts = append(i.orderingOpts, opts...)
+		return nil
+	}
+}
+
+// FieldmaskOpt sets the options of the update_mask parsers of all the update requests.
+func FieldmaskOpt(opts ...fieldmask.OptionFn) Option {
+	return func(i *Interceptor) error {
+		i.fieldmaskOpts = append(i.fieldmaskOpts, opts...)
+		return nil
+	}
+}
+
+// PageTokenOpt enables the validation of the page_token fields with the token validator v.
+// Without it, the page token is passed to the handler as it is.
+func PageTokenOpt(v *pagination.TokenValidator) Option {
+	return func(i *Interceptor) error {
+		if v == nil {
+			return errors.New("token validator is not set")
+		}
+		i.tokens = v
+		return nil
+	}
+}
+
+// Interceptor parses the standard AIP fields of the request messages, detected with the protobuf reflection:
+//   - filter and order_by of the list requests registered with the ListRequestOpt,
+//   - page_token of any request, validated if the PageTokenOpt is set,
+//   - update_mask of any request, which contains a singular resource message field, i.e.: UpdateBookRequest.book.
+//
+// The parse errors are returned as the codes.InvalidArgument status with the errdetails.BadRequest field violations.
+// It is safe for concurrent use by multiple goroutines.
+type Interceptor struct {
+	lists         map[protoreflect.FullName]*listRequest
+	orderingOpts  []ordering.ParserOpt
+	fieldmaskOpts []fieldmask.OptionFn
+	tokens        *pagination.TokenValidator
+
+	// requests caches the *requestFields of the request messages.
+	requests sync.Map
+	// updates caches the *fieldmask.Parser of the resource messages.
+	updates sync.Map
+}
+
+type listRequest struct {
+	resource      protoreflect.MessageDescriptor
+	filteringOpts []filtering.Option
+	interpreter   *filtering.Interpreter
+	ordering      *ordering.Parser
+}
+
+// requestFields are the standard fields of a request message.
+type requestFields struct {
+	filter, orderBy, pageToken protoreflect.FieldDescriptor
+	updateMask, resource       protoreflect.FieldDescriptor
+}
+
+// NewInterceptor creates a new interceptor with given options.
+func NewInterceptor(opts ...Option) (*Interceptor, error) {
+	i := &Interceptor{lists: make(map[protoreflect.FullName]*listRequest)}
+	for _, opt := range opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, lr := range i.lists {
+		var err error
+		lr.interpreter, err = filtering.NewInterpreter(lr.resource, lr.filteringOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create filter interpreter of %s: %w", name, err)
+		}
+		lr.ordering, err = ordering.NewParser(lr.resource, i.orderingOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create order by parser of %s: %w", name, err)
+		}
+	}
+	return i, nil
+}
+
+// Unary is a grpc.UnaryServerInterceptor, which parses the request fields and attaches them to the handler context.
+// Use it with the grpc.UnaryInterceptor or grpc.ChainUnaryInterceptor server options.
+func (i *Interceptor) Unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+	p, err := i.Parse(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Free()
+	return handler(NewContext(ctx, p), req)
+}
+
+// Parse parses the standard fields of the request message.
+// The returned error is a gRPC status error. The caller is responsible for freeing the result.
+func (i *Interceptor) Parse(ctx context.Context, req proto.Message) (*Parsed, error) {
+	m := req.ProtoReflect()
+	rf := i.requestFields(m.Descriptor())
+
+	p := &Parsed{}
+	if lr, ok := i.lists[m.Descriptor().FullName()]; ok {
+		if err := i.parseList(p, lr, rf, m); err != nil {
+			p.Free()
+			return nil, err
+		}
+	}
+	if rf.pageToken != nil {
+		p.PageToken = m.Get(rf.pageToken).String()
+		if p.PageToken != "" && i.tokens != nil {
+			if err := i.tokens.Validate(ctx, p.PageToken); err != nil {
+				p.Free()
+				if errors.Is(err, pagination.ErrInvalidToken) {
+					return nil, invalidArgument(string(pageTokenField), err.Error())
+				}
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+	}
+	if rf.updateMask != nil && rf.resource != nil && m.Has(rf.updateMask) {
+		if err := i.parseUpdate(p, rf, m); err != nil {
+			p.Free()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (i *Interceptor) parseList(p *Parsed, lr *listRequest, rf *requestFields, m protoreflect.Message) error {
+	if rf.filter != nil {
+		x, err := lr.interpreter.Parse(m.Get(rf.filter).String())
+		if err != nil {
+			var fe *filtering.FilterError
+			if errors.As(err, &fe) {
+				return fe.GRPCStatus().Err()
+			}
+			return invalidArgument(string(filterField), err.Error())
+		}
+		p.Filter = x
+	}
+	if rf.orderBy != nil {
+		if orderBy := m.Get(rf.orderBy).String(); orderBy != "" {
+			o, err := lr.ordering.Parse(orderBy)
+			if err != nil {
+				if errors.Is(err, ordering.ErrInternalError) {
+					return status.Error(codes.Internal, err.Error())
+				}
+				return invalidArgument(string(orderByField), err.Error())
+			}
+			p.OrderBy = o
+		}
+	}
+	return nil
+}
+
+func (i *Interceptor) parseUpdate(p *Parsed, rf *requestFields, m protoreflect.Message) error {
+	mask := fieldMaskOf(m.Get(rf.updateMask).Message())
+	resource := m.Get(rf.resource).Message()
+
+	fp, err := i.fieldmaskParser(resource.Interface())
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	ue, err := fp.ParseUpdateExpr(resource.Interface(), mask)
+	if err != nil {
+		if errors.Is(err, fieldmask.ErrInternalError) {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		// Find the detailed violations of the mask, if there are any.
+		var ve *fieldmask.ValidationError
+		if errors.As(fieldmask.Validate(mask, resource.Descriptor()), &ve) {
+			descriptions := make([]string, len(ve.Violations))
+			for j, v := range ve.Violations {
+				descriptions[j] = v.Error()
+			}
+			return invalidArgument(string(updateMaskField), descriptions...)
+		}
+		return invalidArgument(string(updateMaskField), err.Error())
+	}
+	p.Update = ue
+	return nil
+}
+
+// fieldmaskParser returns the shared update mask parser of the resource message.
+func (i *Interceptor) fieldmaskParser(resource proto.Message) (*fieldmask.Parser, error) {
+	name := resource.ProtoReflect().Descriptor().FullName()
+	if fp, ok := i.updates.Load(name); ok {
+		return fp.(*fieldmask.Parser), nil
+	}
+	var fp fieldmask.Parser
+	if err := fp.Reset(resource, i.fieldmaskOpts...); err != nil {
+		return nil, err
+	}
+	v, _ := i.updates.LoadOrStore(name, &fp)
+	return v.(*fieldmask.Parser), nil
+}
+
+// requestFields returns the standard fields of the request message descriptor md.
+func (i *Interceptor) requestFields(md protoreflect.MessageDescriptor) *requestFields {
+	if rf, ok := i.requests.Load(md.FullName()); ok {
+		return rf.(*requestFields)
+	}
+
+	rf := &requestFields{}
+	fields := md.Fields()
+	stringField := func(name protoreflect.Name) protoreflect.FieldDescriptor {
+		fd := fields.ByName(name)
+		if fd == nil || fd.Kind() != protoreflect.StringKind || fd.Cardinality() == protoreflect.Repeated {
+			return nil
+		}
+		return fd
+	}
+	rf.filter = stringField(filterField)
+	rf.orderBy = stringField(orderByField)
+	rf.pageToken = stringField(pageTokenField)
+
+	if fd := fields.ByName(updateMaskField); fd != nil && fd.Message() != nil && !fd.IsList() &&
+		fd.Message().FullName() == fieldMaskName {
+		rf.updateMask = fd
+		// The resource is the only singular message field of the request, other than the update mask.
+		for j := 0; j < fields.Len(); j++ {
+			f := fields.Get(j)
+			if f == fd || f.Kind() != protoreflect.MessageKind || f.IsList() || f.IsMap() {
+				continue
+			}
+			if rf.resource != nil {
+				rf.resource = nil
+				break
+			}
+			rf.resource = f
+		}
+	}
+
+	v, _ := i.requests.LoadOrStore(md.FullName(), rf)
+	return v.(*requestFields)
+}
+
+var fieldMaskName = (*fieldmaskpb.FieldMask)(nil).ProtoReflect().Descriptor().FullName()
+
+// fieldMaskOf returns the field mask of the m message, which could also be a dynamic message.
+func fieldMaskOf(m protoreflect.Message) *fieldmaskpb.FieldMask {
+	if fm, ok := m.Interface().(*fieldmaskpb.FieldMask); ok {
+		return fm
+	}
+	fm := &fieldmaskpb.FieldMask{}
+	if fd := m.Descriptor().Fields().ByName("paths"); fd != nil && fd.IsList() {
+		ls := m.Get(fd).List()
+		fm.Paths = make([]string, ls.Len())
+		for j := 0; j < ls.Len(); j++ {
+			fm.Paths[j] = ls.Get(j).String()
+		}
+	}
+	return fm
+}
+
+// invalidArgument returns the codes.InvalidArgument status error with the field violations of the request field.
+func invalidArgument(field string, descriptions ...string) error {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", field, descriptions[0]))
+	br := &errdetails.BadRequest{}
+	for _, d := range descriptions {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: field, Description: d})
+	}
+	ds, err := st.WithDetails(br)
+	if err != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
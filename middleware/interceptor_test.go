@@ -0,0 +1,201 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/blockysource/blocky-aip/cache"
+	"github.com/blockysource/blocky-aip/internal/testpb"
+	"github.com/blockysource/blocky-aip/pagination"
+)
+
+// requestDescriptors returns the list and update request descriptors of the testpb.Message resource.
+func requestDescriptors(t *testing.T) (list, update protoreflect.MessageDescriptor) {
+	t.Helper()
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	msg := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	opt := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("middleware/requests.proto"),
+		Package:    proto.String("middleware"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"internal/testpb/message.proto", "google/protobuf/field_mask.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("ListMessagesRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("filter"), Number: proto.Int32(1), Type: str, Label: opt},
+					{Name: proto.String("order_by"), Number: proto.Int32(2), Type: str, Label: opt},
+					{Name: proto.String("page_token"), Number: proto.Int32(3), Type: str, Label: opt},
+				},
+			},
+			{
+				Name: proto.String("UpdateMessageRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("message"), Number: proto.Int32(1), Type: msg, Label: opt, TypeName: proto.String(".testpb.Message")},
+					{Name: proto.String("update_mask"), Number: proto.Int32(2), Type: msg, Label: opt, TypeName: proto.String(".google.protobuf.FieldMask")},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to create file descriptor: %v", err)
+	}
+	return fd.Messages().Get(0), fd.Messages().Get(1)
+}
+
+func newListRequest(md protoreflect.MessageDescriptor, filter, orderBy, pageToken string) proto.Message {
+	m := dynamicpb.NewMessage(md)
+	m.Set(md.Fields().ByName("filter"), protoreflect.ValueOfString(filter))
+	m.Set(md.Fields().ByName("order_by"), protoreflect.ValueOfString(orderBy))
+	m.Set(md.Fields().ByName("page_token"), protoreflect.ValueOfString(pageToken))
+	return m
+}
+
+func newUpdateRequest(md protoreflect.MessageDescriptor, msg *testpb.Message, paths ...string) proto.Message {
+	m := dynamicpb.NewMessage(md)
+	m.Set(md.Fields().ByName("message"), protoreflect.ValueOfMessage(msg.ProtoReflect()))
+	m.Set(md.Fields().ByName("update_mask"), protoreflect.ValueOfMessage((&fieldmaskpb.FieldMask{Paths: paths}).ProtoReflect()))
+	return m
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+	listDesc, updateDesc := requestDescriptors(t)
+	resource := new(testpb.Message).ProtoReflect().Descriptor()
+
+	c, err := cache.NewMemory()
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	tokens, err := pagination.NewTokenValidator(c, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token validator: %v", err)
+	}
+	if err = tokens.Register(context.Background(), "issued"); err != nil {
+		t.Fatalf("failed to register token: %v", err)
+	}
+
+	ic, err := NewInterceptor(ListRequestOpt(listDesc, resource), PageTokenOpt(tokens))
+	if err != nil {
+		t.Fatalf("failed to create interceptor: %v", err)
+	}
+
+	tc := []struct {
+		name  string
+		req   proto.Message
+		check func(t *testing.T, p *Parsed)
+		field string
+	}{
+		{
+			name: "list",
+			req:  newListRequest(listDesc, `i32 > 1`, `name desc`, "issued"),
+			check: func(t *testing.T, p *Parsed) {
+				if p.Filter == nil || p.OrderBy == nil || p.PageToken != "issued" {
+					t.Errorf("expected filter, order by and page token, got %+v", p)
+				}
+			},
+		},
+		{
+			name: "empty list",
+			req:  newListRequest(listDesc, "", "", ""),
+			check: func(t *testing.T, p *Parsed) {
+				if p.Filter != nil || p.OrderBy != nil || p.PageToken != "" {
+					t.Errorf("expected empty parsed fields, got %+v", p)
+				}
+			},
+		},
+		{name: "invalid filter", req: newListRequest(listDesc, `unknown = 1`, "", ""), field: "filter"},
+		{name: "invalid order by", req: newListRequest(listDesc, "", `unknown desc`, ""), field: "order_by"},
+		{name: "invalid page token", req: newListRequest(listDesc, "", "", "forged"), field: "page_token"},
+		{
+			name: "update",
+			req:  newUpdateRequest(updateDesc, &testpb.Message{Str: "foo"}, "str"),
+			check: func(t *testing.T, p *Parsed) {
+				if p.Update == nil || len(p.Update.Elements) != 1 {
+					t.Errorf("expected single update element, got %+v", p.Update)
+				}
+			},
+		},
+		{name: "invalid update mask", req: newUpdateRequest(updateDesc, &testpb.Message{}, "unknown"), field: "update_mask"},
+		{
+			name: "not registered",
+			req:  new(testpb.Message),
+			check: func(t *testing.T, p *Parsed) {
+				if p.Filter != nil || p.OrderBy != nil || p.Update != nil {
+					t.Errorf("expected no parsed fields, got %+v", p)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				p, ok := FromContext(ctx)
+				if !ok {
+					t.Fatal("expected parsed fields in the context")
+				}
+				tt.check(t, p)
+				return req, nil
+			}
+
+			_, err := ic.Unary(context.Background(), tt.req, &grpc.UnaryServerInfo{}, handler)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !called {
+					t.Error("expected handler to be called")
+				}
+				return
+			}
+
+			if called {
+				t.Error("expected handler not to be called")
+			}
+			st, ok := status.FromError(err)
+			if !ok || st.Code() != codes.InvalidArgument {
+				t.Fatalf("expected invalid argument status, got %v", err)
+			}
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok && len(br.FieldViolations) > 0 {
+					if got := br.FieldViolations[0].Field; got != tt.field {
+						t.Errorf("expected violation of %q field, got %q", tt.field, got)
+					}
+					return
+				}
+			}
+			t.Errorf("expected bad request details, got %v", st.Details())
+		})
+	}
+}
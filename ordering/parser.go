@@ -17,6 +17,7 @@ package ordering
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 
@@ -48,6 +49,11 @@ type Parser struct {
 	msgDesc    protoreflect.MessageDescriptor
 	errHandler scanner.ErrorHandler
 
+	// randomOrder enables the random() order element.
+	randomOrder bool
+	// collations are the names of the allowed field collations.
+	collations []string
+
 	msgInfo info.MessagesInfo
 }
 
@@ -62,6 +68,31 @@ func ErrHandler(fn scanner.ErrorHandler) ParserOpt {
 	}
 }
 
+// RandomOrder enables the random order element 'random()', which shuffles the resources equal by the preceding
+// fields, i.e.: "priority desc, random()". It results in the expr.OrderByFieldExpr with the Random flag set.
+func RandomOrder() ParserOpt {
+	return func(p *Parser) error {
+		p.randomOrder = true
+		return nil
+	}
+}
+
+// Collations enables the collation suffixes of the string fields with given names, i.e.: "name collate ci desc".
+// The collation name is set in the expr.OrderByFieldExpr Collation, and its meaning is up to the backend.
+func Collations(names ...string) ParserOpt {
+	return func(p *Parser) error {
+		for _, name := range names {
+			if !isCollationName(name) {
+				return fmt.Errorf("invalid collation name: %q", name)
+			}
+			if !slices.Contains(p.collations, name) {
+				p.collations = append(p.collations, name)
+			}
+		}
+		return nil
+	}
+}
+
 // NewParser creates a new parser with a message descriptor and optional error handler.
 func NewParser(msg protoreflect.MessageDescriptor, opts ...ParserOpt) (*Parser, error) {
 	p := &Parser{msgDesc: msg}
@@ -134,6 +165,31 @@ func (p *Parser) Parse(orderBy string) (oe *expr.OrderByExpr, err error) {
 			return nil, ErrInvalidSyntax
 		}
 
+		if p.randomOrder && lit == "random" && peekToken(&s, token.LPAREN) {
+			cur, err := p.parseRandom(&s, pos)
+			if err != nil {
+				oe.Free()
+				return nil, err
+			}
+			oe.Fields = append(oe.Fields, cur)
+
+			s.SkipWhitespace()
+			pos, tok, _ = s.Scan()
+			switch tok {
+			case token.COMMA:
+				s.SkipWhitespace()
+				continue
+			case token.EOF:
+				return oe, nil
+			default:
+				if p.errHandler != nil {
+					p.errHandler(pos, fmt.Sprintf("expected comma or EOF but got %s", tok))
+				}
+				oe.Free()
+				return nil, ErrInvalidSyntax
+			}
+		}
+
 		// Set up current field context.
 		cur := expr.AcquireOrderByFieldExpr()
 
@@ -198,8 +254,17 @@ func (p *Parser) Parse(orderBy string) (oe *expr.OrderByExpr, err error) {
 		s.SkipWhitespace()
 
 		// Scan next token.
-		// It may either be a comma, order or EOF.
+		// It may either be a comma, order, collation or EOF.
 		pos, tok, lit = s.Scan()
+		if len(p.collations) > 0 && lit == "collate" {
+			if err = p.parseCollation(&s, pos, fd, cur); err != nil {
+				cur.Free()
+				oe.Free()
+				return nil, err
+			}
+			s.SkipWhitespace()
+			pos, tok, lit = s.Scan()
+		}
 		switch tok {
 		case token.COMMA:
 			// This means the end of the field order by expression
@@ -248,6 +313,62 @@ func (p *Parser) Parse(orderBy string) (oe *expr.OrderByExpr, err error) {
 	return oe, nil
 }
 
+// parseRandom parses the parentheses of the random() order element at pos.
+func (p *Parser) parseRandom(s *scanner.Scanner, pos token.Position) (*expr.OrderByFieldExpr, error) {
+	if _, tok, _ := s.Scan(); tok != token.RPAREN {
+		if p.errHandler != nil {
+			p.errHandler(pos, "expected random()")
+		}
+		return nil, ErrInvalidSyntax
+	}
+	cur := expr.AcquireOrderByFieldExpr()
+	cur.Random = true
+	return cur, nil
+}
+
+// parseCollation parses the collation name following the 'collate' keyword at pos, of the field fd.
+func (p *Parser) parseCollation(s *scanner.Scanner, pos token.Position, fd protoreflect.FieldDescriptor, cur *expr.OrderByFieldExpr) error {
+	if fd.Kind() != protoreflect.StringKind || fd.IsList() || fd.IsMap() {
+		if p.errHandler != nil {
+			p.errHandler(pos, fmt.Sprintf("field: %s is not a string field and cannot have a collation", fd.Name()))
+		}
+		return ErrInvalidField
+	}
+	s.SkipWhitespace()
+	npos, tok, lit := s.Scan()
+	if !tok.IsIdent() || !slices.Contains(p.collations, lit) {
+		if p.errHandler != nil {
+			p.errHandler(npos, fmt.Sprintf("unknown collation: %q", lit))
+		}
+		return ErrInvalidSyntax
+	}
+	cur.Collation = lit
+	return nil
+}
+
+// peekToken checks if the next token is tok, and consumes it if so.
+func peekToken(s *scanner.Scanner, tok token.Token) bool {
+	var ok bool
+	s.Peek(func(_ token.Position, t token.Token, _ string) bool {
+		ok = t == tok
+		return ok
+	})
+	return ok
+}
+
+// isCollationName checks if the name is a valid collation name, composed of the letters, digits and underscores.
+func isCollationName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 func setLatestTraverseField(obfe *expr.OrderByFieldExpr, fs *expr.FieldSelectorExpr) {
 	if obfe.Field == nil {
 		obfe.Field = fs
@@ -15,6 +15,7 @@
 package ordering
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/blockysource/blocky-aip/expr"
@@ -217,6 +218,79 @@ func TestParser_Parse(t *testing.T) {
 	}
 }
 
+func TestParser_ParseExtensions(t *testing.T) {
+	p, err := NewParser(md, RandomOrder(), Collations("ci", "de_DE"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []*expr.OrderByFieldExpr
+		err   error
+	}{
+		{name: "random", input: "random()", want: []*expr.OrderByFieldExpr{{Random: true}}},
+		{
+			name:  "random tiebreak",
+			input: "i64 desc, random()",
+			want: []*expr.OrderByFieldExpr{
+				{Field: &expr.FieldSelectorExpr{Field: "i64"}, Order: expr.DESC},
+				{Random: true},
+			},
+		},
+		{
+			name:  "collation",
+			input: "name collate ci, str collate de_DE desc",
+			want: []*expr.OrderByFieldExpr{
+				{Field: &expr.FieldSelectorExpr{Field: "name"}, Collation: "ci"},
+				{Field: &expr.FieldSelectorExpr{Field: "str"}, Collation: "de_DE", Order: expr.DESC},
+			},
+		},
+		{name: "random with order", input: "random() desc", err: ErrInvalidSyntax},
+		{name: "random without parentheses", input: "random(", err: ErrInvalidSyntax},
+		{name: "unknown collation", input: "name collate cs", err: ErrInvalidSyntax},
+		{name: "non string collation", input: "i64 collate ci", err: ErrInvalidField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Parse(tt.input)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer got.Free()
+
+			want := &expr.OrderByExpr{Fields: tt.want}
+			if !got.Equals(want) {
+				t.Errorf("expected %v but got %v", want.Fields, got.Fields)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		plain, err := NewParser(md)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err = plain.Parse("random()"); err == nil {
+			t.Errorf("expected error for the disabled random order")
+		}
+		if _, err = plain.Parse("name collate ci"); !errors.Is(err, ErrInvalidSyntax) {
+			t.Errorf("expected syntax error for the disabled collation, got %v", err)
+		}
+	})
+
+	if _, err = NewParser(md, Collations("c i")); err == nil {
+		t.Errorf("expected invalid collation name error")
+	}
+}
+
 func testErrHandler(t testing.TB, isErr bool) func(pos token.Position, msg string) {
 	return func(pos token.Position, msg string) {
 		if !isErr {
@@ -0,0 +1,87 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pagination
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blockysource/blocky-aip/cache"
+)
+
+// ErrInvalidToken is an error returned by the TokenValidator if the page token was not issued by the service,
+// or is expired.
+var ErrInvalidToken = errors.New("invalid page token")
+
+// TokenValidator verifies that the page tokens were issued by the service, and are not expired.
+// The issued tokens are registered in the cache, which could be shared across the service instances.
+type TokenValidator struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewTokenValidator creates a new validator, which keeps the registered tokens in the cache c for the ttl.
+func NewTokenValidator(c cache.Cache, ttl time.Duration) (*TokenValidator, error) {
+	if c == nil {
+		return nil, errors.New("cache is not set")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	return &TokenValidator{cache: c, ttl: ttl}, nil
+}
+
+// Tokenize encodes the input value with the TokenizeStruct, and registers the resulting token.
+func (v *TokenValidator) Tokenize(ctx context.Context, in any) (string, error) {
+	token, err := TokenizeStruct(in)
+	if err != nil {
+		return "", err
+	}
+	if err = v.Register(ctx, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Register registers the page token issued by the service.
+func (v *TokenValidator) Register(ctx context.Context, token string) error {
+	if err := v.cache.Set(ctx, tokenKey(token), []byte{1}, v.ttl); err != nil {
+		return fmt.Errorf("failed to register page token: %w", err)
+	}
+	return nil
+}
+
+// Validate checks if the page token was registered and is not expired.
+// If it is not, the returned error is ErrInvalidToken.
+func (v *TokenValidator) Validate(ctx context.Context, token string) error {
+	_, ok, err := v.cache.Get(ctx, tokenKey(token))
+	if err != nil {
+		return fmt.Errorf("failed to validate page token: %w", err)
+	}
+	if !ok {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// tokenKey returns the cache key of the token, which size doesn't depend on the token length.
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "pagination:" + hex.EncodeToString(sum[:])
+}
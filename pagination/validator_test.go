@@ -0,0 +1,67 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pagination
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blockysource/blocky-aip/cache"
+)
+
+func TestTokenValidator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, err := cache.NewMemory(cache.ClockOpt(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	v, err := NewTokenValidator(c, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	type token struct{ Offset int }
+	tk, err := v.Tokenize(ctx, token{Offset: 10})
+	if err != nil {
+		t.Fatalf("failed to tokenize: %v", err)
+	}
+	if err = v.Validate(ctx, tk); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	forged, err := TokenizeStruct(token{Offset: 20})
+	if err != nil {
+		t.Fatalf("failed to tokenize: %v", err)
+	}
+	if err = v.Validate(ctx, forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err = v.Validate(ctx, tk); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected error %v for expired token but got %v", ErrInvalidToken, err)
+	}
+
+	noop, err := NewTokenValidator(cache.Noop{}, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	if err = noop.Validate(ctx, tk); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected error %v but got %v", ErrInvalidToken, err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcename
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/blockysource/blocky-aip/internal/info"
+)
+
+// FromDescriptor compiles the patterns of the google.api.resource descriptor.
+func FromDescriptor(rd *annotations.ResourceDescriptor) (Patterns, error) {
+	if rd == nil || len(rd.GetPattern()) == 0 {
+		return nil, errors.New("resource descriptor has no patterns")
+	}
+	ps := make(Patterns, 0, len(rd.GetPattern()))
+	for _, raw := range rd.GetPattern() {
+		p, err := Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("resource %q: %w", rd.GetType(), err)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// ResourceOf returns the google.api.resource descriptor the message is annotated with.
+func ResourceOf(md protoreflect.MessageDescriptor) (*annotations.ResourceDescriptor, bool) {
+	rd, ok := info.GetExtension(md.Options(), annotations.E_Resource).(*annotations.ResourceDescriptor)
+	if !ok || rd == nil || rd.GetType() == "" {
+		return nil, false
+	}
+	return rd, true
+}
+
+// Lookup finds the descriptor of the resource type, i.e. 'library.googleapis.com/Book',
+// among the messages annotated with the google.api.resource and the google.api.resource_definition
+// of the file and its imports.
+func Lookup(f protoreflect.FileDescriptor, typ string) (*annotations.ResourceDescriptor, bool) {
+	return lookupFile(f, typ, make(map[string]struct{}))
+}
+
+func lookupFile(f protoreflect.FileDescriptor, typ string, visited map[string]struct{}) (*annotations.ResourceDescriptor, bool) {
+	if f == nil {
+		return nil, false
+	}
+	if _, ok := visited[f.Path()]; ok {
+		return nil, false
+	}
+	visited[f.Path()] = struct{}{}
+
+	defs, _ := info.GetExtension(f.Options(), annotations.E_ResourceDefinition).([]*annotations.ResourceDescriptor)
+	for _, rd := range defs {
+		if rd.GetType() == typ {
+			return rd, true
+		}
+	}
+	if rd, ok := lookupMessages(f.Messages(), typ); ok {
+		return rd, true
+	}
+	imports := f.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if rd, ok := lookupFile(imports.Get(i).FileDescriptor, typ, visited); ok {
+			return rd, true
+		}
+	}
+	return nil, false
+}
+
+func lookupMessages(mds protoreflect.MessageDescriptors, typ string) (*annotations.ResourceDescriptor, bool) {
+	for i := 0; i < mds.Len(); i++ {
+		md := mds.Get(i)
+		if rd, ok := ResourceOf(md); ok && rd.GetType() == typ {
+			return rd, true
+		}
+		if rd, ok := lookupMessages(md.Messages(), typ); ok {
+			return rd, true
+		}
+	}
+	return nil, false
+}
+
+// FieldPatterns returns the patterns of the resource names held by the string field:
+//
+//   - the name field of the message annotated with the google.api.resource, i.e. 'name',
+//   - the field annotated with the google.api.resource_reference type, i.e. 'book',
+//   - the field annotated with the google.api.resource_reference child_type, i.e. 'parent',
+//     whose patterns are the parents of the child resource patterns.
+//
+// The referenced resources are looked up in the field file and its imports.
+// It returns false if the field holds no resource names, or the resource is unknown.
+func FieldPatterns(fd protoreflect.FieldDescriptor) (Patterns, bool, error) {
+	if fd.Kind() != protoreflect.StringKind || fd.IsMap() {
+		return nil, false, nil
+	}
+
+	if rd, ok := ResourceOf(fd.ContainingMessage()); ok {
+		nameField := rd.GetNameField()
+		if nameField == "" {
+			nameField = "name"
+		}
+		if string(fd.Name()) == nameField {
+			ps, err := FromDescriptor(rd)
+			return ps, err == nil, err
+		}
+	}
+
+	ref, ok := info.GetExtension(fd.Options(), annotations.E_ResourceReference).(*annotations.ResourceReference)
+	if !ok || ref == nil {
+		return nil, false, nil
+	}
+	switch {
+	case ref.GetType() != "" && ref.GetType() != "*":
+		rd, ok := Lookup(fd.ParentFile(), ref.GetType())
+		if !ok {
+			return nil, false, nil
+		}
+		ps, err := FromDescriptor(rd)
+		return ps, err == nil, err
+	case ref.GetChildType() != "" && ref.GetChildType() != "*":
+		rd, ok := Lookup(fd.ParentFile(), ref.GetChildType())
+		if !ok {
+			return nil, false, nil
+		}
+		ps, err := FromDescriptor(rd)
+		if err != nil {
+			return nil, false, err
+		}
+		ps = ps.Parents()
+		return ps, len(ps) > 0, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,18 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resourcename provides the AIP-122 resource name patterns, i.e. 'projects/{project}/books/{book}',
+// compiled from the strings or the google.api.resource annotations, which parse the resource names
+// into the identifiers of their variables, and format the names back.
+package resourcename
@@ -0,0 +1,281 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcename
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	gob.Register(Name{})
+}
+
+// segment is a single segment of the pattern, either the collection literal or the variable.
+type segment struct {
+	value      string
+	isVariable bool
+}
+
+// Pattern is a compiled resource name pattern, i.e. 'projects/{project}/books/{book}'.
+// The literal segments are the collection identifiers, and the variables in braces are the resource identifiers.
+type Pattern struct {
+	raw      string
+	segments []segment
+}
+
+// Compile compiles the resource name pattern, i.e. 'projects/{project}/books/{book}'.
+// The segments are separated by the slashes and none of them could be empty.
+// The variable names need to be lower snake case, i.e. '{book_shelf}', and unique within the pattern.
+func Compile(pattern string) (*Pattern, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+	p := Pattern{raw: pattern}
+	seen := make(map[string]struct{})
+	for _, s := range strings.Split(pattern, "/") {
+		if s == "" {
+			return nil, fmt.Errorf("pattern %q has an empty segment", pattern)
+		}
+		if s[0] != '{' {
+			if strings.ContainsAny(s, "{}") {
+				return nil, fmt.Errorf("pattern %q has an invalid segment: %q", pattern, s)
+			}
+			p.segments = append(p.segments, segment{value: s})
+			continue
+		}
+		if s[len(s)-1] != '}' || !isVariableName(s[1:len(s)-1]) {
+			return nil, fmt.Errorf("pattern %q has an invalid variable: %q", pattern, s)
+		}
+		name := s[1 : len(s)-1]
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("pattern %q has a duplicated variable: %q", pattern, name)
+		}
+		seen[name] = struct{}{}
+		p.segments = append(p.segments, segment{value: name, isVariable: true})
+	}
+	return &p, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is invalid.
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the pattern in its string form.
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Variables returns the names of the pattern variables in their order, i.e. ['project', 'book'].
+func (p *Pattern) Variables() []string {
+	var vars []string
+	for _, s := range p.segments {
+		if s.isVariable {
+			vars = append(vars, s.value)
+		}
+	}
+	return vars
+}
+
+// Parent returns the pattern of the parent resource, i.e. 'projects/{project}' of the 'projects/{project}/books/{book}',
+// or nil if the pattern is a top level resource.
+// The singleton resources, i.e. 'projects/{project}/config', are the children of the resource they are nested in.
+func (p *Pattern) Parent() *Pattern {
+	n := len(p.segments) - 1
+	if n >= 0 && p.segments[n].isVariable {
+		n--
+	}
+	if n <= 0 {
+		return nil
+	}
+	parent := Pattern{segments: p.segments[:n:n]}
+	parent.raw = parent.format(nil)
+	return &parent
+}
+
+// Match checks if the resource name matches the pattern.
+func (p *Pattern) Match(name string) bool {
+	return p.parse(name, nil)
+}
+
+// Parse parses the resource name, i.e. 'projects/p1/books/b1', into the identifiers of the pattern variables.
+// Each identifier needs to be a non-empty segment of the name.
+func (p *Pattern) Parse(name string) (Name, error) {
+	ids := make(map[string]string, len(p.segments)/2)
+	if !p.parse(name, ids) {
+		return Name{}, fmt.Errorf("resource name %q does not match the pattern %q", name, p.raw)
+	}
+	return Name{Pattern: p.raw, IDs: ids}, nil
+}
+
+func (p *Pattern) parse(name string, ids map[string]string) bool {
+	for i, s := range p.segments {
+		var part string
+		if idx := strings.IndexByte(name, '/'); idx >= 0 {
+			if i == len(p.segments)-1 {
+				return false
+			}
+			part, name = name[:idx], name[idx+1:]
+		} else {
+			if i != len(p.segments)-1 {
+				return false
+			}
+			part, name = name, ""
+		}
+		if part == "" {
+			return false
+		}
+		if !s.isVariable {
+			if part != s.value {
+				return false
+			}
+			continue
+		}
+		if ids != nil {
+			ids[s.value] = part
+		}
+	}
+	return true
+}
+
+// Format formats the resource name with the identifiers of the pattern variables.
+// It returns an error if any identifier is missing, empty or contains a slash.
+func (p *Pattern) Format(ids map[string]string) (string, error) {
+	for _, s := range p.segments {
+		if !s.isVariable {
+			continue
+		}
+		id := ids[s.value]
+		if id == "" {
+			return "", fmt.Errorf("missing identifier of the variable %q", s.value)
+		}
+		if strings.IndexByte(id, '/') >= 0 {
+			return "", fmt.Errorf("identifier of the variable %q contains a slash: %q", s.value, id)
+		}
+	}
+	return p.format(ids), nil
+}
+
+func (p *Pattern) format(ids map[string]string) string {
+	var sb strings.Builder
+	for i, s := range p.segments {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		switch {
+		case !s.isVariable:
+			sb.WriteString(s.value)
+		case ids == nil:
+			sb.WriteByte('{')
+			sb.WriteString(s.value)
+			sb.WriteByte('}')
+		default:
+			sb.WriteString(ids[s.value])
+		}
+	}
+	return sb.String()
+}
+
+// Patterns are the patterns of a single resource type.
+// A resource could have multiple patterns, i.e. when it is nested in different parents.
+type Patterns []*Pattern
+
+// Parse parses the resource name with the first matching pattern.
+func (ps Patterns) Parse(name string) (Name, error) {
+	for _, p := range ps {
+		if n, err := p.Parse(name); err == nil {
+			return n, nil
+		}
+	}
+	if len(ps) == 1 {
+		return Name{}, fmt.Errorf("resource name %q does not match the pattern %q", name, ps[0].raw)
+	}
+	return Name{}, fmt.Errorf("resource name %q does not match any of the patterns: %s", name, ps)
+}
+
+// Match checks if the resource name matches any of the patterns.
+func (ps Patterns) Match(name string) bool {
+	for _, p := range ps {
+		if p.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parents returns the distinct parent patterns, skipping the top level resources.
+func (ps Patterns) Parents() Patterns {
+	var parents Patterns
+	seen := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		parent := p.Parent()
+		if parent == nil {
+			continue
+		}
+		if _, ok := seen[parent.raw]; ok {
+			continue
+		}
+		seen[parent.raw] = struct{}{}
+		parents = append(parents, parent)
+	}
+	return parents
+}
+
+// String returns the comma separated patterns.
+func (ps Patterns) String() string {
+	var sb strings.Builder
+	for i, p := range ps {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.raw)
+	}
+	return sb.String()
+}
+
+// Name is a parsed resource name.
+type Name struct {
+	// Pattern is the pattern matched by the name.
+	Pattern string
+
+	// IDs are the resource identifiers by the pattern variable names, i.e.: {"project": "p1", "book": "b1"}.
+	IDs map[string]string
+}
+
+// ID returns the identifier of the pattern variable, or an empty string if there is no such variable.
+func (n Name) ID(variable string) string {
+	return n.IDs[variable]
+}
+
+// isVariableName checks if the name is a lower snake case identifier, i.e. 'book_shelf'.
+func isVariableName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcename
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestCompile(t *testing.T) {
+	tc := []struct {
+		pattern string
+		vars    []string
+		isErr   bool
+	}{
+		{pattern: "projects/{project}/books/{book}", vars: []string{"project", "book"}},
+		{pattern: "projects/{project}/config", vars: []string{"project"}},
+		{pattern: "publishers/{publisher_id}", vars: []string{"publisher_id"}},
+		{pattern: "", isErr: true},
+		{pattern: "projects//books", isErr: true},
+		{pattern: "projects/{project", isErr: true},
+		{pattern: "projects/{Project}", isErr: true},
+		{pattern: "projects/x{project}", isErr: true},
+		{pattern: "projects/{id}/books/{id}", isErr: true},
+	}
+	for _, tt := range tc {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := Compile(tt.pattern)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.String() != tt.pattern {
+				t.Fatalf("expected pattern %q but got %q", tt.pattern, p.String())
+			}
+			if !reflect.DeepEqual(p.Variables(), tt.vars) {
+				t.Fatalf("expected variables %v but got %v", tt.vars, p.Variables())
+			}
+		})
+	}
+}
+
+func TestPattern_Parse(t *testing.T) {
+	p := MustCompile("projects/{project}/books/{book}")
+
+	tc := []struct {
+		name  string
+		ids   map[string]string
+		isErr bool
+	}{
+		{name: "projects/p1/books/b1", ids: map[string]string{"project": "p1", "book": "b1"}},
+		{name: "projects/-/books/b1", ids: map[string]string{"project": "-", "book": "b1"}},
+		{name: "projects/p1", isErr: true},
+		{name: "projects/p1/books/b1/pages/1", isErr: true},
+		{name: "projects/p1/shelves/b1", isErr: true},
+		{name: "projects//books/b1", isErr: true},
+		{name: "projects/p1/books/", isErr: true},
+		{name: "", isErr: true},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := p.Parse(tt.name)
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				if p.Match(tt.name) {
+					t.Fatalf("expected no match")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n.Pattern != p.String() || !reflect.DeepEqual(n.IDs, tt.ids) {
+				t.Fatalf("expected %v of %q but got %v of %q", tt.ids, p, n.IDs, n.Pattern)
+			}
+			s, err := p.Format(n.IDs)
+			if err != nil || s != tt.name {
+				t.Fatalf("expected formatted name %q but got %q, %v", tt.name, s, err)
+			}
+		})
+	}
+}
+
+func TestPattern_Format(t *testing.T) {
+	p := MustCompile("projects/{project}/books/{book}")
+	if _, err := p.Format(map[string]string{"project": "p1"}); err == nil {
+		t.Fatalf("expected missing identifier error")
+	}
+	if _, err := p.Format(map[string]string{"project": "p1", "book": "a/b"}); err == nil {
+		t.Fatalf("expected slash identifier error")
+	}
+}
+
+func TestPattern_Parent(t *testing.T) {
+	tc := []struct {
+		pattern string
+		parent  string
+	}{
+		{pattern: "projects/{project}/books/{book}", parent: "projects/{project}"},
+		{pattern: "projects/{project}/config", parent: "projects/{project}"},
+		{pattern: "projects/{project}", parent: ""},
+	}
+	for _, tt := range tc {
+		t.Run(tt.pattern, func(t *testing.T) {
+			parent := MustCompile(tt.pattern).Parent()
+			if tt.parent == "" {
+				if parent != nil {
+					t.Fatalf("expected no parent but got %q", parent)
+				}
+				return
+			}
+			if parent == nil || parent.String() != tt.parent {
+				t.Fatalf("expected parent %q but got %v", tt.parent, parent)
+			}
+			if !parent.Match("projects/p1") {
+				t.Fatalf("expected parent to match the project name")
+			}
+		})
+	}
+}
+
+func TestFromDescriptor(t *testing.T) {
+	ps, err := FromDescriptor(&annotations.ResourceDescriptor{
+		Type:    "library.example.com/Book",
+		Pattern: []string{"projects/{project}/books/{book}", "shelves/{shelf}/books/{book}"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n, err := ps.Parse("shelves/s1/books/b1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Pattern != "shelves/{shelf}/books/{book}" || n.ID("shelf") != "s1" || n.ID("book") != "b1" {
+		t.Fatalf("unexpected name: %v", n)
+	}
+	if _, err = ps.Parse("books/b1"); err == nil {
+		t.Fatalf("expected error")
+	}
+	if parents := ps.Parents(); parents.String() != "projects/{project}, shelves/{shelf}" {
+		t.Fatalf("unexpected parents: %s", parents)
+	}
+
+	if _, err = FromDescriptor(&annotations.ResourceDescriptor{Type: "library.example.com/Book"}); err == nil {
+		t.Fatalf("expected error of no patterns")
+	}
+	if _, err = FromDescriptor(&annotations.ResourceDescriptor{Pattern: []string{"books/{Book}"}}); err == nil {
+		t.Fatalf("expected error of invalid pattern")
+	}
+}
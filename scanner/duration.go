@@ -38,7 +38,7 @@ func (s *Scanner) scanDuration(used int, isFloating, isExp bool) (token.Token, s
 			topPow = 1
 		}
 	default:
-		topPow = prefixPower[s.ch]
+		topPow = durationPower(s.ch)
 	}
 
 	if isComposedDurationPrefix(s.ch) {
@@ -96,7 +96,7 @@ func (s *Scanner) scanDuration(used int, isFloating, isExp bool) (token.Token, s
 			return token.ILLEGAL, ""
 		}
 
-		pow := prefixPower[ch]
+		pow := durationPower(ch)
 		if pow >= topPow {
 			s.error(offset, "invalid duration")
 			return token.ILLEGAL, ""
@@ -130,7 +130,7 @@ func (s *Scanner) scanDuration(used int, isFloating, isExp bool) (token.Token, s
 }
 
 func isDurationPrefix(ch rune) bool {
-	return ch == 'n' || ch == 'u' || ch == 'µ' || ch == 'μ' || ch == 'm' || ch == 's' || ch == 'h'
+	return ch == 'n' || ch == 'u' || ch == 'µ' || ch == 'μ' || ch == 'm' || ch == 's' || ch == 'h' || ch == 'd' || ch == 'w'
 }
 
 func isComposedDurationPrefix(ch rune) bool {
@@ -143,4 +143,17 @@ var prefixPower = [...]rune{
 	'µ': -6,
 	's': 0,
 	'h': 2,
+	'd': 3,
+	'w': 4,
+}
+
+// durationPower returns the power of the duration unit prefix, where the Greek small letter mu is the micro sign alias.
+func durationPower(ch rune) rune {
+	if ch == 'μ' {
+		ch = 'µ'
+	}
+	if ch < 0 || int(ch) >= len(prefixPower) {
+		return 0
+	}
+	return prefixPower[ch]
 }
@@ -0,0 +1,80 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner_test
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+var fuzzSeeds = []string{
+	``,
+	`a = "b"`,
+	`a.b.c:* AND NOT d < 1.5e3 OR e != -10`,
+	`map."key" = 'value' AND ts > 2023-01-01T00:00:00Z`,
+	`d >= 10s AND fn(a, [1, 2], x{y: "z"})`,
+	`"unterminated`,
+	"`quoted` ~ @param",
+	`((((((((`,
+	"\xef\xbb\xbfa\xff = \x00",
+}
+
+func FuzzScanner(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		s := scanner.New(src, func(pos token.Position, msg string) {})
+		s.RegisterComparators([]string{"~", "^="})
+
+		// The scanner needs to make progress, though some of the invalid numbers result in the empty tokens.
+		last := token.Position(0)
+		for i := 0; i <= 4*len(src)+4; i++ {
+			pos, tok, _ := s.Scan()
+			if tok == token.EOF {
+				return
+			}
+			if pos < last || int(pos) > len(src) {
+				t.Fatalf("token %s position %d out of order after %d in %q", tok, pos, last, src)
+			}
+			last = pos
+		}
+		t.Fatalf("scanner didn't reach the EOF of %q", src)
+	})
+}
+
+func TestScanner_FuzzRegressions(t *testing.T) {
+	tc := []struct {
+		src string
+		tok token.Token
+		lit string
+	}{
+		{src: "0800", tok: token.INT, lit: "0800"},
+		{src: "0999-12-31T00:00:00Z", tok: token.TIMESTAMP, lit: "0999-12-31T00:00:00Z"},
+		{src: "0000-00-00T00:00:00Z", tok: token.ILLEGAL},
+		{src: "2023-01-00T00:00:00Z", tok: token.ILLEGAL},
+		{src: "0μ", tok: token.ILLEGAL},
+		{src: "\xf8", tok: token.ILLEGAL},
+	}
+	for _, tt := range tc {
+		s := scanner.New(tt.src, nil)
+		_, tok, lit := s.Scan()
+		if tok != tt.tok || lit != tt.lit {
+			t.Errorf("%q: expected %s %q but got %s %q", tt.src, tt.tok, tt.lit, tok, lit)
+		}
+	}
+}
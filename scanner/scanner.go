@@ -29,12 +29,19 @@ type Scanner struct {
 
 	// scanning state
 	ch         rune // current character
+	chw        int  // width in bytes of the current character
 	pch        rune // previous character
 	prev       token.Token
 	offset     int // character offset
 	err        ErrorHandler
 	ErrorCount int
 
+	reserved ReservedWords
+
+	comparators []string
+
+	trace TokenHandler
+
 	initialized bool
 
 	peeked struct {
@@ -56,6 +63,65 @@ func New(src string, err ErrorHandler) *Scanner {
 // ErrorHandler is an error message handler.
 type ErrorHandler func(pos token.Position, msg string)
 
+// ReservedWords is a set of the words which are not a part of the filtering syntax,
+// i.e. the operators of other query languages, like BETWEEN or LIKE.
+// Each word is mapped to an optional hint, explaining the supported alternative.
+type ReservedWords map[string]string
+
+// Reserve makes the scanner recognize the words as the token.RESERVED, instead of the identifiers.
+// The words are case-sensitive, and the backtick quoted identifiers are never reserved.
+// The reserved words are kept across the Reset calls.
+func (s *Scanner) Reserve(words ReservedWords) {
+	s.reserved = words
+}
+
+// RegisterComparators makes the scanner recognize the custom comparators as the token.CUSTOM, i.e. "~" or "^=".
+// A symbol comparator starts with one of the characters not used by the filtering syntax: ~ ^ $ % & | ? @ #,
+// followed by any of these characters or = < > ! :, i.e. "@>". A word comparator is an identifier, i.e. "LIKE",
+// which takes precedence over the reserved words. Of the symbols matching the input, the longest one is used.
+// Use the IsValidComparator to check the symbols before registering them.
+// The comparators are kept across the Reset calls.
+func (s *Scanner) RegisterComparators(symbols []string) {
+	s.comparators = symbols
+}
+
+// TokenHandler is a handler of the tokens consumed by the scanner.
+type TokenHandler func(pos token.Position, tok token.Token, lit string)
+
+// Trace sets the handler called with each token consumed by the Scan or the Peek, but not the peeked ones,
+// and not the whitespaces skipped by the SkipWhitespace. The tokens scanned again after the Restore
+// are reported again. A nil handler disables the tracing.
+// The handler is kept across the Reset calls.
+func (s *Scanner) Trace(fn TokenHandler) {
+	s.trace = fn
+}
+
+// IsValidComparator checks if the symbol could be registered as a custom comparator.
+func IsValidComparator(symbol string) bool {
+	if symbol == "" {
+		return false
+	}
+	if isCustomComparatorStart(rune(symbol[0])) {
+		for i := 1; i < len(symbol); i++ {
+			if ch := rune(symbol[i]); !isCustomComparatorStart(ch) && !isComparator(ch) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, ch := range symbol {
+		if !isLetter(ch) && (i == 0 || !isDecimal(ch)) {
+			return false
+		}
+	}
+	switch symbol {
+	case "true", "false", "AND", "OR", "NOT", "IN", "ASC", "asc", "DESC", "desc", "null":
+		return false
+	}
+	return true
+}
+
 // Reset prepares the scanner s to tokenize the text src by setting the scanner at the beginning of src.
 func (s *Scanner) Reset(src string, err ErrorHandler) {
 	s.src = src
@@ -86,12 +152,17 @@ func (s *Scanner) Peek(fn func(pos token.Position, tok token.Token, lit string)
 		s.peeked.pos = pos
 		s.peeked.tok = tok
 		s.peeked.lit = lit
+		return
+	}
+	if s.trace != nil {
+		s.trace(pos, tok, lit)
 	}
 }
 
 // Breakpoint is a breakpoint that can be used to restore the scanner to the current state.
 type Breakpoint struct {
 	ch     rune
+	chw    int
 	offset int
 	peeked struct {
 		pos      token.Position
@@ -106,6 +177,7 @@ type Breakpoint struct {
 func (s *Scanner) Breakpoint() Breakpoint {
 	return Breakpoint{
 		ch:               s.ch,
+		chw:              s.chw,
 		offset:           s.offset,
 		peeked:           s.peeked,
 		createdByScanner: true,
@@ -118,13 +190,18 @@ func (s *Scanner) Restore(bp Breakpoint) {
 		panic("breakpoint not created by scanner")
 	}
 	s.ch = bp.ch
+	s.chw = bp.chw
 	s.offset = bp.offset
 	s.peeked = bp.peeked
 }
 
 // Scan scans the next token and returns the token position, the token, and its literal.
 func (s *Scanner) Scan() (pos token.Position, tok token.Token, lit string) {
-	return s.scanOrPeekToken()
+	pos, tok, lit = s.scanOrPeekToken()
+	if s.trace != nil {
+		s.trace(pos, tok, lit)
+	}
+	return pos, tok, lit
 }
 
 func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit string) {
@@ -144,16 +221,31 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 	}
 
 	pos = s.pos()
+	if sym := s.matchComparator(); sym != "" {
+		for range sym {
+			s.next()
+		}
+		tok, lit = token.CUSTOM, sym
+		s.prev = tok
+		return pos, tok, lit
+	}
+
 	var (
-		isText, isString, isNumeric bool
+		isText, isString, isBytes, isNumeric, isQuotedIdent bool
 	)
 	switch s.ch {
 	case ' ', '\t', '\n', '\r':
 		tok = token.WS
 		lit = " "
 	case '=':
-		tok = token.EQUAL
-		lit = "="
+		if s.peek() == '~' {
+			s.next()
+			tok = token.MATCH
+			lit = "=~"
+		} else {
+			tok = token.EQUAL
+			lit = "="
+		}
 	case ':':
 		tok = token.COLON
 		lit = ":"
@@ -183,6 +275,12 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 		if p := s.peek(); isDecimal(p) || p == '.' {
 			isNumeric = true
 		}
+	case '+':
+		tok = token.PLUS
+		lit = "+"
+	case '/':
+		tok = token.SLASH
+		lit = "/"
 	case '<':
 		if s.peek() == '=' {
 			s.next()
@@ -209,11 +307,28 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 			s.pos()
 			tok = token.NEQ
 			lit = "!="
+		} else if s.peek() == ':' {
+			s.next()
+			tok = token.NHAS
+			lit = "!:"
 		} else {
 			isText = true
 		}
-	case '"', '\'', '`':
+	case '"', '\'':
 		isString = true
+	case 'b':
+		// The b prefixed quoted string is a bytes literal, i.e. b"aGVsbG8=".
+		// It is scanned as the BYTES token, and its content is decoded by the interpreter.
+		if p := s.peek(); p == '"' || p == '\'' {
+			s.next()
+			isBytes = true
+		} else {
+			isText = true
+		}
+	case '`':
+		// The backtick quoted text is an identifier, which is never a keyword,
+		// i.e.: `AND` is a field named AND.
+		isQuotedIdent = true
 	case eof:
 		tok = token.EOF
 	case '[':
@@ -232,14 +347,18 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 		// This is number or numeric.
 		isNumeric = true
 	default:
-		if isDecimal(s.ch) {
+		switch {
+		case isDecimal(s.ch):
 			isNumeric = true
-		} else {
+		case s.ch == utf8.RuneError && s.chw == 1:
+			s.error(int(pos), "invalid UTF-8 encoding")
+			tok = token.ILLEGAL
+		default:
 			isText = true
 		}
 	}
 
-	if !isText && !isString && !isNumeric {
+	if !isText && !isString && !isBytes && !isNumeric && !isQuotedIdent {
 		s.next() // consume the token character
 		return
 	}
@@ -248,6 +367,12 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 	case isString:
 		tok = token.STRING
 		lit = s.scanString()
+	case isBytes:
+		tok = token.BYTES
+		lit = s.scanString()
+	case isQuotedIdent:
+		tok = token.IDENT
+		lit = s.scanString()
 	case isNumeric:
 		tok, lit = s.scanNumber()
 	case isText:
@@ -272,6 +397,12 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 			tok = token.DESC
 		case "null":
 			tok = token.NULL
+		default:
+			if s.isWordComparator(lit) {
+				tok = token.CUSTOM
+			} else if _, ok := s.reserved[lit]; ok {
+				tok = token.RESERVED
+			}
 		}
 	}
 
@@ -281,27 +412,47 @@ func (s *Scanner) scanOrPeekToken() (pos token.Position, tok token.Token, lit st
 
 func (s *Scanner) scanString() string {
 	// opening quote is already consumed
-	isEscape := false
 	open := s.ch
-	var sb strings.Builder
+	start := s.offset
+
+	// The string without escape sequences is a substring of the source, and is returned without copying it.
 	for {
-		ch, _ := s.next()
+		ch, w := s.next()
+		if ch == open {
+			lit := s.src[start : s.offset-w]
+			s.next() // consume closing quote
+			return lit
+		}
+		if isEOF(ch) || ch == '\\' || (ch == utf8.RuneError && w == 1) {
+			break
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s.src[start : s.offset-s.chw])
+	isEscape := false
+	for ch := s.ch; ; ch, _ = s.next() {
 		if isEOF(ch) {
 			s.error(s.offset, "unterminated string")
 			break
 		}
 
 		// Handle escape characters.
+		// The escaped quote and backslash are unescaped, any other escape sequence is kept as is,
+		// so that i.e. the regular expression patterns like "\d+" are preserved.
+		if isEscape {
+			isEscape = false
+			if ch != open && ch != '\\' {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(ch)
+			continue
+		}
 		if ch == '\\' {
 			isEscape = true
 			continue
 		}
 		if ch == open {
-			if isEscape {
-				sb.WriteRune(ch)
-				isEscape = false
-				continue
-			}
 			s.next() // consume closing quote
 			break
 		}
@@ -311,12 +462,12 @@ func (s *Scanner) scanString() string {
 }
 
 func (s *Scanner) scanText() (token.Token, string) {
-	sum := utf8.RuneLen(s.ch)
+	sum := s.chw
 	offset := s.offset - sum
 
 	for {
 		ch, w := s.next()
-		if isBreaking(ch) {
+		if isBreaking(ch) || (len(s.comparators) > 0 && isCustomComparatorStart(ch)) {
 			break
 		}
 		sum += w
@@ -347,9 +498,11 @@ func (s *Scanner) next() (ch rune, w int) {
 		s.offset += w
 		s.pch = s.ch
 		s.ch = ch
+		s.chw = w
 		return ch, w
 	}
 	s.ch = eof
+	s.chw = 0
 	return s.ch, 1
 }
 
@@ -421,7 +574,7 @@ func (s *Scanner) isComparator(ch rune) bool {
 	}
 
 	if ch == '!' {
-		if s.peek() == '=' {
+		if p := s.peek(); p == '=' || p == ':' {
 			return true
 		}
 	}
@@ -433,6 +586,16 @@ func (s *Scanner) scanNumber() (token.Token, string) {
 		return s.scanNumeric(1)
 	}
 
+	// Check if it is a date or a time of day, i.e.: 2021-01-01 or 13:45:00.
+	if s.ch != '-' {
+		if n := dateLen(s.src[s.offset-1:]); n > 0 {
+			return s.scanFixed(token.DATE, n)
+		}
+		if n := timeOfDayLen(s.src[s.offset-1:]); n > 0 {
+			return s.scanFixed(token.TIME_OF_DAY, n)
+		}
+	}
+
 	// Check if it is not a timestamp in RFC3339 format.
 	offset := s.offset - 1
 	sum := 1
@@ -456,7 +619,9 @@ func (s *Scanner) scanNumber() (token.Token, string) {
 		case peek == '.':
 			return s.scanNumeric(1)
 		case isDurationPrefix(peek):
-			return s.scanDuration(1, false, false)
+			// A zero duration, i.e. '0s', move to the unit.
+			_, w := s.next()
+			return s.scanDuration(sum+w, false, false)
 		case isBreaking(peek):
 			s.next()
 			return token.INT, s.src[offset : offset+sum]
@@ -611,6 +776,40 @@ func isComparator(ch rune) bool {
 	return ch == '=' || ch == '<' || ch == '>' || ch == ':' || ch == '!'
 }
 
+// isCustomComparatorStart checks if the character could start a custom symbol comparator.
+func isCustomComparatorStart(ch rune) bool {
+	switch ch {
+	case '~', '^', '$', '%', '&', '|', '?', '@', '#':
+		return true
+	}
+	return false
+}
+
+// matchComparator returns the longest custom symbol comparator at the current position, if any.
+func (s *Scanner) matchComparator() string {
+	if len(s.comparators) == 0 || !isCustomComparatorStart(s.ch) {
+		return ""
+	}
+	rest := s.src[s.offset-1:]
+	var match string
+	for _, sym := range s.comparators {
+		if len(sym) > len(match) && isCustomComparatorStart(rune(sym[0])) && strings.HasPrefix(rest, sym) {
+			match = sym
+		}
+	}
+	return match
+}
+
+// isWordComparator checks if the text literal is a custom word comparator.
+func (s *Scanner) isWordComparator(lit string) bool {
+	for _, sym := range s.comparators {
+		if sym == lit {
+			return !isCustomComparatorStart(rune(sym[0]))
+		}
+	}
+	return false
+}
+
 func isNonPeriodBreaking(ch rune) bool {
 	return isEOF(ch) || isWhitespace(ch) || ch == '(' || ch == ')' || ch == ',' || isComparator(ch) ||
 		ch == ']' || ch == '}' || ch == '[' || ch == '{'
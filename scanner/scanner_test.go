@@ -15,6 +15,7 @@
 package scanner_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/blockysource/blocky-aip/scanner"
@@ -142,6 +143,82 @@ func TestScanner(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "timestamp with nanos",
+			src:  `2021-01-01T00:00:00.000000001+01:00 AND`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %d", pos)
+				}
+
+				if tok != token.TIMESTAMP {
+					t.Errorf("unexpected token: %s", tok)
+				}
+
+				if lit != "2021-01-01T00:00:00.000000001+01:00" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+			},
+		},
+		{
+			name: "timestamp with fraction",
+			src:  `2021-01-01T00:00:00.5Z`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				_, tok, lit := s.Scan()
+				if tok != token.TIMESTAMP {
+					t.Errorf("unexpected token: %s", tok)
+				}
+
+				if lit != "2021-01-01T00:00:00.5Z" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+			},
+		},
+		{
+			name: "date",
+			src:  `2021-01-01 AND`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %d", pos)
+				}
+
+				if tok != token.DATE {
+					t.Errorf("unexpected token: %s", tok)
+				}
+
+				if lit != "2021-01-01" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+
+				if _, tok, _ = s.Scan(); tok != token.WS {
+					t.Errorf("unexpected token: %s", tok)
+				}
+			},
+		},
+		{
+			name: "time of day",
+			src:  `13:45:00.5)`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %d", pos)
+				}
+
+				if tok != token.TIME_OF_DAY {
+					t.Errorf("unexpected token: %s", tok)
+				}
+
+				if lit != "13:45:00.5" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+
+				if _, tok, _ = s.Scan(); tok != token.RPAREN {
+					t.Errorf("unexpected token: %s", tok)
+				}
+			},
+		},
 		{
 			name: "integer",
 			src:  `123`,
@@ -232,6 +309,77 @@ func TestScanner(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "string with kept escape sequence",
+			src:  `"a\d+\\"`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				_, tok, lit := s.Scan()
+				if tok != token.STRING {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if lit != `a\d+\` {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+				if _, tok, _ = s.Scan(); tok != token.EOF {
+					t.Errorf("unexpected token: %s", tok)
+				}
+			},
+		},
+		{
+			name: "string with invalid utf-8",
+			src:  "\"a\xffb\"",
+			check: func(t *testing.T, s *scanner.Scanner) {
+				_, tok, lit := s.Scan()
+				if tok != token.STRING {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if lit != "a\uFFFDb" {
+					t.Errorf("unexpected literal: %q", lit)
+				}
+			},
+		},
+		{
+			name:  "unterminated string",
+			src:   `"abc`,
+			isErr: true,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				_, tok, lit := s.Scan()
+				if tok != token.STRING {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if lit != "abc" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+				if s.ErrorCount != 1 {
+					t.Errorf("expected one error but got %d", s.ErrorCount)
+				}
+			},
+		},
+		{
+			name: "backtick quoted identifier",
+			src:  "`AND`.`a\\`b`",
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %d", pos)
+				}
+				if tok != token.IDENT {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if lit != "AND" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+				if _, tok, _ = s.Scan(); tok != token.PERIOD {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if _, tok, lit = s.Scan(); tok != token.IDENT {
+					t.Errorf("unexpected token: %s", tok)
+				}
+				if lit != "a`b" {
+					t.Errorf("unexpected literal: %s", lit)
+				}
+			},
+		},
 		{
 			name: "text ws string",
 			src:  `text "string"`,
@@ -505,6 +653,70 @@ func TestScanner(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "duration zero second",
+			src:  `0s`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %v", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "0s" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
+		{
+			name: "duration zero milliseconds",
+			src:  `0ms`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %v", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "0ms" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
+		{
+			name: "duration zero minute",
+			src:  `0m`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %v", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "0m" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
+		{
+			name: "duration zero days",
+			src:  `0d`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position: %v", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "0d" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
 		{
 			name: "duration int minute",
 			src:  `1m`,
@@ -633,6 +845,91 @@ func TestScanner(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "negative duration days",
+			src:  `-7d`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position %d", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "-7d" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
+		{
+			name: "duration weeks and days",
+			src:  `1w2d12h)`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position %d", pos)
+				}
+				if tok != token.DURATION {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "1w2d12h" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+				if _, tok, _ = s.Scan(); tok != token.RPAREN {
+					t.Errorf("unexpected token: %v", tok)
+				}
+			},
+		},
+		{
+			name: "string escapes",
+			src:  `"a\"b\\c\d"`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				_, tok, lit := s.Scan()
+				if tok != token.STRING {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != `a"b\c\d` {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+				if _, tok, _ = s.Scan(); tok != token.EOF {
+					t.Errorf("unexpected token: %v", tok)
+				}
+			},
+		},
+		{
+			name: "regex match",
+			src:  `name=~"^foo"`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				if _, tok, _ := s.Scan(); tok != token.IDENT {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				pos, tok, lit := s.Scan()
+				if pos != 4 {
+					t.Errorf("unexpected position %d", pos)
+				}
+				if tok != token.MATCH {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "=~" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+				if _, tok, _ = s.Scan(); tok != token.STRING {
+					t.Errorf("unexpected token: %v", tok)
+				}
+			},
+		},
+		{
+			name: "arithmetic operators",
+			src:  "a + b / 2",
+			check: func(t *testing.T, s *scanner.Scanner) {
+				want := []token.Token{token.IDENT, token.WS, token.PLUS, token.WS, token.IDENT, token.WS, token.SLASH, token.WS, token.INT, token.EOF}
+				for i, w := range want {
+					if _, tok, _ := s.Scan(); tok != w {
+						t.Errorf("unexpected token %d: %v, want: %v", i, tok, w)
+					}
+				}
+			},
+		},
 		{
 			name: "hex int",
 			src:  "0x123",
@@ -649,6 +946,34 @@ func TestScanner(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "bytes string",
+			src:  `b"aGVsbG8="`,
+			check: func(t *testing.T, s *scanner.Scanner) {
+				pos, tok, lit := s.Scan()
+				if pos != 0 {
+					t.Errorf("unexpected position %d", pos)
+				}
+				if tok != token.BYTES {
+					t.Errorf("unexpected token: %v", tok)
+				}
+				if lit != "aGVsbG8=" {
+					t.Errorf("unexpected literal: %v", lit)
+				}
+			},
+		},
+		{
+			name: "b prefixed text",
+			src:  "bytes = b",
+			check: func(t *testing.T, s *scanner.Scanner) {
+				want := []token.Token{token.IDENT, token.WS, token.EQUAL, token.WS, token.IDENT, token.EOF}
+				for i, w := range want {
+					if _, tok, _ := s.Scan(); tok != w {
+						t.Errorf("unexpected token %d: %v, want: %v", i, tok, w)
+					}
+				}
+			},
+		},
 		{
 			name: "octal int",
 			src:  "0123",
@@ -699,3 +1024,140 @@ func errHandler(t *testing.T, src string, wantsErr bool) func(pos token.Position
 		}
 	}
 }
+
+func TestScanner_Reserve(t *testing.T) {
+	s := scanner.New("", nil)
+	s.Reserve(scanner.ReservedWords{"BETWEEN": ""})
+	s.Reset("x BETWEEN between `BETWEEN`", nil)
+
+	want := []struct {
+		tok token.Token
+		lit string
+	}{
+		{token.IDENT, "x"},
+		{token.WS, " "},
+		{token.RESERVED, "BETWEEN"},
+		{token.WS, " "},
+		{token.IDENT, "between"},
+		{token.WS, " "},
+		{token.IDENT, "BETWEEN"},
+		{token.EOF, ""},
+	}
+	for _, w := range want {
+		_, tok, lit := s.Scan()
+		if tok != w.tok || lit != w.lit {
+			t.Errorf("expected %s %q but got %s %q", w.tok, w.lit, tok, lit)
+		}
+	}
+}
+
+func TestScanner_RegisterComparators(t *testing.T) {
+	s := scanner.New("", nil)
+	s.RegisterComparators([]string{"~", "~~", "^=", "LIKE"})
+	s.Reset("a~b c ~~ d e^=\"f\" g LIKE h ~= i", nil)
+
+	want := []struct {
+		tok token.Token
+		lit string
+	}{
+		{token.IDENT, "a"},
+		{token.CUSTOM, "~"},
+		{token.IDENT, "b"},
+		{token.WS, " "},
+		{token.IDENT, "c"},
+		{token.WS, " "},
+		{token.CUSTOM, "~~"},
+		{token.WS, " "},
+		{token.IDENT, "d"},
+		{token.WS, " "},
+		{token.IDENT, "e"},
+		{token.CUSTOM, "^="},
+		{token.STRING, "f"},
+		{token.WS, " "},
+		{token.IDENT, "g"},
+		{token.WS, " "},
+		{token.CUSTOM, "LIKE"},
+		{token.WS, " "},
+		{token.IDENT, "h"},
+		{token.WS, " "},
+		{token.CUSTOM, "~"},
+		{token.EQUAL, "="},
+	}
+	for _, w := range want {
+		_, tok, lit := s.Scan()
+		if tok != w.tok || lit != w.lit {
+			t.Errorf("expected %s %q but got %s %q", w.tok, w.lit, tok, lit)
+		}
+	}
+}
+
+func TestScanner_Trace(t *testing.T) {
+	var got []string
+	s := scanner.New("", nil)
+	s.Trace(func(pos token.Position, tok token.Token, lit string) {
+		got = append(got, fmt.Sprintf("%d:%s", pos, lit))
+	})
+	s.Reset("a = b", nil)
+
+	s.Scan()
+	// The peeked token is not reported until it is consumed.
+	s.Peek(func(pos token.Position, tok token.Token, lit string) bool { return false })
+	s.Peek(func(pos token.Position, tok token.Token, lit string) bool { return true })
+	s.SkipWhitespace()
+	bp := s.Breakpoint()
+	s.Scan()
+	s.Restore(bp)
+	s.Scan()
+
+	want := []string{"0:a", "1: ", "2:=", "2:="}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v but got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestIsValidComparator(t *testing.T) {
+	for _, symbol := range []string{"~", "^=", "@>", "!~", "LIKE", "starts_with"} {
+		want := symbol != "!~"
+		if got := scanner.IsValidComparator(symbol); got != want {
+			t.Errorf("expected IsValidComparator(%q) = %v", symbol, want)
+		}
+	}
+	for _, symbol := range []string{"", "=", "<=", "AND", "a b", "~ "} {
+		if scanner.IsValidComparator(symbol) {
+			t.Errorf("expected %q not to be a valid comparator", symbol)
+		}
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		src  string
+	}{
+		{name: "Text", src: "a.b.c = d AND e:f"},
+		{name: "String", src: `a = "some string value"`},
+		{name: "EscapedString", src: `a = "some \"escaped\" string"`},
+		{name: "Numbers", src: "a > 10 AND b < 2.5e3 AND c = 0x1F"},
+		{name: "Timestamp", src: "a > 2023-01-01T00:00:00.123Z AND b < 10.5s"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var s scanner.Scanner
+			b.SetBytes(int64(len(bc.src)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Reset(bc.src, nil)
+				for {
+					if _, tok, _ := s.Scan(); tok == token.EOF {
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -37,7 +37,12 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 			return token.ILLEGAL, ""
 		}
 	}
-	year, err := strconv.Atoi(s.src[offset : offset+sum])
+	// Unless the year was scanned by the caller, the current character is its last digit.
+	yearLen := sum
+	if used == 4 {
+		yearLen += s.chw
+	}
+	year, err := strconv.Atoi(s.src[offset : offset+yearLen])
 	if err != nil {
 		s.error(s.offset, "invalid timestamp")
 		return token.ILLEGAL, ""
@@ -46,7 +51,7 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 	if used == 4 {
 		ch, w := s.next()
 		if isBreaking(ch) {
-			return token.INT, s.src[offset : offset+sum]
+			return token.INT, s.src[offset : offset+yearLen]
 		}
 
 		if ch != '-' {
@@ -54,6 +59,7 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 			return token.ILLEGAL, ""
 		}
 		sum += w
+		used++
 	}
 
 	var (
@@ -95,7 +101,7 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 		mm += int(ch - '0')
 	}
 
-	if mm > 12 {
+	if mm < 1 || mm > 12 {
 		s.error(s.offset, "invalid timestamp")
 		return token.ILLEGAL, ""
 	}
@@ -143,7 +149,7 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 		dd += int(ch - '0')
 	}
 
-	if dd > 31 {
+	if dd < 1 || dd > 31 {
 		s.error(s.offset, "invalid timestamp")
 		return token.ILLEGAL, ""
 	}
@@ -292,6 +298,23 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 
 	if used == 19 {
 		ch, w := s.next()
+		if isPeriod(ch) {
+			// The fractional seconds, up to the nanosecond precision.
+			sum += w
+			var digits int
+			for {
+				ch, w = s.next()
+				if !isDecimal(ch) {
+					break
+				}
+				sum += w
+				digits++
+			}
+			if digits == 0 || digits > 9 {
+				s.error(s.offset, "invalid timestamp")
+				return token.ILLEGAL, ""
+			}
+		}
 		if isBreaking(ch) {
 			return token.ILLEGAL, ""
 		}
@@ -391,6 +414,82 @@ func (s *Scanner) scanTimestamp(used int) (token.Token, string) {
 	return token.TIMESTAMP, s.src[offset : offset+sum]
 }
 
+// scanFixed scans the literal of the known length n, starting at the current character.
+func (s *Scanner) scanFixed(tok token.Token, n int) (token.Token, string) {
+	offset := s.offset - 1
+	for i := 0; i < n; i++ {
+		s.next()
+	}
+	return tok, s.src[offset : offset+n]
+}
+
+// dateLen returns the length of the date literal at the beginning of src, i.e.: 2021-01-01,
+// or 0 if src doesn't start with a date, or the date is a part of the timestamp.
+// The values of the date are not verified.
+func dateLen(src string) int {
+	const n = len("2006-01-02")
+	if len(src) < n {
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		switch i {
+		case 4, 7:
+			if src[i] != '-' {
+				return 0
+			}
+		default:
+			if !isDecimal(rune(src[i])) {
+				return 0
+			}
+		}
+	}
+	if len(src) == n {
+		return n
+	}
+	switch ch := rune(src[n]); {
+	case ch == 'T':
+		return 0
+	case ch == ' ' && len(src) > n+1 && isDecimal(rune(src[n+1])):
+		// A timestamp with the space separator, i.e.: 2021-01-01 00:00:00Z.
+		return 0
+	case isBreaking(ch):
+		return n
+	}
+	return 0
+}
+
+// timeOfDayLen returns the length of the time of day literal at the beginning of src,
+// i.e.: 13:45:00 or 13:45:00.123, or 0 if src doesn't start with a time of day.
+// The values of the time are not verified.
+func timeOfDayLen(src string) int {
+	n := len("15:04:05")
+	if len(src) < n {
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		switch i {
+		case 2, 5:
+			if src[i] != ':' {
+				return 0
+			}
+		default:
+			if !isDecimal(rune(src[i])) {
+				return 0
+			}
+		}
+	}
+	if len(src) > n+1 && src[n] == '.' && isDecimal(rune(src[n+1])) {
+		n++
+		for n < len(src) && isDecimal(rune(src[n])) {
+			n++
+		}
+	}
+	if len(src) == n || isBreaking(rune(src[n])) {
+		return n
+	}
+	return 0
+}
+
 func isLeap(year int) bool {
 	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
 }
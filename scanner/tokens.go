@@ -0,0 +1,156 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/blockysource/blocky-aip/token"
+)
+
+// Token is a scanned token along with its position in the source.
+type Token struct {
+	// Pos is the byte offset of the first character of the token in the source.
+	Pos token.Position
+
+	// End is the byte offset just past the last character of the token,
+	// so that the source text of the token is src[Pos:End].
+	End token.Position
+
+	// Tok is the type of the token.
+	Tok token.Token
+
+	// Lit is the literal of the token, i.e. the unquoted value of a string,
+	// or the whole run of the whitespace characters.
+	Lit string
+}
+
+// String returns a human-readable representation of the token.
+func (t Token) String() string {
+	return fmt.Sprintf("%d:%d %s %q", t.Pos, t.End, t.Tok, t.Lit)
+}
+
+// Error is an error found while tokenizing the source.
+type Error struct {
+	// Pos is the position of the error in the source.
+	Pos token.Position
+
+	// Msg is the error message.
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// TokenIterator iterates over the tokens of the source, without invoking the parser.
+// It is meant for the tooling, i.e. syntax highlighters or linters.
+// The consecutive whitespace characters are returned as a single WS token.
+// The iteration stops after the EOF token is returned.
+//
+//	it := scanner.Tokens(src)
+//	for it.Next() {
+//		tok := it.Token()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type TokenIterator struct {
+	s    Scanner
+	tok  Token
+	errs []*Error
+	done bool
+}
+
+// Tokens returns a TokenIterator over the tokens of the src.
+func Tokens(src string) *TokenIterator {
+	it := &TokenIterator{}
+	it.s.Reset(src, func(pos token.Position, msg string) {
+		it.errs = append(it.errs, &Error{Pos: pos, Msg: msg})
+	})
+	return it
+}
+
+// Next advances the iterator to the next token, which is then available through the Token method.
+// It returns false when the iteration is finished, i.e. after the EOF token.
+// The iteration continues after an invalid token, which is returned as the ILLEGAL token,
+// and its error is reported by the Err method.
+func (it *TokenIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	start := it.s.current()
+	_, tok, lit := it.s.Scan()
+	end := it.s.current()
+
+	switch {
+	case tok == token.EOF:
+		it.done = true
+	case tok == token.WS:
+		for isWhitespace(it.s.ch) {
+			it.s.next()
+		}
+		end = it.s.current()
+		lit = it.s.src[start:end]
+	case end == start:
+		// Guard against the scanner not advancing, so that the iteration always finishes.
+		it.errs = append(it.errs, &Error{Pos: token.Position(start), Msg: "scanner did not advance"})
+		it.done = true
+		return false
+	}
+
+	it.tok = Token{Pos: token.Position(start), End: token.Position(end), Tok: tok, Lit: lit}
+	return true
+}
+
+// Token returns the current token of the iterator.
+func (it *TokenIterator) Token() Token {
+	return it.tok
+}
+
+// Err returns the first error found while tokenizing the source, or nil.
+func (it *TokenIterator) Err() error {
+	if len(it.errs) == 0 {
+		return nil
+	}
+	return it.errs[0]
+}
+
+// Errors returns all the errors found while tokenizing the source so far.
+func (it *TokenIterator) Errors() []*Error {
+	return it.errs
+}
+
+// Tokenize returns all the tokens of the src, including the final EOF token,
+// along with the first error found while tokenizing it.
+func Tokenize(src string) ([]Token, error) {
+	it := Tokens(src)
+	var out []Token
+	for it.Next() {
+		out = append(out, it.Token())
+	}
+	return out, it.Err()
+}
+
+// current returns the byte offset of the current character of the scanner.
+func (s *Scanner) current() int {
+	if s.ch == eof {
+		return len(s.src)
+	}
+	return s.offset - s.chw
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Blocky Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner_test
+
+import (
+	"testing"
+
+	"github.com/blockysource/blocky-aip/scanner"
+	"github.com/blockysource/blocky-aip/token"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		want  []token.Token
+		isErr bool
+	}{
+		{name: "empty", src: ``, want: []token.Token{token.EOF}},
+		{
+			name: "restriction",
+			src:  `a.b  >= "x y"`,
+			want: []token.Token{token.IDENT, token.PERIOD, token.IDENT, token.WS, token.GEQ, token.WS, token.STRING, token.EOF},
+		},
+		{
+			name: "logical",
+			src:  "NOT a:1 OR\t`AND` = true",
+			want: []token.Token{token.NOT, token.WS, token.IDENT, token.COLON, token.INT, token.WS, token.OR, token.WS, token.IDENT, token.WS, token.EQUAL, token.WS, token.TRUE, token.EOF},
+		},
+		{
+			name:  "unterminated string",
+			src:   `a = "foo`,
+			want:  []token.Token{token.IDENT, token.WS, token.EQUAL, token.WS, token.STRING, token.EOF},
+			isErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toks, err := scanner.Tokenize(tt.src)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(toks) != len(tt.want) {
+				t.Fatalf("expected %d tokens but got %d: %v", len(tt.want), len(toks), toks)
+			}
+			for i, tok := range toks {
+				if tok.Tok != tt.want[i] {
+					t.Errorf("token %d: expected %s but got %s", i, tt.want[i], tok)
+				}
+			}
+		})
+	}
+}
+
+func TestTokens_Positions(t *testing.T) {
+	src := `a.bé  <= "x\"y"`
+	it := scanner.Tokens(src)
+
+	var raw []string
+	var last scanner.Token
+	for it.Next() {
+		tok := it.Token()
+		if tok.Pos != last.End {
+			t.Errorf("expected token %s to start at %d", tok, last.End)
+		}
+		raw = append(raw, src[tok.Pos:tok.End])
+		last = tok
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if int(last.End) != len(src) || last.Tok != token.EOF {
+		t.Errorf("expected EOF at %d but got %s", len(src), last)
+	}
+
+	want := []string{"a", ".", "bé", "  ", "<=", " ", `"x\"y"`, ""}
+	if len(raw) != len(want) {
+		t.Fatalf("expected %q but got %q", want, raw)
+	}
+	for i := range want {
+		if raw[i] != want[i] {
+			t.Errorf("token %d: expected %q but got %q", i, want[i], raw[i])
+		}
+	}
+	if it.Next() {
+		t.Error("expected no tokens after EOF")
+	}
+}
@@ -26,13 +26,22 @@ const (
 	// STRING is a special type of literal, which is not defined by the standard EBNF.
 	// It defines a string literal.
 	STRING // "abc" or 'abc'
+	// BYTES is a special type of literal, which is not defined by the standard EBNF.
+	// It defines a b prefixed string literal of the base64 encoded bytes value.
+	BYTES // b"YWJj"
 	non_string_literal_beg
 	// IDENT is a special type of literal, which is not defined by the standard EBNF.
-	// It defines either an identifier or a keyword.
-	IDENT // abc
+	// It defines either an identifier or a keyword, the backtick quoted identifier is never a keyword.
+	IDENT // abc | `AND`
 	// TIMESTAMP is a special type of literal, which is not defined by the standard EBNF.
 	// It is used to represent a IDENT literal, which is a valid timestamp.
 	TIMESTAMP // 2021-01-01T00:00:00Z
+	// DATE is a special type of literal, which is not defined by the standard EBNF.
+	// It is used to represent a IDENT literal, which is a calendar date without the time.
+	DATE // 2021-01-01
+	// TIME_OF_DAY is a special type of literal, which is not defined by the standard EBNF.
+	// It is used to represent a IDENT literal, which is a time of day, optionally with the fractional seconds.
+	TIME_OF_DAY // 13:45:00 | 13:45:00.5
 
 	numbers_beg
 	// NUMERIC is a special type of literal, which is not defined by the standard EBNF.
@@ -86,6 +95,11 @@ const (
 	GT    // >
 	GEQ   // >=
 	NEQ   // !=
+	MATCH // =~ regex match extension to the standard
+	NHAS  // !: negated has extension to the standard
+	// CUSTOM is a special token, which is not defined by the standard EBNF.
+	// It represents a custom comparator registered with the scanner, i.e. ~ or ^=.
+	CUSTOM
 	comparator_end
 
 	additional_beg
@@ -100,7 +114,14 @@ const (
 	COLON         // :
 	ASTERISK      // *
 	MINUS         // -
+	PLUS          // + arithmetic extension to the standard
+	SLASH         // / arithmetic extension to the standard
 	additional_end
+
+	// RESERVED is a special token, which is not defined by the standard EBNF.
+	// It represents a word reserved by the scanner, which is not a part of the filtering syntax,
+	// i.e. an operator of another query language, like BETWEEN.
+	RESERVED
 )
 
 var tokens = [...]string{
@@ -108,18 +129,21 @@ var tokens = [...]string{
 	EOF:     "EOF",
 	WS:      "WS",
 
-	IDENT:     "IDENT",
-	TIMESTAMP: "TIMESTAMP",
-	NUMERIC:   "NUMERIC",
-	INT:       "INT",
-	HEX:       "HEX",
-	OCT:       "OCT",
-	DURATION:  "DURATION",
-	TRUE:      "true",
-	FALSE:     "false",
-	NULL:      "null",
+	IDENT:       "IDENT",
+	TIMESTAMP:   "TIMESTAMP",
+	DATE:        "DATE",
+	TIME_OF_DAY: "TIME_OF_DAY",
+	NUMERIC:     "NUMERIC",
+	INT:         "INT",
+	HEX:         "HEX",
+	OCT:         "OCT",
+	DURATION:    "DURATION",
+	TRUE:        "true",
+	FALSE:       "false",
+	NULL:        "null",
 
 	STRING: "STRING",
+	BYTES:  "BYTES",
 
 	AND:  "AND",
 	OR:   "OR",
@@ -134,6 +158,10 @@ var tokens = [...]string{
 	GT:    ">",
 	GEQ:   ">=",
 	NEQ:   "!=",
+	MATCH: "=~",
+	NHAS:  "!:",
+
+	CUSTOM: "CUSTOM",
 
 	LPAREN:        "(",
 	RPAREN:        ")",
@@ -145,6 +173,10 @@ var tokens = [...]string{
 	BRACE_CLOSE:   "}",
 	COLON:         ":",
 	MINUS:         "-",
+	PLUS:          "+",
+	SLASH:         "/",
+
+	RESERVED: "RESERVED",
 }
 
 func (t Token) String() string        { return tokens[t] }